@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// subBuckets is the number of buckets per power of two of microseconds,
+// which bounds the relative error of the recorded latencies by 1/subBuckets.
+const subBuckets = 16
+
+// histogram counts latencies in log-linear buckets of microseconds, so that
+// quantiles can be read at any time with bounded memory.
+type histogram struct {
+	counts []uint64
+	n      uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+// bucketOf returns the bucket of us microseconds.
+func bucketOf(us uint64) int {
+	if us < subBuckets {
+		return int(us)
+	}
+	shift := bits.Len64(us) - bits.Len64(subBuckets)
+	return shift*subBuckets + int(us>>shift)
+}
+
+// bucketBounds returns the lowest and highest microseconds of bucket i.
+func bucketBounds(i int) (lo, hi uint64) {
+	if i < subBuckets {
+		return uint64(i), uint64(i)
+	}
+	shift := i/subBuckets - 1
+	sub := uint64(i%subBuckets + subBuckets)
+	return sub << shift, (sub+1)<<shift - 1
+}
+
+// Record adds a latency.
+func (h *histogram) Record(d time.Duration) {
+	i := bucketOf(uint64(max(d.Microseconds(), 0)))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	h.n++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+// Merge adds the latencies of o.
+func (h *histogram) Merge(o *histogram) {
+	if len(o.counts) > len(h.counts) {
+		h.counts = append(h.counts, make([]uint64, len(o.counts)-len(h.counts))...)
+	}
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.n += o.n
+	h.sum += o.sum
+	h.max = max(h.max, o.max)
+}
+
+// Count returns the number of latencies recorded.
+func (h *histogram) Count() uint64 {
+	return h.n
+}
+
+// Mean returns the mean latency, 0 if none was recorded.
+func (h *histogram) Mean() time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.n)
+}
+
+// Max returns the highest latency recorded.
+func (h *histogram) Max() time.Duration {
+	return h.max
+}
+
+// Quantile returns the latency below which a fraction q of the latencies
+// fall, rounded up to the end of its bucket, 0 if none was recorded.
+func (h *histogram) Quantile(q float64) time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(q * float64(h.n)))
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= max(rank, 1) {
+			_, hi := bucketBounds(i)
+			return min(time.Duration(hi+1)*time.Microsecond, h.max)
+		}
+	}
+	return h.max
+}
+
+// histogramWidth is the width of the bars of WriteTo
+const histogramWidth = 40
+
+// WriteTo draws the histogram with one bar per power of two, from the
+// fastest latency to the slowest.
+func (h *histogram) WriteTo(w io.Writer) (int64, error) {
+	type row struct {
+		lo, hi time.Duration
+		count  uint64
+	}
+	var rows []row
+	var most uint64
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		lo, _ := bucketBounds(i)
+		from := time.Duration(1) << max(bits.Len64(lo)-1, 0) * time.Microsecond
+		if len(rows) == 0 || rows[len(rows)-1].lo != from {
+			rows = append(rows, row{lo: from, hi: 2 * from})
+		}
+		rows[len(rows)-1].count += c
+		most = max(most, rows[len(rows)-1].count)
+	}
+	var n int64
+	for _, r := range rows {
+		bar := strings.Repeat("#", int(math.Ceil(float64(r.count)/float64(most)*histogramWidth)))
+		m, err := fmt.Fprintf(w, "  %9s - %-9s %-*s %d\n", r.lo, r.hi, histogramWidth, bar, r.count)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
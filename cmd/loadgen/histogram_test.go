@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBucketOf tests that every latency falls within the bounds of its
+// bucket, and that buckets are within 1/subBuckets of their latencies
+func TestBucketOf(t *testing.T) {
+	for us := uint64(0); us < 1<<20; us += 1 + us/100 {
+		i := bucketOf(us)
+		lo, hi := bucketBounds(i)
+		if us < lo || us > hi {
+			t.Fatalf("%dµs in bucket %d of %d to %d", us, i, lo, hi)
+		}
+		if hi-lo > lo/subBuckets {
+			t.Fatalf("bucket %d of %d to %d is too wide", i, lo, hi)
+		}
+	}
+}
+
+// TestHistogram tests the statistics and quantiles of a histogram
+func TestHistogram(t *testing.T) {
+	var h, other histogram
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	other.Record(5 * time.Second)
+	h.Merge(&other)
+
+	if h.Count() != 1001 || h.Max() != 5*time.Second {
+		t.Fatalf("expected 1001 latencies up to 5s, got %d up to %s", h.Count(), h.Max())
+	}
+	if mean := h.Mean(); mean < 504*time.Millisecond || mean > 505*time.Millisecond {
+		t.Errorf("expected a mean of about 504.5ms, got %s", mean)
+	}
+	for _, tc := range []struct {
+		q    float64
+		want time.Duration
+	}{{0.5, 501 * time.Millisecond}, {0.99, 991 * time.Millisecond}, {1, 5 * time.Second}} {
+		got := h.Quantile(tc.q)
+		if got < tc.want || got > tc.want+tc.want/subBuckets {
+			t.Errorf("quantile %v: expected %s to %s, got %s", tc.q, tc.want, tc.want+tc.want/subBuckets, got)
+		}
+	}
+
+	var empty histogram
+	if empty.Quantile(0.99) != 0 || empty.Mean() != 0 {
+		t.Error("expected an empty histogram to report zero")
+	}
+}
+
+// TestHistogramWriteTo tests that the histogram is drawn with a row per
+// power of two
+func TestHistogramWriteTo(t *testing.T) {
+	var h histogram
+	for range 10 {
+		h.Record(1500 * time.Microsecond)
+	}
+	h.Record(3 * time.Millisecond)
+
+	var b strings.Builder
+	if _, err := h.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 rows, got %q", b.String())
+	}
+	if !strings.Contains(lines[0], "1.024ms") || !strings.Contains(lines[0], strings.Repeat("#", histogramWidth)+" 10") {
+		t.Errorf("unexpected first row %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "2.048ms") || !strings.Contains(lines[1], " #### ") || !strings.HasSuffix(lines[1], " 1") {
+		t.Errorf("unexpected second row %q", lines[1])
+	}
+}
@@ -0,0 +1,257 @@
+// Command loadgen drives a mix of transfers and reads against the transfers
+// API for a while, then prints the latency of each operation and checks it
+// against service level objectives, exiting with status 1 if one is missed.
+//
+// Usage:
+//
+//	loadgen [flags]
+//
+// It creates -accounts accounts with a large balance to make its requests
+// on, or reuses the accounts given by -account-ids. For example:
+//
+//	loadgen -url http://localhost:8080 -duration 1m -rate 200 \
+//		-mix transfer=70,balance=25,history=5 -slo 'p99<250ms,transfer:p99.9<1s,errors<1%'
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// errObjectivesMissed is returned when a run misses one of its objectives
+var errObjectivesMissed = errors.New("service level objectives missed")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+}
+
+// flags are the flags of loadgen
+type flags struct {
+	url, apiKey    string
+	load           load
+	accounts       int
+	accountIDs     []int64
+	initialBalance decimal.Decimal
+	currency       string
+	amount         decimal.Decimal
+	timeout        time.Duration
+	objectives     []objective
+}
+
+// parseFlags parses the flags of loadgen.
+func parseFlags(args []string) (flags, error) {
+	f := flags{
+		load:           load{duration: 30 * time.Second, concurrency: 10},
+		accounts:       100,
+		initialBalance: decimal.NewFromInt(1_000_000_000),
+		currency:       model.DefaultCurrency,
+		amount:         decimal.RequireFromString("0.01"),
+	}
+	mix, slo := "transfer=70,balance=25,history=5", "p99<250ms,errors<1%"
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	fs.StringVar(&f.url, "url", "http://localhost:8080", "base URL of the API")
+	fs.StringVar(&f.apiKey, "api-key", os.Getenv("LOADGEN_API_KEY"), "API key to authenticate with (default $LOADGEN_API_KEY)")
+	fs.DurationVar(&f.load.duration, "duration", f.load.duration, "how long to make requests for")
+	fs.Float64Var(&f.load.rate, "rate", 0, "requests per second over all workers (default as many as they can make)")
+	fs.IntVar(&f.load.concurrency, "concurrency", f.load.concurrency, "requests in flight at once")
+	fs.StringVar(&mix, "mix", mix, "weights of the operations: transfer, balance and history")
+	fs.IntVar(&f.accounts, "accounts", f.accounts, "accounts to create and make requests on")
+	fs.Func("account-ids", "existing accounts to make requests on instead, as ids and ranges such as 1-100,205", func(s string) error {
+		ids, err := parseAccountIDs(s)
+		f.accountIDs = ids
+		return err
+	})
+	fs.Func("initial-balance", "balance of the accounts created (default "+f.initialBalance.String()+")", decimalFlag(&f.initialBalance))
+	fs.StringVar(&f.currency, "currency", f.currency, "currency of the accounts created")
+	fs.Func("amount", "amount of each transfer (default "+f.amount.String()+")", decimalFlag(&f.amount))
+	fs.DurationVar(&f.timeout, "timeout", 10*time.Second, "timeout of each request")
+	fs.StringVar(&slo, "slo", slo, "comma-separated objectives, [operation:]metric<value with metrics pNN, mean, max, errors (%) and rps; empty for none")
+	if err := fs.Parse(args); err != nil {
+		return f, err
+	}
+	if fs.NArg() > 0 {
+		return f, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	var err error
+	if f.load.mix, err = parseMix(mix); err != nil {
+		return f, fmt.Errorf("-mix: %w", err)
+	}
+	if f.objectives, err = parseObjectives(slo); err != nil {
+		return f, fmt.Errorf("-slo: %w", err)
+	}
+	switch {
+	case f.load.duration <= 0:
+		return f, errors.New("-duration must be positive")
+	case f.load.rate < 0:
+		return f, errors.New("-rate must not be negative")
+	case f.load.concurrency < 1:
+		return f, errors.New("-concurrency must be at least 1")
+	case f.accountIDs == nil && f.accounts < 2:
+		return f, errors.New("-accounts must be at least 2")
+	case f.accountIDs != nil && len(f.accountIDs) < 2:
+		return f, errors.New("-account-ids must list at least 2 accounts")
+	case !f.amount.IsPositive():
+		return f, errors.New("-amount must be positive")
+	case f.timeout <= 0:
+		return f, errors.New("-timeout must be positive")
+	}
+	return f, nil
+}
+
+// parseAccountIDs parses ids and ranges of ids such as "1-100,205".
+func parseAccountIDs(s string) ([]int64, error) {
+	ids := []int64{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		from, to, isRange := strings.Cut(part, "-")
+		lo, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account id %q", part)
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.ParseInt(to, 10, 64); err != nil || hi < lo {
+				return nil, fmt.Errorf("invalid account range %q", part)
+			}
+		}
+		for id := lo; id <= hi; id++ {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// decimalFlag parses a flag into d.
+func decimalFlag(d *decimal.Decimal) func(string) error {
+	return func(s string) error {
+		v, err := decimal.NewFromString(s)
+		if err != nil {
+			return fmt.Errorf("invalid decimal %q", s)
+		}
+		*d = v
+		return nil
+	}
+}
+
+// run runs loadgen with args, without the program name, and returns
+// errObjectivesMissed if the run misses an objective.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	f, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+	c := &client{
+		http:     &http.Client{Timeout: f.timeout, Transport: &http.Transport{MaxIdleConnsPerHost: f.load.concurrency}},
+		baseURL:  strings.TrimSuffix(f.url, "/"),
+		apiKey:   f.apiKey,
+		accounts: f.accountIDs,
+		amount:   f.amount,
+	}
+	if c.accounts == nil {
+		if c.accounts, err = createAccounts(ctx, c, f); err != nil {
+			return fmt.Errorf("create accounts: %w", err)
+		}
+		fmt.Fprintf(out, "created %d accounts\n", len(c.accounts))
+	}
+
+	fmt.Fprintf(out, "running for %s with %d workers\n\n", f.load.duration, f.load.concurrency)
+	rep := runLoad(ctx, c, f.load)
+	if _, err := rep.WriteTo(out); err != nil {
+		return err
+	}
+	if len(f.objectives) == 0 {
+		return ctx.Err()
+	}
+
+	fmt.Fprintln(out, "\nobjectives:")
+	missed := false
+	for _, o := range f.objectives {
+		ok, got := o.check(rep.stats(o.op), rep.elapsed)
+		verdict := "PASS"
+		if !ok {
+			verdict, missed = "FAIL", true
+		}
+		fmt.Fprintf(out, "  %s %-24s got %s\n", verdict, o.text, got)
+	}
+	if missed {
+		return errObjectivesMissed
+	}
+	return ctx.Err()
+}
+
+// createAccounts creates the accounts of a run through POST /v1/accounts/batch,
+// with external ids unique to the run, and returns their ids.
+func createAccounts(ctx context.Context, c *client, f flags) ([]int64, error) {
+	prefix := "loadgen-" + strconv.FormatInt(time.Now().UnixMilli(), 36) + "-"
+	ids := make([]int64, 0, f.accounts)
+	for len(ids) < f.accounts {
+		var req model.BatchCreateAccountRequest
+		for i := len(ids); i < min(f.accounts, len(ids)+model.MaxBatchAccounts); i++ {
+			req.Accounts = append(req.Accounts, model.CreateAccountRequest{
+				ExternalID:     prefix + strconv.Itoa(i+1),
+				InitialBalance: model.DecimalString{Decimal: f.initialBalance},
+				Currency:       f.currency,
+				Tags:           []string{"loadgen"},
+			})
+		}
+		body, err := json.Marshal(req)
+		if err != nil {
+			return ids, err
+		}
+		httpReq, err := c.newRequest(http.MethodPost, "/v1/accounts/batch", body)
+		if err != nil {
+			return ids, err
+		}
+		resp, err := c.http.Do(httpReq.WithContext(ctx))
+		if err != nil {
+			return ids, err
+		}
+		var batch model.BatchCreateAccountResponse
+		err = decodeResponse(resp, &batch)
+		if err != nil {
+			return ids, err
+		}
+		for _, res := range batch.Results {
+			if res.Status != model.BatchAccountCreated {
+				return ids, fmt.Errorf("account %s: %s", req.Accounts[res.Index].ExternalID, res.Error.Message)
+			}
+			ids = append(ids, res.AccountID)
+		}
+	}
+	return ids, nil
+}
+
+// decodeResponse decodes the JSON body of a 2xx response into v, and closes it.
+func decodeResponse(resp *http.Response, v any) error {
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return json.Unmarshal(body, v)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/you/internal-transfers/pkg/transferstest"
+)
+
+// TestRun tests a short run against an in-process API, with objectives it
+// meets and one it misses
+func TestRun(t *testing.T) {
+	srv := transferstest.NewServer(t)
+	ctx := context.Background()
+
+	var out strings.Builder
+	err := run(ctx, []string{"-url", srv.URL, "-duration", "300ms", "-concurrency", "4", "-accounts", "5",
+		"-slo", "errors<1%,transfer:rps>0,balance:p99<10s"}, &out)
+	if err != nil {
+		t.Fatalf("run: %v\n%s", err, out.String())
+	}
+	for _, want := range []string{"created 5 accounts", "transfer", "balance", "history", "all", "statuses: 200=", "PASS errors<1%"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected the output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	err = run(ctx, []string{"-url", srv.URL, "-duration", "100ms", "-account-ids", "1-5", "-mix", "balance=1", "-slo", "rps>1000000"}, &out)
+	if !errors.Is(err, errObjectivesMissed) {
+		t.Fatalf("expected the objectives to be missed, got %v\n%s", err, out.String())
+	}
+	if !strings.Contains(out.String(), "FAIL rps>1000000") {
+		t.Errorf("expected a failed objective, got:\n%s", out.String())
+	}
+}
+
+// TestParseFlags tests that invalid flags are rejected
+func TestParseFlags(t *testing.T) {
+	f, err := parseFlags([]string{"-account-ids", "3-5,9"})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !slices.Equal(f.accountIDs, []int64{3, 4, 5, 9}) {
+		t.Errorf("expected accounts 3 to 5 and 9, got %v", f.accountIDs)
+	}
+
+	for _, args := range [][]string{
+		{"-mix", "transfer=0"},
+		{"-mix", "deposit=1"},
+		{"-slo", "p99<soon"},
+		{"-concurrency", "0"},
+		{"-accounts", "1"},
+		{"-account-ids", "5-3"},
+		{"-amount", "-1"},
+		{"extra"},
+	} {
+		if _, err := parseFlags(args); err == nil {
+			t.Errorf("expected %q to be rejected", args)
+		}
+	}
+}
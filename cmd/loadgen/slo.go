@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// objective is a service level objective checked at the end of a run, such
+// as "p99<250ms", "errors<1%" or "transfer:rps>=100".
+type objective struct {
+	text   string
+	op     string // the operation it applies to, all of them if empty
+	metric string // pNN, mean, max, errors or rps
+	cmp    string // <, <=, > or >=
+	value  float64
+}
+
+// parseObjectives parses comma-separated objectives.
+func parseObjectives(s string) ([]objective, error) {
+	var objs []objective
+	for _, text := range strings.Split(s, ",") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		o, err := parseObjective(text)
+		if err != nil {
+			return nil, fmt.Errorf("objective %q: %w", text, err)
+		}
+		objs = append(objs, o)
+	}
+	return objs, nil
+}
+
+// parseObjective parses one objective, [op:]metric cmp value.
+func parseObjective(text string) (objective, error) {
+	o := objective{text: text}
+	rest := text
+	if op, r, ok := strings.Cut(rest, ":"); ok {
+		if _, known := operations[op]; !known {
+			return o, fmt.Errorf("unknown operation %s", op)
+		}
+		o.op, rest = op, r
+	}
+	i := strings.IndexAny(rest, "<>")
+	if i <= 0 {
+		return o, fmt.Errorf("must be [operation:]metric<value or metric>value")
+	}
+	o.metric, o.cmp, rest = rest[:i], rest[i:i+1], rest[i+1:]
+	if strings.HasPrefix(rest, "=") {
+		o.cmp, rest = o.cmp+"=", rest[1:]
+	}
+
+	var err error
+	switch {
+	case o.metric == "mean" || o.metric == "max" || strings.HasPrefix(o.metric, "p"):
+		if _, ok := percentile(o.metric); !ok && o.metric != "mean" && o.metric != "max" {
+			return o, fmt.Errorf("unknown metric %s", o.metric)
+		}
+		var d time.Duration
+		d, err = time.ParseDuration(rest)
+		o.value = float64(d)
+	case o.metric == "errors":
+		pct, ok := strings.CutSuffix(rest, "%")
+		if !ok {
+			return o, fmt.Errorf("errors must be a percentage, e.g. errors<1%%")
+		}
+		o.value, err = strconv.ParseFloat(pct, 64)
+	case o.metric == "rps":
+		o.value, err = strconv.ParseFloat(rest, 64)
+	default:
+		return o, fmt.Errorf("unknown metric %s", o.metric)
+	}
+	if err != nil {
+		return o, fmt.Errorf("invalid value %q", rest)
+	}
+	return o, nil
+}
+
+// measure returns the metric of o in s: a duration for latencies, a
+// percentage for errors and a rate for rps.
+func (o objective) measure(s *opStats, elapsed time.Duration) float64 {
+	switch {
+	case o.metric == "mean":
+		return float64(s.latency.Mean())
+	case o.metric == "max":
+		return float64(s.latency.Max())
+	case o.metric == "errors":
+		return s.errorRate() * 100
+	case o.metric == "rps":
+		return float64(s.latency.Count()) / elapsed.Seconds()
+	}
+	p, _ := percentile(o.metric)
+	return float64(s.latency.Quantile(p / 100))
+}
+
+// percentile parses a percentile metric such as p99 or p99.9.
+func percentile(metric string) (float64, bool) {
+	s, ok := strings.CutPrefix(metric, "p")
+	if !ok {
+		return 0, false
+	}
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p <= 0 || p >= 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// check reports whether the objective is met by s, with the measured value
+// formatted as in the objective.
+func (o objective) check(s *opStats, elapsed time.Duration) (bool, string) {
+	v := o.measure(s, elapsed)
+	var ok bool
+	switch o.cmp {
+	case "<":
+		ok = v < o.value
+	case "<=":
+		ok = v <= o.value
+	case ">":
+		ok = v > o.value
+	case ">=":
+		ok = v >= o.value
+	}
+	switch o.metric {
+	case "errors":
+		return ok, strconv.FormatFloat(v, 'f', 2, 64) + "%"
+	case "rps":
+		return ok, strconv.FormatFloat(v, 'f', 1, 64)
+	}
+	return ok, time.Duration(v).Round(time.Microsecond).String()
+}
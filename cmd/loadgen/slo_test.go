@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseObjectives tests the objectives accepted and rejected
+func TestParseObjectives(t *testing.T) {
+	objs, err := parseObjectives("p99<250ms, transfer:p99.9<=1s,errors<1%,rps>=100,mean<50ms,")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := []objective{
+		{text: "p99<250ms", metric: "p99", cmp: "<", value: float64(250 * time.Millisecond)},
+		{text: "transfer:p99.9<=1s", op: "transfer", metric: "p99.9", cmp: "<=", value: float64(time.Second)},
+		{text: "errors<1%", metric: "errors", cmp: "<", value: 1},
+		{text: "rps>=100", metric: "rps", cmp: ">=", value: 100},
+		{text: "mean<50ms", metric: "mean", cmp: "<", value: float64(50 * time.Millisecond)},
+	}
+	if len(objs) != len(want) {
+		t.Fatalf("expected %d objectives, got %+v", len(want), objs)
+	}
+	for i := range want {
+		if objs[i] != want[i] {
+			t.Errorf("expected %+v, got %+v", want[i], objs[i])
+		}
+	}
+
+	for _, s := range []string{"p99", "p100<1s", "p99<fast", "errors<1", "deposit:p99<1s", "median<1s", "<1s"} {
+		if _, err := parseObjectives(s); err == nil {
+			t.Errorf("expected %q to be rejected", s)
+		}
+	}
+}
+
+// TestObjectiveCheck tests objectives against the outcomes of a run
+func TestObjectiveCheck(t *testing.T) {
+	s := newOpStats()
+	for i := 1; i <= 100; i++ {
+		s.latency.Record(time.Duration(i) * time.Millisecond)
+		s.statuses[201]++
+	}
+	s.latency.Record(time.Second)
+	s.statuses[500]++
+
+	for _, tc := range []struct {
+		text string
+		ok   bool
+		got  string
+	}{
+		{"p50<60ms", true, ""},
+		{"p99<50ms", false, ""},
+		{"max<=1s", true, "1s"},
+		{"errors<1%", true, "0.99%"},
+		{"errors<0.5%", false, "0.99%"},
+		{"rps>=50", true, "50.5"},
+		{"rps>100", false, "50.5"},
+	} {
+		o, err := parseObjective(tc.text)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.text, err)
+		}
+		ok, got := o.check(s, 2*time.Second)
+		if ok != tc.ok || tc.got != "" && got != tc.got {
+			t.Errorf("%s: expected %v (%s), got %v (%s)", tc.text, tc.ok, tc.got, ok, got)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"golang.org/x/time/rate"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// operations build the requests loadgen can make, by name, between or on
+// random accounts of the client.
+var operations = map[string]func(c *client, rng *rand.Rand) (*http.Request, error){
+	"transfer": func(c *client, rng *rand.Rand) (*http.Request, error) {
+		src := rng.IntN(len(c.accounts))
+		dst := rng.IntN(len(c.accounts) - 1)
+		if dst >= src {
+			dst++
+		}
+		body, err := json.Marshal(model.CreateTransactionRequest{TransactionRequest: model.TransactionRequest{
+			SourceAccountID:      model.AccountID{ID: c.accounts[src]},
+			DestinationAccountID: model.AccountID{ID: c.accounts[dst]},
+			Amount:               model.DecimalString{Decimal: c.amount},
+			Reference:            "loadgen",
+		}})
+		if err != nil {
+			return nil, err
+		}
+		return c.newRequest(http.MethodPost, "/v1/transactions", body)
+	},
+	"balance": func(c *client, rng *rand.Rand) (*http.Request, error) {
+		return c.newRequest(http.MethodGet, "/v1/accounts/"+strconv.FormatInt(c.randomAccount(rng), 10), nil)
+	},
+	"history": func(c *client, rng *rand.Rand) (*http.Request, error) {
+		return c.newRequest(http.MethodGet, "/v1/accounts/"+strconv.FormatInt(c.randomAccount(rng), 10)+"/transactions?limit=20", nil)
+	},
+}
+
+// weightedOp is an operation of a mix and its share of the requests
+type weightedOp struct {
+	name   string
+	weight int
+}
+
+// parseMix parses a mix of operations such as "transfer=70,balance=25,history=5".
+func parseMix(s string) ([]weightedOp, error) {
+	var mix []weightedOp
+	for _, part := range strings.Split(s, ",") {
+		name, w, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("mix must be operation=weight pairs, got %q", part)
+		}
+		if _, known := operations[name]; !known {
+			return nil, fmt.Errorf("unknown operation %s", name)
+		}
+		weight, err := strconv.Atoi(w)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("weight of %s must be a non-negative integer, got %q", name, w)
+		}
+		if weight > 0 {
+			mix = append(mix, weightedOp{name, weight})
+		}
+	}
+	if len(mix) == 0 {
+		return nil, fmt.Errorf("mix has no operation with a positive weight")
+	}
+	return mix, nil
+}
+
+// pick draws an operation of mix in proportion to its weight.
+func pick(mix []weightedOp, rng *rand.Rand) string {
+	total := 0
+	for _, op := range mix {
+		total += op.weight
+	}
+	n := rng.IntN(total)
+	for _, op := range mix {
+		if n < op.weight {
+			return op.name
+		}
+		n -= op.weight
+	}
+	return mix[len(mix)-1].name
+}
+
+// client makes the requests of a run
+type client struct {
+	http     *http.Client
+	baseURL  string
+	apiKey   string
+	accounts []int64
+	amount   decimal.Decimal
+}
+
+// newRequest returns a request for path, with a JSON body if not nil.
+func (c *client) newRequest(method, path string, body []byte) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set(auth.APIKeyHeader, c.apiKey)
+	}
+	return req, nil
+}
+
+// randomAccount returns one of the accounts of the run.
+func (c *client) randomAccount(rng *rand.Rand) int64 {
+	return c.accounts[rng.IntN(len(c.accounts))]
+}
+
+// maxErrorBody caps the part of an error response kept as an example
+const maxErrorBody = 200
+
+// do sends req and returns its status, 0 if it got no response, and for
+// statuses other than 2xx an error quoting the response.
+func (c *client) do(ctx context.Context, req *http.Request) (int, error) {
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return resp.StatusCode, fmt.Errorf("%s %s: %d %s", req.Method, req.URL.Path, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	_, err = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, err
+}
+
+// opStats are the outcomes of the requests of an operation
+type opStats struct {
+	latency  histogram
+	statuses map[int]uint64 // by HTTP status, 0 for requests that got no response
+}
+
+func newOpStats() *opStats {
+	return &opStats{statuses: make(map[int]uint64)}
+}
+
+// errors returns the number of requests that failed, without a response or
+// with a status other than 2xx.
+func (s *opStats) errors() uint64 {
+	var n uint64
+	for status, c := range s.statuses {
+		if status < 200 || status > 299 {
+			n += c
+		}
+	}
+	return n
+}
+
+// errorRate returns the fraction of requests that failed.
+func (s *opStats) errorRate() float64 {
+	if s.latency.Count() == 0 {
+		return 0
+	}
+	return float64(s.errors()) / float64(s.latency.Count())
+}
+
+func (s *opStats) merge(o *opStats) {
+	s.latency.Merge(&o.latency)
+	for status, c := range o.statuses {
+		s.statuses[status] += c
+	}
+}
+
+// load configures a run
+type load struct {
+	duration    time.Duration
+	rate        float64 // requests per second over all workers, 0 for as many as they can make
+	concurrency int
+	mix         []weightedOp
+}
+
+// report holds the outcomes of a run, by operation and overall ("all")
+type report struct {
+	elapsed time.Duration
+	ops     map[string]*opStats
+	total   *opStats
+	sample  map[int]string // an error message per status, of the first failure
+}
+
+// runLoad makes requests with l.concurrency workers until l.duration is over or
+// ctx is done. Requests still running then are cancelled and not counted.
+func runLoad(ctx context.Context, c *client, l load) *report {
+	ctx, cancel := context.WithTimeout(ctx, l.duration)
+	defer cancel()
+	var limiter *rate.Limiter
+	if l.rate > 0 {
+		limiter = rate.NewLimiter(rate.Limit(l.rate), max(1, l.concurrency))
+	}
+
+	var mu sync.Mutex
+	rep := &report{ops: make(map[string]*opStats), total: newOpStats(), sample: make(map[int]string)}
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range l.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+			ops := make(map[string]*opStats)
+			samples := make(map[int]string)
+			for {
+				if limiter != nil && limiter.Wait(ctx) != nil || ctx.Err() != nil {
+					break
+				}
+				name := pick(l.mix, rng)
+				req, err := operations[name](c, rng)
+				if err != nil {
+					samples[0] = err.Error()
+					continue
+				}
+				began := time.Now()
+				status, err := c.do(ctx, req)
+				took := time.Since(began)
+				if ctx.Err() != nil {
+					break
+				}
+				if err != nil && samples[status] == "" {
+					samples[status] = err.Error()
+				}
+				s := ops[name]
+				if s == nil {
+					s = newOpStats()
+					ops[name] = s
+				}
+				s.latency.Record(took)
+				s.statuses[status]++
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for name, s := range ops {
+				if rep.ops[name] == nil {
+					rep.ops[name] = newOpStats()
+				}
+				rep.ops[name].merge(s)
+				rep.total.merge(s)
+			}
+			for status, msg := range samples {
+				if rep.sample[status] == "" {
+					rep.sample[status] = msg
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	rep.elapsed = time.Since(start)
+	return rep
+}
+
+// stats returns the outcomes of op, or of all operations if op is empty.
+func (r *report) stats(op string) *opStats {
+	if op == "" {
+		return r.total
+	}
+	if s := r.ops[op]; s != nil {
+		return s
+	}
+	return newOpStats()
+}
+
+// WriteTo prints a table of the outcomes of each operation, the status
+// counts, and the latency histogram of all requests.
+func (r *report) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-9s %9s %8s %9s %9s %9s %9s %9s %9s %9s\n", "operation", "requests", "errors", "rps", "mean", "p50", "p90", "p99", "p99.9", "max")
+	names := make([]string, 0, len(r.ops))
+	for name := range r.ops {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range append(names, "all") {
+		s := r.ops[name]
+		if name == "all" {
+			s = r.total
+		}
+		h := &s.latency
+		fmt.Fprintf(&b, "%-9s %9d %8d %9.1f %9s %9s %9s %9s %9s %9s\n", name, h.Count(), s.errors(),
+			float64(h.Count())/r.elapsed.Seconds(), round(h.Mean()), round(h.Quantile(0.5)), round(h.Quantile(0.9)),
+			round(h.Quantile(0.99)), round(h.Quantile(0.999)), round(h.Max()))
+	}
+
+	statuses := make([]int, 0, len(r.total.statuses))
+	for status := range r.total.statuses {
+		statuses = append(statuses, status)
+	}
+	slices.Sort(statuses)
+	b.WriteString("\nstatuses:")
+	for _, status := range statuses {
+		label := strconv.Itoa(status)
+		if status == 0 {
+			label = "no response"
+		}
+		fmt.Fprintf(&b, " %s=%d", label, r.total.statuses[status])
+	}
+	b.WriteString("\n")
+	for _, status := range statuses {
+		if msg := r.sample[status]; msg != "" {
+			fmt.Fprintf(&b, "  e.g. %s\n", msg)
+		}
+	}
+	b.WriteString("\nlatency of all requests:\n")
+
+	n, err := io.WriteString(w, b.String())
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := r.total.latency.WriteTo(w)
+	return int64(n) + m, err
+}
+
+// round rounds d for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of a setting, from the highest precedence to the lowest
+const (
+	sourceFlag   = "flag"
+	sourceEnv    = "env"
+	sourceDotEnv = ".env"
+	sourceFile   = "config file"
+)
+
+// settings lists the environment variables configuring the server. Those
+// marked true hold secrets, which the config summary redacts.
+var settings = map[string]bool{
+	"ALLOW_CROSS_TENANT_TRANSFERS":     false,
+	"AMOUNT_NUMBERS":                   false,
+	"AMOUNT_SCALE":                     false,
+	"ANOMALY_AMOUNT_Z":                 false,
+	"ANOMALY_COUNTERPARTY_Z":           false,
+	"ANOMALY_DETECTION":                false,
+	"ANOMALY_FREQUENCY_Z":              false,
+	"ANOMALY_MIN_SAMPLES":              false,
+	"ANOMALY_WINDOW":                   false,
+	"API_KEYS":                         true,
+	"API_KEY_NETWORKS":                 false,
+	"ARCHIVE_DIR":                      false,
+	"ASYNC_TRANSFERS":                  false,
+	"AUTH_MODE":                        false,
+	"AWS_ACCESS_KEY_ID":                false,
+	"AWS_DEFAULT_REGION":               false,
+	"AWS_ENDPOINT_URL_SECRETS_MANAGER": false,
+	"AWS_REGION":                       false,
+	"AWS_SECRET_ACCESS_KEY":            true,
+	"AWS_SESSION_TOKEN":                true,
+	"BALANCE_SNAPSHOT_INTERVAL":        false,
+	"CACHE_BACKEND":                    false,
+	"CAPTURE_FILE":                     false,
+	"CAPTURE_REQUESTS":                 false,
+	"CACHE_TTL":                        false,
+	"CURRENCY_SCALES":                  false,
+	"DB_APPLICATION_NAME":              false,
+	"DB_BREAKER_COOLDOWN":              false,
+	"DB_BREAKER_THRESHOLD":             false,
+	"DB_CONNECT_WAIT":                  false,
+	"DB_CONN_MAX_IDLE":                 false,
+	"DB_CONN_MAX_LIFETIME":             false,
+	"DB_MAX_CONNS":                     false,
+	"DB_MAX_RETRIES":                   false,
+	"DB_MIN_CONNS":                     false,
+	"DB_STATEMENT_TIMEOUT":             false,
+	"DEBUG_ADDR":                       false,
+	"EVENT_BROKER":                     false,
+	"HMAC_CLIENTS":                     true,
+	"HMAC_MAX_SKEW":                    false,
+	"HOLD_EXPIRY_INTERVAL_SEC":         false,
+	"IP_ALLOWLIST":                     false,
+	"JOB_WORKERS":                      false,
+	"JWT_AUDIENCE":                     false,
+	"JWT_ISSUER":                       false,
+	"JWT_JWKS_URL":                     false,
+	"JWT_ROLE_CLAIM":                   false,
+	"JWT_TENANT_CLAIM":                 false,
+	"KAFKA_BROKERS":                    false,
+	"KAFKA_TOPIC":                      false,
+	"KYC_UNVERIFIED_MAX_AMOUNT":        false,
+	"KYC_UNVERIFIED_RECEIVE_ONLY":      false,
+	"LEGACY_ROUTES":                    false,
+	"LIVE_UPDATES":                     false,
+	"LOG_LEVEL":                        false,
+	"MAX_AMOUNT":                       false,
+	"MAX_BODY_BYTES":                   false,
+	"MAX_IMPORT_FILE_BYTES":            false,
+	"MAX_JOB_FILE_BYTES":               false,
+	"NOTIFY_EVENTS":                    false,
+	"NATS_SUBJECT_PREFIX":              false,
+	"NATS_URL":                         true,
+	"OUTBOX_RETENTION":                 false,
+	"PORT":                             false,
+	"POSTGRES_DSN":                     true,
+	"POSTGRES_DSN_FILE":                false,
+	"POSTGRES_DSN_SECRET":              false,
+	"POSTGRES_REPLICA_DSN":             true,
+	"PURPOSE_CODES":                    false,
+	"RATE_LIMIT_BURST":                 false,
+	"RATE_LIMIT_RPS":                   false,
+	"RECONCILIATION_INTERVAL":          false,
+	"RECORD_TRANSFER_ATTEMPTS":         false,
+	"REDIS_URL":                        true,
+	"REQ_TIMEOUT_SEC":                  false,
+	"RULES_FILE":                       false,
+	"RULES_RELOAD_INTERVAL":            false,
+	"RUN_MIGRATIONS":                   false,
+	"SCHEDULER_INTERVAL_SEC":           false,
+	"SECRETS_PROVIDER":                 false,
+	"SECRETS_REFRESH_INTERVAL":         false,
+	"SEED_ENDPOINT":                    false,
+	"SERIAL_TRANSACTION_IDS":           false,
+	"SETTLEMENT_CUTOFF":                false,
+	"SHUTDOWN_DRAIN_SEC":               false,
+	"SLACK_WEBHOOK_URL":                true,
+	"SMTP_ADDR":                        false,
+	"SMTP_FROM":                        false,
+	"SMTP_PASSWORD":                    true,
+	"SMTP_TO":                          false,
+	"SMTP_USERNAME":                    false,
+	"SQLITE_DSN":                       false,
+	"STORE_BACKEND":                    false,
+	"SWAGGER_UI":                       false,
+	"SWEEP_INTERVAL":                   false,
+	"TRANSACTION_RETENTION_MONTHS":     false,
+	"TRANSFER_CONCURRENCY":             false,
+	"TRANSFER_DAILY_LIMIT":             false,
+	"TRANSFER_MAX_AMOUNT":              false,
+	"TRANSFER_QUEUE_WAIT":              false,
+	"TRANSFER_TIMEOUT_SEC":             false,
+	"TRUSTED_PROXIES":                  false,
+	"VAULT_ADDR":                       false,
+	"VAULT_TOKEN":                      true,
+	"WEBHOOK_SECRET":                   true,
+	"WEBHOOK_URL":                      false,
+	"WS_MAX_SUBSCRIPTIONS":             false,
+}
+
+// runtimeSettings are the settings read again on SIGHUP, which take effect
+// without a restart; they can also be changed with PUT /v1/admin/config.
+var runtimeSettings = []string{
+	"ASYNC_TRANSFERS",
+	"LOG_LEVEL",
+	"RATE_LIMIT_BURST",
+	"RATE_LIMIT_RPS",
+	"TRANSFER_DAILY_LIMIT",
+	"TRANSFER_MAX_AMOUNT",
+}
+
+// flags are the command line flags of the server
+type flags struct {
+	configFile  string
+	set         map[string]string // -set NAME=VALUE
+	checkConfig bool
+}
+
+// parseFlags parses the command line arguments args, without the program name.
+func parseFlags(args []string) (flags, error) {
+	f := flags{set: make(map[string]string)}
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&f.configFile, "config", os.Getenv("CONFIG_FILE"), "YAML config file (default $CONFIG_FILE)")
+	fs.Func("set", "set a setting, as NAME=VALUE, overriding the environment and config file; repeatable", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("must be NAME=VALUE, got %q", s)
+		}
+		name = settingName(name)
+		if _, known := settings[name]; !known {
+			return fmt.Errorf("unknown setting %s", name)
+		}
+		f.set[name] = value
+		return nil
+	})
+	fs.BoolVar(&f.checkConfig, "check-config", false, "validate the configuration, print it and exit")
+	if err := fs.Parse(args); err != nil {
+		return f, err
+	}
+	if fs.NArg() > 0 {
+		return f, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	return f, nil
+}
+
+// settingName returns the environment variable of a setting named in a flag
+// or a config file, such as "db.max_conns" for DB_MAX_CONNS.
+func settingName(s string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(s))
+}
+
+// applyConfigSources sets the environment variables of the settings given by
+// f, a .env file and f's config file, each leaving those set by the former
+// alone, so that flags override the environment, which overrides .env, which
+// overrides the config file. It returns the source of each setting.
+func applyConfigSources(f flags) (map[string]string, error) {
+	sources := make(map[string]string)
+	for name := range settings {
+		if _, ok := os.LookupEnv(name); ok {
+			sources[name] = sourceEnv
+		}
+	}
+	for name, v := range f.set {
+		if err := os.Setenv(name, v); err != nil {
+			return nil, err
+		}
+		sources[name] = sourceFlag
+	}
+
+	dotEnv, file, err := fileSettings(f)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range []struct {
+		source   string
+		settings map[string]string
+	}{{sourceDotEnv, dotEnv}, {sourceFile, file}} {
+		for name, v := range s.settings {
+			if _, ok := os.LookupEnv(name); !ok {
+				os.Setenv(name, v)
+				sources[name] = s.source
+			}
+		}
+	}
+	return sources, nil
+}
+
+// fileSettings reads the settings of the .env file, if any, and of f's config
+// file, if given.
+func fileSettings(f flags) (dotEnv, file map[string]string, err error) {
+	dotEnv, err = godotenv.Read()
+	if err != nil {
+		log.Printf("info: .env not loaded: %v (continuing with environment variables)", err)
+		dotEnv = nil
+	}
+	if f.configFile == "" {
+		return dotEnv, nil, nil
+	}
+	b, err := os.ReadFile(f.configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config file: %w", err)
+	}
+	file, err = parseConfigFile(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", f.configFile, err)
+	}
+	return dotEnv, file, nil
+}
+
+// reapplyRuntimeSettings reads the .env and config files again and sets, or
+// unsets if no longer there, the environment variables of the runtime
+// settings they gave, leaving those set by flags or the environment alone.
+// sources is updated to match.
+func reapplyRuntimeSettings(f flags, sources map[string]string) error {
+	dotEnv, file, err := fileSettings(f)
+	if err != nil {
+		return err
+	}
+	for _, name := range runtimeSettings {
+		if src := sources[name]; src == sourceFlag || src == sourceEnv {
+			continue
+		}
+		if v, ok := dotEnv[name]; ok {
+			os.Setenv(name, v)
+			sources[name] = sourceDotEnv
+		} else if v, ok := file[name]; ok {
+			os.Setenv(name, v)
+			sources[name] = sourceFile
+		} else {
+			os.Unsetenv(name)
+			delete(sources, name)
+		}
+	}
+	return nil
+}
+
+// parseConfigFile reads the settings of a YAML config file. Nested keys are
+// joined with underscores and upper-cased, so that
+//
+//	db:
+//	  max_conns: 20
+//
+// sets DB_MAX_CONNS; lists become comma-separated values. Unknown settings
+// are rejected.
+func parseConfigFile(b []byte) (map[string]string, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	out := make(map[string]string)
+	var unknown []string
+	var walk func(prefix string, m map[string]interface{}) error
+	walk = func(prefix string, m map[string]interface{}) error {
+		for k, v := range m {
+			name := settingName(prefix + k)
+			if nested, ok := v.(map[string]interface{}); ok {
+				if err := walk(name+"_", nested); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, known := settings[name]; !known {
+				unknown = append(unknown, name)
+				continue
+			}
+			s, err := settingValue(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if v != nil {
+				out[name] = s
+			}
+		}
+		return nil
+	}
+	if err := walk("", doc); err != nil {
+		return nil, err
+	}
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, fmt.Errorf("unknown settings %s", strings.Join(unknown, ", "))
+	}
+	return out, nil
+}
+
+// settingValue formats a value of a config file as an environment variable.
+func settingValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			if _, list := item.([]interface{}); list {
+				return "", errors.New("lists cannot be nested")
+			}
+			s, err := settingValue(item)
+			if err != nil {
+				return "", err
+			}
+			items[i] = s
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", v)
+}
+
+// configSummary lists the settings that are set, with their source, redacting
+// secrets. The others keep their defaults.
+func configSummary(sources map[string]string) string {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		if _, known := settings[name]; known {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	var b strings.Builder
+	for _, name := range names {
+		v := os.Getenv(name)
+		if settings[name] && v != "" {
+			v = "[redacted]"
+		}
+		fmt.Fprintf(&b, "\n  %s=%s (%s)", name, v, sources[name])
+	}
+	if b.Len() == 0 {
+		return " all settings at their defaults"
+	}
+	return b.String()
+}
+
+// envBool reads the boolean setting name, def if unset.
+func envBool(name string, def bool) (bool, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", name, s)
+	}
+	return v, nil
+}
+
+// envSeconds reads the setting name, a positive number of seconds, def if
+// unset.
+func envSeconds(name string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(name)
+	if s == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %q", name, s)
+	}
+	return time.Duration(v) * time.Second, nil
+}
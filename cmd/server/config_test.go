@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestSettingsListed tests that every setting read by the server is known to
+// the config file, the -set flag and the config summary
+func TestSettingsListed(t *testing.T) {
+	for _, file := range []string{"main.go", "config.go"} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range regexp.MustCompile(`(?:Getenv\(|env[A-Za-z]*\(|\{)"([A-Z][A-Z0-9_]+)"`).FindAllStringSubmatch(string(b), -1) {
+			if _, ok := settings[m[1]]; !ok && m[1] != "CONFIG_FILE" {
+				t.Errorf("%s: setting %s is missing from settings", file, m[1])
+			}
+		}
+	}
+}
+
+// TestParseConfigFile tests that nested keys and lists map to settings, and
+// that unknown settings are rejected
+func TestParseConfigFile(t *testing.T) {
+	got, err := parseConfigFile([]byte(`
+port: 9090
+postgres_dsn: postgres://u:p@db/transfers
+run_migrations: true
+rate_limit_rps: 2.5
+db:
+  max_conns: 20
+  breaker:
+    cooldown: 30s
+kafka_brokers: [k1:9092, k2:9092]
+debug_addr: null
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"PORT":                "9090",
+		"POSTGRES_DSN":        "postgres://u:p@db/transfers",
+		"RUN_MIGRATIONS":      "true",
+		"RATE_LIMIT_RPS":      "2.5",
+		"DB_MAX_CONNS":        "20",
+		"DB_BREAKER_COOLDOWN": "30s",
+		"KAFKA_BROKERS":       "k1:9092,k2:9092",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("%s: expected %q, got %q", k, v, got[k])
+		}
+	}
+
+	for _, bad := range []string{"db:\n  max_con: 20\n", "kafka_brokers: [[a]]\n", "port: [\n"} {
+		if _, err := parseConfigFile([]byte(bad)); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+// TestApplyConfigSources tests that flags override the environment, which
+// overrides the config file, and that the summary redacts secrets
+func TestApplyConfigSources(t *testing.T) {
+	for _, name := range []string{"PORT", "POSTGRES_DSN", "RUN_MIGRATIONS", "DB_MAX_CONNS"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	t.Setenv("RUN_MIGRATIONS", "false")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("port: 9090\npostgres_dsn: postgres://u:secret@db/transfers\nrun_migrations: true\ndb:\n  max_conns: 20\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parseFlags([]string{"-config", path, "-set", "db.max_conns=30"})
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	sources, err := applyConfigSources(f)
+	if err != nil {
+		t.Fatalf("apply config sources: %v", err)
+	}
+	for name, want := range map[string]string{"PORT": "9090", "RUN_MIGRATIONS": "false", "DB_MAX_CONNS": "30"} {
+		if got := os.Getenv(name); got != want {
+			t.Fatalf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	if sources["PORT"] != sourceFile || sources["RUN_MIGRATIONS"] != sourceEnv || sources["DB_MAX_CONNS"] != sourceFlag {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+
+	summary := configSummary(sources)
+	if strings.Contains(summary, "secret") || !strings.Contains(summary, "POSTGRES_DSN=[redacted] (config file)") || !strings.Contains(summary, "DB_MAX_CONNS=30 (flag)") {
+		t.Fatalf("unexpected summary: %s", summary)
+	}
+
+	if _, err := parseFlags([]string{"-set", "NO_SUCH_SETTING=1"}); err == nil {
+		t.Fatal("expected an unknown setting to be rejected")
+	}
+}
+
+// TestReapplyRuntimeSettings tests that SIGHUP picks up config file changes
+// to runtime settings only, and not those set in the environment
+func TestReapplyRuntimeSettings(t *testing.T) {
+	for _, name := range []string{"PORT", "RATE_LIMIT_RPS", "LOG_LEVEL", "TRANSFER_MAX_AMOUNT"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	t.Setenv("LOG_LEVEL", "warn")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("port: 9090\nrate_limit_rps: 10\ntransfer_max_amount: 500\n")
+
+	f, err := parseFlags([]string{"-config", path})
+	if err != nil {
+		t.Fatalf("parse flags: %v", err)
+	}
+	sources, err := applyConfigSources(f)
+	if err != nil {
+		t.Fatalf("apply config sources: %v", err)
+	}
+
+	write("port: 9191\nrate_limit_rps: 20\nlog_level: debug\n")
+	if err := reapplyRuntimeSettings(f, sources); err != nil {
+		t.Fatalf("reapply runtime settings: %v", err)
+	}
+	for name, want := range map[string]string{"PORT": "9090", "RATE_LIMIT_RPS": "20", "LOG_LEVEL": "warn", "TRANSFER_MAX_AMOUNT": ""} {
+		if got := os.Getenv(name); got != want {
+			t.Fatalf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+	if _, ok := sources["TRANSFER_MAX_AMOUNT"]; ok || sources["LOG_LEVEL"] != sourceEnv {
+		t.Fatalf("unexpected sources %v", sources)
+	}
+
+	cfg := &Config{}
+	if err := loadRuntimeConfig(cfg); err != nil {
+		t.Fatalf("load runtime config: %v", err)
+	}
+	if c := runtimeConfig(cfg); c.RateLimitRPS != 20 || c.RateLimitBurst != 20 || c.LogLevel != "warn" || !c.AsyncTransfers || c.TransferLimits.MaxTransferAmount != nil {
+		t.Fatalf("unexpected runtime config %+v", c)
+	}
+}
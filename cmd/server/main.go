@@ -3,94 +3,1745 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 	"github.com/you/internal-transfers/internal/api"
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/cache"
+	rediscache "github.com/you/internal-transfers/internal/cache/redis"
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/events"
+	"github.com/you/internal-transfers/internal/events/kafka"
+	"github.com/you/internal-transfers/internal/events/nats"
+	"github.com/you/internal-transfers/internal/events/webhook"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/notify"
+	"github.com/you/internal-transfers/internal/notify/email"
+	"github.com/you/internal-transfers/internal/notify/slack"
+	"github.com/you/internal-transfers/internal/secrets"
+	"github.com/you/internal-transfers/internal/secrets/awssm"
+	"github.com/you/internal-transfers/internal/secrets/vault"
+	"github.com/you/internal-transfers/internal/seed"
 	"github.com/you/internal-transfers/internal/store"
+	"github.com/you/internal-transfers/internal/store/archive"
+	"github.com/you/internal-transfers/internal/store/sqlite"
+	"github.com/you/internal-transfers/internal/telemetry"
+	"github.com/you/internal-transfers/internal/worker"
+	"github.com/you/internal-transfers/migrations"
 )
 
 type Config struct {
-	PostgresDSN string
-	Port        string
-	ReqTimeout  time.Duration
+	StoreBackend        string
+	SQLiteDSN           string
+	PostgresDSN         string
+	DSNSecret           string // name of the secret holding the DSN, if not PostgresDSN
+	SecretsProvider     string
+	VaultAddr           string
+	VaultToken          string
+	AWSRegion           string
+	AWSEndpoint         string
+	AWSCredentials      awssm.Credentials
+	SecretsRefresh      time.Duration
+	ReplicaDSN          string
+	Pool                store.PoolConfig
+	Port                string
+	DebugAddr           string
+	ReqTimeout          time.Duration
+	TransferTimeout     time.Duration
+	TransferConcurrency int
+	TransferQueueWait   time.Duration
+	BreakerThreshold    int
+	BreakerCooldown     time.Duration
+	DrainTimeout        time.Duration
+	LegacyRoutes        bool
+	SerialIDs           bool
+	RunMigrations       bool
+	AuthMode            string
+	APIKeys             map[string]auth.Principal
+	JWT                 auth.JWTConfig
+	HMAC                auth.HMACConfig
+	Network             api.NetworkPolicy
+	RateLimitRPS        float64
+	RateLimitBurst      int
+	MaxBodyBytes        int64
+	MaxJobFileBytes     int64
+	MaxImportBytes      int64
+	AsyncTransfers      bool
+	LogLevel            string
+	HoldExpiry          time.Duration
+	SchedulerTick       time.Duration
+	JobWorkers          int
+	SnapshotInterval    time.Duration
+	ReconcileEvery      time.Duration
+	RulesReload         time.Duration
+	RulesFile           string
+	SettlementCutoff    time.Duration // past midnight UTC
+	SweepInterval       time.Duration
+	AnomalyDetection    bool
+	Anomalies           store.AnomalyConfig
+	KYC                 store.KYCPolicy
+	DBMaxRetries        int
+	TransferLimits      store.TransferLimits
+	DecimalNumbers      model.NumberMode
+	Amounts             model.AmountRules
+	PurposeCodes        []string // nil accepts any well-formed code
+	EventBroker         string
+	KafkaBrokers        []string
+	KafkaTopic          string
+	NATSURL             string
+	NATSSubject         string
+	WebhookURL          string
+	WebhookSecret       string
+	OutboxRetention     time.Duration
+	SlackWebhookURL     string
+	SMTP                email.Config // SMTP.Addr empty unless emails are sent
+	NotifyEvents        string
+	TxRetentionMonths   int
+	ArchiveDir          string
+	SwaggerUI           bool
+	SeedEndpoint        bool
+	RecordAttempts      bool   // rejected transfer requests go to transfer_attempts
+	CaptureRequests     int    // exchanges kept for GET /admin/captures, 0 for none
+	CaptureFile         string // appended with every exchange if set
+	CacheBackend        string
+	RedisURL            string
+	CacheTTL            time.Duration
+	CrossTenant         bool
+	LiveUpdates         bool
+	WSSubscriptions     int
 }
 
+// serverWriteTimeout is the write timeout of the HTTP server. Requests give up
+// shortly before it, so that they can still answer with a 504.
+const serverWriteTimeout = 15 * time.Second
+
+// scheduledBatchSize caps the scheduled transfers executed per scheduler run
+const scheduledBatchSize = 100
+
+// jobPollInterval is how often queued bulk transfer jobs are picked up, and a
+// running job not renewed for jobLease is taken over
+const (
+	jobPollInterval = 2 * time.Second
+	jobLease        = time.Minute
+)
+
+// Asynchronous transfers are executed in batches of pendingBatchSize every pendingPollInterval
+const (
+	pendingPollInterval = time.Second
+	pendingBatchSize    = 100
+)
+
+// snapshotPollInterval is how often the age of the latest balance snapshot is checked
+const snapshotPollInterval = time.Minute
+
+// statementPollInterval is how often last month's statements are checked for
+const statementPollInterval = time.Hour
+
+// settlementPollInterval is how often business days past their cutoff are
+// checked for clearing transfers to settle
+const settlementPollInterval = time.Minute
+
+// Outbox events are relayed in batches of outboxBatchSize every
+// outboxPollInterval, after a first run in batches of outboxCatchUpBatchSize
+// relaying the events that piled up while the service was down
+const (
+	outboxPollInterval     = time.Second
+	outboxBatchSize        = 100
+	outboxCatchUpBatchSize = 1000
+)
+
+// Delivered outbox events are kept for OUTBOX_RETENTION, purged every outboxPurgeInterval
+const (
+	defaultOutboxRetention = 24 * time.Hour
+	outboxPurgeInterval    = time.Hour
+)
+
+// signaturePurgeInterval is how often the signatures of signed requests past
+// HMAC_MAX_SKEW are deleted
+const signaturePurgeInterval = 10 * time.Minute
+
+// anomalyPollInterval is how often the transfers made since are analyzed for
+// anomalies
+const anomalyPollInterval = time.Minute
+
+// Newly flagged transfers, alerts and dead-lettered events are checked for
+// every notificationPollInterval and notified in batches of
+// notificationBatchSize; up to notificationQueueSize notifications wait for a
+// slow channel
+const (
+	notificationPollInterval = 30 * time.Second
+	notificationBatchSize    = 50
+	notificationQueueSize    = 100
+)
+
+// maxCaptureRequests caps CAPTURE_REQUESTS, as each exchange may hold two
+// bodies of up to api.DefaultCaptureBodyBytes
+const maxCaptureRequests = 10000
+
+// partitionInterval is how often the transaction partitions of the coming
+// months are created and the months past TRANSACTION_RETENTION_MONTHS archived
+const partitionInterval = time.Hour
+
+// Supported values of STORE_BACKEND
+const (
+	storeBackendPostgres = "postgres"
+	storeBackendSQLite   = "sqlite"
+)
+
+// Supported values of SECRETS_PROVIDER
+const (
+	secretsProviderFile  = "file"
+	secretsProviderVault = "vault"
+	secretsProviderAWS   = "aws"
+)
+
+// Supported values of EVENT_BROKER
+const (
+	eventBrokerNone  = "none"
+	eventBrokerKafka = "kafka"
+	eventBrokerNATS  = "nats"
+)
+
+// Notification channels, as named in NOTIFY_EVENTS
+const (
+	notifyChannelSlack = "slack"
+	notifyChannelEmail = "email"
+)
+
+// Supported values of CACHE_BACKEND
+const (
+	cacheBackendNone   = "none"
+	cacheBackendMemory = "memory"
+	cacheBackendRedis  = "redis"
+)
+
+// Supported values of AUTH_MODE
+const (
+	authModeNone   = "none"
+	authModeAPIKey = "apikey"
+	authModeJWT    = "jwt"
+	authModeHMAC   = "hmac"
+)
+
+// loadConfig reads the configuration from the environment, into which
+// applyConfigSources sets the settings of flags, .env and the config file, and
+// validates it.
 func loadConfig() (*Config, error) {
-	if err := godotenv.Load(); err != nil {
-		log.Printf("info: .env not loaded: %v (continuing with environment variables)", err)
+	// SQLite serves single-node edge deployments and smoke tests without a Postgres server
+	storeBackend := os.Getenv("STORE_BACKEND")
+	if storeBackend == "" {
+		storeBackend = storeBackendPostgres
 	}
-
+	sqliteDSN := os.Getenv("SQLITE_DSN")
 	dsn := os.Getenv("POSTGRES_DSN")
-	if dsn == "" {
-		return nil, errors.New("POSTGRES_DSN is required")
+	switch storeBackend {
+	case storeBackendPostgres:
+	case storeBackendSQLite:
+		if sqliteDSN == "" {
+			sqliteDSN = "file:transfers.db"
+		}
+	default:
+		return nil, fmt.Errorf("STORE_BACKEND must be one of %q, %q, got %q", storeBackendPostgres, storeBackendSQLite, storeBackend)
+	}
+
+	pool, err := loadPoolConfig()
+	if err != nil {
+		return nil, err
 	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return nil, fmt.Errorf("PORT must be a port number, got %q", port)
+	}
+
+	reqTimeout, err := envSeconds("REQ_TIMEOUT_SEC", api.DefaultRequestTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transfers may retry serialization failures, so they get longer
+	transferTimeout, err := envSeconds("TRANSFER_TIMEOUT_SEC", api.DefaultTransferTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// Database operations fail fast once this many connection failures in a
+	// row open the circuit breaker; DB_BREAKER_THRESHOLD=0 disables it
+	breakerThreshold := store.DefaultBreakerThreshold
+	if s := os.Getenv("DB_BREAKER_THRESHOLD"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("DB_BREAKER_THRESHOLD must be a non-negative integer, got %q", s)
+		}
+		breakerThreshold = v
+	}
+	breakerCooldown := store.DefaultBreakerCooldown
+	if s := os.Getenv("DB_BREAKER_COOLDOWN"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("DB_BREAKER_COOLDOWN must be a positive duration, got %q", s)
+		}
+		breakerCooldown = d
+	}
+
+	// Requests moving money queue for a share of the pool, leaving connections
+	// for reads; TRANSFER_CONCURRENCY=0 lifts the limit
+	transferConcurrency := max(int(pool.MaxConns)/2, 1)
+	if s := os.Getenv("TRANSFER_CONCURRENCY"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("TRANSFER_CONCURRENCY must be a non-negative integer, got %q", s)
+		}
+		transferConcurrency = v
+	}
+	transferQueueWait := api.DefaultTransferQueueWait
+	if s := os.Getenv("TRANSFER_QUEUE_WAIT"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("TRANSFER_QUEUE_WAIT must be a positive duration, got %q", s)
+		}
+		transferQueueWait = d
+	}
+
+	// On shutdown, in-flight requests and background runs get this long to finish
+	drainTimeout, err := envSeconds("SHUTDOWN_DRAIN_SEC", 15*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unversioned routes stay enabled during the /v1 deprecation window
+	legacyRoutes, err := envBool("LEGACY_ROUTES", true)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serial transaction ids are only kept in responses until their sunset
+	serialIDs, err := envBool("SERIAL_TRANSACTION_IDS", false)
+	if err != nil {
+		return nil, err
+	}
+	if serialIDs && !time.Now().Before(api.SerialTransactionIDsSunset) {
+		return nil, fmt.Errorf("SERIAL_TRANSACTION_IDS is no longer supported since %s", api.SerialTransactionIDsSunset.Format(time.DateOnly))
+	}
+
+	// Swagger UI loads its assets from a CDN, so it is opt-in
+	swaggerUI, err := envBool("SWAGGER_UI", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// POST /v1/admin/seed generates demo data; never enable it in production
+	seedEndpoint, err := envBool("SEED_ENDPOINT", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rejected transfer requests are kept in transfer_attempts for fraud analysis
+	recordAttempts, err := envBool("RECORD_TRANSFER_ATTEMPTS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests and responses are recorded for debugging only when asked to
+	var captureRequests int
+	if s := os.Getenv("CAPTURE_REQUESTS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 || v > maxCaptureRequests {
+			return nil, fmt.Errorf("CAPTURE_REQUESTS must be an integer between 0 and %d, got %q", maxCaptureRequests, s)
+		}
+		captureRequests = v
+	}
+	captureFile := os.Getenv("CAPTURE_FILE")
+
+	// The pprof and runtime debug server is off unless given an address,
+	// which should not be reachable from outside, e.g. localhost:6060
+	debugAddr := os.Getenv("DEBUG_ADDR")
+
+	runMigrations, err := envBool("RUN_MIGRATIONS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transfers between accounts of different tenants are opt-in
+	crossTenant, err := envBool("ALLOW_CROSS_TENANT_TRANSFERS", false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Transfers are streamed to clients as they commit only when enabled
+	liveUpdates, err := envBool("LIVE_UPDATES", false)
+	if err != nil {
+		return nil, err
+	}
+
+	wsSubscriptions := api.DefaultWebSocketSubscriptions
+	if s := os.Getenv("WS_MAX_SUBSCRIPTIONS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("WS_MAX_SUBSCRIPTIONS must be a positive integer, got %q", s)
+		}
+		wsSubscriptions = v
+	}
+
+	authMode := os.Getenv("AUTH_MODE")
+	if authMode == "" {
+		authMode = authModeNone
+	}
+	if authMode != authModeNone && authMode != authModeAPIKey && authMode != authModeJWT && authMode != authModeHMAC {
+		return nil, fmt.Errorf("AUTH_MODE must be one of %q, %q, %q, %q, got %q", authModeNone, authModeAPIKey, authModeJWT, authModeHMAC, authMode)
+	}
+
+	jwtCfg := auth.JWTConfig{
+		JWKSURL:     os.Getenv("JWT_JWKS_URL"),
+		Issuer:      os.Getenv("JWT_ISSUER"),
+		Audience:    os.Getenv("JWT_AUDIENCE"),
+		TenantClaim: os.Getenv("JWT_TENANT_CLAIM"),
+		RoleClaim:   os.Getenv("JWT_ROLE_CLAIM"),
+	}
+	if authMode == authModeJWT && jwtCfg.JWKSURL == "" {
+		return nil, errors.New("JWT_JWKS_URL is required when AUTH_MODE=jwt")
+	}
+
+	apiKeys, err := auth.ParseStaticKeys(os.Getenv("API_KEYS"))
+	if err != nil {
+		return nil, fmt.Errorf("API_KEYS: %w", err)
+	}
+
+	// Clients signing their requests, accepted along with the keys or tokens
+	// of AUTH_MODE, or alone with AUTH_MODE=hmac
+	hmacClients, err := auth.ParseHMACClients(os.Getenv("HMAC_CLIENTS"))
+	if err != nil {
+		return nil, fmt.Errorf("HMAC_CLIENTS: %w", err)
+	}
+	if authMode == authModeHMAC && len(hmacClients) == 0 {
+		return nil, errors.New("HMAC_CLIENTS is required when AUTH_MODE=hmac")
+	}
+	if authMode == authModeNone && len(hmacClients) > 0 {
+		return nil, errors.New("HMAC_CLIENTS requires AUTH_MODE to be set")
+	}
+	// Static keys and signing clients restricted to networks, by name
+	keyNetworks, err := auth.ParseKeyNetworks(os.Getenv("API_KEY_NETWORKS"))
+	if err != nil {
+		return nil, fmt.Errorf("API_KEY_NETWORKS: %w", err)
+	}
+	for name, networks := range keyNetworks {
+		found := false
+		for hash, p := range apiKeys {
+			if principalName(p) == name {
+				p.Networks = networks
+				apiKeys[hash] = p
+				found = true
+			}
+		}
+		if c, ok := hmacClients[name]; ok {
+			c.Principal.Networks = networks
+			hmacClients[name] = c
+			found = true
+		}
+		if !found {
+			return nil, fmt.Errorf("API_KEY_NETWORKS: %s is neither a key of API_KEYS nor a client of HMAC_CLIENTS", name)
+		}
+	}
+
+	// Application routes are only served to IP_ALLOWLIST, if set, with the
+	// clients behind TRUSTED_PROXIES told by X-Forwarded-For
+	var network api.NetworkPolicy
+	if network.Allow, err = auth.ParseNetworks(os.Getenv("IP_ALLOWLIST"), ","); err != nil {
+		return nil, fmt.Errorf("IP_ALLOWLIST: %w", err)
+	}
+	if network.TrustedProxies, err = auth.ParseNetworks(os.Getenv("TRUSTED_PROXIES"), ","); err != nil {
+		return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+
+	hmacMaxSkew := auth.DefaultHMACMaxSkew
+	if s := os.Getenv("HMAC_MAX_SKEW"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("HMAC_MAX_SKEW must be a positive duration, got %q", s)
+		}
+		hmacMaxSkew = d
+	}
+
+	// Larger JSON request bodies are rejected with 413
+	maxBodyBytes := int64(api.DefaultMaxBodyBytes)
+	if s := os.Getenv("MAX_BODY_BYTES"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("MAX_BODY_BYTES must be a positive integer, got %q", s)
+		}
+		maxBodyBytes = v
+	}
+
+	// Larger bulk transfer files are rejected with 413
+	maxJobFileBytes := int64(api.DefaultMaxJobFileBytes)
+	if s := os.Getenv("MAX_JOB_FILE_BYTES"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("MAX_JOB_FILE_BYTES must be a positive integer, got %q", s)
+		}
+		maxJobFileBytes = v
+	}
+
+	// Larger account import files are rejected with 413
+	maxImportBytes := int64(api.DefaultMaxImportBytes)
+	if s := os.Getenv("MAX_IMPORT_FILE_BYTES"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("MAX_IMPORT_FILE_BYTES must be a positive integer, got %q", s)
+		}
+		maxImportBytes = v
+	}
+
+	// How often pending holds past their expiry are released
+	holdExpiry := time.Minute
+	if s := os.Getenv("HOLD_EXPIRY_INTERVAL_SEC"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("HOLD_EXPIRY_INTERVAL_SEC must be a positive integer, got %q", s)
+		}
+		holdExpiry = time.Duration(v) * time.Second
+	}
+
+	// How often due scheduled transfers are executed
+	schedulerTick := 10 * time.Second
+	if s := os.Getenv("SCHEDULER_INTERVAL_SEC"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("SCHEDULER_INTERVAL_SEC must be a positive integer, got %q", s)
+		}
+		schedulerTick = time.Duration(v) * time.Second
+	}
+
+	// Number of rows of a bulk transfer job executed concurrently
+	jobWorkers := 4
+	if s := os.Getenv("JOB_WORKERS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf("JOB_WORKERS must be a positive integer, got %q", s)
+		}
+		jobWorkers = v
+	}
+
+	// How often account balances are snapshotted
+	snapshotInterval := 24 * time.Hour
+	if s := os.Getenv("BALANCE_SNAPSHOT_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("BALANCE_SNAPSHOT_INTERVAL must be a positive duration, got %q", s)
+		}
+		snapshotInterval = d
+	}
+
+	// How often balances are reconciled against the ledger
+	reconcileEvery := time.Hour
+	if s := os.Getenv("RECONCILIATION_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("RECONCILIATION_INTERVAL must be a positive duration, got %q", s)
+		}
+		reconcileEvery = d
+	}
+
+	// How often the fraud rules are reloaded from the database
+	rulesReload := 30 * time.Second
+	if s := os.Getenv("RULES_RELOAD_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("RULES_RELOAD_INTERVAL must be a positive duration, got %q", s)
+		}
+		rulesReload = d
+	}
+	// YAML file of rules checked along with those of the database, reloaded with them
+	rulesFile := os.Getenv("RULES_FILE")
+
+	// Time of day, UTC, ending the business day of clearing transfers
+	var settlementCutoff time.Duration
+	if s := os.Getenv("SETTLEMENT_CUTOFF"); s != "" {
+		t, err := time.Parse("15:04", s)
+		if err != nil {
+			return nil, fmt.Errorf("SETTLEMENT_CUTOFF must be a time of day as HH:MM, got %q", s)
+		}
+		settlementCutoff = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+
+	// How often the sweep rules are evaluated
+	sweepInterval := time.Minute
+	if s := os.Getenv("SWEEP_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("SWEEP_INTERVAL must be a positive duration, got %q", s)
+		}
+		sweepInterval = d
+	}
+
+	// Transfers are analyzed against the baselines of their source accounts
+	// only when ANOMALY_DETECTION is on; a zero z-score turns its score off
+	anomalyDetection, err := envBool("ANOMALY_DETECTION", false)
+	if err != nil {
+		return nil, err
+	}
+	anomalies := store.DefaultAnomalyConfig()
+	if s := os.Getenv("ANOMALY_WINDOW"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 48*time.Hour {
+			return nil, fmt.Errorf("ANOMALY_WINDOW must be a duration of at least 48h, got %q", s)
+		}
+		anomalies.Window = d
+	}
+	if s := os.Getenv("ANOMALY_MIN_SAMPLES"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 {
+			return nil, fmt.Errorf("ANOMALY_MIN_SAMPLES must be a positive integer, got %q", s)
+		}
+		anomalies.MinSamples = v
+	}
+	for name, z := range map[string]*float64{
+		"ANOMALY_AMOUNT_Z":       &anomalies.AmountZ,
+		"ANOMALY_FREQUENCY_Z":    &anomalies.FrequencyZ,
+		"ANOMALY_COUNTERPARTY_Z": &anomalies.CounterpartyZ,
+	} {
+		if s := os.Getenv(name); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || v < 0 || math.IsInf(v, 0) {
+				return nil, fmt.Errorf("%s must be a non-negative number, got %q", name, s)
+			}
+			*z = v
+		}
+	}
+
+	// What unverified accounts may do; blocked accounts can never transfer
+	var kyc store.KYCPolicy
+	if kyc.UnverifiedReceiveOnly, err = envBool("KYC_UNVERIFIED_RECEIVE_ONLY", false); err != nil {
+		return nil, err
+	}
+	if s := os.Getenv("KYC_UNVERIFIED_MAX_AMOUNT"); s != "" {
+		d, err := decimal.NewFromString(s)
+		if err != nil || !d.IsPositive() {
+			return nil, fmt.Errorf("KYC_UNVERIFIED_MAX_AMOUNT must be a positive decimal, got %q", s)
+		}
+		kyc.UnverifiedMaxAmount = decimal.NewNullDecimal(d)
+	}
+
+	// Decimal places and maximum of the amounts accepted in requests
+	amounts := model.AmountRules{DefaultScale: model.DefaultAmountScale, Max: model.DefaultMaxAmount}
+	if s := os.Getenv("AMOUNT_SCALE"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 || v > model.MaxAmountScale {
+			return nil, fmt.Errorf("AMOUNT_SCALE must be an integer between 0 and %d, got %q", model.MaxAmountScale, s)
+		}
+		amounts.DefaultScale = int32(v)
+	}
+	if amounts.Scales, err = model.ParseCurrencyScales(os.Getenv("CURRENCY_SCALES")); err != nil {
+		return nil, fmt.Errorf("CURRENCY_SCALES: %w", err)
+	}
+	if s := os.Getenv("MAX_AMOUNT"); s != "" {
+		d, err := decimal.NewFromString(s)
+		if err != nil || !d.IsPositive() {
+			return nil, fmt.Errorf("MAX_AMOUNT must be a positive decimal, got %q", s)
+		}
+		amounts.Max = d
+	}
 
-	reqTimeout := 5 * time.Second
-	if s := os.Getenv("REQ_TIMEOUT_SEC"); s != "" {
-		if v, err := strconv.Atoi(s); err == nil && v > 0 {
-			reqTimeout = time.Duration(v) * time.Second
+	// Amounts sent as JSON numbers go through float64 unless AMOUNT_NUMBERS says otherwise
+	decimalNumbers := model.NumbersAsFloat
+	if s := os.Getenv("AMOUNT_NUMBERS"); s != "" {
+		if decimalNumbers, err = model.ParseNumberMode(s); err != nil {
+			return nil, fmt.Errorf("AMOUNT_NUMBERS %w", err)
 		}
 	}
 
-	return &Config{
-		PostgresDSN: dsn,
-		Port:        port,
-		ReqTimeout:  reqTimeout,
-	}, nil
+	// Purpose codes transfers may carry; any well-formed code if unset
+	purposeCodes, err := model.ParsePurposeCodes(os.Getenv("PURPOSE_CODES"))
+	if err != nil {
+		return nil, fmt.Errorf("PURPOSE_CODES: %w", err)
+	}
+
+	// Transfers aborted by a serialization failure or deadlock are retried up to DB_MAX_RETRIES times
+	dbMaxRetries := store.DefaultMaxRetries
+	if s := os.Getenv("DB_MAX_RETRIES"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("DB_MAX_RETRIES must be a non-negative integer, got %q", s)
+		}
+		dbMaxRetries = v
+	}
+
+	// Events are only written to the outbox when a broker or webhook is configured
+	eventBroker := os.Getenv("EVENT_BROKER")
+	if eventBroker == "" {
+		eventBroker = eventBrokerNone
+	}
+	var kafkaBrokers []string
+	kafkaTopic, natsURL, natsSubject := os.Getenv("KAFKA_TOPIC"), os.Getenv("NATS_URL"), os.Getenv("NATS_SUBJECT_PREFIX")
+	switch eventBroker {
+	case eventBrokerNone:
+	case eventBrokerKafka:
+		for _, b := range strings.Split(os.Getenv("KAFKA_BROKERS"), ",") {
+			if b = strings.TrimSpace(b); b != "" {
+				kafkaBrokers = append(kafkaBrokers, b)
+			}
+		}
+		if len(kafkaBrokers) == 0 {
+			return nil, errors.New("KAFKA_BROKERS is required when EVENT_BROKER=kafka")
+		}
+		if kafkaTopic == "" {
+			kafkaTopic = "transfer-events"
+		}
+	case eventBrokerNATS:
+		if natsURL == "" {
+			return nil, errors.New("NATS_URL is required when EVENT_BROKER=nats")
+		}
+		if natsSubject == "" {
+			natsSubject = "transfers"
+		}
+	default:
+		return nil, fmt.Errorf("EVENT_BROKER must be one of %q, %q, %q, got %q", eventBrokerNone, eventBrokerKafka, eventBrokerNATS, eventBroker)
+	}
+	// Events are also, or only, delivered to WEBHOOK_URL when set
+	webhookURL := os.Getenv("WEBHOOK_URL")
+	if webhookURL != "" {
+		u, err := url.Parse(webhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("WEBHOOK_URL must be an http or https URL, got %q", webhookURL)
+		}
+	}
+	outboxRetention := defaultOutboxRetention
+	if s := os.Getenv("OUTBOX_RETENTION"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("OUTBOX_RETENTION must be a non-negative duration, got %q", s)
+		}
+		outboxRetention = d
+	}
+
+	// Operators are notified over Slack and email when configured; each kind
+	// of notification goes to the channels NOTIFY_EVENTS routes it to, all of
+	// them by default
+	slackWebhookURL := os.Getenv("SLACK_WEBHOOK_URL")
+	if slackWebhookURL != "" {
+		u, err := url.Parse(slackWebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("SLACK_WEBHOOK_URL must be an http or https URL")
+		}
+	}
+	smtp := email.Config{
+		Addr:     os.Getenv("SMTP_ADDR"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	for _, to := range strings.Split(os.Getenv("SMTP_TO"), ",") {
+		if to = strings.TrimSpace(to); to != "" {
+			smtp.To = append(smtp.To, to)
+		}
+	}
+	if smtp.Addr != "" {
+		if _, _, err := net.SplitHostPort(smtp.Addr); err != nil {
+			return nil, fmt.Errorf("SMTP_ADDR must be host:port, got %q", smtp.Addr)
+		}
+		if smtp.From == "" || len(smtp.To) == 0 {
+			return nil, errors.New("SMTP_FROM and SMTP_TO are required when SMTP_ADDR is set")
+		}
+	}
+	notifyEvents := os.Getenv("NOTIFY_EVENTS")
+	if _, err := notify.ParseRoutes(notifyEvents, notifyChannels(slackWebhookURL, smtp)); err != nil {
+		return nil, fmt.Errorf("NOTIFY_EVENTS: %w", err)
+	}
+
+	// Transactions are kept forever unless a retention is set; archived months
+	// go to the archive table, or to ARCHIVE_DIR when set
+	txRetentionMonths := 0
+	if s := os.Getenv("TRANSACTION_RETENTION_MONTHS"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("TRANSACTION_RETENTION_MONTHS must be a non-negative integer, got %q", s)
+		}
+		txRetentionMonths = v
+	}
+	archiveDir := os.Getenv("ARCHIVE_DIR")
+	if archiveDir != "" {
+		if fi, err := os.Stat(archiveDir); err != nil || !fi.IsDir() {
+			return nil, fmt.Errorf("ARCHIVE_DIR must be an existing directory, got %q", archiveDir)
+		}
+	}
+
+	// Account reads are cached only when a backend is configured
+	cacheBackend := os.Getenv("CACHE_BACKEND")
+	if cacheBackend == "" {
+		cacheBackend = cacheBackendNone
+	}
+	redisURL := os.Getenv("REDIS_URL")
+	switch cacheBackend {
+	case cacheBackendNone, cacheBackendMemory:
+	case cacheBackendRedis:
+		if redisURL == "" {
+			return nil, errors.New("REDIS_URL is required when CACHE_BACKEND=redis")
+		}
+	default:
+		return nil, fmt.Errorf("CACHE_BACKEND must be one of %q, %q, %q, got %q", cacheBackendNone, cacheBackendMemory, cacheBackendRedis, cacheBackend)
+	}
+	// Events and the account cache are only wired into the Postgres store
+	if storeBackend == storeBackendSQLite && (eventBroker != eventBrokerNone || webhookURL != "" || cacheBackend != cacheBackendNone || liveUpdates) {
+		return nil, errors.New("EVENT_BROKER, WEBHOOK_URL, CACHE_BACKEND and LIVE_UPDATES are not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && (txRetentionMonths > 0 || archiveDir != "") {
+		return nil, errors.New("TRANSACTION_RETENTION_MONTHS and ARCHIVE_DIR are not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && seedEndpoint {
+		return nil, errors.New("SEED_ENDPOINT is not supported with STORE_BACKEND=sqlite; use transferctl seed")
+	}
+	if storeBackend == storeBackendSQLite && (slackWebhookURL != "" || smtp.Addr != "") {
+		return nil, errors.New("SLACK_WEBHOOK_URL and SMTP_ADDR are not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && anomalyDetection {
+		return nil, errors.New("ANOMALY_DETECTION is not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && (kyc.UnverifiedReceiveOnly || kyc.UnverifiedMaxAmount.Valid) {
+		return nil, errors.New("KYC_UNVERIFIED_RECEIVE_ONLY and KYC_UNVERIFIED_MAX_AMOUNT are not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && rulesFile != "" {
+		return nil, errors.New("RULES_FILE is not supported with STORE_BACKEND=sqlite")
+	}
+	if storeBackend == storeBackendSQLite && recordAttempts {
+		return nil, errors.New("RECORD_TRANSFER_ATTEMPTS is not supported with STORE_BACKEND=sqlite")
+	}
+	cacheTTL := store.DefaultAccountCacheTTL
+	if s := os.Getenv("CACHE_TTL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("CACHE_TTL must be a positive duration, got %q", s)
+		}
+		cacheTTL = d
+	}
+
+	cfg := &Config{
+		StoreBackend:        storeBackend,
+		SQLiteDSN:           sqliteDSN,
+		PostgresDSN:         dsn,
+		ReplicaDSN:          os.Getenv("POSTGRES_REPLICA_DSN"),
+		Pool:                pool,
+		Port:                port,
+		DebugAddr:           debugAddr,
+		ReqTimeout:          reqTimeout,
+		TransferTimeout:     transferTimeout,
+		TransferConcurrency: transferConcurrency,
+		TransferQueueWait:   transferQueueWait,
+		BreakerThreshold:    breakerThreshold,
+		BreakerCooldown:     breakerCooldown,
+		DrainTimeout:        drainTimeout,
+		LegacyRoutes:        legacyRoutes,
+		SerialIDs:           serialIDs,
+		RunMigrations:       runMigrations,
+		AuthMode:            authMode,
+		APIKeys:             apiKeys,
+		JWT:                 jwtCfg,
+		HMAC:                auth.HMACConfig{Clients: hmacClients, MaxSkew: hmacMaxSkew},
+		Network:             network,
+		MaxBodyBytes:        maxBodyBytes,
+		MaxJobFileBytes:     maxJobFileBytes,
+		MaxImportBytes:      maxImportBytes,
+		HoldExpiry:          holdExpiry,
+		SchedulerTick:       schedulerTick,
+		JobWorkers:          jobWorkers,
+		SnapshotInterval:    snapshotInterval,
+		ReconcileEvery:      reconcileEvery,
+		RulesReload:         rulesReload,
+		RulesFile:           rulesFile,
+		SettlementCutoff:    settlementCutoff,
+		SweepInterval:       sweepInterval,
+		AnomalyDetection:    anomalyDetection,
+		Anomalies:           anomalies,
+		KYC:                 kyc,
+		DBMaxRetries:        dbMaxRetries,
+		Amounts:             amounts,
+		PurposeCodes:        purposeCodes,
+		DecimalNumbers:      decimalNumbers,
+		EventBroker:         eventBroker,
+		KafkaBrokers:        kafkaBrokers,
+		KafkaTopic:          kafkaTopic,
+		NATSURL:             natsURL,
+		NATSSubject:         natsSubject,
+		WebhookURL:          webhookURL,
+		WebhookSecret:       os.Getenv("WEBHOOK_SECRET"),
+		OutboxRetention:     outboxRetention,
+		SlackWebhookURL:     slackWebhookURL,
+		SMTP:                smtp,
+		NotifyEvents:        notifyEvents,
+		TxRetentionMonths:   txRetentionMonths,
+		ArchiveDir:          archiveDir,
+		SwaggerUI:           swaggerUI,
+		SeedEndpoint:        seedEndpoint,
+		RecordAttempts:      recordAttempts,
+		CaptureRequests:     captureRequests,
+		CaptureFile:         captureFile,
+		CacheBackend:        cacheBackend,
+		RedisURL:            redisURL,
+		CacheTTL:            cacheTTL,
+		CrossTenant:         crossTenant,
+		LiveUpdates:         liveUpdates,
+		WSSubscriptions:     wsSubscriptions,
+	}
+	if err := loadRuntimeConfig(cfg); err != nil {
+		return nil, err
+	}
+	if storeBackend == storeBackendPostgres {
+		if err := loadDSNSource(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+// loadDSNSource reads where the Postgres DSN comes from: POSTGRES_DSN, the
+// file POSTGRES_DSN_FILE, such as a Docker or Kubernetes secret, or the secret
+// POSTGRES_DSN_SECRET of SECRETS_PROVIDER, keeping the password out of the
+// environment. Secrets are fetched again every SECRETS_REFRESH_INTERVAL, if
+// set, and on SIGHUP.
+func loadDSNSource(cfg *Config) error {
+	dsnFile, dsnSecret := os.Getenv("POSTGRES_DSN_FILE"), os.Getenv("POSTGRES_DSN_SECRET")
+	set := 0
+	for _, s := range []string{cfg.PostgresDSN, dsnFile, dsnSecret} {
+		if s != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("exactly one of POSTGRES_DSN, POSTGRES_DSN_FILE and POSTGRES_DSN_SECRET is required")
+	}
+
+	cfg.SecretsProvider = os.Getenv("SECRETS_PROVIDER")
+	switch {
+	case cfg.PostgresDSN != "":
+		if cfg.SecretsProvider != "" {
+			return errors.New("SECRETS_PROVIDER requires POSTGRES_DSN_SECRET instead of POSTGRES_DSN")
+		}
+	case dsnFile != "":
+		if cfg.SecretsProvider != "" && cfg.SecretsProvider != secretsProviderFile {
+			return errors.New("POSTGRES_DSN_FILE cannot be used with SECRETS_PROVIDER; set POSTGRES_DSN_SECRET")
+		}
+		cfg.SecretsProvider, cfg.DSNSecret = secretsProviderFile, dsnFile
+	default:
+		cfg.DSNSecret = dsnSecret
+		switch cfg.SecretsProvider {
+		case secretsProviderFile:
+		case secretsProviderVault:
+			cfg.VaultAddr, cfg.VaultToken = os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+			if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+				return errors.New("VAULT_ADDR and VAULT_TOKEN are required when SECRETS_PROVIDER=vault")
+			}
+		case secretsProviderAWS:
+			cfg.AWSRegion = os.Getenv("AWS_REGION")
+			if cfg.AWSRegion == "" {
+				cfg.AWSRegion = os.Getenv("AWS_DEFAULT_REGION")
+			}
+			cfg.AWSEndpoint = os.Getenv("AWS_ENDPOINT_URL_SECRETS_MANAGER")
+			cfg.AWSCredentials = awssm.Credentials{
+				AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			}
+			if cfg.AWSRegion == "" || cfg.AWSCredentials.AccessKeyID == "" || cfg.AWSCredentials.SecretAccessKey == "" {
+				return errors.New("AWS_REGION, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when SECRETS_PROVIDER=aws")
+			}
+		default:
+			return fmt.Errorf("SECRETS_PROVIDER must be one of %q, %q, %q with POSTGRES_DSN_SECRET, got %q",
+				secretsProviderFile, secretsProviderVault, secretsProviderAWS, cfg.SecretsProvider)
+		}
+	}
+
+	if s := os.Getenv("SECRETS_REFRESH_INTERVAL"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("SECRETS_REFRESH_INTERVAL must be a positive duration, got %q", s)
+		}
+		if cfg.DSNSecret == "" {
+			return errors.New("SECRETS_REFRESH_INTERVAL requires POSTGRES_DSN_FILE or POSTGRES_DSN_SECRET")
+		}
+		cfg.SecretsRefresh = d
+	}
+	return nil
+}
+
+// loadRuntimeConfig reads into cfg the settings that can change while the
+// server runs, listed in runtimeSettings.
+func loadRuntimeConfig(cfg *Config) error {
+	// Rate limiting is disabled unless RATE_LIMIT_RPS is set
+	var rateLimitRPS float64
+	if s := os.Getenv("RATE_LIMIT_RPS"); s != "" {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) {
+			return fmt.Errorf("RATE_LIMIT_RPS must be a non-negative number, got %q", s)
+		}
+		rateLimitRPS = v
+	}
+	rateLimitBurst := int(math.Ceil(rateLimitRPS))
+	if s := os.Getenv("RATE_LIMIT_BURST"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v <= 0 {
+			return fmt.Errorf("RATE_LIMIT_BURST must be a positive integer, got %q", s)
+		}
+		rateLimitBurst = v
+	}
+
+	// Prefer: respond-async is honoured unless disabled
+	asyncTransfers, err := envBool("ASYNC_TRANSFERS", true)
+	if err != nil {
+		return err
+	}
+
+	// Default transfer limits; accounts may override them
+	var limits store.TransferLimits
+	for _, v := range []struct {
+		env string
+		dst *decimal.NullDecimal
+	}{
+		{"TRANSFER_MAX_AMOUNT", &limits.MaxAmount},
+		{"TRANSFER_DAILY_LIMIT", &limits.Daily},
+	} {
+		if s := os.Getenv(v.env); s != "" {
+			d, err := decimal.NewFromString(s)
+			if err != nil || !d.IsPositive() {
+				return fmt.Errorf("%s must be a positive decimal, got %q", v.env, s)
+			}
+			*v.dst = decimal.NewNullDecimal(d)
+		}
+	}
+
+	// Which requests are logged
+	logLevel := model.LogLevelInfo
+	if s := os.Getenv("LOG_LEVEL"); s != "" {
+		switch s {
+		case model.LogLevelDebug, model.LogLevelInfo, model.LogLevelWarn, model.LogLevelError:
+			logLevel = s
+		default:
+			return fmt.Errorf("LOG_LEVEL must be debug, info, warn or error, got %q", s)
+		}
+	}
+
+	cfg.RateLimitRPS, cfg.RateLimitBurst = rateLimitRPS, rateLimitBurst
+	cfg.AsyncTransfers = asyncTransfers
+	cfg.TransferLimits = limits
+	cfg.LogLevel = logLevel
+	return nil
+}
+
+// runtimeConfig returns the runtime settings of cfg as the API takes them.
+func runtimeConfig(cfg *Config) model.RuntimeConfig {
+	c := model.RuntimeConfig{
+		RateLimitRPS:   cfg.RateLimitRPS,
+		RateLimitBurst: cfg.RateLimitBurst,
+		AsyncTransfers: cfg.AsyncTransfers,
+		LogLevel:       cfg.LogLevel,
+	}
+	if cfg.TransferLimits.MaxAmount.Valid {
+		c.TransferLimits.MaxTransferAmount = &model.DecimalString{Decimal: cfg.TransferLimits.MaxAmount.Decimal}
+	}
+	if cfg.TransferLimits.Daily.Valid {
+		c.TransferLimits.DailyTransferLimit = &model.DecimalString{Decimal: cfg.TransferLimits.Daily.Decimal}
+	}
+	return c
+}
+
+// reloadRuntimeConfig reads the runtime settings of cfg again, from the
+// .env and config files of f where not set by flags or the environment, and
+// puts them into effect in a. sources is updated as applyConfigSources does.
+func reloadRuntimeConfig(a *api.API, cfg *Config, f flags, sources map[string]string) error {
+	if err := reapplyRuntimeSettings(f, sources); err != nil {
+		return err
+	}
+	c := *cfg
+	if err := loadRuntimeConfig(&c); err != nil {
+		return err
+	}
+	if err := a.SetRuntimeConfig(runtimeConfig(&c)); err != nil {
+		return err
+	}
+	log.Printf("runtime config reloaded: %+v", a.Runtime())
+	return nil
+}
+
+// loadPoolConfig reads the DB_* connection pool settings, falling back to
+// store.DefaultPoolConfig. Durations use Go syntax, e.g. "30m" or "5s".
+func loadPoolConfig() (store.PoolConfig, error) {
+	pc := store.DefaultPoolConfig()
+	for _, v := range []struct {
+		env string
+		dst *int32
+	}{
+		{"DB_MAX_CONNS", &pc.MaxConns},
+		{"DB_MIN_CONNS", &pc.MinConns},
+	} {
+		if s := os.Getenv(v.env); s != "" {
+			n, err := strconv.ParseInt(s, 10, 32)
+			if err != nil || n < 0 {
+				return pc, fmt.Errorf("%s must be a non-negative integer, got %q", v.env, s)
+			}
+			*v.dst = int32(n)
+		}
+	}
+	if pc.MaxConns == 0 {
+		return pc, errors.New("DB_MAX_CONNS must be positive")
+	}
+	if pc.MinConns > pc.MaxConns {
+		return pc, fmt.Errorf("DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", pc.MinConns, pc.MaxConns)
+	}
+
+	for _, v := range []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"DB_CONN_MAX_LIFETIME", &pc.MaxConnLifetime},
+		{"DB_CONN_MAX_IDLE", &pc.MaxConnIdleTime},
+		{"DB_STATEMENT_TIMEOUT", &pc.StatementTimeout},
+		{"DB_CONNECT_WAIT", &pc.ConnectWait},
+	} {
+		if s := os.Getenv(v.env); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				return pc, fmt.Errorf("%s must be a non-negative duration, got %q", v.env, s)
+			}
+			*v.dst = d
+		}
+	}
+
+	if s := os.Getenv("DB_APPLICATION_NAME"); s != "" {
+		pc.ApplicationName = s
+	}
+	return pc, nil
 }
 
 func main() {
 
-	// Loading required config
+	// Loading required config: flags override the environment, which
+	// overrides .env, which overrides the config file
+	f, err := parseFlags(os.Args[1:])
+	if err != nil {
+		log.Fatalf("flags: %v", err)
+	}
+	sources, err := applyConfigSources(f)
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
 	cfg, err := loadConfig()
 	if err != nil {
 		log.Fatalf("config: %v", err)
 	}
+	log.Printf("effective config:%s", configSummary(sources))
+	if f.checkConfig {
+		return
+	}
+	model.Amounts = cfg.Amounts
+	model.PurposeCodes = cfg.PurposeCodes
+	model.DecimalNumbers = cfg.DecimalNumbers
+	info := buildInfo(cfg)
+	log.Printf("internal-transfers %s (commit %s, built %s, %s)", info.Version, info.GitSHA, info.BuildTime, info.GoVersion)
 
-	// Connecting to Database
+	// Configuring tracing (no-op unless an OTLP endpoint is set)
 	ctx := context.Background()
-	pool, err := store.Connect(ctx, cfg.PostgresDSN)
+	shutdownTracing, err := telemetry.SetupTracing(ctx)
+	if err != nil {
+		log.Fatalf("tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Printf("tracing shutdown: %v", err)
+		}
+	}()
+	shutdownMetrics, err := telemetry.SetupMetrics(ctx)
+	if err != nil {
+		log.Fatalf("metrics: %v", err)
+	}
+	defer func() {
+		if err := shutdownMetrics(context.Background()); err != nil {
+			log.Printf("metrics shutdown: %v", err)
+		}
+	}()
+	if err := telemetry.RegisterBuildInfo(info.Version, info.GitSHA, info.GoVersion); err != nil {
+		log.Fatalf("metrics: %v", err)
+	}
+
+	if cfg.StoreBackend == storeBackendSQLite {
+		runSQLite(ctx, cfg, f, sources)
+		return
+	}
+
+	// Connecting to Database, each pool with its own circuit breaker. A DSN
+	// read from a secret is read again on SIGHUP and every
+	// SECRETS_REFRESH_INTERVAL, and the pool moves to it once rotated.
+	primaryPool := cfg.Pool
+	primaryPool.Breaker = newBreaker(cfg, "primary")
+	dsn := cfg.PostgresDSN
+	secretsProvider := newSecretsProvider(cfg)
+	if secretsProvider != nil {
+		dsn, err = secretsProvider.Get(ctx, cfg.DSNSecret)
+		if err != nil {
+			log.Fatalf("db DSN secret: %v", err)
+		}
+		primaryPool.Rotation = &store.RotatingDSN{}
+	}
+	pool, err := store.Connect(ctx, dsn, primaryPool)
 	if err != nil {
 		log.Fatalf("db connect: %v", err)
 	}
 	defer pool.Close()
+	var refreshDSN func(context.Context) error
+	if secretsProvider != nil {
+		refreshDSN = func(ctx context.Context) error {
+			dsn, err := secretsProvider.Get(ctx, cfg.DSNSecret)
+			if err != nil {
+				return err
+			}
+			_, err = primaryPool.Rotation.Rotate(ctx, dsn)
+			return err
+		}
+		go reloadOnSIGHUP(ctx, "db DSN", refreshDSN)
+	}
+
+	// Reads that tolerate replication lag go to the replica, if configured
+	var replica *pgxpool.Pool
+	replicaPool := cfg.Pool
+	if cfg.ReplicaDSN != "" {
+		replicaPool.Breaker = newBreaker(cfg, "replica")
+		replica, err = store.Connect(ctx, cfg.ReplicaDSN, replicaPool)
+		if err != nil {
+			log.Fatalf("replica connect: %v", err)
+		}
+		defer replica.Close()
+	}
+
+	// Applying pending schema migrations
+	if cfg.RunMigrations {
+		applied, err := store.Migrate(ctx, pool, migrations.FS)
+		if err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		log.Printf("migrations applied: %d", len(applied))
+	}
+
+	// Connecting to the event broker, if any
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		log.Fatalf("event broker: %v", err)
+	}
+	storeOpts := []store.Option{store.WithMaxRetries(cfg.DBMaxRetries), store.WithDefaultLimits(cfg.TransferLimits), store.WithFeeScale(model.Amounts.Scale),
+		store.WithSettlementCutoff(cfg.SettlementCutoff), store.WithKYCPolicy(cfg.KYC)}
+	if cfg.CrossTenant {
+		storeOpts = append(storeOpts, store.WithCrossTenantTransfers())
+	}
+	if cfg.RulesFile != "" {
+		storeOpts = append(storeOpts, store.WithRulesFile(func() ([]store.Rule, error) { return api.LoadRulesFile(cfg.RulesFile) }))
+	}
+
+	// Connecting to the account cache, if any
+	accountCache, err := newCache(ctx, cfg)
+	if err != nil {
+		log.Fatalf("cache: %v", err)
+	}
+	if accountCache != nil {
+		storeOpts = append(storeOpts, store.WithAccountCache(accountCache, cfg.CacheTTL))
+		defer func() {
+			if err := accountCache.Close(); err != nil {
+				log.Printf("cache close: %v", err)
+			}
+		}()
+	}
+	if replica != nil {
+		storeOpts = append(storeOpts, store.WithReplica(replica))
+	}
+	if publisher != nil {
+		storeOpts = append(storeOpts, store.WithOutbox())
+		defer func() {
+			if err := publisher.Close(); err != nil {
+				log.Printf("event broker close: %v", err)
+			}
+		}()
+	}
+
+	// Transfers notify a channel on commit, which a dedicated connection
+	// listens on to feed the event streams
+	var listener *store.Listener
+	if cfg.LiveUpdates {
+		storeOpts = append(storeOpts, store.WithNotify())
+		listener = store.NewListener(pool, store.TransferChannel)
+		listenCtx, stopListening := context.WithCancel(ctx)
+		defer stopListening()
+		go listener.Run(listenCtx)
+	}
 
 	// Initializing HTTP API and Router
-	s := store.NewStore(pool)
-	a := api.New(s)
+	s := store.NewStore(pool, storeOpts...)
+	apiOpts := apiOptions(cfg, apiKeyLookup(s), s.RecordSignature)
+	if listener != nil {
+		apiOpts = append(apiOpts, api.WithTransferFeed(listener))
+	}
+	if cfg.SeedEndpoint {
+		log.Printf("warning: SEED_ENDPOINT is enabled; POST /v1/admin/seed generates demo data")
+		apiOpts = append(apiOpts, api.WithSeeder(func(ctx context.Context, o seed.Options) (seed.Result, error) {
+			return seed.Postgres(ctx, pool, o)
+		}))
+	}
+	if cfg.RecordAttempts {
+		apiOpts = append(apiOpts, api.WithAttemptLog(s))
+	}
+	// Notifications are delivered in the background, and those waiting on
+	// shutdown are still sent
+	var notifier notify.Notifier
+	router, err := notify.ParseRoutes(cfg.NotifyEvents, notifyChannels(cfg.SlackWebhookURL, cfg.SMTP))
+	if err != nil {
+		log.Fatalf("notifications: %v", err)
+	}
+	if cfg.SlackWebhookURL != "" || cfg.SMTP.Addr != "" {
+		queue := notify.NewAsync(router, notificationQueueSize)
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), notify.DefaultTimeout)
+			defer cancel()
+			if err := queue.Close(ctx); err != nil {
+				log.Printf("notifications close: %v", err)
+			}
+		}()
+		notifier = queue
+	}
+	a := api.New(s, apiOpts...)
+	go reloadOnSIGHUP(ctx, "runtime config", func(context.Context) error {
+		return reloadRuntimeConfig(a, cfg, f, sources)
+	})
 
-	// Router and routes
-	r := setupRouter(a, pool)
+	// Transfers are checked against the fraud rules and charged fees from the start
+	if _, err := s.ReloadRules(ctx); err != nil {
+		log.Fatalf("load rules: %v", err)
+	}
+	if _, err := s.ReloadFeePolicies(ctx); err != nil {
+		log.Fatalf("load fee policies: %v", err)
+	}
 
-	// Configuring HTTP server
+	// Background jobs run until the server shuts down
+	workers := worker.NewGroup(ctx)
+	// Every instance reloads the rules and fee policies, so that changes
+	// apply everywhere
+	workers.Go("rules-reload", cfg.RulesReload, func(ctx context.Context) error {
+		if _, err := s.ReloadRules(ctx); err != nil {
+			return err
+		}
+		_, err := s.ReloadFeePolicies(ctx)
+		return err
+	})
+	workers.Go("hold-expiry", cfg.HoldExpiry, func(ctx context.Context) error {
+		n, err := s.ExpireHolds(ctx, time.Now())
+		if n > 0 {
+			log.Printf("expired %d holds", n)
+		}
+		return err
+	})
+	if len(cfg.HMAC.Clients) > 0 {
+		workers.Go("signature-purge", signaturePurgeInterval, func(ctx context.Context) error {
+			_, err := s.PurgeSignatures(ctx, time.Now())
+			return err
+		})
+	}
+	if refreshDSN != nil && cfg.SecretsRefresh > 0 {
+		workers.Go("dsn-refresh", cfg.SecretsRefresh, refreshDSN)
+	}
+	// Only the instance holding the scheduler lock executes scheduled transfers
+	schedulerLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.SchedulerLockID)
+	}
+	workers.Go("scheduler", cfg.SchedulerTick, worker.Exclusive(schedulerLock, func(ctx context.Context) error {
+		n, err := s.ExecuteDueTransfers(ctx, time.Now(), scheduledBatchSize)
+		if n > 0 {
+			log.Printf("executed %d scheduled transfers", n)
+		}
+		return err
+	}))
+	workers.Go("async-transfers", pendingPollInterval, func(ctx context.Context) error {
+		_, err := s.ExecutePendingTransfers(ctx, pendingBatchSize)
+		return err
+	})
+	workers.Go("transfer-jobs", jobPollInterval, worker.TransferJobs(s, cfg.JobWorkers, jobLease))
+	// Only the instance holding the snapshot lock takes balance snapshots
+	snapshotLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.SnapshotLockID)
+	}
+	workers.Go("balance-snapshots", snapshotPollInterval, worker.Exclusive(snapshotLock, worker.BalanceSnapshots(s, cfg.SnapshotInterval, clock.Real{})))
+	// Only the instance holding the reconciliation lock reconciles balances
+	reconcileLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.ReconciliationLockID)
+	}
+	workers.Go("reconciliation", cfg.ReconcileEvery, worker.Exclusive(reconcileLock, worker.Reconciliation(s, notifier)))
+	// Only the instance holding the statement lock generates monthly statements
+	statementLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.StatementLockID)
+	}
+	workers.Go("statements", statementPollInterval, worker.Exclusive(statementLock, worker.MonthlyStatements(s, clock.Real{})))
+	// Only the instance holding the settlement lock settles clearing transfers
+	settlementLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.SettlementLockID)
+	}
+	workers.Go("settlement", settlementPollInterval, worker.Exclusive(settlementLock, worker.Settlement(s)))
+	// Only the instance holding the sweep lock runs the sweep rules
+	sweepLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.SweepLockID)
+	}
+	workers.Go("sweeps", cfg.SweepInterval, worker.Exclusive(sweepLock, worker.Sweeps(s)))
+	// Only the instance holding the anomaly lock analyzes transfers
+	if cfg.AnomalyDetection {
+		anomalyLock := func(ctx context.Context) (func(), bool, error) {
+			return s.TryAdvisoryLock(ctx, store.AnomalyLockID)
+		}
+		workers.Go("anomalies", anomalyPollInterval, worker.Exclusive(anomalyLock, worker.Anomalies(s, cfg.Anomalies)))
+	}
+	// Each flagged transfer, alert and dead-lettered event is claimed for a
+	// notification by one instance
+	if notifier != nil && router.Routed(notify.KindReview) {
+		workers.Go("review-notifications", notificationPollInterval, worker.ReviewNotifications(s, notifier, notificationBatchSize))
+	}
+	if notifier != nil && router.Routed(notify.KindAlert) {
+		workers.Go("alert-notifications", notificationPollInterval, worker.AlertNotifications(s, notifier, notificationBatchSize))
+	}
+	if notifier != nil && router.Routed(notify.KindDeadLetter) {
+		workers.Go("dead-letter-notifications", notificationPollInterval, worker.DeadLetterNotifications(s, notifier, notificationBatchSize))
+	}
+	// Only the instance holding the partition lock creates and archives partitions
+	partitionLock := func(ctx context.Context) (func(), bool, error) {
+		return s.TryAdvisoryLock(ctx, store.PartitionLockID)
+	}
+	var exporter store.TransactionExporter
+	if cfg.ArchiveDir != "" {
+		exporter = archive.NewDir(cfg.ArchiveDir)
+	}
+	workers.Go("transaction-partitions", partitionInterval, worker.Exclusive(partitionLock,
+		worker.TransactionPartitions(s, cfg.TxRetentionMonths, exporter, clock.Real{})))
+	// Only the instance holding the outbox lock relays events, keeping them in
+	// order; the first run catches up on the events left while it was down
+	if publisher != nil {
+		outboxLock := func(ctx context.Context) (func(), bool, error) {
+			return s.TryAdvisoryLock(ctx, store.OutboxLockID)
+		}
+		workers.Once("outbox-catch-up", worker.Exclusive(outboxLock, worker.OutboxCatchUp(s, publisher, outboxCatchUpBatchSize)))
+		workers.Go("outbox-relay", outboxPollInterval, worker.Exclusive(outboxLock, worker.Outbox(s, publisher, outboxBatchSize)))
+		workers.Go("outbox-purge", outboxPurgeInterval, worker.PurgeOutbox(s, cfg.OutboxRetention, clock.Real{}))
+	}
+
+	// Router and routes; readiness requires the primary pool, the replica pool
+	// when configured and an up-to-date schema. The service works on without the
+	// cache, the broker (events wait in the outbox) or a stalled worker, so those
+	// only degrade it. The circuit breakers' state is reported alongside.
+	checks := []api.ReadinessCheck{api.PoolCheck("db", pool)}
+	if replica != nil {
+		checks = append(checks, api.PoolCheck("db-replica", replica))
+	}
+	if b := primaryPool.Breaker; b != nil {
+		checks = append(checks, api.ReadinessCheck{Name: "db-breaker", Check: b.Check})
+	}
+	if b := replicaPool.Breaker; b != nil {
+		checks = append(checks, api.ReadinessCheck{Name: "db-replica-breaker", Check: b.Check})
+	}
+	checks = append(checks, api.ReadinessCheck{Name: "migrations", Required: true, Check: migrationsCheck(pool)})
+	if p, ok := accountCache.(pinger); ok {
+		checks = append(checks, api.ReadinessCheck{Name: "cache", Check: p.Ping})
+	}
+	if p, ok := publisher.(pinger); ok {
+		checks = append(checks, api.ReadinessCheck{Name: "broker", Check: p.Ping})
+	}
+	if listener != nil {
+		checks = append(checks, api.ReadinessCheck{Name: "db-listener", Check: listener.Check})
+	}
+	checks = append(checks, api.ReadinessCheck{Name: "workers", Check: workers.Check})
+	serve(a, setupRouter(a, cfg, api.ReadyHandler(checks...)), cfg, func(ctx context.Context) {
+		if err := workers.Stop(ctx); err != nil {
+			log.Printf("background jobs cancelled: %v", err)
+		}
+		// Relay the events of the last transfers rather than leave them to
+		// another instance
+		if publisher != nil {
+			outboxLock := func(ctx context.Context) (func(), bool, error) {
+				return s.TryAdvisoryLock(ctx, store.OutboxLockID)
+			}
+			if err := worker.Exclusive(outboxLock, worker.Outbox(s, publisher, outboxBatchSize))(ctx); err != nil {
+				log.Printf("flush outbox: %v", err)
+			}
+		}
+	})
+}
+
+// runSQLite serves the API from the SQLite database at cfg.SQLiteDSN. Of the
+// background workers only hold expiry runs: the features of the others are
+// not supported by the SQLite store.
+func runSQLite(ctx context.Context, cfg *Config, f flags, sources map[string]string) {
+	opts := []sqlite.Option{sqlite.WithDefaultLimits(cfg.TransferLimits)}
+	if cfg.CrossTenant {
+		opts = append(opts, sqlite.WithCrossTenantTransfers())
+	}
+	s, err := sqlite.Open(cfg.SQLiteDSN, opts...)
+	if err != nil {
+		log.Fatalf("sqlite: %v", err)
+	}
+	defer s.Close()
+
+	// API keys can only be configured statically
+	a := api.New(s, apiOptions(cfg, nil, nil)...)
+	go reloadOnSIGHUP(ctx, "runtime config", func(context.Context) error {
+		return reloadRuntimeConfig(a, cfg, f, sources)
+	})
+
+	workers := worker.NewGroup(ctx)
+	workers.Go("hold-expiry", cfg.HoldExpiry, func(ctx context.Context) error {
+		n, err := s.ExpireHolds(ctx, time.Now())
+		if n > 0 {
+			log.Printf("expired %d holds", n)
+		}
+		return err
+	})
+
+	ready := api.ReadyHandler(
+		api.ReadinessCheck{Name: "db", Required: true, Check: s.Ping},
+		api.ReadinessCheck{Name: "workers", Check: workers.Check},
+	)
+	serve(a, setupRouter(a, cfg, ready), cfg, func(ctx context.Context) {
+		if err := workers.Stop(ctx); err != nil {
+			log.Printf("background jobs cancelled: %v", err)
+		}
+	})
+}
+
+// apiOptions returns the API options selected by cfg. API keys not configured
+// statically are resolved with lookup, if not nil, and the signatures of
+// signed requests are recorded with firstUse, if not nil, for every instance
+// to refuse their replays.
+func apiOptions(cfg *Config, lookup auth.KeyLookupFunc, firstUse auth.FirstUseFunc) []api.Option {
+	opts := []api.Option{
+		api.WithTimeout(cfg.ReqTimeout),
+		api.WithTransferTimeout(cfg.TransferTimeout),
+		api.WithTransferConcurrency(cfg.TransferConcurrency, cfg.TransferQueueWait),
+		api.WithWriteTimeout(serverWriteTimeout),
+		api.WithMaxBodyBytes(cfg.MaxBodyBytes),
+		api.WithMaxJobFileBytes(cfg.MaxJobFileBytes),
+		api.WithMaxImportBytes(cfg.MaxImportBytes),
+		api.WithAsyncTransfers(cfg.AsyncTransfers),
+		api.WithSerialTransactionIDs(cfg.SerialIDs),
+		api.WithLogLevel(cfg.LogLevel),
+		api.WithWebSocketSubscriptions(cfg.WSSubscriptions),
+	}
+	var authn auth.Authenticator
+	switch cfg.AuthMode {
+	case authModeAPIKey:
+		authn = auth.NewAPIKeyAuthenticator(cfg.APIKeys, lookup)
+	case authModeJWT:
+		authn = auth.NewJWTAuthenticator(cfg.JWT)
+	}
+	// Signed bodies are read whole to be verified before the caller is known,
+	// so they are held to the smallest limit
+	if len(cfg.HMAC.Clients) > 0 {
+		hmacCfg := cfg.HMAC
+		hmacCfg.MaxBodyBytes = cfg.MaxBodyBytes
+		hmacCfg.FirstUse = firstUse
+		authn = auth.NewHMACAuthenticator(hmacCfg, authn)
+	}
+	if authn != nil {
+		opts = append(opts, api.WithAuthenticator(authn))
+	}
+	opts = append(opts, api.WithNetworkPolicy(cfg.Network))
+	if cfg.RateLimitRPS > 0 {
+		opts = append(opts, api.WithRateLimiter(api.NewRateLimiter(cfg.RateLimitRPS, cfg.RateLimitBurst)))
+	}
+	if cfg.CaptureRequests > 0 || cfg.CaptureFile != "" {
+		opts = append(opts, api.WithRecorder(newRecorder(cfg)))
+	}
+	return opts
+}
+
+// newRecorder returns the recorder of the capture mode, appending to
+// CAPTURE_FILE if set. The file stays open until the process exits.
+func newRecorder(cfg *Config) *api.Recorder {
+	log.Printf("warning: capture mode is on; request and response bodies are recorded")
+	if cfg.CaptureFile == "" {
+		return api.NewRecorder(cfg.CaptureRequests, nil)
+	}
+	f, err := os.OpenFile(cfg.CaptureFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		log.Fatalf("CAPTURE_FILE: %v", err)
+	}
+	return api.NewRecorder(cfg.CaptureRequests, f)
+}
+
+// serve runs an HTTP server with handler, serving a, until a shutdown signal,
+// then drains it (see shutdownOnSignal). The caller closes the store once
+// serve returns.
+func serve(a *api.API, handler http.Handler, cfg *Config, drain func(context.Context)) {
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
-		Handler:      r,
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: serverWriteTimeout,
 		IdleTimeout:  60 * time.Second,
 	}
+	// Event streams never finish on their own
+	srv.RegisterOnShutdown(a.StopStreams)
+
+	if cfg.DebugAddr != "" {
+		debugSrv := startDebugServer(a, cfg.DebugAddr)
+		defer debugSrv.Close()
+	}
 
 	// Start server and wait for shutdown
 	serverErr := startServer(srv)
-	shutdownOnSignal(srv, serverErr)
+	shutdownOnSignal(srv, serverErr, a, cfg.DrainTimeout, drain)
 	log.Println("server gracefully stopped")
 }
 
+// newPublisher connects to the broker selected by EVENT_BROKER, delivering to
+// WEBHOOK_URL as well if set. It returns nil when neither is configured.
+func newPublisher(cfg *Config) (events.Publisher, error) {
+	var pubs []events.Publisher
+	switch cfg.EventBroker {
+	case eventBrokerKafka:
+		pubs = append(pubs, kafka.NewPublisher(cfg.KafkaBrokers, cfg.KafkaTopic))
+	case eventBrokerNATS:
+		p, err := nats.NewPublisher(cfg.NATSURL, cfg.NATSSubject)
+		if err != nil {
+			return nil, err
+		}
+		pubs = append(pubs, p)
+	}
+	if cfg.WebhookURL != "" {
+		pubs = append(pubs, webhook.NewPublisher(cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	if len(pubs) == 0 {
+		return nil, nil
+	}
+	return events.Fanout(pubs...), nil
+}
+
+// notifyChannels returns the notification channels configured, by the names
+// NOTIFY_EVENTS routes to.
+func notifyChannels(slackWebhookURL string, smtp email.Config) map[string]notify.Notifier {
+	channels := make(map[string]notify.Notifier)
+	if slackWebhookURL != "" {
+		channels[notifyChannelSlack] = slack.New(slackWebhookURL)
+	}
+	if smtp.Addr != "" {
+		channels[notifyChannelEmail] = email.New(smtp)
+	}
+	return channels
+}
+
+// newSecretsProvider returns the provider of the DSN secret selected by
+// SECRETS_PROVIDER, or nil if the DSN is given by POSTGRES_DSN.
+func newSecretsProvider(cfg *Config) secrets.Provider {
+	switch cfg.SecretsProvider {
+	case secretsProviderFile:
+		return secrets.Files{}
+	case secretsProviderVault:
+		return vault.New(cfg.VaultAddr, cfg.VaultToken)
+	case secretsProviderAWS:
+		return awssm.New(cfg.AWSRegion, cfg.AWSEndpoint, cfg.AWSCredentials)
+	}
+	return nil
+}
+
+// reloadOnSIGHUP runs reload, logging its errors, each time the process
+// receives SIGHUP, until ctx is done.
+func reloadOnSIGHUP(ctx context.Context, name string, reload func(context.Context) error) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Printf("SIGHUP received, reloading %s", name)
+			if err := reload(ctx); err != nil {
+				log.Printf("reload %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// newBreaker returns the circuit breaker of the pool name, or nil if they are
+// disabled.
+func newBreaker(cfg *Config, name string) *store.CircuitBreaker {
+	if cfg.BreakerThreshold == 0 {
+		return nil
+	}
+	return store.NewCircuitBreaker(name, cfg.BreakerThreshold, cfg.BreakerCooldown)
+}
+
+// pinger is implemented by the caches and brokers that can check their
+// connection.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// migrationsCheck returns a readiness check failing while migrations are
+// pending, such as when another instance is still applying them.
+func migrationsCheck(pool *pgxpool.Pool) func(context.Context) error {
+	return func(ctx context.Context) error {
+		pending, err := store.PendingMigrations(ctx, pool, migrations.FS)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending: %s", len(pending), strings.Join(pending, ", "))
+		}
+		return nil
+	}
+}
+
+// newCache returns the configured account cache, or nil if caching is disabled.
+func newCache(ctx context.Context, cfg *Config) (cache.Cache, error) {
+	switch cfg.CacheBackend {
+	case cacheBackendMemory:
+		return cache.NewMemory(), nil
+	case cacheBackendRedis:
+		c, err := rediscache.New(ctx, cfg.RedisURL, "internal-transfers:")
+		if err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+// principalName returns the name of p as configured in API_KEYS: tenant/name
+// for a key scoped to a tenant.
+func principalName(p auth.Principal) string {
+	if p.Tenant != "" {
+		return p.Tenant + "/" + p.Subject
+	}
+	return p.Subject
+}
+
+// apiKeyLookup resolves API keys stored in the database.
+func apiKeyLookup(s *store.Store) auth.KeyLookupFunc {
+	return func(ctx context.Context, keyHash string) (auth.Principal, error) {
+		k, err := s.LookupAPIKey(ctx, keyHash)
+		if err != nil {
+			if errors.Is(err, store.ErrAPIKeyNotFound) {
+				return auth.Principal{}, auth.ErrUnauthenticated
+			}
+			return auth.Principal{}, err
+		}
+		return auth.Principal{Subject: k.Name, Tenant: k.Tenant, Role: auth.Role(k.Role), Networks: k.Networks}, nil
+	}
+}
+
 // startServer starts the HTTP server in a goroutine and returns a channel receiving any server error.
 func startServer(srv *http.Server) <-chan error {
 	ch := make(chan error, 1)
@@ -101,8 +1752,34 @@ func startServer(srv *http.Server) <-chan error {
 	return ch
 }
 
-// shutdownOnSignal waits for an OS signal or server error and performs a graceful shutdown.
-func shutdownOnSignal(srv *http.Server, serverErr <-chan error) {
+// startDebugServer serves the pprof profiles and runtime statistics of a on
+// addr. It has no write timeout, since CPU profiles and traces stream for as
+// long as the caller asks.
+func startDebugServer(a *api.API, addr string) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           a.DebugHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		log.Printf("debug server listening on %s", addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("debug server: %v", err)
+		}
+	}()
+	return srv
+}
+
+// abortGrace is how long requests cancelled at the end of the drain period get
+// to return, which they do as soon as their store calls notice
+const abortGrace = 5 * time.Second
+
+// shutdownOnSignal waits for an OS signal or server error and performs a
+// graceful shutdown: the server stops accepting requests, and in-flight
+// requests then drain, the background work of a stops and the outbox is
+// flushed, all within drainPeriod. Requests still running after it are
+// cancelled. Either way none is left using the store on return.
+func shutdownOnSignal(srv *http.Server, serverErr <-chan error, a *api.API, drainPeriod time.Duration, drain func(context.Context)) {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
@@ -115,24 +1792,48 @@ func shutdownOnSignal(srv *http.Server, serverErr <-chan error) {
 		}
 	}
 
-	ctxShutdown, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), drainPeriod)
 	defer cancel()
-	if err := srv.Shutdown(ctxShutdown); err != nil {
-		log.Fatalf("server shutdown failed: %v", err)
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("requests still in flight after %s, cancelling them: %v", drainPeriod, err)
+		// Closing their connections cancels their contexts
+		_ = srv.Close()
+	}
+	abortCtx, cancelAbort := context.WithTimeout(context.Background(), abortGrace)
+	defer cancelAbort()
+	if err := a.Drain(abortCtx); err != nil {
+		log.Printf("requests still in flight: %v", err)
 	}
+	drain(ctx)
 }
 
 // setupRouter configures middleware, health endpoints and application routes.
-func setupRouter(a *api.API, pool *pgxpool.Pool) *mux.Router {
+// ready answers the readiness probe.
+func setupRouter(a *api.API, cfg *Config, ready http.HandlerFunc) *mux.Router {
 	r := mux.NewRouter()
-	r.Use(api.LoggingMiddleware)
+	r.NotFoundHandler = api.NotFoundHandler()
+	r.MethodNotAllowedHandler = api.MethodNotAllowedHandler()
+	r.Use(api.TracingMiddleware)
+	r.Use(a.LoggingMiddleware)
+	r.Use(api.CompressionMiddleware)
 
 	// Health endpoints
 	r.HandleFunc("/healthz", api.HealthHandler).Methods(http.MethodGet)
-	r.HandleFunc("/readyz", api.ReadyHandler(pool)).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", ready).Methods(http.MethodGet)
+	r.HandleFunc("/version", api.VersionHandler(buildInfo(cfg))).Methods(http.MethodGet)
+
+	// API documentation
+	r.HandleFunc("/openapi.json", api.OpenAPIHandler).Methods(http.MethodGet)
+	r.HandleFunc("/errors", api.ErrorCatalogHandler).Methods(http.MethodGet)
+	if cfg.SwaggerUI {
+		r.HandleFunc("/docs", api.SwaggerUIHandler).Methods(http.MethodGet)
+	}
 
 	// Application routes
 	a.RegisterRoutes(r)
+	if cfg.LegacyRoutes {
+		a.RegisterLegacyRoutes(r)
+	}
 
 	return r
 }
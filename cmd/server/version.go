@@ -0,0 +1,81 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// Set at build time, e.g. by make build:
+//
+//	go build -ldflags "-X main.version=v1.4.0 -X main.gitSHA=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)"
+//
+// Without them, the revision and time of the commit recorded by the Go
+// toolchain are used, when built from a git checkout.
+var (
+	version   = "dev"
+	gitSHA    string
+	buildTime string
+)
+
+// buildInfo describes the running build and the features cfg enables.
+func buildInfo(cfg *Config) model.VersionResponse {
+	info := model.VersionResponse{
+		Version:   version,
+		GitSHA:    gitSHA,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Features:  features(cfg),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.GitSHA == "":
+				info.GitSHA = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	if info.GitSHA == "" {
+		info.GitSHA = "unknown"
+	}
+	return info
+}
+
+// features lists the optional features cfg enables, with the backends chosen.
+func features(cfg *Config) []string {
+	features := []string{"store:" + cfg.StoreBackend, "auth:" + cfg.AuthMode}
+	if cfg.EventBroker != eventBrokerNone {
+		features = append(features, "events:"+cfg.EventBroker)
+	}
+	if cfg.WebhookURL != "" {
+		features = append(features, "events:webhook")
+	}
+	if cfg.CacheBackend != cacheBackendNone {
+		features = append(features, "cache:"+cfg.CacheBackend)
+	}
+	for _, f := range []struct {
+		name string
+		on   bool
+	}{
+		{"async_transfers", cfg.AsyncTransfers},
+		{"cross_tenant_transfers", cfg.CrossTenant},
+		{"legacy_routes", cfg.LegacyRoutes},
+		{"serial_transaction_ids", cfg.SerialIDs},
+		{"swagger_ui", cfg.SwaggerUI},
+		{"read_replica", cfg.ReplicaDSN != ""},
+		{"debug_server", cfg.DebugAddr != ""},
+		{"request_capture", cfg.CaptureRequests > 0 || cfg.CaptureFile != ""},
+		{"transfer_attempts", cfg.RecordAttempts},
+		{"rate_limit", cfg.RateLimitRPS > 0},
+		{"live_updates", cfg.LiveUpdates},
+		{"transaction_archival", cfg.TxRetentionMonths > 0},
+	} {
+		if f.on {
+			features = append(features, f.name)
+		}
+	}
+	return features
+}
@@ -0,0 +1,139 @@
+// Command transferctl runs maintenance tasks against the database of the
+// transfers service.
+//
+// Usage:
+//
+//	transferctl seed [flags]
+//
+// seed generates accounts and historical transfers between them, for load
+// tests and demo environments. It writes to the Postgres database at -dsn
+// ($POSTGRES_DSN), which must be migrated, or to the SQLite database at
+// -sqlite ($SQLITE_DSN).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/seed"
+	"github.com/you/internal-transfers/internal/store"
+	"github.com/you/internal-transfers/internal/store/sqlite"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "transferctl:", err)
+		os.Exit(1)
+	}
+}
+
+// run runs the command given by args, without the program name.
+func run(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: transferctl seed [flags]")
+	}
+	switch args[0] {
+	case "seed":
+		return runSeed(ctx, args[1:], out)
+	}
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+// seedFlags are the flags of transferctl seed
+type seedFlags struct {
+	dsn, sqlitePath, tenant string
+	opts                    seed.Options
+}
+
+// parseSeedFlags parses the flags of transferctl seed.
+func parseSeedFlags(args []string) (seedFlags, error) {
+	f := seedFlags{opts: seed.DefaultOptions()}
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.StringVar(&f.dsn, "dsn", os.Getenv("POSTGRES_DSN"), "Postgres DSN (default $POSTGRES_DSN)")
+	fs.StringVar(&f.sqlitePath, "sqlite", os.Getenv("SQLITE_DSN"), "SQLite database, instead of Postgres (default $SQLITE_DSN)")
+	fs.StringVar(&f.tenant, "tenant", "", "tenant of the accounts")
+	fs.IntVar(&f.opts.Accounts, "accounts", f.opts.Accounts, fmt.Sprintf("accounts to create, at most %d", seed.MaxAccounts))
+	fs.StringVar(&f.opts.Currency, "currency", f.opts.Currency, "currency of the accounts")
+	fs.StringVar(&f.opts.Distribution, "distribution", f.opts.Distribution, "distribution of the initial balances: uniform, normal or pareto")
+	fs.Func("min-balance", "lowest initial balance (default 0)", decimalFlag(&f.opts.MinBalance))
+	fs.Func("max-balance", "highest initial balance (default "+f.opts.MaxBalance.String()+")", decimalFlag(&f.opts.MaxBalance))
+	fs.IntVar(&f.opts.Transfers, "transfers", 0, fmt.Sprintf("transfers to make between the accounts, at most %d", seed.MaxTransfers))
+	fs.DurationVar(&f.opts.Period, "period", f.opts.Period, "period before now the transfers are spread over")
+	fs.Uint64Var(&f.opts.Seed, "seed", 0, "seed of the random generator, for repeatable data (default random)")
+	if err := fs.Parse(args); err != nil {
+		return f, err
+	}
+	if fs.NArg() > 0 {
+		return f, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	if (f.dsn == "") == (f.sqlitePath == "") {
+		return f, errors.New("exactly one of -dsn and -sqlite is required")
+	}
+	return f, f.opts.Validate()
+}
+
+// decimalFlag parses a flag into d.
+func decimalFlag(d *decimal.Decimal) func(string) error {
+	return func(s string) error {
+		v, err := decimal.NewFromString(s)
+		if err != nil {
+			return fmt.Errorf("invalid decimal %q", s)
+		}
+		*d = v
+		return nil
+	}
+}
+
+// runSeed runs transferctl seed.
+func runSeed(ctx context.Context, args []string, out io.Writer) error {
+	f, err := parseSeedFlags(args)
+	if err != nil {
+		return err
+	}
+	if f.tenant != "" {
+		ctx = store.WithTenant(ctx, f.tenant)
+	}
+
+	start := time.Now()
+	res, err := seedDatabase(ctx, f)
+	if err != nil {
+		return fmt.Errorf("seed: %w (created %d accounts and %d transfers)", err, len(res.Accounts), res.Transfers)
+	}
+
+	fmt.Fprintf(out, "created %d accounts (external ids %s1 to %s%d) and %d transfers from %s to %s, skipped %d, in %s\n",
+		len(res.Accounts), res.ExternalID, res.ExternalID, len(res.Accounts), res.Transfers,
+		res.From.UTC().Format(time.RFC3339), res.To.UTC().Format(time.RFC3339), res.Skipped, time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// seedDatabase seeds the database selected by f.
+func seedDatabase(ctx context.Context, f seedFlags) (seed.Result, error) {
+	if f.sqlitePath != "" {
+		clk := clock.NewFake(time.Now())
+		s, err := sqlite.Open(f.sqlitePath, sqlite.WithClock(clk))
+		if err != nil {
+			return seed.Result{}, err
+		}
+		defer s.Close()
+		return seed.Run(ctx, s, clk, f.opts)
+	}
+	pool, err := pgxpool.New(ctx, f.dsn)
+	if err != nil {
+		return seed.Result{}, fmt.Errorf("connect: %w", err)
+	}
+	defer pool.Close()
+	return seed.Postgres(ctx, pool, f.opts)
+}
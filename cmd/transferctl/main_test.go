@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunSeed tests transferctl seed against a SQLite database
+func TestRunSeed(t *testing.T) {
+	t.Setenv("POSTGRES_DSN", "")
+	t.Setenv("SQLITE_DSN", "")
+	path := filepath.Join(t.TempDir(), "seed.db")
+	var out bytes.Buffer
+	err := run(context.Background(), []string{"seed", "-sqlite", path, "-accounts", "10", "-transfers", "50", "-distribution", "normal", "-max-balance", "500"}, &out)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "created 10 accounts") {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"unknown"},
+		{"seed"},
+		{"seed", "-sqlite", path, "-dsn", "postgres://localhost/transfers"},
+		{"seed", "-sqlite", path, "-accounts", "0"},
+		{"seed", "-sqlite", path, "-min-balance", "ten"},
+	} {
+		if err := run(context.Background(), args, &out); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
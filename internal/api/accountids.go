@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// accountPath lets the {id} of an account route be the account's external id
+// or one of its aliases: it is looked up and replaced by the numeric id before
+// h runs. Numeric and malformed ids are left for h to handle.
+func (a *API) accountPath(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		ref, err := model.ParseAccountID(vars["id"])
+		if err != nil || ref.ExternalID == "" {
+			h(w, r)
+			return
+		}
+
+		ctx, cancel := a.requestContext(r)
+		defer cancel()
+
+		ids, err := a.resolveAccountIDs(ctx, ref)
+		if err != nil {
+			a.logger.Printf("resolve account failed: externalID=%s, error=%v", ref.ExternalID, err)
+			internalError(w, err)
+			return
+		}
+		if ids[0] == 0 {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		resolved := make(map[string]string, len(vars))
+		for k, v := range vars {
+			resolved[k] = v
+		}
+		resolved["id"] = strconv.FormatInt(ids[0], 10)
+		h(w, mux.SetURLVars(r, resolved))
+	}
+}
+
+// resolveAccountIDs returns the numeric ids of refs. Names are looked up as
+// external ids first, then as aliases. Unknown names and wallets resolve to 0,
+// which no account has, so that the store reports them as not found like
+// unknown numeric ids.
+func (a *API) resolveAccountIDs(ctx context.Context, refs ...model.AccountID) ([]int64, error) {
+	ids := make([]int64, len(refs))
+	var external []string
+	for i, ref := range refs {
+		ids[i] = ref.ID
+		if ref.ExternalID != "" {
+			external = append(external, ref.ExternalID)
+		}
+	}
+	if len(external) > 0 {
+		found, err := a.store.AccountIDs(ctx, external)
+		if err != nil {
+			return nil, err
+		}
+		var aliases []string
+		for i, ref := range refs {
+			if ref.ExternalID != "" {
+				if ids[i] = found[ref.ExternalID]; ids[i] == 0 {
+					aliases = append(aliases, ref.ExternalID)
+				}
+			}
+		}
+		if len(aliases) > 0 {
+			if found, err = a.store.AliasAccountIDs(ctx, aliases); err != nil {
+				return nil, err
+			}
+			for i, ref := range refs {
+				if ref.ExternalID != "" && ids[i] == 0 {
+					ids[i] = found[ref.ExternalID]
+				}
+			}
+		}
+	}
+
+	// Wallets are looked up once their parents are resolved
+	var wallets []store.WalletRef
+	for i, ref := range refs {
+		if ref.Wallet != "" && ids[i] != 0 {
+			wallets = append(wallets, store.WalletRef{ParentID: ids[i], Wallet: ref.Wallet})
+		}
+	}
+	if len(wallets) == 0 {
+		return ids, nil
+	}
+	found, err := a.store.WalletIDs(ctx, wallets)
+	if err != nil {
+		return nil, err
+	}
+	for i, ref := range refs {
+		if ref.Wallet != "" {
+			ids[i] = found[store.WalletRef{ParentID: ids[i], Wallet: ref.Wallet}]
+		}
+	}
+	return ids, nil
+}
+
+// transferAccounts resolves the source and destination of a transfer request,
+// writing the error response if either is unknown. Transfers from an account
+// to itself are left to the distinctAccounts hook.
+func (a *API) transferAccounts(ctx context.Context, w http.ResponseWriter, src, dst model.AccountID) (srcID, dstID int64, ok bool) {
+	ids, err := a.resolveAccountIDs(ctx, src, dst)
+	if err != nil {
+		a.logger.Printf("resolve accounts failed: src=%s, dst=%s, error=%v", src, dst, err)
+		internalError(w, err)
+		return 0, 0, false
+	}
+	if ids[0] == 0 || ids[1] == 0 {
+		writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		return 0, 0, false
+	}
+	return ids[0], ids[1], true
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// externalIDStore knows account 100 as ext-100 and account 200 as ext-200
+func externalIDStore() *MockStore {
+	known := map[string]int64{"ext-100": 100, "ext-200": 200}
+	return &MockStore{
+		AccountIDsFunc: func(ctx context.Context, externalIDs []string) (map[string]int64, error) {
+			ids := map[string]int64{}
+			for _, ext := range externalIDs {
+				if id, ok := known[ext]; ok {
+					ids[ext] = id
+				}
+			}
+			return ids, nil
+		},
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			if accountID != 100 && accountID != 200 {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			return store.Account{ID: accountID, Currency: "USD", Status: store.AccountActive, ExternalID: "ext-" + strconv.FormatInt(accountID, 10)}, nil
+		},
+	}
+}
+
+// TestAccountPath_ExternalID tests that account routes accept external ids
+func TestAccountPath_ExternalID(t *testing.T) {
+	r := mux.NewRouter()
+	New(externalIDStore()).RegisterRoutes(r)
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/v1/accounts/ext-100", http.StatusOK},
+		{"/v1/accounts/100", http.StatusOK},
+		{"/v1/accounts/ext-999", http.StatusNotFound},
+		{"/v1/accounts/a!b", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.wantCode, w.Code)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.AccountID != 100 || resp.ExternalID != "ext-100" {
+			t.Fatalf("%s: expected account 100 known as ext-100, got %+v", tt.path, resp)
+		}
+	}
+}
+
+// TestCreateTransaction_ExternalIDs tests transfers between accounts given by external id
+func TestCreateTransaction_ExternalIDs(t *testing.T) {
+	mockStore := externalIDStore()
+	var gotSrc, gotDst int64
+	mockStore.TransferFunc = func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+		gotSrc, gotDst = srcID, dstID
+		return 42, nil
+	}
+	api := New(mockStore)
+
+	tests := []struct {
+		body     string
+		wantCode int
+	}{
+		{`{"source_account_id": "ext-100", "destination_account_id": 200, "amount": "5"}`, http.StatusOK},
+		{`{"source_account_id": "ext-100", "destination_account_id": "ext-999", "amount": "5"}`, http.StatusNotFound},
+		{`{"source_account_id": "ext-100", "destination_account_id": 100, "amount": "5"}`, http.StatusBadRequest},
+		{`{"source_account_id": "not a valid id", "destination_account_id": 200, "amount": "5"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(tt.body))))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.body, tt.wantCode, w.Code)
+		}
+	}
+	if gotSrc != 100 || gotDst != 200 {
+		t.Fatalf("expected transfer from 100 to 200, got %d to %d", gotSrc, gotDst)
+	}
+}
+
+// TestCreateAccount_ExternalID tests creating an account known only by its external id
+func TestCreateAccount_ExternalID(t *testing.T) {
+	var gotExternal string
+	var gotID int64
+	mockStore := &MockStore{
+		CreateExternalFunc: func(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error) {
+			gotExternal, gotID = externalID, accountID
+			return 1000, nil
+		},
+	}
+	api := New(mockStore)
+
+	tests := []struct {
+		body     string
+		wantCode int
+	}{
+		{`{"external_id": "8f14e45f-ceea-467f-a0e6-1b5e6c7e4c3b", "initial_balance": "10"}`, http.StatusCreated},
+		{`{"external_id": "12345", "initial_balance": "10"}`, http.StatusBadRequest},
+		{`{"initial_balance": "10"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		api.CreateAccount(w, httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader([]byte(tt.body))))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.body, tt.wantCode, w.Code)
+		}
+	}
+	if gotExternal != "8f14e45f-ceea-467f-a0e6-1b5e6c7e4c3b" || gotID != 0 {
+		t.Fatalf("expected an assigned id for the external id, got %q, %d", gotExternal, gotID)
+	}
+}
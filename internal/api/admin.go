@@ -0,0 +1,306 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toAPIKeyResponse maps a stored key to its JSON representation
+func toAPIKeyResponse(k store.APIKey) model.APIKeyResponse {
+	resp := model.APIKeyResponse{
+		ID:        k.ID,
+		Name:      k.Name,
+		Role:      k.Role,
+		TenantID:  k.Tenant,
+		CreatedAt: k.CreatedAt,
+		RevokedAt: k.RevokedAt,
+	}
+	for _, n := range k.Networks {
+		resp.AllowedNetworks = append(resp.AllowedNetworks, n.String())
+	}
+	return resp
+}
+
+// CreateAPIKey issues a new API key. The key is returned once and only its hash is stored.
+// Admins of a tenant can only issue keys of their own tenant.
+func (a *API) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateAPIKeyRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	var networks []netip.Prefix
+	for _, s := range req.AllowedNetworks {
+		n, err := auth.ParseNetwork(strings.TrimSpace(s))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "allowed_networks: "+err.Error())
+			return
+		}
+		networks = append(networks, n)
+	}
+	if tenant, ok := store.TenantFromContext(r.Context()); ok {
+		if req.TenantID != "" && req.TenantID != tenant {
+			writeError(w, http.StatusForbidden, model.ErrCodeForbidden, "keys can only be issued for the caller's tenant")
+			return
+		}
+		req.TenantID = tenant
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		a.logger.Printf("generate api key failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	k, err := a.store.CreateAPIKey(ctx, req.Name, string(role), req.TenantID, networks, auth.HashKey(key))
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("create api key failed: name=%s, error=%v", req.Name, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := toAPIKeyResponse(k)
+	resp.Key = key
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+// ListAPIKeys lists all API keys of the caller's tenant without their secrets
+func (a *API) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	keys, err := a.store.ListAPIKeys(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list api keys failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := make([]model.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		resp = append(resp, toAPIKeyResponse(k))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RevokeAPIKey revokes an API key by ID
+func (a *API) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid api key id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.RevokeAPIKey(ctx, id); err != nil {
+		if errors.Is(err, store.ErrAPIKeyNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAPIKeyNotFound, "api key not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("revoke api key failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SnapshotBalances takes a balance snapshot of every account on demand, in
+// addition to the periodic ones.
+func (a *API) SnapshotBalances(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	run, err := a.store.SnapshotBalances(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("snapshot balances failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model.BalanceSnapshotResponse{TakenAt: run.TakenAt, Accounts: run.Accounts})
+}
+
+// LatestReconciliation reports the latest reconciliation of balances against
+// the ledger and the accounts that disagreed
+func (a *API) LatestReconciliation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	run, err := a.store.LatestReconciliation(ctx)
+	if err != nil {
+		if errors.Is(err, store.ErrReconciliationNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeReconciliationNotFound, "no reconciliation has run yet")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get latest reconciliation failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.ReconciliationReport{
+		RunID:           run.ID,
+		CreatedAt:       run.CreatedAt,
+		AccountsChecked: run.AccountsChecked,
+		Discrepancies:   make([]model.ReconciliationDiscrepancy, 0, len(run.Discrepancies)),
+	}
+	for _, d := range run.Discrepancies {
+		resp.Discrepancies = append(resp.Discrepancies, model.ReconciliationDiscrepancy{
+			AccountID:     d.AccountID,
+			Balance:       model.DecimalString{Decimal: d.Balance},
+			LedgerBalance: model.DecimalString{Decimal: d.LedgerBalance},
+			HeldBalance:   model.DecimalString{Decimal: d.HeldBalance},
+			PendingHolds:  model.DecimalString{Decimal: d.PendingHolds},
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// TrialBalance reports the current totals of all accounts by currency and the
+// transfers in [?from=, ?to=) by currency and purpose code (RFC 3339; to
+// defaults to now and from to a week before to)
+func (a *API) TrialBalance(w http.ResponseWriter, r *http.Request) {
+	to := a.clock.Now()
+	from := time.Time{}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		s := r.URL.Query().Get(p.name)
+		if s == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, p.name+" must be an RFC 3339 timestamp", map[string]interface{}{"parameter": p.name})
+			return
+		}
+		*p.dst = t
+	}
+	if from.IsZero() {
+		from = to.Add(-model.DefaultReportPeriod)
+	}
+	if !from.Before(to) {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "from must be before to", map[string]interface{}{"parameter": "from"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	tb, err := a.store.TrialBalance(ctx, from, to)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("trial balance failed: from=%s, to=%s, error=%v", from.Format(time.RFC3339), to.Format(time.RFC3339), err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.TrialBalanceReport{
+		From:                 tb.From,
+		To:                   tb.To,
+		Currencies:           make([]model.CurrencyTotals, 0, len(tb.Currencies)),
+		TransfersByPurpose:   make([]model.PurposeTotals, 0, len(tb.Purposes)),
+		TransactionsByStatus: tb.StatusCounts,
+	}
+	for _, c := range tb.Currencies {
+		resp.Currencies = append(resp.Currencies, model.CurrencyTotals{
+			Currency:       c.Currency,
+			Accounts:       c.Accounts,
+			TotalBalance:   model.DecimalString{Decimal: c.Balance},
+			HeldBalance:    model.DecimalString{Decimal: c.HeldBalance},
+			TransferCount:  c.TransferCount,
+			TransferVolume: model.DecimalString{Decimal: c.TransferVolume},
+		})
+	}
+	for _, p := range tb.Purposes {
+		resp.TransfersByPurpose = append(resp.TransfersByPurpose, model.PurposeTotals{
+			PurposeCode:    p.PurposeCode,
+			Currency:       p.Currency,
+			TransferCount:  p.TransferCount,
+			TransferVolume: model.DecimalString{Decimal: p.TransferVolume},
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AdjustBalance credits or debits an account outside a transfer, recording the
+// reason and the calling admin in the ledger
+func (a *API) AdjustBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.AdjustmentRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	amount := req.Amount.Decimal
+	if req.Type == model.AdjustmentDebit {
+		amount = amount.Neg()
+	}
+	var adjustedBy string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		adjustedBy = p.Subject
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	t, err := a.store.AdjustBalance(ctx, id, amount, strings.TrimSpace(req.Reason), adjustedBy)
+	if err != nil {
+		status, resp, ok := transferError(err)
+		if !ok {
+			a.logger.Printf("adjust balance failed: accountID=%d, error=%v", id, err)
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+	writeJSON(w, http.StatusCreated, a.toTransaction(t))
+}
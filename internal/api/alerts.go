@@ -0,0 +1,218 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// alertStatuses are the accepted values of ?status= in GET /accounts/{id}/alerts
+var alertStatuses = map[string]bool{
+	store.AlertActive:   true,
+	store.AlertResolved: true,
+}
+
+// toAlertResponse maps a stored alert to its JSON representation
+func (a *API) toAlertResponse(al store.Alert) model.AlertResponse {
+	return model.AlertResponse{
+		AlertID:             al.ID,
+		AccountID:           al.AccountID,
+		Kind:                al.Kind,
+		Status:              al.Status,
+		Threshold:           model.DecimalString{Decimal: al.Threshold},
+		Value:               model.DecimalString{Decimal: al.Value},
+		TransactionPublicID: publicID(al.TransactionUUID),
+		TransactionID:       a.serialID(al.TransactionID),
+		CreatedAt:           al.CreatedAt,
+		ResolvedAt:          al.ResolvedAt,
+	}
+}
+
+// toAlertThresholdsResponse maps stored alert thresholds to their JSON representation
+func toAlertThresholdsResponse(t store.AlertThresholds) model.AlertThresholdsResponse {
+	resp := model.AlertThresholdsResponse{AccountID: t.AccountID}
+	if t.LowBalance.Valid {
+		resp.LowBalance = &model.DecimalString{Decimal: t.LowBalance.Decimal}
+	}
+	if t.LargeTransfer.Valid {
+		resp.LargeTransfer = &model.DecimalString{Decimal: t.LargeTransfer.Decimal}
+	}
+	if !t.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &t.UpdatedAt
+	}
+	return resp
+}
+
+// GetAlertThresholds returns the alert thresholds of the account
+func (a *API) GetAlertThresholds(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	t, err := a.store.AlertThresholds(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get alert thresholds failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAlertThresholdsResponse(t))
+}
+
+// SetAlertThresholds replaces the alert thresholds of the account
+func (a *API) SetAlertThresholds(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.SetAlertThresholdsRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	t := store.AlertThresholds{AccountID: id}
+	if req.LowBalance != nil {
+		t.LowBalance = decimal.NewNullDecimal(req.LowBalance.Decimal)
+	}
+	if req.LargeTransfer != nil {
+		t.LargeTransfer = decimal.NewNullDecimal(req.LargeTransfer.Decimal)
+	}
+	t, err = a.store.SetAlertThresholds(ctx, t)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("set alert thresholds failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAlertThresholdsResponse(t))
+}
+
+// ListAlerts lists the alerts of the account with the status query
+// parameter, active by default, newest first
+func (a *API) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch {
+	case status == "":
+		status = store.AlertActive
+	case status == "all":
+		status = ""
+	case !alertStatuses[status]:
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "status must be active, resolved or all", map[string]interface{}{"parameter": "status"})
+		return
+	}
+	var cursor int64
+	if c := q.Get("cursor"); c != "" {
+		cursor, err = strconv.ParseInt(c, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid cursor", map[string]interface{}{"parameter": "cursor"})
+			return
+		}
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	alerts, next, err := a.store.ListAlerts(ctx, id, status, cursor, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list alerts failed: accountID=%d, status=%s, error=%v", id, status, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.AlertListResponse{Alerts: make([]model.AlertResponse, 0, len(alerts))}
+	for _, al := range alerts {
+		resp.Alerts = append(resp.Alerts, a.toAlertResponse(al))
+	}
+	if next != 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ResolveAlert resolves the {alert_id} of the path, an active alert of the account
+func (a *API) ResolveAlert(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	alertID, err := strconv.ParseInt(vars["alert_id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid alert id", map[string]interface{}{"parameter": "alert_id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	al, err := a.store.ResolveAlert(ctx, id, alertID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAlertNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAlertNotFound, "alert not found")
+			return
+		case errors.Is(err, store.ErrAlertResolved):
+			writeError(w, http.StatusConflict, model.ErrCodeAlertResolved, "alert already resolved")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("resolve alert failed: accountID=%d, alertID=%d, error=%v", id, alertID, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toAlertResponse(al))
+}
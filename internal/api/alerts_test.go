@@ -0,0 +1,145 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestAlertThresholds tests setting and reading the alert thresholds of an account
+func TestAlertThresholds(t *testing.T) {
+	var stored store.AlertThresholds
+	mockStore := &MockStore{
+		SetThresholdsFunc: func(ctx context.Context, th store.AlertThresholds) (store.AlertThresholds, error) {
+			if th.AccountID == 404 {
+				return store.AlertThresholds{}, store.ErrAccountNotFound
+			}
+			stored = th
+			return th, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1/alert-thresholds", `{"low_balance": "100", "large_transfer": "10000"}`, http.StatusOK},
+		{"/v1/accounts/1/alert-thresholds", `{"low_balance": "-1"}`, http.StatusBadRequest},
+		{"/v1/accounts/1/alert-thresholds", `{"large_transfer": "0"}`, http.StatusBadRequest},
+		{"/v1/accounts/404/alert-thresholds", `{}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path, bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", c.path, c.body, c.want, w.Code, w.Body.String())
+		}
+	}
+	if !stored.LowBalance.Valid || !stored.LowBalance.Decimal.Equal(decimal.NewFromInt(100)) ||
+		!stored.LargeTransfer.Valid || !stored.LargeTransfer.Decimal.Equal(decimal.NewFromInt(10000)) {
+		t.Fatalf("unexpected thresholds stored: %+v", stored)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/1/alert-thresholds", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp model.AlertThresholdsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.AccountID != 1 || resp.LowBalance != nil || resp.LargeTransfer != nil || resp.UpdatedAt != nil {
+		t.Fatalf("expected no thresholds set, got %+v", resp)
+	}
+}
+
+// TestListAlerts tests listing the alerts of an account by status
+func TestListAlerts(t *testing.T) {
+	var gotStatus string
+	mockStore := &MockStore{
+		ListAlertsFunc: func(ctx context.Context, accountID int64, status string, cursor int64, limit int) ([]store.Alert, int64, error) {
+			if accountID == 404 {
+				return nil, 0, store.ErrAccountNotFound
+			}
+			gotStatus = status
+			return []store.Alert{{ID: 3, AccountID: accountID, Kind: store.AlertLowBalance, Status: store.AlertActive,
+				Threshold: decimal.NewFromInt(100), Value: decimal.RequireFromString("42.5"), TransactionID: 9}}, 3, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/1/alerts", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp model.AlertListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if gotStatus != store.AlertActive {
+		t.Fatalf("expected active alerts by default, got status %q", gotStatus)
+	}
+	if len(resp.Alerts) != 1 || resp.Alerts[0].Kind != store.AlertLowBalance || resp.Alerts[0].Value.String() != "42.5" || resp.NextCursor != "3" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/accounts/1/alerts?status=all":    http.StatusOK,
+		"/v1/accounts/1/alerts?status=closed": http.StatusBadRequest,
+		"/v1/accounts/1/alerts?cursor=-1":     http.StatusBadRequest,
+		"/v1/accounts/404/alerts":             http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+	if gotStatus != "" {
+		t.Fatalf("expected status=all to list any status, got %q", gotStatus)
+	}
+}
+
+// TestResolveAlert tests resolving alerts and their errors
+func TestResolveAlert(t *testing.T) {
+	mockStore := &MockStore{
+		ResolveAlertFunc: func(ctx context.Context, accountID, alertID int64) (store.Alert, error) {
+			switch alertID {
+			case 4:
+				return store.Alert{}, store.ErrAlertNotFound
+			case 5:
+				return store.Alert{}, store.ErrAlertResolved
+			}
+			return store.Alert{ID: alertID, AccountID: accountID, Status: store.AlertResolved}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	for path, want := range map[string]int{
+		"/v1/accounts/1/alerts/3/resolve":   http.StatusOK,
+		"/v1/accounts/1/alerts/4/resolve":   http.StatusNotFound,
+		"/v1/accounts/1/alerts/5/resolve":   http.StatusConflict,
+		"/v1/accounts/1/alerts/abc/resolve": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+		if w.Code != want {
+			t.Fatalf("POST %s: expected status %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
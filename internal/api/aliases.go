@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toAccountAlias maps a stored alias to its JSON representation
+func toAccountAlias(al store.AccountAlias) model.AccountAlias {
+	return model.AccountAlias{Alias: al.Alias, AccountID: al.AccountID, CreatedAt: al.CreatedAt}
+}
+
+// AddAccountAlias registers the {alias} of the path for the account. It
+// answers 201 if registered and 200 if the account already had it.
+func (a *API) AddAccountAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	alias := vars["alias"]
+	if err := model.ValidateAlias(alias); err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error(), map[string]interface{}{"parameter": "alias"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	al, created, err := a.store.AddAccountAlias(ctx, id, alias)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		case errors.Is(err, store.ErrAliasTaken):
+			writeError(w, http.StatusConflict, model.ErrCodeAliasTaken, "alias is taken")
+		default:
+			a.logger.Printf("add account alias failed: accountID=%d, alias=%s, error=%v", id, alias, err)
+			internalError(w, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, toAccountAlias(al))
+}
+
+// RemoveAccountAlias unregisters the {alias} of the path from the account
+func (a *API) RemoveAccountAlias(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.RemoveAccountAlias(ctx, id, vars["alias"]); err != nil {
+		if errors.Is(err, store.ErrAliasNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAliasNotFound, "alias not found")
+			return
+		}
+		a.logger.Printf("remove account alias failed: accountID=%d, alias=%s, error=%v", id, vars["alias"], err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAccountAliases returns the aliases of the account in alphabetical order
+func (a *API) ListAccountAliases(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	aliases, err := a.store.AccountAliases(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("list account aliases failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.AccountAliasList{Aliases: make([]model.AccountAlias, len(aliases))}
+	for i, al := range aliases {
+		resp.Aliases[i] = toAccountAlias(al)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
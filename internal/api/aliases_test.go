@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// aliasStore is externalIDStore with account 200 also known as ops-float-usd
+func aliasStore() *MockStore {
+	m := externalIDStore()
+	m.AliasIDsFunc = func(ctx context.Context, aliases []string) (map[string]int64, error) {
+		ids := map[string]int64{}
+		for _, al := range aliases {
+			if al == "ops-float-usd" {
+				ids[al] = 200
+			}
+		}
+		return ids, nil
+	}
+	return m
+}
+
+// TestAccountPath_Alias tests that account routes and transfers accept aliases
+func TestAccountPath_Alias(t *testing.T) {
+	mockStore := aliasStore()
+	var gotSrc, gotDst int64
+	mockStore.TransferFunc = func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+		gotSrc, gotDst = srcID, dstID
+		return 42, nil
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/ops-float-usd", nil))
+	var resp model.AccountResponse
+	if w.Code != http.StatusOK || json.NewDecoder(w.Body).Decode(&resp) != nil || resp.AccountID != 200 {
+		t.Fatalf("expected account 200, got %d: %+v", w.Code, resp)
+	}
+
+	w = httptest.NewRecorder()
+	body := `{"source_account_id": "ops-float-usd", "destination_account_id": "ext-100", "amount": "5"}`
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusOK || gotSrc != 200 || gotDst != 100 {
+		t.Fatalf("expected a transfer from 200 to 100, got %d: %d to %d", w.Code, gotSrc, gotDst)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/ops-float-eur", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d for an unknown alias, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestAddAccountAlias tests registering, re-registering and removing aliases
+func TestAddAccountAlias(t *testing.T) {
+	aliases := map[string]int64{"treasury": 200}
+	mockStore := aliasStore()
+	mockStore.AddAliasFunc = func(ctx context.Context, accountID int64, alias string) (store.AccountAlias, bool, error) {
+		if owner, ok := aliases[alias]; ok {
+			if owner != accountID {
+				return store.AccountAlias{}, false, store.ErrAliasTaken
+			}
+			return store.AccountAlias{Alias: alias, AccountID: accountID}, false, nil
+		}
+		aliases[alias] = accountID
+		return store.AccountAlias{Alias: alias, AccountID: accountID}, true, nil
+	}
+	mockStore.RemoveAliasFunc = func(ctx context.Context, accountID int64, alias string) error {
+		if aliases[alias] != accountID {
+			return store.ErrAliasNotFound
+		}
+		delete(aliases, alias)
+		return nil
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	tests := []struct {
+		method, path string
+		wantCode     int
+	}{
+		{http.MethodPut, "/v1/accounts/100/aliases/ops-float-usd-2", http.StatusCreated},
+		{http.MethodPut, "/v1/accounts/ext-100/aliases/ops-float-usd-2", http.StatusOK},
+		{http.MethodPut, "/v1/accounts/100/aliases/treasury", http.StatusConflict},
+		{http.MethodPut, "/v1/accounts/100/aliases/12345", http.StatusBadRequest},
+		{http.MethodDelete, "/v1/accounts/100/aliases/treasury", http.StatusNotFound},
+		{http.MethodDelete, "/v1/accounts/100/aliases/ops-float-usd-2", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", tt.method, tt.path, tt.wantCode, w.Code, w.Body.String())
+		}
+	}
+	if _, ok := aliases["ops-float-usd-2"]; ok {
+		t.Fatalf("expected the alias removed, got %v", aliases)
+	}
+}
+
+// TestListAccountAliases tests that an account without aliases lists none
+func TestListAccountAliases(t *testing.T) {
+	r := mux.NewRouter()
+	New(aliasStore()).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100/aliases", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Body.String(); got != `{"aliases":[]}`+"\n" {
+		t.Fatalf("expected an empty list, got %s", got)
+	}
+}
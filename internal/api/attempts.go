@@ -0,0 +1,266 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// maxAttemptBody caps the part of the request and response bodies read to
+// record a rejected attempt
+const maxAttemptBody = 4 << 10
+
+// AttemptLog keeps the transfer requests the API rejects, e.g. *store.Store
+type AttemptLog interface {
+	RecordTransferAttempt(ctx context.Context, a store.TransferAttempt) (store.TransferAttempt, error)
+	ListTransferAttempts(ctx context.Context, f store.TransferAttemptFilter, cursor int64, limit int) ([]store.TransferAttempt, int64, error)
+}
+
+// WithAttemptLog records the transfers, batches, splits, jobs, holds and
+// scheduled transfers the API rejects with a 4xx status to l, with the caller, the error
+// and the accounts, amount and reference sent, and enables GET
+// /admin/transfer-attempts to list them. Rejections by the store, such as
+// insufficient funds, are recorded too, although they also leave a failed
+// transaction.
+func WithAttemptLog(l AttemptLog) Option {
+	return func(a *API) {
+		a.attempts = l
+	}
+}
+
+// sentTransfer is a transfer of a rejected request, with its fields as sent
+type sentTransfer struct {
+	SourceAccountID      json.RawMessage `json:"source_account_id"`
+	DestinationAccountID json.RawMessage `json:"destination_account_id"`
+	Amount               json.RawMessage `json:"amount"`
+	Reference            json.RawMessage `json:"reference"`
+}
+
+// attempt returns t as an attempt of kind
+func (t sentTransfer) attempt(kind string) store.TransferAttempt {
+	return store.TransferAttempt{
+		Kind:               kind,
+		SourceAccount:      jsonText(t.SourceAccountID),
+		DestinationAccount: jsonText(t.DestinationAccountID),
+		Amount:             jsonText(t.Amount),
+		Reference:          jsonText(t.Reference),
+	}
+}
+
+// attemptSummary returns the transfers of a rejected request, as attempts of
+// kind, from the start of its body and the details of the error it got.
+type attemptSummary func(r *http.Request, kind string, body []byte, details map[string]interface{}) []store.TransferAttempt
+
+// summarizeTransfer summarizes a request for a single transfer. Bodies that do
+// not decode, e.g. invalid JSON, leave the fields empty.
+func summarizeTransfer(r *http.Request, kind string, body []byte, details map[string]interface{}) []store.TransferAttempt {
+	var sent sentTransfer
+	_ = json.Unmarshal(body, &sent)
+	return []store.TransferAttempt{sent.attempt(kind)}
+}
+
+// summarizeBatch summarizes a POST /transactions/batch request by the
+// transfer at fault, or by all the transfers sent when the error is not about
+// a single one.
+func summarizeBatch(r *http.Request, kind string, body []byte, details map[string]interface{}) []store.TransferAttempt {
+	var sent struct {
+		Transfers []sentTransfer `json:"transfers"`
+	}
+	_ = json.Unmarshal(body, &sent)
+	return summarizeItems(kind, sent.Transfers, details["index"], 0)
+}
+
+// summarizeSplit summarizes a POST /transactions/split request by the leg at
+// fault, or by all the legs sent when the error is not about a single one.
+func summarizeSplit(r *http.Request, kind string, body []byte, details map[string]interface{}) []store.TransferAttempt {
+	var sent struct {
+		sentTransfer
+		Legs []struct {
+			AccountID json.RawMessage `json:"account_id"`
+			Amount    json.RawMessage `json:"amount"`
+		} `json:"legs"`
+	}
+	_ = json.Unmarshal(body, &sent)
+	legs := make([]sentTransfer, len(sent.Legs))
+	for i, leg := range sent.Legs {
+		legs[i] = sent.sentTransfer
+		legs[i].Amount = leg.Amount
+		if jsonText(sent.SourceAccountID) == "" || string(sent.SourceAccountID) == "null" {
+			legs[i].SourceAccountID = leg.AccountID
+		} else {
+			legs[i].DestinationAccountID = leg.AccountID
+		}
+	}
+	return summarizeItems(kind, legs, details["index"], 0)
+}
+
+// summarizeJob summarizes a POST /jobs/transfers upload by the row at fault,
+// or by all the rows read when the error is not about a single one. Only the
+// rows within the captured start of the body are read.
+func summarizeJob(r *http.Request, kind string, body []byte, details map[string]interface{}) []store.TransferAttempt {
+	var rows []sentTransfer
+	_, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if boundary := params["boundary"]; boundary != "" {
+		mr := multipart.NewReader(bytes.NewReader(body), boundary)
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			if part.FormName() != "file" {
+				continue
+			}
+			cr := csv.NewReader(part)
+			cr.FieldsPerRecord = -1
+			cr.TrimLeadingSpace = true
+			// The header row is skipped, keeping rows 1-based
+			for i := 0; ; i++ {
+				rec, err := cr.Read()
+				if err != nil {
+					break
+				}
+				if i == 0 {
+					continue
+				}
+				var row sentTransfer
+				for j, field := range []*json.RawMessage{&row.SourceAccountID, &row.DestinationAccountID, &row.Amount} {
+					if j < len(rec) {
+						*field = json.RawMessage(strconv.Quote(rec[j]))
+					}
+				}
+				rows = append(rows, row)
+			}
+			break
+		}
+	}
+	return summarizeItems(kind, rows, details["row"], 1)
+}
+
+// summarizeItems returns the item at pos, counted from base, if pos is a
+// position among items, or else all of them, and a single empty attempt when
+// there are none.
+func summarizeItems(kind string, items []sentTransfer, pos interface{}, base int) []store.TransferAttempt {
+	if p, ok := pos.(float64); ok && int(p)-base >= 0 && int(p)-base < len(items) {
+		return []store.TransferAttempt{items[int(p)-base].attempt(kind)}
+	}
+	if len(items) == 0 {
+		return []store.TransferAttempt{{Kind: kind}}
+	}
+	attempts := make([]store.TransferAttempt, len(items))
+	for i, item := range items {
+		attempts[i] = item.attempt(kind)
+	}
+	return attempts
+}
+
+// logRejections records the requests next answers with a 4xx status as
+// attempts of kind, one per transfer summary finds in the request, if an
+// AttemptLog is set.
+func (a *API) logRejections(kind string, summary attemptSummary, next http.HandlerFunc) http.HandlerFunc {
+	if a.attempts == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqBody := &captureBuffer{max: maxAttemptBody}
+		if r.Body != nil {
+			r.Body = readCloser{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+		cw := &captureWriter{ResponseWriter: w, status: http.StatusOK, body: captureBuffer{max: maxAttemptBody}}
+		next(cw, r)
+		if cw.status < 400 || cw.status > 499 {
+			return
+		}
+
+		var resp model.ErrorResponse
+		_ = json.Unmarshal(cw.body.data, &resp)
+		p, _ := auth.FromContext(r.Context())
+
+		// The request context may be done once the response is written
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), a.reqTimeout)
+		defer cancel()
+		for _, attempt := range summary(r, kind, reqBody.data, resp.Details) {
+			attempt.Status = cw.status
+			attempt.Code = string(resp.Code)
+			attempt.Message = resp.Message
+			attempt.Subject = p.Subject
+			if _, err := a.attempts.RecordTransferAttempt(ctx, attempt); err != nil {
+				a.logger.Printf("record transfer attempt failed: kind=%s, status=%d, error=%v", kind, cw.status, err)
+				return
+			}
+		}
+	}
+}
+
+// jsonText returns a JSON string as its text, and other JSON values as they were sent.
+func jsonText(v json.RawMessage) string {
+	var s string
+	if json.Unmarshal(v, &s) == nil {
+		return s
+	}
+	return string(v)
+}
+
+// ListTransferAttempts returns the rejected transfer requests, newest first,
+// filtered by the subject, account and code query parameters.
+func (a *API) ListTransferAttempts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var cursor int64
+	if c := q.Get("cursor"); c != "" {
+		var err error
+		cursor, err = strconv.ParseInt(c, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid cursor", map[string]interface{}{"parameter": "cursor"})
+			return
+		}
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+	f := store.TransferAttemptFilter{Subject: q.Get("subject"), Account: q.Get("account"), Code: q.Get("code")}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	attempts, next, err := a.attempts.ListTransferAttempts(ctx, f, cursor, limit)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list transfer attempts failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.TransferAttemptListResponse{Attempts: make([]model.TransferAttemptResponse, 0, len(attempts))}
+	for _, at := range attempts {
+		resp.Attempts = append(resp.Attempts, model.TransferAttemptResponse{
+			ID:                 at.ID,
+			Kind:               at.Kind,
+			SourceAccount:      at.SourceAccount,
+			DestinationAccount: at.DestinationAccount,
+			Amount:             at.Amount,
+			Reference:          at.Reference,
+			Status:             at.Status,
+			Code:               model.ErrorCode(at.Code),
+			Message:            at.Message,
+			Subject:            at.Subject,
+			Tenant:             at.Tenant,
+			CreatedAt:          at.CreatedAt,
+		})
+	}
+	if next != 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
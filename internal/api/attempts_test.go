@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// memoryAttemptLog keeps the recorded attempts, newest last
+type memoryAttemptLog struct {
+	mu       sync.Mutex
+	attempts []store.TransferAttempt
+}
+
+func (l *memoryAttemptLog) RecordTransferAttempt(ctx context.Context, a store.TransferAttempt) (store.TransferAttempt, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a.ID = int64(len(l.attempts) + 1)
+	a.Tenant, _ = store.TenantFromContext(ctx)
+	l.attempts = append(l.attempts, a)
+	return a, nil
+}
+
+func (l *memoryAttemptLog) ListTransferAttempts(ctx context.Context, f store.TransferAttemptFilter, cursor int64, limit int) ([]store.TransferAttempt, int64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []store.TransferAttempt
+	for i := len(l.attempts) - 1; i >= 0 && len(out) < limit; i-- {
+		if a := l.attempts[i]; f.Code == "" || a.Code == f.Code {
+			out = append(out, a)
+		}
+	}
+	return out, 0, nil
+}
+
+// TestLogRejections tests that rejected transfer requests are recorded with
+// the caller and the payload as sent, and successful ones are not
+func TestLogRejections(t *testing.T) {
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,payments:service:service-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	attempts := &memoryAttemptLog{}
+	r := mux.NewRouter()
+	New(&MockStore{}, WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil)), WithAttemptLog(attempts)).RegisterRoutes(r)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, "service-key")
+		if strings.HasPrefix(path, "/v1/admin/") {
+			req.Header.Set(auth.APIKeyHeader, "admin-key")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/v1/transactions", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10"}`); w.Code != http.StatusOK {
+		t.Fatalf("expected the transfer to succeed, got %d: %s", w.Code, w.Body)
+	}
+	if w := serve(http.MethodPost, "/v1/transactions", `{"source_account_id": 1, "destination_account_id": 1, "amount": "10", "reference": "inv-7"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected the same-account transfer rejected, got %d", w.Code)
+	}
+	serve(http.MethodPost, "/v1/holds", `{"source_account_id": "cust:9", "destination_account_id": 2, "amount": "ten"}`)
+
+	w := serve(http.MethodGet, "/v1/admin/transfer-attempts", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var resp model.TransferAttemptListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %+v", resp.Attempts)
+	}
+	hold, same := resp.Attempts[0], resp.Attempts[1]
+	if same.Kind != TransferKindSingle || same.SourceAccount != "1" || same.DestinationAccount != "1" || same.Amount != "10" ||
+		same.Reference != "inv-7" || same.Status != http.StatusBadRequest || same.Code != model.ErrCodeValidationFailed || same.Subject != "payments" {
+		t.Errorf("unexpected attempt %+v", same)
+	}
+	if hold.Kind != TransferKindHold || hold.SourceAccount != "cust:9" || hold.Amount != "ten" || hold.Code != model.ErrCodeInvalidJSON {
+		t.Errorf("unexpected attempt %+v", hold)
+	}
+}
+
+// TestLogRejections_Batches tests that rejected batches, splits and jobs are
+// recorded by the transfer at fault, or by every transfer sent when none is
+func TestLogRejections_Batches(t *testing.T) {
+	attempts := &memoryAttemptLog{}
+	mockStore := &MockStore{
+		TransferBatchFunc: func(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+			return nil, &store.BatchItemError{Index: 1, Err: store.ErrInsufficientFunds}
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore, WithAttemptLog(attempts)).RegisterRoutes(r)
+	serve := func(req *http.Request) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code < 400 || w.Code > 499 {
+			t.Fatalf("expected %s to be rejected, got %d: %s", req.URL.Path, w.Code, w.Body)
+		}
+	}
+
+	serve(httptest.NewRequest(http.MethodPost, "/v1/transactions/batch", strings.NewReader(`{"mode": "atomic", "transfers": [
+		{"source_account_id": 1, "destination_account_id": 2, "amount": "1"},
+		{"source_account_id": 3, "destination_account_id": 4, "amount": "900", "reference": "inv-8"}]}`)))
+	serve(httptest.NewRequest(http.MethodPost, "/v1/transactions/batch", strings.NewReader(`{"mode": "atomic", "transfers": [
+		{"source_account_id": 5, "destination_account_id": 6, "amount": "1"},
+		{"source_account_id": 7, "destination_account_id": 7, "amount": "2"}]}`)))
+	serve(httptest.NewRequest(http.MethodPost, "/v1/transactions/split", strings.NewReader(`{"source_account_id": 8,
+		"legs": [{"account_id": 9, "amount": "1"}, {"account_id": 8, "amount": "2"}], "reference": "inv-9"}`)))
+	req := newJobUpload(t, "source_account_id,destination_account_id,amount\n1,2,10\n3,4,ten\n")
+	req.URL.Path = "/v1/jobs/transfers"
+	serve(req)
+
+	want := []store.TransferAttempt{
+		{Kind: TransferKindBatch, SourceAccount: "3", DestinationAccount: "4", Amount: "900", Reference: "inv-8", Code: string(model.ErrCodeInsufficientFunds)},
+		{Kind: TransferKindBatch, SourceAccount: "5", DestinationAccount: "6", Amount: "1", Code: string(model.ErrCodeValidationFailed)},
+		{Kind: TransferKindBatch, SourceAccount: "7", DestinationAccount: "7", Amount: "2", Code: string(model.ErrCodeValidationFailed)},
+		{Kind: TransferKindSplit, SourceAccount: "8", DestinationAccount: "9", Amount: "1", Reference: "inv-9", Code: string(model.ErrCodeValidationFailed)},
+		{Kind: TransferKindSplit, SourceAccount: "8", DestinationAccount: "8", Amount: "2", Reference: "inv-9", Code: string(model.ErrCodeValidationFailed)},
+		{Kind: TransferKindJob, SourceAccount: "3", DestinationAccount: "4", Amount: "ten", Code: string(model.ErrCodeValidationFailed)},
+	}
+	if len(attempts.attempts) != len(want) {
+		t.Fatalf("expected %d attempts, got %+v", len(want), attempts.attempts)
+	}
+	for i, a := range attempts.attempts {
+		w := want[i]
+		if a.Kind != w.Kind || a.SourceAccount != w.SourceAccount || a.DestinationAccount != w.DestinationAccount ||
+			a.Amount != w.Amount || a.Reference != w.Reference || a.Code != w.Code {
+			t.Errorf("attempt %d: expected %+v, got %+v", i, w, a)
+		}
+	}
+}
+
+// TestLogRejectionsDisabled tests that the attempts are not listed unless recorded
+func TestLogRejectionsDisabled(t *testing.T) {
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/transfer-attempts", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
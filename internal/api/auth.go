@@ -0,0 +1,72 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// authorize wraps h so that it only runs for callers holding at least role.
+// The authenticated principal is stored in the request context, and store
+// calls made with it are scoped to the principal's tenant, if any, and unless
+// the principal is an admin, to the accounts it owns. Principals restricted to
+// networks are refused from others. Requests are also held to the rate
+// limit of their address before credentials are checked, and then to that of
+// the principal (see RateLimiter).
+// When no authenticator is configured every request is allowed.
+func (a *API) authorize(role auth.Role, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Credentials may cost a lookup or reading the body to check, so
+		// floods of them are shed by address first
+		if !a.limiter.admit(w, addressKey(r)) {
+			return
+		}
+		if a.authn == nil {
+			h(w, r)
+			return
+		}
+
+		p, err := a.authn.Authenticate(r)
+		if err != nil {
+			if errors.Is(err, auth.ErrUnauthenticated) {
+				writeError(w, http.StatusUnauthorized, model.ErrCodeUnauthenticated, "authentication required")
+				return
+			}
+			a.logger.Printf("authenticate failed: path=%s, error=%v", r.URL.Path, err)
+			internalError(w, err)
+			return
+		}
+		if !a.limiter.admit(w, principalKey(p)) {
+			return
+		}
+		if addr, ok := ClientAddr(r.Context()); len(p.Networks) > 0 && (!ok || !p.AllowsAddr(addr)) {
+			writeError(w, http.StatusForbidden, model.ErrCodeNetworkNotAllowed, "the caller's credentials are not allowed from this network")
+			return
+		}
+		if !p.Role.Allows(role) {
+			writeError(w, http.StatusForbidden, model.ErrCodeForbidden, "role "+string(p.Role)+" may not access this endpoint")
+			return
+		}
+
+		ctx := store.WithTenant(auth.WithPrincipal(r.Context(), p), p.Tenant)
+		if p.Role != auth.RoleAdmin {
+			ctx = store.WithOwner(ctx, p.Subject)
+		}
+		h(w, r.WithContext(ctx))
+	}
+}
+
+// deploymentWide wraps h, an endpoint covering every tenant, so that callers
+// scoped to a tenant are refused.
+func (a *API) deploymentWide(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := store.TenantFromContext(r.Context()); ok {
+			writeError(w, http.StatusForbidden, model.ErrCodeForbidden, "this endpoint is not available to callers scoped to a tenant")
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,461 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// newAuthRouter returns a router over mockStore with admin, service and readonly
+// static keys, and an admin key of tenant unit-a
+func newAuthRouter(t *testing.T, mockStore *MockStore) *mux.Router {
+	t.Helper()
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,payments:service:service-key,dashboard:readonly:ro-key,unit-a/teller:admin:tenant-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	r := mux.NewRouter()
+	New(mockStore, WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil))).RegisterRoutes(r)
+	return r
+}
+
+// TestAuthorize_RoleMatrix tests which roles may reach which endpoints
+func TestAuthorize_RoleMatrix(t *testing.T) {
+	r := newAuthRouter(t, &MockStore{})
+
+	cases := []struct {
+		method, path, key string
+		body              string
+		want              int
+	}{
+		{http.MethodGet, "/v1/accounts/1", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/accounts/1", "wrong-key", "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/accounts/1", "ro-key", "", http.StatusOK},
+		{http.MethodPost, "/v1/transactions", "ro-key", `{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`, http.StatusForbidden},
+		{http.MethodPost, "/v1/transactions", "service-key", `{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`, http.StatusOK},
+		{http.MethodPost, "/v1/accounts", "admin-key", `{"account_id": 1, "initial_balance": "1"}`, http.StatusCreated},
+		{http.MethodGet, "/v1/admin/apikeys", "service-key", "", http.StatusForbidden},
+		{http.MethodGet, "/v1/admin/apikeys", "admin-key", "", http.StatusOK},
+		{http.MethodPost, "/v1/admin/balance-snapshots", "service-key", "", http.StatusForbidden},
+		{http.MethodPost, "/v1/admin/balance-snapshots", "admin-key", "", http.StatusCreated},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, bytes.NewReader([]byte(c.body)))
+		if c.key != "" {
+			req.Header.Set(auth.APIKeyHeader, c.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want {
+			t.Fatalf("%s %s with key %q: expected status %d, got %d", c.method, c.path, c.key, c.want, w.Code)
+		}
+	}
+}
+
+// TestAuthorize_PrincipalInContext tests that handlers see the authenticated caller
+func TestAuthorize_PrincipalInContext(t *testing.T) {
+	var got auth.Principal
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			got, _ = auth.FromContext(ctx)
+			return store.Account{ID: accountID}, nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+	req.Header.Set(auth.APIKeyHeader, "ro-key")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.Subject != "dashboard" || got.Role != auth.RoleReadonly {
+		t.Fatalf("unexpected principal: %+v", got)
+	}
+}
+
+// TestAuthorize_SignedRequest tests that a request signed with HMAC reaches the
+// handler with its body intact
+func TestAuthorize_SignedRequest(t *testing.T) {
+	var got auth.Principal
+	var gotAmount decimal.Decimal
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			got, _ = auth.FromContext(ctx)
+			gotAmount = amount
+			return 1, nil
+		},
+	}
+	clients, err := auth.ParseHMACClients("ledger:service:secret")
+	if err != nil {
+		t.Fatalf("ParseHMACClients failed: %v", err)
+	}
+	r := mux.NewRouter()
+	New(mockStore, WithAuthenticator(auth.NewHMACAuthenticator(auth.HMACConfig{Clients: clients}, nil))).RegisterRoutes(r)
+
+	body := `{"source_account_id": 1, "destination_account_id": 2, "amount": "12.50"}`
+	send := func(secret string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(body))
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(auth.ClientIDHeader, "ledger")
+		req.Header.Set(auth.TimestampHeader, ts)
+		req.Header.Set(auth.SignatureHeader, auth.SignRequest([]byte(secret), ts, http.MethodPost, "/v1/transactions", []byte(body)))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := send("secret"); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if got.Subject != "ledger" || got.Role != auth.RoleService || !gotAmount.Equal(decimal.RequireFromString("12.50")) {
+		t.Fatalf("unexpected principal %+v or amount %s", got, gotAmount)
+	}
+	if code := send("guess"); code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d for a wrong signature, got %d", http.StatusUnauthorized, code)
+	}
+}
+
+// TestAuthorize_TenantScope tests that store calls are scoped to the caller's tenant
+func TestAuthorize_TenantScope(t *testing.T) {
+	var tenant string
+	var scoped bool
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			tenant, scoped = store.TenantFromContext(ctx)
+			return store.Account{ID: accountID}, nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+
+	for _, c := range []struct {
+		key        string
+		wantScoped bool
+		wantTenant string
+	}{
+		{"tenant-key", true, "unit-a"},
+		{"admin-key", false, ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		req.Header.Set(auth.APIKeyHeader, c.key)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+
+		if scoped != c.wantScoped || tenant != c.wantTenant {
+			t.Fatalf("key %q: expected scope %q (%v), got %q (%v)", c.key, c.wantTenant, c.wantScoped, tenant, scoped)
+		}
+	}
+
+	// Endpoints covering every tenant are refused to tenant admins
+	for _, path := range []string{"/v1/admin/reconciliation/latest", "/v1/admin/reports/trial-balance"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(auth.APIKeyHeader, "tenant-key")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s: expected status %d, got %d", path, http.StatusForbidden, w.Code)
+		}
+	}
+}
+
+// TestAuthorize_OwnerScope tests that non-admin callers are restricted to the
+// accounts they own, and only create accounts for themselves
+func TestAuthorize_OwnerScope(t *testing.T) {
+	var owner string
+	var scoped bool
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			owner, scoped = store.OwnerFromContext(ctx)
+			return store.Account{ID: accountID}, nil
+		},
+		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+			owner, scoped = store.OwnerFromContext(ctx)
+			return nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+
+	for _, c := range []struct {
+		method, path, key, body string
+		want                    int
+		wantScoped              bool
+		wantOwner               string
+	}{
+		{http.MethodGet, "/v1/accounts/1", "ro-key", "", http.StatusOK, true, "dashboard"},
+		{http.MethodGet, "/v1/accounts/1", "service-key", "", http.StatusOK, true, "payments"},
+		{http.MethodGet, "/v1/accounts/1", "admin-key", "", http.StatusOK, false, ""},
+		{http.MethodPost, "/v1/accounts", "service-key", `{"account_id": 1, "initial_balance": "1"}`, http.StatusCreated, true, "payments"},
+		{http.MethodPost, "/v1/accounts", "service-key", `{"account_id": 1, "initial_balance": "1", "owner_id": "payments"}`, http.StatusCreated, true, "payments"},
+		{http.MethodPost, "/v1/accounts", "admin-key", `{"account_id": 1, "initial_balance": "1", "owner_id": "payroll"}`, http.StatusCreated, true, "payroll"},
+		{http.MethodPost, "/v1/accounts", "admin-key", `{"account_id": 1, "initial_balance": "1"}`, http.StatusCreated, false, ""},
+	} {
+		owner, scoped = "", false
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set(auth.APIKeyHeader, c.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want {
+			t.Fatalf("%s %s with %q: expected status %d, got %d", c.method, c.path, c.key, c.want, w.Code)
+		}
+		if scoped != c.wantScoped || owner != c.wantOwner {
+			t.Fatalf("%s %s with %q: expected owner %q (%v), got %q (%v)", c.method, c.path, c.key, c.wantOwner, c.wantScoped, owner, scoped)
+		}
+	}
+
+	// Accounts cannot be created for someone else
+	req := httptest.NewRequest(http.MethodPost, "/v1/accounts", strings.NewReader(`{"account_id": 1, "initial_balance": "1", "owner_id": "payroll"}`))
+	req.Header.Set(auth.APIKeyHeader, "service-key")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+}
+
+// TestCreateAPIKey_Tenant tests that tenant admins only issue keys of their tenant
+func TestCreateAPIKey_Tenant(t *testing.T) {
+	var storedTenant string
+	mockStore := &MockStore{
+		CreateAPIKeyFunc: func(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error) {
+			storedTenant = tenant
+			return store.APIKey{ID: 7, Name: name, Role: role, Tenant: tenant}, nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+
+	cases := []struct {
+		body       string
+		want       int
+		wantTenant string
+	}{
+		{`{"name": "batch-job", "role": "service"}`, http.StatusCreated, "unit-a"},
+		{`{"name": "batch-job", "role": "service", "tenant_id": "unit-a"}`, http.StatusCreated, "unit-a"},
+		{`{"name": "batch-job", "role": "service", "tenant_id": "unit-b"}`, http.StatusForbidden, ""},
+	}
+	for _, c := range cases {
+		storedTenant = ""
+		req := httptest.NewRequest(http.MethodPost, "/v1/admin/apikeys", bytes.NewReader([]byte(c.body)))
+		req.Header.Set(auth.APIKeyHeader, "tenant-key")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want || storedTenant != c.wantTenant {
+			t.Fatalf("%s: expected status %d and tenant %q, got %d and %q", c.body, c.want, c.wantTenant, w.Code, storedTenant)
+		}
+	}
+}
+
+// TestCreateAPIKey_Success tests that a key is issued once and only its hash is stored
+func TestCreateAPIKey_Success(t *testing.T) {
+	var storedHash string
+	mockStore := &MockStore{
+		CreateAPIKeyFunc: func(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error) {
+			storedHash = keyHash
+			return store.APIKey{ID: 7, Name: name, Role: role}, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"name": "batch-job", "role": "service"}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/apikeys", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAPIKey(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var resp model.APIKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != 7 || resp.Role != "service" || resp.Key == "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if storedHash != auth.HashKey(resp.Key) {
+		t.Fatalf("expected the hash of the returned key to be stored")
+	}
+}
+
+// TestCreateAPIKey_InvalidRole tests role validation
+func TestCreateAPIKey_InvalidRole(t *testing.T) {
+	api := New(&MockStore{})
+
+	body := []byte(`{"name": "batch-job", "role": "superuser"}`)
+	req := httptest.NewRequest(http.MethodPost, "/admin/apikeys", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAPIKey(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestRevokeAPIKey_NotFound tests revoking an unknown key
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	mockStore := &MockStore{
+		RevokeAPIKeyFunc: func(ctx context.Context, id int64) error {
+			return store.ErrAPIKeyNotFound
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/admin/apikeys/9", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestLatestReconciliation tests the reconciliation report before and after a run
+func TestLatestReconciliation(t *testing.T) {
+	mockStore := &MockStore{}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reconciliation/latest", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d before the first run, got %d", http.StatusNotFound, w.Code)
+	}
+
+	mockStore.ReconciliationFunc = func(ctx context.Context) (store.ReconciliationRun, error) {
+		return store.ReconciliationRun{ID: 7, AccountsChecked: 3, Discrepancies: []store.Discrepancy{
+			{AccountID: 2, Balance: decimal.NewFromInt(15), LedgerBalance: decimal.NewFromInt(10)},
+		}}, nil
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reconciliation/latest", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.ReconciliationReport
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.RunID != 7 || len(resp.Discrepancies) != 1 || resp.Discrepancies[0].LedgerBalance.String() != "10" {
+		t.Fatalf("unexpected report: %+v", resp)
+	}
+}
+
+// TestTrialBalance tests the report period defaults and validation
+func TestTrialBalance(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	mockStore := &MockStore{
+		TrialBalanceFunc: func(ctx context.Context, from, to time.Time) (store.TrialBalance, error) {
+			gotFrom, gotTo = from, to
+			return store.TrialBalance{From: from, To: to, StatusCounts: map[string]int{store.StatusSucceeded: 4},
+				Currencies: []store.CurrencyTotals{{Currency: "USD", Accounts: 2, Balance: decimal.NewFromInt(200), TransferCount: 4, TransferVolume: decimal.NewFromInt(40)}},
+				Purposes: []store.PurposeTotals{{Currency: "USD", TransferCount: 1, TransferVolume: decimal.NewFromInt(10)},
+					{PurposeCode: "PAYROLL", Currency: "USD", TransferCount: 3, TransferVolume: decimal.NewFromInt(30)}},
+			}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reports/trial-balance?to=2024-02-01T00:00:00Z", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if want := time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC); !gotFrom.Equal(want) || gotTo.Sub(gotFrom) != model.DefaultReportPeriod {
+		t.Fatalf("expected a week ending at to, got [%s, %s)", gotFrom, gotTo)
+	}
+	var resp model.TrialBalanceReport
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Currencies) != 1 || resp.Currencies[0].TotalBalance.String() != "200" || resp.TransactionsByStatus[store.StatusSucceeded] != 4 {
+		t.Fatalf("unexpected report: %+v", resp)
+	}
+	if len(resp.TransfersByPurpose) != 2 || resp.TransfersByPurpose[1].PurposeCode != "PAYROLL" || resp.TransfersByPurpose[1].TransferVolume.String() != "30" {
+		t.Fatalf("unexpected transfers by purpose: %+v", resp.TransfersByPurpose)
+	}
+
+	for _, query := range []string{"?from=last-week", "?from=2024-02-01T00:00:00Z&to=2024-01-01T00:00:00Z"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reports/trial-balance"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", query, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// TestAdjustBalance tests that adjustments need an admin and record the reason and caller
+func TestAdjustBalance(t *testing.T) {
+	var gotAmount decimal.Decimal
+	var gotReason, gotBy string
+	mockStore := &MockStore{
+		AdjustBalanceFunc: func(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (store.Transaction, error) {
+			if accountID == 999 {
+				return store.Transaction{}, store.ErrAccountNotFound
+			}
+			if amount.LessThan(decimal.NewFromInt(-100)) {
+				return store.Transaction{}, store.ErrInsufficientFunds
+			}
+			gotAmount, gotReason, gotBy = amount, reason, adjustedBy
+			return store.Transaction{ID: 12, SourceAccountID: accountID, Amount: amount.Abs(), Currency: "USD", Status: store.StatusSucceeded,
+				Kind: store.KindAdjustment, Reason: reason, AdjustedBy: adjustedBy, TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000012")}}, nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+	post := func(path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(body)))
+		req.Header.Set(auth.APIKeyHeader, key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "debit", "amount": "25.5", "reason": " duplicate credit on 2024-03-01 "}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	if !gotAmount.Equal(decimal.RequireFromString("-25.5")) || gotReason != "duplicate credit on 2024-03-01" || gotBy != "ops" {
+		t.Fatalf("unexpected adjustment: amount=%s reason=%q by=%q", gotAmount, gotReason, gotBy)
+	}
+	var resp model.Transaction
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "01890a5d-ac97-7000-8000-000000000012" || resp.Kind != store.KindAdjustment || resp.AdjustedBy != "ops" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	cases := []struct {
+		path, key, body string
+		want            int
+	}{
+		{"/v1/admin/accounts/1/adjustments", "service-key", `{"type": "credit", "amount": "1", "reason": "fix"}`, http.StatusForbidden},
+		{"/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "credit", "amount": "1"}`, http.StatusBadRequest},
+		{"/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "credit", "amount": "1", "reason": "   "}`, http.StatusBadRequest},
+		{"/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "refund", "amount": "1", "reason": "fix"}`, http.StatusBadRequest},
+		{"/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "debit", "amount": "-1", "reason": "fix"}`, http.StatusBadRequest},
+		{"/v1/admin/accounts/999/adjustments", "admin-key", `{"type": "credit", "amount": "1", "reason": "fix"}`, http.StatusNotFound},
+		{"/v1/admin/accounts/1/adjustments", "admin-key", `{"type": "debit", "amount": "500", "reason": "fix"}`, http.StatusConflict},
+	}
+	for _, c := range cases {
+		if w := post(c.path, c.key, c.body); w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+	}
+}
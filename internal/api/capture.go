@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// DefaultCaptureBodyBytes caps the part of each body the capture mode keeps
+const DefaultCaptureBodyBytes = 16 << 10
+
+// capturesPath is the route of the recorded exchanges, which are not
+// recorded themselves
+const capturesPath = "/admin/captures"
+
+// redacted replaces credentials in recorded exchanges
+const redacted = "[redacted]"
+
+// credentialHeaders are the headers redacted from recorded exchanges,
+// including those of signed requests, which could otherwise be replayed
+// within the allowed clock skew
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", auth.APIKeyHeader,
+	auth.SignatureHeader, auth.TimestampHeader, auth.ClientIDHeader}
+
+// credentialFields are the query parameters and JSON fields redacted from
+// recorded exchanges, such as the key returned by POST /admin/apikeys
+var credentialFields = map[string]bool{
+	"key":          true,
+	"api_key":      true,
+	"token":        true,
+	"access_token": true,
+	"password":     true,
+	"secret":       true,
+}
+
+// Recorder keeps the last exchanges of the application routes, and writes
+// each of them as a JSON line to a writer if given, so that issues reported
+// by clients can be reproduced. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	ring    []model.CapturedExchange // its capacity is the number kept
+	next    int                      // index the next exchange goes to once the ring is full
+	lastID  int64
+	enc     *json.Encoder // nil without a writer
+	maxBody int
+}
+
+// NewRecorder returns a Recorder keeping the last size exchanges, none if
+// size is 0, and writing them to w if not nil.
+func NewRecorder(size int, w io.Writer) *Recorder {
+	rec := &Recorder{ring: make([]model.CapturedExchange, 0, size), maxBody: DefaultCaptureBodyBytes}
+	if w != nil {
+		rec.enc = json.NewEncoder(w)
+	}
+	return rec
+}
+
+// Record numbers e and adds it, replacing the oldest exchange if the
+// Recorder is full. It returns the error of writing e, if any.
+func (rec *Recorder) Record(e model.CapturedExchange) error {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.lastID++
+	e.ID = rec.lastID
+	if c := cap(rec.ring); c > 0 {
+		if len(rec.ring) < c {
+			rec.ring = append(rec.ring, e)
+		} else {
+			rec.ring[rec.next] = e
+		}
+		rec.next = (rec.next + 1) % c
+	}
+	if rec.enc != nil {
+		return rec.enc.Encode(e)
+	}
+	return nil
+}
+
+// Exchanges returns the exchanges kept, newest first.
+func (rec *Recorder) Exchanges() []model.CapturedExchange {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	c := cap(rec.ring)
+	out := make([]model.CapturedExchange, 0, len(rec.ring))
+	for i := range len(rec.ring) {
+		out = append(out, rec.ring[(rec.next-1-i+c)%c])
+	}
+	return out
+}
+
+// Clear discards the exchanges kept.
+func (rec *Recorder) Clear() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.ring = rec.ring[:0]
+	rec.next = 0
+}
+
+// WithRecorder records the requests to the application routes and their
+// responses with rec, and enables GET and DELETE /admin/captures to read and
+// clear them. Bodies are kept as sent, so the capture mode is meant to be
+// turned on while investigating an issue only.
+func WithRecorder(rec *Recorder) Option {
+	return func(a *API) {
+		a.recorder = rec
+	}
+}
+
+// capture records the exchanges of next, if the capture mode is on.
+// WebSocket upgrades are not recorded, nor are reads of the recordings.
+func (a *API) capture(next http.Handler) http.Handler {
+	if a.recorder == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" || strings.HasSuffix(r.URL.Path, capturesPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		start := a.clock.Now()
+		// Only the part of the body the handler reads is recorded
+		reqBody := &captureBuffer{max: a.recorder.maxBody}
+		if r.Body != nil {
+			r.Body = readCloser{io.TeeReader(r.Body, reqBody), r.Body}
+		}
+		cw := &captureWriter{ResponseWriter: w, status: http.StatusOK, body: captureBuffer{max: a.recorder.maxBody}}
+		next.ServeHTTP(cw, r)
+
+		e := model.CapturedExchange{
+			Time:              start.UTC(),
+			DurationMS:        float64(a.clock.Now().Sub(start)) / float64(time.Millisecond),
+			Method:            r.Method,
+			Path:              r.URL.Path,
+			Query:             redactQuery(r.URL.RawQuery),
+			RequestHeaders:    redactHeaders(r.Header),
+			RequestBody:       reqBody.json(),
+			RequestTruncated:  reqBody.truncated,
+			Status:            cw.status,
+			ResponseHeaders:   redactHeaders(cw.Header()),
+			ResponseBody:      cw.body.json(),
+			ResponseTruncated: cw.body.truncated,
+		}
+		if err := a.recorder.Record(e); err != nil {
+			a.logger.Printf("capture %s %s: %v", r.Method, r.URL.Path, err)
+		}
+	})
+}
+
+// readCloser reads from a reader while closing the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter records the status and the start of the body written by a handler.
+type captureWriter struct {
+	http.ResponseWriter
+	status int
+	body   captureBuffer
+}
+
+func (c *captureWriter) WriteHeader(code int) {
+	c.status = code
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *captureWriter) Write(p []byte) (int, error) {
+	_, _ = c.body.Write(p)
+	return c.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush.
+func (c *captureWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// captureBuffer keeps the first max bytes written to it.
+type captureBuffer struct {
+	data      []byte
+	max       int
+	truncated bool
+}
+
+func (b *captureBuffer) Write(p []byte) (int, error) {
+	keep := p
+	if room := b.max - len(b.data); len(keep) > room {
+		keep, b.truncated = keep[:max(room, 0)], true
+	}
+	b.data = append(b.data, keep...)
+	return len(p), nil
+}
+
+// json returns the body as JSON with its credentials redacted if it is a
+// whole JSON document, and as a JSON string otherwise.
+func (b *captureBuffer) json() json.RawMessage {
+	if len(b.data) == 0 {
+		return nil
+	}
+	if !b.truncated {
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(b.data))
+		dec.UseNumber() // amounts are kept as sent
+		if dec.Decode(&v) == nil && !dec.More() {
+			if !redactJSON(v) {
+				return json.RawMessage(bytes.TrimSpace(b.data))
+			}
+			if out, err := json.Marshal(v); err == nil {
+				return out
+			}
+		}
+	}
+	out, _ := json.Marshal(string(b.data))
+	return out
+}
+
+// redactJSON redacts the credential fields of a decoded JSON value, and
+// reports whether it found any.
+func redactJSON(v any) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if credentialFields[strings.ToLower(k)] {
+				v[k], found = redacted, true
+				continue
+			}
+			found = redactJSON(field) || found
+		}
+	case []any:
+		for _, item := range v {
+			found = redactJSON(item) || found
+		}
+	}
+	return found
+}
+
+// redactHeaders returns a copy of h with the credential headers redacted.
+func redactHeaders(h http.Header) map[string][]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := h.Clone()
+	for k := range out {
+		for _, name := range credentialHeaders {
+			if strings.EqualFold(k, name) {
+				out[k] = []string{redacted}
+			}
+		}
+	}
+	return out
+}
+
+// redactQuery returns the query with the credential parameters redacted.
+func redactQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	q, err := url.ParseQuery(raw)
+	if err != nil {
+		return ""
+	}
+	for k := range q {
+		if credentialFields[strings.ToLower(k)] {
+			q[k] = []string{redacted}
+		}
+	}
+	return q.Encode()
+}
+
+// ListCaptures returns the recorded exchanges, newest first, filtered by the
+// path prefix and min_status query parameters.
+func (a *API) ListCaptures(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+	var minStatus int
+	if s := q.Get("min_status"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 100 || v > 599 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "min_status must be an HTTP status", map[string]interface{}{"parameter": "min_status"})
+			return
+		}
+		minStatus = v
+	}
+	prefix := q.Get("path")
+
+	resp := model.CaptureListResponse{Captures: []model.CapturedExchange{}}
+	for _, e := range a.recorder.Exchanges() {
+		if len(resp.Captures) == limit {
+			break
+		}
+		if e.Status >= minStatus && strings.HasPrefix(e.Path, prefix) {
+			resp.Captures = append(resp.Captures, e)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ClearCaptures discards the recorded exchanges, e.g. before reproducing an issue.
+func (a *API) ClearCaptures(w http.ResponseWriter, r *http.Request) {
+	a.recorder.Clear()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// TestCapture tests that exchanges are recorded with credentials redacted,
+// listed newest first, written to the file and cleared
+func TestCapture(t *testing.T) {
+	var file bytes.Buffer
+	rec := NewRecorder(2, &file)
+	r := mux.NewRouter()
+	New(&MockStore{}, WithRecorder(rec)).RegisterRoutes(r)
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/accounts", strings.NewReader(`{"account_id": 1, "initial_balance": "100.50", "tags": ["vip"]}`))
+	req.Header.Set(auth.APIKeyHeader, "sk-secret")
+	req.Header.Set("Authorization", "Bearer abc")
+	req.Header.Set(auth.SignatureHeader, "c0ffee")
+	serve(req)
+	serve(httptest.NewRequest(http.MethodGet, "/v1/accounts/1?token=abc&fields=balance", nil))
+	serve(httptest.NewRequest(http.MethodGet, "/v1/accounts/oops", nil))
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/v1/admin/captures", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	var resp model.CaptureListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// Only the last two are kept, and the listing itself is not recorded
+	if len(resp.Captures) != 2 || resp.Captures[0].ID != 3 || resp.Captures[1].ID != 2 {
+		t.Fatalf("expected exchanges 3 and 2, got %+v", resp.Captures)
+	}
+	failed, read := resp.Captures[0], resp.Captures[1]
+	if failed.Status != http.StatusNotFound || !strings.Contains(string(failed.ResponseBody), `"code":"ACCOUNT_NOT_FOUND"`) {
+		t.Errorf("unexpected failed exchange %+v", failed)
+	}
+	if read.Method != http.MethodGet || read.Path != "/v1/accounts/1" || read.Query != "fields=balance&token=%5Bredacted%5D" || read.Status != http.StatusOK {
+		t.Errorf("unexpected read exchange %+v", read)
+	}
+
+	// The file has every exchange
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines in the file, got %d", len(lines))
+	}
+	var created model.CapturedExchange
+	if err := json.Unmarshal([]byte(lines[0]), &created); err != nil {
+		t.Fatalf("decode line: %v", err)
+	}
+	if created.Status != http.StatusCreated || string(created.RequestBody) != `{"account_id":1,"initial_balance":"100.50","tags":["vip"]}` {
+		t.Errorf("unexpected created exchange %+v", created)
+	}
+	if got := created.RequestHeaders[http.CanonicalHeaderKey(auth.APIKeyHeader)]; len(got) != 1 || got[0] != redacted {
+		t.Errorf("expected the API key to be redacted, got %q", got)
+	}
+	if strings.Contains(file.String(), "sk-secret") || strings.Contains(file.String(), "Bearer abc") || strings.Contains(file.String(), "c0ffee") {
+		t.Errorf("credentials recorded: %s", file.String())
+	}
+
+	// Filters
+	w = serve(httptest.NewRequest(http.MethodGet, "/v1/admin/captures?min_status=400", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Captures) != 1 || resp.Captures[0].ID != 3 {
+		t.Errorf("expected only exchange 3 of status 404, got %s", w.Body)
+	}
+	w = serve(httptest.NewRequest(http.MethodGet, "/v1/admin/captures?min_status=abc", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid min_status, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	if w = serve(httptest.NewRequest(http.MethodDelete, "/v1/admin/captures", nil)); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+	if got := rec.Exchanges(); len(got) != 0 {
+		t.Fatalf("expected no exchange after clearing, got %d", len(got))
+	}
+}
+
+// TestCaptureDisabled tests that the capture routes only exist with a Recorder
+func TestCaptureDisabled(t *testing.T) {
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/captures", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestCaptureBody tests how bodies are recorded
+func TestCaptureBody(t *testing.T) {
+	for _, tc := range []struct {
+		body string
+		max  int
+		want string
+	}{
+		{`{"amount": 10.10}`, 100, `{"amount": 10.10}`},
+		{`{"id": 1, "key": "sk-1", "nested": [{"Password": "x"}]}`, 100, `{"id":1,"key":"[redacted]","nested":[{"Password":"[redacted]"}]}`},
+		{"id,balance\n1,10\n", 100, `"id,balance\n1,10\n"`},
+		{`{"amount": "10.10"}`, 5, `"{\"amo"`},
+	} {
+		b := &captureBuffer{max: tc.max}
+		_, _ = b.Write([]byte(tc.body))
+		if got := string(b.json()); got != tc.want {
+			t.Errorf("%q: expected %s, got %s", tc.body, tc.want, got)
+		}
+		if b.truncated != (len(tc.body) > tc.max) {
+			t.Errorf("%q: expected truncated to be %v", tc.body, len(tc.body) > tc.max)
+		}
+	}
+}
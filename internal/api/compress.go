@@ -0,0 +1,190 @@
+package api
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// gzipMinBytes is the size from which responses are compressed; below it the
+// gzip framing outweighs the savings.
+const gzipMinBytes = 1024
+
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// CompressionMiddleware gzips responses of at least gzipMinBytes for clients
+// accepting gzip, such as exports and account lists, and decompresses request
+// bodies sent with Content-Encoding: gzip, such as bulk transfer files. The
+// body size limits of the handlers apply to the decompressed body, which
+// bounds what a small compressed body can expand to. Other request encodings
+// are rejected with 415.
+func CompressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch enc := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); enc {
+		case "", "identity":
+		case "gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "request body is not valid gzip",
+					map[string]interface{}{"header": "Content-Encoding"})
+				return
+			}
+			defer zr.Close()
+			r.Body = zr
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		default:
+			writeErrorDetails(w, http.StatusUnsupportedMediaType, model.ErrCodeUnsupportedEncoding, "unsupported Content-Encoding "+strconv.Quote(enc)+"; use gzip",
+				map[string]interface{}{"header": "Content-Encoding"})
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		gw := &gzipResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(gw, r)
+		// Not deferred: a handler aborting with a panic must not get a
+		// well-formed gzip trailer, which would hide a truncated body
+		gw.close()
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		v, err := strconv.ParseFloat(q, 64)
+		return err == nil && v > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response and compresses it once
+// it reaches gzipMinBytes or is flushed; shorter responses are sent as is.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	status int
+	buf    []byte
+	gz     *gzip.Writer
+	sent   bool // the header was sent, compressed or not
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	if g.status == 0 {
+		g.status = code
+	}
+}
+
+func (g *gzipResponseWriter) Write(p []byte) (int, error) {
+	if g.status == 0 {
+		g.status = http.StatusOK
+	}
+	if g.gz != nil {
+		return g.gz.Write(p)
+	}
+	if g.sent {
+		return g.ResponseWriter.Write(p)
+	}
+	g.buf = append(g.buf, p...)
+	if len(g.buf) >= gzipMinBytes {
+		if err := g.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// start sends the header and the buffered body, compressed if compress and the
+// handler has not encoded the body itself.
+func (g *gzipResponseWriter) start(compress bool) error {
+	g.sent = true
+	h := g.Header()
+	if compress && h.Get("Content-Encoding") == "" && bodyAllowed(g.status) {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		g.gz = gzipWriters.Get().(*gzip.Writer)
+		g.gz.Reset(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(g.status)
+	buf := g.buf
+	g.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if g.gz != nil {
+		_, err = g.gz.Write(buf)
+	} else {
+		_, err = g.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// Flush implements http.Flusher.
+func (g *gzipResponseWriter) Flush() {
+	_ = g.FlushError()
+}
+
+// FlushError sends what was written so far, compressing from then on: a
+// flushed response is being streamed and is likely to grow. It is what
+// http.ResponseController calls.
+func (g *gzipResponseWriter) FlushError() error {
+	if !g.sent {
+		if g.status == 0 {
+			g.status = http.StatusOK
+		}
+		if err := g.start(true); err != nil {
+			return err
+		}
+	}
+	if g.gz != nil {
+		if err := g.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	return http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// set deadlines.
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// close sends a response shorter than gzipMinBytes as is, or ends the gzip
+// stream.
+func (g *gzipResponseWriter) close() {
+	if !g.sent {
+		if g.status == 0 {
+			return
+		}
+		_ = g.start(false)
+		return
+	}
+	if g.gz != nil {
+		_ = g.gz.Close()
+		g.gz.Reset(nil)
+		gzipWriters.Put(g.gz)
+		g.gz = nil
+	}
+}
+
+// bodyAllowed reports whether a response with status may have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
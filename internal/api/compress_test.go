@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// gzipBytes compresses s
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(s)); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestCompressionMiddleware_Responses tests which responses are gzipped
+func TestCompressionMiddleware_Responses(t *testing.T) {
+	large := strings.Repeat(`{"id": 1}`, gzipMinBytes)
+	cases := []struct {
+		name, accept, body string
+		wantGzip           bool
+	}{
+		{"large", "gzip, deflate", large, true},
+		{"small", "gzip", `{"id": 1}`, false},
+		{"not accepted", "", large, false},
+		{"refused", "gzip;q=0, identity", large, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusCreated)
+				io.WriteString(w, c.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", c.accept)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Fatalf("expected Vary: Accept-Encoding, got %q", got)
+			}
+			body := w.Body.Bytes()
+			if gzipped := w.Header().Get("Content-Encoding") == "gzip"; gzipped != c.wantGzip {
+				t.Fatalf("expected gzip %v, got Content-Encoding %q", c.wantGzip, w.Header().Get("Content-Encoding"))
+			}
+			if c.wantGzip {
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("gunzip: %v", err)
+				}
+				if body, err = io.ReadAll(zr); err != nil {
+					t.Fatalf("gunzip: %v", err)
+				}
+			}
+			if string(body) != c.body {
+				t.Fatalf("expected the body back, got %d bytes", len(body))
+			}
+		})
+	}
+}
+
+// TestCompressionMiddleware_Flush tests that a flushed response is compressed as it streams
+func TestCompressionMiddleware_Flush(t *testing.T) {
+	h := CompressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "row 1\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("flush: %v", err)
+		}
+		io.WriteString(w, "row 2\n")
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if !w.Flushed || w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a flushed gzip response, got flushed=%v encoding=%q", w.Flushed, w.Header().Get("Content-Encoding"))
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gunzip: %v", err)
+	}
+	body, err := io.ReadAll(zr)
+	if err != nil || string(body) != "row 1\nrow 2\n" {
+		t.Fatalf("expected both rows, got %q (%v)", body, err)
+	}
+}
+
+// TestCompressionMiddleware_Requests tests the decompression of request bodies
+func TestCompressionMiddleware_Requests(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(CompressionMiddleware)
+	New(&MockStore{}, WithMaxBodyBytes(256)).RegisterRoutes(r)
+
+	cases := []struct {
+		name, encoding string
+		body           []byte
+		wantStatus     int
+		wantCode       model.ErrorCode
+	}{
+		{"gzip", "gzip", gzipBytes(t, `{"account_id": 1, "initial_balance": "10"}`), http.StatusCreated, ""},
+		{"identity", "identity", []byte(`{"account_id": 1, "initial_balance": "10"}`), http.StatusCreated, ""},
+		{"expands past the limit", "gzip", gzipBytes(t, `{"account_id": 1, "initial_balance": "10"`+strings.Repeat(" ", 1<<20)+`}`), http.StatusRequestEntityTooLarge, model.ErrCodeBodyTooLarge},
+		{"not gzip", "gzip", []byte(`{"account_id": 1}`), http.StatusBadRequest, model.ErrCodeValidationFailed},
+		{"unsupported", "br", []byte(`{"account_id": 1}`), http.StatusUnsupportedMediaType, model.ErrCodeUnsupportedEncoding},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/accounts", bytes.NewReader(c.body))
+			req.Header.Set("Content-Encoding", c.encoding)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != c.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", c.wantStatus, w.Code, w.Body)
+			}
+			if c.wantCode == "" {
+				return
+			}
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Code != c.wantCode {
+				t.Fatalf("expected code %s, got %s", c.wantCode, resp.Code)
+			}
+		})
+	}
+}
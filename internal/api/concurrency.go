@@ -0,0 +1,61 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// DefaultTransferQueueWait is how long a request moving money waits for a
+// free slot, once WithTransferConcurrency limits them, before it is refused.
+const DefaultTransferQueueWait = time.Second
+
+// WithTransferConcurrency lets at most n requests moving money, such as
+// transfers, batches and hold captures, run at a time, so that a burst of them
+// cannot take every DB connection and starve reads. Further requests queue for
+// up to maxWait, or DefaultTransferQueueWait if it is not positive, and are
+// then refused with 503 and a Retry-After header. Non-positive values of n
+// leave them unlimited, the default.
+func WithTransferConcurrency(n int, maxWait time.Duration) Option {
+	return func(a *API) {
+		if n <= 0 {
+			a.transferSlots = nil
+			return
+		}
+		if maxWait <= 0 {
+			maxWait = DefaultTransferQueueWait
+		}
+		a.transferSlots = make(chan struct{}, n)
+		a.transferQueueWait = maxWait
+	}
+}
+
+// limitTransfers wraps h, a handler moving money, so that it waits for one of
+// the slots of WithTransferConcurrency.
+func (a *API) limitTransfers(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a.transferSlots == nil {
+			h(w, r)
+			return
+		}
+		timer := time.NewTimer(a.transferQueueWait)
+		defer timer.Stop()
+		select {
+		case a.transferSlots <- struct{}{}:
+		case <-timer.C:
+			retry := int(math.Ceil(a.transferQueueWait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retry))
+			writeError(w, http.StatusServiceUnavailable, model.ErrCodeServiceUnavailable, "too many transfers in progress")
+			return
+		case <-r.Context().Done():
+			// The caller's deadline passed, or it went away
+			internalError(w, r.Context().Err())
+			return
+		}
+		defer func() { <-a.transferSlots }()
+		h(w, r)
+	}
+}
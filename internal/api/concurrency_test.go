@@ -0,0 +1,65 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestTransferConcurrency tests that transfers over the limit queue, then get 503
+func TestTransferConcurrency(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			if srcID == 1 {
+				started <- struct{}{}
+				<-release
+			}
+			return 1, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore, WithTransferConcurrency(1, 20*time.Millisecond)).RegisterRoutes(r)
+	transfer := func(src string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions",
+			strings.NewReader(`{"source_account_id": `+src+`, "destination_account_id": 3, "amount": "1"}`)))
+		return w
+	}
+
+	first := make(chan *httptest.ResponseRecorder)
+	go func() { first <- transfer("1") }()
+	<-started
+
+	// Reads are not limited
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected reads to go through, got status %d", w.Code)
+	}
+
+	w = transfer("2")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Fatalf("expected Retry-After: 1, got %q", got)
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusOK {
+		t.Fatalf("expected the first transfer to succeed, got status %d", w.Code)
+	}
+	if w := transfer("2"); w.Code != http.StatusOK {
+		t.Fatalf("expected a freed slot to be reused, got status %d", w.Code)
+	}
+}
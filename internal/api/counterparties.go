@@ -0,0 +1,85 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toCounterpartiesResponse maps a stored counterparty allowlist to its JSON representation
+func toCounterpartiesResponse(l store.CounterpartyAllowlist) model.CounterpartiesResponse {
+	resp := model.CounterpartiesResponse{AccountID: l.AccountID, Outgoing: l.Outgoing, Incoming: l.Incoming}
+	if !l.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &l.UpdatedAt
+	}
+	return resp
+}
+
+// GetCounterparties returns the counterparty allowlist of the account
+func (a *API) GetCounterparties(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	l, err := a.store.CounterpartyAllowlist(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get counterparties failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCounterpartiesResponse(l))
+}
+
+// SetCounterparties replaces the counterparty allowlist of the account
+func (a *API) SetCounterparties(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.SetCounterpartiesRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	l, err := a.store.SetCounterpartyAllowlist(ctx, store.CounterpartyAllowlist{AccountID: id, Outgoing: req.Outgoing, Incoming: req.Incoming})
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("set counterparties failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toCounterpartiesResponse(l))
+}
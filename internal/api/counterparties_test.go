@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCounterparties tests setting and reading the counterparty allowlist of an account
+func TestCounterparties(t *testing.T) {
+	var stored store.CounterpartyAllowlist
+	mockStore := &MockStore{
+		SetCounterpartyFunc: func(ctx context.Context, l store.CounterpartyAllowlist) (store.CounterpartyAllowlist, error) {
+			if l.AccountID == 404 {
+				return store.CounterpartyAllowlist{}, store.ErrAccountNotFound
+			}
+			stored = l
+			return l, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1/counterparties", `{"outgoing": [0]}`, http.StatusBadRequest},
+		{"/v1/accounts/1/counterparties", `{"outgoing": ["2"]}`, http.StatusBadRequest},
+		{"/v1/accounts/404/counterparties", `{}`, http.StatusNotFound},
+		{"/v1/accounts/1/counterparties", `{"outgoing": [3, 2], "incoming": []}`, http.StatusOK},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path, bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", c.path, c.body, c.want, w.Code, w.Body.String())
+		}
+	}
+	if !slices.Equal(stored.Outgoing, []int64{3, 2}) || stored.Incoming == nil || len(stored.Incoming) != 0 {
+		t.Fatalf("unexpected allowlist stored: %+v", stored)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/1/counterparties", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := strings.TrimSpace(w.Body.String()); got != `{"account_id":1,"outgoing":null,"incoming":null}` {
+		t.Fatalf("expected an unrestricted allowlist, got %s", got)
+	}
+}
+
+// TestCreateTransaction_CounterpartyNotAllowed tests transfers between accounts the allowlists keep apart
+func TestCreateTransaction_CounterpartyNotAllowed(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrCounterpartyNotAllowed
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	w := httptest.NewRecorder()
+	api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeCounterpartyNotAllowed {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeCounterpartyNotAllowed, resp.Code)
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// DefaultTransferTimeout bounds the store calls of requests moving money
+// unless WithTransferTimeout is given. It leaves transfers room to retry
+// serialization failures.
+const DefaultTransferTimeout = 10 * time.Second
+
+// writeTimeoutMargin is how long before the server's write timeout requests
+// give up, leaving time to write the 504 response.
+const writeTimeoutMargin = time.Second
+
+// Headers carrying the caller's deadline
+const (
+	headerRequestDeadline = "X-Request-Deadline" // an RFC 3339 timestamp
+	headerGRPCTimeout     = "Grpc-Timeout"       // a gRPC timeout, e.g. "500m" for 500ms
+)
+
+// WithTransferTimeout bounds the store calls of requests moving money, such as
+// transfers, batches and hold captures, by d. Non-positive values keep
+// DefaultTransferTimeout.
+func WithTransferTimeout(d time.Duration) Option {
+	return func(a *API) {
+		if d > 0 {
+			a.transferTimeout = d
+		}
+	}
+}
+
+// WithWriteTimeout tells the API the write timeout of its http.Server, so that
+// requests give up shortly before it and answer with a 504 rather than have
+// their connection closed.
+func WithWriteTimeout(d time.Duration) Option {
+	return func(a *API) {
+		a.writeTimeout = d
+	}
+}
+
+// requestContext returns the context of a request's store calls, bounded by
+// the request timeout.
+func (a *API) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return a.boundedContext(r, a.reqTimeout)
+}
+
+// transferContext is requestContext for requests moving money, bounded by the
+// transfer timeout instead.
+func (a *API) transferContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return a.boundedContext(r, a.transferTimeout)
+}
+
+// boundedContext bounds r's context by d, and by the write timeout less
+// writeTimeoutMargin. A deadline the caller sent is already on r's context.
+func (a *API) boundedContext(r *http.Request, d time.Duration) (context.Context, context.CancelFunc) {
+	if limit := a.writeTimeout - writeTimeoutMargin; limit > 0 && d > limit {
+		d = limit
+	}
+	return context.WithTimeout(r.Context(), d)
+}
+
+// callerDeadline applies the deadline of the X-Request-Deadline or
+// Grpc-Timeout header, if any, to the request context; it can only shorten the
+// route's timeout. A malformed header is rejected with 400.
+func callerDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+		if s := r.Header.Get(headerRequestDeadline); s != "" {
+			deadline, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, headerRequestDeadline+" must be an RFC 3339 timestamp",
+					map[string]interface{}{"header": headerRequestDeadline})
+				return
+			}
+			ctx, cancel = context.WithDeadline(r.Context(), deadline)
+		} else if s := r.Header.Get(headerGRPCTimeout); s != "" {
+			timeout, err := parseGRPCTimeout(s)
+			if err != nil {
+				writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error(),
+					map[string]interface{}{"header": headerGRPCTimeout})
+				return
+			}
+			ctx, cancel = context.WithTimeout(r.Context(), timeout)
+		} else {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// grpcTimeoutUnits are the units of the gRPC timeout format
+var grpcTimeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+var errInvalidGRPCTimeout = errors.New(headerGRPCTimeout + " must be at most 8 digits followed by a unit (H, M, S, m, u or n)")
+
+// parseGRPCTimeout parses a timeout in the format of the grpc-timeout header:
+// an integer of at most 8 digits followed by a unit. Timeouts too long for a
+// time.Duration are capped.
+func parseGRPCTimeout(s string) (time.Duration, error) {
+	if len(s) < 2 || len(s) > 9 {
+		return 0, errInvalidGRPCTimeout
+	}
+	digits := s[:len(s)-1]
+	unit, ok := grpcTimeoutUnits[s[len(s)-1]]
+	if !ok || strings.TrimLeft(digits, "0123456789") != "" {
+		return 0, errInvalidGRPCTimeout
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errInvalidGRPCTimeout
+	}
+	if n > math.MaxInt64/int64(unit) {
+		return math.MaxInt64, nil
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestParseGRPCTimeout tests the grpc-timeout format
+func TestParseGRPCTimeout(t *testing.T) {
+	valid := map[string]time.Duration{
+		"500m":      500 * time.Millisecond,
+		"2S":        2 * time.Second,
+		"1H":        time.Hour,
+		"0n":        0,
+		"99999999H": math.MaxInt64,
+	}
+	for s, want := range valid {
+		got, err := parseGRPCTimeout(s)
+		if err != nil || got != want {
+			t.Errorf("%q: expected %s, got %s, %v", s, want, got, err)
+		}
+	}
+	for _, s := range []string{"", "S", "5", "5s", "-5S", "+5S", "123456789S", "1.5S"} {
+		if _, err := parseGRPCTimeout(s); err == nil {
+			t.Errorf("%q: expected an error", s)
+		}
+	}
+}
+
+// TestCallerDeadline tests that the caller's deadline bounds store calls and
+// that running out of time is answered with 504
+func TestCallerDeadline(t *testing.T) {
+	var remaining time.Duration
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			d, _ := ctx.Deadline()
+			remaining = time.Until(d)
+			<-ctx.Done()
+			return store.Account{}, ctx.Err()
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	for header, value := range map[string]string{
+		"Grpc-Timeout":       "20m",
+		"X-Request-Deadline": time.Now().Add(20 * time.Millisecond).Format(time.RFC3339Nano),
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Fatalf("%s: expected status %d, got %d", header, http.StatusGatewayTimeout, w.Code)
+		}
+		var resp model.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != model.ErrCodeTimeout {
+			t.Fatalf("%s: expected code %s, got %s", header, model.ErrCodeTimeout, resp.Code)
+		}
+		if remaining > 20*time.Millisecond {
+			t.Fatalf("%s: expected the caller's deadline, got %s left", header, remaining)
+		}
+	}
+
+	for header, value := range map[string]string{
+		"Grpc-Timeout":       "soon",
+		"X-Request-Deadline": "tomorrow",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil)
+		req.Header.Set(header, value)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d for %q, got %d", header, http.StatusBadRequest, value, w.Code)
+		}
+	}
+}
+
+// TestTransferTimeout tests that transfers get the transfer timeout, capped
+// short of the server's write timeout
+func TestTransferTimeout(t *testing.T) {
+	var remaining time.Duration
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			d, _ := ctx.Deadline()
+			remaining = time.Until(d)
+			return 42, nil
+		},
+	}
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+
+	cases := []struct {
+		opts []Option
+		want time.Duration
+	}{
+		{nil, DefaultTransferTimeout},
+		{[]Option{WithTransferTimeout(20 * time.Second)}, 20 * time.Second},
+		{[]Option{WithTransferTimeout(20 * time.Second), WithWriteTimeout(15 * time.Second)}, 15*time.Second - writeTimeoutMargin},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		New(mockStore, c.opts...).CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("case %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+		if remaining > c.want || remaining < c.want-time.Second {
+			t.Errorf("case %d: expected a timeout of %s, got %s left", i, c.want, remaining)
+		}
+	}
+}
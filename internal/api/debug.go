@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// DebugHandler returns the handler of the debug server: the net/http/pprof
+// profiles under /debug/pprof/ and runtime statistics at /debug/runtime.
+// Profiles expose the internals of the process, so when authentication is
+// configured only deployment-wide admins may read them; either way the debug
+// server should only listen on an internal address.
+func (a *API) DebugHandler() http.Handler {
+	mux := http.NewServeMux()
+	handle := func(path string, h http.HandlerFunc) {
+		mux.Handle(path, a.authorize(auth.RoleAdmin, a.deploymentWide(h)))
+	}
+	handle("/debug/pprof/", pprof.Index)
+	handle("/debug/pprof/cmdline", pprof.Cmdline)
+	handle("/debug/pprof/profile", pprof.Profile)
+	handle("/debug/pprof/symbol", pprof.Symbol)
+	handle("/debug/pprof/trace", pprof.Trace)
+	handle("/debug/runtime", a.RuntimeStats)
+	return mux
+}
+
+// RuntimeStats returns goroutine, heap and GC statistics of the process.
+func (a *API) RuntimeStats(w http.ResponseWriter, r *http.Request) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	resp := model.RuntimeStatsResponse{
+		Goroutines:     runtime.NumGoroutine(),
+		GOMAXPROCS:     runtime.GOMAXPROCS(0),
+		HeapAllocBytes: m.HeapAlloc,
+		HeapInuseBytes: m.HeapInuse,
+		HeapObjects:    m.HeapObjects,
+		SysBytes:       m.Sys,
+		NumGC:          m.NumGC,
+		GCPauseTotalMS: float64(m.PauseTotalNs) / float64(time.Millisecond),
+	}
+	if m.NumGC > 0 {
+		last := time.Unix(0, int64(m.LastGC)).UTC()
+		resp.LastGC = &last
+		resp.LastGCPauseMS = float64(m.PauseNs[(m.NumGC+255)%256]) / float64(time.Millisecond)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// TestDebugHandler_Auth tests that only deployment-wide admins reach the debug endpoints
+func TestDebugHandler_Auth(t *testing.T) {
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,payments:service:service-key,unit-a/teller:admin:tenant-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	h := New(&MockStore{}, WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil))).DebugHandler()
+
+	cases := []struct {
+		path, key string
+		want      int
+	}{
+		{"/debug/runtime", "", http.StatusUnauthorized},
+		{"/debug/runtime", "service-key", http.StatusForbidden},
+		{"/debug/runtime", "tenant-key", http.StatusForbidden},
+		{"/debug/runtime", "admin-key", http.StatusOK},
+		{"/debug/pprof/", "service-key", http.StatusForbidden},
+		{"/debug/pprof/", "admin-key", http.StatusOK},
+		{"/debug/pprof/goroutine?debug=1", "admin-key", http.StatusOK},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.path, nil)
+		if c.key != "" {
+			req.Header.Set(auth.APIKeyHeader, c.key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Errorf("GET %s with %q: expected status %d, got %d", c.path, c.key, c.want, w.Code)
+		}
+	}
+}
+
+// TestRuntimeStats tests the runtime statistics
+func TestRuntimeStats(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(&MockStore{}).DebugHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/runtime", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.RuntimeStatsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Goroutines < 1 || resp.GOMAXPROCS < 1 || resp.HeapAllocBytes == 0 {
+		t.Fatalf("expected live statistics, got %+v", resp)
+	}
+}
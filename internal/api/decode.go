@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// DefaultMaxBodyBytes caps JSON request bodies unless WithMaxBodyBytes is given.
+const DefaultMaxBodyBytes = 1 << 20
+
+// WithMaxBodyBytes caps the size of JSON request bodies; larger bodies are
+// rejected with 413.
+func WithMaxBodyBytes(n int64) Option {
+	return func(a *API) {
+		a.maxBodyBytes = n
+	}
+}
+
+// decodeJSON decodes the request body into v, rejecting bodies over the size
+// limit, unknown fields and anything after the JSON value. On failure it writes
+// the error response and returns false.
+func (a *API) decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, a.maxBodyBytes))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil {
+		// A second value, or garbage, after the body is as bad as a malformed one
+		if err = dec.Decode(&struct{}{}); errors.Is(err, io.EOF) {
+			return true
+		}
+		if err == nil {
+			err = errTrailingData
+		}
+	}
+
+	var (
+		tooLarge  *http.MaxBytesError
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	// encoding/json has no error type for unknown fields
+	field, unknown := strings.CutPrefix(err.Error(), "json: unknown field ")
+	switch {
+	case errors.As(err, &tooLarge):
+		writeErrorDetails(w, http.StatusRequestEntityTooLarge, model.ErrCodeBodyTooLarge,
+			fmt.Sprintf("request body must be at most %d bytes", tooLarge.Limit), map[string]interface{}{"limit": tooLarge.Limit})
+	case errors.Is(err, io.EOF):
+		writeError(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, "request body is empty")
+	case errors.Is(err, model.ErrNumericAmount), errors.Is(err, model.ErrDecimalOutOfRange), errors.Is(err, model.ErrInvalidExternalID), errors.Is(err, model.ErrInvalidWallet):
+		writeError(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, err.Error())
+	case errors.Is(err, errTrailingData):
+		writeError(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, "unexpected data after the JSON body")
+	case unknown:
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, "unknown field "+field,
+			map[string]interface{}{"field": strings.Trim(field, `"`)})
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeInvalidJSON,
+			fmt.Sprintf("%s must be a JSON %s", typeErr.Field, jsonType(typeErr.Type.Kind())), map[string]interface{}{"field": typeErr.Field})
+	case errors.As(err, &syntaxErr):
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, "invalid JSON", map[string]interface{}{"offset": syntaxErr.Offset})
+	default:
+		writeError(w, http.StatusBadRequest, model.ErrCodeInvalidJSON, "invalid JSON")
+	}
+	return false
+}
+
+// errTrailingData is reported for bodies with more than one JSON value
+var errTrailingData = errors.New("trailing data")
+
+// jsonType names the JSON type a Go kind is decoded from
+func jsonType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Struct, reflect.Map:
+		return "object"
+	}
+	return kind.String()
+}
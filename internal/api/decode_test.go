@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestDecodeJSON_Strict tests that malformed, misspelt and oversized bodies are rejected
+// with a structured error before reaching the store
+func TestDecodeJSON_Strict(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			t.Fatal("unexpected transfer")
+			return 0, nil
+		},
+	}
+	api := New(mockStore, WithMaxBodyBytes(128))
+	defer func(m model.NumberMode) { model.DecimalNumbers = m }(model.DecimalNumbers)
+	model.DecimalNumbers = model.NumbersRejected
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  model.ErrorCode
+		details  map[string]interface{}
+	}{
+		{"unknown field", `{"source_account_id": 100, "destination_account_id": 200, "amout": "50.00"}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, map[string]interface{}{"field": "amout"}},
+		{"trailing data", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"} {}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, nil},
+		{"wrong type", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "dry_run": "yes"}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, map[string]interface{}{"field": "dry_run"}},
+		{"syntax error", `{"source_account_id": 100,}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, map[string]interface{}{"offset": float64(27)}},
+		{"empty", ``, http.StatusBadRequest, model.ErrCodeInvalidJSON, nil},
+		{"too large", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "reference": "` + strings.Repeat("x", 100) + `"}`,
+			http.StatusRequestEntityTooLarge, model.ErrCodeBodyTooLarge, map[string]interface{}{"limit": float64(128)}},
+		{"numeric amount", `{"source_account_id": 100, "destination_account_id": 200, "amount": 50.00}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, nil},
+		{"amount out of range", `{"source_account_id": 100, "destination_account_id": 200, "amount": "1e200000000"}`,
+			http.StatusBadRequest, model.ErrCodeInvalidJSON, nil},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/transactions", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+
+		api.CreateTransaction(w, req)
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.name, tt.wantCode, w.Code)
+		}
+		var resp model.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", tt.name, err)
+		}
+		if resp.Code != tt.wantErr {
+			t.Fatalf("%s: expected code %s, got %s", tt.name, tt.wantErr, resp.Code)
+		}
+		for k, v := range tt.details {
+			if resp.Details[k] != v {
+				t.Fatalf("%s: expected details.%s %v, got %v", tt.name, k, v, resp.Details)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// openAPISpec documents every route. Keep it in sync with registerRoutes and
+// the model types; TestOpenAPISpec_CoversRoutes catches missing routes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPIHandler serves the OpenAPI 3 document of the API.
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+// ErrorCatalogHandler lists the error codes the API answers with, their
+// status and meaning.
+func ErrorCatalogHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, model.ErrorCatalogResponse{Errors: model.ErrorCodes()})
+}
+
+// swaggerUIPage renders /openapi.json with Swagger UI loaded from a CDN
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Internal Transfers API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// SwaggerUIHandler serves an interactive Swagger UI for the OpenAPI document.
+func SwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(swaggerUIPage))
+}
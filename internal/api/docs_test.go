@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// TestOpenAPISpec_CoversRoutes tests that every application route is documented
+func TestOpenAPISpec_CoversRoutes(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		for _, m := range methods {
+			if _, ok := spec.Paths[path][strings.ToLower(m)]; !ok {
+				t.Errorf("%s %s is not documented in openapi.json", m, path)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+}
+
+// TestOpenAPIHandler tests that the spec is served as JSON
+func TestOpenAPIHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	OpenAPIHandler(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !json.Valid(w.Body.Bytes()) {
+		t.Fatal("expected a JSON body")
+	}
+}
+
+// TestErrorCatalogHandler tests that the error codes are listed, and that the
+// OpenAPI document enumerates the same codes
+func TestErrorCatalogHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	ErrorCatalogHandler(w, httptest.NewRequest(http.MethodGet, "/errors", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp model.ErrorCatalogResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+
+	var spec struct {
+		Components struct {
+			Schemas struct {
+				Error struct {
+					Properties struct {
+						Code struct {
+							Enum []model.ErrorCode `json:"enum"`
+						} `json:"code"`
+					} `json:"properties"`
+				} `json:"Error"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("openapi.json is not valid JSON: %v", err)
+	}
+	enum := spec.Components.Schemas.Error.Properties.Code.Enum
+	if len(enum) != len(resp.Errors) {
+		t.Fatalf("openapi.json enumerates %d codes, the catalog %d", len(enum), len(resp.Errors))
+	}
+	for _, info := range resp.Errors {
+		if !slices.Contains(enum, info.Code) {
+			t.Errorf("%s is not enumerated in openapi.json", info.Code)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// writeError writes a JSON error body with a machine-readable code
+func writeError(w http.ResponseWriter, status int, code model.ErrorCode, message string) {
+	writeErrorDetails(w, status, code, message, nil)
+}
+
+// writeErrorDetails writes a JSON error body carrying additional details
+func writeErrorDetails(w http.ResponseWriter, status int, code model.ErrorCode, message string, details map[string]interface{}) {
+	writeJSON(w, status, model.ErrorResponse{Code: code, Message: message, Details: details})
+}
+
+// transferError maps the store errors a transfer can fail with to a status and
+// error body. ok is false for unexpected errors.
+func transferError(err error) (status int, resp model.ErrorResponse, ok bool) {
+	var limitErr *store.LimitError
+	var notFoundErr *store.AccountNotFoundError
+	switch {
+	case errors.As(err, &notFoundErr):
+		return http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeAccountNotFound, Message: notFoundErr.Error(),
+			Details: map[string]interface{}{"account_id": notFoundErr.AccountID, "field": notFoundErr.Party + "_account_id"}}, true
+	case errors.Is(err, store.ErrAccountNotFound):
+		return http.StatusNotFound, model.ErrorResponse{Code: model.ErrCodeAccountNotFound, Message: "account not found"}, true
+	case errors.Is(err, store.ErrSameAccount):
+		return http.StatusBadRequest, model.ErrorResponse{Code: model.ErrCodeValidationFailed, Message: model.ErrSameSourceDestination.Error()}, true
+	case errors.Is(err, store.ErrInsufficientFunds):
+		return http.StatusConflict, model.ErrorResponse{Code: model.ErrCodeInsufficientFunds, Message: "insufficient funds"}, true
+	case errors.Is(err, store.ErrAccountInactive):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeAccountInactive, Message: "source or destination account is not active"}, true
+	case errors.As(err, &limitErr):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeLimitExceeded, Message: limitErr.Error(),
+			Details: map[string]interface{}{"limit": limitErr.Limit}}, true
+	case errors.Is(err, store.ErrCurrencyMismatch):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeCurrencyMismatch, Message: "source and destination accounts have different currencies"}, true
+	case errors.Is(err, store.ErrKYCRestricted):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeKYCRestricted, Message: "the kyc status of the source or destination account does not allow the transfer"}, true
+	case errors.Is(err, store.ErrCounterpartyNotAllowed):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeCounterpartyNotAllowed, Message: "the counterparty allowlists of the accounts do not allow the transfer"}, true
+	case errors.Is(err, store.ErrCrossTenant):
+		return http.StatusForbidden, model.ErrorResponse{Code: model.ErrCodeCrossTenant, Message: "source and destination accounts belong to different tenants"}, true
+	case errors.Is(err, store.ErrTransferBlocked):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeTransferBlocked, Message: "transfer blocked by a fraud rule"}, true
+	case errors.Is(err, store.ErrApprovalRequired):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeApprovalRequired, Message: "a rule requires the transfer to be approved; submit it as a single transfer"}, true
+	case errors.Is(err, store.ErrFeeAccountUnavailable):
+		return http.StatusUnprocessableEntity, model.ErrorResponse{Code: model.ErrCodeFeeAccountUnavailable, Message: "fee account unavailable"}, true
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, timeoutResponse, false
+	case errors.Is(err, store.ErrCircuitOpen):
+		return http.StatusServiceUnavailable, unavailableResponse, true
+	}
+	return http.StatusInternalServerError, model.ErrorResponse{Code: model.ErrCodeInternal, Message: "internal error"}, false
+}
+
+// timeoutResponse is the body of requests that ran out of time
+var timeoutResponse = model.ErrorResponse{Code: model.ErrCodeTimeout, Message: "request timed out"}
+
+// unavailableResponse is the body of requests failed fast while the
+// database is unreachable
+var unavailableResponse = model.ErrorResponse{Code: model.ErrCodeServiceUnavailable, Message: "database unavailable"}
+
+// internalError answers an unexpected error with 500 Internal Server Error,
+// with 504 Gateway Timeout if the request ran out of time, which clients may
+// retry with a longer deadline, or with 503 Service Unavailable if the
+// database is known to be down.
+func internalError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, timeoutResponse)
+		return
+	case errors.Is(err, store.ErrCircuitOpen):
+		writeJSON(w, http.StatusServiceUnavailable, unavailableResponse)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, model.ErrCodeInternal, "internal error")
+}
+
+// notSupported answers with 501 Not Implemented if err is store.ErrNotSupported,
+// which stores without the feature behind a route return, and reports whether it did.
+func notSupported(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, store.ErrNotSupported) {
+		return false
+	}
+	writeError(w, http.StatusNotImplemented, model.ErrCodeNotSupported, "not supported by this deployment")
+	return true
+}
+
+// NotFoundHandler answers unmatched routes with a JSON error.
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, model.ErrCodeNotFound, "route not found")
+	})
+}
+
+// MethodNotAllowedHandler answers requests with an unsupported method with a JSON error.
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusMethodNotAllowed, model.ErrCodeMethodNotAllowed, "method not allowed")
+	})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// Export formats of GET /accounts/{id}/transactions/export
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+// exportTimeout bounds an export, which may run far longer than other requests
+const exportTimeout = 10 * time.Minute
+
+// exportFlushRows is how many rows are written between flushes to the client
+const exportFlushRows = 500
+
+// exportCSVHeader names the columns of a CSV export. The deprecated serial
+// transaction_id and reversal_of are empty unless serial ids are enabled.
+var exportCSVHeader = []string{
+	"transaction_id", "created_at", "source_account_id", "destination_account_id", "amount", "currency",
+	"status", "error_message", "reversal_of", "reference", "purpose_code", "kind", "reason", "id",
+	"initiated_by", "channel", "request_id", "reversal_of_id", "fee_of_id",
+}
+
+// ExportAccountTransactions streams the account's transactions created between
+// the optional from and to query timestamps, oldest first, as CSV or JSON lines.
+// The response is written as rows are read rather than buffered, so an error
+// after the first row aborts the connection instead of returning an error body.
+func (a *API) ExportAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportJSONL {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "format must be csv or jsonl", map[string]interface{}{"parameter": "format"})
+		return
+	}
+	var from, to time.Time
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &from}, {"to", &to}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, p.name+" must be an RFC 3339 timestamp", map[string]interface{}{"parameter": p.name})
+				return
+			}
+			*p.dst = t
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "from must be before to", map[string]interface{}{"parameter": "from"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), exportTimeout)
+	defer cancel()
+	rc := http.NewResponseController(w)
+	// The server's write timeout is sized for ordinary requests
+	_ = rc.SetWriteDeadline(time.Now().Add(exportTimeout))
+
+	// The headers are written with the first row, so that errors up to then
+	// still get an error response
+	var cw *csv.Writer
+	var enc *json.Encoder
+	started := false
+	start := func() error {
+		started = true
+		contentType := "text/csv; charset=utf-8"
+		if format == exportJSONL {
+			contentType = "application/x-ndjson"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d-transactions.%s"`, id, format))
+		w.WriteHeader(http.StatusOK)
+		if format == exportJSONL {
+			enc = json.NewEncoder(w)
+			return nil
+		}
+		cw = csv.NewWriter(w)
+		return cw.Write(exportCSVHeader)
+	}
+	n := 0
+	err = a.store.ExportTransactions(ctx, id, from, to, func(t store.Transaction) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if cw != nil {
+			if err := cw.Write(a.transactionRecord(t)); err != nil {
+				return err
+			}
+		} else if err := enc.Encode(a.toTransaction(t)); err != nil {
+			return err
+		}
+		if n++; n%exportFlushRows == 0 {
+			if cw != nil {
+				cw.Flush()
+			}
+			return rc.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		if started {
+			// Headers and rows are out; only a broken connection tells the
+			// client the export is incomplete
+			a.logger.Printf("export transactions aborted: accountID=%d, rows=%d, error=%v", id, n, err)
+			panic(http.ErrAbortHandler)
+		}
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("export transactions failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	if !started {
+		_ = start()
+	}
+	if cw != nil {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			a.logger.Printf("export transactions: accountID=%d, error=%v", id, err)
+		}
+	}
+}
+
+// transactionRecord returns t as a CSV record in exportCSVHeader order
+func (a *API) transactionRecord(t store.Transaction) []string {
+	id, reversalOf := "", ""
+	if a.serialIDs {
+		id = strconv.FormatInt(t.ID, 10)
+		if t.ReversalOf != 0 {
+			reversalOf = strconv.FormatInt(t.ReversalOf, 10)
+		}
+	}
+	return []string{
+		id,
+		t.CreatedAt.UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(t.SourceAccountID, 10),
+		strconv.FormatInt(t.DestinationAccountID, 10),
+		t.Amount.String(),
+		t.Currency,
+		t.Status,
+		t.ErrorMessage,
+		reversalOf,
+		t.Reference,
+		t.PurposeCode,
+		t.Kind,
+		t.Reason,
+		t.UUID.String(),
+		t.InitiatedBy,
+		t.Channel,
+		t.RequestID,
+		publicID(t.ReversalOfUUID),
+		publicID(t.FeeOfUUID),
+	}
+}
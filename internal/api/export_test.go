@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// exportStore returns a store exporting two transactions of account 100
+func exportStore(t *testing.T) *MockStore {
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &MockStore{
+		ExportTxFunc: func(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error {
+			if accountID != 100 {
+				return store.ErrAccountNotFound
+			}
+			if !from.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || !to.IsZero() {
+				t.Fatalf("unexpected range: %v - %v", from, to)
+			}
+			for _, tx := range []store.Transaction{
+				{ID: 1, CreatedAt: created, SourceAccountID: 100, DestinationAccountID: 200, Amount: decimal.RequireFromString("10.50"), Currency: "USD", Status: store.StatusSucceeded,
+					TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057"), Reference: "INV-1, March",
+						InitiatedBy: "payments", Channel: store.ChannelHTTP, RequestID: "req-1"}, Kind: store.KindTransfer},
+				{ID: 2, CreatedAt: created.Add(time.Hour), SourceAccountID: 200, DestinationAccountID: 100, Amount: decimal.NewFromInt(3), Currency: "USD", Status: store.StatusFailed, ErrorMessage: "insufficient funds",
+					TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000002")}},
+			} {
+				if err := fn(tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// TestExportAccountTransactions_CSV tests the CSV export of an account's transactions
+func TestExportAccountTransactions_CSV(t *testing.T) {
+	api := New(exportStore(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/transactions/export?format=csv&from=2024-03-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV content type, got %q", ct)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "transaction_id" {
+		t.Fatalf("expected header and 2 rows, got %v", records)
+	}
+	if got := strings.Join(records[1], "|"); got != "|2024-03-01T12:00:00Z|100|200|10.5|USD|succeeded|||INV-1, March||transfer||01890a5d-ac96-774b-bcce-b302099a8057|payments|http|req-1||" {
+		t.Fatalf("unexpected first row: %s", got)
+	}
+	if records[2][7] != "insufficient funds" {
+		t.Fatalf("unexpected second row: %v", records[2])
+	}
+}
+
+// TestExportAccountTransactions_JSONL tests the JSON lines export
+func TestExportAccountTransactions_JSONL(t *testing.T) {
+	api := New(exportStore(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/transactions/export?format=jsonl&from=2024-03-01T00:00:00Z", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected JSON lines content type, got %q", ct)
+	}
+	var ids []string
+	sc := bufio.NewScanner(w.Body)
+	for sc.Scan() {
+		var tx model.Transaction
+		if err := json.Unmarshal(sc.Bytes(), &tx); err != nil {
+			t.Fatalf("failed to decode line %q: %v", sc.Text(), err)
+		}
+		ids = append(ids, tx.ID)
+	}
+	if len(ids) != 2 || ids[0] != "01890a5d-ac96-774b-bcce-b302099a8057" || ids[1] != "01890a5d-ac97-7000-8000-000000000002" {
+		t.Fatalf("expected transactions 1 and 2, got %v", ids)
+	}
+}
+
+// TestExportAccountTransactions_Errors tests validation and errors raised before streaming
+func TestExportAccountTransactions_Errors(t *testing.T) {
+	mockStore := &MockStore{
+		ExportTxFunc: func(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error {
+			if accountID == 999 {
+				return store.ErrAccountNotFound
+			}
+			return errors.New("connection refused")
+		},
+	}
+	api := New(mockStore)
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/v1/accounts/100/transactions/export?format=xml", http.StatusBadRequest},
+		{"/v1/accounts/100/transactions/export?from=yesterday", http.StatusBadRequest},
+		{"/v1/accounts/100/transactions/export?from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z", http.StatusBadRequest},
+		{"/v1/accounts/999/transactions/export", http.StatusNotFound},
+		{"/v1/accounts/100/transactions/export", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+
+		r := mux.NewRouter()
+		api.RegisterRoutes(r)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.wantCode, w.Code)
+		}
+	}
+}
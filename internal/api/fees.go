@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toFeePolicyResponse maps a stored fee policy to its JSON representation
+func toFeePolicyResponse(p store.FeePolicy) model.FeePolicyResponse {
+	return model.FeePolicyResponse{
+		FeePolicyID:  p.ID,
+		Name:         p.Name,
+		Priority:     p.Priority,
+		TenantID:     p.Tenant,
+		AccountTag:   p.AccountTag,
+		Currency:     p.Currency,
+		FlatFee:      model.DecimalString{Decimal: p.FlatFee},
+		Percentage:   model.DecimalString{Decimal: p.Percentage},
+		FeeAccountID: p.FeeAccountID,
+		CreatedAt:    p.CreatedAt,
+	}
+}
+
+// CreateFeePolicy adds a fee policy charging the transfers it matches
+func (a *API) CreateFeePolicy(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateFeePolicyRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	p := store.FeePolicy{
+		Name:         strings.TrimSpace(req.Name),
+		Priority:     req.Priority,
+		Tenant:       req.TenantID,
+		AccountTag:   req.AccountTag,
+		Currency:     req.Currency,
+		FeeAccountID: req.FeeAccountID,
+	}
+	if req.FlatFee != nil {
+		p.FlatFee = req.FlatFee.Decimal
+	}
+	if req.Percentage != nil {
+		p.Percentage = req.Percentage.Decimal
+	}
+	p, err := a.store.CreateFeePolicy(ctx, p)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeErrorDetails(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "fee account not found",
+				map[string]interface{}{"account_id": req.FeeAccountID, "field": "fee_account_id"})
+			return
+		case errors.Is(err, store.ErrCurrencyMismatch):
+			writeError(w, http.StatusUnprocessableEntity, model.ErrCodeCurrencyMismatch, "fee account is not in the policy's currency")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("create fee policy failed: name=%s, error=%v", req.Name, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toFeePolicyResponse(p))
+}
+
+// ListFeePolicies lists the fee policies in the order transfers are matched against them
+func (a *API) ListFeePolicies(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	policies, err := a.store.ListFeePolicies(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list fee policies failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.FeePolicyListResponse{FeePolicies: make([]model.FeePolicyResponse, 0, len(policies))}
+	for _, p := range policies {
+		resp.FeePolicies = append(resp.FeePolicies, toFeePolicyResponse(p))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteFeePolicy deletes a fee policy; the fees it charged are kept
+func (a *API) DeleteFeePolicy(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid fee policy id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.DeleteFeePolicy(ctx, id); err != nil {
+		if errors.Is(err, store.ErrFeePolicyNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeFeePolicyNotFound, "fee policy not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("delete fee policy failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateFeePolicy tests creating fee policies and their validation
+func TestCreateFeePolicy(t *testing.T) {
+	var got store.FeePolicy
+	mockStore := &MockStore{
+		CreateFeePolicyFunc: func(ctx context.Context, p store.FeePolicy) (store.FeePolicy, error) {
+			if p.FeeAccountID == 404 {
+				return store.FeePolicy{}, store.ErrAccountNotFound
+			}
+			if p.Currency == "EUR" {
+				return store.FeePolicy{}, store.ErrCurrencyMismatch
+			}
+			got = p
+			p.ID = 3
+			return p, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"name": "retail", "currency": "USD", "account_tag": "retail", "flat_fee": "0.25", "percentage": "1.5", "fee_account_id": 9}`, http.StatusCreated},
+		{`{"name": "retail", "currency": "USD", "fee_account_id": 9}`, http.StatusBadRequest},
+		{`{"name": "retail", "currency": "USD", "percentage": "100", "fee_account_id": 9}`, http.StatusBadRequest},
+		{`{"name": "retail", "currency": "USD", "flat_fee": "1"}`, http.StatusBadRequest},
+		{`{"name": "retail", "currency": "USD", "flat_fee": "1", "fee_account_id": 404}`, http.StatusNotFound},
+		{`{"name": "retail", "currency": "EUR", "flat_fee": "1", "fee_account_id": 9}`, http.StatusUnprocessableEntity},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/fee-policies", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusCreated {
+			continue
+		}
+		var resp model.FeePolicyResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.FeePolicyID != 3 || resp.FeeAccountID != 9 || resp.Percentage.String() != "1.5" {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+	}
+	if got.AccountTag != "retail" || !got.FlatFee.Equal(decimal.RequireFromString("0.25")) {
+		t.Fatalf("unexpected fee policy: %+v", got)
+	}
+}
+
+// TestDeleteFeePolicy tests deleting a fee policy
+func TestDeleteFeePolicy(t *testing.T) {
+	mockStore := &MockStore{
+		DeleteFeePolicyFunc: func(ctx context.Context, id int64) error {
+			if id != 3 {
+				return store.ErrFeePolicyNotFound
+			}
+			return nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	for path, want := range map[string]int{
+		"/v1/admin/fee-policies/3":   http.StatusNoContent,
+		"/v1/admin/fee-policies/4":   http.StatusNotFound,
+		"/v1/admin/fee-policies/abc": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		if w.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+}
@@ -6,42 +6,391 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"net/netip"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
 
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/clock"
 	"github.com/you/internal-transfers/internal/model"
 	"github.com/you/internal-transfers/internal/store"
 )
 
 // interface for store operations
 type StoreAPI interface {
-	CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal) error
-	GetAccount(ctx context.Context, accountID int64) (decimal.Decimal, error)
-	Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error
+	CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error
+	CreateAccountWithExternalID(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error)
+	CreateAccounts(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error)
+	ImportAccounts(ctx context.Context, accounts []store.AccountImport) error
+	AccountIDs(ctx context.Context, externalIDs []string) (map[string]int64, error)
+	AliasAccountIDs(ctx context.Context, aliases []string) (map[string]int64, error)
+	AddAccountAlias(ctx context.Context, accountID int64, alias string) (store.AccountAlias, bool, error)
+	RemoveAccountAlias(ctx context.Context, accountID int64, alias string) error
+	AccountAliases(ctx context.Context, accountID int64) ([]store.AccountAlias, error)
+	CreateWallet(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta store.AccountMetadata) (store.Account, error)
+	WalletIDs(ctx context.Context, refs []store.WalletRef) (map[store.WalletRef]int64, error)
+	Wallets(ctx context.Context, accountID int64) (store.Wallets, error)
+	GetAccount(ctx context.Context, accountID int64) (store.Account, error)
+	BalanceAt(ctx context.Context, accountID int64, at time.Time) (store.Balance, error)
+	UpdateAccountMetadata(ctx context.Context, accountID int64, upd store.MetadataUpdate) (store.Account, error)
+	UpdateAccountStatus(ctx context.Context, accountID int64, status string) (store.Account, error)
+	CloseAccount(ctx context.Context, accountID, sweepTo int64, details store.TransferDetails) (store.Account, store.Transaction, error)
+	SetAccountLimits(ctx context.Context, accountID int64, l store.TransferLimits) (store.Account, error)
+	DefaultLimits() store.TransferLimits
+	SetDefaultLimits(l store.TransferLimits)
+	SetAccountOwner(ctx context.Context, accountID int64, owner string) (store.Account, error)
+	SetMinBalance(ctx context.Context, accountID int64, min decimal.NullDecimal) (store.Account, error)
+	SetKYCStatus(ctx context.Context, accountID int64, status string) (store.Account, error)
+	DryRunTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error
+	NewTransactionID() (uuid.UUID, error)
+	TransactionID(ctx context.Context, uid uuid.UUID) (int64, error)
+	Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error)
+	EnqueueTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error)
+	GetTransaction(ctx context.Context, id int64) (store.Transaction, error)
+	ReverseTransaction(ctx context.Context, id int64) (store.Transaction, error)
+	TransferBatch(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error)
+	SplitTransfer(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error)
+	ListAccounts(ctx context.Context, f store.AccountFilter, after *int64, limit int) ([]store.Account, *int64, error)
+	ListTransactionsByAccount(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error)
+	SearchTransactions(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error)
+	ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error
+	GetStatement(ctx context.Context, accountID int64, period time.Time) (store.Statement, error)
+	AdjustBalance(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (store.Transaction, error)
+	AccountData(ctx context.Context, accountID int64) (store.AccountData, error)
+	EraseAccount(ctx context.Context, accountID int64, reason, erasedBy string) (store.AccountErasure, bool, error)
+	CreateHold(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error)
+	GetHold(ctx context.Context, id int64) (store.Hold, error)
+	CaptureHold(ctx context.Context, id int64) (store.Hold, error)
+	ReleaseHold(ctx context.Context, id int64) (store.Hold, error)
+	CreateScheduledTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (store.ScheduledTransfer, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (store.ScheduledTransfer, error)
+	CreateTransferJob(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error)
+	GetTransferJob(ctx context.Context, id int64) (store.TransferJob, error)
+	ListTransferJobFailures(ctx context.Context, jobID int64, limit int) ([]store.TransferJobRow, error)
+	CreateRule(ctx context.Context, r store.Rule) (store.Rule, error)
+	ListRules(ctx context.Context) ([]store.Rule, error)
+	DeleteRule(ctx context.Context, id int64) error
+	CreateFeePolicy(ctx context.Context, p store.FeePolicy) (store.FeePolicy, error)
+	ListFeePolicies(ctx context.Context) ([]store.FeePolicy, error)
+	DeleteFeePolicy(ctx context.Context, id int64) error
+	ClearingAccounts(ctx context.Context) ([]int64, error)
+	AddClearingAccount(ctx context.Context, accountID int64) error
+	RemoveClearingAccount(ctx context.Context, accountID int64) error
+	CreateClearingTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (store.ClearingTransfer, error)
+	SettlementReport(ctx context.Context, date time.Time) (store.SettlementReport, error)
+	CreateSweepRule(ctx context.Context, r store.SweepRule) (store.SweepRule, error)
+	ListSweepRules(ctx context.Context) ([]store.SweepRule, error)
+	UpdateSweepRule(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (store.SweepRule, error)
+	DeleteSweepRule(ctx context.Context, id int64) error
+	ListSweepExecutions(ctx context.Context, ruleID, cursor int64, limit int) ([]store.SweepExecution, int64, error)
+	CounterpartyAllowlist(ctx context.Context, accountID int64) (store.CounterpartyAllowlist, error)
+	SetCounterpartyAllowlist(ctx context.Context, l store.CounterpartyAllowlist) (store.CounterpartyAllowlist, error)
+	AlertThresholds(ctx context.Context, accountID int64) (store.AlertThresholds, error)
+	SetAlertThresholds(ctx context.Context, t store.AlertThresholds) (store.AlertThresholds, error)
+	ListAlerts(ctx context.Context, accountID int64, status string, cursor int64, limit int) ([]store.Alert, int64, error)
+	ResolveAlert(ctx context.Context, accountID, alertID int64) (store.Alert, error)
+	ListReviews(ctx context.Context, status string, cursor int64, limit int) ([]store.Review, int64, error)
+	ResolveReview(ctx context.Context, transactionID int64, status, reviewedBy string) (store.Review, error)
+	CreateAPIKey(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]store.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) error
+	SnapshotBalances(ctx context.Context) (store.SnapshotRun, error)
+	LatestReconciliation(ctx context.Context) (store.ReconciliationRun, error)
+	TrialBalance(ctx context.Context, from, to time.Time) (store.TrialBalance, error)
 }
 
+// Page sizes for list endpoints
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// DefaultRequestTimeout bounds the store calls of a request unless
+// WithTimeout is given.
+const DefaultRequestTimeout = 5 * time.Second
+
 // API holds the store and request timeout
 type API struct {
-	store      StoreAPI
-	reqTimeout time.Duration
+	store           StoreAPI
+	reqTimeout      time.Duration
+	transferTimeout time.Duration
+	writeTimeout    time.Duration // of the http.Server, 0 if unknown
+	authn           auth.Authenticator
+	limiter         *RateLimiter // never nil; its limit may be 0
+	network         NetworkPolicy
+	logger          *log.Logger
+	logLevel        string
+	inflight        sync.WaitGroup // application requests being served
+
+	maxBodyBytes    int64
+	maxJobFileBytes int64
+	maxImportBytes  int64
+	asyncTransfers  bool
+	serialIDs       bool // deprecated serial transaction ids are in responses
+
+	runtime   atomic.Pointer[model.RuntimeConfig]
+	runtimeMu sync.Mutex // serializes SetRuntimeConfig
+
+	transferSlots     chan struct{} // nil if unlimited
+	transferQueueWait time.Duration
+
+	statementRenderers map[string]StatementRenderer
+
+	beforeTransfer []BeforeTransferHook // the built-in checks first
+	afterTransfer  []AfterTransferHook
+
+	feed            TransferFeed  // nil if live updates are disabled
+	streamsDone     chan struct{} // closed by StopStreams
+	stopStreamsOnce sync.Once
+	wsSubscriptions int // accounts per WebSocket connection
+
+	seeder   Seeder     // nil unless POST /admin/seed is enabled
+	recorder *Recorder  // nil unless the capture mode is on
+	attempts AttemptLog // nil unless rejected transfers are recorded
+
+	clock clock.Clock
+}
+
+// Option configures an API
+type Option func(*API)
+
+// WithTimeout bounds the store calls of each request not moving money by d;
+// requests running out of time fail with 504. Non-positive values keep
+// DefaultRequestTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(a *API) {
+		if d > 0 {
+			a.reqTimeout = d
+		}
+	}
+}
+
+// WithLogger logs failed requests to l instead of the standard logger.
+func WithLogger(l *log.Logger) Option {
+	return func(a *API) {
+		a.logger = l
+	}
+}
+
+// WithAsyncTransfers enables or disables asynchronous transfers (enabled by
+// default). When disabled, Prefer: respond-async is ignored and transfers are
+// made synchronously.
+func WithAsyncTransfers(enabled bool) Option {
+	return func(a *API) {
+		a.asyncTransfers = enabled
+	}
+}
+
+// WithAuthenticator requires callers to authenticate and enforces per-route roles.
+// Without it every request is allowed.
+func WithAuthenticator(authn auth.Authenticator) Option {
+	return func(a *API) {
+		a.authn = authn
+	}
+}
+
+// WithRateLimiter throttles application routes per client.
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(a *API) {
+		a.limiter = l
+	}
+}
+
+// WithClock replaces the system clock, which is the present of hold expiry,
+// scheduled transfers and balance queries. Share it with the store to test
+// time-dependent behaviour with a clock.Fake.
+func WithClock(c clock.Clock) Option {
+	return func(a *API) {
+		a.clock = c
+	}
 }
 
 // New creates an API instance
-func New(s StoreAPI) *API {
-	return &API{
-		store:      s,
-		reqTimeout: 5 * time.Second,
+func New(s StoreAPI, opts ...Option) *API {
+	a := &API{
+		store:           s,
+		reqTimeout:      DefaultRequestTimeout,
+		transferTimeout: DefaultTransferTimeout,
+		logger:          log.Default(),
+		logLevel:        model.LogLevelInfo,
+
+		maxBodyBytes:    DefaultMaxBodyBytes,
+		maxJobFileBytes: DefaultMaxJobFileBytes,
+		maxImportBytes:  DefaultMaxImportBytes,
+		asyncTransfers:  true,
+
+		beforeTransfer:  append([]BeforeTransferHook(nil), builtinBeforeTransfer...),
+		streamsDone:     make(chan struct{}),
+		wsSubscriptions: DefaultWebSocketSubscriptions,
+
+		clock: clock.Real{},
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.limiter == nil {
+		a.limiter = NewRateLimiter(0, 0)
+	}
+	a.runtime.Store(a.initialRuntimeConfig())
+	return a
 }
 
-// RegisterRoutes registers HTTP routes onto the router.
+// RegisterRoutes mounts the application routes under the /v1 prefix.
 func (a *API) RegisterRoutes(r *mux.Router) {
-	r.HandleFunc("/accounts", a.CreateAccount).Methods(http.MethodPost)
-	r.HandleFunc("/accounts/{id}", a.GetAccount).Methods(http.MethodGet)
-	r.HandleFunc("/transactions", a.CreateTransaction).Methods(http.MethodPost)
+	// Full paths rather than a PathPrefix subrouter: a matching prefix makes mux
+	// forget an earlier method mismatch, turning 405s into 404s.
+	a.registerRoutes(func(path string, f func(http.ResponseWriter, *http.Request)) *mux.Route {
+		return r.Handle("/v1"+path, a.wrap(f))
+	})
+}
+
+// RegisterLegacyRoutes mounts the application routes without a version prefix.
+// Responses carry a Deprecation header; these routes are kept only until
+// existing consumers have moved to /v1.
+func (a *API) RegisterLegacyRoutes(r *mux.Router) {
+	a.registerRoutes(func(path string, f func(http.ResponseWriter, *http.Request)) *mux.Route {
+		return r.Handle(path, deprecationMiddleware(a.wrap(f)))
+	})
+}
+
+// wrap applies the API-wide middlewares to an application handler.
+func (a *API) wrap(f func(http.ResponseWriter, *http.Request)) http.Handler {
+	return requestID(a.capture(a.networkPolicy(a.track(callerDeadline(http.HandlerFunc(f))))))
+}
+
+// track counts the requests served by next as in flight, for Drain.
+func (a *API) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.inflight.Add(1)
+		defer a.inflight.Done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Drain waits until no application request is in flight, or until ctx is
+// done. Call it once the server no longer accepts requests, before closing
+// the store.
+func (a *API) Drain(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// routeFunc adds a handler for path; it matches mux.Router.HandleFunc.
+type routeFunc func(path string, f func(http.ResponseWriter, *http.Request)) *mux.Route
+
+// registerRoutes registers the application routes through handle.
+func (a *API) registerRoutes(handle routeFunc) {
+	handle("/accounts", a.authorize(auth.RoleService, a.CreateAccount)).Methods(http.MethodPost)
+	handle("/accounts", a.authorize(auth.RoleReadonly, a.ListAccounts)).Methods(http.MethodGet)
+	handle("/accounts/batch", a.authorize(auth.RoleService, a.CreateAccountBatch)).Methods(http.MethodPost)
+	handle("/accounts/{id}", a.authorize(auth.RoleReadonly, a.accountPath(a.GetAccount))).Methods(http.MethodGet)
+	handle("/accounts/{id}", a.authorize(auth.RoleService, a.accountPath(a.UpdateAccount))).Methods(http.MethodPatch)
+	handle("/accounts/{id}", a.authorize(auth.RoleService, a.PutAccount)).Methods(http.MethodPut)
+	handle("/accounts/{id}", a.authorize(auth.RoleAdmin, a.accountPath(a.DeleteAccount))).Methods(http.MethodDelete)
+	handle("/accounts/{id}/balance", a.authorize(auth.RoleReadonly, a.accountPath(a.GetAccountBalance))).Methods(http.MethodGet)
+	handle("/accounts/{id}/transactions", a.authorize(auth.RoleReadonly, a.accountPath(a.ListAccountTransactions))).Methods(http.MethodGet)
+	handle("/accounts/{id}/transactions/stream", a.authorize(auth.RoleReadonly, a.accountPath(a.StreamAccountTransactions))).Methods(http.MethodGet)
+	handle("/accounts/{id}/transactions/export", a.authorize(auth.RoleReadonly, a.accountPath(a.ExportAccountTransactions))).Methods(http.MethodGet)
+	handle("/accounts/{id}/statements/{period}", a.authorize(auth.RoleReadonly, a.accountPath(a.GetStatement))).Methods(http.MethodGet)
+	handle("/accounts/{id}/freeze", a.authorize(auth.RoleAdmin, a.accountPath(a.FreezeAccount))).Methods(http.MethodPost)
+	handle("/accounts/{id}/unfreeze", a.authorize(auth.RoleAdmin, a.accountPath(a.UnfreezeAccount))).Methods(http.MethodPost)
+	handle("/accounts/{id}/close", a.authorize(auth.RoleAdmin, a.accountPath(a.CloseAccount))).Methods(http.MethodPost)
+	handle("/accounts/{id}/limits", a.authorize(auth.RoleAdmin, a.accountPath(a.SetAccountLimits))).Methods(http.MethodPut)
+	handle("/accounts/{id}/owner", a.authorize(auth.RoleAdmin, a.accountPath(a.SetAccountOwner))).Methods(http.MethodPut)
+	handle("/accounts/{id}/min-balance", a.authorize(auth.RoleAdmin, a.accountPath(a.SetMinBalance))).Methods(http.MethodPut)
+	handle("/accounts/{id}/kyc", a.authorize(auth.RoleAdmin, a.accountPath(a.SetKYCStatus))).Methods(http.MethodPut)
+	handle("/accounts/{id}/wallets", a.authorize(auth.RoleService, a.accountPath(a.CreateWallet))).Methods(http.MethodPost)
+	handle("/accounts/{id}/aliases", a.authorize(auth.RoleReadonly, a.accountPath(a.ListAccountAliases))).Methods(http.MethodGet)
+	handle("/accounts/{id}/aliases/{alias}", a.authorize(auth.RoleAdmin, a.accountPath(a.AddAccountAlias))).Methods(http.MethodPut)
+	handle("/accounts/{id}/aliases/{alias}", a.authorize(auth.RoleAdmin, a.accountPath(a.RemoveAccountAlias))).Methods(http.MethodDelete)
+	handle("/accounts/{id}/wallets", a.authorize(auth.RoleReadonly, a.accountPath(a.ListWallets))).Methods(http.MethodGet)
+	handle("/accounts/{id}/counterparties", a.authorize(auth.RoleReadonly, a.accountPath(a.GetCounterparties))).Methods(http.MethodGet)
+	handle("/accounts/{id}/counterparties", a.authorize(auth.RoleAdmin, a.accountPath(a.SetCounterparties))).Methods(http.MethodPut)
+	handle("/accounts/{id}/alert-thresholds", a.authorize(auth.RoleReadonly, a.accountPath(a.GetAlertThresholds))).Methods(http.MethodGet)
+	handle("/accounts/{id}/alert-thresholds", a.authorize(auth.RoleAdmin, a.accountPath(a.SetAlertThresholds))).Methods(http.MethodPut)
+	handle("/accounts/{id}/alerts", a.authorize(auth.RoleReadonly, a.accountPath(a.ListAlerts))).Methods(http.MethodGet)
+	handle("/accounts/{id}/alerts/{alert_id}/resolve", a.authorize(auth.RoleService, a.accountPath(a.ResolveAlert))).Methods(http.MethodPost)
+	handle("/transactions", a.authorize(auth.RoleService, a.logRejections(TransferKindSingle, summarizeTransfer, a.limitTransfers(a.CreateTransaction)))).Methods(http.MethodPost)
+	handle("/transactions", a.authorize(auth.RoleReadonly, a.SearchTransactions)).Methods(http.MethodGet)
+	handle("/transactions/batch", a.authorize(auth.RoleService, a.logRejections(TransferKindBatch, summarizeBatch, a.limitTransfers(a.CreateTransactionBatch)))).Methods(http.MethodPost)
+	handle("/transactions/split", a.authorize(auth.RoleService, a.logRejections(TransferKindSplit, summarizeSplit, a.limitTransfers(a.CreateSplitTransfer)))).Methods(http.MethodPost)
+	handle("/transactions/{id}", a.authorize(auth.RoleReadonly, a.transactionPath(a.GetTransaction))).Methods(http.MethodGet)
+	handle("/transactions/{id}/reverse", a.authorize(auth.RoleAdmin, a.limitTransfers(a.transactionPath(a.ReverseTransaction)))).Methods(http.MethodPost)
+	handle("/ws", a.authorize(auth.RoleReadonly, a.ServeWebSocket)).Methods(http.MethodGet)
+	handle("/transfers/scheduled", a.authorize(auth.RoleService, a.logRejections(TransferKindScheduled, summarizeTransfer, a.CreateScheduledTransfer))).Methods(http.MethodPost)
+	handle("/transfers/scheduled/{id}", a.authorize(auth.RoleReadonly, a.GetScheduledTransfer)).Methods(http.MethodGet)
+	handle("/jobs/transfers", a.authorize(auth.RoleService, a.logRejections(TransferKindJob, summarizeJob, a.CreateTransferJob))).Methods(http.MethodPost)
+	handle("/jobs/{id}", a.authorize(auth.RoleReadonly, a.GetTransferJob)).Methods(http.MethodGet)
+	handle("/holds", a.authorize(auth.RoleService, a.logRejections(TransferKindHold, summarizeTransfer, a.CreateHold))).Methods(http.MethodPost)
+	handle("/holds/{id}", a.authorize(auth.RoleReadonly, a.GetHold)).Methods(http.MethodGet)
+	handle("/holds/{id}/capture", a.authorize(auth.RoleService, a.limitTransfers(a.CaptureHold))).Methods(http.MethodPost)
+	handle("/holds/{id}/release", a.authorize(auth.RoleService, a.ReleaseHold)).Methods(http.MethodPost)
+	handle("/clearing/transfers", a.authorize(auth.RoleService, a.logRejections(TransferKindClearing, summarizeTransfer, a.CreateClearingTransfer))).Methods(http.MethodPost)
+	handle("/purpose-codes", a.authorize(auth.RoleReadonly, a.ListPurposeCodes)).Methods(http.MethodGet)
+
+	handle("/admin/apikeys", a.authorize(auth.RoleAdmin, a.CreateAPIKey)).Methods(http.MethodPost)
+	handle("/admin/apikeys", a.authorize(auth.RoleAdmin, a.ListAPIKeys)).Methods(http.MethodGet)
+	handle("/admin/apikeys/{id}", a.authorize(auth.RoleAdmin, a.RevokeAPIKey)).Methods(http.MethodDelete)
+	if a.recorder != nil {
+		handle(capturesPath, a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListCaptures))).Methods(http.MethodGet)
+		handle(capturesPath, a.authorize(auth.RoleAdmin, a.deploymentWide(a.ClearCaptures))).Methods(http.MethodDelete)
+	}
+	if a.attempts != nil {
+		handle("/admin/transfer-attempts", a.authorize(auth.RoleAdmin, a.ListTransferAttempts)).Methods(http.MethodGet)
+	}
+	handle("/admin/balance-snapshots", a.authorize(auth.RoleAdmin, a.deploymentWide(a.SnapshotBalances))).Methods(http.MethodPost)
+	handle("/admin/reconciliation/latest", a.authorize(auth.RoleAdmin, a.deploymentWide(a.LatestReconciliation))).Methods(http.MethodGet)
+	handle("/admin/reports/trial-balance", a.authorize(auth.RoleAdmin, a.deploymentWide(a.TrialBalance))).Methods(http.MethodGet)
+	handle("/admin/accounts/import", a.authorize(auth.RoleAdmin, a.ImportAccounts)).Methods(http.MethodPost)
+	handle("/admin/accounts/{id}/adjustments", a.authorize(auth.RoleAdmin, a.limitTransfers(a.accountPath(a.AdjustBalance)))).Methods(http.MethodPost)
+	handle("/admin/accounts/{id}/data-export", a.authorize(auth.RoleAdmin, a.accountPath(a.ExportAccountData))).Methods(http.MethodGet)
+	handle("/admin/accounts/{id}/erasure", a.authorize(auth.RoleAdmin, a.accountPath(a.EraseAccount))).Methods(http.MethodPost)
+	handle("/admin/config", a.authorize(auth.RoleAdmin, a.deploymentWide(a.GetRuntimeConfig))).Methods(http.MethodGet)
+	handle("/admin/config", a.authorize(auth.RoleAdmin, a.deploymentWide(a.UpdateRuntimeConfig))).Methods(http.MethodPut)
+	handle("/admin/rules", a.authorize(auth.RoleAdmin, a.deploymentWide(a.CreateRule))).Methods(http.MethodPost)
+	if a.seeder != nil {
+		handle("/admin/seed", a.authorize(auth.RoleAdmin, a.Seed)).Methods(http.MethodPost)
+	}
+	handle("/admin/rules", a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListRules))).Methods(http.MethodGet)
+	handle("/admin/rules/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.DeleteRule))).Methods(http.MethodDelete)
+	handle("/admin/fee-policies", a.authorize(auth.RoleAdmin, a.deploymentWide(a.CreateFeePolicy))).Methods(http.MethodPost)
+	handle("/admin/fee-policies", a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListFeePolicies))).Methods(http.MethodGet)
+	handle("/admin/fee-policies/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.DeleteFeePolicy))).Methods(http.MethodDelete)
+	handle("/admin/clearing-accounts", a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListClearingAccounts))).Methods(http.MethodGet)
+	handle("/admin/clearing-accounts/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.accountPath(a.AddClearingAccount)))).Methods(http.MethodPut)
+	handle("/admin/clearing-accounts/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.accountPath(a.RemoveClearingAccount)))).Methods(http.MethodDelete)
+	handle("/admin/settlements", a.authorize(auth.RoleAdmin, a.deploymentWide(a.SettlementReport))).Methods(http.MethodGet)
+	handle("/admin/sweep-rules", a.authorize(auth.RoleAdmin, a.deploymentWide(a.CreateSweepRule))).Methods(http.MethodPost)
+	handle("/admin/sweep-rules", a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListSweepRules))).Methods(http.MethodGet)
+	handle("/admin/sweep-rules/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.UpdateSweepRule))).Methods(http.MethodPut)
+	handle("/admin/sweep-rules/{id}", a.authorize(auth.RoleAdmin, a.deploymentWide(a.DeleteSweepRule))).Methods(http.MethodDelete)
+	handle("/admin/sweep-rules/{id}/executions", a.authorize(auth.RoleAdmin, a.deploymentWide(a.ListSweepExecutions))).Methods(http.MethodGet)
+	handle("/admin/reviews", a.authorize(auth.RoleAdmin, a.ListReviews)).Methods(http.MethodGet)
+	handle("/admin/reviews/{id}/approve", a.authorize(auth.RoleAdmin, a.transactionPath(a.ApproveReview))).Methods(http.MethodPost)
+	handle("/admin/reviews/{id}/reject", a.authorize(auth.RoleAdmin, a.transactionPath(a.RejectReview))).Methods(http.MethodPost)
+}
+
+// ListPurposeCodes returns the purpose codes transfers may carry, null if any
+// well-formed code is accepted
+func (a *API) ListPurposeCodes(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, model.PurposeCodesResponse{PurposeCodes: model.PurposeCodes})
 }
 
 // writeJSON writes a JSON response with proper headers
@@ -58,86 +407,1133 @@ func writeJSON(w http.ResponseWriter, code int, v interface{}) {
 // CreateAccount creates a new account
 func (a *API) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	var req model.CreateAccountRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
 	if err := req.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), a.reqTimeout)
+	ctx, cancel := a.requestContext(r)
 	defer cancel()
 
-	if err := a.store.CreateAccount(ctx, req.AccountID, req.InitialBalance.Decimal); err != nil {
-		log.Printf("create account failed: accountID=%d, error=%v", req.AccountID, err)
-		http.Error(w, "failed to create account", http.StatusInternalServerError)
+	if err := a.createAccount(ctx, req); err != nil {
+		switch {
+		case errors.Is(err, errNotCallersAccount):
+			writeError(w, http.StatusForbidden, model.ErrCodeForbidden, err.Error())
+		case errors.Is(err, store.ErrAccountExists):
+			writeError(w, http.StatusConflict, model.ErrCodeDuplicateAccount, "account already exists")
+		default:
+			a.logger.Printf("create account failed: accountID=%d, externalID=%s, error=%v", req.AccountID, req.ExternalID, err)
+			writeError(w, http.StatusInternalServerError, model.ErrCodeInternal, "failed to create account")
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
+// errNotCallersAccount is returned by createAccount for an account owned by
+// someone else than a caller restricted to their own accounts
+var errNotCallersAccount = errors.New("accounts can only be created for the caller")
+
+// createAccount creates the account of a validated req on behalf of the caller
+// of ctx. It returns store.ErrAccountExists if its id or external id is taken.
+func (a *API) createAccount(ctx context.Context, req model.CreateAccountRequest) error {
+	// Callers restricted to their own accounts can only create accounts for themselves
+	acc, ok := toAccountImport(ctx, req)
+	if !ok {
+		return errNotCallersAccount
+	}
+	ctx = store.WithOwner(ctx, acc.OwnerID)
+	if acc.ExternalID != "" {
+		_, err := a.store.CreateAccountWithExternalID(ctx, acc.ExternalID, acc.AccountID, acc.Initial, acc.Currency, acc.Metadata)
+		return err
+	}
+	return a.store.CreateAccount(ctx, acc.AccountID, acc.Initial, acc.Currency, acc.Metadata)
+}
+
+// PutAccount creates the account of the path with the state of the body and
+// answers 201 with it, or answers 200 with the account if it already exists,
+// so that provisioning can be retried without checking for it first. An
+// existing account opened with another initial balance or currency, or whose
+// id or external id is taken by an account the caller cannot see, is a 409.
+func (a *API) PutAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.CreateAccountRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if req.AccountID != 0 && req.AccountID != id {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "account_id must match the path")
+		return
+	}
+	req.AccountID = id
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	status := http.StatusCreated
+	if err := a.createAccount(ctx, req); err != nil {
+		switch {
+		case errors.Is(err, errNotCallersAccount):
+			writeError(w, http.StatusForbidden, model.ErrCodeForbidden, err.Error())
+			return
+		case !errors.Is(err, store.ErrAccountExists):
+			a.logger.Printf("put account failed: accountID=%d, externalID=%s, error=%v", id, req.ExternalID, err)
+			writeError(w, http.StatusInternalServerError, model.ErrCodeInternal, "failed to create account")
+			return
+		}
+		status = http.StatusOK
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	acc, err := a.store.GetAccount(ctx, id)
+	existing := status == http.StatusOK
+	switch {
+	case existing && errors.Is(err, store.ErrAccountNotFound),
+		existing && err == nil && req.ExternalID != "" && acc.ExternalID != req.ExternalID:
+		writeError(w, http.StatusConflict, model.ErrCodeDuplicateAccount, "account id or external id already taken")
+	case err != nil:
+		a.logger.Printf("get account failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+	case existing && (!acc.InitialBalance.Equal(req.InitialBalance.Decimal) || acc.Currency != currency):
+		writeErrorDetails(w, http.StatusConflict, model.ErrCodeDuplicateAccount, "account already exists with another initial balance or currency",
+			map[string]interface{}{"initial_balance": model.DecimalString{Decimal: acc.InitialBalance}, "currency": acc.Currency})
+	default:
+		writeJSON(w, status, toAccountResponse(acc))
+	}
+}
+
+// CreateAccountBatch creates the accounts of a batch, each independently:
+// invalid accounts and the ones whose id or external id is taken are reported
+// in their result, and the others created together.
+func (a *API) CreateAccountBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchCreateAccountRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	resp := model.BatchCreateAccountResponse{Results: make([]model.BatchAccountResult, len(req.Accounts))}
+	var accounts []store.AccountImport
+	var indexes []int // of accounts in the request
+	for i := range req.Accounts {
+		resp.Results[i] = model.BatchAccountResult{Index: i, Status: model.BatchAccountInvalid}
+		if err := req.Accounts[i].Validate(); err != nil {
+			resp.Results[i].Error = &model.ErrorResponse{Code: model.ErrCodeValidationFailed, Message: err.Error()}
+			continue
+		}
+		acc, ok := toAccountImport(ctx, req.Accounts[i])
+		if !ok {
+			resp.Results[i].Error = &model.ErrorResponse{Code: model.ErrCodeForbidden, Message: "accounts can only be created for the caller"}
+			continue
+		}
+		accounts = append(accounts, acc)
+		indexes = append(indexes, i)
+	}
+
+	if len(accounts) > 0 {
+		results, err := a.store.CreateAccounts(ctx, accounts)
+		if err != nil {
+			a.logger.Printf("create account batch failed: size=%d, error=%v", len(accounts), err)
+			internalError(w, err)
+			return
+		}
+		for j, res := range results {
+			result := &resp.Results[indexes[j]]
+			if res.Err != nil {
+				result.Status = model.BatchAccountDuplicate
+				result.Error = &model.ErrorResponse{Code: model.ErrCodeDuplicateAccount, Message: "account already exists"}
+				continue
+			}
+			result.Status = model.BatchAccountCreated
+			result.AccountID = res.AccountID
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // GetAccount retrieves account balance by ID
 func (a *API) GetAccount(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		http.Error(w, "invalid account id", http.StatusBadRequest)
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	acc, err := a.store.GetAccount(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("get account failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// GetAccountBalance returns the balance of an account as of ?at= (an RFC 3339
+// timestamp), reconstructed from its transaction history; at defaults to now.
+func (a *API) GetAccountBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	at := a.clock.Now()
+	if s := r.URL.Query().Get("at"); s != "" {
+		if at, err = time.Parse(time.RFC3339Nano, s); err != nil {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "at must be an RFC 3339 timestamp", map[string]interface{}{"parameter": "at"})
+			return
+		}
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	b, err := a.store.BalanceAt(ctx, id, at)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if errors.Is(err, store.ErrHistoryArchived) {
+			writeErrorDetails(w, http.StatusGone, model.ErrCodeHistoryArchived, "the transactions since this time have been archived", map[string]interface{}{"parameter": "at"})
+			return
+		}
+		a.logger.Printf("get balance failed: accountID=%d, at=%s, error=%v", id, at.Format(time.RFC3339), err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, model.BalanceResponse{
+		AccountID: b.AccountID,
+		Balance:   model.DecimalString{Decimal: b.Amount},
+		Currency:  b.Currency,
+		At:        b.At,
+	})
+}
+
+// UpdateAccount changes the metadata of an account
+func (a *API) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.UpdateAccountRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	upd := store.MetadataUpdate{DisplayName: req.DisplayName, OwnerRef: req.OwnerRef, Tags: req.Tags}
+	acc, err := a.store.UpdateAccountMetadata(ctx, id, upd)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("update account failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// toAccountResponse maps a stored account to its JSON representation
+func toAccountResponse(acc store.Account) model.AccountResponse {
+	tags := acc.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return model.AccountResponse{
+		AccountID:        acc.ID,
+		Balance:          model.DecimalString{Decimal: acc.Balance},
+		AvailableBalance: model.DecimalString{Decimal: acc.AvailableBalance},
+		Currency:         acc.Currency,
+		Status:           acc.Status,
+		KYCStatus:        acc.KYCStatus,
+		TenantID:         acc.Tenant,
+		OwnerID:          acc.OwnerID,
+		ExternalID:       acc.ExternalID,
+		DisplayName:      acc.DisplayName,
+		OwnerRef:         acc.OwnerRef,
+		Tags:             tags,
+		Limits:           toTransferLimits(acc.Limits),
+		MinBalance:       toDecimalString(acc.MinBalance),
+		ParentAccountID:  acc.ParentID,
+		Wallet:           acc.Wallet,
+	}
+}
+
+// toTransferLimits maps the account's own limits to JSON, or nil if it has none
+func toTransferLimits(l store.TransferLimits) *model.TransferLimits {
+	if !l.MaxAmount.Valid && !l.Daily.Valid {
+		return nil
+	}
+	var out model.TransferLimits
+	if l.MaxAmount.Valid {
+		out.MaxTransferAmount = &model.DecimalString{Decimal: l.MaxAmount.Decimal}
+	}
+	if l.Daily.Valid {
+		out.DailyTransferLimit = &model.DecimalString{Decimal: l.Daily.Decimal}
+	}
+	return &out
+}
+
+// SetAccountLimits replaces the transfer limits of an account
+func (a *API) SetAccountLimits(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.TransferLimits
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	acc, err := a.store.SetAccountLimits(ctx, id, storeLimits(req))
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("set account limits failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// toDecimalString maps an optional amount to JSON, or nil if it is unset
+func toDecimalString(d decimal.NullDecimal) *model.DecimalString {
+	if !d.Valid {
+		return nil
+	}
+	return &model.DecimalString{Decimal: d.Decimal}
+}
+
+// SetMinBalance sets or removes the minimum balance of an account
+func (a *API) SetMinBalance(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.SetMinBalanceRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	var min decimal.NullDecimal
+	if req.MinBalance != nil {
+		min = decimal.NewNullDecimal(req.MinBalance.Decimal)
+	}
+	acc, err := a.store.SetMinBalance(ctx, id, min)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("set min balance failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// SetKYCStatus sets the KYC status of an account
+func (a *API) SetKYCStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.SetKYCStatusRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	acc, err := a.store.SetKYCStatus(ctx, id, req.KYCStatus)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("set kyc status failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// SetAccountOwner hands an account over to another owner
+func (a *API) SetAccountOwner(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.SetOwnerRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), a.reqTimeout)
+	ctx, cancel := a.requestContext(r)
 	defer cancel()
 
-	bal, err := a.store.GetAccount(ctx, id)
+	acc, err := a.store.SetAccountOwner(ctx, id, req.OwnerID)
 	if err != nil {
 		if errors.Is(err, store.ErrAccountNotFound) {
-			http.Error(w, "account not found", http.StatusNotFound)
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("set account owner failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// FreezeAccount blocks an active account from sending and receiving transfers
+func (a *API) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	a.updateAccountStatus(w, r, store.AccountFrozen)
+}
+
+// UnfreezeAccount reactivates a frozen account
+func (a *API) UnfreezeAccount(w http.ResponseWriter, r *http.Request) {
+	a.updateAccountStatus(w, r, store.AccountActive)
+}
+
+// CloseAccount permanently closes an account
+func (a *API) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	a.updateAccountStatus(w, r, store.AccountClosed)
+}
+
+// DeleteAccount closes the account in the path, which is kept with its history.
+// Its balance must be zero unless the sweep_to query parameter names an
+// account to transfer it to first, in the same database transaction.
+func (a *API) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	var sweepTo int64
+	if s := r.URL.Query().Get("sweep_to"); s != "" {
+		ref, err := model.ParseAccountID(s)
+		if err != nil || ref.ExternalID == "" && ref.ID <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "sweep_to must be an account id or external id", map[string]interface{}{"parameter": "sweep_to"})
+			return
+		}
+		ids, err := a.resolveAccountIDs(ctx, ref)
+		if err != nil {
+			a.logger.Printf("resolve sweep account failed: sweepTo=%s, error=%v", s, err)
+			internalError(w, err)
+			return
+		}
+		if sweepTo = ids[0]; sweepTo == 0 {
+			writeErrorDetails(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "sweep account not found", map[string]interface{}{"parameter": "sweep_to"})
 			return
 		}
-		log.Printf("get account failed: accountID=%d, error=%v", id, err)
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+
+	acc, sweep, err := a.store.CloseAccount(ctx, id, sweepTo, transferDetails(r, model.TransactionRequest{}, store.ChannelHTTP))
+	if err != nil {
+		var notFoundErr *store.AccountNotFoundError
+		switch {
+		case errors.As(err, &notFoundErr):
+			writeErrorDetails(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "sweep account not found",
+				map[string]interface{}{"account_id": notFoundErr.AccountID, "parameter": "sweep_to"})
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		case errors.Is(err, store.ErrSameAccount):
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "sweep_to must be another account", map[string]interface{}{"parameter": "sweep_to"})
+		case errors.Is(err, store.ErrInvalidTransition):
+			writeError(w, http.StatusConflict, model.ErrCodeInvalidTransition, "account is already closed")
+		case errors.Is(err, store.ErrAccountNotEmpty):
+			writeError(w, http.StatusConflict, model.ErrCodeAccountNotEmpty, "account has pending holds, or a balance and no sweep_to account")
+		default:
+			status, resp, ok := transferError(err)
+			if !ok {
+				a.logger.Printf("close account failed: accountID=%d, sweepTo=%d, error=%v", id, sweepTo, err)
+				internalError(w, err)
+				return
+			}
+			writeJSON(w, status, resp)
+		}
 		return
 	}
 
-	resp := model.AccountResponse{
-		AccountID: id,
-		Balance:   model.DecimalString{Decimal: bal},
+	resp := model.CloseAccountResponse{Account: toAccountResponse(acc)}
+	if sweep.ID != 0 {
+		t := a.toTransaction(sweep)
+		resp.Sweep = &t
 	}
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// CreateTransaction transfers money between accounts
+// updateAccountStatus moves the account in the path to status and returns its new state
+func (a *API) updateAccountStatus(w http.ResponseWriter, r *http.Request, status string) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	acc, err := a.store.UpdateAccountStatus(ctx, id, status)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		case errors.Is(err, store.ErrInvalidTransition):
+			writeError(w, http.StatusConflict, model.ErrCodeInvalidTransition, "account cannot be moved to status "+status)
+		default:
+			a.logger.Printf("update account status failed: accountID=%d, status=%s, error=%v", id, status, err)
+			internalError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(acc))
+}
+
+// CreateTransaction transfers money between accounts. With Prefer: respond-async
+// the transfer is only queued and 202 is returned; poll GET /transactions/{id}.
+// A transfer matching a require_approval rule is recorded pending and also
+// answered with 202; it is executed once an admin approves its review.
 func (a *API) CreateTransaction(w http.ResponseWriter, r *http.Request) {
-	var req model.TransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid JSON", http.StatusBadRequest)
+	var req model.CreateTransactionRequest
+	if !a.decodeJSON(w, r, &req) {
 		return
 	}
 	if err := req.Validate(); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "dry_run must be a boolean", map[string]interface{}{"parameter": "dry_run"})
+			return
+		}
+		req.DryRun = req.DryRun || v
+	}
+
+	details := transferDetails(r, req.TransactionRequest, store.ChannelHTTP)
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	src, dst, ok := a.transferAccounts(ctx, w, req.SourceAccountID, req.DestinationAccountID)
+	if !ok {
+		return
+	}
+	treq := TransferRequest{
+		Kind:                 TransferKindSingle,
+		SourceAccountID:      src,
+		DestinationAccountID: dst,
+		Amount:               req.Amount.Decimal,
+		Details:              details,
+		DryRun:               req.DryRun,
+	}
+	if !a.allowTransfer(ctx, w, treq) {
+		return
+	}
+
+	// A dry run answers like the transfer would, without a transaction id
+	if req.DryRun {
+		err := a.store.DryRunTransfer(ctx, src, dst, req.Amount.Decimal, details)
+		if errors.Is(err, store.ErrAwaitingApproval) {
+			writeJSON(w, http.StatusOK, model.TransactionResponse{Status: store.StatusPending, DryRun: true})
+			return
+		}
+		if err != nil {
+			status, resp, ok := transferError(err)
+			if !ok {
+				a.logger.Printf("dry-run transfer failed: src=%d, dst=%d, amount=%s, error=%v",
+					src, dst, req.Amount.String(), err)
+			}
+			writeJSON(w, status, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, model.TransactionResponse{Status: store.StatusSucceeded, DryRun: true})
+		return
+	}
+
+	// The preference is only a hint: a store without a queue transfers synchronously
+	if a.Runtime().AsyncTransfers && prefersAsync(r) {
+		t, err := a.store.EnqueueTransfer(ctx, src, dst, req.Amount.Decimal, details)
+		if !errors.Is(err, store.ErrNotSupported) {
+			if err != nil {
+				a.transferDone(ctx, TransferResult{TransferRequest: treq, Status: store.StatusFailed, Err: err})
+				status, resp, ok := transferError(err)
+				if !ok {
+					a.logger.Printf("enqueue transfer failed: src=%d, dst=%d, amount=%s, error=%v",
+						src, dst, req.Amount.String(), err)
+				}
+				writeJSON(w, status, resp)
+				return
+			}
+			a.transferDone(ctx, TransferResult{TransferRequest: treq, ID: t.UUID, TransactionID: t.ID, Status: t.Status})
+			w.Header().Set("Preference-Applied", preferRespondAsync)
+			writeJSON(w, http.StatusAccepted, model.TransactionResponse{ID: t.UUID.String(), TransactionID: a.serialID(t.ID), Status: t.Status})
+			return
+		}
+	}
+
+	// The id is drawn up front so that a retried transfer keeps it
+	uid, err := a.store.NewTransactionID()
+	if err != nil {
+		a.logger.Printf("transfer failed: src=%d, dst=%d, error=%v", src, dst, err)
+		internalError(w, err)
+		return
+	}
+	details.UUID = uid
+	treq.Details = details
+	txID, err := a.store.Transfer(ctx, src, dst, req.Amount.Decimal, details)
+	if errors.Is(err, store.ErrAwaitingApproval) {
+		a.transferDone(ctx, TransferResult{TransferRequest: treq, ID: uid, TransactionID: txID, Status: store.StatusPending})
+		writeJSON(w, http.StatusAccepted, model.TransactionResponse{ID: uid.String(), TransactionID: a.serialID(txID), Status: store.StatusPending})
+		return
+	}
+	if err != nil {
+		a.transferDone(ctx, TransferResult{TransferRequest: treq, ID: uid, Status: store.StatusFailed, Err: err})
+		status, resp, ok := transferError(err)
+		if !ok {
+			a.logger.Printf("transfer failed: src=%d, dst=%d, amount=%s, error=%v",
+				src, dst, req.Amount.String(), err)
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+
+	a.transferDone(ctx, TransferResult{TransferRequest: treq, ID: uid, TransactionID: txID, Status: store.StatusSucceeded})
+
+	resp := model.TransactionResponse{
+		ID:            details.UUID.String(),
+		TransactionID: a.serialID(txID),
+		Status:        store.StatusSucceeded,
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// preferRespondAsync is the Prefer header token (RFC 7240) requesting an asynchronous transfer
+const preferRespondAsync = "respond-async"
+
+// prefersAsync reports whether the request carries Prefer: respond-async
+func prefersAsync(r *http.Request) bool {
+	for _, v := range r.Header.Values("Prefer") {
+		for _, pref := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(pref), preferRespondAsync) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetTransaction returns a transaction by id; asynchronous transfers move
+// from pending to succeeded or failed once executed
+func (a *API) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseTransactionID(w, r)
+	if !ok {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), a.reqTimeout)
+	ctx, cancel := a.requestContext(r)
 	defer cancel()
 
-	if err := a.store.Transfer(ctx, req.SourceAccountID, req.DestinationAccountID, req.Amount.Decimal); err != nil {
+	t, err := a.store.GetTransaction(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrTransactionNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeTransactionNotFound, "transaction not found")
+			return
+		}
+		a.logger.Printf("get transaction failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toTransaction(t))
+}
+
+// ReverseTransaction moves the amount of a succeeded transaction back to its
+// source with a new transaction linked to the original
+func (a *API) ReverseTransaction(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseTransactionID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	rev, err := a.store.ReverseTransaction(ctx, id)
+	if err != nil {
 		switch {
-		case errors.Is(err, store.ErrAccountNotFound):
-			http.Error(w, "account not found", http.StatusNotFound)
-		case errors.Is(err, store.ErrInsufficientFunds):
-			http.Error(w, "insufficient funds", http.StatusConflict)
+		case errors.Is(err, store.ErrTransactionNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeTransactionNotFound, "transaction not found")
+		case errors.Is(err, store.ErrAlreadyReversed):
+			writeError(w, http.StatusConflict, model.ErrCodeAlreadyReversed, "transaction has already been reversed")
+		case errors.Is(err, store.ErrNotReversible):
+			writeError(w, http.StatusConflict, model.ErrCodeNotReversible, "only succeeded transfers can be reversed")
 		default:
-			log.Printf("transfer failed: src=%d, dst=%d, amount=%s, error=%v",
-				req.SourceAccountID, req.DestinationAccountID, req.Amount.String(), err)
-			http.Error(w, "internal error", http.StatusInternalServerError)
+			status, resp, ok := transferError(err)
+			if !ok {
+				a.logger.Printf("reverse transaction failed: id=%d, error=%v", id, err)
+			}
+			writeJSON(w, status, resp)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a.toTransaction(rev))
+}
+
+// parseTransactionID reads the transaction id from the path, writing a 400 response if it is invalid
+func parseTransactionID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid transaction id", map[string]interface{}{"parameter": "id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// toTransaction maps a stored transaction to its JSON representation
+func (a *API) toTransaction(t store.Transaction) model.Transaction {
+	return model.Transaction{
+		ID:                   t.UUID.String(),
+		TransactionID:        a.serialID(t.ID),
+		SourceAccountID:      t.SourceAccountID,
+		DestinationAccountID: t.DestinationAccountID,
+		Amount:               model.DecimalString{Decimal: t.Amount},
+		Currency:             t.Currency,
+		Status:               t.Status,
+		ErrorMessage:         t.ErrorMessage,
+		ReversalOfID:         publicID(t.ReversalOfUUID),
+		ReversalOf:           a.serialID(t.ReversalOf),
+		FeeOfID:              publicID(t.FeeOfUUID),
+		FeeOf:                a.serialID(t.FeeOf),
+		TransferGroupID:      transferGroupID(t.GroupID),
+		Reference:            t.Reference,
+		PurposeCode:          t.PurposeCode,
+		Kind:                 t.Kind,
+		Reason:               t.Reason,
+		AdjustedBy:           t.AdjustedBy,
+		InitiatedBy:          t.InitiatedBy,
+		Channel:              t.Channel,
+		RequestID:            t.RequestID,
+		CreatedAt:            t.CreatedAt,
+	}
+}
+
+// transferDetails returns the details of req, made through channel, to record
+// with the transfer, along with the caller and the id of r.
+func transferDetails(r *http.Request, req model.TransactionRequest, channel string) store.TransferDetails {
+	p, _ := auth.FromContext(r.Context())
+	return store.TransferDetails{
+		Reference:   req.Reference,
+		PurposeCode: req.PurposeCode,
+		InitiatedBy: p.Subject,
+		Channel:     channel,
+		RequestID:   RequestIDFromContext(r.Context()),
+	}
+}
+
+// CreateTransactionBatch performs several transfers in one request. In atomic
+// mode a failing transfer aborts the batch with that transfer's error; in
+// best_effort mode every transfer reports its own outcome.
+func (a *API) CreateTransactionBatch(w http.ResponseWriter, r *http.Request) {
+	var req model.BatchTransferRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	// Unknown external ids resolve to 0 and fail like unknown accounts
+	refs := make([]model.AccountID, 0, 2*len(req.Transfers))
+	for _, t := range req.Transfers {
+		refs = append(refs, t.SourceAccountID, t.DestinationAccountID)
+	}
+	ids, err := a.resolveAccountIDs(ctx, refs...)
+	if err != nil {
+		a.logger.Printf("resolve batch accounts failed: size=%d, error=%v", len(req.Transfers), err)
+		internalError(w, err)
+		return
+	}
+	items := make([]store.TransferItem, len(req.Transfers))
+	treqs := make([]TransferRequest, len(req.Transfers))
+	for i, t := range req.Transfers {
+		items[i] = store.TransferItem{
+			SourceAccountID:      ids[2*i],
+			DestinationAccountID: ids[2*i+1],
+			Amount:               t.Amount.Decimal,
+			TransferDetails:      transferDetails(r, t, store.ChannelBatch),
+		}
+		treqs[i] = TransferRequest{
+			Kind:                 TransferKindBatch,
+			SourceAccountID:      items[i].SourceAccountID,
+			DestinationAccountID: items[i].DestinationAccountID,
+			Amount:               items[i].Amount,
+			Details:              items[i].TransferDetails,
+		}
+		if !a.allowBatchItem(ctx, w, treqs[i], "transfers[%d]", i, "index") {
+			return
+		}
+	}
+
+	results, err := a.store.TransferBatch(ctx, items, req.Mode == model.BatchModeAtomic)
+	if err != nil {
+		// Nothing was transferred
+		for _, treq := range treqs {
+			a.transferDone(ctx, TransferResult{TransferRequest: treq, Status: store.StatusFailed, Err: err})
+		}
+		var itemErr *store.BatchItemError
+		if status, resp, ok := transferError(err); ok && errors.As(err, &itemErr) {
+			resp.Details = map[string]interface{}{"index": itemErr.Index}
+			writeJSON(w, status, resp)
+			return
+		}
+		a.logger.Printf("batch transfer failed: size=%d, mode=%s, error=%v", len(items), req.Mode, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.BatchTransferResponse{
+		Mode:    req.Mode,
+		Results: make([]model.BatchTransferResult, len(results)),
+	}
+	for i, res := range results {
+		resp.Results[i] = model.BatchTransferResult{Index: i, Status: store.StatusSucceeded, TransactionID: a.serialID(res.TransactionID)}
+		if res.UUID != uuid.Nil {
+			resp.Results[i].ID = res.UUID.String()
+		}
+		if res.Err != nil {
+			_, errResp, _ := transferError(res.Err)
+			resp.Results[i].Status = store.StatusFailed
+			resp.Results[i].Error = &errResp
+		}
+		treqs[i].Details.UUID = res.UUID
+		a.transferDone(ctx, TransferResult{TransferRequest: treqs[i], ID: res.UUID, TransactionID: res.TransactionID,
+			Status: resp.Results[i].Status, Err: res.Err})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListAccountTransactions returns the account's transaction history, newest first
+func (a *API) ListAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	q := r.URL.Query()
+	var cursor int64
+	if c := q.Get("cursor"); c != "" {
+		cursor, err = strconv.ParseInt(c, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid cursor", map[string]interface{}{"parameter": "cursor"})
+			return
+		}
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	txs, next, err := a.store.ListTransactionsByAccount(ctx, id, cursor, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("list transactions failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.TransactionListResponse{
+		Transactions: make([]model.Transaction, 0, len(txs)),
+	}
+	for _, t := range txs {
+		resp.Transactions = append(resp.Transactions, a.toTransaction(t))
+	}
+	if next != 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// transactionSorts maps the values of ?sort= in GET /transactions to orders
+var transactionSorts = map[string]store.TransactionOrder{
+	"created_at":  {Field: store.OrderCreatedAt},
+	"-created_at": {Field: store.OrderCreatedAt, Desc: true},
+	"amount":      {Field: store.OrderAmount},
+	"-amount":     {Field: store.OrderAmount, Desc: true},
+}
+
+// SearchTransactions returns the transactions matching the from, to, status,
+// min_amount, max_amount and account_id query filters, sorted by ?sort=
+// (newest first by default)
+func (a *API) SearchTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	invalid := func(param, msg string) {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, msg, map[string]interface{}{"parameter": param})
+	}
+
+	var f store.TransactionFilter
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"from", &f.From}, {"to", &f.To}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				invalid(p.name, p.name+" must be an RFC 3339 timestamp")
+				return
+			}
+			*p.dst = t
+		}
+	}
+	switch status := q.Get("status"); status {
+	case "", store.StatusPending, store.StatusSucceeded, store.StatusFailed:
+		f.Status = status
+	default:
+		invalid("status", "status must be one of pending, succeeded, failed")
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dst  **decimal.Decimal
+	}{{"min_amount", &f.MinAmount}, {"max_amount", &f.MaxAmount}} {
+		if v := q.Get(p.name); v != "" {
+			d, err := model.ParseDecimal(v)
+			if err != nil {
+				invalid(p.name, "invalid "+p.name)
+				return
+			}
+			*p.dst = &d
+		}
+	}
+	if v := q.Get("account_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id == 0 {
+			invalid("account_id", "invalid account_id")
+			return
+		}
+		f.AccountID = id
+	}
+	sort := q.Get("sort")
+	if sort == "" {
+		sort = "-created_at"
+	}
+	order, ok := transactionSorts[sort]
+	if !ok {
+		invalid("sort", "sort must be one of created_at, -created_at, amount, -amount")
+		return
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	txs, next, err := a.store.SearchTransactions(ctx, f, order, q.Get("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			invalid("cursor", "invalid cursor")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("search transactions failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.TransactionListResponse{
+		Transactions: make([]model.Transaction, 0, len(txs)),
+		NextCursor:   next,
+	}
+	for _, t := range txs {
+		resp.Transactions = append(resp.Transactions, a.toTransaction(t))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseLimit reads the page size from q, writing a 400 response if it is invalid.
+func parseLimit(w http.ResponseWriter, q url.Values) (int, bool) {
+	l := q.Get("limit")
+	if l == "" {
+		return defaultPageLimit, true
+	}
+	limit, err := strconv.Atoi(l)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "limit must be between 1 and "+strconv.Itoa(maxPageLimit),
+			map[string]interface{}{"parameter": "limit", "max": maxPageLimit})
+		return 0, false
+	}
+	return limit, true
+}
+
+// ListAccounts returns accounts matching the status, min_balance, max_balance and tag
+// query filters in ascending id order
+func (a *API) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	invalid := func(param, msg string) {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, msg, map[string]interface{}{"parameter": param})
+	}
+
+	var f store.AccountFilter
+	var ok bool
+	switch status := q.Get("status"); status {
+	case "", store.AccountActive, store.AccountFrozen, store.AccountClosed:
+		f.Status = status
+	default:
+		invalid("status", "status must be one of active, frozen, closed")
+		return
+	}
+	balance := func(param string) (*decimal.Decimal, bool) {
+		v := q.Get(param)
+		if v == "" {
+			return nil, true
+		}
+		d, err := model.ParseDecimal(v)
+		if err != nil {
+			invalid(param, "invalid "+param)
+			return nil, false
+		}
+		return &d, true
+	}
+	if f.MinBalance, ok = balance("min_balance"); !ok {
+		return
+	}
+	if f.MaxBalance, ok = balance("max_balance"); !ok {
+		return
+	}
+	f.Tag = q.Get("tag")
+
+	var after *int64
+	if c := q.Get("cursor"); c != "" {
+		id, err := strconv.ParseInt(c, 10, 64)
+		if err != nil {
+			invalid("cursor", "invalid cursor")
+			return
 		}
+		after = &id
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	accs, next, err := a.store.ListAccounts(ctx, f, after, limit)
+	if err != nil {
+		a.logger.Printf("list accounts failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.AccountListResponse{
+		Accounts: make([]model.AccountResponse, 0, len(accs)),
+	}
+	for _, acc := range accs {
+		resp.Accounts = append(resp.Accounts, toAccountResponse(acc))
+	}
+	if next != nil {
+		resp.NextCursor = strconv.FormatInt(*next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
@@ -4,10 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
 
@@ -17,284 +24,2485 @@ import (
 
 // MockStore implements StoreAPI for testing
 type MockStore struct {
-	CreateAccountFunc func(ctx context.Context, accountID int64, initial decimal.Decimal) error
-	GetAccountFunc    func(ctx context.Context, accountID int64) (decimal.Decimal, error)
-	TransferFunc      func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error
+	CreateAccountFunc   func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error
+	CreateExternalFunc  func(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error)
+	CreateAccountsFunc  func(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error)
+	ImportFunc          func(ctx context.Context, accounts []store.AccountImport) error
+	AccountIDsFunc      func(ctx context.Context, externalIDs []string) (map[string]int64, error)
+	AliasIDsFunc        func(ctx context.Context, aliases []string) (map[string]int64, error)
+	AddAliasFunc        func(ctx context.Context, accountID int64, alias string) (store.AccountAlias, bool, error)
+	RemoveAliasFunc     func(ctx context.Context, accountID int64, alias string) error
+	ListAliasesFunc     func(ctx context.Context, accountID int64) ([]store.AccountAlias, error)
+	CreateWalletFunc    func(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta store.AccountMetadata) (store.Account, error)
+	WalletIDsFunc       func(ctx context.Context, refs []store.WalletRef) (map[store.WalletRef]int64, error)
+	WalletsFunc         func(ctx context.Context, accountID int64) (store.Wallets, error)
+	GetAccountFunc      func(ctx context.Context, accountID int64) (store.Account, error)
+	BalanceAtFunc       func(ctx context.Context, accountID int64, at time.Time) (store.Balance, error)
+	UpdateMetadataFunc  func(ctx context.Context, accountID int64, upd store.MetadataUpdate) (store.Account, error)
+	UpdateStatusFunc    func(ctx context.Context, accountID int64, status string) (store.Account, error)
+	CloseAccountFunc    func(ctx context.Context, accountID, sweepTo int64, details store.TransferDetails) (store.Account, store.Transaction, error)
+	SetLimitsFunc       func(ctx context.Context, accountID int64, l store.TransferLimits) (store.Account, error)
+	SetOwnerFunc        func(ctx context.Context, accountID int64, owner string) (store.Account, error)
+	SetMinBalanceFunc   func(ctx context.Context, accountID int64, min decimal.NullDecimal) (store.Account, error)
+	SetKYCStatusFunc    func(ctx context.Context, accountID int64, status string) (store.Account, error)
+	NewTxIDFunc         func() (uuid.UUID, error)
+	TxIDFunc            func(ctx context.Context, uid uuid.UUID) (int64, error)
+	TransferFunc        func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error)
+	EnqueueFunc         func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error)
+	DryRunFunc          func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error
+	GetTxFunc           func(ctx context.Context, id int64) (store.Transaction, error)
+	ReverseTxFunc       func(ctx context.Context, id int64) (store.Transaction, error)
+	TransferBatchFunc   func(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error)
+	SplitTransferFunc   func(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error)
+	ListAccountsFunc    func(ctx context.Context, f store.AccountFilter, after *int64, limit int) ([]store.Account, *int64, error)
+	ListTxFunc          func(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error)
+	CreateHoldFunc      func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error)
+	GetHoldFunc         func(ctx context.Context, id int64) (store.Hold, error)
+	CaptureHoldFunc     func(ctx context.Context, id int64) (store.Hold, error)
+	ReleaseHoldFunc     func(ctx context.Context, id int64) (store.Hold, error)
+	CreateScheduledFunc func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (store.ScheduledTransfer, error)
+	GetScheduledFunc    func(ctx context.Context, id int64) (store.ScheduledTransfer, error)
+	CreateJobFunc       func(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error)
+	GetJobFunc          func(ctx context.Context, id int64) (store.TransferJob, error)
+	ListJobFailuresFunc func(ctx context.Context, jobID int64, limit int) ([]store.TransferJobRow, error)
+	CreateRuleFunc      func(ctx context.Context, r store.Rule) (store.Rule, error)
+	ListRulesFunc       func(ctx context.Context) ([]store.Rule, error)
+	DeleteRuleFunc      func(ctx context.Context, id int64) error
+	CreateFeePolicyFunc func(ctx context.Context, p store.FeePolicy) (store.FeePolicy, error)
+	ListFeePoliciesFunc func(ctx context.Context) ([]store.FeePolicy, error)
+	DeleteFeePolicyFunc func(ctx context.Context, id int64) error
+	ClearingAcctsFunc   func(ctx context.Context) ([]int64, error)
+	AddClearingFunc     func(ctx context.Context, accountID int64) error
+	RemoveClearingFunc  func(ctx context.Context, accountID int64) error
+	ClearingTxFunc      func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (store.ClearingTransfer, error)
+	SettlementFunc      func(ctx context.Context, date time.Time) (store.SettlementReport, error)
+	CreateSweepFunc     func(ctx context.Context, r store.SweepRule) (store.SweepRule, error)
+	ListSweepsFunc      func(ctx context.Context) ([]store.SweepRule, error)
+	UpdateSweepFunc     func(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (store.SweepRule, error)
+	DeleteSweepFunc     func(ctx context.Context, id int64) error
+	SweepExecsFunc      func(ctx context.Context, ruleID, cursor int64, limit int) ([]store.SweepExecution, int64, error)
+	CounterpartiesFunc  func(ctx context.Context, accountID int64) (store.CounterpartyAllowlist, error)
+	SetCounterpartyFunc func(ctx context.Context, l store.CounterpartyAllowlist) (store.CounterpartyAllowlist, error)
+	AlertThresholdsFunc func(ctx context.Context, accountID int64) (store.AlertThresholds, error)
+	SetThresholdsFunc   func(ctx context.Context, t store.AlertThresholds) (store.AlertThresholds, error)
+	ListAlertsFunc      func(ctx context.Context, accountID int64, status string, cursor int64, limit int) ([]store.Alert, int64, error)
+	ResolveAlertFunc    func(ctx context.Context, accountID, alertID int64) (store.Alert, error)
+	ListReviewsFunc     func(ctx context.Context, status string, cursor int64, limit int) ([]store.Review, int64, error)
+	ResolveReviewFunc   func(ctx context.Context, transactionID int64, status, reviewedBy string) (store.Review, error)
+	CreateAPIKeyFunc    func(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error)
+	ListAPIKeysFunc     func(ctx context.Context) ([]store.APIKey, error)
+	RevokeAPIKeyFunc    func(ctx context.Context, id int64) error
+	SnapshotFunc        func(ctx context.Context) (store.SnapshotRun, error)
+	ReconciliationFunc  func(ctx context.Context) (store.ReconciliationRun, error)
+	TrialBalanceFunc    func(ctx context.Context, from, to time.Time) (store.TrialBalance, error)
+	SearchTxFunc        func(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error)
+	ExportTxFunc        func(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error
+	GetStatementFunc    func(ctx context.Context, accountID int64, period time.Time) (store.Statement, error)
+	AdjustBalanceFunc   func(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (store.Transaction, error)
+	AccountDataFunc     func(ctx context.Context, accountID int64) (store.AccountData, error)
+	EraseAccountFunc    func(ctx context.Context, accountID int64, reason, erasedBy string) (store.AccountErasure, bool, error)
+
+	Limits store.TransferLimits // default transfer limits
 }
 
-func (m *MockStore) CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal) error {
+func (m *MockStore) CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
 	if m.CreateAccountFunc != nil {
-		return m.CreateAccountFunc(ctx, accountID, initial)
+		return m.CreateAccountFunc(ctx, accountID, initial, currency, meta)
+	}
+	return nil
+}
+
+func (m *MockStore) CreateAccountWithExternalID(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error) {
+	if m.CreateExternalFunc != nil {
+		return m.CreateExternalFunc(ctx, externalID, accountID, initial, currency, meta)
+	}
+	return accountID, nil
+}
+
+func (m *MockStore) CreateAccounts(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error) {
+	if m.CreateAccountsFunc != nil {
+		return m.CreateAccountsFunc(ctx, accounts)
+	}
+	results := make([]store.AccountResult, len(accounts))
+	for i, a := range accounts {
+		results[i].AccountID = a.AccountID
+	}
+	return results, nil
+}
+
+func (m *MockStore) ImportAccounts(ctx context.Context, accounts []store.AccountImport) error {
+	if m.ImportFunc != nil {
+		return m.ImportFunc(ctx, accounts)
+	}
+	return nil
+}
+
+func (m *MockStore) AccountIDs(ctx context.Context, externalIDs []string) (map[string]int64, error) {
+	if m.AccountIDsFunc != nil {
+		return m.AccountIDsFunc(ctx, externalIDs)
+	}
+	return map[string]int64{}, nil
+}
+
+func (m *MockStore) AliasAccountIDs(ctx context.Context, aliases []string) (map[string]int64, error) {
+	if m.AliasIDsFunc != nil {
+		return m.AliasIDsFunc(ctx, aliases)
+	}
+	return map[string]int64{}, nil
+}
+
+func (m *MockStore) AddAccountAlias(ctx context.Context, accountID int64, alias string) (store.AccountAlias, bool, error) {
+	if m.AddAliasFunc != nil {
+		return m.AddAliasFunc(ctx, accountID, alias)
+	}
+	return store.AccountAlias{Alias: alias, AccountID: accountID}, true, nil
+}
+
+func (m *MockStore) RemoveAccountAlias(ctx context.Context, accountID int64, alias string) error {
+	if m.RemoveAliasFunc != nil {
+		return m.RemoveAliasFunc(ctx, accountID, alias)
+	}
+	return nil
+}
+
+func (m *MockStore) AccountAliases(ctx context.Context, accountID int64) ([]store.AccountAlias, error) {
+	if m.ListAliasesFunc != nil {
+		return m.ListAliasesFunc(ctx, accountID)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) CreateWallet(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta store.AccountMetadata) (store.Account, error) {
+	if m.CreateWalletFunc != nil {
+		return m.CreateWalletFunc(ctx, parentID, wallet, accountID, initial, meta)
+	}
+	return store.Account{ID: accountID, Balance: initial, ParentID: parentID, Wallet: wallet}, nil
+}
+
+func (m *MockStore) WalletIDs(ctx context.Context, refs []store.WalletRef) (map[store.WalletRef]int64, error) {
+	if m.WalletIDsFunc != nil {
+		return m.WalletIDsFunc(ctx, refs)
+	}
+	return map[store.WalletRef]int64{}, nil
+}
+
+func (m *MockStore) Wallets(ctx context.Context, accountID int64) (store.Wallets, error) {
+	if m.WalletsFunc != nil {
+		return m.WalletsFunc(ctx, accountID)
+	}
+	return store.Wallets{}, store.ErrAccountNotFound
+}
+
+func (m *MockStore) GetAccount(ctx context.Context, accountID int64) (store.Account, error) {
+	if m.GetAccountFunc != nil {
+		return m.GetAccountFunc(ctx, accountID)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive}, nil
+}
+
+func (m *MockStore) BalanceAt(ctx context.Context, accountID int64, at time.Time) (store.Balance, error) {
+	if m.BalanceAtFunc != nil {
+		return m.BalanceAtFunc(ctx, accountID, at)
+	}
+	return store.Balance{AccountID: accountID, Currency: "USD", At: at}, nil
+}
+
+func (m *MockStore) UpdateAccountMetadata(ctx context.Context, accountID int64, upd store.MetadataUpdate) (store.Account, error) {
+	if m.UpdateMetadataFunc != nil {
+		return m.UpdateMetadataFunc(ctx, accountID, upd)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive}, nil
+}
+
+func (m *MockStore) UpdateAccountStatus(ctx context.Context, accountID int64, status string) (store.Account, error) {
+	if m.UpdateStatusFunc != nil {
+		return m.UpdateStatusFunc(ctx, accountID, status)
+	}
+	return store.Account{ID: accountID, Status: status}, nil
+}
+
+func (m *MockStore) CloseAccount(ctx context.Context, accountID, sweepTo int64, details store.TransferDetails) (store.Account, store.Transaction, error) {
+	if m.CloseAccountFunc != nil {
+		return m.CloseAccountFunc(ctx, accountID, sweepTo, details)
+	}
+	return store.Account{ID: accountID, Status: store.AccountClosed}, store.Transaction{}, nil
+}
+
+func (m *MockStore) DryRunTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error {
+	if m.DryRunFunc != nil {
+		return m.DryRunFunc(ctx, srcID, dstID, amount, details)
+	}
+	return nil
+}
+
+func (m *MockStore) SetAccountLimits(ctx context.Context, accountID int64, l store.TransferLimits) (store.Account, error) {
+	if m.SetLimitsFunc != nil {
+		return m.SetLimitsFunc(ctx, accountID, l)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive, Limits: l}, nil
+}
+
+func (m *MockStore) SetMinBalance(ctx context.Context, accountID int64, min decimal.NullDecimal) (store.Account, error) {
+	if m.SetMinBalanceFunc != nil {
+		return m.SetMinBalanceFunc(ctx, accountID, min)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive, MinBalance: min}, nil
+}
+
+func (m *MockStore) SetKYCStatus(ctx context.Context, accountID int64, status string) (store.Account, error) {
+	if m.SetKYCStatusFunc != nil {
+		return m.SetKYCStatusFunc(ctx, accountID, status)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive, KYCStatus: status}, nil
+}
+
+func (m *MockStore) DefaultLimits() store.TransferLimits {
+	return m.Limits
+}
+
+func (m *MockStore) SetDefaultLimits(l store.TransferLimits) {
+	m.Limits = l
+}
+
+func (m *MockStore) SetAccountOwner(ctx context.Context, accountID int64, owner string) (store.Account, error) {
+	if m.SetOwnerFunc != nil {
+		return m.SetOwnerFunc(ctx, accountID, owner)
+	}
+	return store.Account{ID: accountID, Status: store.AccountActive, OwnerID: owner}, nil
+}
+
+func (m *MockStore) NewTransactionID() (uuid.UUID, error) {
+	if m.NewTxIDFunc != nil {
+		return m.NewTxIDFunc()
+	}
+	return store.UUIDv7{}.NewID()
+}
+
+func (m *MockStore) TransactionID(ctx context.Context, uid uuid.UUID) (int64, error) {
+	if m.TxIDFunc != nil {
+		return m.TxIDFunc(ctx, uid)
+	}
+	return 0, store.ErrTransactionNotFound
+}
+
+func (m *MockStore) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+	if m.TransferFunc != nil {
+		return m.TransferFunc(ctx, srcID, dstID, amount, details)
+	}
+	return 0, nil
+}
+
+func (m *MockStore) EnqueueTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error) {
+	if m.EnqueueFunc != nil {
+		return m.EnqueueFunc(ctx, srcID, dstID, amount, details)
+	}
+	return store.Transaction{ID: 1, Status: store.StatusPending}, nil
+}
+
+func (m *MockStore) SearchTransactions(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error) {
+	if m.SearchTxFunc != nil {
+		return m.SearchTxFunc(ctx, f, order, cursor, limit)
+	}
+	return nil, "", nil
+}
+
+func (m *MockStore) ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error {
+	if m.ExportTxFunc != nil {
+		return m.ExportTxFunc(ctx, accountID, from, to, fn)
+	}
+	return nil
+}
+
+func (m *MockStore) GetStatement(ctx context.Context, accountID int64, period time.Time) (store.Statement, error) {
+	if m.GetStatementFunc != nil {
+		return m.GetStatementFunc(ctx, accountID, period)
+	}
+	return store.Statement{}, store.ErrStatementNotFound
+}
+
+func (m *MockStore) AdjustBalance(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (store.Transaction, error) {
+	if m.AdjustBalanceFunc != nil {
+		return m.AdjustBalanceFunc(ctx, accountID, amount, reason, adjustedBy)
+	}
+	return store.Transaction{}, nil
+}
+
+func (m *MockStore) AccountData(ctx context.Context, accountID int64) (store.AccountData, error) {
+	if m.AccountDataFunc != nil {
+		return m.AccountDataFunc(ctx, accountID)
+	}
+	return store.AccountData{Account: store.Account{ID: accountID}}, nil
+}
+
+func (m *MockStore) EraseAccount(ctx context.Context, accountID int64, reason, erasedBy string) (store.AccountErasure, bool, error) {
+	if m.EraseAccountFunc != nil {
+		return m.EraseAccountFunc(ctx, accountID, reason, erasedBy)
+	}
+	return store.AccountErasure{AccountID: accountID, Reason: reason, ErasedBy: erasedBy}, true, nil
+}
+
+func (m *MockStore) GetTransaction(ctx context.Context, id int64) (store.Transaction, error) {
+	if m.GetTxFunc != nil {
+		return m.GetTxFunc(ctx, id)
+	}
+	return store.Transaction{ID: id, Status: store.StatusSucceeded}, nil
+}
+
+func (m *MockStore) ReverseTransaction(ctx context.Context, id int64) (store.Transaction, error) {
+	if m.ReverseTxFunc != nil {
+		return m.ReverseTxFunc(ctx, id)
+	}
+	return store.Transaction{ID: id + 1, Status: store.StatusSucceeded, ReversalOf: id}, nil
+}
+
+func (m *MockStore) TransferBatch(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+	if m.TransferBatchFunc != nil {
+		return m.TransferBatchFunc(ctx, items, atomic)
+	}
+	return make([]store.TransferResult, len(items)), nil
+}
+
+func (m *MockStore) SplitTransfer(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error) {
+	if m.SplitTransferFunc != nil {
+		return m.SplitTransferFunc(ctx, items)
+	}
+	return uuid.New(), make([]store.TransferResult, len(items)), nil
+}
+
+func (m *MockStore) ListAccounts(ctx context.Context, f store.AccountFilter, after *int64, limit int) ([]store.Account, *int64, error) {
+	if m.ListAccountsFunc != nil {
+		return m.ListAccountsFunc(ctx, f, after, limit)
+	}
+	return nil, nil, nil
+}
+
+func (m *MockStore) ListTransactionsByAccount(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error) {
+	if m.ListTxFunc != nil {
+		return m.ListTxFunc(ctx, accountID, cursor, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *MockStore) CreateHold(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error) {
+	if m.CreateHoldFunc != nil {
+		return m.CreateHoldFunc(ctx, srcID, dstID, amount, expiresAt)
+	}
+	return store.Hold{}, nil
+}
+
+func (m *MockStore) GetHold(ctx context.Context, id int64) (store.Hold, error) {
+	if m.GetHoldFunc != nil {
+		return m.GetHoldFunc(ctx, id)
+	}
+	return store.Hold{ID: id, Status: store.HoldPending}, nil
+}
+
+func (m *MockStore) CaptureHold(ctx context.Context, id int64) (store.Hold, error) {
+	if m.CaptureHoldFunc != nil {
+		return m.CaptureHoldFunc(ctx, id)
+	}
+	return store.Hold{ID: id, Status: store.HoldCaptured}, nil
+}
+
+func (m *MockStore) ReleaseHold(ctx context.Context, id int64) (store.Hold, error) {
+	if m.ReleaseHoldFunc != nil {
+		return m.ReleaseHoldFunc(ctx, id)
+	}
+	return store.Hold{ID: id, Status: store.HoldReleased}, nil
+}
+
+func (m *MockStore) CreateScheduledTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (store.ScheduledTransfer, error) {
+	if m.CreateScheduledFunc != nil {
+		return m.CreateScheduledFunc(ctx, srcID, dstID, amount, executeAt, initiatedBy)
+	}
+	return store.ScheduledTransfer{}, nil
+}
+
+func (m *MockStore) GetScheduledTransfer(ctx context.Context, id int64) (store.ScheduledTransfer, error) {
+	if m.GetScheduledFunc != nil {
+		return m.GetScheduledFunc(ctx, id)
+	}
+	return store.ScheduledTransfer{ID: id, Status: store.ScheduledPending}, nil
+}
+
+func (m *MockStore) CreateTransferJob(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error) {
+	if m.CreateJobFunc != nil {
+		return m.CreateJobFunc(ctx, items, initiatedBy)
+	}
+	return store.TransferJob{Status: store.JobQueued, TotalRows: len(items)}, nil
+}
+
+func (m *MockStore) GetTransferJob(ctx context.Context, id int64) (store.TransferJob, error) {
+	if m.GetJobFunc != nil {
+		return m.GetJobFunc(ctx, id)
+	}
+	return store.TransferJob{ID: id, Status: store.JobQueued}, nil
+}
+
+func (m *MockStore) ListTransferJobFailures(ctx context.Context, jobID int64, limit int) ([]store.TransferJobRow, error) {
+	if m.ListJobFailuresFunc != nil {
+		return m.ListJobFailuresFunc(ctx, jobID, limit)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) CreateRule(ctx context.Context, r store.Rule) (store.Rule, error) {
+	if m.CreateRuleFunc != nil {
+		return m.CreateRuleFunc(ctx, r)
+	}
+	return r, nil
+}
+
+func (m *MockStore) ListRules(ctx context.Context) ([]store.Rule, error) {
+	if m.ListRulesFunc != nil {
+		return m.ListRulesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) DeleteRule(ctx context.Context, id int64) error {
+	if m.DeleteRuleFunc != nil {
+		return m.DeleteRuleFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockStore) CreateFeePolicy(ctx context.Context, p store.FeePolicy) (store.FeePolicy, error) {
+	if m.CreateFeePolicyFunc != nil {
+		return m.CreateFeePolicyFunc(ctx, p)
+	}
+	return p, nil
+}
+
+func (m *MockStore) ListFeePolicies(ctx context.Context) ([]store.FeePolicy, error) {
+	if m.ListFeePoliciesFunc != nil {
+		return m.ListFeePoliciesFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) DeleteFeePolicy(ctx context.Context, id int64) error {
+	if m.DeleteFeePolicyFunc != nil {
+		return m.DeleteFeePolicyFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockStore) ClearingAccounts(ctx context.Context) ([]int64, error) {
+	if m.ClearingAcctsFunc != nil {
+		return m.ClearingAcctsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) AddClearingAccount(ctx context.Context, accountID int64) error {
+	if m.AddClearingFunc != nil {
+		return m.AddClearingFunc(ctx, accountID)
+	}
+	return nil
+}
+
+func (m *MockStore) RemoveClearingAccount(ctx context.Context, accountID int64) error {
+	if m.RemoveClearingFunc != nil {
+		return m.RemoveClearingFunc(ctx, accountID)
+	}
+	return nil
+}
+
+func (m *MockStore) CreateClearingTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (store.ClearingTransfer, error) {
+	if m.ClearingTxFunc != nil {
+		return m.ClearingTxFunc(ctx, srcID, dstID, amount, reference)
+	}
+	return store.ClearingTransfer{ID: 1, SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Reference: reference}, nil
+}
+
+func (m *MockStore) SettlementReport(ctx context.Context, date time.Time) (store.SettlementReport, error) {
+	if m.SettlementFunc != nil {
+		return m.SettlementFunc(ctx, date)
+	}
+	return store.SettlementReport{BusinessDate: date}, nil
+}
+
+func (m *MockStore) CreateSweepRule(ctx context.Context, r store.SweepRule) (store.SweepRule, error) {
+	if m.CreateSweepFunc != nil {
+		return m.CreateSweepFunc(ctx, r)
+	}
+	r.ID = 1
+	return r, nil
+}
+
+func (m *MockStore) ListSweepRules(ctx context.Context) ([]store.SweepRule, error) {
+	if m.ListSweepsFunc != nil {
+		return m.ListSweepsFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) UpdateSweepRule(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (store.SweepRule, error) {
+	if m.UpdateSweepFunc != nil {
+		return m.UpdateSweepFunc(ctx, id, targetID, threshold)
+	}
+	return store.SweepRule{ID: id, TargetAccountID: targetID, Threshold: threshold}, nil
+}
+
+func (m *MockStore) DeleteSweepRule(ctx context.Context, id int64) error {
+	if m.DeleteSweepFunc != nil {
+		return m.DeleteSweepFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockStore) CounterpartyAllowlist(ctx context.Context, accountID int64) (store.CounterpartyAllowlist, error) {
+	if m.CounterpartiesFunc != nil {
+		return m.CounterpartiesFunc(ctx, accountID)
+	}
+	return store.CounterpartyAllowlist{AccountID: accountID}, nil
+}
+
+func (m *MockStore) SetCounterpartyAllowlist(ctx context.Context, l store.CounterpartyAllowlist) (store.CounterpartyAllowlist, error) {
+	if m.SetCounterpartyFunc != nil {
+		return m.SetCounterpartyFunc(ctx, l)
+	}
+	return l, nil
+}
+
+func (m *MockStore) AlertThresholds(ctx context.Context, accountID int64) (store.AlertThresholds, error) {
+	if m.AlertThresholdsFunc != nil {
+		return m.AlertThresholdsFunc(ctx, accountID)
+	}
+	return store.AlertThresholds{AccountID: accountID}, nil
+}
+
+func (m *MockStore) SetAlertThresholds(ctx context.Context, t store.AlertThresholds) (store.AlertThresholds, error) {
+	if m.SetThresholdsFunc != nil {
+		return m.SetThresholdsFunc(ctx, t)
+	}
+	return t, nil
+}
+
+func (m *MockStore) ListAlerts(ctx context.Context, accountID int64, status string, cursor int64, limit int) ([]store.Alert, int64, error) {
+	if m.ListAlertsFunc != nil {
+		return m.ListAlertsFunc(ctx, accountID, status, cursor, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *MockStore) ResolveAlert(ctx context.Context, accountID, alertID int64) (store.Alert, error) {
+	if m.ResolveAlertFunc != nil {
+		return m.ResolveAlertFunc(ctx, accountID, alertID)
+	}
+	return store.Alert{ID: alertID, AccountID: accountID, Status: store.AlertResolved}, nil
+}
+
+func (m *MockStore) ListSweepExecutions(ctx context.Context, ruleID, cursor int64, limit int) ([]store.SweepExecution, int64, error) {
+	if m.SweepExecsFunc != nil {
+		return m.SweepExecsFunc(ctx, ruleID, cursor, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *MockStore) ListReviews(ctx context.Context, status string, cursor int64, limit int) ([]store.Review, int64, error) {
+	if m.ListReviewsFunc != nil {
+		return m.ListReviewsFunc(ctx, status, cursor, limit)
+	}
+	return nil, 0, nil
+}
+
+func (m *MockStore) ResolveReview(ctx context.Context, transactionID int64, status, reviewedBy string) (store.Review, error) {
+	if m.ResolveReviewFunc != nil {
+		return m.ResolveReviewFunc(ctx, transactionID, status, reviewedBy)
+	}
+	return store.Review{}, nil
+}
+
+func (m *MockStore) CreateAPIKey(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error) {
+	if m.CreateAPIKeyFunc != nil {
+		return m.CreateAPIKeyFunc(ctx, name, role, tenant, networks, keyHash)
+	}
+	return store.APIKey{}, nil
+}
+
+func (m *MockStore) ListAPIKeys(ctx context.Context) ([]store.APIKey, error) {
+	if m.ListAPIKeysFunc != nil {
+		return m.ListAPIKeysFunc(ctx)
+	}
+	return nil, nil
+}
+
+func (m *MockStore) RevokeAPIKey(ctx context.Context, id int64) error {
+	if m.RevokeAPIKeyFunc != nil {
+		return m.RevokeAPIKeyFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockStore) SnapshotBalances(ctx context.Context) (store.SnapshotRun, error) {
+	if m.SnapshotFunc != nil {
+		return m.SnapshotFunc(ctx)
+	}
+	return store.SnapshotRun{TakenAt: time.Now().Add(-time.Minute)}, nil
+}
+
+func (m *MockStore) LatestReconciliation(ctx context.Context) (store.ReconciliationRun, error) {
+	if m.ReconciliationFunc != nil {
+		return m.ReconciliationFunc(ctx)
+	}
+	return store.ReconciliationRun{}, store.ErrReconciliationNotFound
+}
+
+func (m *MockStore) TrialBalance(ctx context.Context, from, to time.Time) (store.TrialBalance, error) {
+	if m.TrialBalanceFunc != nil {
+		return m.TrialBalanceFunc(ctx, from, to)
+	}
+	return store.TrialBalance{From: from, To: to, StatusCounts: map[string]int{}}, nil
+}
+
+// TestCreateAccount_Success tests successful account creation
+func TestCreateAccount_Success(t *testing.T) {
+	mockStore := &MockStore{
+		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+			return nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"account_id": 100, "initial_balance": "1000.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAccount(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+}
+
+// TestCreateAccount_InvalidJSON tests malformed JSON
+func TestCreateAccount_InvalidJSON(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{invalid json}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAccount(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateAccount_ZeroAccountID tests validation: account_id cannot be zero
+func TestCreateAccount_ZeroAccountID(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{"account_id": 0, "initial_balance": "1000.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAccount(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte("account_id must be non-zero")) {
+		t.Fatalf("expected error message about account_id, got: %s", w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON error, got content type %q", ct)
+	}
+
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeValidationFailed {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeValidationFailed, resp.Code)
+	}
+}
+
+// TestCreateAccount_NegativeBalance tests validation: initial_balance cannot be negative
+func TestCreateAccount_NegativeBalance(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{"account_id": 100, "initial_balance": "-50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAccount(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateAccount_Duplicate tests that an existing account yields 409 with an error code
+func TestCreateAccount_Duplicate(t *testing.T) {
+	mockStore := &MockStore{
+		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+			return store.ErrAccountExists
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"account_id": 100, "initial_balance": "1000.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateAccount(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeDuplicateAccount {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeDuplicateAccount, resp.Code)
+	}
+}
+
+// TestPutAccount tests that PUT /accounts/{id} creates a missing account and
+// returns an existing one, unless it was opened differently
+func TestPutAccount(t *testing.T) {
+	accounts := map[int64]store.Account{
+		7: {ID: 7, Balance: decimal.NewFromInt(80), InitialBalance: decimal.NewFromInt(100), Currency: "USD", Status: store.AccountActive, ExternalID: "acct-7"},
+	}
+	create := func(externalID string, accountID int64, initial decimal.Decimal, currency string) error {
+		for _, acc := range accounts {
+			if acc.ID == accountID || externalID != "" && acc.ExternalID == externalID {
+				return store.ErrAccountExists
+			}
+		}
+		accounts[accountID] = store.Account{ID: accountID, Balance: initial, InitialBalance: initial, Currency: currency, Status: store.AccountActive, ExternalID: externalID}
+		return nil
+	}
+	mockStore := &MockStore{
+		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+			return create("", accountID, initial, currency)
+		},
+		CreateExternalFunc: func(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error) {
+			return accountID, create(externalID, accountID, initial, currency)
+		},
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			acc, ok := accounts[accountID]
+			if !ok {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			return acc, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		name, path, body string
+		want             int
+		wantBalance      string
+	}{
+		{"created", "/v1/accounts/8", `{"initial_balance": "50.00"}`, http.StatusCreated, "50"},
+		{"retried", "/v1/accounts/8", `{"initial_balance": "50.00", "currency": "USD"}`, http.StatusOK, "50"},
+		{"existing state", "/v1/accounts/7", `{"account_id": 7, "initial_balance": "100", "external_id": "acct-7"}`, http.StatusOK, "80"},
+		{"other initial balance", "/v1/accounts/7", `{"initial_balance": "90"}`, http.StatusConflict, ""},
+		{"other currency", "/v1/accounts/7", `{"initial_balance": "100", "currency": "EUR"}`, http.StatusConflict, ""},
+		{"external id taken", "/v1/accounts/9", `{"initial_balance": "100", "external_id": "acct-7"}`, http.StatusConflict, ""},
+		{"account id mismatch", "/v1/accounts/7", `{"account_id": 8, "initial_balance": "100"}`, http.StatusBadRequest, ""},
+		{"non-numeric id", "/v1/accounts/acct-7", `{"initial_balance": "100"}`, http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path, strings.NewReader(c.body)))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d: %s", c.name, c.want, w.Code, w.Body.String())
+		}
+		if c.wantBalance == "" {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", c.name, err)
+		}
+		if resp.Balance.String() != c.wantBalance {
+			t.Fatalf("%s: expected balance %s, got %s", c.name, c.wantBalance, resp.Balance)
+		}
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	balances := map[int64]decimal.Decimal{1: decimal.Zero, 2: decimal.NewFromInt(40), 3: decimal.Zero}
+	var gotSweepTo int64
+	mockStore := &MockStore{
+		CloseAccountFunc: func(ctx context.Context, accountID, sweepTo int64, details store.TransferDetails) (store.Account, store.Transaction, error) {
+			gotSweepTo = sweepTo
+			balance, ok := balances[accountID]
+			switch {
+			case !ok:
+				return store.Account{}, store.Transaction{}, store.ErrAccountNotFound
+			case sweepTo == accountID:
+				return store.Account{}, store.Transaction{}, store.ErrSameAccount
+			case balance.IsZero():
+				return store.Account{ID: accountID, Status: store.AccountClosed}, store.Transaction{}, nil
+			case sweepTo == 0:
+				return store.Account{}, store.Transaction{}, store.ErrAccountNotEmpty
+			}
+			if details.Channel != store.ChannelHTTP {
+				t.Errorf("expected the sweep recorded as an http transfer, got %+v", details)
+			}
+			sweep := store.Transaction{ID: 9, SourceAccountID: accountID, DestinationAccountID: sweepTo, Amount: balance, Status: store.StatusSucceeded}
+			return store.Account{ID: accountID, Status: store.AccountClosed}, sweep, nil
+		},
+		AccountIDsFunc: func(ctx context.Context, externalIDs []string) (map[string]int64, error) {
+			return map[string]int64{"acct-3": 3}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		name, path  string
+		want        int
+		wantSweepTo int64
+		wantSweep   bool
+	}{
+		{"empty", "/v1/accounts/1", http.StatusOK, 0, false},
+		{"not empty", "/v1/accounts/2", http.StatusConflict, 0, false},
+		{"swept", "/v1/accounts/2?sweep_to=3", http.StatusOK, 3, true},
+		{"swept by external id", "/v1/accounts/2?sweep_to=acct-3", http.StatusOK, 3, true},
+		{"unknown sweep account", "/v1/accounts/2?sweep_to=acct-4", http.StatusNotFound, 0, false},
+		{"invalid sweep account", "/v1/accounts/2?sweep_to=-1", http.StatusBadRequest, 0, false},
+		{"sweep to itself", "/v1/accounts/2?sweep_to=2", http.StatusBadRequest, 2, false},
+		{"unknown account", "/v1/accounts/5", http.StatusNotFound, 0, false},
+	}
+	for _, c := range cases {
+		gotSweepTo = 0
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, c.path, nil))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d: %s", c.name, c.want, w.Code, w.Body.String())
+		}
+		if gotSweepTo != c.wantSweepTo {
+			t.Fatalf("%s: expected sweep to %d, got %d", c.name, c.wantSweepTo, gotSweepTo)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.CloseAccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: failed to decode response: %v", c.name, err)
+		}
+		if resp.Account.Status != store.AccountClosed || (resp.Sweep != nil) != c.wantSweep {
+			t.Fatalf("%s: unexpected response %+v", c.name, resp)
+		}
+		if c.wantSweep && (resp.Sweep.DestinationAccountID != 3 || resp.Sweep.Amount.String() != "40") {
+			t.Fatalf("%s: unexpected sweep %+v", c.name, resp.Sweep)
+		}
+	}
+}
+
+// TestCreateAccountBatch tests that each account of a batch gets its own outcome
+func TestCreateAccountBatch(t *testing.T) {
+	var got []store.AccountImport
+	mockStore := &MockStore{
+		CreateAccountsFunc: func(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error) {
+			got = accounts
+			return []store.AccountResult{{AccountID: 100}, {Err: store.ErrAccountExists}, {AccountID: 7}}, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"accounts": [
+		{"account_id": 100, "initial_balance": "10.00"},
+		{"account_id": 100, "initial_balance": "1"},
+		{"account_id": 101, "initial_balance": "-1"},
+		{"external_id": "acme", "initial_balance": "0", "currency": "EUR"}
+	]}`)
+	w := httptest.NewRecorder()
+	api.CreateAccountBatch(w, httptest.NewRequest(http.MethodPost, "/accounts/batch", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.BatchCreateAccountResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != 3 || got[2].ExternalID != "acme" || got[2].Currency != "EUR" || got[0].Currency != model.DefaultCurrency {
+		t.Fatalf("expected the 3 valid accounts created, got %+v", got)
+	}
+	want := []struct {
+		status string
+		id     int64
+		code   model.ErrorCode
+	}{
+		{model.BatchAccountCreated, 100, ""},
+		{model.BatchAccountDuplicate, 0, model.ErrCodeDuplicateAccount},
+		{model.BatchAccountInvalid, 0, model.ErrCodeValidationFailed},
+		{model.BatchAccountCreated, 7, ""},
+	}
+	if len(resp.Results) != len(want) {
+		t.Fatalf("expected %d results, got %+v", len(want), resp.Results)
+	}
+	for i, res := range resp.Results {
+		var code model.ErrorCode
+		if res.Error != nil {
+			code = res.Error.Code
+		}
+		if res.Index != i || res.Status != want[i].status || res.AccountID != want[i].id || code != want[i].code {
+			t.Fatalf("result %d: expected %+v, got %+v", i, want[i], res)
+		}
+	}
+
+	for _, body := range []string{`{"accounts": []}`, `[{"account_id": 1, "initial_balance": "1"}]`} {
+		w := httptest.NewRecorder()
+		api.CreateAccountBatch(w, httptest.NewRequest(http.MethodPost, "/accounts/batch", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// TestGetAccount_Success tests successful balance retrieval
+func TestGetAccount_Success(t *testing.T) {
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			if accountID == 100 {
+				return store.Account{ID: 100, Balance: decimal.RequireFromString("1000.50"), Status: store.AccountActive}, nil
+			}
+			return store.Account{}, store.ErrAccountNotFound
+		},
+	}
+	api := New(mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/100", nil)
+	w := httptest.NewRecorder()
+
+	// Use gorilla/mux to match the route
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp model.AccountResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.AccountID != 100 {
+		t.Fatalf("expected account_id 100, got %d", resp.AccountID)
+	}
+
+	expected := decimal.RequireFromString("1000.50")
+	if !resp.Balance.Equal(expected) {
+		t.Fatalf("expected balance 1000.50, got %s", resp.Balance.String())
+	}
+	if resp.Status != store.AccountActive {
+		t.Fatalf("expected status %s, got %s", store.AccountActive, resp.Status)
+	}
+}
+
+// TestGetAccount_InvalidID tests with non-numeric account ID
+func TestGetAccount_InvalidID(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/abc", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeValidationFailed || resp.Details["parameter"] != "id" {
+		t.Fatalf("unexpected error body: %+v", resp)
+	}
+}
+
+// TestGetAccount_NotFound tests when account doesn't exist
+func TestGetAccount_NotFound(t *testing.T) {
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			return store.Account{}, store.ErrAccountNotFound
+		},
+	}
+	api := New(mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/accounts/999", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestCircuitOpen tests that store calls failed fast by the circuit breaker answer 503
+func TestCircuitOpen(t *testing.T) {
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			return store.Account{}, fmt.Errorf("get account: %w", store.ErrCircuitOpen)
+		},
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, fmt.Errorf("transfer: %w", store.ErrCircuitOpen)
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil),
+		httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`)),
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s %s: expected status %d, got %d", req.Method, req.URL.Path, http.StatusServiceUnavailable, w.Code)
+		}
+		var resp model.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != model.ErrCodeServiceUnavailable {
+			t.Fatalf("expected code %s, got %s", model.ErrCodeServiceUnavailable, resp.Code)
+		}
+	}
+}
+
+// TestGetAccountBalance tests the point-in-time balance query
+func TestGetAccountBalance(t *testing.T) {
+	monthEnd := time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC)
+	mockStore := &MockStore{
+		BalanceAtFunc: func(ctx context.Context, accountID int64, at time.Time) (store.Balance, error) {
+			if at.Year() < 2020 {
+				return store.Balance{}, store.ErrHistoryArchived
+			}
+			if at.Before(monthEnd) {
+				return store.Balance{}, store.ErrAccountNotFound
+			}
+			return store.Balance{AccountID: accountID, Amount: decimal.NewFromInt(75), Currency: "USD", At: at}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100/balance?at=2024-01-31T23:59:59Z", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.BalanceResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.AccountID != 100 || resp.Balance.String() != "75" || !resp.At.Equal(monthEnd) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	for query, want := range map[string]int{
+		"?at=yesterday":            http.StatusBadRequest,
+		"?at=2023-12-31T00:00:00Z": http.StatusNotFound,
+		"?at=2019-12-31T00:00:00Z": http.StatusGone,
+		"":                         http.StatusOK,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100/balance"+query, nil))
+		if w.Code != want {
+			t.Fatalf("%q: expected status %d, got %d", query, want, w.Code)
+		}
+	}
+}
+
+// TestCreateTransaction_Success tests successful transfer
+func TestCreateTransaction_Success(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 42, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp model.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID == "" || resp.TransactionID != 0 {
+		t.Fatalf("expected an id and no serial transaction_id, got %+v", resp)
+	}
+	if resp.Status != store.StatusSucceeded {
+		t.Fatalf("expected status %q, got %q", store.StatusSucceeded, resp.Status)
+	}
+}
+
+// TestCreateTransaction_InvalidJSON tests malformed JSON
+func TestCreateTransaction_InvalidJSON(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{invalid json}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateTransaction_SameAccount tests validation: source and destination must differ
+func TestCreateTransaction_SameAccount(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 100, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateTransaction_ZeroAmount tests validation: amount must be positive
+func TestCreateTransaction_ZeroAmount(t *testing.T) {
+	mockStore := &MockStore{}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "0"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateTransaction_InsufficientFunds tests transfer with insufficient balance
+func TestCreateTransaction_InsufficientFunds(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrInsufficientFunds
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeInsufficientFunds {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeInsufficientFunds, resp.Code)
+	}
+}
+
+// TestCreateTransaction_AccountInactive tests transfers involving frozen or closed accounts
+func TestCreateTransaction_AccountInactive(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrAccountInactive
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+}
+
+// TestCreateTransaction_CurrencyMismatch tests transfers between accounts in different currencies
+func TestCreateTransaction_CurrencyMismatch(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrCurrencyMismatch
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeCurrencyMismatch {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeCurrencyMismatch, resp.Code)
+	}
+}
+
+// TestCreateTransaction_CrossTenant tests transfers between accounts of different tenants
+func TestCreateTransaction_CrossTenant(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrCrossTenant
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeCrossTenant {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeCrossTenant, resp.Code)
+	}
+}
+
+// TestCreateTransaction_LimitExceeded tests that the exceeded limit is reported in the details
+func TestCreateTransaction_LimitExceeded(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, &store.LimitError{Limit: store.LimitDaily}
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeLimitExceeded || resp.Details["limit"] != store.LimitDaily {
+		t.Fatalf("expected code %s with limit %s, got %+v", model.ErrCodeLimitExceeded, store.LimitDaily, resp)
+	}
+}
+
+// TestCreateTransaction_AccountNotFound tests transfer when account doesn't exist
+func TestCreateTransaction_AccountNotFound(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, &store.AccountNotFoundError{AccountID: dstID, Party: store.PartyDestination}
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeAccountNotFound || resp.Message != "destination account not found" ||
+		resp.Details["account_id"] != float64(200) || resp.Details["field"] != "destination_account_id" {
+		t.Fatalf("expected destination account 200 not to be found, got %+v", resp)
+	}
+}
+
+// TestCreateTransaction_DryRun tests that a dry run is checked but not executed
+func TestCreateTransaction_DryRun(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			t.Fatal("a dry run must not transfer")
+			return 0, nil
+		},
+		DryRunFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error {
+			if amount.GreaterThan(decimal.NewFromInt(100)) {
+				return store.ErrInsufficientFunds
+			}
+			return nil
+		},
+	}
+	api := New(mockStore)
+
+	cases := []struct {
+		query, body string
+		want        int
+	}{
+		{"?dry_run=true", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`, http.StatusOK},
+		{"", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "dry_run": true}`, http.StatusOK},
+		{"?dry_run=1", `{"source_account_id": 100, "destination_account_id": 200, "amount": "500.00"}`, http.StatusConflict},
+		{"?dry_run=maybe", `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/transactions"+c.query, bytes.NewReader([]byte(c.body)))
+		w := httptest.NewRecorder()
+		api.CreateTransaction(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.query, c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.TransactionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if !resp.DryRun || resp.TransactionID != 0 || resp.Status != store.StatusSucceeded {
+			t.Fatalf("unexpected dry-run response: %+v", resp)
+		}
+	}
+}
+
+// TestCreateTransaction_Details tests that reference, purpose code, channel and the transaction id reach the store
+func TestCreateTransaction_Details(t *testing.T) {
+	var got store.TransferDetails
+	uid := uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")
+	mockStore := &MockStore{
+		NewTxIDFunc: func() (uuid.UUID, error) { return uid, nil },
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			got = details
+			return 42, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "reference": "INV-42", "purpose_code": "SUPP"}`)
+	w := httptest.NewRecorder()
+	api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if want := (store.TransferDetails{UUID: uid, Reference: "INV-42", PurposeCode: "SUPP", Channel: store.ChannelHTTP}); got != want {
+		t.Fatalf("expected details %+v, got %+v", want, got)
+	}
+
+	body = []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "purpose_code": "supp"}`)
+	w = httptest.NewRecorder()
+	api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid purpose code, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestListPurposeCodes tests that the configured vocabulary is listed and
+// enforced, and that without one any well-formed code is accepted
+func TestListPurposeCodes(t *testing.T) {
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
+	list := func() model.PurposeCodesResponse {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/purpose-codes", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var resp model.PurposeCodesResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp
+	}
+	transfer := func() int {
+		body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00", "purpose_code": "SUPP"}`)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions", bytes.NewReader(body)))
+		return w.Code
+	}
+
+	if resp := list(); resp.PurposeCodes != nil {
+		t.Fatalf("expected no vocabulary, got %v", resp.PurposeCodes)
+	}
+	if code := transfer(); code != http.StatusOK {
+		t.Fatalf("expected status %d without a vocabulary, got %d", http.StatusOK, code)
+	}
+
+	defer func(codes []string) { model.PurposeCodes = codes }(model.PurposeCodes)
+	model.PurposeCodes = []string{"PAYROLL", "REFUND"}
+	if resp := list(); strings.Join(resp.PurposeCodes, ",") != "PAYROLL,REFUND" {
+		t.Fatalf("expected %v, got %v", model.PurposeCodes, resp.PurposeCodes)
+	}
+	if code := transfer(); code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a code outside the vocabulary, got %d", http.StatusBadRequest, code)
+	}
+}
+
+// TestCreateTransaction_Async tests that Prefer: respond-async queues the transfer
+func TestCreateTransaction_Async(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			t.Fatal("Transfer must not be called for an asynchronous request")
+			return 0, nil
+		},
+		EnqueueFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error) {
+			return store.Transaction{ID: 43, SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Status: store.StatusPending,
+				TransferDetails: details}, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	req.Header.Set("Prefer", "respond-async, wait=10")
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	if got := w.Header().Get("Preference-Applied"); got != "respond-async" {
+		t.Fatalf("expected Preference-Applied respond-async, got %q", got)
+	}
+	var resp model.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID == "" || resp.TransactionID != 0 || resp.Status != store.StatusPending {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// TestCreateTransaction_AsyncNotSupported tests that a store without a queue transfers synchronously
+func TestCreateTransaction_AsyncNotSupported(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 42, nil
+		},
+		EnqueueFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error) {
+			return store.Transaction{}, store.ErrNotSupported
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	req.Header.Set("Prefer", "respond-async")
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got := w.Header().Get("Preference-Applied"); got != "" {
+		t.Fatalf("expected no Preference-Applied, got %q", got)
+	}
+}
+
+// TestCreateTransaction_AsyncDisabled tests that Prefer: respond-async is
+// ignored when asynchronous transfers are disabled
+func TestCreateTransaction_AsyncDisabled(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 42, nil
+		},
+		EnqueueFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error) {
+			t.Fatal("expected no transfer to be queued")
+			return store.Transaction{}, nil
+		},
+	}
+	api := New(mockStore, WithAsyncTransfers(false))
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	req.Header.Set("Prefer", "respond-async")
+	w := httptest.NewRecorder()
+
+	api.CreateTransaction(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+}
+
+// TestWithTimeout tests that the configured timeout bounds store calls and
+// that failures are logged to the configured logger
+func TestWithTimeout(t *testing.T) {
+	var deadline time.Duration
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			d, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("expected a deadline")
+			}
+			deadline = time.Until(d)
+			return store.Account{}, errors.New("connection refused")
+		},
+	}
+	var logs bytes.Buffer
+	r := mux.NewRouter()
+	New(mockStore, WithTimeout(30*time.Second), WithLogger(log.New(&logs, "", 0))).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if deadline <= DefaultRequestTimeout || deadline > 30*time.Second {
+		t.Fatalf("expected a deadline of about 30s, got %s", deadline)
+	}
+	if !strings.Contains(logs.String(), "connection refused") {
+		t.Fatalf("expected the failure logged, got %q", logs.String())
+	}
+}
+
+// TestDrain tests that Drain waits for the requests in flight
+func TestDrain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			close(started)
+			<-release
+			return store.Account{ID: accountID, Balance: decimal.NewFromInt(1), Currency: "USD"}, nil
+		},
+	}
+	api := New(mockStore)
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+
+	served := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil))
+		served <- w.Code
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := api.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected Drain to wait for the request, got %v", err)
+	}
+	close(release)
+	if code := <-served; code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if err := api.Drain(context.Background()); err != nil {
+		t.Fatalf("expected nothing in flight, got %v", err)
+	}
+}
+
+// TestNotSupported tests that features the store lacks are answered with 501
+func TestNotSupported(t *testing.T) {
+	mockStore := &MockStore{
+		ListRulesFunc: func(ctx context.Context) ([]store.Rule, error) {
+			return nil, store.ErrNotSupported
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/rules", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeNotSupported {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeNotSupported, resp.Code)
+	}
+}
+
+// TestGetTransaction tests status reporting and not found mapping
+func TestGetTransaction(t *testing.T) {
+	mockStore := &MockStore{
+		GetTxFunc: func(ctx context.Context, id int64) (store.Transaction, error) {
+			if id != 43 {
+				return store.Transaction{}, store.ErrTransactionNotFound
+			}
+			return store.Transaction{ID: 43, Status: store.StatusFailed, ErrorMessage: "insufficient funds",
+				TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/transactions/43", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.Transaction
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "01890a5d-ac96-774b-bcce-b302099a8057" || resp.TransactionID != 0 || resp.Status != store.StatusFailed || resp.ErrorMessage != "insufficient funds" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/transactions/44", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestReverseTransaction tests the reversal response and error mapping
+func TestReverseTransaction(t *testing.T) {
+	mockStore := &MockStore{
+		ReverseTxFunc: func(ctx context.Context, id int64) (store.Transaction, error) {
+			switch id {
+			case 1:
+				return store.Transaction{ID: 2, SourceAccountID: 200, DestinationAccountID: 100, Status: store.StatusSucceeded, ReversalOf: 1,
+					ReversalOfUUID:  uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057"),
+					TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000002")}}, nil
+			case 3:
+				return store.Transaction{}, store.ErrAlreadyReversed
+			case 4:
+				return store.Transaction{}, store.ErrNotReversible
+			case 5:
+				return store.Transaction{}, store.ErrInsufficientFunds
+			}
+			return store.Transaction{}, store.ErrTransactionNotFound
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions/1/reverse", nil))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var resp model.Transaction
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != "01890a5d-ac97-7000-8000-000000000002" || resp.ReversalOfID != "01890a5d-ac96-774b-bcce-b302099a8057" || resp.ReversalOf != 0 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	tests := []struct {
+		id       string
+		wantCode int
+		wantErr  model.ErrorCode
+	}{
+		{"3", http.StatusConflict, model.ErrCodeAlreadyReversed},
+		{"4", http.StatusConflict, model.ErrCodeNotReversible},
+		{"5", http.StatusConflict, model.ErrCodeInsufficientFunds},
+		{"6", http.StatusNotFound, model.ErrCodeTransactionNotFound},
+		{"x", http.StatusBadRequest, model.ErrCodeValidationFailed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions/"+tt.id+"/reverse", nil))
+		var errResp model.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+			t.Fatalf("id %s: failed to decode response: %v", tt.id, err)
+		}
+		if w.Code != tt.wantCode || errResp.Code != tt.wantErr {
+			t.Fatalf("id %s: expected %d %s, got %d %s", tt.id, tt.wantCode, tt.wantErr, w.Code, errResp.Code)
+		}
+	}
+}
+
+// TestListAccountTransactions_Success tests history listing with pagination params
+func TestListAccountTransactions_Success(t *testing.T) {
+	mockStore := &MockStore{
+		ListTxFunc: func(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error) {
+			if accountID != 100 || cursor != 10 || limit != 2 {
+				t.Fatalf("unexpected args: accountID=%d cursor=%d limit=%d", accountID, cursor, limit)
+			}
+			return []store.Transaction{
+				{ID: 9, SourceAccountID: 100, DestinationAccountID: 200, Amount: decimal.RequireFromString("5.5"), Status: store.StatusSucceeded,
+					TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000009")}},
+				{ID: 7, SourceAccountID: 200, DestinationAccountID: 100, Amount: decimal.RequireFromString("1"), Status: store.StatusSucceeded},
+			}, 7, nil
+		},
+	}
+	api := New(mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/transactions?cursor=10&limit=2", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var resp model.TransactionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Transactions) != 2 || resp.Transactions[0].ID != "01890a5d-ac97-7000-8000-000000000009" {
+		t.Fatalf("unexpected transactions: %+v", resp.Transactions)
+	}
+	if resp.NextCursor != "7" {
+		t.Fatalf("expected next_cursor 7, got %q", resp.NextCursor)
+	}
+}
+
+// TestListAccountTransactions_InvalidLimit tests limit validation
+func TestListAccountTransactions_InvalidLimit(t *testing.T) {
+	api := New(&MockStore{})
+
+	for _, q := range []string{"limit=0", "limit=1000", "limit=abc", "cursor=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/transactions?"+q, nil)
+		w := httptest.NewRecorder()
+
+		r := mux.NewRouter()
+		api.RegisterRoutes(r)
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", q, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+// TestListAccountTransactions_NotFound tests listing for a missing account
+func TestListAccountTransactions_NotFound(t *testing.T) {
+	mockStore := &MockStore{
+		ListTxFunc: func(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error) {
+			return nil, 0, store.ErrAccountNotFound
+		},
+	}
+	api := New(mockStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/999/transactions", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestReadyHandler_NoPool tests that readiness failures are reported as JSON
+func TestReadyHandler_NoPool(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	w := httptest.NewRecorder()
+
+	ReadyHandler(PoolCheck("db", nil))(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+	var resp model.ReadinessResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != model.ReadinessNotReady || len(resp.Components) != 1 || resp.Components[0].Status != model.ComponentDown {
+		t.Fatalf("expected the db to be down, got %+v", resp)
+	}
+}
+
+// TestTrackOutage tests that a failing check reports since when it fails
+func TestTrackOutage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var down error
+	check := trackOutage(func(context.Context) error { return down }, func() time.Time { return now })
+
+	down = errors.New("connection refused")
+	check(ctx)
+	now = now.Add(time.Minute)
+	err := check(ctx)
+	if err == nil || !errors.Is(err, down) || !strings.Contains(err.Error(), "down since 2024-01-01T00:00:00Z (2 failed checks)") {
+		t.Fatalf("expected the outage to be reported from its start, got %v", err)
+	}
+
+	down = nil
+	if err := check(ctx); err != nil {
+		t.Fatalf("expected the check to pass, got %v", err)
+	}
+	down = errors.New("connection refused")
+	if err := check(ctx); err == nil || !strings.Contains(err.Error(), "2024-01-01T00:01:00Z (1 failed checks)") {
+		t.Fatalf("expected a new outage, got %v", err)
+	}
+}
+
+// TestVersionHandler tests that the build info is returned as JSON
+func TestVersionHandler(t *testing.T) {
+	info := model.VersionResponse{Version: "v1.2.3", GitSHA: "abc123", GoVersion: "go1.23.0", Features: []string{"legacy_routes"}}
+	w := httptest.NewRecorder()
+	VersionHandler(info)(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.VersionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Version != info.Version || resp.GitSHA != info.GitSHA || len(resp.Features) != 1 {
+		t.Fatalf("expected %+v, got %+v", info, resp)
+	}
+}
+
+// TestReadyHandler_Components tests the overall status given the components
+func TestReadyHandler_Components(t *testing.T) {
+	up := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("unreachable") }
+	tests := []struct {
+		name       string
+		checks     []ReadinessCheck
+		wantCode   int
+		wantStatus string
+	}{
+		{"all up", []ReadinessCheck{{Name: "db", Required: true, Check: up}, {Name: "cache", Check: up}}, http.StatusOK, model.ReadinessReady},
+		{"optional down", []ReadinessCheck{{Name: "db", Required: true, Check: up}, {Name: "cache", Check: down}}, http.StatusOK, model.ReadinessDegraded},
+		{"required down", []ReadinessCheck{{Name: "db", Required: true, Check: down}, {Name: "cache", Check: down}}, http.StatusServiceUnavailable, model.ReadinessNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ReadyHandler(tt.checks...)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			var resp model.ReadinessResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Status != tt.wantStatus {
+				t.Fatalf("expected status %s, got %s", tt.wantStatus, resp.Status)
+			}
+			for i, c := range resp.Components {
+				if c.Name != tt.checks[i].Name {
+					t.Fatalf("expected component %s at %d, got %s", tt.checks[i].Name, i, c.Name)
+				}
+				if (c.Status == model.ComponentDown) != (c.Error != "") {
+					t.Fatalf("expected an error exactly when down, got %+v", c)
+				}
+			}
+		})
 	}
-	return nil
 }
 
-func (m *MockStore) GetAccount(ctx context.Context, accountID int64) (decimal.Decimal, error) {
-	if m.GetAccountFunc != nil {
-		return m.GetAccountFunc(ctx, accountID)
+// TestNotFoundHandler tests JSON errors for unknown routes
+func TestNotFoundHandler(t *testing.T) {
+	r := mux.NewRouter()
+	r.NotFoundHandler = NotFoundHandler()
+	r.MethodNotAllowedHandler = MethodNotAllowedHandler()
+	api := New(&MockStore{})
+	api.RegisterRoutes(r)
+	api.RegisterLegacyRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected JSON error, got content type %q", ct)
 	}
-	return decimal.Zero, nil
-}
 
-func (m *MockStore) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error {
-	if m.TransferFunc != nil {
-		return m.TransferFunc(ctx, srcID, dstID, amount)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/transactions", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeMethodNotAllowed {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeMethodNotAllowed, resp.Code)
 	}
-	return nil
 }
 
-// TestCreateAccount_Success tests successful account creation
-func TestCreateAccount_Success(t *testing.T) {
+// TestRegisterRoutes_Versioned tests that routes are served under /v1 and,
+// when enabled, on the deprecated unversioned paths
+func TestRegisterRoutes_Versioned(t *testing.T) {
 	mockStore := &MockStore{
-		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal) error {
-			return nil
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			return store.Account{ID: accountID, Balance: decimal.NewFromInt(1)}, nil
 		},
 	}
 	api := New(mockStore)
 
-	body := []byte(`{"account_id": 100, "initial_balance": "1000.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
-	w := httptest.NewRecorder()
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
 
-	api.CreateAccount(w, req)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Deprecation") != "" {
+		t.Fatalf("versioned route must not be marked deprecated")
+	}
 
-	if w.Code != http.StatusCreated {
-		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts/100", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected legacy route to be absent, got %d", w.Code)
 	}
-}
 
-// TestCreateAccount_InvalidJSON tests malformed JSON
-func TestCreateAccount_InvalidJSON(t *testing.T) {
-	mockStore := &MockStore{}
-	api := New(mockStore)
+	api.RegisterLegacyRoutes(r)
 
-	body := []byte(`{invalid json}`)
-	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
-	w := httptest.NewRecorder()
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/accounts/100", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Header().Get("Deprecation") != "true" {
+		t.Fatalf("expected legacy route to be marked deprecated")
+	}
+	if link := w.Header().Get("Link"); link != `</v1/accounts/100>; rel="successor-version"` {
+		t.Fatalf("unexpected Link header: %s", link)
+	}
+}
 
-	api.CreateAccount(w, req)
+// TestAccountStatusEndpoints tests freeze, unfreeze and close routing and error mapping
+func TestAccountStatusEndpoints(t *testing.T) {
+	mockStore := &MockStore{
+		UpdateStatusFunc: func(ctx context.Context, accountID int64, status string) (store.Account, error) {
+			switch accountID {
+			case 404:
+				return store.Account{}, store.ErrAccountNotFound
+			case 409:
+				return store.Account{}, store.ErrInvalidTransition
+			}
+			return store.Account{ID: accountID, Balance: decimal.NewFromInt(5), Status: status}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	cases := []struct {
+		path       string
+		wantCode   int
+		wantStatus string
+	}{
+		{"/v1/accounts/1/freeze", http.StatusOK, store.AccountFrozen},
+		{"/v1/accounts/1/unfreeze", http.StatusOK, store.AccountActive},
+		{"/v1/accounts/1/close", http.StatusOK, store.AccountClosed},
+		{"/v1/accounts/404/freeze", http.StatusNotFound, ""},
+		{"/v1/accounts/409/unfreeze", http.StatusConflict, ""},
+		{"/v1/accounts/a!b/close", http.StatusBadRequest, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, c.path, nil))
+		if w.Code != c.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", c.path, c.wantCode, w.Code)
+		}
+		if c.wantStatus == "" {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Status != c.wantStatus {
+			t.Fatalf("%s: expected account status %s, got %s", c.path, c.wantStatus, resp.Status)
+		}
 	}
 }
 
-// TestCreateAccount_ZeroAccountID tests validation: account_id cannot be zero
-func TestCreateAccount_ZeroAccountID(t *testing.T) {
-	mockStore := &MockStore{}
+// TestCreateAccount_Metadata tests that metadata is passed to the store
+func TestCreateAccount_Metadata(t *testing.T) {
+	var got store.AccountMetadata
+	var gotCurrency string
+	mockStore := &MockStore{
+		CreateAccountFunc: func(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+			got, gotCurrency = meta, currency
+			return nil
+		},
+	}
 	api := New(mockStore)
 
-	body := []byte(`{"account_id": 0, "initial_balance": "1000.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
+	body := []byte(`{"account_id": 100, "initial_balance": "1", "display_name": "Payroll", "owner_ref": "team-42", "tags": ["ops", "eu"]}`)
 	w := httptest.NewRecorder()
+	api.CreateAccount(w, httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body)))
 
-	api.CreateAccount(w, req)
-
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
 	}
-	if !bytes.Contains(w.Body.Bytes(), []byte("account_id must be non-zero")) {
-		t.Fatalf("expected error message about account_id, got: %s", w.Body.String())
+	if got.DisplayName != "Payroll" || got.OwnerRef != "team-42" || len(got.Tags) != 2 {
+		t.Fatalf("unexpected metadata: %+v", got)
+	}
+	if gotCurrency != model.DefaultCurrency {
+		t.Fatalf("expected default currency %s, got %q", model.DefaultCurrency, gotCurrency)
 	}
 }
 
-// TestCreateAccount_NegativeBalance tests validation: initial_balance cannot be negative
-func TestCreateAccount_NegativeBalance(t *testing.T) {
-	mockStore := &MockStore{}
-	api := New(mockStore)
-
-	body := []byte(`{"account_id": 100, "initial_balance": "-50.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/accounts", bytes.NewReader(body))
-	w := httptest.NewRecorder()
+// TestUpdateAccount tests PATCH /v1/accounts/{id} validation and error mapping
+func TestUpdateAccount(t *testing.T) {
+	var got store.MetadataUpdate
+	mockStore := &MockStore{
+		UpdateMetadataFunc: func(ctx context.Context, accountID int64, upd store.MetadataUpdate) (store.Account, error) {
+			if accountID == 404 {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			got = upd
+			return store.Account{ID: accountID, Status: store.AccountActive, AccountMetadata: store.AccountMetadata{DisplayName: *upd.DisplayName}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
 
-	api.CreateAccount(w, req)
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1", `{"display_name": "Payroll"}`, http.StatusOK},
+		{"/v1/accounts/1", `{}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `{"tags": [""]}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `not json`, http.StatusBadRequest},
+		{"/v1/accounts/a!b", `{"display_name": "x"}`, http.StatusBadRequest},
+		{"/v1/accounts/404", `{"display_name": "x"}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, c.path, bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+	}
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if got.DisplayName == nil || *got.DisplayName != "Payroll" || got.OwnerRef != nil || got.Tags != nil {
+		t.Fatalf("expected only display_name to be updated, got %+v", got)
 	}
 }
 
-// TestGetAccount_Success tests successful balance retrieval
-func TestGetAccount_Success(t *testing.T) {
+// TestSetAccountLimits tests PUT /v1/accounts/{id}/limits
+func TestSetAccountLimits(t *testing.T) {
+	var got store.TransferLimits
 	mockStore := &MockStore{
-		GetAccountFunc: func(ctx context.Context, accountID int64) (decimal.Decimal, error) {
-			if accountID == 100 {
-				return decimal.RequireFromString("1000.50"), nil
+		SetLimitsFunc: func(ctx context.Context, accountID int64, l store.TransferLimits) (store.Account, error) {
+			if accountID == 404 {
+				return store.Account{}, store.ErrAccountNotFound
 			}
-			return decimal.Zero, store.ErrAccountNotFound
+			got = l
+			return store.Account{ID: accountID, Status: store.AccountActive, Limits: l}, nil
 		},
 	}
-	api := New(mockStore)
-
-	req := httptest.NewRequest(http.MethodGet, "/accounts/100", nil)
-	w := httptest.NewRecorder()
-
-	// Use gorilla/mux to match the route
 	r := mux.NewRouter()
-	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
-	r.ServeHTTP(w, req)
+	New(mockStore).RegisterRoutes(r)
 
-	if w.Code != http.StatusOK {
-		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1", `{"daily_transfer_limit": "5000"}`, http.StatusOK},
+		{"/v1/accounts/1", `{"max_transfer_amount": "0"}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `not json`, http.StatusBadRequest},
+		{"/v1/accounts/a!b", `{}`, http.StatusBadRequest},
+		{"/v1/accounts/404", `{}`, http.StatusNotFound},
 	}
-
-	var resp model.AccountResponse
-	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path+"/limits", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Limits == nil || resp.Limits.MaxTransferAmount != nil || resp.Limits.DailyTransferLimit.String() != "5000" {
+			t.Fatalf("unexpected limits in response: %+v", resp.Limits)
+		}
 	}
 
-	if resp.AccountID != 100 {
-		t.Fatalf("expected account_id 100, got %d", resp.AccountID)
+	if got.MaxAmount.Valid || !got.Daily.Valid || !got.Daily.Decimal.Equal(decimal.NewFromInt(5000)) {
+		t.Fatalf("expected only the daily limit to be set, got %+v", got)
 	}
+}
 
-	expected := decimal.RequireFromString("1000.50")
-	if !resp.Balance.Equal(expected) {
-		t.Fatalf("expected balance 1000.50, got %s", resp.Balance.String())
+// TestSetMinBalance tests PUT /v1/accounts/{id}/min-balance
+func TestSetMinBalance(t *testing.T) {
+	var got decimal.NullDecimal
+	mockStore := &MockStore{
+		SetMinBalanceFunc: func(ctx context.Context, accountID int64, min decimal.NullDecimal) (store.Account, error) {
+			if accountID == 404 {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			got = min
+			return store.Account{ID: accountID, Status: store.AccountActive, MinBalance: min}, nil
+		},
 	}
-}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
 
-// TestGetAccount_InvalidID tests with non-numeric account ID
-func TestGetAccount_InvalidID(t *testing.T) {
-	mockStore := &MockStore{}
-	api := New(mockStore)
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1", `{"min_balance": "500"}`, http.StatusOK},
+		{"/v1/accounts/1", `{"min_balance": "-1"}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `{"min_balance": "0.001"}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `not json`, http.StatusBadRequest},
+		{"/v1/accounts/a!b", `{}`, http.StatusBadRequest},
+		{"/v1/accounts/404", `{}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path+"/min-balance", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.MinBalance == nil || resp.MinBalance.String() != "500" {
+			t.Fatalf("unexpected min_balance in response: %+v", resp.MinBalance)
+		}
+	}
+	if !got.Valid || !got.Decimal.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("expected min balance 500 to be set, got %+v", got)
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/accounts/abc", nil)
 	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/v1/accounts/1/min-balance", bytes.NewReader([]byte(`{"min_balance": null}`))))
+	if w.Code != http.StatusOK || got.Valid || strings.Contains(w.Body.String(), "min_balance") {
+		t.Fatalf("expected the min balance removed, got %d %s", w.Code, w.Body.String())
+	}
+}
 
+// TestSetKYCStatus tests PUT /v1/accounts/{id}/kyc
+func TestSetKYCStatus(t *testing.T) {
+	mockStore := &MockStore{
+		SetKYCStatusFunc: func(ctx context.Context, accountID int64, status string) (store.Account, error) {
+			if accountID == 404 {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			return store.Account{ID: accountID, Status: store.AccountActive, KYCStatus: status}, nil
+		},
+	}
 	r := mux.NewRouter()
-	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
-	r.ServeHTTP(w, req)
+	New(mockStore).RegisterRoutes(r)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1", `{"kyc_status": "verified"}`, http.StatusOK},
+		{"/v1/accounts/1", `{"kyc_status": "pending"}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `{}`, http.StatusBadRequest},
+		{"/v1/accounts/404", `{"kyc_status": "blocked"}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path+"/kyc", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.KYCStatus != model.KYCVerified {
+			t.Fatalf("unexpected kyc_status in response: %q", resp.KYCStatus)
+		}
 	}
 }
 
-// TestGetAccount_NotFound tests when account doesn't exist
-func TestGetAccount_NotFound(t *testing.T) {
+// TestCreateTransaction_KYCRestricted tests transfers the KYC statuses of the accounts do not allow
+func TestCreateTransaction_KYCRestricted(t *testing.T) {
 	mockStore := &MockStore{
-		GetAccountFunc: func(ctx context.Context, accountID int64) (decimal.Decimal, error) {
-			return decimal.Zero, store.ErrAccountNotFound
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrKYCRestricted
 		},
 	}
 	api := New(mockStore)
 
-	req := httptest.NewRequest(http.MethodGet, "/accounts/999", nil)
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
 	w := httptest.NewRecorder()
+	api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeKYCRestricted {
+		t.Fatalf("expected code %s, got %s", model.ErrCodeKYCRestricted, resp.Code)
+	}
+}
 
+// TestSetAccountOwner tests PUT /v1/accounts/{id}/owner
+func TestSetAccountOwner(t *testing.T) {
+	mockStore := &MockStore{
+		SetOwnerFunc: func(ctx context.Context, accountID int64, owner string) (store.Account, error) {
+			if accountID == 404 {
+				return store.Account{}, store.ErrAccountNotFound
+			}
+			return store.Account{ID: accountID, Status: store.AccountActive, OwnerID: owner}, nil
+		},
+	}
 	r := mux.NewRouter()
-	r.HandleFunc("/accounts/{id}", api.GetAccount).Methods(http.MethodGet)
-	r.ServeHTTP(w, req)
+	New(mockStore).RegisterRoutes(r)
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	cases := []struct {
+		path, body string
+		want       int
+	}{
+		{"/v1/accounts/1", `{"owner_id": "payments"}`, http.StatusOK},
+		{"/v1/accounts/1", `{"owner_id": "` + strings.Repeat("x", model.MaxOwnerIDLen+1) + `"}`, http.StatusBadRequest},
+		{"/v1/accounts/1", `not json`, http.StatusBadRequest},
+		{"/v1/accounts/a!b", `{}`, http.StatusBadRequest},
+		{"/v1/accounts/404", `{}`, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, c.path+"/owner", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.path, c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusOK {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.OwnerID != "payments" {
+			t.Fatalf("expected owner payments, got %q", resp.OwnerID)
+		}
 	}
 }
 
-// TestCreateTransaction_Success tests successful transfer
-func TestCreateTransaction_Success(t *testing.T) {
+// TestListAccounts_Filters tests query parsing and pagination of GET /v1/accounts
+func TestListAccounts_Filters(t *testing.T) {
+	var gotFilter store.AccountFilter
+	var gotAfter *int64
+	var gotLimit int
 	mockStore := &MockStore{
-		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error {
-			return nil
+		ListAccountsFunc: func(ctx context.Context, f store.AccountFilter, after *int64, limit int) ([]store.Account, *int64, error) {
+			gotFilter, gotAfter, gotLimit = f, after, limit
+			next := int64(8)
+			return []store.Account{{ID: 7, Balance: decimal.NewFromInt(50), Status: store.AccountFrozen}, {ID: 8}}, &next, nil
 		},
 	}
-	api := New(mockStore)
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
 
-	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
 	w := httptest.NewRecorder()
-
-	api.CreateTransaction(w, req)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts?status=frozen&min_balance=10&max_balance=100.5&tag=ops&cursor=-3&limit=2", nil))
 
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
 	}
-}
-
-// TestCreateTransaction_InvalidJSON tests malformed JSON
-func TestCreateTransaction_InvalidJSON(t *testing.T) {
-	mockStore := &MockStore{}
-	api := New(mockStore)
+	if gotFilter.Status != store.AccountFrozen || gotFilter.Tag != "ops" ||
+		!gotFilter.MinBalance.Equal(decimal.NewFromInt(10)) || !gotFilter.MaxBalance.Equal(decimal.RequireFromString("100.5")) {
+		t.Fatalf("unexpected filter: %+v", gotFilter)
+	}
+	if gotAfter == nil || *gotAfter != -3 || gotLimit != 2 {
+		t.Fatalf("unexpected page: after=%v limit=%d", gotAfter, gotLimit)
+	}
 
-	body := []byte(`{invalid json}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
-	w := httptest.NewRecorder()
+	var resp model.AccountListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Accounts) != 2 || resp.Accounts[0].Status != store.AccountFrozen || resp.NextCursor != "8" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
 
-	api.CreateTransaction(w, req)
+// TestListAccounts_InvalidQuery tests rejection of malformed filters
+func TestListAccounts_InvalidQuery(t *testing.T) {
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
 
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	for _, q := range []string{"status=pending", "min_balance=abc", "max_balance=1x", "cursor=abc", "limit=0"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts?"+q, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", q, http.StatusBadRequest, w.Code)
+		}
 	}
 }
 
-// TestCreateTransaction_SameAccount tests validation: source and destination must differ
-func TestCreateTransaction_SameAccount(t *testing.T) {
-	mockStore := &MockStore{}
+// TestCreateTransactionBatch_BestEffort tests per-item results
+func TestCreateTransactionBatch_BestEffort(t *testing.T) {
+	var gotAtomic bool
+	mockStore := &MockStore{
+		TransferBatchFunc: func(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+			gotAtomic = atomic
+			return []store.TransferResult{{TransactionID: 10, UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000010")}, {Err: store.ErrInsufficientFunds}}, nil
+		},
+	}
 	api := New(mockStore)
 
-	body := []byte(`{"source_account_id": 100, "destination_account_id": 100, "amount": "50.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	body := []byte(`{"mode": "best_effort", "transfers": [
+		{"source_account_id": 1, "destination_account_id": 2, "amount": "1"},
+		{"source_account_id": 2, "destination_account_id": 3, "amount": "900"}]}`)
 	w := httptest.NewRecorder()
+	api.CreateTransactionBatch(w, httptest.NewRequest(http.MethodPost, "/transactions/batch", bytes.NewReader(body)))
 
-	api.CreateTransaction(w, req)
-
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if gotAtomic {
+		t.Fatalf("expected best_effort mode to be passed as non-atomic")
+	}
+	var resp model.BatchTransferResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].ID != "01890a5d-ac97-7000-8000-000000000010" || resp.Results[0].TransactionID != 0 || resp.Results[0].Status != store.StatusSucceeded {
+		t.Fatalf("unexpected first result: %+v", resp.Results)
+	}
+	if r := resp.Results[1]; r.Status != store.StatusFailed || r.Error == nil || r.Error.Code != model.ErrCodeInsufficientFunds {
+		t.Fatalf("unexpected second result: %+v", r)
 	}
 }
 
-// TestCreateTransaction_ZeroAmount tests validation: amount must be positive
-func TestCreateTransaction_ZeroAmount(t *testing.T) {
-	mockStore := &MockStore{}
+// TestCreateTransactionBatch_Atomic tests that an aborted batch reports the failing item
+func TestCreateTransactionBatch_Atomic(t *testing.T) {
+	mockStore := &MockStore{
+		TransferBatchFunc: func(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+			return nil, &store.BatchItemError{Index: 1, Err: store.ErrAccountInactive}
+		},
+	}
 	api := New(mockStore)
 
-	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "0"}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	body := []byte(`{"mode": "atomic", "transfers": [
+		{"source_account_id": 1, "destination_account_id": 2, "amount": "1"},
+		{"source_account_id": 2, "destination_account_id": 3, "amount": "1"}]}`)
 	w := httptest.NewRecorder()
+	api.CreateTransactionBatch(w, httptest.NewRequest(http.MethodPost, "/transactions/batch", bytes.NewReader(body)))
 
-	api.CreateTransaction(w, req)
-
-	if w.Code != http.StatusBadRequest {
-		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var resp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Code != model.ErrCodeAccountInactive || resp.Details["index"] != float64(1) {
+		t.Fatalf("unexpected response: %+v", resp)
 	}
 }
 
-// TestCreateTransaction_InsufficientFunds tests transfer with insufficient balance
-func TestCreateTransaction_InsufficientFunds(t *testing.T) {
+// TestSearchTransactions tests filter and sort parsing for transaction search
+func TestSearchTransactions(t *testing.T) {
 	mockStore := &MockStore{
-		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error {
-			return store.ErrInsufficientFunds
+		SearchTxFunc: func(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error) {
+			if f.Status != store.StatusFailed || f.AccountID != 100 || f.MinAmount == nil || !f.MinAmount.Equal(decimal.NewFromInt(5)) || f.MaxAmount != nil {
+				t.Fatalf("unexpected filter: %+v", f)
+			}
+			if !f.From.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) || !f.To.IsZero() {
+				t.Fatalf("unexpected range: %v - %v", f.From, f.To)
+			}
+			if order != (store.TransactionOrder{Field: store.OrderCreatedAt, Desc: true}) || cursor != "abc" || limit != 2 {
+				t.Fatalf("unexpected args: order=%+v cursor=%q limit=%d", order, cursor, limit)
+			}
+			return []store.Transaction{{ID: 3, SourceAccountID: 100, DestinationAccountID: 200, Amount: decimal.NewFromInt(7), Status: store.StatusFailed,
+				TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000003")}}}, "next", nil
 		},
 	}
 	api := New(mockStore)
 
-	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
+	req := httptest.NewRequest(http.MethodGet, "/v1/transactions?from=2024-01-01T00:00:00Z&status=failed&min_amount=5&account_id=100&cursor=abc&limit=2", nil)
 	w := httptest.NewRecorder()
 
-	api.CreateTransaction(w, req)
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusConflict {
-		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.TransactionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Transactions) != 1 || resp.Transactions[0].ID != "01890a5d-ac97-7000-8000-000000000003" || resp.NextCursor != "next" {
+		t.Fatalf("unexpected response: %+v", resp)
 	}
 }
 
-// TestCreateTransaction_AccountNotFound tests transfer when account doesn't exist
-func TestCreateTransaction_AccountNotFound(t *testing.T) {
-	mockStore := &MockStore{
-		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error {
-			return store.ErrAccountNotFound
+// TestSearchTransactions_Invalid tests rejection of bad search parameters
+func TestSearchTransactions_Invalid(t *testing.T) {
+	api := New(&MockStore{
+		SearchTxFunc: func(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error) {
+			return nil, "", store.ErrInvalidCursor
 		},
-	}
-	api := New(mockStore)
+	})
 
-	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
-	req := httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body))
-	w := httptest.NewRecorder()
+	for _, q := range []string{"from=yesterday", "status=done", "min_amount=x", "account_id=0", "sort=id", "limit=0", "cursor=bad"} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/transactions?"+q, nil)
+		w := httptest.NewRecorder()
 
-	api.CreateTransaction(w, req)
+		r := mux.NewRouter()
+		api.RegisterRoutes(r)
+		r.ServeHTTP(w, req)
 
-	if w.Code != http.StatusNotFound {
-		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", q, http.StatusBadRequest, w.Code)
+		}
 	}
 }
@@ -1,30 +1,125 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/you/internal-transfers/internal/model"
 )
 
+// readinessCheckTimeout bounds each check of the readiness probe
+const readinessCheckTimeout = 2 * time.Second
+
 // HealthHandler returns 200 OK when server is alive.
 func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
 
-// ReadyHandler returns a handler that checks DB pool connectivity.
-func ReadyHandler(pool *pgxpool.Pool) http.HandlerFunc {
+// VersionHandler returns a handler describing the running build.
+func VersionHandler(info model.VersionResponse) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, info)
+	}
+}
+
+// ReadinessCheck checks one dependency of the service. The service is not
+// ready while a Required check fails; other failures only degrade it, for
+// dependencies it works without, such as the cache.
+type ReadinessCheck struct {
+	Name     string
+	Required bool
+	Check    func(ctx context.Context) error
+}
+
+// PoolCheck returns a required check pinging a DB pool. The pool reconnects
+// by itself once the database is back; until then the check reports since
+// when it has been failing.
+func PoolCheck(name string, pool *pgxpool.Pool) ReadinessCheck {
+	return ReadinessCheck{Name: name, Required: true, Check: trackOutage(func(ctx context.Context) error {
 		if pool == nil {
-			http.Error(w, "db not configured", http.StatusServiceUnavailable)
-			return
+			return errors.New("not configured")
 		}
-		// Simple ping using Ping context with short timeout
-		if err := pool.Ping(r.Context()); err != nil {
-			http.Error(w, "db not ready", http.StatusServiceUnavailable)
-			return
+		return pool.Ping(ctx)
+	}, time.Now)}
+}
+
+// trackOutage wraps check so that its errors tell since when, and for how many
+// checks in a row, it has been failing.
+func trackOutage(check func(ctx context.Context) error, now func() time.Time) func(ctx context.Context) error {
+	var (
+		mu        sync.Mutex
+		downSince time.Time
+		failures  int
+	)
+	return func(ctx context.Context) error {
+		err := check(ctx)
+		mu.Lock()
+		defer mu.Unlock()
+		if err == nil {
+			downSince, failures = time.Time{}, 0
+			return nil
 		}
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("ok"))
+		if failures == 0 {
+			downSince = now()
+		}
+		failures++
+		return fmt.Errorf("down since %s (%d failed checks): %w", downSince.UTC().Format(time.RFC3339), failures, err)
+	}
+}
+
+// ReadyHandler returns a handler that runs checks concurrently and reports
+// the status and latency of each component. It answers 200 when every required
+// check passes, even if the service is degraded, and 503 otherwise.
+func ReadyHandler(checks ...ReadinessCheck) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := model.ReadinessResponse{Status: model.ReadinessReady, Components: make([]model.ComponentStatus, len(checks))}
+		var wg sync.WaitGroup
+		for i, c := range checks {
+			wg.Add(1)
+			go func(i int, c ReadinessCheck) {
+				defer wg.Done()
+				resp.Components[i] = runCheck(r.Context(), c)
+			}(i, c)
+		}
+		wg.Wait()
+
+		status := http.StatusOK
+		for _, c := range resp.Components {
+			switch {
+			case c.Status == model.ComponentUp:
+			case c.Required:
+				resp.Status = model.ReadinessNotReady
+				status = http.StatusServiceUnavailable
+			case resp.Status == model.ReadinessReady:
+				resp.Status = model.ReadinessDegraded
+			}
+		}
+		writeJSON(w, status, resp)
+	}
+}
+
+// runCheck runs c with readinessCheckTimeout and times it.
+func runCheck(ctx context.Context, c ReadinessCheck) model.ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := c.Check(ctx)
+	status := model.ComponentStatus{
+		Name:      c.Name,
+		Status:    model.ComponentUp,
+		Required:  c.Required,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		status.Status = model.ComponentDown
+		status.Error = err.Error()
 	}
+	return status
 }
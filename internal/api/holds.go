@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toHoldResponse maps a stored hold to its JSON representation
+func (a *API) toHoldResponse(h store.Hold) model.HoldResponse {
+	return model.HoldResponse{
+		HoldID:               h.ID,
+		SourceAccountID:      h.SourceAccountID,
+		DestinationAccountID: h.DestinationAccountID,
+		Amount:               model.DecimalString{Decimal: h.Amount},
+		Currency:             h.Currency,
+		Status:               h.Status,
+		TransactionPublicID:  publicID(h.TransactionUUID),
+		TransactionID:        a.serialID(h.TransactionID),
+		CreatedAt:            h.CreatedAt,
+		ExpiresAt:            h.ExpiresAt,
+		Fee:                  model.DecimalString{Decimal: h.Fee},
+		FeeAccountID:         h.FeeAccountID,
+	}
+}
+
+// writeHoldError maps store errors from hold operations to responses
+func (a *API) writeHoldError(w http.ResponseWriter, err error, op string, id int64) {
+	switch {
+	case errors.Is(err, store.ErrHoldNotFound):
+		writeError(w, http.StatusNotFound, model.ErrCodeHoldNotFound, "hold not found")
+	case errors.Is(err, store.ErrHoldNotPending):
+		writeError(w, http.StatusConflict, model.ErrCodeHoldNotPending, "hold is no longer pending")
+	default:
+		status, resp, ok := transferError(err)
+		if !ok {
+			a.logger.Printf("%s failed: holdID=%d, error=%v", op, id, err)
+		}
+		writeJSON(w, status, resp)
+	}
+}
+
+// CreateHold reserves an amount on the source account for a later capture or release
+func (a *API) CreateHold(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateHoldRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	src, dst, ok := a.transferAccounts(ctx, w, req.SourceAccountID, req.DestinationAccountID)
+	if !ok {
+		return
+	}
+	treq := TransferRequest{Kind: TransferKindHold, SourceAccountID: src, DestinationAccountID: dst, Amount: req.Amount.Decimal}
+	if !a.allowTransfer(ctx, w, treq) {
+		return
+	}
+	h, err := a.store.CreateHold(ctx, src, dst, req.Amount.Decimal, a.clock.Now().Add(req.Expiry()))
+	if err != nil {
+		a.writeHoldError(w, err, "create hold", 0)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a.toHoldResponse(h))
+}
+
+// GetHold returns a hold by id
+func (a *API) GetHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	h, err := a.store.GetHold(ctx, id)
+	if err != nil {
+		a.writeHoldError(w, err, "get hold", id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toHoldResponse(h))
+}
+
+// CaptureHold transfers the held amount to the destination account
+func (a *API) CaptureHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	h, err := a.store.CaptureHold(ctx, id)
+	if err != nil {
+		a.writeHoldError(w, err, "capture hold", id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toHoldResponse(h))
+}
+
+// ReleaseHold cancels a hold and makes the amount available again
+func (a *API) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseHoldID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	h, err := a.store.ReleaseHold(ctx, id)
+	if err != nil {
+		a.writeHoldError(w, err, "release hold", id)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toHoldResponse(h))
+}
+
+// parseHoldID reads the hold id from the path, writing a 400 response if it is invalid
+func parseHoldID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid hold id", map[string]interface{}{"parameter": "id"})
+		return 0, false
+	}
+	return id, true
+}
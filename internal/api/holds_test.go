@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateHold_Success tests that a hold is created with the requested expiry
+func TestCreateHold_Success(t *testing.T) {
+	var gotExpiry time.Time
+	mockStore := &MockStore{
+		CreateHoldFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error) {
+			gotExpiry = expiresAt
+			return store.Hold{ID: 3, SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Currency: "USD", Status: store.HoldPending, ExpiresAt: expiresAt}, nil
+		},
+	}
+	clk := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	api := New(mockStore, WithClock(clk))
+
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 2, "amount": "25.00", "expires_in_seconds": 60}`)
+	w := httptest.NewRecorder()
+	api.CreateHold(w, httptest.NewRequest(http.MethodPost, "/holds", bytes.NewReader(body)))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if want := clk.Now().Add(time.Minute); !gotExpiry.Equal(want) {
+		t.Fatalf("expected expiry %s, got %s", want, gotExpiry)
+	}
+	var resp model.HoldResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.HoldID != 3 || resp.Status != store.HoldPending || !resp.Amount.Equal(decimal.NewFromInt(25)) {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// TestCreateHold_Validation tests request validation and store error mapping
+func TestCreateHold_Validation(t *testing.T) {
+	mockStore := &MockStore{
+		CreateHoldFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error) {
+			return store.Hold{}, store.ErrInsufficientFunds
+		},
+	}
+	api := New(mockStore)
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"source_account_id": 1, "destination_account_id": 1, "amount": "1"}`, http.StatusBadRequest},
+		{`{"source_account_id": 1, "destination_account_id": 2, "amount": "1", "expires_in_seconds": -1}`, http.StatusBadRequest},
+		{`{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`, http.StatusConflict},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		api.CreateHold(w, httptest.NewRequest(http.MethodPost, "/holds", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.body, c.want, w.Code)
+		}
+	}
+}
+
+// TestHoldTransitions tests capture and release routing and error mapping
+func TestHoldTransitions(t *testing.T) {
+	notPending := func(ctx context.Context, id int64) (store.Hold, error) {
+		switch id {
+		case 404:
+			return store.Hold{}, store.ErrHoldNotFound
+		case 409:
+			return store.Hold{}, store.ErrHoldNotPending
+		}
+		return store.Hold{ID: id, Status: store.HoldCaptured, TransactionID: 11}, nil
+	}
+	r := mux.NewRouter()
+	New(&MockStore{CaptureHoldFunc: notPending, ReleaseHoldFunc: notPending}).RegisterRoutes(r)
+
+	cases := []struct {
+		path string
+		want int
+	}{
+		{"/v1/holds/1/capture", http.StatusOK},
+		{"/v1/holds/1/release", http.StatusOK},
+		{"/v1/holds/404/capture", http.StatusNotFound},
+		{"/v1/holds/409/release", http.StatusConflict},
+		{"/v1/holds/abc/capture", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, c.path, nil))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.path, c.want, w.Code)
+		}
+	}
+}
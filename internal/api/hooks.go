@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// Kinds of TransferRequest, by the route that requested the transfer
+const (
+	TransferKindSingle    = "transfer"  // POST /transactions
+	TransferKindBatch     = "batch"     // an item of POST /transactions/batch
+	TransferKindScheduled = "scheduled" // POST /transfers/scheduled, made later by the scheduler
+	TransferKindHold      = "hold"      // POST /holds, made when the hold is captured
+	TransferKindJob       = "job"       // a row of POST /jobs/transfers, made later by the job worker
+	TransferKindSplit     = "split"     // a leg of POST /transactions/split
+	TransferKindClearing  = "clearing"  // POST /clearing/transfers, settled at the cutoff
+)
+
+// TransferRequest is a transfer about to be requested, with its accounts
+// resolved to internal ids.
+type TransferRequest struct {
+	Kind                 string
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Details              store.TransferDetails
+	DryRun               bool // answered without moving money
+}
+
+// TransferResult is the outcome of a transfer: Status is store.StatusSucceeded,
+// StatusPending for a queued transfer, or StatusFailed with Err set.
+type TransferResult struct {
+	TransferRequest
+	ID            uuid.UUID // uuid.Nil if the transfer failed before one was drawn
+	TransactionID int64
+	Status        string
+	Err           error
+}
+
+// BeforeTransferHook checks a transfer before it is made. A non-nil error
+// rejects the transfer: a *TransferRejection or a store error such as
+// store.ErrTransferBlocked is answered like the API's own errors, anything
+// else with 500.
+type BeforeTransferHook interface {
+	BeforeTransfer(ctx context.Context, req TransferRequest) error
+}
+
+// AfterTransferHook is told the outcome of every transfer that was attempted.
+// It runs before the response is written, so slow work such as notifications
+// should be handed off.
+type AfterTransferHook interface {
+	AfterTransfer(ctx context.Context, res TransferResult)
+}
+
+// BeforeTransferFunc adapts a function to a BeforeTransferHook
+type BeforeTransferFunc func(ctx context.Context, req TransferRequest) error
+
+// BeforeTransfer calls f
+func (f BeforeTransferFunc) BeforeTransfer(ctx context.Context, req TransferRequest) error {
+	return f(ctx, req)
+}
+
+// AfterTransferFunc adapts a function to an AfterTransferHook
+type AfterTransferFunc func(ctx context.Context, res TransferResult)
+
+// AfterTransfer calls f
+func (f AfterTransferFunc) AfterTransfer(ctx context.Context, res TransferResult) {
+	f(ctx, res)
+}
+
+// WithBeforeTransferHook runs h before every transfer is requested, whatever
+// its kind, after the built-in checks and the hooks given earlier.
+func WithBeforeTransferHook(h BeforeTransferHook) Option {
+	return func(a *API) {
+		a.beforeTransfer = append(a.beforeTransfer, h)
+	}
+}
+
+// WithAfterTransferHook runs h after every transfer and batch item, in the
+// order the hooks were given. Dry runs, and the kinds of transfer made later
+// by a worker, are not reported.
+func WithAfterTransferHook(h AfterTransferHook) Option {
+	return func(a *API) {
+		a.afterTransfer = append(a.afterTransfer, h)
+	}
+}
+
+// TransferRejection is the error of a BeforeTransferHook refusing a transfer,
+// answered with its status and code.
+type TransferRejection struct {
+	Status  int
+	Code    model.ErrorCode
+	Message string
+}
+
+func (e *TransferRejection) Error() string {
+	return e.Message
+}
+
+// RejectTransfer returns the error of a hook refusing a transfer, answered
+// with 422 TRANSFER_REJECTED and message.
+func RejectTransfer(message string) error {
+	return &TransferRejection{Status: http.StatusUnprocessableEntity, Code: model.ErrCodeTransferRejected, Message: message}
+}
+
+// invalidTransfer rejects a transfer failing validation with 400
+func invalidTransfer(err error) error {
+	return &TransferRejection{Status: http.StatusBadRequest, Code: model.ErrCodeValidationFailed, Message: err.Error()}
+}
+
+// builtinBeforeTransfer are the checks every API runs ahead of the hooks of
+// WithBeforeTransferHook.
+var builtinBeforeTransfer = []BeforeTransferHook{
+	BeforeTransferFunc(distinctAccounts),
+}
+
+// distinctAccounts rejects a transfer from an account to itself. Validate only
+// catches the same account given twice in the same form; this one compares the
+// resolved ids.
+func distinctAccounts(_ context.Context, req TransferRequest) error {
+	if req.SourceAccountID == req.DestinationAccountID {
+		return invalidTransfer(model.ErrSameSourceDestination)
+	}
+	return nil
+}
+
+// checkTransfer runs the before hooks on req, stopping at the first rejection.
+func (a *API) checkTransfer(ctx context.Context, req TransferRequest) error {
+	for _, h := range a.beforeTransfer {
+		if err := h.BeforeTransfer(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allowTransfer runs the before hooks on req, writing the error response if
+// one rejects it.
+func (a *API) allowTransfer(ctx context.Context, w http.ResponseWriter, req TransferRequest) bool {
+	err := a.checkTransfer(ctx, req)
+	if err == nil {
+		return true
+	}
+	if status, resp, ok := rejectionError(err); ok {
+		writeJSON(w, status, resp)
+		return false
+	}
+	a.logger.Printf("transfer hook failed: kind=%s, src=%d, dst=%d, error=%v", req.Kind, req.SourceAccountID, req.DestinationAccountID, err)
+	internalError(w, err)
+	return false
+}
+
+// allowBatchItem is allowTransfer for an item of a batch or job, whose
+// position pos is given in the error details under key and, formatted by
+// format, prefixes the message. Items with an unknown account are left to fail
+// like the store fails them.
+func (a *API) allowBatchItem(ctx context.Context, w http.ResponseWriter, req TransferRequest, format string, pos int, key string) bool {
+	if req.SourceAccountID == 0 || req.DestinationAccountID == 0 {
+		return true
+	}
+	err := a.checkTransfer(ctx, req)
+	if err == nil {
+		return true
+	}
+	status, resp, ok := rejectionError(err)
+	if !ok {
+		a.logger.Printf("transfer hook failed: kind=%s, %s, src=%d, dst=%d, error=%v",
+			req.Kind, fmt.Sprintf(format, pos), req.SourceAccountID, req.DestinationAccountID, err)
+		internalError(w, err)
+		return false
+	}
+	resp.Message = fmt.Sprintf(format, pos) + ": " + resp.Message
+	if resp.Details == nil {
+		resp.Details = make(map[string]interface{}, 1)
+	}
+	resp.Details[key] = pos
+	writeJSON(w, status, resp)
+	return false
+}
+
+// transferDone runs the after hooks on res.
+func (a *API) transferDone(ctx context.Context, res TransferResult) {
+	for _, h := range a.afterTransfer {
+		h.AfterTransfer(ctx, res)
+	}
+}
+
+// rejectionError maps the error of a before hook to a status and error body
+// like transferError, which it falls back to. ok is false for unexpected errors.
+func rejectionError(err error) (status int, resp model.ErrorResponse, ok bool) {
+	var rej *TransferRejection
+	if errors.As(err, &rej) {
+		return rej.Status, model.ErrorResponse{Code: rej.Code, Message: rej.Message}, true
+	}
+	return transferError(err)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestTransferHooks tests that before hooks can reject a transfer and after
+// hooks see its outcome
+func TestTransferHooks(t *testing.T) {
+	transferred := false
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			transferred = true
+			return 7, nil
+		},
+	}
+	var seen []TransferRequest
+	var results []TransferResult
+	limit := BeforeTransferFunc(func(ctx context.Context, req TransferRequest) error {
+		seen = append(seen, req)
+		if req.Amount.GreaterThan(decimal.NewFromInt(100)) {
+			return RejectTransfer("amount above the deployment limit")
+		}
+		return nil
+	})
+	notify := AfterTransferFunc(func(ctx context.Context, res TransferResult) {
+		results = append(results, res)
+	})
+	r := mux.NewRouter()
+	New(mockStore, WithBeforeTransferHook(limit), WithAfterTransferHook(notify)).RegisterRoutes(r)
+	transfer := func(amount string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions",
+			strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "`+amount+`", "reference": "rent"}`)))
+		return w
+	}
+
+	w := transfer("150")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var errResp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if errResp.Code != model.ErrCodeTransferRejected || errResp.Message != "amount above the deployment limit" {
+		t.Fatalf("unexpected error response %+v", errResp)
+	}
+	if transferred || len(results) != 0 {
+		t.Fatal("expected a rejected transfer not to be made nor reported")
+	}
+
+	if w := transfer("50"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(seen) != 2 || seen[1].Kind != TransferKindSingle || seen[1].SourceAccountID != 1 ||
+		seen[1].DestinationAccountID != 2 || seen[1].Details.Reference != "rent" {
+		t.Fatalf("unexpected requests seen by the before hook: %+v", seen)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if res := results[0]; res.Status != store.StatusSucceeded || res.TransactionID != 7 || res.ID.String() == "" || res.Err != nil {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+// TestTransferHooks_Failure tests that after hooks see failed transfers, and
+// that store errors returned by before hooks are answered like the store's
+func TestTransferHooks_Failure(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 0, store.ErrInsufficientFunds
+		},
+	}
+	var results []TransferResult
+	screen := BeforeTransferFunc(func(ctx context.Context, req TransferRequest) error {
+		if req.DestinationAccountID == 666 {
+			return store.ErrTransferBlocked
+		}
+		return nil
+	})
+	notify := AfterTransferFunc(func(ctx context.Context, res TransferResult) {
+		results = append(results, res)
+	})
+	r := mux.NewRouter()
+	New(mockStore, WithBeforeTransferHook(screen), WithAfterTransferHook(notify)).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions",
+		strings.NewReader(`{"source_account_id": 1, "destination_account_id": 666, "amount": "1"}`)))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), string(model.ErrCodeTransferBlocked)) {
+		t.Fatalf("expected a blocked transfer, got status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions",
+		strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	if len(results) != 1 || results[0].Status != store.StatusFailed || !errors.Is(results[0].Err, store.ErrInsufficientFunds) {
+		t.Fatalf("unexpected results %+v", results)
+	}
+}
+
+// TestTransferHooks_Batch tests that a rejected batch item fails the request
+// with its index, and that after hooks see every item
+func TestTransferHooks_Batch(t *testing.T) {
+	mockStore := &MockStore{
+		TransferBatchFunc: func(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+			results := make([]store.TransferResult, len(items))
+			for i := range items {
+				results[i].TransactionID = int64(i + 1)
+			}
+			results[1].Err = store.ErrInsufficientFunds
+			return results, nil
+		},
+	}
+	var results []TransferResult
+	limit := BeforeTransferFunc(func(ctx context.Context, req TransferRequest) error {
+		if req.Amount.GreaterThan(decimal.NewFromInt(100)) {
+			return RejectTransfer("amount above the deployment limit")
+		}
+		return nil
+	})
+	notify := AfterTransferFunc(func(ctx context.Context, res TransferResult) {
+		results = append(results, res)
+	})
+	r := mux.NewRouter()
+	New(mockStore, WithBeforeTransferHook(limit), WithAfterTransferHook(notify)).RegisterRoutes(r)
+	batch := func(second string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions/batch",
+			strings.NewReader(`{"mode": "best_effort", "transfers": [
+				{"source_account_id": 1, "destination_account_id": 2, "amount": "10"},
+				{"source_account_id": 2, "destination_account_id": 3, "amount": "`+second+`"}]}`)))
+		return w
+	}
+
+	w := batch("500")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, w.Code)
+	}
+	var errResp model.ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&errResp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if errResp.Message != "transfers[1]: amount above the deployment limit" || errResp.Details["index"] != float64(1) {
+		t.Fatalf("unexpected error response %+v", errResp)
+	}
+
+	if w := batch("20"); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Kind != TransferKindBatch || results[0].Status != store.StatusSucceeded || results[0].TransactionID != 1 {
+		t.Fatalf("unexpected first result %+v", results[0])
+	}
+	if results[1].Status != store.StatusFailed || !errors.Is(results[1].Err, store.ErrInsufficientFunds) {
+		t.Fatalf("unexpected second result %+v", results[1])
+	}
+}
+
+// TestTransferHooks_HookError tests that an unexpected hook error is answered with 500
+func TestTransferHooks_HookError(t *testing.T) {
+	broken := BeforeTransferFunc(func(ctx context.Context, req TransferRequest) error {
+		return errors.New("screening service unreachable")
+	})
+	r := mux.NewRouter()
+	New(&MockStore{}, WithBeforeTransferHook(broken)).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/holds",
+		strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`)))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+}
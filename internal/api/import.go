@@ -0,0 +1,342 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// DefaultMaxImportBytes caps the size of an account import file unless
+// WithMaxImportBytes is given.
+const DefaultMaxImportBytes = 256 << 20
+
+// WithMaxImportBytes caps the size of account import files; larger files are
+// rejected with 413.
+func WithMaxImportBytes(n int64) Option {
+	return func(a *API) {
+		a.maxImportBytes = n
+	}
+}
+
+// importTimeout bounds an import, which may run far longer than other requests
+const importTimeout = 10 * time.Minute
+
+// maxImportLineBytes caps a line of a JSON lines import
+const maxImportLineBytes = 64 << 10
+
+// importCSVColumns sets the field of a column of a CSV import. Columns may
+// come in any order; initial_balance and either account_id or external_id
+// are required.
+var importCSVColumns = map[string]func(req *model.CreateAccountRequest, v string) error{
+	"account_id": func(req *model.CreateAccountRequest, v string) error {
+		if v == "" {
+			return nil
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return errors.New("invalid account_id")
+		}
+		req.AccountID = id
+		return nil
+	},
+	"external_id": func(req *model.CreateAccountRequest, v string) error {
+		req.ExternalID = v
+		return nil
+	},
+	"initial_balance": func(req *model.CreateAccountRequest, v string) error {
+		d, err := model.ParseDecimal(v)
+		if err != nil {
+			return errors.New("invalid initial_balance")
+		}
+		req.InitialBalance = model.DecimalString{Decimal: d}
+		return nil
+	},
+	"currency": func(req *model.CreateAccountRequest, v string) error {
+		req.Currency = v
+		return nil
+	},
+	"display_name": func(req *model.CreateAccountRequest, v string) error {
+		req.DisplayName = v
+		return nil
+	},
+	"owner_ref": func(req *model.CreateAccountRequest, v string) error {
+		req.OwnerRef = v
+		return nil
+	},
+	// Tags are separated by semicolons
+	"tags": func(req *model.CreateAccountRequest, v string) error {
+		if v != "" {
+			req.Tags = strings.Split(v, ";")
+		}
+		return nil
+	},
+	"owner_id": func(req *model.CreateAccountRequest, v string) error {
+		req.OwnerID = v
+		return nil
+	},
+}
+
+// importFile is a parsed account import file
+type importFile struct {
+	reqs     []model.CreateAccountRequest
+	rows     []int // the file row of each request
+	rejected int
+	errors   []model.AccountImportError // the first MaxImportErrors rejected rows
+}
+
+// reject records row as rejected for msg, about field if not empty.
+func (f *importFile) reject(row int, field, msg string) {
+	f.rejected++
+	if len(f.errors) < model.MaxImportErrors {
+		f.errors = append(f.errors, model.AccountImportError{Row: row, Field: field, Error: msg})
+	}
+}
+
+// add records req, read from row, validating it.
+func (f *importFile) add(row int, req model.CreateAccountRequest) error {
+	if err := req.Validate(); err != nil {
+		f.reject(row, "", err.Error())
+		return nil
+	}
+	if len(f.reqs) == model.MaxImportRows {
+		return fmt.Errorf("file must contain at most %d accounts", model.MaxImportRows)
+	}
+	f.reqs = append(f.reqs, req)
+	f.rows = append(f.rows, row)
+	return nil
+}
+
+// details returns the error details listing the rejected rows.
+func (f *importFile) details() map[string]interface{} {
+	return map[string]interface{}{"rejected_rows": f.rejected, "rows": f.errors}
+}
+
+// ImportAccounts creates the accounts of the request body, a CSV file (the
+// default) or JSON lines, each line a POST /accounts body, per ?format=. The
+// import is all or nothing: invalid rows are reported with 400, and rows
+// whose ids are taken with 409, listing the first MaxImportErrors of them.
+func (a *API) ImportAccounts(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = exportCSV
+	}
+	if format != exportCSV && format != exportJSONL {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "format must be csv or jsonl", map[string]interface{}{"parameter": "format"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), importTimeout)
+	defer cancel()
+	// The server's timeouts are sized for ordinary requests
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(importTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(importTimeout))
+
+	body := http.MaxBytesReader(w, r.Body, a.maxImportBytes)
+	var f *importFile
+	var err error
+	if format == exportJSONL {
+		f, err = parseImportJSONL(body)
+	} else {
+		f, err = parseImportCSV(body)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorDetails(w, http.StatusRequestEntityTooLarge, model.ErrCodeBodyTooLarge,
+				fmt.Sprintf("file must be at most %d bytes", tooLarge.Limit), map[string]interface{}{"limit": tooLarge.Limit})
+			return
+		}
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	accounts := make([]store.AccountImport, 0, len(f.reqs))
+	for i, req := range f.reqs {
+		acc, ok := toAccountImport(ctx, req)
+		if !ok {
+			f.reject(f.rows[i], "owner_id", "accounts can only be created for the caller")
+			continue
+		}
+		accounts = append(accounts, acc)
+	}
+	if f.rejected > 0 {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed,
+			fmt.Sprintf("%d invalid rows; nothing was imported", f.rejected), f.details())
+		return
+	}
+	if len(accounts) == 0 {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "file contains no accounts")
+		return
+	}
+
+	if err := a.store.ImportAccounts(ctx, accounts); err != nil {
+		var importErr *store.ImportError
+		if errors.As(err, &importErr) {
+			conflicts := &importFile{}
+			for _, row := range importErr.Rows {
+				conflicts.reject(f.rows[row.Row-1], row.Field, row.Field+" is already taken")
+			}
+			writeErrorDetails(w, http.StatusConflict, model.ErrCodeDuplicateAccount,
+				fmt.Sprintf("%d rows conflict with existing accounts or earlier rows; nothing was imported", conflicts.rejected), conflicts.details())
+			return
+		}
+		if errors.Is(err, store.ErrAccountExists) {
+			writeError(w, http.StatusConflict, model.ErrCodeDuplicateAccount, "an account of the file was created concurrently; nothing was imported")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("import accounts failed: rows=%d, error=%v", len(accounts), err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model.AccountImportResponse{ImportedRows: len(accounts)})
+}
+
+// toAccountImport maps req to the account to create, defaulting its currency
+// and, for callers restricted to their own accounts, its owner. ok is false if
+// req is for another owner than such a caller.
+func toAccountImport(ctx context.Context, req model.CreateAccountRequest) (_ store.AccountImport, ok bool) {
+	if owner, scoped := store.OwnerFromContext(ctx); scoped {
+		if req.OwnerID != "" && req.OwnerID != owner {
+			return store.AccountImport{}, false
+		}
+		req.OwnerID = owner
+	}
+	currency := req.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	return store.AccountImport{
+		AccountID:  req.AccountID,
+		ExternalID: req.ExternalID,
+		Initial:    req.InitialBalance.Decimal,
+		Currency:   currency,
+		Metadata:   store.AccountMetadata{DisplayName: req.DisplayName, OwnerRef: req.OwnerRef, Tags: req.Tags},
+		OwnerID:    req.OwnerID,
+	}, true
+}
+
+// parseImportCSV reads a CSV import file, collecting its invalid rows. It
+// fails on errors about the whole file.
+func parseImportCSV(rd io.Reader) (*importFile, error) {
+	cr := csv.NewReader(rd)
+	cr.TrimLeadingSpace = true
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("file is empty")
+		}
+		return nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	setters := make([]func(*model.CreateAccountRequest, string) error, len(header))
+	names := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		set, ok := importCSVColumns[name]
+		if !ok || seen[name] {
+			return nil, fmt.Errorf("header: unknown or repeated column %q", name)
+		}
+		setters[i], names[i], seen[name] = set, name, true
+	}
+	if !seen["initial_balance"] || !seen["account_id"] && !seen["external_id"] {
+		return nil, errors.New("header must have initial_balance, and account_id or external_id")
+	}
+
+	f := &importFile{}
+	for row := 1; ; row++ {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return f, nil
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			f.reject(row, "", "invalid CSV: "+parseErr.Err.Error())
+			continue
+		}
+		var req model.CreateAccountRequest
+		var field string
+		for i, v := range rec {
+			if err = setters[i](&req, strings.TrimSpace(v)); err != nil {
+				field = names[i]
+				break
+			}
+		}
+		if err != nil {
+			f.reject(row, field, err.Error())
+			continue
+		}
+		if err := f.add(row, req); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseImportJSONL reads a JSON lines import file, collecting its invalid
+// rows, which are numbered by line. Blank lines are skipped. It fails on
+// errors about the whole file.
+func parseImportJSONL(rd io.Reader) (*importFile, error) {
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 0, 4096), maxImportLineBytes)
+
+	f := &importFile{}
+	for row := 1; sc.Scan(); row++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req model.CreateAccountRequest
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.DisallowUnknownFields()
+		err := dec.Decode(&req)
+		if err == nil && dec.More() {
+			err = errTrailingData
+		}
+		if err != nil {
+			var typeErr *json.UnmarshalTypeError
+			field, unknown := strings.CutPrefix(err.Error(), "json: unknown field ")
+			switch {
+			case unknown:
+				f.reject(row, strings.Trim(field, `"`), "unknown field")
+			case errors.Is(err, model.ErrNumericAmount), errors.Is(err, model.ErrDecimalOutOfRange):
+				f.reject(row, "initial_balance", err.Error())
+			case errors.As(err, &typeErr):
+				f.reject(row, typeErr.Field, "must be a JSON "+typeErr.Type.Kind().String())
+			default:
+				f.reject(row, "", "invalid JSON")
+			}
+			continue
+		}
+		if err := f.add(row, req); err != nil {
+			return nil, err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, fmt.Errorf("lines must be at most %d bytes", maxImportLineBytes)
+		}
+		return nil, err
+	}
+	return f, nil
+}
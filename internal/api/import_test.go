@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// importErrorRows decodes the rejected rows of an import error response
+func importErrorRows(t *testing.T, w *httptest.ResponseRecorder) (model.ErrorResponse, []model.AccountImportError) {
+	t.Helper()
+	var resp struct {
+		model.ErrorResponse
+		Details struct {
+			RejectedRows int                        `json:"rejected_rows"`
+			Rows         []model.AccountImportError `json:"rows"`
+		} `json:"details"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Details.RejectedRows != len(resp.Details.Rows) {
+		t.Fatalf("expected %d rows listed, got %+v", resp.Details.RejectedRows, resp.Details.Rows)
+	}
+	return resp.ErrorResponse, resp.Details.Rows
+}
+
+// TestImportAccounts tests that the accounts of CSV and JSON lines files are
+// imported at once
+func TestImportAccounts(t *testing.T) {
+	tests := []struct {
+		format string
+		body   string
+	}{
+		{"", "initial_balance,account_id,tags,currency\n10.50,1,a;b,EUR\n0, 2,,\n"},
+		{"jsonl", `{"account_id":1,"initial_balance":"10.50","tags":["a","b"],"currency":"EUR"}` + "\n\n" + `{"account_id":2,"initial_balance":"0"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var got []store.AccountImport
+			api := New(&MockStore{ImportFunc: func(ctx context.Context, accounts []store.AccountImport) error {
+				got = accounts
+				return nil
+			}})
+
+			w := httptest.NewRecorder()
+			api.ImportAccounts(w, httptest.NewRequest(http.MethodPost, "/admin/accounts/import?format="+tt.format, strings.NewReader(tt.body)))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+			}
+			var resp model.AccountImportResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.ImportedRows != 2 || len(got) != 2 {
+				t.Fatalf("expected 2 accounts imported, got %+v and %+v", resp, got)
+			}
+			if got[0].AccountID != 1 || got[0].Initial.String() != "10.5" || got[0].Currency != "EUR" || len(got[0].Metadata.Tags) != 2 {
+				t.Fatalf("unexpected first account: %+v", got[0])
+			}
+			if got[1].AccountID != 2 || !got[1].Initial.IsZero() || got[1].Currency != model.DefaultCurrency {
+				t.Fatalf("unexpected second account: %+v", got[1])
+			}
+		})
+	}
+}
+
+// TestImportAccounts_InvalidRows tests that the invalid rows are reported and
+// nothing is imported
+func TestImportAccounts_InvalidRows(t *testing.T) {
+	api := New(&MockStore{ImportFunc: func(ctx context.Context, accounts []store.AccountImport) error {
+		t.Fatal("store must not be called for an invalid file")
+		return nil
+	}})
+
+	tests := []struct {
+		name   string
+		format string
+		body   string
+		rows   []int
+		field  string
+	}{
+		{"csv", "csv", "account_id,initial_balance\n1,10\nx,10\n3,-1\n4\n", []int{2, 3, 4}, "account_id"},
+		{"jsonl", "jsonl", `{"account_id":1,"initial_balance":"1"}` + "\n" + `{"account_id":"2","initial_balance":"1"}` + "\n" + `{"account_id":3,"balance":"1"}` + "\n{\n", []int{2, 3, 4}, "account_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.ImportAccounts(w, httptest.NewRequest(http.MethodPost, "/admin/accounts/import?format="+tt.format, strings.NewReader(tt.body)))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			resp, rows := importErrorRows(t, w)
+			if resp.Code != model.ErrCodeValidationFailed || len(rows) != len(tt.rows) {
+				t.Fatalf("unexpected error response: %+v, rows %+v", resp, rows)
+			}
+			for i, row := range tt.rows {
+				if rows[i].Row != row || rows[i].Error == "" {
+					t.Fatalf("expected row %d rejected, got %+v", row, rows[i])
+				}
+			}
+			if rows[0].Field != tt.field {
+				t.Fatalf("expected the first rejection about %s, got %+v", tt.field, rows[0])
+			}
+		})
+	}
+}
+
+// TestImportAccounts_InvalidFile tests the files rejected as a whole
+func TestImportAccounts_InvalidFile(t *testing.T) {
+	api := New(&MockStore{}, WithMaxImportBytes(64))
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+	}{
+		{"empty", "", "", http.StatusBadRequest},
+		{"unknown column", "", "account_id,initial_balance,balance\n1,1,1\n", http.StatusBadRequest},
+		{"no id column", "", "initial_balance\n1\n", http.StatusBadRequest},
+		{"no rows", "", "account_id,initial_balance\n", http.StatusBadRequest},
+		{"blank lines", "?format=jsonl", "\n\n", http.StatusBadRequest},
+		{"format", "?format=xml", "", http.StatusBadRequest},
+		{"too large", "", "account_id,initial_balance\n" + strings.Repeat("1,1\n", 20), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.ImportAccounts(w, httptest.NewRequest(http.MethodPost, "/admin/accounts/import"+tt.query, strings.NewReader(tt.body)))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// TestImportAccounts_Conflicts tests that the rows conflicting with existing
+// accounts are reported by their row in the file
+func TestImportAccounts_Conflicts(t *testing.T) {
+	api := New(&MockStore{ImportFunc: func(ctx context.Context, accounts []store.AccountImport) error {
+		return &store.ImportError{Rows: []store.ImportRowError{{Row: 2, Field: "external_id"}}}
+	}})
+
+	body := `{"account_id":1,"initial_balance":"1"}` + "\n\n" + `{"external_id":"acme","initial_balance":"1"}` + "\n"
+	w := httptest.NewRecorder()
+	api.ImportAccounts(w, httptest.NewRequest(http.MethodPost, "/admin/accounts/import?format=jsonl", strings.NewReader(body)))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+	resp, rows := importErrorRows(t, w)
+	if resp.Code != model.ErrCodeDuplicateAccount || len(rows) != 1 || rows[0].Row != 3 || rows[0].Field != "external_id" {
+		t.Fatalf("expected line 3 to conflict, got %+v, rows %+v", resp, rows)
+	}
+}
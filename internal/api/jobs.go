@@ -0,0 +1,240 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// DefaultMaxJobFileBytes caps the size of an uploaded bulk transfer file
+// unless WithMaxJobFileBytes is given.
+const DefaultMaxJobFileBytes = 10 << 20
+
+// WithMaxJobFileBytes caps the size of uploaded bulk transfer files; larger
+// files are rejected with 413.
+func WithMaxJobFileBytes(n int64) Option {
+	return func(a *API) {
+		a.maxJobFileBytes = n
+	}
+}
+
+// jobCSVHeader is the required header row of a bulk transfer file
+var jobCSVHeader = []string{"source_account_id", "destination_account_id", "amount"}
+
+// toTransferJobResponse maps a stored job and its failed rows to their JSON representation
+func toTransferJobResponse(job store.TransferJob, failures []store.TransferJobRow) model.TransferJobResponse {
+	resp := model.TransferJobResponse{
+		JobID:         job.ID,
+		Status:        job.Status,
+		TotalRows:     job.TotalRows,
+		ProcessedRows: job.Succeeded + job.Failed,
+		SucceededRows: job.Succeeded,
+		FailedRows:    job.Failed,
+		CreatedAt:     job.CreatedAt,
+		FinishedAt:    job.FinishedAt,
+		Failures:      make([]model.TransferJobFailure, 0, len(failures)),
+	}
+	for _, f := range failures {
+		resp.Failures = append(resp.Failures, model.TransferJobFailure{
+			Row:                  f.Row,
+			SourceAccountID:      f.SourceAccountID,
+			DestinationAccountID: f.DestinationAccountID,
+			Amount:               model.DecimalString{Decimal: f.Amount},
+			Error:                f.ErrorMessage,
+		})
+	}
+	return resp
+}
+
+// CreateTransferJob queues the transfers of an uploaded CSV file for asynchronous execution.
+// The file is sent as the "file" field of a multipart form.
+func (a *API) CreateTransferJob(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, a.maxJobFileBytes)
+	if err := r.ParseMultipartForm(a.maxJobFileBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, model.ErrCodeValidationFailed, fmt.Sprintf("file must be at most %d bytes", a.maxJobFileBytes))
+			return
+		}
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "expected a multipart form")
+		return
+	}
+	f, _, err := r.FormFile("file")
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "file is required", map[string]interface{}{"parameter": "file"})
+		return
+	}
+	defer f.Close()
+
+	reqs, row, err := parseTransferCSV(f)
+	if err != nil {
+		details := map[string]interface{}{}
+		if row > 0 {
+			details["row"] = row
+		}
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error(), details)
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	// Unknown external ids resolve to 0 and their rows fail like unknown accounts
+	refs := make([]model.AccountID, 0, 2*len(reqs))
+	for _, req := range reqs {
+		refs = append(refs, req.SourceAccountID, req.DestinationAccountID)
+	}
+	ids, err := a.resolveAccountIDs(ctx, refs...)
+	if err != nil {
+		a.logger.Printf("resolve job accounts failed: rows=%d, error=%v", len(reqs), err)
+		internalError(w, err)
+		return
+	}
+	items := make([]store.TransferItem, len(reqs))
+	for i, req := range reqs {
+		items[i] = store.TransferItem{
+			SourceAccountID:      ids[2*i],
+			DestinationAccountID: ids[2*i+1],
+			Amount:               req.Amount.Decimal,
+		}
+		treq := TransferRequest{
+			Kind:                 TransferKindJob,
+			SourceAccountID:      items[i].SourceAccountID,
+			DestinationAccountID: items[i].DestinationAccountID,
+			Amount:               items[i].Amount,
+		}
+		if !a.allowBatchItem(ctx, w, treq, "row %d", i+1, "row") {
+			return
+		}
+	}
+
+	p, _ := auth.FromContext(ctx)
+	job, err := a.store.CreateTransferJob(ctx, items, p.Subject)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("create transfer job failed: rows=%d, error=%v", len(items), err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, toTransferJobResponse(job, nil))
+}
+
+// parseTransferCSV reads and validates a bulk transfer file. On error, row is
+// the 1-based data row at fault, or 0 when the error is not about a single row.
+func parseTransferCSV(rd io.Reader) (_ []model.TransactionRequest, row int, _ error) {
+	cr := csv.NewReader(rd)
+	cr.FieldsPerRecord = len(jobCSVHeader)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, errors.New("file is empty")
+		}
+		return nil, 0, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	for i, name := range jobCSVHeader {
+		if strings.TrimSpace(header[i]) != name {
+			return nil, 0, fmt.Errorf("header must be %s", strings.Join(jobCSVHeader, ","))
+		}
+	}
+
+	var reqs []model.TransactionRequest
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		row++
+		if err != nil {
+			return nil, row, fmt.Errorf("row %d: invalid CSV: %w", row, err)
+		}
+		if row > model.MaxJobRows {
+			return nil, 0, fmt.Errorf("file must contain at most %d rows", model.MaxJobRows)
+		}
+
+		req, err := parseTransferRecord(rec)
+		if err == nil {
+			err = req.Validate()
+		}
+		if err != nil {
+			return nil, row, fmt.Errorf("row %d: %w", row, err)
+		}
+		reqs = append(reqs, req)
+	}
+	if len(reqs) == 0 {
+		return nil, 0, errors.New("file contains no transfers")
+	}
+	return reqs, 0, nil
+}
+
+// parseTransferRecord converts a CSV record laid out as jobCSVHeader
+func parseTransferRecord(rec []string) (model.TransactionRequest, error) {
+	src, err := model.ParseAccountID(strings.TrimSpace(rec[0]))
+	if err != nil {
+		return model.TransactionRequest{}, errors.New("invalid source_account_id")
+	}
+	dst, err := model.ParseAccountID(strings.TrimSpace(rec[1]))
+	if err != nil {
+		return model.TransactionRequest{}, errors.New("invalid destination_account_id")
+	}
+	amount, err := model.ParseDecimal(strings.TrimSpace(rec[2]))
+	if err != nil {
+		return model.TransactionRequest{}, errors.New("invalid amount")
+	}
+	return model.TransactionRequest{
+		SourceAccountID:      src,
+		DestinationAccountID: dst,
+		Amount:               model.DecimalString{Decimal: amount},
+	}, nil
+}
+
+// GetTransferJob reports the progress of a bulk transfer job and its failed rows
+func (a *API) GetTransferJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid job id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	job, err := a.store.GetTransferJob(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrTransferJobNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeJobNotFound, "job not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get transfer job failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+	var failures []store.TransferJobRow
+	if job.Failed > 0 {
+		failures, err = a.store.ListTransferJobFailures(ctx, id, model.MaxJobFailures)
+		if err != nil {
+			a.logger.Printf("list transfer job failures failed: id=%d, error=%v", id, err)
+			internalError(w, err)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, toTransferJobResponse(job, failures))
+}
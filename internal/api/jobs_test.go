@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// newJobUpload builds a multipart POST /jobs/transfers request carrying csv as the file field
+func newJobUpload(t *testing.T, csv string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "transfers.csv")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := fw.Write([]byte(csv)); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("close multipart writer: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/jobs/transfers", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+// TestCreateTransferJob tests that the rows of an uploaded file are queued as a job
+func TestCreateTransferJob(t *testing.T) {
+	var got []store.TransferItem
+	var gotInitiatedBy string
+	mockStore := &MockStore{
+		CreateJobFunc: func(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error) {
+			got, gotInitiatedBy = items, initiatedBy
+			return store.TransferJob{ID: 9, Status: store.JobQueued, TotalRows: len(items)}, nil
+		},
+	}
+	api := New(mockStore)
+
+	w := httptest.NewRecorder()
+	req := newJobUpload(t, "source_account_id,destination_account_id,amount\n1,2,10.50\n2,3,1\n")
+	api.CreateTransferJob(w, req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "ops", Role: auth.RoleAdmin})))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	if len(got) != 2 || gotInitiatedBy != "ops" || got[0].SourceAccountID != 1 || got[0].DestinationAccountID != 2 || !got[0].Amount.Equal(decimal.RequireFromString("10.50")) {
+		t.Fatalf("unexpected items: %+v", got)
+	}
+	var resp model.TransferJobResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.JobID != 9 || resp.Status != store.JobQueued || resp.TotalRows != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// TestCreateTransferJob_InvalidFile tests that bad files are rejected before a job is created
+func TestCreateTransferJob_InvalidFile(t *testing.T) {
+	mockStore := &MockStore{
+		CreateJobFunc: func(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error) {
+			t.Fatal("store must not be called for an invalid file")
+			return store.TransferJob{}, nil
+		},
+	}
+	api := New(mockStore)
+
+	tests := []struct {
+		name    string
+		csv     string
+		wantRow float64
+	}{
+		{"empty", "", 0},
+		{"wrong header", "src,dst,amount\n1,2,10\n", 0},
+		{"no rows", "source_account_id,destination_account_id,amount\n", 0},
+		{"invalid amount", "source_account_id,destination_account_id,amount\n1,2,10\n1,2,abc\n", 2},
+		{"same accounts", "source_account_id,destination_account_id,amount\n1,1,10\n", 1},
+		{"missing column", "source_account_id,destination_account_id,amount\n1,2\n", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			api.CreateTransferJob(w, newJobUpload(t, tt.csv))
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			row, _ := resp.Details["row"].(float64)
+			if resp.Code != model.ErrCodeValidationFailed || row != tt.wantRow {
+				t.Fatalf("unexpected error response: %+v", resp)
+			}
+		})
+	}
+}
+
+// TestGetTransferJob tests progress reporting, failed rows and not found mapping
+func TestGetTransferJob(t *testing.T) {
+	mockStore := &MockStore{
+		GetJobFunc: func(ctx context.Context, id int64) (store.TransferJob, error) {
+			if id != 9 {
+				return store.TransferJob{}, store.ErrTransferJobNotFound
+			}
+			return store.TransferJob{ID: 9, Status: store.JobRunning, TotalRows: 5, Succeeded: 2, Failed: 1}, nil
+		},
+		ListJobFailuresFunc: func(ctx context.Context, jobID int64, limit int) ([]store.TransferJobRow, error) {
+			return []store.TransferJobRow{{JobID: jobID, Row: 3, Status: store.JobRowFailed, ErrorMessage: "insufficient funds"}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/jobs/9", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.TransferJobResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ProcessedRows != 3 || resp.FailedRows != 1 || len(resp.Failures) != 1 || resp.Failures[0].Row != 3 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/jobs/10", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -1,15 +1,144 @@
 package api
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/you/internal-transfers/internal/model"
 )
 
-func LoggingMiddleware(next http.Handler) http.Handler {
+const tracerName = "github.com/you/internal-transfers/internal/api"
+
+// LoggingMiddleware logs the requests selected by the runtime log level: all
+// of them at debug and info, with their query and client address at debug,
+// and only failed ones at warn and error.
+func (a *API) LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		level := a.Runtime().LogLevel
+		switch {
+		case level == model.LogLevelDebug:
+			a.logger.Printf("%s %s?%s %d %s from %s", r.Method, r.URL.Path, r.URL.RawQuery, rec.status, time.Since(start), r.RemoteAddr)
+		case level == model.LogLevelWarn && rec.status < http.StatusBadRequest,
+			level == model.LogLevelError && rec.status < http.StatusInternalServerError:
+		default:
+			a.logger.Printf("%s %s %d %s", r.Method, r.URL.Path, rec.status, time.Since(start))
+		}
+	})
+}
+
+// TracingMiddleware starts a server span per request, continuing any trace
+// propagated by the caller. Spans are named after the matched route template
+// so that e.g. all balance lookups share one span name.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		route := r.URL.Path
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if tpl, err := cr.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		ctx, span := otel.Tracer(tracerName).Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", rec.status))
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+	})
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	s.status = code
+	s.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// deprecationMiddleware marks responses of unversioned routes as deprecated
+// and points clients at the /v1 equivalent.
+func deprecationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "</v1"+r.URL.Path+">; rel=\"successor-version\"")
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
 	})
 }
+
+// HeaderRequestID carries the id of a request. A valid id sent by the caller is
+// kept, e.g. to follow a request across services; otherwise one is generated.
+// Either way it is echoed in the response and recorded with the transfers the
+// request makes.
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLen bounds the length of request ids accepted from callers
+const maxRequestIDLen = 128
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the id of the request ctx belongs to, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestID gives each request an id, the caller's X-Request-ID if valid, sets
+// it on the response and makes it available through RequestIDFromContext.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// validRequestID reports whether id is a non-empty request id of at most
+// maxRequestIDLen printable ASCII characters other than spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestTracingMiddleware_SpanPerRequest tests that a server span named after the route is recorded
+func TestTracingMiddleware_SpanPerRequest(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	mockStore := &MockStore{
+		GetAccountFunc: func(ctx context.Context, accountID int64) (store.Account, error) {
+			return store.Account{ID: accountID, Balance: decimal.NewFromInt(1)}, nil
+		},
+	}
+	r := mux.NewRouter()
+	r.Use(TracingMiddleware)
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100", nil))
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if name := spans[0].Name(); name != "GET /v1/accounts/{id}" {
+		t.Fatalf("unexpected span name %q", name)
+	}
+	var status attribute.Value
+	for _, kv := range spans[0].Attributes() {
+		if kv.Key == "http.response.status_code" {
+			status = kv.Value
+		}
+	}
+	if status.AsInt64() != http.StatusOK {
+		t.Fatalf("expected status attribute %d, got %v", http.StatusOK, status.Emit())
+	}
+}
+
+// TestRequestID tests that transfers are recorded with the caller, the channel
+// and the request id, which is the caller's if valid and echoed in the response
+func TestRequestID(t *testing.T) {
+	var got store.TransferDetails
+	r := newAuthRouter(t, &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			got = details
+			return 1, nil
+		},
+	})
+	transfer := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/transactions", strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "1"}`))
+		req.Header.Set(auth.APIKeyHeader, "service-key")
+		if id != "" {
+			req.Header.Set(HeaderRequestID, id)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	w := transfer("order-7/attempt-1")
+	if got.InitiatedBy != "payments" || got.Channel != store.ChannelHTTP || got.RequestID != "order-7/attempt-1" {
+		t.Fatalf("unexpected details: %+v", got)
+	}
+	if h := w.Header().Get(HeaderRequestID); h != "order-7/attempt-1" {
+		t.Fatalf("expected the request id to be echoed, got %q", h)
+	}
+
+	for _, id := range []string{"", "has space", strings.Repeat("x", maxRequestIDLen+1)} {
+		w := transfer(id)
+		if _, err := uuid.Parse(got.RequestID); err != nil {
+			t.Fatalf("expected a generated request id for %q, got %q", id, got.RequestID)
+		}
+		if h := w.Header().Get(HeaderRequestID); h != got.RequestID {
+			t.Fatalf("expected header %q, got %q", got.RequestID, h)
+		}
+	}
+}
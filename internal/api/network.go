@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// NetworkPolicy restricts the networks application routes are served to.
+type NetworkPolicy struct {
+	// Allow lists the networks clients may connect from; any if empty.
+	Allow []netip.Prefix
+	// TrustedProxies lists the networks of the proxies whose X-Forwarded-For
+	// is believed. Requests through them are attributed to the last address
+	// of X-Forwarded-For not of a trusted proxy.
+	TrustedProxies []netip.Prefix
+}
+
+// WithNetworkPolicy refuses application requests from networks p does not
+// allow, and callers restricted to networks (auth.Principal.Networks) when
+// connecting from others, with 403. Health endpoints stay open.
+func WithNetworkPolicy(p NetworkPolicy) Option {
+	return func(a *API) {
+		a.network = p
+	}
+}
+
+type clientAddrKey struct{}
+
+// ClientAddr returns the address of the client of the request ctx belongs to,
+// as resolved by the network policy.
+func ClientAddr(ctx context.Context) (netip.Addr, bool) {
+	addr, ok := ctx.Value(clientAddrKey{}).(netip.Addr)
+	return addr, ok
+}
+
+// networkPolicy resolves the client's address, making it available through
+// ClientAddr, and refuses clients outside the allowed networks.
+func (a *API) networkPolicy(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addr, ok := clientAddr(r, a.network.TrustedProxies)
+		if !ok {
+			if len(a.network.Allow) > 0 {
+				writeError(w, http.StatusForbidden, model.ErrCodeNetworkNotAllowed, "client address unknown")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(a.network.Allow) > 0 && !auth.Contains(a.network.Allow, addr) {
+			writeError(w, http.StatusForbidden, model.ErrCodeNetworkNotAllowed, "requests from "+addr.String()+" are not allowed")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientAddrKey{}, addr)))
+	})
+}
+
+// clientAddr returns the address of the client of r: its remote address or,
+// when that is a trusted proxy, the last address of X-Forwarded-For not of a
+// trusted proxy (the first if all are). Addresses added by untrusted hops
+// cannot be told from forged ones, so they are never looked past. ok is false
+// if the address cannot be parsed.
+func clientAddr(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if !auth.Contains(trusted, addr) {
+		return addr, true
+	}
+	var hops []string
+	for _, v := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = hop.Unmap()
+		if !auth.Contains(trusted, addr) {
+			return addr, true
+		}
+	}
+	return addr, true
+}
@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestClientAddr tests resolving the client's address behind trusted proxies
+func TestClientAddr(t *testing.T) {
+	trusted, err := auth.ParseNetworks("10.0.0.0/8", ",")
+	if err != nil {
+		t.Fatalf("ParseNetworks failed: %v", err)
+	}
+	for name, c := range map[string]struct {
+		remote string
+		xff    []string
+		want   string // "" if unknown
+	}{
+		"direct":                 {"203.0.113.9:5000", nil, "203.0.113.9"},
+		"direct ignores xff":     {"203.0.113.9:5000", []string{"198.51.100.1"}, "203.0.113.9"},
+		"behind proxy":           {"10.0.0.2:5000", []string{"198.51.100.1"}, "198.51.100.1"},
+		"forged hops ignored":    {"10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1, 10.0.0.3"}, "198.51.100.1"},
+		"several headers":        {"10.0.0.2:5000", []string{"1.2.3.4", "198.51.100.1"}, "198.51.100.1"},
+		"only proxies":           {"10.0.0.2:5000", []string{"10.0.0.4"}, "10.0.0.4"},
+		"proxy without xff":      {"10.0.0.2:5000", nil, "10.0.0.2"},
+		"ipv4-mapped":            {"[::ffff:203.0.113.9]:5000", nil, "203.0.113.9"},
+		"malformed xff":          {"10.0.0.2:5000", []string{"not-an-ip"}, ""},
+		"malformed remote":       {"somewhere", nil, ""},
+		"ipv6 behind proxy":      {"10.0.0.2:5000", []string{"2001:db8::1"}, "2001:db8::1"},
+		"xff with spaces parsed": {"10.0.0.2:5000", []string{" 198.51.100.1 "}, "198.51.100.1"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/v1/accounts", nil)
+		r.RemoteAddr = c.remote
+		for _, v := range c.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		addr, ok := clientAddr(r, trusted)
+		if c.want == "" {
+			if ok {
+				t.Fatalf("%s: expected an unknown address, got %s", name, addr)
+			}
+			continue
+		}
+		if !ok || addr.String() != c.want {
+			t.Fatalf("%s: expected %s, got %s (%v)", name, c.want, addr, ok)
+		}
+	}
+}
+
+// TestNetworkPolicy tests the allowlists of the deployment and of API keys
+func TestNetworkPolicy(t *testing.T) {
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,payments:service:service-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	for hash, p := range keys {
+		if p.Subject == "payments" {
+			p.Networks = []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+			keys[hash] = p
+		}
+	}
+	allow, _ := auth.ParseNetworks("192.168.0.0/16,203.0.113.9", ",")
+	trusted, _ := auth.ParseNetworks("10.0.0.0/8", ",")
+	r := mux.NewRouter()
+	New(&MockStore{}, WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil)),
+		WithNetworkPolicy(NetworkPolicy{Allow: allow, TrustedProxies: trusted})).RegisterRoutes(r)
+
+	for _, c := range []struct {
+		remote, xff, key string
+		want             int
+	}{
+		{"192.168.1.5:1000", "", "admin-key", http.StatusOK},
+		{"203.0.113.9:1000", "", "admin-key", http.StatusOK},
+		{"203.0.113.10:1000", "", "admin-key", http.StatusForbidden},
+		{"10.0.0.2:1000", "192.168.2.1", "admin-key", http.StatusOK},
+		{"10.0.0.2:1000", "198.51.100.1", "admin-key", http.StatusForbidden},
+		{"198.51.100.1:1000", "192.168.1.5", "admin-key", http.StatusForbidden},
+		{"192.168.1.5:1000", "", "service-key", http.StatusOK},
+		{"192.168.2.1:1000", "", "service-key", http.StatusForbidden},
+		{"203.0.113.9:1000", "", "", http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		req.RemoteAddr = c.remote
+		if c.xff != "" {
+			req.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if c.key != "" {
+			req.Header.Set(auth.APIKeyHeader, c.key)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want {
+			t.Fatalf("%s via %q with %q: expected status %d, got %d", c.remote, c.xff, c.key, c.want, w.Code)
+		}
+		if c.want == http.StatusForbidden {
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != model.ErrCodeNetworkNotAllowed {
+				t.Fatalf("%s: expected %s, got %+v, %v", c.remote, model.ErrCodeNetworkNotAllowed, resp, err)
+			}
+		}
+	}
+}
+
+// TestCreateAPIKey_Networks tests restricting a stored key to networks
+func TestCreateAPIKey_Networks(t *testing.T) {
+	var got []netip.Prefix
+	mockStore := &MockStore{
+		CreateAPIKeyFunc: func(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error) {
+			got = networks
+			return store.APIKey{ID: 7, Name: name, Role: role, Networks: networks}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	body := `{"name": "payroll", "role": "service", "allowed_networks": ["10.1.2.3/16", "192.168.1.5"]}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/apikeys", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	var resp model.APIKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(got) != 2 || len(resp.AllowedNetworks) != 2 || resp.AllowedNetworks[0] != "10.1.0.0/16" || resp.AllowedNetworks[1] != "192.168.1.5/32" {
+		t.Fatalf("unexpected networks: stored %v, returned %v", got, resp.AllowedNetworks)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/apikeys", bytes.NewReader([]byte(`{"name": "payroll", "role": "service", "allowed_networks": ["10.0.0.0/40"]}`))))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid network, got %d", http.StatusBadRequest, w.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toAccountErasureResponse maps a stored erasure to its JSON representation
+func toAccountErasureResponse(e store.AccountErasure) model.AccountErasureResponse {
+	return model.AccountErasureResponse{
+		ErasureID: e.ID,
+		AccountID: e.AccountID,
+		Reason:    e.Reason,
+		ErasedBy:  e.ErasedBy,
+		Fields:    e.Fields,
+		ErasedAt:  e.ErasedAt,
+	}
+}
+
+// toAccountDataExport maps everything held about an account to its JSON representation
+func (a *API) toAccountDataExport(d store.AccountData) model.AccountDataExport {
+	resp := model.AccountDataExport{
+		Account:            toAccountResponse(d.Account),
+		Wallets:            make([]model.AccountResponse, len(d.Wallets)),
+		Aliases:            make([]model.AccountAlias, len(d.Aliases)),
+		Counterparties:     toCounterpartiesResponse(d.Counterparties),
+		AlertThresholds:    toAlertThresholdsResponse(d.AlertThresholds),
+		Alerts:             make([]model.AlertResponse, len(d.Alerts)),
+		Holds:              make([]model.HoldResponse, len(d.Holds)),
+		ScheduledTransfers: make([]model.ScheduledTransferResponse, len(d.ScheduledTransfers)),
+		Transactions:       make([]model.Transaction, len(d.Transactions)),
+		Erasures:           make([]model.AccountErasureResponse, len(d.Erasures)),
+		ExportedAt:         d.ExportedAt,
+	}
+	for i, acc := range d.Wallets {
+		resp.Wallets[i] = toAccountResponse(acc)
+	}
+	for i, al := range d.Aliases {
+		resp.Aliases[i] = toAccountAlias(al)
+	}
+	for i, al := range d.Alerts {
+		resp.Alerts[i] = a.toAlertResponse(al)
+	}
+	for i, h := range d.Holds {
+		resp.Holds[i] = a.toHoldResponse(h)
+	}
+	for i, st := range d.ScheduledTransfers {
+		resp.ScheduledTransfers[i] = a.toScheduledTransferResponse(st)
+	}
+	for i, t := range d.Transactions {
+		resp.Transactions[i] = a.toTransaction(t)
+	}
+	for i, e := range d.Erasures {
+		resp.Erasures[i] = toAccountErasureResponse(e)
+	}
+	return resp
+}
+
+// ExportAccountData returns everything held about the account, e.g. to answer
+// a data subject access request
+func (a *API) ExportAccountData(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	d, err := a.store.AccountData(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("export account data failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-%d-data.json"`, id))
+	writeJSON(w, http.StatusOK, a.toAccountDataExport(d))
+}
+
+// EraseAccount erases the personal data of a closed account and of its
+// wallets, keeping their ledger. It answers 201 with the recorded erasure, or
+// 200 with the latest one if they hold no personal data since.
+func (a *API) EraseAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.EraseAccountRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+	var erasedBy string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		erasedBy = p.Subject
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	e, created, err := a.store.EraseAccount(ctx, id, strings.TrimSpace(req.Reason), erasedBy)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		case errors.Is(err, store.ErrAccountNotClosed):
+			writeError(w, http.StatusConflict, model.ErrCodeAccountNotClosed, "account is not closed")
+		default:
+			a.logger.Printf("erase account failed: accountID=%d, error=%v", id, err)
+			internalError(w, err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	writeJSON(w, status, toAccountErasureResponse(e))
+}
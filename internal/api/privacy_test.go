@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestExportAccountData tests exporting everything held about an account
+func TestExportAccountData(t *testing.T) {
+	erasedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockStore := &MockStore{
+		AccountDataFunc: func(ctx context.Context, accountID int64) (store.AccountData, error) {
+			if accountID == 404 {
+				return store.AccountData{}, store.ErrAccountNotFound
+			}
+			return store.AccountData{
+				Account:      store.Account{ID: accountID, Balance: decimal.NewFromInt(10), Status: store.AccountClosed},
+				Aliases:      []store.AccountAlias{{Alias: "acme-ops", AccountID: accountID}},
+				Holds:        []store.Hold{{ID: 3, SourceAccountID: accountID, DestinationAccountID: 2, Amount: decimal.NewFromInt(1)}},
+				Transactions: []store.Transaction{{ID: 7, SourceAccountID: accountID, DestinationAccountID: 2, Amount: decimal.NewFromInt(5)}},
+				Erasures:     []store.AccountErasure{{ID: 1, AccountID: accountID, Reason: "DSR-1", Fields: []string{store.ErasedDisplayName}, ErasedAt: erasedAt}},
+			}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/accounts/1/data-export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="account-1-data.json"` {
+		t.Fatalf("unexpected Content-Disposition %q", got)
+	}
+	var resp model.AccountDataExport
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Account.AccountID != 1 || len(resp.Aliases) != 1 || len(resp.Holds) != 1 || len(resp.Transactions) != 1 ||
+		resp.Wallets == nil || resp.Alerts == nil || resp.ScheduledTransfers == nil {
+		t.Fatalf("unexpected export: %+v", resp)
+	}
+	if len(resp.Erasures) != 1 || resp.Erasures[0].Reason != "DSR-1" || !resp.Erasures[0].ErasedAt.Equal(erasedAt) {
+		t.Fatalf("unexpected erasures: %+v", resp.Erasures)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/accounts/404/data-export", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", w.Code)
+	}
+}
+
+// TestEraseAccount tests erasing the personal data of an account
+func TestEraseAccount(t *testing.T) {
+	var gotReason, gotBy string
+	mockStore := &MockStore{
+		EraseAccountFunc: func(ctx context.Context, accountID int64, reason, erasedBy string) (store.AccountErasure, bool, error) {
+			switch accountID {
+			case 404:
+				return store.AccountErasure{}, false, store.ErrAccountNotFound
+			case 409:
+				return store.AccountErasure{}, false, store.ErrAccountNotClosed
+			case 2:
+				return store.AccountErasure{ID: 1, AccountID: accountID, Reason: "earlier", Fields: []string{}}, false, nil
+			}
+			gotReason, gotBy = reason, erasedBy
+			return store.AccountErasure{ID: 2, AccountID: accountID, Reason: reason, ErasedBy: erasedBy,
+				Fields: []string{store.ErasedDisplayName, store.ErasedAliases}}, true, nil
+		},
+	}
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,payments:service:service-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	r := mux.NewRouter()
+	New(mockStore, WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil))).RegisterRoutes(r)
+
+	cases := []struct {
+		path, body, key string
+		want            int
+	}{
+		{"/v1/admin/accounts/1/erasure", `{"reason": "DSR-2"}`, "service-key", http.StatusForbidden},
+		{"/v1/admin/accounts/1/erasure", `{"reason": " "}`, "admin-key", http.StatusBadRequest},
+		{"/v1/admin/accounts/1/erasure", `{}`, "admin-key", http.StatusBadRequest},
+		{"/v1/admin/accounts/404/erasure", `{"reason": "DSR-2"}`, "admin-key", http.StatusNotFound},
+		{"/v1/admin/accounts/409/erasure", `{"reason": "DSR-2"}`, "admin-key", http.StatusConflict},
+		{"/v1/admin/accounts/2/erasure", `{"reason": "DSR-2"}`, "admin-key", http.StatusOK},
+		{"/v1/admin/accounts/1/erasure", `{"reason": " DSR-2 "}`, "admin-key", http.StatusCreated},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, c.path, bytes.NewReader([]byte(c.body)))
+		req.Header.Set(auth.APIKeyHeader, c.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", c.path, c.body, c.want, w.Code, w.Body.String())
+		}
+		if c.want == http.StatusCreated {
+			var resp model.AccountErasureResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.ErasureID != 2 || !slices.Equal(resp.Fields, []string{"display_name", "aliases"}) {
+				t.Fatalf("unexpected erasure: %+v", resp)
+			}
+		}
+	}
+	if gotReason != "DSR-2" || gotBy != "ops" {
+		t.Fatalf("expected reason DSR-2 by ops, got %q by %q", gotReason, gotBy)
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// Idle clients are forgotten after limiterIdleTTL; the map is swept at most once per limiterSweepEvery.
+const (
+	limiterIdleTTL    = 10 * time.Minute
+	limiterSweepEvery = time.Minute
+)
+
+// RateLimiter applies a token bucket per client. Every request is held to the
+// bucket of its address before its credentials are checked, so that made-up
+// credentials neither escape the limit nor fill the buckets, and authenticated
+// ones also to the bucket of their principal. A limit of 0 lets every request
+// through.
+type RateLimiter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	limit     rate.Limit
+	burst     int
+	clients   map[string]*clientBucket
+	lastSweep time.Time
+}
+
+type clientBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter allows each client perSecond requests on average with bursts up to burst.
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		limit:   rate.Limit(perSecond),
+		burst:   burst,
+		now:     time.Now,
+		clients: make(map[string]*clientBucket),
+	}
+}
+
+// Limit returns the requests per second and burst allowed to each client.
+func (l *RateLimiter) Limit() (perSecond float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return float64(l.limit), l.burst
+}
+
+// SetLimit changes the requests per second and burst allowed to each client,
+// keeping the tokens clients have left; 0 requests per second lifts the limit.
+func (l *RateLimiter) SetLimit(perSecond float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.limit, l.burst = rate.Limit(perSecond), burst
+	for _, c := range l.clients {
+		c.limiter.SetLimitAt(now, l.limit)
+		c.limiter.SetBurstAt(now, burst)
+	}
+}
+
+// admit takes a request from the budget of the client identified by key.
+// Requests over budget are answered 429 with a Retry-After header, and admit
+// returns false.
+func (l *RateLimiter) admit(w http.ResponseWriter, key string) bool {
+	now := l.now()
+	bucket := l.bucket(key, now)
+	if bucket == nil {
+		return true
+	}
+	res := bucket.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); !res.OK() || delay > 0 {
+		res.CancelAt(now)
+		retry := int(math.Ceil(delay.Seconds()))
+		if retry < 1 {
+			retry = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		writeError(w, http.StatusTooManyRequests, model.ErrCodeRateLimited, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// bucket returns the limiter for key, creating it on first use, or nil if
+// requests are not limited.
+func (l *RateLimiter) bucket(key string, now time.Time) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == 0 {
+		return nil
+	}
+
+	if now.Sub(l.lastSweep) > limiterSweepEvery {
+		for k, c := range l.clients {
+			if now.Sub(c.lastSeen) > limiterIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	c, ok := l.clients[key]
+	if !ok {
+		c = &clientBucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[key] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// principalKey identifies an authenticated caller for rate limiting.
+func principalKey(p auth.Principal) string {
+	return "principal:" + p.Tenant + "/" + p.Subject
+}
+
+// addressKey identifies an unauthenticated caller of r for rate limiting, by
+// its address as the network policy resolved it, or else by its remote
+// address.
+func addressKey(r *http.Request) string {
+	if addr, ok := ClientAddr(r.Context()); ok {
+		return "ip:" + addr.String()
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+)
+
+// TestRateLimiter_PerClientBurst tests that each client gets its own bucket and excess requests get 429
+func TestRateLimiter_PerClientBurst(t *testing.T) {
+	keys, err := auth.ParseStaticKeys("ops:admin:admin-key,other:admin:other-key")
+	if err != nil {
+		t.Fatalf("ParseStaticKeys failed: %v", err)
+	}
+	r := mux.NewRouter()
+	New(&MockStore{}, WithRateLimiter(NewRateLimiter(0.01, 2)),
+		WithAuthenticator(auth.NewAPIKeyAuthenticator(keys, nil))).RegisterRoutes(r)
+
+	get := func(remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set(auth.APIKeyHeader, apiKey)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := get("10.0.0.1:1234", "admin-key"); w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusOK, w.Code)
+		}
+	}
+	w := get("10.0.0.2:5678", "admin-key")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected Retry-After header")
+	}
+
+	// Other principals have their own budget, but not from the same address
+	if w := get("10.0.0.5:1234", "other-key"); w.Code != http.StatusOK {
+		t.Fatalf("expected other principal to be allowed, got %d", w.Code)
+	}
+	if w := get("10.0.0.1:1234", "other-key"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the address to be limited across principals, got %d", w.Code)
+	}
+
+	// Refused credentials share the budget of their address, whatever they are
+	for i, key := range []string{"made-up-1", "made-up-2"} {
+		if w := get("10.0.0.3:1234", key); w.Code != http.StatusUnauthorized {
+			t.Fatalf("request %d: expected status %d, got %d", i, http.StatusUnauthorized, w.Code)
+		}
+	}
+	if w := get("10.0.0.3:1234", "made-up-3"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected made-up keys to be limited by address, got %d", w.Code)
+	}
+	if w := get("10.0.0.4:1234", "made-up-3"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected other address to be allowed, got %d", w.Code)
+	}
+}
+
+// TestRateLimiter_NoAuthenticator tests that callers are limited by address
+// when requests are not authenticated
+func TestRateLimiter_NoAuthenticator(t *testing.T) {
+	l := NewRateLimiter(0.01, 1)
+	r := mux.NewRouter()
+	New(&MockStore{}, WithRateLimiter(l)).RegisterRoutes(r)
+
+	get := func(remoteAddr, apiKey string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(auth.APIKeyHeader, apiKey)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := get("192.0.2.1:4000", "k1"); code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, code)
+	}
+	if code := get("192.0.2.1:4001", "k2"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, code)
+	}
+	if len(l.clients) != 1 {
+		t.Fatalf("expected a single bucket, got %d", len(l.clients))
+	}
+}
+
+// countingAuthenticator refuses every request, counting them
+type countingAuthenticator struct {
+	calls int
+}
+
+func (c *countingAuthenticator) Authenticate(r *http.Request) (auth.Principal, error) {
+	c.calls++
+	return auth.Principal{}, auth.ErrUnauthenticated
+}
+
+// TestRateLimiter_BeforeAuthentication tests that requests over the budget of
+// their address are refused without checking their credentials
+func TestRateLimiter_BeforeAuthentication(t *testing.T) {
+	authn := &countingAuthenticator{}
+	r := mux.NewRouter()
+	New(&MockStore{}, WithRateLimiter(NewRateLimiter(0.01, 2)), WithAuthenticator(authn)).RegisterRoutes(r)
+
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		req.RemoteAddr = "192.0.2.7:4000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Fatalf("request %d: expected status %d, got %d", i, want, w.Code)
+		}
+	}
+	if authn.calls != 2 {
+		t.Fatalf("expected 2 authentications, got %d", authn.calls)
+	}
+}
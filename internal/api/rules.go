@@ -0,0 +1,331 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toRuleResponse maps a stored rule to its JSON representation
+func toRuleResponse(r store.Rule) model.RuleResponse {
+	resp := model.RuleResponse{
+		RuleID:               r.ID,
+		Name:                 r.Name,
+		Kind:                 r.Kind,
+		Action:               r.Action,
+		Priority:             r.Priority,
+		SourceAccountID:      r.SourceAccountID,
+		DestinationAccountID: r.DestinationAccountID,
+		TenantID:             r.TenantID,
+		MaxCount:             r.MaxCount,
+		WindowSeconds:        int64(r.Window / time.Second),
+		File:                 r.ID == 0,
+		CreatedAt:            r.CreatedAt,
+	}
+	if r.MinAmount.Valid {
+		resp.MinAmount = &model.DecimalString{Decimal: r.MinAmount.Decimal}
+	}
+	if r.MaxAmount.Valid {
+		resp.MaxAmount = &model.DecimalString{Decimal: r.MaxAmount.Decimal}
+	}
+	if r.ActiveFrom != r.ActiveUntil {
+		resp.ActiveFrom = formatTimeOfDay(r.ActiveFrom)
+		resp.ActiveUntil = formatTimeOfDay(r.ActiveUntil)
+	}
+	return resp
+}
+
+// formatTimeOfDay formats an offset from midnight as HH:MM
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
+
+// toRule maps a validated rule request to the rule to store
+func toRule(req model.CreateRuleRequest) store.Rule {
+	rule := store.Rule{
+		Name:                 strings.TrimSpace(req.Name),
+		Kind:                 req.Kind,
+		Action:               req.Action,
+		Priority:             req.Priority,
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		TenantID:             req.TenantID,
+		MaxCount:             req.MaxCount,
+		Window:               time.Duration(req.WindowSeconds) * time.Second,
+	}
+	if req.MinAmount != nil {
+		rule.MinAmount = decimal.NewNullDecimal(req.MinAmount.Decimal)
+	}
+	if req.MaxAmount != nil {
+		rule.MaxAmount = decimal.NewNullDecimal(req.MaxAmount.Decimal)
+	}
+	if req.ActiveFrom != "" {
+		rule.ActiveFrom, _ = model.ParseTimeOfDay(req.ActiveFrom)
+		rule.ActiveUntil, _ = model.ParseTimeOfDay(req.ActiveUntil)
+	}
+	return rule
+}
+
+// LoadRulesFile reads the transfer rules of a YAML file (RULES_FILE), a list
+// under rules: whose entries take the fields of POST /admin/rules:
+//
+//	rules:
+//	  - name: large-night-transfers
+//	    kind: match
+//	    action: require_approval
+//	    min_amount: "10000"
+//	    active_from: "22:00"
+//	    active_until: "06:00"
+//
+// Every rule must be valid; names must be unique within the file. The rules
+// are created when the file was last modified.
+func LoadRulesFile(path string) ([]store.Rule, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so the file reads exactly like the API payload.
+	j, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Rules []model.CreateRuleRequest `json:"rules"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&file); err != nil {
+		return nil, err
+	}
+	rules := make([]store.Rule, 0, len(file.Rules))
+	seen := make(map[string]bool, len(file.Rules))
+	for i, req := range file.Rules {
+		if err := req.Validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, req.Name, err)
+		}
+		rule := toRule(req)
+		rule.CreatedAt = fi.ModTime().UTC()
+		if seen[rule.Name] {
+			return nil, fmt.Errorf("rule %d: duplicate name %q", i+1, rule.Name)
+		}
+		seen[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// toReviewResponse maps a stored review to its JSON representation
+func (a *API) toReviewResponse(r store.Review) model.ReviewResponse {
+	return model.ReviewResponse{
+		Transaction: a.toTransaction(r.Transaction),
+		RuleID:      r.RuleID,
+		RuleName:    r.RuleName,
+		Status:      r.Status,
+		ReviewedBy:  r.ReviewedBy,
+		Reason:      r.Reason,
+		FlaggedAt:   r.FlaggedAt,
+		ReviewedAt:  r.ReviewedAt,
+	}
+}
+
+// CreateRule adds a fraud rule checked before every transfer
+func (a *API) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateRuleRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	rule, err := a.store.CreateRule(ctx, toRule(req))
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("create rule failed: name=%s, error=%v", req.Name, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toRuleResponse(rule))
+}
+
+// ListRules lists the fraud rules in the order transfers are checked against them
+func (a *API) ListRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	rules, err := a.store.ListRules(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list rules failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.RuleListResponse{Rules: make([]model.RuleResponse, 0, len(rules))}
+	for _, rule := range rules {
+		resp.Rules = append(resp.Rules, toRuleResponse(rule))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// DeleteRule deletes a fraud rule; the reviews of transfers it flagged are kept
+func (a *API) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid rule id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.DeleteRule(ctx, id); err != nil {
+		if errors.Is(err, store.ErrRuleNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeRuleNotFound, "rule not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("delete rule failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// reviewStatuses are the accepted values of ?status= in GET /admin/reviews
+var reviewStatuses = map[string]bool{
+	store.ReviewPending:  true,
+	store.ReviewApproved: true,
+	store.ReviewRejected: true,
+}
+
+// ListReviews lists the transfers flagged by a rule, oldest first, filtered by
+// ?status= (pending by default; "all" for any)
+func (a *API) ListReviews(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch {
+	case status == "":
+		status = store.ReviewPending
+	case status == "all":
+		status = ""
+	case !reviewStatuses[status]:
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "status must be pending, approved, rejected or all", map[string]interface{}{"parameter": "status"})
+		return
+	}
+	var cursor int64
+	if c := q.Get("cursor"); c != "" {
+		var err error
+		cursor, err = strconv.ParseInt(c, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid cursor", map[string]interface{}{"parameter": "cursor"})
+			return
+		}
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	reviews, next, err := a.store.ListReviews(ctx, status, cursor, limit)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list reviews failed: status=%s, error=%v", status, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.ReviewListResponse{Reviews: make([]model.ReviewResponse, 0, len(reviews))}
+	for _, rv := range reviews {
+		resp.Reviews = append(resp.Reviews, a.toReviewResponse(rv))
+	}
+	if next != 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ApproveReview clears a flagged transfer. A transfer awaiting approval is
+// executed by the pending transfer worker.
+func (a *API) ApproveReview(w http.ResponseWriter, r *http.Request) {
+	a.resolveReview(w, r, store.ReviewApproved)
+}
+
+// RejectReview marks a flagged transfer as fraudulent. The transfer stands
+// until it is reversed; a transfer awaiting approval fails instead.
+func (a *API) RejectReview(w http.ResponseWriter, r *http.Request) {
+	a.resolveReview(w, r, store.ReviewRejected)
+}
+
+// resolveReview records the calling admin's decision on a flagged transfer
+func (a *API) resolveReview(w http.ResponseWriter, r *http.Request, status string) {
+	id, ok := parseTransactionID(w, r)
+	if !ok {
+		return
+	}
+	var reviewedBy string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		reviewedBy = p.Subject
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	rv, err := a.store.ResolveReview(ctx, id, status, reviewedBy)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrReviewNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeReviewNotFound, "no review of this transaction")
+		case errors.Is(err, store.ErrReviewResolved):
+			writeError(w, http.StatusConflict, model.ErrCodeReviewResolved, "review already resolved")
+		default:
+			if notSupported(w, err) {
+				return
+			}
+			a.logger.Printf("resolve review failed: transactionID=%d, status=%s, error=%v", id, status, err)
+			internalError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toReviewResponse(rv))
+}
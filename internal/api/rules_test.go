@@ -0,0 +1,300 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateRule tests creating rules and their validation
+func TestCreateRule(t *testing.T) {
+	var got store.Rule
+	mockStore := &MockStore{
+		CreateRuleFunc: func(ctx context.Context, r store.Rule) (store.Rule, error) {
+			got = r
+			r.ID = 3
+			return r, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		body string
+		want int
+	}{
+		{`{"name": "burst", "kind": "velocity", "action": "block", "max_count": 10, "window_seconds": 60, "priority": 5}`, http.StatusCreated},
+		{`{"name": "burst", "kind": "velocity", "action": "block", "max_count": 10}`, http.StatusBadRequest},
+		{`{"name": "big", "kind": "amount", "action": "flag"}`, http.StatusBadRequest},
+		{`{"name": "big", "kind": "amount", "action": "flag", "min_amount": "-1"}`, http.StatusBadRequest},
+		{`{"name": "new", "kind": "new_counterparty", "action": "notify"}`, http.StatusBadRequest},
+		{`{"name": "new", "kind": "geo", "action": "flag"}`, http.StatusBadRequest},
+		{`{"name": "night", "kind": "match", "action": "require_approval", "max_amount": "10", "min_amount": "20"}`, http.StatusBadRequest},
+		{`{"name": "night", "kind": "match", "action": "require_approval", "active_from": "22:00"}`, http.StatusBadRequest},
+		{`{"name": "night", "kind": "match", "action": "require_approval", "active_from": "22:00", "active_until": "25:00"}`, http.StatusBadRequest},
+		{`{"kind": "new_counterparty", "action": "flag"}`, http.StatusBadRequest},
+		{`not json`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/rules", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.body, c.want, w.Code)
+		}
+		if c.want != http.StatusCreated {
+			continue
+		}
+		var resp model.RuleResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.RuleID != 3 || resp.WindowSeconds != 60 || resp.MaxCount != 10 || resp.Priority != 5 {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+	}
+	if got.Kind != store.RuleVelocity || got.Action != store.RuleBlock || got.Window != time.Minute || got.MinAmount.Valid {
+		t.Fatalf("unexpected rule: %+v", got)
+	}
+
+	body := `{"name": "night", "kind": "match", "action": "require_approval", "destination_account_id": 9, "max_amount": "500",
+		"tenant_id": "acme", "active_from": "22:00", "active_until": "06:30"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/rules", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if got.DestinationAccountID != 9 || !got.MaxAmount.Decimal.Equal(decimal.NewFromInt(500)) || got.TenantID != "acme" ||
+		got.ActiveFrom != 22*time.Hour || got.ActiveUntil != 6*time.Hour+30*time.Minute {
+		t.Fatalf("unexpected rule: %+v", got)
+	}
+	var resp model.RuleResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ActiveFrom != "22:00" || resp.ActiveUntil != "06:30" || resp.MaxAmount.String() != "500" || resp.File {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// TestLoadRulesFile tests reading the rules of RULES_FILE
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`rules:
+  - name: large-night-transfers
+    kind: match
+    action: require_approval
+    min_amount: "10000"
+    active_from: "22:00"
+    active_until: "06:00"
+  - name: treasury
+    kind: match
+    action: allow
+    priority: -1
+    source_account_id: 1
+`)
+	rules, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatalf("load rules file: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Action != store.RuleRequireApproval || !rules[0].MinAmount.Decimal.Equal(decimal.NewFromInt(10000)) ||
+		rules[0].ActiveFrom != 22*time.Hour || rules[0].ActiveUntil != 6*time.Hour || rules[0].ID != 0 || rules[0].CreatedAt.IsZero() ||
+		rules[1].Priority != -1 || rules[1].SourceAccountID != 1 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	for _, bad := range []string{
+		"rules:\n  - name: x\n    kind: match\n    action: notify\n",
+		"rules:\n  - name: x\n    kind: match\n    action: flag\n    colour: red\n",
+		"rules:\n  - name: x\n    kind: match\n    action: flag\n  - name: x\n    kind: match\n    action: block\n",
+		"rules: [",
+	} {
+		write(bad)
+		if _, err := LoadRulesFile(path); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+	if _, err := LoadRulesFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+// TestCreateTransaction_AwaitingApproval tests that a transfer a rule holds
+// for approval is answered with 202
+func TestCreateTransaction_AwaitingApproval(t *testing.T) {
+	mockStore := &MockStore{
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 42, store.ErrAwaitingApproval
+		},
+		DryRunFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error {
+			return store.ErrAwaitingApproval
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	body := `{"source_account_id": 1, "destination_account_id": 2, "amount": "20000"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions", bytes.NewReader([]byte(body))))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d", http.StatusAccepted, w.Code)
+	}
+	var resp model.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.TransactionID != 0 || resp.Status != store.StatusPending || resp.ID == "" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions?dry_run=true", bytes.NewReader([]byte(body))))
+	resp = model.TransactionResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || resp.Status != store.StatusPending || !resp.DryRun {
+		t.Fatalf("unexpected dry run: status %d, %+v", w.Code, resp)
+	}
+}
+
+// TestRules_DeploymentWide tests that tenant admins cannot manage rules
+func TestRules_DeploymentWide(t *testing.T) {
+	r := newAuthRouter(t, &MockStore{})
+
+	for _, c := range []struct {
+		key  string
+		want int
+	}{
+		{"admin-key", http.StatusOK},
+		{"tenant-key", http.StatusForbidden},
+		{"service-key", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/rules", nil)
+		req.Header.Set(auth.APIKeyHeader, c.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want {
+			t.Fatalf("key %q: expected status %d, got %d", c.key, c.want, w.Code)
+		}
+	}
+}
+
+// TestListReviews tests listing flagged transfers
+func TestListReviews(t *testing.T) {
+	var gotStatus string
+	mockStore := &MockStore{
+		ListReviewsFunc: func(ctx context.Context, status string, cursor int64, limit int) ([]store.Review, int64, error) {
+			gotStatus = status
+			return []store.Review{{
+				Transaction: store.Transaction{ID: 7, SourceAccountID: 1, DestinationAccountID: 2, Amount: decimal.NewFromInt(5000), Status: store.StatusSucceeded,
+					TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000007")}},
+				RuleID: 3, RuleName: "big payouts", Status: store.ReviewPending,
+			}, {
+				Transaction: store.Transaction{ID: 8, SourceAccountID: 1, DestinationAccountID: 4, Amount: decimal.NewFromInt(90), Status: store.StatusSucceeded},
+				RuleName:    store.AnomalyRuleName, Reason: "new counterparty 4", Status: store.ReviewPending,
+			}}, 8, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reviews?limit=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.ReviewListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if gotStatus != store.ReviewPending {
+		t.Fatalf("expected pending reviews by default, got %q", gotStatus)
+	}
+	if len(resp.Reviews) != 2 || resp.Reviews[0].Transaction.ID != "01890a5d-ac97-7000-8000-000000000007" || resp.Reviews[0].RuleName != "big payouts" || resp.Reviews[0].Reason != "" || resp.NextCursor != "8" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if rv := resp.Reviews[1]; rv.RuleID != 0 || rv.RuleName != store.AnomalyRuleName || rv.Reason != "new counterparty 4" {
+		t.Fatalf("unexpected review of an anomaly: %+v", rv)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/admin/reviews?status=all":     http.StatusOK,
+		"/v1/admin/reviews?status=flagged": http.StatusBadRequest,
+		"/v1/admin/reviews?cursor=abc":     http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", path, want, w.Code)
+		}
+	}
+	if gotStatus != "" {
+		t.Fatalf("expected any status for status=all, got %q", gotStatus)
+	}
+}
+
+// TestResolveReview tests approving and rejecting flagged transfers
+func TestResolveReview(t *testing.T) {
+	var gotStatus, gotBy string
+	mockStore := &MockStore{
+		ResolveReviewFunc: func(ctx context.Context, transactionID int64, status, reviewedBy string) (store.Review, error) {
+			switch transactionID {
+			case 404:
+				return store.Review{}, store.ErrReviewNotFound
+			case 409:
+				return store.Review{}, store.ErrReviewResolved
+			}
+			gotStatus, gotBy = status, reviewedBy
+			return store.Review{Transaction: store.Transaction{ID: transactionID}, Status: status, ReviewedBy: reviewedBy}, nil
+		},
+	}
+	r := newAuthRouter(t, mockStore)
+
+	for _, c := range []struct {
+		path, key  string
+		want       int
+		wantStatus string
+	}{
+		{"/v1/admin/reviews/7/approve", "admin-key", http.StatusOK, store.ReviewApproved},
+		{"/v1/admin/reviews/7/reject", "admin-key", http.StatusOK, store.ReviewRejected},
+		{"/v1/admin/reviews/7/reject", "service-key", http.StatusForbidden, ""},
+		{"/v1/admin/reviews/abc/reject", "admin-key", http.StatusBadRequest, ""},
+		{"/v1/admin/reviews/404/approve", "admin-key", http.StatusNotFound, ""},
+		{"/v1/admin/reviews/409/approve", "admin-key", http.StatusConflict, ""},
+	} {
+		gotStatus, gotBy = "", ""
+		req := httptest.NewRequest(http.MethodPost, c.path, nil)
+		req.Header.Set(auth.APIKeyHeader, c.key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d", c.path, c.want, w.Code)
+		}
+		if c.wantStatus != "" && (gotStatus != c.wantStatus || gotBy != "ops") {
+			t.Fatalf("%s: expected %s by ops, got %s by %q", c.path, c.wantStatus, gotStatus, gotBy)
+		}
+	}
+}
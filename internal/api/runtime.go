@@ -0,0 +1,95 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// WithLogLevel sets which requests LoggingMiddleware logs, one of the
+// model.LogLevel constants (info by default).
+func WithLogLevel(level string) Option {
+	return func(a *API) {
+		a.logLevel = level
+	}
+}
+
+// initialRuntimeConfig gathers the runtime settings given by the options and
+// the store.
+func (a *API) initialRuntimeConfig() *model.RuntimeConfig {
+	rps, burst := a.limiter.Limit()
+	var limits model.TransferLimits
+	if l := toTransferLimits(a.store.DefaultLimits()); l != nil {
+		limits = *l
+	}
+	return &model.RuntimeConfig{
+		RateLimitRPS:   rps,
+		RateLimitBurst: burst,
+		TransferLimits: limits,
+		AsyncTransfers: a.asyncTransfers,
+		LogLevel:       a.logLevel,
+	}
+}
+
+// Runtime returns the settings currently in effect of those that can change
+// while the service runs.
+func (a *API) Runtime() model.RuntimeConfig {
+	return *a.runtime.Load()
+}
+
+// SetRuntimeConfig validates c and puts it into effect: the rate limit and
+// default transfer limits apply from the next request on, as do the other
+// settings, which are read by each request.
+func (a *API) SetRuntimeConfig(c model.RuntimeConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	a.runtimeMu.Lock()
+	defer a.runtimeMu.Unlock()
+	a.store.SetDefaultLimits(storeLimits(c.TransferLimits))
+	a.limiter.SetLimit(c.RateLimitRPS, c.RateLimitBurst)
+	a.runtime.Store(&c)
+	return nil
+}
+
+// storeLimits maps JSON transfer limits to the store's; absent limits are
+// unlimited.
+func storeLimits(l model.TransferLimits) store.TransferLimits {
+	var out store.TransferLimits
+	if l.MaxTransferAmount != nil {
+		out.MaxAmount = decimal.NewNullDecimal(l.MaxTransferAmount.Decimal)
+	}
+	if l.DailyTransferLimit != nil {
+		out.Daily = decimal.NewNullDecimal(l.DailyTransferLimit.Decimal)
+	}
+	return out
+}
+
+// GetRuntimeConfig returns the runtime settings in effect
+func (a *API) GetRuntimeConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.Runtime())
+}
+
+// UpdateRuntimeConfig changes runtime settings without a restart. Fields
+// absent from the body keep their value.
+func (a *API) UpdateRuntimeConfig(w http.ResponseWriter, r *http.Request) {
+	c := a.Runtime()
+	if !a.decodeJSON(w, r, &c) {
+		return
+	}
+	if err := a.SetRuntimeConfig(c); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	var changedBy string
+	if p, ok := auth.FromContext(r.Context()); ok {
+		changedBy = p.Subject
+	}
+	a.logger.Printf("runtime config changed by %q: %+v", changedBy, a.Runtime())
+	writeJSON(w, http.StatusOK, a.Runtime())
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestRuntimeConfig tests GET and PUT /v1/admin/config
+func TestRuntimeConfig(t *testing.T) {
+	mockStore := &MockStore{Limits: store.TransferLimits{Daily: decimal.NewNullDecimal(decimal.NewFromInt(500))}}
+	r := mux.NewRouter()
+	a := New(mockStore, WithAsyncTransfers(false))
+	a.RegisterRoutes(r)
+
+	do := func(method, body string) (*httptest.ResponseRecorder, model.RuntimeConfig) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, "/v1/admin/config", strings.NewReader(body)))
+		var c model.RuntimeConfig
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &c); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+		}
+		return w, c
+	}
+
+	w, c := do(http.MethodGet, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if c.RateLimitRPS != 0 || c.AsyncTransfers || c.LogLevel != model.LogLevelInfo ||
+		c.TransferLimits.MaxTransferAmount != nil || c.TransferLimits.DailyTransferLimit == nil || c.TransferLimits.DailyTransferLimit.String() != "500" {
+		t.Fatalf("unexpected initial config %+v", c)
+	}
+
+	// Absent fields keep their value
+	w, c = do(http.MethodPut, `{"rate_limit_rps": 0.01, "rate_limit_burst": 1, "transfer_limits": {"max_transfer_amount": "100"}, "log_level": "warn"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if c.RateLimitRPS != 0.01 || c.RateLimitBurst != 1 || c.AsyncTransfers || c.LogLevel != model.LogLevelWarn ||
+		c.TransferLimits.MaxTransferAmount.String() != "100" || c.TransferLimits.DailyTransferLimit.String() != "500" {
+		t.Fatalf("unexpected updated config %+v", c)
+	}
+	if !mockStore.Limits.MaxAmount.Valid || !mockStore.Limits.MaxAmount.Decimal.Equal(decimal.NewFromInt(100)) ||
+		!mockStore.Limits.Daily.Decimal.Equal(decimal.NewFromInt(500)) {
+		t.Fatalf("store limits not updated: %+v", mockStore.Limits)
+	}
+
+	// The new rate limit applies from the next request on
+	if w, _ := do(http.MethodGet, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w, _ := do(http.MethodGet, ""); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if err := a.SetRuntimeConfig(model.RuntimeConfig{LogLevel: model.LogLevelInfo}); err != nil {
+		t.Fatalf("SetRuntimeConfig: %v", err)
+	}
+	if w, _ := do(http.MethodGet, ""); w.Code != http.StatusOK {
+		t.Fatalf("expected rate limit lifted, got %d", w.Code)
+	}
+
+	for _, body := range []string{
+		`{"rate_limit_rps": -1}`,
+		`{"rate_limit_rps": 5, "rate_limit_burst": 0}`,
+		`{"log_level": "verbose"}`,
+		`{"transfer_limits": {"daily_transfer_limit": "0"}}`,
+		`{"unknown": true}`,
+	} {
+		if w, _ := do(http.MethodPut, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+	if got := a.Runtime(); got.RateLimitRPS != 0 || got.LogLevel != model.LogLevelInfo {
+		t.Fatalf("rejected update applied: %+v", got)
+	}
+}
+
+// TestLoggingMiddleware_Level tests which requests are logged at each level
+func TestLoggingMiddleware_Level(t *testing.T) {
+	var buf bytes.Buffer
+	a := New(&MockStore{}, WithLogger(log.New(&buf, "", 0)))
+	h := a.LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for _, tc := range []struct {
+		level  string
+		logged []string
+	}{
+		{model.LogLevelDebug, []string{"/ok?page=2", "/missing?page=2", "/broken?page=2"}},
+		{model.LogLevelInfo, []string{"/ok", "/missing", "/broken"}},
+		{model.LogLevelWarn, []string{"/missing", "/broken"}},
+		{model.LogLevelError, []string{"/broken"}},
+	} {
+		if err := a.SetRuntimeConfig(model.RuntimeConfig{LogLevel: tc.level}); err != nil {
+			t.Fatalf("SetRuntimeConfig: %v", err)
+		}
+		buf.Reset()
+		for _, path := range []string{"/ok", "/missing", "/broken"} {
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path+"?page=2", nil))
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(tc.logged) {
+			t.Fatalf("%s: expected %d lines, got %q", tc.level, len(tc.logged), buf.String())
+		}
+		for i, want := range tc.logged {
+			if !strings.HasPrefix(lines[i], "GET "+want+" ") {
+				t.Fatalf("%s: unexpected line %q", tc.level, lines[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toScheduledTransferResponse maps a stored scheduled transfer to its JSON representation
+func (a *API) toScheduledTransferResponse(st store.ScheduledTransfer) model.ScheduledTransferResponse {
+	return model.ScheduledTransferResponse{
+		ScheduledTransferID:  st.ID,
+		SourceAccountID:      st.SourceAccountID,
+		DestinationAccountID: st.DestinationAccountID,
+		Amount:               model.DecimalString{Decimal: st.Amount},
+		Status:               st.Status,
+		TransactionPublicID:  publicID(st.TransactionUUID),
+		TransactionID:        a.serialID(st.TransactionID),
+		ErrorMessage:         st.ErrorMessage,
+		CreatedAt:            st.CreatedAt,
+		ExecuteAt:            st.ExecuteAt,
+		ExecutedAt:           st.ExecutedAt,
+	}
+}
+
+// CreateScheduledTransfer records a transfer to be executed by the scheduler at execute_at
+func (a *API) CreateScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateScheduledTransferRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.ValidateAt(a.clock.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	src, dst, ok := a.transferAccounts(ctx, w, req.SourceAccountID, req.DestinationAccountID)
+	if !ok {
+		return
+	}
+	treq := TransferRequest{Kind: TransferKindScheduled, SourceAccountID: src, DestinationAccountID: dst, Amount: req.Amount.Decimal}
+	if !a.allowTransfer(ctx, w, treq) {
+		return
+	}
+	p, _ := auth.FromContext(ctx)
+	st, err := a.store.CreateScheduledTransfer(ctx, src, dst, req.Amount.Decimal, req.ExecuteAt, p.Subject)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("create scheduled transfer failed: src=%d, dst=%d, error=%v", src, dst, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, a.toScheduledTransferResponse(st))
+}
+
+// GetScheduledTransfer returns a scheduled transfer and, once executed, its outcome
+func (a *API) GetScheduledTransfer(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid scheduled transfer id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	st, err := a.store.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrScheduledTransferNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeScheduledNotFound, "scheduled transfer not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get scheduled transfer failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toScheduledTransferResponse(st))
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/auth"
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateScheduledTransfer tests scheduling and execute_at validation
+func TestCreateScheduledTransfer(t *testing.T) {
+	var gotExecuteAt time.Time
+	var gotInitiatedBy string
+	mockStore := &MockStore{
+		CreateScheduledFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (store.ScheduledTransfer, error) {
+			gotExecuteAt, gotInitiatedBy = executeAt, initiatedBy
+			return store.ScheduledTransfer{ID: 5, SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Status: store.ScheduledPending, ExecuteAt: executeAt}, nil
+		},
+	}
+	clk := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	api := New(mockStore, WithClock(clk))
+
+	executeAt := clk.Now().Add(time.Hour)
+	body := []byte(`{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "execute_at": "` + executeAt.Format(time.RFC3339) + `"}`)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/transfers/scheduled", bytes.NewReader(body))
+	api.CreateScheduledTransfer(w, req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Subject: "ops", Role: auth.RoleAdmin})))
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d", http.StatusCreated, w.Code)
+	}
+	if !gotExecuteAt.Equal(executeAt) || gotInitiatedBy != "ops" {
+		t.Fatalf("expected execute_at %s initiated by ops, got %s by %q", executeAt, gotExecuteAt, gotInitiatedBy)
+	}
+	var resp model.ScheduledTransferResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ScheduledTransferID != 5 || resp.Status != store.ScheduledPending {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	// The same execute_at is in the past once the clock has moved past it
+	clk.Advance(time.Hour)
+	w = httptest.NewRecorder()
+	api.CreateScheduledTransfer(w, httptest.NewRequest(http.MethodPost, "/transfers/scheduled", bytes.NewReader(body)))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for a past execute_at, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestGetScheduledTransfer tests outcome reporting and not found mapping
+func TestGetScheduledTransfer(t *testing.T) {
+	mockStore := &MockStore{
+		GetScheduledFunc: func(ctx context.Context, id int64) (store.ScheduledTransfer, error) {
+			if id != 5 {
+				return store.ScheduledTransfer{}, store.ErrScheduledTransferNotFound
+			}
+			return store.ScheduledTransfer{ID: 5, Status: store.ScheduledFailed, ErrorMessage: "insufficient funds"}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/transfers/scheduled/5", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.ScheduledTransferResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Status != store.ScheduledFailed || resp.ErrorMessage != "insufficient funds" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/transfers/scheduled/6", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/seed"
+)
+
+// seedTimeout bounds a seeding run, which may run far longer than other requests
+const seedTimeout = 10 * time.Minute
+
+// Seeder generates accounts and transfers, e.g. with seed.Postgres
+type Seeder func(ctx context.Context, o seed.Options) (seed.Result, error)
+
+// WithSeeder enables POST /admin/seed, which generates demo data with s. It is
+// meant for development and load test environments only.
+func WithSeeder(s Seeder) Option {
+	return func(a *API) {
+		a.seeder = s
+	}
+}
+
+// Seed generates accounts in the caller's tenant and historical transfers
+// between them
+func (a *API) Seed(w http.ResponseWriter, r *http.Request) {
+	var req model.SeedRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	o := seed.DefaultOptions()
+	if req.Accounts != 0 {
+		o.Accounts = req.Accounts
+	}
+	if req.Currency != "" {
+		o.Currency = req.Currency
+	}
+	if req.Distribution != "" {
+		o.Distribution = req.Distribution
+	}
+	if req.MinBalance != nil {
+		o.MinBalance = req.MinBalance.Decimal
+	}
+	if req.MaxBalance != nil {
+		o.MaxBalance = req.MaxBalance.Decimal
+	}
+	if req.PeriodDays != 0 {
+		o.Period = time.Duration(req.PeriodDays) * 24 * time.Hour
+	}
+	o.Transfers, o.Seed = req.Transfers, req.Seed
+	if err := o.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), seedTimeout)
+	defer cancel()
+	// The server's timeouts are sized for ordinary requests
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Now().Add(seedTimeout))
+	_ = rc.SetWriteDeadline(time.Now().Add(seedTimeout))
+
+	res, err := a.seeder(ctx, o)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("seed failed: accounts=%d, transfers=%d, error=%v", len(res.Accounts), res.Transfers, err)
+		internalError(w, err)
+		return
+	}
+	a.logger.Printf("seeded %d accounts and %d transfers", len(res.Accounts), res.Transfers)
+
+	writeJSON(w, http.StatusCreated, model.SeedResponse{
+		AccountIDs:       res.Accounts,
+		ExternalIDPrefix: res.ExternalID,
+		Transfers:        res.Transfers,
+		Skipped:          res.Skipped,
+		From:             res.From,
+		To:               res.To,
+	})
+}
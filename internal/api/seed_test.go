@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/seed"
+)
+
+// TestSeed tests POST /v1/admin/seed
+func TestSeed(t *testing.T) {
+	// Disabled unless a seeder is given
+	r := mux.NewRouter()
+	New(&MockStore{}).RegisterRoutes(r)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/seed", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+
+	var got seed.Options
+	r = mux.NewRouter()
+	New(&MockStore{}, WithSeeder(func(ctx context.Context, o seed.Options) (seed.Result, error) {
+		got = o
+		return seed.Result{Accounts: []int64{7, 8}, ExternalID: "seed-x-", Transfers: 3, To: time.Now()}, nil
+	})).RegisterRoutes(r)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/seed", strings.NewReader(body)))
+		return w
+	}
+
+	w = post(`{"accounts": 2, "distribution": "pareto", "min_balance": "10", "transfers": 3, "period_days": 7, "seed": 5}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body)
+	}
+	if got.Accounts != 2 || got.Currency != "USD" || got.Distribution != seed.Pareto || got.MinBalance.String() != "10" ||
+		got.MaxBalance.String() != "10000" || got.Transfers != 3 || got.Period != 7*24*time.Hour || got.Seed != 5 {
+		t.Fatalf("unexpected options %+v", got)
+	}
+	var resp model.SeedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.AccountIDs) != 2 || resp.ExternalIDPrefix != "seed-x-" || resp.Transfers != 3 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	for _, body := range []string{
+		`{"accounts": 100001}`,
+		`{"distribution": "zipf"}`,
+		`{"min_balance": "20000"}`,
+		`{"transfers": 10, "period_days": -1}`,
+	} {
+		if w := post(body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toSettlementReportResponse maps a settlement report to its JSON representation
+func (a *API) toSettlementReportResponse(rep store.SettlementReport) model.SettlementReportResponse {
+	resp := model.SettlementReportResponse{
+		BusinessDate: rep.BusinessDate.Format(time.DateOnly),
+		Cutoff:       rep.Cutoff,
+		Positions:    make([]model.SettlementPositionResponse, 0, len(rep.Positions)),
+	}
+	for _, p := range rep.Positions {
+		resp.Positions = append(resp.Positions, model.SettlementPositionResponse{
+			AccountA:            p.AccountA,
+			AccountB:            p.AccountB,
+			Currency:            p.Currency,
+			AToB:                model.DecimalString{Decimal: p.AToB},
+			BToA:                model.DecimalString{Decimal: p.BToA},
+			Net:                 model.DecimalString{Decimal: p.Net},
+			Transfers:           p.Transfers,
+			Settled:             p.SettlementID != 0,
+			TransactionPublicID: publicID(p.TransactionUUID),
+			TransactionID:       a.serialID(p.TransactionID),
+			SettledAt:           p.SettledAt,
+		})
+	}
+	return resp
+}
+
+// CreateClearingTransfer accepts a transfer between two clearing accounts, to
+// be netted with the others between them and settled at the cutoff
+func (a *API) CreateClearingTransfer(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateClearingTransferRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	src, dst, ok := a.transferAccounts(ctx, w, req.SourceAccountID, req.DestinationAccountID)
+	if !ok {
+		return
+	}
+	treq := TransferRequest{Kind: TransferKindClearing, SourceAccountID: src, DestinationAccountID: dst, Amount: req.Amount.Decimal,
+		Details: store.TransferDetails{Reference: req.Reference}}
+	if !a.allowTransfer(ctx, w, treq) {
+		return
+	}
+	ct, err := a.store.CreateClearingTransfer(ctx, src, dst, req.Amount.Decimal, req.Reference)
+	if err != nil {
+		if errors.Is(err, store.ErrNotClearingAccount) {
+			writeError(w, http.StatusUnprocessableEntity, model.ErrCodeNotClearingAccount, "source or destination account is not a clearing account")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		status, resp, ok := transferError(err)
+		if !ok {
+			a.logger.Printf("create clearing transfer failed: src=%d, dst=%d, error=%v", src, dst, err)
+		}
+		writeJSON(w, status, resp)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, model.ClearingTransferResponse{
+		ClearingTransferID:   ct.ID,
+		BusinessDate:         ct.BusinessDate.Format(time.DateOnly),
+		SourceAccountID:      ct.SourceAccountID,
+		DestinationAccountID: ct.DestinationAccountID,
+		Amount:               model.DecimalString{Decimal: ct.Amount},
+		Currency:             ct.Currency,
+		Reference:            ct.Reference,
+		CreatedAt:            ct.CreatedAt,
+	})
+}
+
+// ListClearingAccounts lists the accounts designated for clearing
+func (a *API) ListClearingAccounts(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	ids, err := a.store.ClearingAccounts(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list clearing accounts failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+	writeJSON(w, http.StatusOK, model.ClearingAccountListResponse{AccountIDs: ids})
+}
+
+// AddClearingAccount designates the account for clearing
+func (a *API) AddClearingAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.AddClearingAccount(ctx, id); err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("add clearing account failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveClearingAccount withdraws the account from clearing; its clearing
+// transfers already accepted are still settled
+func (a *API) RemoveClearingAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.RemoveClearingAccount(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotClearingAccount) {
+			writeError(w, http.StatusUnprocessableEntity, model.ErrCodeNotClearingAccount, "account is not a clearing account")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("remove clearing account failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SettlementReport returns the positions of the pairs of clearing accounts
+// netted over the business day in the date query parameter, the current one by
+// default
+func (a *API) SettlementReport(w http.ResponseWriter, r *http.Request) {
+	var date time.Time
+	if s := r.URL.Query().Get("date"); s != "" {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "date must be YYYY-MM-DD", map[string]interface{}{"parameter": "date"})
+			return
+		}
+		date = d
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	rep, err := a.store.SettlementReport(ctx, date)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("settlement report failed: date=%s, error=%v", date.Format(time.DateOnly), err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, a.toSettlementReportResponse(rep))
+}
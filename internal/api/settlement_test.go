@@ -0,0 +1,158 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateClearingTransfer tests accepting clearing transfers and their errors
+func TestCreateClearingTransfer(t *testing.T) {
+	mockStore := &MockStore{
+		ClearingTxFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (store.ClearingTransfer, error) {
+			switch dstID {
+			case 3:
+				return store.ClearingTransfer{}, store.ErrNotClearingAccount
+			case 4:
+				return store.ClearingTransfer{}, store.ErrCurrencyMismatch
+			}
+			return store.ClearingTransfer{ID: 7, BusinessDate: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Currency: "USD", Reference: reference}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		body string
+		want int
+		code model.ErrorCode
+	}{
+		{`{"source_account_id": 1, "destination_account_id": 2, "amount": "50", "reference": "interbank"}`, http.StatusCreated, ""},
+		{`{"source_account_id": 1, "destination_account_id": 1, "amount": "50"}`, http.StatusBadRequest, model.ErrCodeValidationFailed},
+		{`{"source_account_id": 1, "destination_account_id": 2, "amount": "0"}`, http.StatusBadRequest, model.ErrCodeValidationFailed},
+		{`{"source_account_id": 1, "destination_account_id": 3, "amount": "50"}`, http.StatusUnprocessableEntity, model.ErrCodeNotClearingAccount},
+		{`{"source_account_id": 1, "destination_account_id": 4, "amount": "50"}`, http.StatusUnprocessableEntity, model.ErrCodeCurrencyMismatch},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/clearing/transfers", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d: %s", c.body, c.want, w.Code, w.Body.String())
+		}
+		if c.want != http.StatusCreated {
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != c.code {
+				t.Fatalf("%s: expected code %s, got %+v (%v)", c.body, c.code, resp, err)
+			}
+			continue
+		}
+		var resp model.ClearingTransferResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.ClearingTransferID != 7 || resp.BusinessDate != "2024-03-05" || resp.Reference != "interbank" || resp.Amount.String() != "50" {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+	}
+}
+
+// TestClearingAccounts tests designating accounts for clearing and withdrawing them
+func TestClearingAccounts(t *testing.T) {
+	clearing := map[int64]bool{}
+	mockStore := &MockStore{
+		AddClearingFunc: func(ctx context.Context, accountID int64) error {
+			if accountID == 404 {
+				return store.ErrAccountNotFound
+			}
+			clearing[accountID] = true
+			return nil
+		},
+		RemoveClearingFunc: func(ctx context.Context, accountID int64) error {
+			if !clearing[accountID] {
+				return store.ErrNotClearingAccount
+			}
+			delete(clearing, accountID)
+			return nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	for _, c := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPut, "/v1/admin/clearing-accounts/1", http.StatusNoContent},
+		{http.MethodPut, "/v1/admin/clearing-accounts/1", http.StatusNoContent},
+		{http.MethodPut, "/v1/admin/clearing-accounts/404", http.StatusNotFound},
+		{http.MethodDelete, "/v1/admin/clearing-accounts/1", http.StatusNoContent},
+		{http.MethodDelete, "/v1/admin/clearing-accounts/1", http.StatusUnprocessableEntity},
+		{http.MethodGet, "/v1/admin/clearing-accounts", http.StatusOK},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.path, nil))
+		if w.Code != c.want {
+			t.Fatalf("%s %s: expected status %d, got %d", c.method, c.path, c.want, w.Code)
+		}
+	}
+}
+
+// TestSettlementReport tests the settlement report and its date parameter
+func TestSettlementReport(t *testing.T) {
+	settledAt := time.Date(2024, 3, 5, 17, 1, 0, 0, time.UTC)
+	var gotDate time.Time
+	mockStore := &MockStore{
+		SettlementFunc: func(ctx context.Context, date time.Time) (store.SettlementReport, error) {
+			gotDate = date
+			return store.SettlementReport{BusinessDate: date, Cutoff: time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC), Positions: []store.SettlementPosition{{
+				AccountA: 1, AccountB: 2, Currency: "USD", AToB: decimal.NewFromInt(80), BToA: decimal.NewFromInt(30), Net: decimal.NewFromInt(50),
+				Transfers: 3, SettlementID: 1, TransactionID: 42, TransactionUUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000042"), SettledAt: &settledAt,
+			}}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/settlements?date=2024-03-05", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", w.Code)
+	}
+	var resp model.SettlementReportResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !gotDate.Equal(time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)) || resp.BusinessDate != "2024-03-05" {
+		t.Fatalf("unexpected business date: %s, %s", gotDate, resp.BusinessDate)
+	}
+	if len(resp.Positions) != 1 {
+		t.Fatalf("expected 1 position, got %+v", resp.Positions)
+	}
+	if p := resp.Positions[0]; p.Net.String() != "50" || !p.Settled || p.TransactionPublicID != "01890a5d-ac97-7000-8000-000000000042" || p.TransactionID != 0 || p.Transfers != 3 {
+		t.Fatalf("unexpected position: %+v", p)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/settlements", nil))
+	if w.Code != http.StatusOK || !gotDate.IsZero() {
+		t.Fatalf("expected the current business day to be asked for, got status %d and %s", w.Code, gotDate)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/settlements?date=05-03-2024", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an invalid date, got %d", w.Code)
+	}
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// transferGroupID returns the JSON representation of a transfer group id,
+// empty for transactions in none
+func transferGroupID(group uuid.UUID) string {
+	if group == uuid.Nil {
+		return ""
+	}
+	return group.String()
+}
+
+// CreateSplitTransfer moves money from one source to several destinations, or
+// from several sources to one destination, atomically: a failing leg aborts
+// the split with that leg's error and details.index.
+func (a *API) CreateSplitTransfer(w http.ResponseWriter, r *http.Request) {
+	var req model.SplitTransferRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.transferContext(r)
+	defer cancel()
+
+	// Unknown external ids resolve to 0 and fail like unknown accounts
+	transfers := req.Transfers()
+	refs := make([]model.AccountID, 0, 2*len(transfers))
+	for _, t := range transfers {
+		refs = append(refs, t.SourceAccountID, t.DestinationAccountID)
+	}
+	ids, err := a.resolveAccountIDs(ctx, refs...)
+	if err != nil {
+		a.logger.Printf("resolve split accounts failed: legs=%d, error=%v", len(transfers), err)
+		internalError(w, err)
+		return
+	}
+	items := make([]store.TransferItem, len(transfers))
+	treqs := make([]TransferRequest, len(transfers))
+	for i, t := range transfers {
+		items[i] = store.TransferItem{
+			SourceAccountID:      ids[2*i],
+			DestinationAccountID: ids[2*i+1],
+			Amount:               t.Amount.Decimal,
+			TransferDetails:      transferDetails(r, t, store.ChannelHTTP),
+		}
+		treqs[i] = TransferRequest{
+			Kind:                 TransferKindSplit,
+			SourceAccountID:      items[i].SourceAccountID,
+			DestinationAccountID: items[i].DestinationAccountID,
+			Amount:               items[i].Amount,
+			Details:              items[i].TransferDetails,
+		}
+		if !a.allowBatchItem(ctx, w, treqs[i], "legs[%d]", i, "index") {
+			return
+		}
+	}
+
+	group, results, err := a.store.SplitTransfer(ctx, items)
+	if err != nil {
+		// Nothing was transferred
+		for _, treq := range treqs {
+			a.transferDone(ctx, TransferResult{TransferRequest: treq, Status: store.StatusFailed, Err: err})
+		}
+		var itemErr *store.BatchItemError
+		if status, resp, ok := transferError(err); ok && errors.As(err, &itemErr) {
+			resp.Details = map[string]interface{}{"index": itemErr.Index}
+			writeJSON(w, status, resp)
+			return
+		}
+		if errors.Is(err, store.ErrInvalidSplit) {
+			writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+			return
+		}
+		a.logger.Printf("split transfer failed: legs=%d, error=%v", len(items), err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.SplitTransferResponse{
+		TransferGroupID: group.String(),
+		Legs:            make([]model.SplitLegResult, len(results)),
+	}
+	for i, res := range results {
+		resp.Legs[i] = model.SplitLegResult{
+			Index:                i,
+			ID:                   res.UUID.String(),
+			SourceAccountID:      items[i].SourceAccountID,
+			DestinationAccountID: items[i].DestinationAccountID,
+			Amount:               model.DecimalString{Decimal: items[i].Amount},
+		}
+		treqs[i].Details.UUID, treqs[i].Details.GroupID = res.UUID, group
+		a.transferDone(ctx, TransferResult{TransferRequest: treqs[i], ID: res.UUID, TransactionID: res.TransactionID,
+			Status: store.StatusSucceeded})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateSplitTransfer tests that a split is performed leg by leg from its
+// fixed side
+func TestCreateSplitTransfer(t *testing.T) {
+	group := uuid.New()
+	var got []store.TransferItem
+	mockStore := &MockStore{
+		SplitTransferFunc: func(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error) {
+			got = items
+			results := make([]store.TransferResult, len(items))
+			for i := range results {
+				results[i] = store.TransferResult{TransactionID: int64(10 + i), UUID: uuid.New()}
+			}
+			return group, results, nil
+		},
+	}
+	api := New(mockStore)
+
+	body := []byte(`{"source_account_id": 1, "reference": "payroll", "legs": [
+		{"account_id": 2, "amount": "10"},
+		{"account_id": 3, "amount": "5.50"}]}`)
+	w := httptest.NewRecorder()
+	api.CreateSplitTransfer(w, httptest.NewRequest(http.MethodPost, "/transactions/split", bytes.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body)
+	}
+	if len(got) != 2 || got[0].SourceAccountID != 1 || got[0].DestinationAccountID != 2 || got[1].DestinationAccountID != 3 ||
+		got[1].Reference != "payroll" || got[1].Channel != store.ChannelHTTP {
+		t.Fatalf("unexpected legs: %+v", got)
+	}
+	var resp model.SplitTransferResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.TransferGroupID != group.String() || len(resp.Legs) != 2 || resp.Legs[1].Index != 1 ||
+		resp.Legs[1].DestinationAccountID != 3 || resp.Legs[1].Amount.String() != "5.5" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+// TestCreateSplitTransfer_Errors tests validation and the failing leg of an
+// aborted split
+func TestCreateSplitTransfer_Errors(t *testing.T) {
+	mockStore := &MockStore{
+		SplitTransferFunc: func(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error) {
+			return uuid.Nil, nil, &store.BatchItemError{Index: 1, Err: store.ErrInsufficientFunds}
+		},
+	}
+	api := New(mockStore)
+
+	for body, want := range map[string]int{
+		`{"legs": [{"account_id": 2, "amount": "1"}]}`:                                                                http.StatusBadRequest,
+		`{"source_account_id": 1, "destination_account_id": 2, "legs": [{"account_id": 3, "amount": "1"}]}`:           http.StatusBadRequest,
+		`{"source_account_id": 1, "legs": []}`:                                                                        http.StatusBadRequest,
+		`{"source_account_id": 1, "legs": [{"account_id": 1, "amount": "1"}]}`:                                        http.StatusBadRequest,
+		`{"destination_account_id": 1, "legs": [{"account_id": 2, "amount": "1"}, {"account_id": 3, "amount": "1"}]}`: http.StatusConflict,
+	} {
+		w := httptest.NewRecorder()
+		api.CreateSplitTransfer(w, httptest.NewRequest(http.MethodPost, "/transactions/split", bytes.NewReader([]byte(body))))
+		if w.Code != want {
+			t.Fatalf("%s: expected status %d, got %d", body, want, w.Code)
+		}
+		if want != http.StatusConflict {
+			continue
+		}
+		var resp model.ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.Code != model.ErrCodeInsufficientFunds || resp.Details["index"] != float64(1) {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+	}
+}
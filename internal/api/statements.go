@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// statementPeriodLayout is the format of the {period} of statement routes
+const statementPeriodLayout = "2006-01"
+
+// StatementRenderer renders a statement in a format other than JSON, e.g. PDF.
+type StatementRenderer interface {
+	// ContentType is the media type of the rendered statement.
+	ContentType() string
+	Render(w io.Writer, s model.Statement) error
+}
+
+// WithStatementRenderer serves statements as ?format=<format> through r.
+func WithStatementRenderer(format string, r StatementRenderer) Option {
+	return func(a *API) {
+		if a.statementRenderers == nil {
+			a.statementRenderers = make(map[string]StatementRenderer)
+		}
+		a.statementRenderers[format] = r
+	}
+}
+
+// toStatement maps a stored statement to its JSON representation
+func (a *API) toStatement(s store.Statement) model.Statement {
+	resp := model.Statement{
+		AccountID:      s.AccountID,
+		Period:         s.Period.Format(statementPeriodLayout),
+		Currency:       s.Currency,
+		OpeningBalance: model.DecimalString{Decimal: s.OpeningBalance},
+		ClosingBalance: model.DecimalString{Decimal: s.ClosingBalance},
+		Entries:        make([]model.StatementEntry, 0, len(s.Entries)),
+		GeneratedAt:    s.CreatedAt,
+	}
+	for _, e := range s.Entries {
+		resp.Entries = append(resp.Entries, model.StatementEntry{
+			TransactionPublicID:   publicID(e.TransactionUUID),
+			TransactionID:         a.serialID(e.TransactionID),
+			SettledAt:             e.SettledAt,
+			CounterpartyAccountID: e.CounterpartyAccountID,
+			Amount:                model.DecimalString{Decimal: e.Amount},
+			BalanceAfter:          model.DecimalString{Decimal: e.BalanceAfter},
+			Reference:             e.Reference,
+		})
+	}
+	return resp
+}
+
+// GetStatement returns the account's statement for the month {period}
+// (yyyy-mm), as JSON or in a ?format= with a registered renderer
+func (a *API) GetStatement(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	period, err := time.Parse(statementPeriodLayout, vars["period"])
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "period must be yyyy-mm", map[string]interface{}{"parameter": "period"})
+		return
+	}
+	var renderer StatementRenderer
+	if format := r.URL.Query().Get("format"); format != "" && format != "json" {
+		if renderer = a.statementRenderers[format]; renderer == nil {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "unsupported format", map[string]interface{}{"parameter": "format"})
+			return
+		}
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	st, err := a.store.GetStatement(ctx, id, period)
+	if err != nil {
+		if errors.Is(err, store.ErrStatementNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeStatementNotFound, "statement not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("get statement failed: accountID=%d, period=%s, error=%v", id, vars["period"], err)
+		internalError(w, err)
+		return
+	}
+
+	if renderer == nil {
+		writeJSON(w, http.StatusOK, a.toStatement(st))
+		return
+	}
+	w.Header().Set("Content-Type", renderer.ContentType())
+	w.WriteHeader(http.StatusOK)
+	if err := renderer.Render(w, a.toStatement(st)); err != nil {
+		a.logger.Printf("render statement failed: accountID=%d, period=%s, error=%v", id, vars["period"], err)
+	}
+}
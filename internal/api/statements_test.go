@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// textRenderer renders statements as a line of text
+type textRenderer struct{}
+
+func (textRenderer) ContentType() string { return "text/plain" }
+
+func (textRenderer) Render(w io.Writer, s model.Statement) error {
+	_, err := fmt.Fprintf(w, "%d %s %s..%s", s.AccountID, s.Period, s.OpeningBalance.String(), s.ClosingBalance.String())
+	return err
+}
+
+// statementStore returns a store with a March 2024 statement of account 100
+func statementStore(t *testing.T) *MockStore {
+	return &MockStore{
+		GetStatementFunc: func(ctx context.Context, accountID int64, period time.Time) (store.Statement, error) {
+			if accountID != 100 || !period.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+				return store.Statement{}, store.ErrStatementNotFound
+			}
+			return store.Statement{
+				AccountID: 100, Period: period, Currency: "USD",
+				OpeningBalance: decimal.NewFromInt(50), ClosingBalance: decimal.NewFromInt(40),
+				Entries: []store.StatementEntry{
+					{TransactionID: 7, TransactionUUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000007"), CounterpartyAccountID: 200, Amount: decimal.NewFromInt(-10), BalanceAfter: decimal.NewFromInt(40)},
+				},
+			}, nil
+		},
+	}
+}
+
+// TestGetStatement tests fetching a statement as JSON
+func TestGetStatement(t *testing.T) {
+	api := New(statementStore(t))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/statements/2024-03", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.Statement
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Period != "2024-03" || !resp.OpeningBalance.Equal(decimal.NewFromInt(50)) || !resp.ClosingBalance.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("unexpected statement: %+v", resp)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].TransactionPublicID != "01890a5d-ac97-7000-8000-000000000007" || resp.Entries[0].TransactionID != 0 || !resp.Entries[0].Amount.Equal(decimal.NewFromInt(-10)) {
+		t.Fatalf("unexpected entries: %+v", resp.Entries)
+	}
+}
+
+// TestGetStatement_Renderer tests rendering a statement through a registered renderer
+func TestGetStatement_Renderer(t *testing.T) {
+	api := New(statementStore(t), WithStatementRenderer("txt", textRenderer{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/accounts/100/statements/2024-03?format=txt", nil)
+	w := httptest.NewRecorder()
+
+	r := mux.NewRouter()
+	api.RegisterRoutes(r)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Fatalf("expected the renderer's content type, got %q", ct)
+	}
+	if got := w.Body.String(); got != "100 2024-03 50..40" {
+		t.Fatalf("unexpected body: %q", got)
+	}
+}
+
+// TestGetStatement_Errors tests validation and a missing statement
+func TestGetStatement_Errors(t *testing.T) {
+	api := New(statementStore(t))
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/v1/accounts/100/statements/2024-3", http.StatusBadRequest},
+		{"/v1/accounts/100/statements/march", http.StatusBadRequest},
+		{"/v1/accounts/100/statements/2024-03?format=pdf", http.StatusBadRequest},
+		{"/v1/accounts/100/statements/2024-04", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+
+		r := mux.NewRouter()
+		api.RegisterRoutes(r)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.wantCode, w.Code)
+		}
+	}
+}
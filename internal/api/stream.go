@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TransferFeed delivers the notifications of committed transfers, such as a
+// *store.Listener on store.TransferChannel.
+type TransferFeed interface {
+	Subscribe(buffer int) (<-chan store.Notification, func(), error)
+}
+
+// WithTransferFeed serves live transfers from f on the stream routes, which
+// answer 501 without it.
+func WithTransferFeed(f TransferFeed) Option {
+	return func(a *API) {
+		a.feed = f
+	}
+}
+
+// Tuning of the event streams
+const (
+	streamBuffer       = 64               // notifications a stream may fall behind by before it is ended
+	streamKeepAlive    = 15 * time.Second // between comments keeping idle streams open through proxies
+	streamWriteTimeout = 10 * time.Second // for each write to a stream's client
+)
+
+// StopStreams ends the event streams in progress, which would otherwise hold
+// the server's shutdown up until its deadline. Register it with
+// http.Server.RegisterOnShutdown.
+func (a *API) StopStreams() {
+	a.stopStreamsOnce.Do(func() { close(a.streamsDone) })
+}
+
+// StreamAccountTransactions streams the account's transfers as server-sent
+// events as they commit: one transfer.completed or transfer.failed event per
+// transfer, whose data is the transfer event of the outbox. The stream ends
+// when the feed loses its connection or the client falls behind; clients
+// reconnect and read GET /accounts/{id}/transactions for what they missed.
+func (a *API) StreamAccountTransactions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	if a.feed == nil {
+		writeError(w, http.StatusNotImplemented, model.ErrCodeNotSupported, "live updates are not enabled")
+		return
+	}
+
+	// The account must be visible to the caller
+	ctx, cancel := a.requestContext(r)
+	_, err = a.store.GetAccount(ctx, id)
+	cancel()
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("stream account transactions failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	notifications, unsubscribe, err := a.feed.Subscribe(streamBuffer)
+	if err != nil {
+		a.logger.Printf("stream account transactions failed: accountID=%d, error=%v", id, err)
+		writeError(w, http.StatusServiceUnavailable, model.ErrCodeServiceUnavailable, "live updates are unavailable")
+		return
+	}
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	// The server's write timeout is sized for ordinary requests
+	write := func(format string, args ...any) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	if !write(": listening\n\n") {
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-a.streamsDone:
+			return
+		case <-keepAlive.C:
+			if !write(": keep-alive\n\n") {
+				return
+			}
+		case n, ok := <-notifications:
+			if !ok {
+				return
+			}
+			var t store.TransferNotification
+			if err := json.Unmarshal([]byte(n.Payload), &t); err != nil {
+				a.logger.Printf("stream account transactions: malformed notification: %v", err)
+				continue
+			}
+			if t.SourceAccountID != id && t.DestinationAccountID != id {
+				continue
+			}
+			data, err := json.Marshal(t.TransferPayload)
+			if err != nil {
+				continue
+			}
+			if !write("id: %s\nevent: %s\ndata: %s\n\n", t.ID, t.Type, data) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/events"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakeFeed hands out its channel, or err
+type fakeFeed struct {
+	c   chan store.Notification
+	err error
+}
+
+func (f *fakeFeed) Subscribe(buffer int) (<-chan store.Notification, func(), error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return f.c, func() {}, nil
+}
+
+// notification returns the notification of a completed transfer
+func notification(t *testing.T, id string, src, dst int64) store.Notification {
+	t.Helper()
+	payload, err := json.Marshal(store.TransferNotification{Type: events.TransferCompleted, TransferPayload: events.TransferPayload{
+		ID: id, SourceAccountID: src, DestinationAccountID: dst, Amount: "5",
+	}})
+	if err != nil {
+		t.Fatalf("marshal notification: %v", err)
+	}
+	return store.Notification{Channel: store.TransferChannel, Payload: string(payload)}
+}
+
+// TestStreamAccountTransactions tests that the account's transfers are
+// streamed as server-sent events, and those of other accounts left out
+func TestStreamAccountTransactions(t *testing.T) {
+	feed := &fakeFeed{c: make(chan store.Notification, 3)}
+	r := mux.NewRouter()
+	New(&MockStore{}, WithTransferFeed(feed)).RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	feed.c <- notification(t, "a", 1, 2)
+	feed.c <- notification(t, "b", 3, 4)
+	feed.c <- notification(t, "c", 5, 1)
+	close(feed.c)
+
+	resp, err := srv.Client().Get(srv.URL + "/v1/accounts/1/transactions/stream")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got status %d and %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	var ids []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if id, ok := strings.CutPrefix(scanner.Text(), "id: "); ok {
+			ids = append(ids, id)
+		}
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			var p events.TransferPayload
+			if err := json.Unmarshal([]byte(data), &p); err != nil || p.Amount != "5" {
+				t.Fatalf("unexpected data %q: %v", data, err)
+			}
+		}
+	}
+	if strings.Join(ids, ",") != "a,c" {
+		t.Fatalf("expected the transfers of account 1, got %v", ids)
+	}
+}
+
+// TestStreamAccountTransactions_Unavailable tests the answers when live
+// updates are disabled or the feed is down
+func TestStreamAccountTransactions_Unavailable(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		status int
+	}{
+		{"disabled", nil, http.StatusNotImplemented},
+		{"down", []Option{WithTransferFeed(&fakeFeed{err: store.ErrNotListening})}, http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			New(&MockStore{}, tc.opts...).RegisterRoutes(r)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/1/transactions/stream", nil))
+			if w.Code != tc.status {
+				t.Fatalf("expected status %d, got %d", tc.status, w.Code)
+			}
+		})
+	}
+}
+
+// TestStopStreams tests that StopStreams ends the streams in progress
+func TestStopStreams(t *testing.T) {
+	feed := &fakeFeed{c: make(chan store.Notification)}
+	r := mux.NewRouter()
+	a := New(&MockStore{}, WithTransferFeed(feed))
+	a.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/v1/accounts/1/transactions/stream")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	if line, err := reader.ReadString('\n'); err != nil || line != ": listening\n" {
+		t.Fatalf("expected the stream to open, got %q: %v", line, err)
+	}
+
+	a.StopStreams()
+	done := make(chan error, 1)
+	go func() {
+		_, err := io.ReadAll(reader)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected the stream to end, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stream to end")
+	}
+}
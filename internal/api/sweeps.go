@@ -0,0 +1,234 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// toSweepRuleResponse maps a stored sweep rule to its JSON representation
+func toSweepRuleResponse(sr store.SweepRule) model.SweepRuleResponse {
+	return model.SweepRuleResponse{
+		SweepRuleID:     sr.ID,
+		AccountID:       sr.AccountID,
+		TargetAccountID: sr.TargetAccountID,
+		Threshold:       model.DecimalString{Decimal: sr.Threshold},
+		CreatedAt:       sr.CreatedAt,
+	}
+}
+
+// sweepRuleID parses the sweep rule id of the request path, answering 400 if
+// it is invalid
+func sweepRuleID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid sweep rule id", map[string]interface{}{"parameter": "id"})
+		return 0, false
+	}
+	return id, true
+}
+
+// writeSweepRuleError answers the errors creating or updating a sweep rule of
+// accountID can fail with on account checks. It returns false for others.
+func writeSweepRuleError(w http.ResponseWriter, err error, accountID int64) bool {
+	var notFoundErr *store.AccountNotFoundError
+	switch {
+	case errors.As(err, &notFoundErr):
+		field := "target_account_id"
+		if notFoundErr.AccountID == accountID {
+			field = "account_id"
+		}
+		writeErrorDetails(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found",
+			map[string]interface{}{"account_id": notFoundErr.AccountID, "field": field})
+	case errors.Is(err, store.ErrSameAccount):
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, model.ErrSweepSameAccount.Error())
+	case errors.Is(err, store.ErrCurrencyMismatch):
+		writeError(w, http.StatusUnprocessableEntity, model.ErrCodeCurrencyMismatch, "account and target account have different currencies")
+	case errors.Is(err, store.ErrCrossTenant):
+		writeError(w, http.StatusForbidden, model.ErrCodeCrossTenant, "account and target account belong to different tenants")
+	default:
+		return notSupported(w, err)
+	}
+	return true
+}
+
+// CreateSweepRule adds a sweep rule moving the available balance of an
+// account above a threshold to a target account
+func (a *API) CreateSweepRule(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateSweepRuleRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	sr, err := a.store.CreateSweepRule(ctx, store.SweepRule{
+		AccountID:       req.AccountID,
+		TargetAccountID: req.TargetAccountID,
+		Threshold:       req.Threshold.Decimal,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrSweepRuleExists) {
+			writeError(w, http.StatusConflict, model.ErrCodeSweepRuleExists, "account already has a sweep rule")
+			return
+		}
+		if writeSweepRuleError(w, err, req.AccountID) {
+			return
+		}
+		a.logger.Printf("create sweep rule failed: accountID=%d, error=%v", req.AccountID, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toSweepRuleResponse(sr))
+}
+
+// ListSweepRules lists the sweep rules
+func (a *API) ListSweepRules(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	rules, err := a.store.ListSweepRules(ctx)
+	if err != nil {
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list sweep rules failed: error=%v", err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.SweepRuleListResponse{SweepRules: make([]model.SweepRuleResponse, 0, len(rules))}
+	for _, sr := range rules {
+		resp.SweepRules = append(resp.SweepRules, toSweepRuleResponse(sr))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// UpdateSweepRule replaces the target account and threshold of a sweep rule
+func (a *API) UpdateSweepRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := sweepRuleID(w, r)
+	if !ok {
+		return
+	}
+	var req model.UpdateSweepRuleRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	sr, err := a.store.UpdateSweepRule(ctx, id, req.TargetAccountID, req.Threshold.Decimal)
+	if err != nil {
+		if errors.Is(err, store.ErrSweepRuleNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeSweepRuleNotFound, "sweep rule not found")
+			return
+		}
+		if writeSweepRuleError(w, err, 0) {
+			return
+		}
+		a.logger.Printf("update sweep rule failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toSweepRuleResponse(sr))
+}
+
+// DeleteSweepRule deletes a sweep rule with its execution history; the
+// transfers it made are kept
+func (a *API) DeleteSweepRule(w http.ResponseWriter, r *http.Request) {
+	id, ok := sweepRuleID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	if err := a.store.DeleteSweepRule(ctx, id); err != nil {
+		if errors.Is(err, store.ErrSweepRuleNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeSweepRuleNotFound, "sweep rule not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("delete sweep rule failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListSweepExecutions lists the sweeps made by a rule, oldest first
+func (a *API) ListSweepExecutions(w http.ResponseWriter, r *http.Request) {
+	id, ok := sweepRuleID(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	var cursor int64
+	if c := q.Get("cursor"); c != "" {
+		var err error
+		cursor, err = strconv.ParseInt(c, 10, 64)
+		if err != nil || cursor <= 0 {
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid cursor", map[string]interface{}{"parameter": "cursor"})
+			return
+		}
+	}
+	limit, ok := parseLimit(w, q)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	execs, next, err := a.store.ListSweepExecutions(ctx, id, cursor, limit)
+	if err != nil {
+		if errors.Is(err, store.ErrSweepRuleNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeSweepRuleNotFound, "sweep rule not found")
+			return
+		}
+		if notSupported(w, err) {
+			return
+		}
+		a.logger.Printf("list sweep executions failed: id=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.SweepExecutionListResponse{Executions: make([]model.SweepExecutionResponse, 0, len(execs))}
+	for _, e := range execs {
+		resp.Executions = append(resp.Executions, model.SweepExecutionResponse{
+			SweepExecutionID:    e.ID,
+			AccountID:           e.AccountID,
+			TargetAccountID:     e.TargetAccountID,
+			Amount:              model.DecimalString{Decimal: e.Amount},
+			TransactionPublicID: publicID(e.TransactionUUID),
+			TransactionID:       a.serialID(e.TransactionID),
+			ExecutedAt:          e.ExecutedAt,
+		})
+	}
+	if next != 0 {
+		resp.NextCursor = strconv.FormatInt(next, 10)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
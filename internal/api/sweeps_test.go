@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestCreateSweepRule tests creating sweep rules and the errors of their accounts
+func TestCreateSweepRule(t *testing.T) {
+	mockStore := &MockStore{
+		CreateSweepFunc: func(ctx context.Context, sr store.SweepRule) (store.SweepRule, error) {
+			switch sr.TargetAccountID {
+			case 3:
+				return store.SweepRule{}, &store.AccountNotFoundError{AccountID: 3, Party: store.PartyDestination}
+			case 4:
+				return store.SweepRule{}, store.ErrCurrencyMismatch
+			case 5:
+				return store.SweepRule{}, store.ErrSweepRuleExists
+			}
+			sr.ID = 7
+			return sr, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	cases := []struct {
+		body  string
+		want  int
+		code  model.ErrorCode
+		field string
+	}{
+		{`{"account_id": 1, "target_account_id": 2, "threshold": "1000"}`, http.StatusCreated, "", ""},
+		{`{"account_id": 1, "target_account_id": 1, "threshold": "1000"}`, http.StatusBadRequest, model.ErrCodeValidationFailed, ""},
+		{`{"account_id": 1, "target_account_id": 2}`, http.StatusBadRequest, model.ErrCodeValidationFailed, ""},
+		{`{"account_id": 1, "target_account_id": 2, "threshold": "-1"}`, http.StatusBadRequest, model.ErrCodeValidationFailed, ""},
+		{`{"account_id": 1, "target_account_id": 3, "threshold": "1000"}`, http.StatusNotFound, model.ErrCodeAccountNotFound, "target_account_id"},
+		{`{"account_id": 1, "target_account_id": 4, "threshold": "1000"}`, http.StatusUnprocessableEntity, model.ErrCodeCurrencyMismatch, ""},
+		{`{"account_id": 1, "target_account_id": 5, "threshold": "1000"}`, http.StatusConflict, model.ErrCodeSweepRuleExists, ""},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/sweep-rules", bytes.NewReader([]byte(c.body))))
+		if w.Code != c.want {
+			t.Fatalf("%s: expected status %d, got %d: %s", c.body, c.want, w.Code, w.Body.String())
+		}
+		if c.want != http.StatusCreated {
+			var resp model.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != c.code {
+				t.Fatalf("%s: expected code %s, got %+v (%v)", c.body, c.code, resp, err)
+			}
+			if c.field != "" && resp.Details["field"] != c.field {
+				t.Fatalf("%s: expected field %s, got %+v", c.body, c.field, resp.Details)
+			}
+			continue
+		}
+		var resp model.SweepRuleResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.SweepRuleID != 7 || resp.AccountID != 1 || resp.TargetAccountID != 2 || resp.Threshold.String() != "1000" {
+			t.Fatalf("unexpected response: %+v", resp)
+		}
+	}
+}
+
+// TestUpdateAndDeleteSweepRule tests replacing and deleting sweep rules by id
+func TestUpdateAndDeleteSweepRule(t *testing.T) {
+	mockStore := &MockStore{
+		UpdateSweepFunc: func(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (store.SweepRule, error) {
+			if id == 4 {
+				return store.SweepRule{}, store.ErrSweepRuleNotFound
+			}
+			return store.SweepRule{ID: id, AccountID: 1, TargetAccountID: targetID, Threshold: threshold}, nil
+		},
+		DeleteSweepFunc: func(ctx context.Context, id int64) error {
+			if id == 4 {
+				return store.ErrSweepRuleNotFound
+			}
+			return nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	body := `{"target_account_id": 9, "threshold": "250.50"}`
+	for path, want := range map[string]int{
+		"/v1/admin/sweep-rules/3":   http.StatusOK,
+		"/v1/admin/sweep-rules/4":   http.StatusNotFound,
+		"/v1/admin/sweep-rules/abc": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body))))
+		if w.Code != want {
+			t.Fatalf("PUT %s: expected status %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+		if want == http.StatusOK {
+			var resp model.SweepRuleResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.TargetAccountID != 9 || resp.Threshold.String() != "250.5" {
+				t.Fatalf("unexpected response: %+v (%v)", resp, err)
+			}
+		}
+	}
+	for path, want := range map[string]int{
+		"/v1/admin/sweep-rules/3":   http.StatusNoContent,
+		"/v1/admin/sweep-rules/4":   http.StatusNotFound,
+		"/v1/admin/sweep-rules/abc": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, path, nil))
+		if w.Code != want {
+			t.Fatalf("DELETE %s: expected status %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestListSweepExecutions tests paging through the history of a sweep rule
+func TestListSweepExecutions(t *testing.T) {
+	executedAt := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	mockStore := &MockStore{
+		SweepExecsFunc: func(ctx context.Context, ruleID, cursor int64, limit int) ([]store.SweepExecution, int64, error) {
+			if ruleID == 4 {
+				return nil, 0, store.ErrSweepRuleNotFound
+			}
+			if cursor != 0 {
+				return []store.SweepExecution{{ID: 2, RuleID: ruleID, AccountID: 1, TargetAccountID: 2, Amount: decimal.NewFromInt(5), TransactionID: 12, TransactionUUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000012"), ExecutedAt: executedAt}}, 0, nil
+			}
+			return []store.SweepExecution{{ID: 1, RuleID: ruleID, AccountID: 1, TargetAccountID: 2, Amount: decimal.NewFromInt(40), TransactionID: 11, TransactionUUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000011"), ExecutedAt: executedAt}}, 1, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/sweep-rules/3/executions?limit=1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp model.SweepExecutionListResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Executions) != 1 || resp.Executions[0].TransactionPublicID != "01890a5d-ac97-7000-8000-000000000011" || resp.Executions[0].TransactionID != 0 || resp.Executions[0].Amount.String() != "40" || resp.NextCursor != "1" {
+		t.Fatalf("unexpected first page: %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/sweep-rules/3/executions?cursor="+resp.NextCursor, nil))
+	resp = model.SweepExecutionListResponse{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || len(resp.Executions) != 1 || resp.Executions[0].SweepExecutionID != 2 || resp.NextCursor != "" {
+		t.Fatalf("unexpected last page: %+v (%v)", resp, err)
+	}
+
+	for path, want := range map[string]int{
+		"/v1/admin/sweep-rules/4/executions":          http.StatusNotFound,
+		"/v1/admin/sweep-rules/3/executions?cursor=0": http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != want {
+			t.Fatalf("GET %s: expected status %d, got %d: %s", path, want, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// SerialTransactionIDsSunset is the date serial transaction ids are removed
+// from responses for good; WithSerialTransactionIDs is a migration aid until then.
+var SerialTransactionIDsSunset = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+// WithSerialTransactionIDs enables or disables the deprecated serial ids of
+// transactions in responses (disabled by default): transaction_id, and the
+// reversal_of and fee_of references, next to the public UUIDs that replace
+// them. Serial ids reveal how many transactions there are, so they are only
+// meant for clients still moving off them before SerialTransactionIDsSunset.
+// Either way, transactions can be looked up by serial id.
+func WithSerialTransactionIDs(enabled bool) Option {
+	return func(a *API) {
+		a.serialIDs = enabled
+	}
+}
+
+// serialID returns the serial transaction id id if serial ids are enabled,
+// or 0, which is left out of responses.
+func (a *API) serialID(id int64) int64 {
+	if !a.serialIDs {
+		return 0
+	}
+	return id
+}
+
+// publicID returns the public id of a referenced transaction, or "" if there
+// is none.
+func publicID(uid uuid.UUID) string {
+	if uid == uuid.Nil {
+		return ""
+	}
+	return uid.String()
+}
+
+// transactionPath lets the {id} of a transaction route be the transaction's
+// public UUID: it is looked up and replaced by the serial id before h runs.
+// Serial and malformed ids are left for h to handle.
+func (a *API) transactionPath(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		uid, err := uuid.Parse(vars["id"])
+		if err != nil {
+			h(w, r)
+			return
+		}
+
+		ctx, cancel := a.requestContext(r)
+		defer cancel()
+
+		id, err := a.store.TransactionID(ctx, uid)
+		if err != nil {
+			if errors.Is(err, store.ErrTransactionNotFound) {
+				writeError(w, http.StatusNotFound, model.ErrCodeTransactionNotFound, "transaction not found")
+				return
+			}
+			a.logger.Printf("resolve transaction failed: uuid=%s, error=%v", uid, err)
+			internalError(w, err)
+			return
+		}
+		resolved := make(map[string]string, len(vars))
+		for k, v := range vars {
+			resolved[k] = v
+		}
+		resolved["id"] = strconv.FormatInt(id, 10)
+		h(w, mux.SetURLVars(r, resolved))
+	}
+}
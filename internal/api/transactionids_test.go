@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestTransactionPath_UUID tests that transaction routes accept public UUIDs
+func TestTransactionPath_UUID(t *testing.T) {
+	known := uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")
+	mockStore := &MockStore{
+		TxIDFunc: func(ctx context.Context, uid uuid.UUID) (int64, error) {
+			if uid != known {
+				return 0, store.ErrTransactionNotFound
+			}
+			return 7, nil
+		},
+		GetTxFunc: func(ctx context.Context, id int64) (store.Transaction, error) {
+			if id != 7 {
+				return store.Transaction{}, store.ErrTransactionNotFound
+			}
+			return store.Transaction{ID: 7, Status: store.StatusSucceeded, TransferDetails: store.TransferDetails{UUID: known}}, nil
+		},
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	tests := []struct {
+		path     string
+		wantCode int
+	}{
+		{"/v1/transactions/" + known.String(), http.StatusOK},
+		{"/v1/transactions/7", http.StatusOK},
+		{"/v1/transactions/" + uuid.Nil.String(), http.StatusNotFound},
+		{"/v1/transactions/not-an-id", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d", tt.path, tt.wantCode, w.Code)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		var resp model.Transaction
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.ID != known.String() || resp.TransactionID != 0 {
+			t.Fatalf("%s: expected transaction 7 with id %s, got %+v", tt.path, known, resp)
+		}
+	}
+}
+
+// TestCreateTransaction_PublicID tests that the id drawn before a transfer is the one returned
+func TestCreateTransaction_PublicID(t *testing.T) {
+	uid := uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")
+	api := New(&MockStore{NewTxIDFunc: func() (uuid.UUID, error) { return uid, nil }})
+
+	body := []byte(`{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`)
+	w := httptest.NewRecorder()
+	api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader(body)))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	var resp model.TransactionResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.ID != uid.String() {
+		t.Fatalf("expected id %s, got %+v", uid, resp)
+	}
+}
+
+// TestSerialTransactionIDs tests that serial transaction ids are left out of
+// responses unless enabled, and that a fee refund references the reversal and
+// the refunded fee by public id either way
+func TestSerialTransactionIDs(t *testing.T) {
+	rev := uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057")
+	refunded := uuid.MustParse("01890a5d-ac97-7000-8000-000000000005")
+	mockStore := &MockStore{
+		GetTxFunc: func(ctx context.Context, id int64) (store.Transaction, error) {
+			return store.Transaction{ID: 6, Status: store.StatusSucceeded, Kind: store.KindFee, ReversalOf: 4, ReversalOfUUID: refunded,
+				FeeOf: 3, FeeOfUUID: rev, TransferDetails: store.TransferDetails{UUID: uuid.MustParse("01890a5d-ac97-7000-8000-000000000006")}}, nil
+		},
+		TransferFunc: func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+			return 42, nil
+		},
+		GetHoldFunc: func(ctx context.Context, id int64) (store.Hold, error) {
+			return store.Hold{ID: id, Status: store.HoldCaptured, TransactionID: 7, TransactionUUID: refunded}, nil
+		},
+	}
+
+	for _, serial := range []bool{false, true} {
+		r := mux.NewRouter()
+		New(mockStore, WithSerialTransactionIDs(serial)).RegisterRoutes(r)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/transactions/6", nil))
+		var got map[string]any
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got["reversal_of_id"] != refunded.String() || got["fee_of_id"] != rev.String() {
+			t.Fatalf("serial=%t: expected references by public id, got %v", serial, got)
+		}
+		for key, want := range map[string]float64{"transaction_id": 6, "reversal_of": 4, "fee_of": 3} {
+			if v, ok := got[key]; ok != serial || serial && v != want {
+				t.Fatalf("serial=%t: unexpected %s in %v", serial, key, got)
+			}
+		}
+
+		w = httptest.NewRecorder()
+		body := `{"source_account_id": 100, "destination_account_id": 200, "amount": "50.00"}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/transactions", bytes.NewReader([]byte(body))))
+		var resp model.TransactionResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.ID == "" || (resp.TransactionID == 42) != serial {
+			t.Fatalf("serial=%t: unexpected response %+v", serial, resp)
+		}
+
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/holds/3", nil))
+		var hold model.HoldResponse
+		if err := json.NewDecoder(w.Body).Decode(&hold); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if hold.TransactionPublicID != refunded.String() || (hold.TransactionID == 7) != serial {
+			t.Fatalf("serial=%t: unexpected hold %+v", serial, hold)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r := mux.NewRouter()
+	New(exportStore(t), WithSerialTransactionIDs(true)).RegisterRoutes(r)
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/100/transactions/export?from=2024-03-01T00:00:00Z", nil))
+	if records, err := csv.NewReader(w.Body).ReadAll(); err != nil || len(records) != 3 || records[1][0] != "1" || records[2][0] != "2" {
+		t.Fatalf("expected serial ids in the CSV export, got %v, %v", records, err)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// CreateWallet creates a labelled wallet under the account in the path. The
+// wallet is an account of its own, addressed as "<account>/<wallet>" in
+// transfers.
+func (a *API) CreateWallet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+	var req model.CreateWalletRequest
+	if !a.decodeJSON(w, r, &req) {
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error())
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	meta := store.AccountMetadata{DisplayName: req.DisplayName, Tags: req.Tags}
+	acc, err := a.store.CreateWallet(ctx, id, req.Wallet, req.AccountID, req.InitialBalance.Decimal, meta)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAccountNotFound):
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+		case errors.Is(err, store.ErrNestedWallet):
+			writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, err.Error(), map[string]interface{}{"parameter": "id"})
+		case errors.Is(err, store.ErrAccountInactive):
+			writeError(w, http.StatusUnprocessableEntity, model.ErrCodeAccountInactive, "account is not active")
+		case errors.Is(err, store.ErrAccountExists):
+			writeError(w, http.StatusConflict, model.ErrCodeDuplicateAccount, "wallet or account id already exists")
+		default:
+			a.logger.Printf("create wallet failed: accountID=%d, wallet=%s, error=%v", id, req.Wallet, err)
+			internalError(w, err)
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, toAccountResponse(acc))
+}
+
+// ListWallets returns the account in the path with its wallets and their
+// balances added up.
+func (a *API) ListWallets(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		writeErrorDetails(w, http.StatusBadRequest, model.ErrCodeValidationFailed, "invalid account id", map[string]interface{}{"parameter": "id"})
+		return
+	}
+
+	ctx, cancel := a.requestContext(r)
+	defer cancel()
+
+	ws, err := a.store.Wallets(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrAccountNotFound) {
+			writeError(w, http.StatusNotFound, model.ErrCodeAccountNotFound, "account not found")
+			return
+		}
+		a.logger.Printf("list wallets failed: accountID=%d, error=%v", id, err)
+		internalError(w, err)
+		return
+	}
+
+	resp := model.WalletsResponse{
+		AccountID:        ws.Account.ID,
+		Currency:         ws.Account.Currency,
+		Balance:          model.DecimalString{Decimal: ws.Balance},
+		AvailableBalance: model.DecimalString{Decimal: ws.AvailableBalance},
+		Account:          toAccountResponse(ws.Account),
+		Wallets:          make([]model.AccountResponse, len(ws.Wallets)),
+	}
+	for i, acc := range ws.Wallets {
+		resp.Wallets[i] = toAccountResponse(acc)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// walletStore is externalIDStore with wallet "savings" of account 100 as account 101
+func walletStore() *MockStore {
+	m := externalIDStore()
+	m.WalletIDsFunc = func(ctx context.Context, refs []store.WalletRef) (map[store.WalletRef]int64, error) {
+		ids := map[store.WalletRef]int64{}
+		for _, ref := range refs {
+			if ref == (store.WalletRef{ParentID: 100, Wallet: "savings"}) {
+				ids[ref] = 101
+			}
+		}
+		return ids, nil
+	}
+	return m
+}
+
+// TestCreateTransaction_Wallets tests transfers addressed to the wallet of an account
+func TestCreateTransaction_Wallets(t *testing.T) {
+	mockStore := walletStore()
+	var gotSrc, gotDst int64
+	mockStore.TransferFunc = func(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+		gotSrc, gotDst = srcID, dstID
+		return 42, nil
+	}
+	api := New(mockStore)
+
+	tests := []struct {
+		body             string
+		wantCode         int
+		wantSrc, wantDst int64
+	}{
+		{`{"source_account_id": 200, "destination_account_id": "100/savings", "amount": "5"}`, http.StatusOK, 200, 101},
+		{`{"source_account_id": "ext-100/savings", "destination_account_id": "ext-100", "amount": "5"}`, http.StatusOK, 101, 100},
+		{`{"source_account_id": 200, "destination_account_id": "100/checking", "amount": "5"}`, http.StatusNotFound, 0, 0},
+		{`{"source_account_id": 200, "destination_account_id": "ext-999/savings", "amount": "5"}`, http.StatusNotFound, 0, 0},
+		{`{"source_account_id": 200, "destination_account_id": "100/Savings", "amount": "5"}`, http.StatusBadRequest, 0, 0},
+	}
+	for _, tt := range tests {
+		gotSrc, gotDst = 0, 0
+		w := httptest.NewRecorder()
+		api.CreateTransaction(w, httptest.NewRequest(http.MethodPost, "/transactions", bytes.NewReader([]byte(tt.body))))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.body, tt.wantCode, w.Code, w.Body.String())
+		}
+		if gotSrc != tt.wantSrc || gotDst != tt.wantDst {
+			t.Fatalf("%s: expected transfer from %d to %d, got %d to %d", tt.body, tt.wantSrc, tt.wantDst, gotSrc, gotDst)
+		}
+	}
+}
+
+// TestCreateWallet tests creating a wallet under an account given by external id
+func TestCreateWallet(t *testing.T) {
+	mockStore := walletStore()
+	mockStore.CreateWalletFunc = func(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta store.AccountMetadata) (store.Account, error) {
+		switch {
+		case parentID == 101:
+			return store.Account{}, store.ErrNestedWallet
+		case parentID != 100:
+			return store.Account{}, store.ErrAccountNotFound
+		case wallet == "savings":
+			return store.Account{}, store.ErrAccountExists
+		}
+		return store.Account{ID: 102, Balance: initial, Currency: "USD", Status: store.AccountActive, ParentID: parentID, Wallet: wallet}, nil
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	tests := []struct {
+		path, body string
+		wantCode   int
+	}{
+		{"/v1/accounts/ext-100/wallets", `{"wallet": "fx-eur", "initial_balance": "10"}`, http.StatusCreated},
+		{"/v1/accounts/100/wallets", `{"wallet": "savings", "initial_balance": "0"}`, http.StatusConflict},
+		{"/v1/accounts/101/wallets", `{"wallet": "fx-eur", "initial_balance": "0"}`, http.StatusBadRequest},
+		{"/v1/accounts/300/wallets", `{"wallet": "fx-eur", "initial_balance": "0"}`, http.StatusNotFound},
+		{"/v1/accounts/100/wallets", `{"wallet": "FX EUR", "initial_balance": "0"}`, http.StatusBadRequest},
+		{"/v1/accounts/100/wallets", `{"wallet": "fx-eur", "initial_balance": "-1"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader([]byte(tt.body))))
+
+		if w.Code != tt.wantCode {
+			t.Fatalf("%s %s: expected status %d, got %d: %s", tt.path, tt.body, tt.wantCode, w.Code, w.Body.String())
+		}
+		if w.Code != http.StatusCreated {
+			continue
+		}
+		var resp model.AccountResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if resp.AccountID != 102 || resp.ParentAccountID != 100 || resp.Wallet != "fx-eur" || resp.Balance.String() != "10" {
+			t.Fatalf("unexpected wallet %+v", resp)
+		}
+	}
+}
+
+// TestListWallets tests that the balances of an account and its wallets are added up
+func TestListWallets(t *testing.T) {
+	mockStore := walletStore()
+	mockStore.WalletsFunc = func(ctx context.Context, accountID int64) (store.Wallets, error) {
+		if accountID != 100 {
+			return store.Wallets{}, store.ErrAccountNotFound
+		}
+		return store.SumWallets(100, []store.Account{
+			{ID: 100, Balance: decimal.NewFromInt(50), AvailableBalance: decimal.NewFromInt(40), Currency: "USD"},
+			{ID: 101, Balance: decimal.RequireFromString("25.5"), AvailableBalance: decimal.RequireFromString("25.5"), Currency: "USD", ParentID: 100, Wallet: "savings"},
+		}), nil
+	}
+	r := mux.NewRouter()
+	New(mockStore).RegisterRoutes(r)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/ext-100/wallets", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp model.WalletsResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.AccountID != 100 || resp.Balance.String() != "75.5" || resp.AvailableBalance.String() != "65.5" {
+		t.Fatalf("unexpected totals %+v", resp)
+	}
+	if len(resp.Wallets) != 1 || resp.Wallets[0].Wallet != "savings" || resp.Account.AccountID != 100 {
+		t.Fatalf("unexpected wallets %+v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/accounts/200/wallets", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
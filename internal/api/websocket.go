@@ -0,0 +1,294 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/you/internal-transfers/internal/events"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// DefaultWebSocketSubscriptions is the number of accounts a WebSocket
+// connection may subscribe to unless WithWebSocketSubscriptions is given.
+const DefaultWebSocketSubscriptions = 100
+
+// WithWebSocketSubscriptions caps the accounts each WebSocket connection may
+// subscribe to at n. Non-positive values keep DefaultWebSocketSubscriptions.
+func WithWebSocketSubscriptions(n int) Option {
+	return func(a *API) {
+		if n > 0 {
+			a.wsSubscriptions = n
+		}
+	}
+}
+
+// Tuning of the WebSocket connections; writes are bounded by streamWriteTimeout
+const (
+	wsMaxMessageBytes = 4 << 10          // of a client message
+	wsPingInterval    = 30 * time.Second // between pings checking the client is alive
+	wsPongWait        = 2 * wsPingInterval
+)
+
+// upgrader only accepts same-origin browser connections, as browsers send the
+// cookies of the API's origin along with cross-origin ones.
+var upgrader = websocket.Upgrader{}
+
+// ServeWebSocket upgrades the connection to a WebSocket on which the client
+// subscribes to accounts and receives their balances and committed transfers.
+//
+// The client sends {"type":"subscribe","account_ids":[...]} and
+// {"type":"unsubscribe","account_ids":[...]}; each request is answered with
+// the subscriptions, or an error message leaving them unchanged. Subscribing
+// sends each new account's balance, then every committed transfer of a
+// subscribed account is sent as a transfer message followed by the balances it
+// changed. The connection is closed with 1013 (try again later) when the feed
+// loses its connection or the client falls behind, and with 1001 (going away)
+// when the server shuts down; clients reconnect, subscribe again and read
+// GET /accounts/{id}/transactions for what they missed.
+func (a *API) ServeWebSocket(w http.ResponseWriter, r *http.Request) {
+	if a.feed == nil {
+		writeError(w, http.StatusNotImplemented, model.ErrCodeNotSupported, "live updates are not enabled")
+		return
+	}
+	notifications, unsubscribe, err := a.feed.Subscribe(streamBuffer)
+	if err != nil {
+		a.logger.Printf("websocket failed: error=%v", err)
+		writeError(w, http.StatusServiceUnavailable, model.ErrCodeServiceUnavailable, "live updates are unavailable")
+		return
+	}
+	defer unsubscribe()
+
+	// The upgrader answers failed handshakes itself
+	conn, err := upgrader.Upgrade(hijacker{w}, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxMessageBytes)
+	// The server's read timeout is still set on the connection
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// Client messages are read apart, and handled in turn with the notifications
+	requests := make(chan []byte)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(requests)
+		for {
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case requests <- msg:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ws := &wsConn{a: a, r: r, conn: conn, subs: make(map[int64]struct{})}
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-a.streamsDone:
+			ws.close(websocket.CloseGoingAway, "server shutting down")
+			return
+		case <-ping.C:
+			_ = conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case msg, ok := <-requests:
+			if !ok || !ws.handle(msg) {
+				return
+			}
+		case n, ok := <-notifications:
+			if !ok {
+				ws.close(websocket.CloseTryAgainLater, "live updates interrupted")
+				return
+			}
+			if !ws.notify(n) {
+				return
+			}
+		}
+	}
+}
+
+// wsConn is the state of a WebSocket connection, only used by its handler.
+type wsConn struct {
+	a    *API
+	r    *http.Request // the upgraded request, whose context scopes the store calls
+	conn *websocket.Conn
+	subs map[int64]struct{}
+}
+
+// handle answers the client message msg. It returns false once the
+// connection is unusable.
+func (ws *wsConn) handle(msg []byte) bool {
+	var req model.WSRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return ws.fail(model.ErrCodeInvalidJSON, "message is not valid JSON", nil)
+	}
+	switch req.Type {
+	case model.WSSubscribe:
+		return ws.subscribe(req.AccountIDs)
+	case model.WSUnsubscribe:
+		for _, id := range req.AccountIDs {
+			delete(ws.subs, id)
+		}
+		return ws.subscribed()
+	default:
+		return ws.fail(model.ErrCodeValidationFailed, "type must be subscribe or unsubscribe", map[string]interface{}{"field": "type"})
+	}
+}
+
+// subscribe adds the accounts ids to the subscriptions and sends their
+// balances, unless one of them is not visible to the caller or they would
+// exceed the limit.
+func (ws *wsConn) subscribe(ids []int64) bool {
+	var added []int64
+	for _, id := range ids {
+		if _, ok := ws.subs[id]; !ok && !slices.Contains(added, id) {
+			added = append(added, id)
+		}
+	}
+	if len(ws.subs)+len(added) > ws.a.wsSubscriptions {
+		return ws.fail(model.ErrCodeTooManySubscriptions, "a connection may subscribe to at most "+strconv.Itoa(ws.a.wsSubscriptions)+" accounts",
+			map[string]interface{}{"limit": ws.a.wsSubscriptions})
+	}
+
+	balances := make([]model.BalanceResponse, 0, len(added))
+	for _, id := range added {
+		b, err := ws.balance(id)
+		if err != nil {
+			if errors.Is(err, store.ErrAccountNotFound) {
+				return ws.fail(model.ErrCodeAccountNotFound, "account not found", map[string]interface{}{"account_id": id})
+			}
+			ws.a.logger.Printf("websocket subscribe failed: accountID=%d, error=%v", id, err)
+			return ws.fail(model.ErrCodeInternal, "internal server error", nil)
+		}
+		balances = append(balances, b)
+	}
+	for _, id := range added {
+		ws.subs[id] = struct{}{}
+	}
+	if !ws.subscribed() {
+		return false
+	}
+	for i := range balances {
+		if !ws.send(model.WSMessage{Type: model.WSBalance, Balance: &balances[i]}) {
+			return false
+		}
+	}
+	return true
+}
+
+// notify relays the transfer notification n if it concerns a subscribed
+// account, followed by the balances it changed.
+func (ws *wsConn) notify(n store.Notification) bool {
+	var t store.TransferNotification
+	if err := json.Unmarshal([]byte(n.Payload), &t); err != nil {
+		ws.a.logger.Printf("websocket: malformed notification: %v", err)
+		return true
+	}
+	var changed []int64
+	for _, id := range []int64{t.SourceAccountID, t.DestinationAccountID} {
+		if _, ok := ws.subs[id]; ok && !slices.Contains(changed, id) {
+			changed = append(changed, id)
+		}
+	}
+	if len(changed) == 0 {
+		return true
+	}
+	data, err := json.Marshal(t.TransferPayload)
+	if err != nil {
+		return true
+	}
+	if !ws.send(model.WSMessage{Type: model.WSTransfer, Event: t.Type, Transfer: data}) {
+		return false
+	}
+	// A failed transfer moved no money
+	if t.Type != events.TransferCompleted {
+		return true
+	}
+	for _, id := range changed {
+		b, err := ws.balance(id)
+		if err != nil {
+			// Gone or out of reach for now; the next transfer sends it again
+			ws.a.logger.Printf("websocket balance failed: accountID=%d, error=%v", id, err)
+			continue
+		}
+		if !ws.send(model.WSMessage{Type: model.WSBalance, Balance: &b}) {
+			return false
+		}
+	}
+	return true
+}
+
+// balance returns the current balance of the account id, as visible to the
+// caller.
+func (ws *wsConn) balance(id int64) (model.BalanceResponse, error) {
+	ctx, cancel := ws.a.requestContext(ws.r)
+	defer cancel()
+	acc, err := ws.a.store.GetAccount(ctx, id)
+	if err != nil {
+		return model.BalanceResponse{}, err
+	}
+	return model.BalanceResponse{
+		AccountID: acc.ID,
+		Balance:   model.DecimalString{Decimal: acc.Balance},
+		Currency:  acc.Currency,
+		At:        ws.a.clock.Now(),
+	}, nil
+}
+
+// subscribed sends the subscriptions, in ascending order.
+func (ws *wsConn) subscribed() bool {
+	ids := make([]int64, 0, len(ws.subs))
+	for id := range ws.subs {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ws.send(model.WSMessage{Type: model.WSSubscribed, AccountIDs: ids})
+}
+
+// fail sends an error message.
+func (ws *wsConn) fail(code model.ErrorCode, message string, details map[string]interface{}) bool {
+	return ws.send(model.WSMessage{Type: model.WSError, Error: &model.ErrorResponse{Code: code, Message: message, Details: details}})
+}
+
+// send writes m, reporting whether it could. A client not reading its
+// messages makes writes time out, and falls behind the notifications.
+func (ws *wsConn) send(m model.WSMessage) bool {
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	return ws.conn.WriteJSON(m) == nil
+}
+
+// close sends a close message with code and reason.
+func (ws *wsConn) close(code int, reason string) {
+	_ = ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(streamWriteTimeout))
+}
+
+// hijacker lets the upgrader take over the connection from behind the
+// middlewares' response writers, which only expose it through Unwrap.
+type hijacker struct {
+	http.ResponseWriter
+}
+
+func (h hijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(h.ResponseWriter).Hijack()
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// dialWebSocket serves a on a test server and connects to its /ws
+func dialWebSocket(t *testing.T, a *API) *websocket.Conn {
+	t.Helper()
+	r := mux.NewRouter()
+	r.Use(CompressionMiddleware)
+	a.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", http.Header{"Accept-Encoding": {"gzip"}})
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readWS reads the next message of conn
+func readWS(t *testing.T, conn *websocket.Conn) model.WSMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var m model.WSMessage
+	if err := conn.ReadJSON(&m); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return m
+}
+
+// wsStore serves accounts 1 to 3, whose balance is their id
+func wsStore() *MockStore {
+	return &MockStore{GetAccountFunc: func(ctx context.Context, id int64) (store.Account, error) {
+		if id < 1 || id > 3 {
+			return store.Account{}, store.ErrAccountNotFound
+		}
+		return store.Account{ID: id, Balance: decimal.NewFromInt(id), Currency: "USD"}, nil
+	}}
+}
+
+// TestServeWebSocket tests that subscribers get the balances of their
+// accounts, then the transfers of these accounts and the balances they change
+func TestServeWebSocket(t *testing.T) {
+	feed := &fakeFeed{c: make(chan store.Notification, 3)}
+	conn := dialWebSocket(t, New(wsStore(), WithTransferFeed(feed)))
+
+	if err := conn.WriteJSON(model.WSRequest{Type: model.WSSubscribe, AccountIDs: []int64{2, 1, 2}}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if m := readWS(t, conn); m.Type != model.WSSubscribed || len(m.AccountIDs) != 2 || m.AccountIDs[0] != 1 {
+		t.Fatalf("expected the subscriptions, got %+v", m)
+	}
+	for _, id := range []int64{2, 1} {
+		if m := readWS(t, conn); m.Type != model.WSBalance || m.Balance.AccountID != id || m.Balance.Balance.IntPart() != id {
+			t.Fatalf("expected the balance of account %d, got %+v", id, m)
+		}
+	}
+
+	feed.c <- notification(t, "a", 3, 4)
+	feed.c <- notification(t, "b", 3, 2)
+	m := readWS(t, conn)
+	if m.Type != model.WSTransfer || m.Event != "transfer.completed" || !strings.Contains(string(m.Transfer), `"id":"b"`) {
+		t.Fatalf("expected transfer b, got %+v", m)
+	}
+	if m := readWS(t, conn); m.Type != model.WSBalance || m.Balance.AccountID != 2 {
+		t.Fatalf("expected the balance of account 2, got %+v", m)
+	}
+
+	if err := conn.WriteJSON(model.WSRequest{Type: model.WSUnsubscribe, AccountIDs: []int64{1, 2}}); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if m := readWS(t, conn); m.Type != model.WSSubscribed || len(m.AccountIDs) != 0 {
+		t.Fatalf("expected no subscriptions, got %+v", m)
+	}
+
+	// Losing the feed ends the connection
+	close(feed.c)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err := conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
+
+// TestServeWebSocket_Errors tests that failed requests are answered with an
+// error and leave the subscriptions and the connection as they were
+func TestServeWebSocket_Errors(t *testing.T) {
+	feed := &fakeFeed{c: make(chan store.Notification)}
+	conn := dialWebSocket(t, New(wsStore(), WithTransferFeed(feed), WithWebSocketSubscriptions(2)))
+
+	for _, tc := range []struct {
+		msg  string
+		code model.ErrorCode
+	}{
+		{`{"type":"subscribe","account_ids":[1,2,3]}`, model.ErrCodeTooManySubscriptions},
+		{`{"type":"subscribe","account_ids":[1,9]}`, model.ErrCodeAccountNotFound},
+		{`{"type":"publish"}`, model.ErrCodeValidationFailed},
+		{`{`, model.ErrCodeInvalidJSON},
+	} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(tc.msg)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		if m := readWS(t, conn); m.Type != model.WSError || m.Error.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %+v", tc.msg, tc.code, m)
+		}
+	}
+	if err := conn.WriteJSON(model.WSRequest{Type: model.WSUnsubscribe}); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if m := readWS(t, conn); m.Type != model.WSSubscribed || len(m.AccountIDs) != 0 {
+		t.Fatalf("expected no subscriptions, got %+v", m)
+	}
+}
+
+// TestServeWebSocket_Unavailable tests the answers to the handshake when live
+// updates are disabled or the feed is down
+func TestServeWebSocket_Unavailable(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		opts   []Option
+		status int
+	}{
+		{"disabled", nil, http.StatusNotImplemented},
+		{"down", []Option{WithTransferFeed(&fakeFeed{err: store.ErrNotListening})}, http.StatusServiceUnavailable},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := mux.NewRouter()
+			New(&MockStore{}, tc.opts...).RegisterRoutes(r)
+			srv := httptest.NewServer(r)
+			defer srv.Close()
+			_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/v1/ws", nil)
+			if !errors.Is(err, websocket.ErrBadHandshake) || resp.StatusCode != tc.status {
+				t.Fatalf("expected status %d, got %v", tc.status, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries the caller's API key.
+const APIKeyHeader = "X-API-Key"
+
+// keyPrefix marks generated keys so they are easy to spot in config and logs.
+const keyPrefix = "itk_"
+
+// HashKey returns the hex SHA-256 of key. Only hashes are stored or compared.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateKey returns a new random API key.
+func GenerateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate key: %w", err)
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// ParseStaticKeys parses a comma-separated list of name:role:key entries
+// (the API_KEYS format) into principals keyed by key hash. A name of the form
+// tenant/name scopes the key to tenant.
+func ParseStaticKeys(s string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	err := parseCredentials(s, "api key", "key", func(_ string, p Principal, key string) {
+		keys[HashKey(key)] = p
+	})
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// parseCredentials parses a comma-separated list of name:role:secret entries,
+// calling add with the name as given, its principal and the secret of each.
+// what and secret name the entries and their secret in errors.
+func parseCredentials(s, what, secret string, add func(name string, p Principal, secret string)) error {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return fmt.Errorf("invalid %s entry %q: want name:role:%s", what, parts[0], secret)
+		}
+		role, err := ParseRole(parts[1])
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", what, parts[0], err)
+		}
+		p := Principal{Subject: parts[0], Role: role}
+		if tenant, name, ok := strings.Cut(parts[0], "/"); ok {
+			if tenant == "" || name == "" {
+				return fmt.Errorf("invalid %s entry %q: want tenant/name:role:%s", what, parts[0], secret)
+			}
+			p.Tenant, p.Subject = tenant, name
+		}
+		add(parts[0], p, parts[2])
+	}
+	return nil
+}
+
+// KeyLookupFunc resolves a key hash to its principal, returning
+// ErrUnauthenticated for unknown or revoked keys.
+type KeyLookupFunc func(ctx context.Context, keyHash string) (Principal, error)
+
+// APIKeyAuthenticator authenticates requests by the X-API-Key header against
+// static keys first and then, if set, a lookup (e.g. the api_keys table).
+type APIKeyAuthenticator struct {
+	static map[string]Principal
+	lookup KeyLookupFunc
+}
+
+// NewAPIKeyAuthenticator creates an authenticator over static keys (by hash)
+// and an optional lookup.
+func NewAPIKeyAuthenticator(static map[string]Principal, lookup KeyLookupFunc) *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{static: static, lookup: lookup}
+}
+
+// Authenticate implements Authenticator.
+func (a *APIKeyAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	hash := HashKey(key)
+	if p, ok := a.static[hash]; ok {
+		return p, nil
+	}
+	if a.lookup == nil {
+		return Principal{}, ErrUnauthenticated
+	}
+	p, err := a.lookup(r.Context(), hash)
+	if err != nil {
+		if errors.Is(err, ErrUnauthenticated) {
+			return Principal{}, ErrUnauthenticated
+		}
+		return Principal{}, fmt.Errorf("lookup api key: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,72 @@
+// Package auth identifies API callers and the roles they hold.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrInvalidRole     = errors.New("role must be one of admin, service, readonly")
+)
+
+// Role grants access to a class of endpoints. Roles are ordered:
+// admin includes service, which includes readonly.
+type Role string
+
+const (
+	RoleReadonly Role = "readonly"
+	RoleService  Role = "service"
+	RoleAdmin    Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleReadonly: 1,
+	RoleService:  2,
+	RoleAdmin:    3,
+}
+
+// ParseRole converts s into a known Role.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := roleRank[r]; !ok {
+		return "", fmt.Errorf("%w: %q", ErrInvalidRole, s)
+	}
+	return r, nil
+}
+
+// Allows reports whether r grants at least the required role.
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required] && roleRank[r] > 0
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject  string
+	Tenant   string
+	Role     Role
+	Networks []netip.Prefix // if not empty, the only networks the caller may connect from
+}
+
+// Authenticator resolves the caller of an HTTP request. It returns
+// ErrUnauthenticated when the request carries no valid credentials.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
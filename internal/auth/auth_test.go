@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRole_Allows(t *testing.T) {
+	cases := []struct {
+		have, need Role
+		want       bool
+	}{
+		{RoleAdmin, RoleService, true},
+		{RoleAdmin, RoleReadonly, true},
+		{RoleService, RoleService, true},
+		{RoleService, RoleAdmin, false},
+		{RoleReadonly, RoleService, false},
+		{Role(""), RoleReadonly, false},
+	}
+	for _, c := range cases {
+		if got := c.have.Allows(c.need); got != c.want {
+			t.Fatalf("%q.Allows(%q) = %v, want %v", c.have, c.need, got, c.want)
+		}
+	}
+}
+
+func TestParseStaticKeys(t *testing.T) {
+	keys, err := ParseStaticKeys("ops:admin:k1, dash:readonly:k2, unit-a/payments:service:k3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := keys[HashKey("k1")]; p.Subject != "ops" || p.Role != RoleAdmin {
+		t.Fatalf("unexpected principal for k1: %+v", p)
+	}
+	if p := keys[HashKey("k2")]; p.Subject != "dash" || p.Role != RoleReadonly {
+		t.Fatalf("unexpected principal for k2: %+v", p)
+	}
+	if p := keys[HashKey("k3")]; p.Subject != "payments" || p.Tenant != "unit-a" || p.Role != RoleService {
+		t.Fatalf("unexpected principal for k3: %+v", p)
+	}
+
+	if _, err := ParseStaticKeys("ops:root:k1"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := ParseStaticKeys("missing-parts"); err == nil {
+		t.Fatalf("expected error for malformed entry")
+	}
+	if _, err := ParseStaticKeys("/ops:admin:k1"); err == nil {
+		t.Fatalf("expected error for an empty tenant")
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	static := map[string]Principal{HashKey("static"): {Subject: "ops", Role: RoleAdmin}}
+	lookup := func(ctx context.Context, keyHash string) (Principal, error) {
+		if keyHash == HashKey("stored") {
+			return Principal{Subject: "job", Role: RoleService}, nil
+		}
+		return Principal{}, ErrUnauthenticated
+	}
+	a := NewAPIKeyAuthenticator(static, lookup)
+
+	for key, want := range map[string]string{"static": "ops", "stored": "job"} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(APIKeyHeader, key)
+		p, err := a.Authenticate(req)
+		if err != nil || p.Subject != want {
+			t.Fatalf("key %q: got %+v, %v", key, p, err)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated without key, got %v", err)
+	}
+	req.Header.Set(APIKeyHeader, "unknown")
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated for unknown key, got %v", err)
+	}
+}
+
+func TestGenerateKey_Unique(t *testing.T) {
+	k1, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	k2, _ := GenerateKey()
+	if k1 == k2 || len(k1) != len(keyPrefix)+64 {
+		t.Fatalf("unexpected keys %q %q", k1, k2)
+	}
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers of signed requests
+const (
+	ClientIDHeader  = "X-Client-ID"
+	TimestampHeader = "X-Timestamp"
+	SignatureHeader = "X-Signature"
+)
+
+// Defaults for HMACConfig
+const (
+	// DefaultHMACMaxSkew is how far the timestamp of a signed request may be
+	// from the server's clock.
+	DefaultHMACMaxSkew      = 5 * time.Minute
+	defaultHMACMaxBodyBytes = 1 << 20
+)
+
+// HMACClient is a caller signing its requests with a shared secret.
+type HMACClient struct {
+	Principal Principal
+	Secret    []byte
+}
+
+// ParseHMACClients parses a comma-separated list of name:role:secret entries
+// (the HMAC_CLIENTS format) into clients keyed by name, which callers send in
+// X-Client-ID. A name of the form tenant/name scopes the client to tenant.
+func ParseHMACClients(s string) (map[string]HMACClient, error) {
+	clients := make(map[string]HMACClient)
+	err := parseCredentials(s, "hmac client", "secret", func(name string, p Principal, secret string) {
+		clients[name] = HMACClient{Principal: p, Secret: []byte(secret)}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// SignRequest returns the hex HMAC-SHA256, under secret, of the timestamp (Unix
+// seconds), method, request URI (path and query) and body of a request, each
+// followed by a newline but the body.
+func SignRequest(secret []byte, timestamp, method, requestURI string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", timestamp, method, requestURI)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// FirstUseFunc records the signature of an accepted request until expiresAt
+// and reports whether it was not recorded already, so that each is accepted
+// once.
+type FirstUseFunc func(ctx context.Context, sig string, expiresAt time.Time) (bool, error)
+
+// HMACConfig configures HMACAuthenticator.
+type HMACConfig struct {
+	Clients      map[string]HMACClient
+	MaxSkew      time.Duration // DefaultHMACMaxSkew if 0
+	MaxBodyBytes int64         // bodies beyond are refused unread; 1 MiB if 0
+	FirstUse     FirstUseFunc  // shared by the instances; in memory, per instance, if nil
+}
+
+// HMACAuthenticator authenticates requests signed by a known client (see
+// SignRequest). Requests without X-Signature are passed to the next
+// authenticator, if any.
+//
+// Timestamps further than MaxSkew from the clock are refused, and so is a
+// signature already seen within that window, so that a captured request cannot
+// be replayed. Without a shared FirstUse, seen signatures are kept in
+// memory, and each instance only refuses the replays it receives itself.
+//
+// Bodies are buffered to be verified before the caller is known, so
+// MaxBodyBytes bounds the memory an unauthenticated request can take.
+type HMACAuthenticator struct {
+	cfg      HMACConfig
+	next     Authenticator
+	now      func() time.Time
+	firstUse FirstUseFunc
+}
+
+// NewHMACAuthenticator creates an HMACAuthenticator falling back to next, which
+// may be nil for signed requests only.
+func NewHMACAuthenticator(cfg HMACConfig, next Authenticator) *HMACAuthenticator {
+	if cfg.MaxSkew <= 0 {
+		cfg.MaxSkew = DefaultHMACMaxSkew
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultHMACMaxBodyBytes
+	}
+	a := &HMACAuthenticator{cfg: cfg, next: next, now: time.Now, firstUse: cfg.FirstUse}
+	if a.firstUse == nil {
+		a.firstUse = (&memoryReplays{maxSkew: cfg.MaxSkew, seen: make(map[string]time.Time)}).firstUse
+	}
+	return a
+}
+
+// Authenticate implements Authenticator. The body is read to verify it and
+// replaced with a copy.
+func (a *HMACAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	sig := r.Header.Get(SignatureHeader)
+	if sig == "" {
+		if a.next != nil {
+			return a.next.Authenticate(r)
+		}
+		return Principal{}, ErrUnauthenticated
+	}
+	client, ok := a.cfg.Clients[r.Header.Get(ClientIDHeader)]
+	if !ok {
+		return Principal{}, fmt.Errorf("%w: unknown client", ErrUnauthenticated)
+	}
+	ts := r.Header.Get(TimestampHeader)
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: invalid timestamp", ErrUnauthenticated)
+	}
+	signedAt := time.Unix(secs, 0)
+	now := a.now()
+	if d := now.Sub(signedAt); d > a.cfg.MaxSkew || d < -a.cfg.MaxSkew {
+		return Principal{}, fmt.Errorf("%w: stale timestamp", ErrUnauthenticated)
+	}
+
+	if r.ContentLength > a.cfg.MaxBodyBytes {
+		return Principal{}, fmt.Errorf("%w: body too large to verify", ErrUnauthenticated)
+	}
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(io.LimitReader(r.Body, a.cfg.MaxBodyBytes+1))
+		if err != nil {
+			return Principal{}, fmt.Errorf("read body: %w", err)
+		}
+		if int64(len(body)) > a.cfg.MaxBodyBytes {
+			return Principal{}, fmt.Errorf("%w: body too large to verify", ErrUnauthenticated)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	want := SignRequest(client.Secret, ts, r.Method, r.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(strings.ToLower(sig)), []byte(want)) {
+		return Principal{}, fmt.Errorf("%w: invalid signature", ErrUnauthenticated)
+	}
+	first, err := a.firstUse(r.Context(), want, signedAt.Add(a.cfg.MaxSkew))
+	if err != nil {
+		return Principal{}, fmt.Errorf("record signature: %w", err)
+	}
+	if !first {
+		return Principal{}, fmt.Errorf("%w: replayed signature", ErrUnauthenticated)
+	}
+	return client.Principal, nil
+}
+
+// memoryReplays records the signatures accepted by a single instance. Expired signatures
+// are forgotten every maxSkew.
+type memoryReplays struct {
+	maxSkew time.Duration
+
+	mu     sync.Mutex
+	seen   map[string]time.Time // signature -> expiry
+	pruned time.Time
+}
+
+func (m *memoryReplays) firstUse(_ context.Context, sig string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if now.Sub(m.pruned) > m.maxSkew {
+		for s, t := range m.seen {
+			if now.After(t) {
+				delete(m.seen, s)
+			}
+		}
+		m.pruned = now
+	}
+	if _, ok := m.seen[sig]; ok {
+		return false, nil
+	}
+	m.seen[sig] = expiresAt
+	return true, nil
+}
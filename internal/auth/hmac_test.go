@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseHMACClients(t *testing.T) {
+	clients, err := ParseHMACClients("ledger:service:s1, unit-a/payroll:admin:s2")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c := clients["ledger"]; c.Principal.Subject != "ledger" || c.Principal.Role != RoleService || string(c.Secret) != "s1" {
+		t.Fatalf("unexpected client ledger: %+v", c)
+	}
+	if c := clients["unit-a/payroll"]; c.Principal.Subject != "payroll" || c.Principal.Tenant != "unit-a" || string(c.Secret) != "s2" {
+		t.Fatalf("unexpected client unit-a/payroll: %+v", c)
+	}
+	if _, err := ParseHMACClients("ledger:root:s1"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+	if _, err := ParseHMACClients("ledger:service:"); err == nil {
+		t.Fatal("expected error for a missing secret")
+	}
+}
+
+func TestHMACAuthenticator(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	clients, _ := ParseHMACClients("ledger:service:secret")
+	keys, _ := ParseStaticKeys("ops:admin:admin-key")
+	a := NewHMACAuthenticator(HMACConfig{Clients: clients, MaxBodyBytes: 64}, NewAPIKeyAuthenticator(keys, nil))
+	a.now = func() time.Time { return now }
+
+	body := `{"amount": "10"}`
+	signed := func(client, secret string, at time.Time, target, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		ts := strconv.FormatInt(at.Unix(), 10)
+		r.Header.Set(ClientIDHeader, client)
+		r.Header.Set(TimestampHeader, ts)
+		r.Header.Set(SignatureHeader, SignRequest([]byte(secret), ts, http.MethodPost, target, []byte(body)))
+		return r
+	}
+
+	r := signed("ledger", "secret", now.Add(-time.Minute), "/v1/transactions?dry_run=true", body)
+	p, err := a.Authenticate(r)
+	if err != nil || p.Subject != "ledger" || p.Role != RoleService {
+		t.Fatalf("Authenticate: got %+v, %v", p, err)
+	}
+	if b, _ := io.ReadAll(r.Body); string(b) != body {
+		t.Fatalf("expected the body to be readable after verification, got %q", b)
+	}
+
+	// The same request is refused the second time
+	if _, err := a.Authenticate(signed("ledger", "secret", now.Add(-time.Minute), "/v1/transactions?dry_run=true", body)); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a replay to be refused, got %v", err)
+	}
+
+	tampered := signed("ledger", "secret", now, "/v1/transactions", body)
+	tampered.Body = io.NopCloser(strings.NewReader(`{"amount": "10000"}`))
+	otherPath := signed("ledger", "secret", now, "/v1/transactions", body)
+	otherPath.URL.Path = "/v1/admin/adjustments"
+	noTimestamp := signed("ledger", "secret", now, "/v1/transactions", body)
+	noTimestamp.Header.Del(TimestampHeader)
+	for name, r := range map[string]*http.Request{
+		"stale":          signed("ledger", "secret", now.Add(-6*time.Minute), "/v1/transactions", body),
+		"future":         signed("ledger", "secret", now.Add(6*time.Minute), "/v1/transactions", body),
+		"wrong secret":   signed("ledger", "guess", now, "/v1/transactions", body),
+		"unknown client": signed("billing", "secret", now, "/v1/transactions", body),
+		"tampered body":  tampered,
+		"other path":     otherPath,
+		"no timestamp":   noTimestamp,
+		"body too large": signed("ledger", "secret", now, "/v1/transactions", strings.Repeat("x", 65)),
+	} {
+		if _, err := a.Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+
+	// Unsigned requests fall back to the API key
+	r = httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+	r.Header.Set(APIKeyHeader, "admin-key")
+	if p, err := a.Authenticate(r); err != nil || p.Subject != "ops" {
+		t.Fatalf("expected the API key to authenticate, got %+v, %v", p, err)
+	}
+	only := NewHMACAuthenticator(HMACConfig{Clients: clients}, nil)
+	if _, err := only.Authenticate(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected an unsigned request to be refused without fallback, got %v", err)
+	}
+}
+
+func TestHMACAuthenticator_SharedReplays(t *testing.T) {
+	clients, _ := ParseHMACClients("ledger:service:secret")
+	seen := make(map[string]time.Time)
+	firstUse := func(ctx context.Context, sig string, expiresAt time.Time) (bool, error) {
+		if _, ok := seen[sig]; ok {
+			return false, nil
+		}
+		seen[sig] = expiresAt
+		return true, nil
+	}
+	a := NewHMACAuthenticator(HMACConfig{Clients: clients, FirstUse: firstUse}, nil)
+	b := NewHMACAuthenticator(HMACConfig{Clients: clients, FirstUse: firstUse}, nil)
+
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signed := func() *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/v1/accounts/1", nil)
+		r.Header.Set(ClientIDHeader, "ledger")
+		r.Header.Set(TimestampHeader, ts)
+		r.Header.Set(SignatureHeader, SignRequest([]byte("secret"), ts, http.MethodGet, "/v1/accounts/1", nil))
+		return r
+	}
+	r := signed()
+	if _, err := a.Authenticate(r); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if _, err := b.Authenticate(signed()); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a replay on another instance to be refused, got %v", err)
+	}
+	if exp, ok := seen[r.Header.Get(SignatureHeader)]; !ok || time.Until(exp) <= 0 || time.Until(exp) > DefaultHMACMaxSkew {
+		t.Fatalf("expected the signature recorded until the skew passes, got %v", exp)
+	}
+}
@@ -0,0 +1,268 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for JWTConfig
+const (
+	DefaultTenantClaim  = "tenant"
+	DefaultRoleClaim    = "role"
+	defaultJWKSRefresh  = 5 * time.Minute
+	defaultClockLeeway  = 30 * time.Second
+	minJWKSRefetchDelay = 10 * time.Second
+)
+
+// JWTConfig configures JWTAuthenticator.
+type JWTConfig struct {
+	JWKSURL     string
+	Issuer      string // optional; checked against "iss" when set
+	Audience    string // optional; must be contained in "aud" when set
+	TenantClaim string // claim copied into Principal.Tenant
+	RoleClaim   string // claim holding the Role; callers without one are readonly
+	HTTPClient  *http.Client
+}
+
+// JWTAuthenticator validates RS256 bearer tokens against keys published at a JWKS URL.
+type JWTAuthenticator struct {
+	cfg  JWTConfig
+	keys *jwks
+	now  func() time.Time
+}
+
+// NewJWTAuthenticator creates a JWTAuthenticator. Keys are fetched lazily.
+func NewJWTAuthenticator(cfg JWTConfig) *JWTAuthenticator {
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = DefaultTenantClaim
+	}
+	if cfg.RoleClaim == "" {
+		cfg.RoleClaim = DefaultRoleClaim
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 5 * time.Second}
+	}
+	return &JWTAuthenticator{
+		cfg:  cfg,
+		keys: &jwks{url: cfg.JWKSURL, client: cfg.HTTPClient},
+		now:  time.Now,
+	}
+}
+
+// Authenticate implements Authenticator for "Authorization: Bearer <jwt>".
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (Principal, error) {
+	h := r.Header.Get("Authorization")
+	token, ok := strings.CutPrefix(h, "Bearer ")
+	if !ok || token == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+	claims, err := a.verify(r.Context(), token)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	p := Principal{Subject: sub, Role: RoleReadonly}
+	if t, ok := claims[a.cfg.TenantClaim].(string); ok {
+		p.Tenant = t
+	}
+	if s, ok := claims[a.cfg.RoleClaim].(string); ok {
+		role, err := ParseRole(s)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		p.Role = role
+	}
+	return p, nil
+}
+
+// verify checks the token signature and registered claims and returns its claims.
+// Every failure caused by the token itself wraps ErrUnauthenticated.
+func (a *JWTAuthenticator) verify(ctx context.Context, token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthenticated)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrUnauthenticated, err)
+	}
+	// Only RS256 is accepted; this also rules out "none" and HMAC confusion attacks
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported alg %q", ErrUnauthenticated, header.Alg)
+	}
+
+	key, err := a.keys.get(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding", ErrUnauthenticated)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrUnauthenticated)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrUnauthenticated, err)
+	}
+	if err := a.checkClaims(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return claims, nil
+}
+
+// checkClaims validates exp, nbf, iss and aud.
+func (a *JWTAuthenticator) checkClaims(claims map[string]interface{}) error {
+	now := a.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(defaultClockLeeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(defaultClockLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+	if a.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != a.cfg.Issuer {
+			return fmt.Errorf("unexpected issuer %q", iss)
+		}
+	}
+	if a.cfg.Audience != "" && !hasAudience(claims["aud"], a.cfg.Audience) {
+		return errors.New("token not issued for this audience")
+	}
+	return nil
+}
+
+// hasAudience reports whether the aud claim (string or array) contains want.
+func hasAudience(aud interface{}, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []interface{}:
+		for _, s := range v {
+			if s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// jwks caches RSA keys published at a JWKS URL. Keys are refreshed
+// periodically and when a token references an unknown kid.
+type jwks struct {
+	url    string
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	lastErr     error
+}
+
+func (j *jwks) get(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	// Refresh periodically, and early when a kid is unknown (key rotation),
+	// but never attempt more than once per minJWKSRefetchDelay
+	_, known := j.keys[kid]
+	age := time.Since(j.fetchedAt)
+	due := age > defaultJWKSRefresh || (!known && age > minJWKSRefetchDelay)
+	if due && time.Since(j.attemptedAt) > minJWKSRefetchDelay {
+		j.attemptedAt = time.Now()
+		j.lastErr = j.fetch(ctx)
+	}
+	if j.keys == nil && j.lastErr != nil {
+		return nil, j.lastErr
+	}
+
+	key, ok := j.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key id %q", ErrUnauthenticated, kid)
+	}
+	return key, nil
+}
+
+func (j *jwks) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return fmt.Errorf("build jwks request: %w", err)
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	j.keys = keys
+	j.fetchedAt = time.Now()
+	return nil
+}
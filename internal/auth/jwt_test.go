@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signRS256 builds a compact JWS for claims signed with key
+func signRS256(t *testing.T, key *rsa.PrivateKey, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	enc := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	input := enc(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + enc(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// newJWKSServer serves key's public half under kid
+func newJWKSServer(t *testing.T, key *rsa.PrivateKey, kid string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	srv := newJWKSServer(t, key, "k1")
+
+	a := NewJWTAuthenticator(JWTConfig{JWKSURL: srv.URL, Issuer: "https://idp", Audience: "transfers"})
+	exp := float64(time.Now().Add(time.Hour).Unix())
+	valid := map[string]interface{}{"sub": "svc-payroll", "tenant": "acme", "role": "service", "iss": "https://idp", "aud": []string{"transfers"}, "exp": exp}
+
+	authenticate := func(token string) (Principal, error) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return a.Authenticate(req)
+	}
+
+	p, err := authenticate(signRS256(t, key, "RS256", "k1", valid))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Subject != "svc-payroll" || p.Tenant != "acme" || p.Role != RoleService {
+		t.Fatalf("unexpected principal: %+v", p)
+	}
+
+	with := func(k string, v interface{}) map[string]interface{} {
+		c := make(map[string]interface{}, len(valid))
+		for kk, vv := range valid {
+			c[kk] = vv
+		}
+		if v == nil {
+			delete(c, k)
+		} else {
+			c[k] = v
+		}
+		return c
+	}
+	rejected := map[string]string{
+		"no token":       "",
+		"expired":        signRS256(t, key, "RS256", "k1", with("exp", float64(time.Now().Add(-time.Hour).Unix()))),
+		"wrong issuer":   signRS256(t, key, "RS256", "k1", with("iss", "https://evil")),
+		"wrong audience": signRS256(t, key, "RS256", "k1", with("aud", "other")),
+		"no subject":     signRS256(t, key, "RS256", "k1", with("sub", nil)),
+		"bad role":       signRS256(t, key, "RS256", "k1", with("role", "root")),
+		"wrong key":      signRS256(t, other, "RS256", "k1", valid),
+		"unknown kid":    signRS256(t, key, "RS256", "k2", valid),
+		"alg mismatch":   signRS256(t, key, "HS256", "k1", valid),
+		"malformed":      "not.a.jwt.at.all",
+	}
+	for name, token := range rejected {
+		if _, err := authenticate(token); !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestJWTAuthenticator_DefaultRole(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	srv := newJWKSServer(t, key, "k1")
+	a := NewJWTAuthenticator(JWTConfig{JWKSURL: srv.URL})
+
+	token := signRS256(t, key, "RS256", "k1", map[string]interface{}{"sub": "dash", "exp": float64(time.Now().Add(time.Minute).Unix())})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	p, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Role != RoleReadonly {
+		t.Fatalf("expected tokens without role to be readonly, got %q", p.Role)
+	}
+}
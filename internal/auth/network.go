@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ParseNetworks parses a list of CIDRs or IP addresses, the latter taken as
+// single-address networks, separated by sep.
+func ParseNetworks(s, sep string) ([]netip.Prefix, error) {
+	var networks []netip.Prefix
+	for _, n := range strings.Split(s, sep) {
+		n = strings.TrimSpace(n)
+		if n == "" {
+			continue
+		}
+		p, err := ParseNetwork(n)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, p)
+	}
+	return networks, nil
+}
+
+// ParseNetwork parses a CIDR or an IP address, taken as a single-address
+// network. Address bits beyond the prefix length are cleared.
+func ParseNetwork(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid network %q: want a CIDR or an IP address", s)
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q: want a CIDR or an IP address", s)
+	}
+	return p.Masked(), nil
+}
+
+// ParseKeyNetworks parses a comma-separated list of name=networks entries (the
+// API_KEY_NETWORKS format), the networks separated by |, into the networks of
+// each name.
+func ParseKeyNetworks(s string) (map[string][]netip.Prefix, error) {
+	out := make(map[string][]netip.Prefix)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid entry %q: want name=network|network", entry)
+		}
+		networks, err := ParseNetworks(list, "|")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if len(networks) == 0 {
+			return nil, fmt.Errorf("%s: no networks", name)
+		}
+		out[name] = networks
+	}
+	return out, nil
+}
+
+// Contains reports whether addr is in one of networks.
+func Contains(networks []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, n := range networks {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsAddr reports whether p may connect from addr.
+func (p Principal) AllowsAddr(addr netip.Addr) bool {
+	return len(p.Networks) == 0 || Contains(p.Networks, addr)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks("10.1.2.3/8, 192.168.0.7,2001:db8::/32", ",")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"10.0.0.0/8", "192.168.0.7/32", "2001:db8::/32"}
+	if len(networks) != len(want) {
+		t.Fatalf("expected %d networks, got %v", len(want), networks)
+	}
+	for i, w := range want {
+		if networks[i].String() != w {
+			t.Fatalf("network %d: expected %s, got %s", i, w, networks[i])
+		}
+	}
+	for addr, in := range map[string]bool{
+		"10.200.0.1":       true,
+		"::ffff:10.0.0.1":  true,
+		"192.168.0.7":      true,
+		"192.168.0.8":      false,
+		"2001:db8:1::1":    true,
+		"2001:db9::1":      false,
+		"::ffff:11.0.0.1":  false,
+		"172.16.0.1":       false,
+		"2001:0db8:0::abc": true,
+	} {
+		if got := Contains(networks, netip.MustParseAddr(addr)); got != in {
+			t.Fatalf("Contains(%s) = %v, want %v", addr, got, in)
+		}
+	}
+
+	for _, bad := range []string{"10.0.0.0/33", "example.com", "10.0.0"} {
+		if _, err := ParseNetworks(bad, ","); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestParseKeyNetworks(t *testing.T) {
+	got, err := ParseKeyNetworks("ops=10.0.0.0/8|192.168.1.5, unit-a/teller=172.16.0.0/12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got["ops"]) != 2 || got["ops"][1].String() != "192.168.1.5/32" || len(got["unit-a/teller"]) != 1 {
+		t.Fatalf("unexpected networks: %v", got)
+	}
+	for _, bad := range []string{"ops", "ops=", "=10.0.0.0/8", "ops=10.0.0.0/8|nope"} {
+		if _, err := ParseKeyNetworks(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+
+	p := Principal{Subject: "ops", Networks: got["ops"]}
+	if !p.AllowsAddr(netip.MustParseAddr("10.9.9.9")) || p.AllowsAddr(netip.MustParseAddr("192.168.1.6")) {
+		t.Fatalf("unexpected AllowsAddr for %+v", p)
+	}
+	if !(Principal{}).AllowsAddr(netip.MustParseAddr("203.0.113.1")) {
+		t.Fatal("expected a principal without networks to be allowed anywhere")
+	}
+}
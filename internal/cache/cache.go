@@ -0,0 +1,85 @@
+// Package cache defines the key-value cache used for hot reads, with an
+// in-process implementation. A Redis implementation lives in cache/redis.
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cache stores byte values with a time to live. Implementations must be safe
+// for concurrent use. Callers treat errors as misses: the cache only ever
+// speeds up reads and never holds the only copy of a value.
+type Cache interface {
+	// Get returns the value of key, with ok false if it is absent or expired.
+	Get(ctx context.Context, key string) (val []byte, ok bool, err error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Close() error
+}
+
+type memoryEntry struct {
+	val     []byte
+	expires time.Time
+}
+
+// Memory is a Cache local to the process. It suits single-instance
+// deployments; with several instances, an entry is only invalidated on the
+// instance that made the change, so reads elsewhere may be stale for up to the
+// TTL.
+type Memory struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+var _ Cache = (*Memory)(nil)
+
+// NewMemory creates an empty Memory cache.
+func NewMemory() *Memory {
+	return &Memory{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// Get implements Cache.
+func (m *Memory) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !m.now().Before(e.expires) {
+		delete(m.entries, key)
+		return nil, false, nil
+	}
+	return e.val, true, nil
+}
+
+// Set implements Cache. Expired entries are swept on each Set, so the cache
+// does not grow beyond the keys set within one TTL.
+func (m *Memory) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for k, e := range m.entries {
+		if !now.Before(e.expires) {
+			delete(m.entries, k)
+		}
+	}
+	m.entries[key] = memoryEntry{val: val, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements Cache.
+func (m *Memory) Delete(_ context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range keys {
+		delete(m.entries, k)
+	}
+	return nil
+}
+
+// Close implements Cache.
+func (m *Memory) Close() error { return nil }
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Fatal("expected a miss on an empty cache")
+	}
+	_ = m.Set(ctx, "a", []byte("1"), time.Second)
+	_ = m.Set(ctx, "b", []byte("2"), time.Minute)
+	if v, ok, _ := m.Get(ctx, "a"); !ok || string(v) != "1" {
+		t.Fatalf("Get(a) = %q, %v; want 1, true", v, ok)
+	}
+
+	now = now.Add(time.Second)
+	if _, ok, _ := m.Get(ctx, "a"); ok {
+		t.Fatal("expected a to have expired")
+	}
+	if _, ok, _ := m.Get(ctx, "b"); !ok {
+		t.Fatal("expected b to still be cached")
+	}
+
+	_ = m.Delete(ctx, "b", "missing")
+	if _, ok, _ := m.Get(ctx, "b"); ok {
+		t.Fatal("expected b to be deleted")
+	}
+}
+
+func TestMemorySweepsExpiredEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(0, 0)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+
+	_ = m.Set(ctx, "a", []byte("1"), time.Second)
+	now = now.Add(time.Second)
+	_ = m.Set(ctx, "b", []byte("2"), time.Second)
+	if len(m.entries) != 1 {
+		t.Fatalf("expected the expired entry to be swept, have %d entries", len(m.entries))
+	}
+}
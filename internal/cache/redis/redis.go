@@ -0,0 +1,84 @@
+// Package redis implements cache.Cache on a Redis server, shared by all
+// instances of the service.
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/you/internal-transfers/internal/cache"
+)
+
+// Cache stores entries as Redis strings under an optional key prefix.
+type Cache struct {
+	c      *goredis.Client
+	prefix string
+}
+
+var _ cache.Cache = (*Cache)(nil)
+
+// New connects to the Redis server at url (redis://[user:pass@]host:port/db).
+func New(ctx context.Context, url, keyPrefix string) (*Cache, error) {
+	opts, err := goredis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	c := goredis.NewClient(opts)
+	if err := c.Ping(ctx).Err(); err != nil {
+		_ = c.Close()
+		return nil, fmt.Errorf("redis ping: %w", err)
+	}
+	return &Cache{c: c, prefix: keyPrefix}, nil
+}
+
+// Get implements cache.Cache.
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	val, err := c.c.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get: %w", err)
+	}
+	return val, true, nil
+}
+
+// Set implements cache.Cache.
+func (c *Cache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := c.c.Set(ctx, c.prefix+key, val, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set: %w", err)
+	}
+	return nil
+}
+
+// Delete implements cache.Cache.
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	full := make([]string, len(keys))
+	for i, k := range keys {
+		full[i] = c.prefix + k
+	}
+	if err := c.c.Del(ctx, full...).Err(); err != nil {
+		return fmt.Errorf("redis del: %w", err)
+	}
+	return nil
+}
+
+// Ping checks that the Redis server answers.
+func (c *Cache) Ping(ctx context.Context) error {
+	if err := c.c.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("redis ping: %w", err)
+	}
+	return nil
+}
+
+// Close implements cache.Cache.
+func (c *Cache) Close() error {
+	return c.c.Close()
+}
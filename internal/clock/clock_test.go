@@ -0,0 +1,37 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+// TestFake tests that a Fake clock only moves when set or advanced
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	c := NewFake(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Fatalf("expected %v, got %v", start, got)
+	}
+
+	want := start.Add(90 * time.Minute)
+	if got := c.Advance(90 * time.Minute); !got.Equal(want) {
+		t.Errorf("Advance: expected %v, got %v", want, got)
+	}
+	if got := c.Now(); !got.Equal(want) {
+		t.Errorf("expected %v after Advance, got %v", want, got)
+	}
+
+	c.Set(start)
+	if got := c.Now(); !got.Equal(start) {
+		t.Errorf("expected %v after Set, got %v", start, got)
+	}
+}
+
+// TestReal tests that the real clock follows the system time
+func TestReal(t *testing.T) {
+	before := time.Now()
+	got := Real{}.Now()
+	if got.Before(before) || got.After(time.Now()) {
+		t.Errorf("expected a time between %v and now, got %v", before, got)
+	}
+}
@@ -0,0 +1,161 @@
+// Package events defines the domain events written to the transactional outbox
+// and the Publisher that relays them to a message broker.
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Event types
+const (
+	AccountCreated    = "account.created"
+	TransferCompleted = "transfer.completed"
+	TransferFailed    = "transfer.failed"
+	BalanceAdjusted   = "balance.adjusted"
+	AlertTriggered    = "alert.triggered"
+	AlertResolved     = "alert.resolved"
+	AccountErased     = "account.erased"
+)
+
+// Event is an entry of the outbox. It is written in the same DB transaction as
+// the change it describes, so it exists if and only if that change committed.
+type Event struct {
+	ID        int64 // unique and increasing; consumers deduplicate redeliveries on it
+	Type      string
+	Key       string // account the event is about; events with the same key keep their order
+	Payload   []byte // JSON
+	CreatedAt time.Time
+}
+
+// Publisher delivers events to a message broker.
+type Publisher interface {
+	// Publish sends evs in order and returns once the broker has acknowledged
+	// all of them. After an error, any of evs may have to be sent again.
+	Publish(ctx context.Context, evs []Event) error
+	Close() error
+}
+
+// permanentError marks an error of Publish that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err, the error of publishing a single event, to tell the
+// relay that the event can never be delivered, e.g. because the broker
+// rejects its size, so that it is dead-lettered rather than retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked by Permanent.
+func IsPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// Fanout returns a Publisher delivering events to each of pubs in turn. An
+// event is only acknowledged once all of them have it, so a failure of one
+// sends it again to the others, which deduplicate it on Event.ID.
+func Fanout(pubs ...Publisher) Publisher {
+	if len(pubs) == 1 {
+		return pubs[0]
+	}
+	return fanout(pubs)
+}
+
+type fanout []Publisher
+
+func (f fanout) Publish(ctx context.Context, evs []Event) error {
+	for _, p := range f {
+		if err := p.Publish(ctx, evs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping checks those of the publishers that can check their connection.
+func (f fanout) Ping(ctx context.Context) error {
+	for _, p := range f {
+		if p, ok := p.(interface{ Ping(context.Context) error }); ok {
+			if err := p.Ping(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (f fanout) Close() error {
+	var errs []error
+	for _, p := range f {
+		errs = append(errs, p.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// AccountCreatedPayload is the payload of AccountCreated events
+type AccountCreatedPayload struct {
+	AccountID       int64  `json:"account_id"`
+	ExternalID      string `json:"external_id,omitempty"`
+	InitialBalance  string `json:"initial_balance"`
+	Currency        string `json:"currency"`
+	Tenant          string `json:"tenant_id,omitempty"`
+	OwnerID         string `json:"owner_id,omitempty"`
+	ParentAccountID int64  `json:"parent_account_id,omitempty"` // set on wallets
+	Wallet          string `json:"wallet,omitempty"`
+}
+
+// TransferPayload is the payload of TransferCompleted and TransferFailed events
+type TransferPayload struct {
+	ID                   string `json:"id"`
+	TransactionID        int64  `json:"transaction_id"`
+	SourceAccountID      int64  `json:"source_account_id"`
+	DestinationAccountID int64  `json:"destination_account_id"`
+	Amount               string `json:"amount"`
+	Currency             string `json:"currency"`
+	ReversalOf           int64  `json:"reversal_of,omitempty"`
+	FeeOf                int64  `json:"fee_of,omitempty"`
+	Reference            string `json:"reference,omitempty"`
+	PurposeCode          string `json:"purpose_code,omitempty"`
+	Error                string `json:"error,omitempty"`
+}
+
+// AlertPayload is the payload of AlertTriggered and AlertResolved events.
+// Value is the available balance or transfer amount that raised the alert.
+type AlertPayload struct {
+	AlertID       int64  `json:"alert_id"`
+	AccountID     int64  `json:"account_id"`
+	Kind          string `json:"kind"`
+	Threshold     string `json:"threshold"`
+	Value         string `json:"value"`
+	TransactionID int64  `json:"transaction_id"`
+}
+
+// AdjustmentPayload is the payload of BalanceAdjusted events. Amount is
+// positive for a credit and negative for a debit.
+type AdjustmentPayload struct {
+	ID            string `json:"id"`
+	TransactionID int64  `json:"transaction_id"`
+	AccountID     int64  `json:"account_id"`
+	Amount        string `json:"amount"`
+	Currency      string `json:"currency"`
+	Reason        string `json:"reason"`
+	AdjustedBy    string `json:"adjusted_by,omitempty"`
+}
+
+// AccountErasedPayload is the payload of AccountErased events. Consumers
+// keeping copies of the account's personal data should erase the fields too.
+type AccountErasedPayload struct {
+	ErasureID int64    `json:"erasure_id"`
+	AccountID int64    `json:"account_id"`
+	Fields    []string `json:"fields"`
+}
@@ -0,0 +1,88 @@
+// Package kafka publishes outbox events to a Kafka topic.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	kafkago "github.com/segmentio/kafka-go"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// Publisher writes events to a single topic, keyed by Event.Key so that the
+// events of an account land on the same partition. The event id and type are
+// sent as the event-id and event-type headers.
+type Publisher struct {
+	w       *kafkago.Writer
+	brokers []string
+}
+
+var _ events.Publisher = (*Publisher)(nil)
+
+// NewPublisher creates a Publisher writing to topic on brokers.
+func NewPublisher(brokers []string, topic string) *Publisher {
+	return &Publisher{w: &kafkago.Writer{
+		Addr:         kafkago.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafkago.Hash{},
+		RequiredAcks: kafkago.RequireAll,
+	}, brokers: brokers}
+}
+
+// Publish implements events.Publisher.
+func (p *Publisher) Publish(ctx context.Context, evs []events.Event) error {
+	msgs := make([]kafkago.Message, len(evs))
+	for i, e := range evs {
+		msgs[i] = kafkago.Message{
+			Key:   []byte(e.Key),
+			Value: e.Payload,
+			Time:  e.CreatedAt,
+			Headers: []kafkago.Header{
+				{Key: "event-id", Value: []byte(strconv.FormatInt(e.ID, 10))},
+				{Key: "event-type", Value: []byte(e.Type)},
+			},
+		}
+	}
+	if err := p.w.WriteMessages(ctx, msgs...); err != nil {
+		err = fmt.Errorf("kafka write: %w", err)
+		if len(evs) == 1 && rejected(err) {
+			return events.Permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// rejected reports whether the brokers refused a message for good, as too
+// large or malformed, rather than failed to take it for now.
+func rejected(err error) bool {
+	var writeErrs kafkago.WriteErrors
+	if errors.As(err, &writeErrs) {
+		for _, e := range writeErrs {
+			if e != nil {
+				err = e
+			}
+		}
+	}
+	return errors.Is(err, kafkago.MessageSizeTooLarge) || errors.Is(err, kafkago.InvalidRecord)
+}
+
+// Ping checks that one of the brokers accepts connections.
+func (p *Publisher) Ping(ctx context.Context) error {
+	err := errors.New("no brokers")
+	for _, broker := range p.brokers {
+		var conn *kafkago.Conn
+		if conn, err = kafkago.DialContext(ctx, "tcp", broker); err == nil {
+			return conn.Close()
+		}
+	}
+	return fmt.Errorf("kafka dial: %w", err)
+}
+
+// Close flushes pending writes and closes the connections.
+func (p *Publisher) Close() error {
+	return p.w.Close()
+}
@@ -0,0 +1,71 @@
+// Package nats publishes outbox events to NATS JetStream.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	natsgo "github.com/nats-io/nats.go"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// Publisher publishes each event to the subject <prefix>.<event type>, which
+// must be bound to a JetStream stream. The event id is sent as Nats-Msg-Id, so
+// the stream drops redeliveries within its duplicate window.
+type Publisher struct {
+	nc     *natsgo.Conn
+	js     natsgo.JetStreamContext
+	prefix string
+}
+
+var _ events.Publisher = (*Publisher)(nil)
+
+// NewPublisher connects to the NATS server at url.
+func NewPublisher(url, subjectPrefix string) (*Publisher, error) {
+	nc, err := natsgo.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("nats connect: %w", err)
+	}
+	js, err := nc.JetStream()
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("nats jetstream: %w", err)
+	}
+	return &Publisher{nc: nc, js: js, prefix: subjectPrefix}, nil
+}
+
+// Publish implements events.Publisher.
+func (p *Publisher) Publish(ctx context.Context, evs []events.Event) error {
+	for _, e := range evs {
+		msg := natsgo.NewMsg(p.prefix + "." + e.Type)
+		msg.Data = e.Payload
+		msg.Header.Set(natsgo.MsgIdHdr, strconv.FormatInt(e.ID, 10))
+		msg.Header.Set("Event-Key", e.Key)
+		if _, err := p.js.PublishMsg(msg, natsgo.Context(ctx)); err != nil {
+			err = fmt.Errorf("nats publish event %d: %w", e.ID, err)
+			// The server refuses payloads over its limit whatever the retries
+			if errors.Is(err, natsgo.ErrMaxPayload) {
+				return events.Permanent(err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Ping checks that the connection to the NATS server is up. The client
+// reconnects on its own, so a connection that is down may come back.
+func (p *Publisher) Ping(ctx context.Context) error {
+	if status := p.nc.Status(); status != natsgo.CONNECTED {
+		return fmt.Errorf("nats connection %s", status)
+	}
+	return nil
+}
+
+// Close drains and closes the connection.
+func (p *Publisher) Close() error {
+	return p.nc.Drain()
+}
@@ -0,0 +1,112 @@
+// Package webhook delivers outbox events to an HTTP endpoint.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// DefaultTimeout bounds each delivery unless the Publisher is given a client
+// of its own.
+const DefaultTimeout = 10 * time.Second
+
+// Headers of a delivery
+const (
+	HeaderEventID   = "Event-Id"
+	HeaderEventType = "Event-Type"
+	HeaderSignature = "Webhook-Signature" // sha256=<hex HMAC-SHA256 of the body>
+)
+
+// Publisher POSTs each event to a URL, one request per event. The event id is
+// sent as Event-Id for receivers to deduplicate redeliveries, and the body is
+// signed with HMAC-SHA256 when a secret is set.
+type Publisher struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+var _ events.Publisher = (*Publisher)(nil)
+
+// NewPublisher creates a Publisher delivering to url, signing with secret
+// unless it is empty.
+func NewPublisher(url, secret string) *Publisher {
+	return &Publisher{url: url, secret: []byte(secret), client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// Delivery is the body of a delivery
+type Delivery struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Key       string          `json:"key"`
+	CreatedAt time.Time       `json:"created_at"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Publish implements events.Publisher. A 2xx response acknowledges an event;
+// other 4xx responses than 408 and 429 reject it for good.
+func (p *Publisher) Publish(ctx context.Context, evs []events.Event) error {
+	for _, e := range evs {
+		if err := p.deliver(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Publisher) deliver(ctx context.Context, e events.Event) error {
+	body, err := json.Marshal(Delivery{ID: e.ID, Type: e.Type, Key: e.Key, CreatedAt: e.CreatedAt, Payload: e.Payload})
+	if err != nil {
+		return events.Permanent(fmt.Errorf("webhook marshal event %d: %w", e.ID, err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, strconv.FormatInt(e.ID, 10))
+	req.Header.Set(HeaderEventType, e.Type)
+	if len(p.secret) > 0 {
+		req.Header.Set(HeaderSignature, "sha256="+Sign(p.secret, body))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook deliver event %d: %w", e.ID, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	switch code := resp.StatusCode; {
+	case code >= 200 && code < 300:
+		return nil
+	case code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests:
+		return events.Permanent(fmt.Errorf("webhook deliver event %d: status %d", e.ID, code))
+	default:
+		return fmt.Errorf("webhook deliver event %d: status %d", e.ID, code)
+	}
+}
+
+// Sign returns the hex HMAC-SHA256 of body under secret, which receivers
+// compare to the Webhook-Signature header.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Close implements events.Publisher.
+func (p *Publisher) Close() error {
+	p.client.CloseIdleConnections()
+	return nil
+}
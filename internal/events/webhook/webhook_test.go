@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// TestPublisher tests that events are delivered signed, and that client
+// errors reject them for good while server errors are retried
+func TestPublisher(t *testing.T) {
+	status := http.StatusNoContent
+	var got []Delivery
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if sig := r.Header.Get(HeaderSignature); sig != "sha256="+Sign([]byte("s3cret"), body) {
+			t.Errorf("unexpected signature %q", sig)
+		}
+		var d Delivery
+		if err := json.Unmarshal(body, &d); err != nil {
+			t.Errorf("unmarshal delivery: %v", err)
+		}
+		if r.Header.Get(HeaderEventID) != "7" || r.Header.Get(HeaderEventType) != events.TransferCompleted {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		got = append(got, d)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	p := NewPublisher(srv.URL, "s3cret")
+	defer p.Close()
+	ev := events.Event{ID: 7, Type: events.TransferCompleted, Key: "1", Payload: []byte(`{"amount":"4"}`)}
+
+	if err := p.Publish(context.Background(), []events.Event{ev}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != 7 || string(got[0].Payload) != `{"amount":"4"}` {
+		t.Fatalf("unexpected deliveries %+v", got)
+	}
+
+	status = http.StatusServiceUnavailable
+	if err := p.Publish(context.Background(), []events.Event{ev}); err == nil || events.IsPermanent(err) {
+		t.Fatalf("expected a transient error, got %v", err)
+	}
+	status = http.StatusBadRequest
+	if err := p.Publish(context.Background(), []events.Event{ev}); !events.IsPermanent(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+}
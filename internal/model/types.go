@@ -2,7 +2,12 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -13,11 +18,83 @@ type DecimalString struct {
 	decimal.Decimal
 }
 
-// UnmarshalJSON parses decimal from JSON string or number (prefers string).
+// NumberMode says how DecimalString decodes amounts sent as JSON numbers
+// rather than strings.
+type NumberMode int
+
+const (
+	// NumbersAsFloat parses numbers as float64, losing the digits beyond its precision
+	NumbersAsFloat NumberMode = iota
+	// NumbersExact parses numbers digit for digit, like strings
+	NumbersExact
+	// NumbersRejected rejects numbers with ErrNumericAmount
+	NumbersRejected
+)
+
+// DecimalNumbers is how DecimalString decodes JSON numbers. The server sets it
+// from its configuration at startup.
+var DecimalNumbers = NumbersAsFloat
+
+// ErrNumericAmount is returned when decoding a JSON number into a DecimalString
+// under NumbersRejected.
+var ErrNumericAmount = errors.New(`amounts must be JSON strings, e.g. "10.50"`)
+
+// Decimal values are refused beyond these bounds, before any arithmetic or
+// comparison, whose cost grows with the exponent: "1e200000000" is short to
+// send but long to compare.
+const (
+	MaxDecimalDigits   = 40
+	MaxDecimalExponent = 40
+	maxDecimalLen      = 100 // characters, checked before parsing
+)
+
+// ErrDecimalOutOfRange is returned for decimals with more than
+// MaxDecimalDigits digits or an exponent beyond MaxDecimalExponent.
+var ErrDecimalOutOfRange = fmt.Errorf("decimals must have at most %d digits and an exponent within ±%d", MaxDecimalDigits, MaxDecimalExponent)
+
+// ParseDecimal parses s like decimal.NewFromString, refusing values out of
+// range with ErrDecimalOutOfRange.
+func ParseDecimal(s string) (decimal.Decimal, error) {
+	if len(s) > maxDecimalLen {
+		return decimal.Decimal{}, ErrDecimalOutOfRange
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	return d, checkDecimalRange(d)
+}
+
+// checkDecimalRange returns ErrDecimalOutOfRange if d is out of range.
+func checkDecimalRange(d decimal.Decimal) error {
+	if e := d.Exponent(); e > MaxDecimalExponent || e < -MaxDecimalExponent || d.NumDigits() > MaxDecimalDigits {
+		return ErrDecimalOutOfRange
+	}
+	return nil
+}
+
+// ParseNumberMode parses a NumberMode from "float", "exact" or "reject".
+func ParseNumberMode(s string) (NumberMode, error) {
+	switch s {
+	case "float":
+		return NumbersAsFloat, nil
+	case "exact":
+		return NumbersExact, nil
+	case "reject":
+		return NumbersRejected, nil
+	}
+	return 0, fmt.Errorf("must be float, exact or reject, got %q", s)
+}
+
+// UnmarshalJSON parses decimal from JSON string or, depending on
+// DecimalNumbers, number.
 func (d *DecimalString) UnmarshalJSON(b []byte) error {
 	var s string
 	if err := json.Unmarshal(b, &s); err == nil {
-		dec, err := decimal.NewFromString(s)
+		dec, err := ParseDecimal(s)
+		if errors.Is(err, ErrDecimalOutOfRange) {
+			return err
+		}
 		if err != nil {
 			return fmt.Errorf("invalid decimal string: %w", err)
 		}
@@ -25,14 +102,44 @@ func (d *DecimalString) UnmarshalJSON(b []byte) error {
 		return nil
 	}
 
-	// Fallback to float if string parsing fails
-	var f float64
-	if err := json.Unmarshal(b, &f); err == nil {
-		d.Decimal = decimal.NewFromFloat(f)
-		return nil
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("invalid decimal value")
+	}
+	switch DecimalNumbers {
+	case NumbersRejected:
+		return ErrNumericAmount
+	case NumbersExact:
+		dec, err := ParseDecimal(n.String())
+		if errors.Is(err, ErrDecimalOutOfRange) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("invalid decimal value")
+		}
+		d.Decimal = dec
+	default:
+		f, err := n.Float64()
+		if err != nil {
+			return fmt.Errorf("invalid decimal value")
+		}
+		dec := decimal.NewFromFloat(f)
+		if err := checkDecimalRange(dec); err != nil {
+			return err
+		}
+		d.Decimal = dec
 	}
+	return nil
+}
 
-	return fmt.Errorf("invalid decimal value")
+// Scale returns the number of decimal places needed to write d exactly, so
+// that "1.50" has a scale of 1.
+func (d DecimalString) Scale() int32 {
+	scale := -d.Exponent()
+	for scale > 0 && d.Truncate(scale-1).Equal(d.Decimal) {
+		scale--
+	}
+	return max(scale, 0)
 }
 
 // MarshalJSON outputs decimal as JSON string to preserve precision.
@@ -40,21 +147,1216 @@ func (d DecimalString) MarshalJSON() ([]byte, error) {
 	return json.Marshal(d.String())
 }
 
+// ErrorCode is the machine-readable code of an ErrorResponse. Codes are
+// stable; clients should match on them rather than on messages. GET /errors
+// lists them with ErrorCodes.
+type ErrorCode string
+
+// Error codes
+const (
+	ErrCodeInvalidJSON            ErrorCode = "INVALID_JSON"
+	ErrCodeBodyTooLarge           ErrorCode = "REQUEST_BODY_TOO_LARGE"
+	ErrCodeUnsupportedEncoding    ErrorCode = "UNSUPPORTED_CONTENT_ENCODING"
+	ErrCodeValidationFailed       ErrorCode = "VALIDATION_FAILED"
+	ErrCodeAccountNotFound        ErrorCode = "ACCOUNT_NOT_FOUND"
+	ErrCodeDuplicateAccount       ErrorCode = "DUPLICATE_ACCOUNT"
+	ErrCodeAliasTaken             ErrorCode = "ALIAS_TAKEN"
+	ErrCodeAliasNotFound          ErrorCode = "ALIAS_NOT_FOUND"
+	ErrCodeAlertNotFound          ErrorCode = "ALERT_NOT_FOUND"
+	ErrCodeAlertResolved          ErrorCode = "ALERT_ALREADY_RESOLVED"
+	ErrCodeInsufficientFunds      ErrorCode = "INSUFFICIENT_FUNDS"
+	ErrCodeNotFound               ErrorCode = "NOT_FOUND"
+	ErrCodeMethodNotAllowed       ErrorCode = "METHOD_NOT_ALLOWED"
+	ErrCodeServiceUnavailable     ErrorCode = "SERVICE_UNAVAILABLE"
+	ErrCodeInternal               ErrorCode = "INTERNAL_ERROR"
+	ErrCodeUnauthenticated        ErrorCode = "UNAUTHENTICATED"
+	ErrCodeForbidden              ErrorCode = "FORBIDDEN"
+	ErrCodeNetworkNotAllowed      ErrorCode = "NETWORK_NOT_ALLOWED"
+	ErrCodeAPIKeyNotFound         ErrorCode = "API_KEY_NOT_FOUND"
+	ErrCodeRateLimited            ErrorCode = "RATE_LIMITED"
+	ErrCodeAccountInactive        ErrorCode = "ACCOUNT_INACTIVE"
+	ErrCodeInvalidTransition      ErrorCode = "INVALID_STATUS_TRANSITION"
+	ErrCodeAccountNotEmpty        ErrorCode = "ACCOUNT_NOT_EMPTY"
+	ErrCodeAccountNotClosed       ErrorCode = "ACCOUNT_NOT_CLOSED"
+	ErrCodeCurrencyMismatch       ErrorCode = "CURRENCY_MISMATCH"
+	ErrCodeCounterpartyNotAllowed ErrorCode = "COUNTERPARTY_NOT_ALLOWED"
+	ErrCodeKYCRestricted          ErrorCode = "KYC_RESTRICTED"
+	ErrCodeHoldNotFound           ErrorCode = "HOLD_NOT_FOUND"
+	ErrCodeHoldNotPending         ErrorCode = "HOLD_NOT_PENDING"
+	ErrCodeScheduledNotFound      ErrorCode = "SCHEDULED_TRANSFER_NOT_FOUND"
+	ErrCodeJobNotFound            ErrorCode = "JOB_NOT_FOUND"
+	ErrCodeTransactionNotFound    ErrorCode = "TRANSACTION_NOT_FOUND"
+	ErrCodeAlreadyReversed        ErrorCode = "TRANSACTION_ALREADY_REVERSED"
+	ErrCodeNotReversible          ErrorCode = "TRANSACTION_NOT_REVERSIBLE"
+	ErrCodeLimitExceeded          ErrorCode = "TRANSFER_LIMIT_EXCEEDED"
+	ErrCodeReconciliationNotFound ErrorCode = "RECONCILIATION_NOT_FOUND"
+	ErrCodeStatementNotFound      ErrorCode = "STATEMENT_NOT_FOUND"
+	ErrCodeCrossTenant            ErrorCode = "CROSS_TENANT_TRANSFER"
+	ErrCodeTransferBlocked        ErrorCode = "TRANSFER_BLOCKED"
+	ErrCodeApprovalRequired       ErrorCode = "APPROVAL_REQUIRED"
+	ErrCodeTransferRejected       ErrorCode = "TRANSFER_REJECTED"
+	ErrCodeRuleNotFound           ErrorCode = "RULE_NOT_FOUND"
+	ErrCodeFeePolicyNotFound      ErrorCode = "FEE_POLICY_NOT_FOUND"
+	ErrCodeFeeAccountUnavailable  ErrorCode = "FEE_ACCOUNT_UNAVAILABLE"
+	ErrCodeNotClearingAccount     ErrorCode = "NOT_CLEARING_ACCOUNT"
+	ErrCodeSweepRuleNotFound      ErrorCode = "SWEEP_RULE_NOT_FOUND"
+	ErrCodeSweepRuleExists        ErrorCode = "SWEEP_RULE_EXISTS"
+	ErrCodeReviewNotFound         ErrorCode = "REVIEW_NOT_FOUND"
+	ErrCodeReviewResolved         ErrorCode = "REVIEW_ALREADY_RESOLVED"
+	ErrCodeNotSupported           ErrorCode = "NOT_SUPPORTED"
+	ErrCodeTimeout                ErrorCode = "TIMEOUT"
+	ErrCodeTooManySubscriptions   ErrorCode = "TOO_MANY_SUBSCRIPTIONS"
+	ErrCodeHistoryArchived        ErrorCode = "BALANCE_HISTORY_ARCHIVED"
+)
+
+// ErrorCodeInfo documents an error code in the catalog served by GET /errors
+type ErrorCodeInfo struct {
+	Code        ErrorCode `json:"code"`
+	Status      int       `json:"status,omitempty"` // HTTP status answered with; none for WebSocket-only codes
+	Description string    `json:"description"`
+}
+
+// errorCatalog lists every error code. TestErrorCodes keeps it in sync with
+// the constants and the OpenAPI document.
+var errorCatalog = []ErrorCodeInfo{
+	{ErrCodeInvalidJSON, 400, "The request body is empty or not valid JSON, has unknown or mistyped fields, or holds an amount as a JSON number"},
+	{ErrCodeBodyTooLarge, 413, "The request body exceeds the size limit of the route"},
+	{ErrCodeUnsupportedEncoding, 415, "The Content-Encoding of the request body is not supported"},
+	{ErrCodeValidationFailed, 400, "A field or query parameter is missing or invalid; details name it when known"},
+	{ErrCodeAccountNotFound, 404, "An account does not exist or is outside the caller's scope; for transfers, details give its account_id and field"},
+	{ErrCodeDuplicateAccount, 409, "An account with this id or external id already exists"},
+	{ErrCodeAliasTaken, 409, "The alias is already registered for another account, or is an account's external id"},
+	{ErrCodeAliasNotFound, 404, "The account has no such alias"},
+	{ErrCodeAlertNotFound, 404, "The account has no such alert"},
+	{ErrCodeAlertResolved, 409, "The alert was already resolved"},
+	{ErrCodeInsufficientFunds, 409, "The source account balance does not cover the amount"},
+	{ErrCodeNotFound, 404, "No route matches the request path"},
+	{ErrCodeMethodNotAllowed, 405, "The route does not accept the request method"},
+	{ErrCodeServiceUnavailable, 503, "Too many transfers are in progress, live updates are unavailable, or the database is unreachable; retry later"},
+	{ErrCodeInternal, 500, "An unexpected error occurred"},
+	{ErrCodeUnauthenticated, 401, "The API key is missing, unknown or revoked"},
+	{ErrCodeForbidden, 403, "The API key's role or scope does not allow the request"},
+	{ErrCodeNetworkNotAllowed, 403, "The request comes from a network the deployment or the caller's credentials do not allow"},
+	{ErrCodeAPIKeyNotFound, 404, "The API key does not exist"},
+	{ErrCodeRateLimited, 429, "Too many requests; retry after the Retry-After header"},
+	{ErrCodeAccountInactive, 422, "The source or destination account is frozen or closed"},
+	{ErrCodeInvalidTransition, 409, "The account cannot move to the requested status from its current one"},
+	{ErrCodeAccountNotEmpty, 409, "The account to delete has pending holds, or a balance and no sweep_to account to move it to"},
+	{ErrCodeAccountNotClosed, 409, "Only closed accounts can have their personal data erased"},
+	{ErrCodeCurrencyMismatch, 422, "The source and destination accounts have different currencies"},
+	{ErrCodeCounterpartyNotAllowed, 422, "The counterparty allowlist of the source or destination account does not include the other account"},
+	{ErrCodeKYCRestricted, 422, "The source or destination account is KYC blocked, or the source is unverified and unverified accounts may only receive"},
+	{ErrCodeHoldNotFound, 404, "The hold does not exist"},
+	{ErrCodeHoldNotPending, 409, "The hold was already captured, released or expired"},
+	{ErrCodeScheduledNotFound, 404, "The scheduled transfer does not exist"},
+	{ErrCodeJobNotFound, 404, "The transfer job does not exist"},
+	{ErrCodeTransactionNotFound, 404, "The transaction does not exist"},
+	{ErrCodeAlreadyReversed, 409, "The transaction was already reversed"},
+	{ErrCodeNotReversible, 409, "Only succeeded transfers can be reversed"},
+	{ErrCodeLimitExceeded, 422, "The transfer exceeds a limit of the source account; details give the limit"},
+	{ErrCodeReconciliationNotFound, 404, "No reconciliation has run yet"},
+	{ErrCodeStatementNotFound, 404, "No statement exists for the account and period"},
+	{ErrCodeCrossTenant, 403, "The source and destination accounts belong to different tenants"},
+	{ErrCodeTransferBlocked, 422, "A fraud rule blocked the transfer"},
+	{ErrCodeApprovalRequired, 422, "A rule requires the batch or split leg to be approved; submit it as a single transfer to have it await approval"},
+	{ErrCodeTransferRejected, 422, "A before-transfer hook of the deployment rejected the transfer"},
+	{ErrCodeRuleNotFound, 404, "The fraud rule does not exist"},
+	{ErrCodeFeePolicyNotFound, 404, "The fee policy does not exist"},
+	{ErrCodeFeeAccountUnavailable, 422, "The fee account of the fee policy charging the transfer is not active or not in the transfer's currency"},
+	{ErrCodeNotClearingAccount, 422, "An account of the clearing transfer, or the account to withdraw from clearing, is not designated for clearing"},
+	{ErrCodeSweepRuleNotFound, 404, "The sweep rule does not exist"},
+	{ErrCodeSweepRuleExists, 409, "The account already has a sweep rule"},
+	{ErrCodeReviewNotFound, 404, "No transfer awaits review with this id"},
+	{ErrCodeReviewResolved, 409, "The review was already approved or rejected"},
+	{ErrCodeNotSupported, 501, "The deployment's store does not support the feature"},
+	{ErrCodeTimeout, 504, "The request ran out of time; it may be retried with a longer deadline"},
+	{ErrCodeTooManySubscriptions, 0, "A WebSocket connection subscribed to more accounts than allowed"},
+	{ErrCodeHistoryArchived, 410, "The transactions since the requested time were archived, so the balance at that time cannot be computed"},
+}
+
+// ErrorCodes returns the catalog of error codes.
+func ErrorCodes() []ErrorCodeInfo {
+	return slices.Clone(errorCatalog)
+}
+
+// ErrorCatalogResponse is the body of GET /errors
+type ErrorCatalogResponse struct {
+	Errors []ErrorCodeInfo `json:"errors"`
+}
+
+// JSON error body returned by every handler
+type ErrorResponse struct {
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// VersionResponse describes the running build
+type VersionResponse struct {
+	Version   string   `json:"version"`
+	GitSHA    string   `json:"git_sha"`
+	BuildTime string   `json:"build_time,omitempty"`
+	GoVersion string   `json:"go_version"`
+	Features  []string `json:"features"` // enabled feature flags
+}
+
+// RuntimeStatsResponse reports the goroutines, heap and GC of the process
+type RuntimeStatsResponse struct {
+	Goroutines     int        `json:"goroutines"`
+	GOMAXPROCS     int        `json:"gomaxprocs"`
+	HeapAllocBytes uint64     `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64     `json:"heap_inuse_bytes"`
+	HeapObjects    uint64     `json:"heap_objects"`
+	SysBytes       uint64     `json:"sys_bytes"` // obtained from the OS
+	NumGC          uint32     `json:"num_gc"`
+	GCPauseTotalMS float64    `json:"gc_pause_total_ms"`
+	LastGC         *time.Time `json:"last_gc,omitempty"`
+	LastGCPauseMS  float64    `json:"last_gc_pause_ms,omitempty"`
+}
+
+// Readiness of the service and of its components
+const (
+	ReadinessReady    = "ready"     // every component is up
+	ReadinessDegraded = "degraded"  // an optional component is down; requests are still served
+	ReadinessNotReady = "not_ready" // a required component is down
+
+	ComponentUp   = "up"
+	ComponentDown = "down"
+)
+
+// ReadinessResponse is returned by the readiness probe
+type ReadinessResponse struct {
+	Status     string            `json:"status"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// ComponentStatus is the result of the readiness check of one dependency
+type ComponentStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Required  bool    `json:"required"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// AccountID identifies an account in a request, either by its numeric
+// account_id or by its external_id, and optionally one of its wallets,
+// written after a slash: "100/savings" or "acme/savings". It is decoded from
+// a JSON number or string; a string of digits is a numeric id, since external
+// ids never are.
+type AccountID struct {
+	ID         int64
+	ExternalID string
+	Wallet     string // the wallet of the account given by ID or ExternalID, if any
+}
+
+// ParseAccountID parses an account id from a path segment or CSV field.
+func ParseAccountID(s string) (AccountID, error) {
+	if parent, wallet, ok := strings.Cut(s, "/"); ok {
+		if !isWalletName(wallet) {
+			return AccountID{}, ErrInvalidWallet
+		}
+		id, err := ParseAccountID(parent)
+		if err != nil || id.Wallet != "" {
+			return AccountID{}, ErrInvalidExternalID
+		}
+		id.Wallet = wallet
+		return id, nil
+	}
+	if id, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return AccountID{ID: id}, nil
+	}
+	if !isExternalID(s) {
+		return AccountID{}, ErrInvalidExternalID
+	}
+	return AccountID{ExternalID: s}, nil
+}
+
+// IsZero reports whether no account is given.
+func (id AccountID) IsZero() bool {
+	return id.ID == 0 && id.ExternalID == ""
+}
+
+// String returns the id as given.
+func (id AccountID) String() string {
+	s := id.ExternalID
+	if s == "" {
+		s = strconv.FormatInt(id.ID, 10)
+	}
+	if id.Wallet != "" {
+		s += "/" + id.Wallet
+	}
+	return s
+}
+
+// UnmarshalJSON accepts a numeric id as a JSON number or string, or an
+// external id as a JSON string.
+func (id *AccountID) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid account id %s", b)
+		}
+		*id = AccountID{ID: n}
+		return nil
+	}
+	parsed, err := ParseAccountID(s)
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// MarshalJSON outputs a numeric id as a JSON number, and an external id or a
+// wallet as a string.
+func (id AccountID) MarshalJSON() ([]byte, error) {
+	if id.ExternalID != "" || id.Wallet != "" {
+		return json.Marshal(id.String())
+	}
+	return json.Marshal(id.ID)
+}
+
+// DefaultCurrency is used for accounts created without a currency
+const DefaultCurrency = "USD"
+
 // Incoming payload for POST /accounts
 type CreateAccountRequest struct {
 	AccountID      int64         `json:"account_id"`
 	InitialBalance DecimalString `json:"initial_balance"`
+	Currency       string        `json:"currency,omitempty"`
+	ExternalID     string        `json:"external_id,omitempty"` // with it, account_id may be omitted to have one assigned
+	DisplayName    string        `json:"display_name,omitempty"`
+	OwnerRef       string        `json:"owner_ref,omitempty"`
+	Tags           []string      `json:"tags,omitempty"`
+	OwnerID        string        `json:"owner_id,omitempty"` // defaults to the caller, unless an admin
+}
+
+// Outcomes of the accounts of POST /accounts/batch
+const (
+	BatchAccountCreated   = "created"
+	BatchAccountDuplicate = "duplicate"
+	BatchAccountInvalid   = "invalid"
+)
+
+// Incoming payload for POST /accounts/batch
+type BatchCreateAccountRequest struct {
+	Accounts []CreateAccountRequest `json:"accounts"`
+}
+
+// Outcome of one account of a batch, in request order. Error is set unless
+// the account was created.
+type BatchAccountResult struct {
+	Index     int            `json:"index"`
+	Status    string         `json:"status"`
+	AccountID int64          `json:"account_id,omitempty"`
+	Error     *ErrorResponse `json:"error,omitempty"`
+}
+
+// JSON returned by POST /accounts/batch
+type BatchCreateAccountResponse struct {
+	Results []BatchAccountResult `json:"results"`
+}
+
+// Incoming payload for PATCH /accounts/{id}.
+// Omitted fields are left unchanged; an empty tags list clears the tags.
+type UpdateAccountRequest struct {
+	DisplayName *string   `json:"display_name,omitempty"`
+	OwnerRef    *string   `json:"owner_ref,omitempty"`
+	Tags        *[]string `json:"tags,omitempty"`
 }
 
-// JSON returned by GET /accounts/{id}
+// JSON returned by GET /accounts/{id}, PATCH /accounts/{id} and the status change endpoints.
+// Balance is the ledger balance; AvailableBalance excludes amounts reserved by pending holds.
 type AccountResponse struct {
+	AccountID        int64           `json:"account_id"`
+	Balance          DecimalString   `json:"balance"`
+	AvailableBalance DecimalString   `json:"available_balance"`
+	Currency         string          `json:"currency"`
+	Status           string          `json:"status"`
+	KYCStatus        string          `json:"kyc_status,omitempty"` // absent on stores without KYC
+	TenantID         string          `json:"tenant_id,omitempty"`
+	OwnerID          string          `json:"owner_id,omitempty"`
+	ExternalID       string          `json:"external_id,omitempty"`
+	DisplayName      string          `json:"display_name,omitempty"`
+	OwnerRef         string          `json:"owner_ref,omitempty"`
+	Tags             []string        `json:"tags"`
+	Limits           *TransferLimits `json:"limits,omitempty"`            // only when the account overrides the defaults
+	ParentAccountID  int64           `json:"parent_account_id,omitempty"` // set on wallets
+	Wallet           string          `json:"wallet,omitempty"`
+	MinBalance       *DecimalString  `json:"min_balance,omitempty"`
+}
+
+// AccountAlias is a human-readable name of an account, as returned by
+// PUT and GET /accounts/{id}/aliases
+type AccountAlias struct {
+	Alias     string    `json:"alias"`
+	AccountID int64     `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// JSON returned by GET /accounts/{id}/aliases
+type AccountAliasList struct {
+	Aliases []AccountAlias `json:"aliases"`
+}
+
+// CreateWalletRequest is the body of POST /accounts/{id}/wallets. The wallet
+// gets the currency, tenant and owner of its parent account; account_id may be
+// omitted to have one assigned.
+type CreateWalletRequest struct {
+	Wallet         string        `json:"wallet"`
+	AccountID      int64         `json:"account_id,omitempty"`
+	InitialBalance DecimalString `json:"initial_balance"`
+	DisplayName    string        `json:"display_name,omitempty"`
+	Tags           []string      `json:"tags,omitempty"`
+}
+
+// JSON returned by GET /accounts/{id}/wallets: the account, its wallets, and
+// the balances of all of them added up
+type WalletsResponse struct {
+	AccountID        int64             `json:"account_id"`
+	Currency         string            `json:"currency"`
+	Balance          DecimalString     `json:"balance"`
+	AvailableBalance DecimalString     `json:"available_balance"`
+	Account          AccountResponse   `json:"account"`
+	Wallets          []AccountResponse `json:"wallets"`
+}
+
+// JSON returned by DELETE /accounts/{id}: the closed account and, if its
+// balance was swept to another account, the transfer that moved it
+type CloseAccountResponse struct {
+	Account AccountResponse `json:"account"`
+	Sweep   *Transaction    `json:"sweep,omitempty"`
+}
+
+// JSON returned by GET /accounts/{id}/balance: the ledger balance as of At
+type BalanceResponse struct {
 	AccountID int64         `json:"account_id"`
 	Balance   DecimalString `json:"balance"`
+	Currency  string        `json:"currency"`
+	At        time.Time     `json:"at"`
+}
+
+// SetOwnerRequest is the body of PUT /accounts/{id}/owner. An empty owner_id
+// leaves the account to admins.
+type SetOwnerRequest struct {
+	OwnerID string `json:"owner_id"`
+}
+
+// KYC statuses of an account
+const (
+	KYCUnverified = "unverified"
+	KYCVerified   = "verified"
+	KYCBlocked    = "blocked"
+)
+
+// SetKYCStatusRequest is the body of PUT /accounts/{id}/kyc.
+type SetKYCStatusRequest struct {
+	KYCStatus string `json:"kyc_status"`
+}
+
+// TransferLimits is the body of PUT /accounts/{id}/limits. A null or absent
+// field removes the account's own limit, so that the service default applies.
+type TransferLimits struct {
+	MaxTransferAmount  *DecimalString `json:"max_transfer_amount"`
+	DailyTransferLimit *DecimalString `json:"daily_transfer_limit"`
+}
+
+// SetMinBalanceRequest is the body of PUT /accounts/{id}/min-balance. A null
+// or absent min_balance removes the account's minimum balance.
+type SetMinBalanceRequest struct {
+	MinBalance *DecimalString `json:"min_balance"`
+}
+
+// SetAlertThresholdsRequest is the body of PUT /accounts/{id}/alert-thresholds.
+// A null or absent threshold removes it.
+type SetAlertThresholdsRequest struct {
+	LowBalance    *DecimalString `json:"low_balance"`
+	LargeTransfer *DecimalString `json:"large_transfer"`
+}
+
+// JSON returned by the /accounts/{id}/alert-thresholds endpoints. UpdatedAt
+// is absent if the thresholds were never set.
+type AlertThresholdsResponse struct {
+	AccountID     int64          `json:"account_id"`
+	LowBalance    *DecimalString `json:"low_balance,omitempty"`
+	LargeTransfer *DecimalString `json:"large_transfer,omitempty"`
+	UpdatedAt     *time.Time     `json:"updated_at,omitempty"`
+}
+
+// SetCounterpartiesRequest is the body of PUT /accounts/{id}/counterparties.
+// A null or absent list leaves its direction unrestricted; an empty one
+// allows no counterparty.
+type SetCounterpartiesRequest struct {
+	Outgoing []int64 `json:"outgoing"`
+	Incoming []int64 `json:"incoming"`
+}
+
+// JSON returned by the /accounts/{id}/counterparties endpoints. A null list
+// is unrestricted. UpdatedAt is absent if the allowlist was never set.
+type CounterpartiesResponse struct {
+	AccountID int64      `json:"account_id"`
+	Outgoing  []int64    `json:"outgoing"`
+	Incoming  []int64    `json:"incoming"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// An alert raised by a transfer of the account. Value is the available
+// balance (low_balance) or the transfer amount (large_transfer) that raised it.
+// TransactionPublicID is the public id of the transfer; TransactionID, its
+// deprecated serial id, is only set while the server keeps serial ids.
+type AlertResponse struct {
+	AlertID             int64         `json:"alert_id"`
+	AccountID           int64         `json:"account_id"`
+	Kind                string        `json:"kind"`
+	Status              string        `json:"status"`
+	Threshold           DecimalString `json:"threshold"`
+	Value               DecimalString `json:"value"`
+	TransactionPublicID string        `json:"transaction_public_id"`
+	TransactionID       int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	CreatedAt           time.Time     `json:"created_at"`
+	ResolvedAt          *time.Time    `json:"resolved_at,omitempty"`
+}
+
+// JSON returned by GET /accounts/{id}/alerts, newest first.
+// NextCursor is empty on the last page.
+type AlertListResponse struct {
+	Alerts     []AlertResponse `json:"alerts"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
-// Incoming payload for POST /transactions
+// JSON returned by GET /accounts.
+// NextCursor is empty on the last page.
+type AccountListResponse struct {
+	Accounts   []AccountResponse `json:"accounts"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// A single transfer, as in POST /transactions and POST /transactions/batch.
+// Reference and PurposeCode are optional and recorded on the transaction.
 type TransactionRequest struct {
+	SourceAccountID      AccountID     `json:"source_account_id"`
+	DestinationAccountID AccountID     `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Reference            string        `json:"reference,omitempty"`
+	PurposeCode          string        `json:"purpose_code,omitempty"`
+}
+
+// Adjustment types
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// Incoming payload for POST /admin/accounts/{id}/adjustments. Amount is
+// positive; Type says whether it is credited or debited.
+type AdjustmentRequest struct {
+	Type   string        `json:"type"`
+	Amount DecimalString `json:"amount"`
+	Reason string        `json:"reason"`
+}
+
+// Incoming payload for POST /admin/accounts/{id}/erasure.
+type EraseAccountRequest struct {
+	Reason string `json:"reason"`
+}
+
+// JSON returned by POST /admin/accounts/{id}/erasure and listed in data
+// exports: an erasure of the account's personal data and the fields that held
+// some
+type AccountErasureResponse struct {
+	ErasureID int64     `json:"erasure_id"`
+	AccountID int64     `json:"account_id"`
+	Reason    string    `json:"reason"`
+	ErasedBy  string    `json:"erased_by,omitempty"`
+	Fields    []string  `json:"fields"`
+	ErasedAt  time.Time `json:"erased_at"`
+}
+
+// JSON returned by GET /admin/accounts/{id}/data-export: everything held
+// about the account. Holds, scheduled transfers and transactions are those
+// into or out of it.
+type AccountDataExport struct {
+	Account            AccountResponse             `json:"account"`
+	Wallets            []AccountResponse           `json:"wallets"`
+	Aliases            []AccountAlias              `json:"aliases"`
+	Counterparties     CounterpartiesResponse      `json:"counterparties"`
+	AlertThresholds    AlertThresholdsResponse     `json:"alert_thresholds"`
+	Alerts             []AlertResponse             `json:"alerts"`
+	Holds              []HoldResponse              `json:"holds"`
+	ScheduledTransfers []ScheduledTransferResponse `json:"scheduled_transfers"`
+	Transactions       []Transaction               `json:"transactions"`
+	Erasures           []AccountErasureResponse    `json:"erasures"`
+	ExportedAt         time.Time                   `json:"exported_at"`
+}
+
+// Incoming payload for POST /transactions.
+// DryRun runs all checks without transferring; ?dry_run=true has the same effect.
+type CreateTransactionRequest struct {
+	TransactionRequest
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// Modes of POST /transactions/batch
+const (
+	BatchModeAtomic     = "atomic"
+	BatchModeBestEffort = "best_effort"
+)
+
+// Incoming payload for POST /transactions/batch
+type BatchTransferRequest struct {
+	Mode      string               `json:"mode"`
+	Transfers []TransactionRequest `json:"transfers"`
+}
+
+// Outcome of one transfer of a batch, in request order
+type BatchTransferResult struct {
+	Index         int            `json:"index"`
+	Status        string         `json:"status"`
+	ID            string         `json:"id,omitempty"`
+	TransactionID int64          `json:"transaction_id,omitempty"` // deprecated, use ID
+	Error         *ErrorResponse `json:"error,omitempty"`
+}
+
+// JSON returned by POST /transactions/batch
+type BatchTransferResponse struct {
+	Mode    string                `json:"mode"`
+	Results []BatchTransferResult `json:"results"`
+}
+
+// A leg of POST /transactions/split: the account on the other side of the
+// split's source or destination, and the amount moved to or from it.
+type SplitLeg struct {
+	AccountID AccountID     `json:"account_id"`
+	Amount    DecimalString `json:"amount"`
+}
+
+// Incoming payload for POST /transactions/split. Exactly one of
+// SourceAccountID and DestinationAccountID is given: the source funds every
+// leg's account, or every leg's account funds the destination. Reference and
+// PurposeCode are recorded on every leg.
+type SplitTransferRequest struct {
+	SourceAccountID      AccountID  `json:"source_account_id"`
+	DestinationAccountID AccountID  `json:"destination_account_id"`
+	Legs                 []SplitLeg `json:"legs"`
+	Reference            string     `json:"reference,omitempty"`
+	PurposeCode          string     `json:"purpose_code,omitempty"`
+}
+
+// Transfers returns the legs of r as transfers, in order.
+func (r *SplitTransferRequest) Transfers() []TransactionRequest {
+	ts := make([]TransactionRequest, len(r.Legs))
+	for i, leg := range r.Legs {
+		ts[i] = TransactionRequest{
+			SourceAccountID:      r.SourceAccountID,
+			DestinationAccountID: r.DestinationAccountID,
+			Amount:               leg.Amount,
+			Reference:            r.Reference,
+			PurposeCode:          r.PurposeCode,
+		}
+		if r.SourceAccountID.IsZero() {
+			ts[i].SourceAccountID = leg.AccountID
+		} else {
+			ts[i].DestinationAccountID = leg.AccountID
+		}
+	}
+	return ts
+}
+
+// A leg of a split transfer as performed, in request order
+type SplitLegResult struct {
+	Index                int           `json:"index"`
+	ID                   string        `json:"id"`
+	SourceAccountID      int64         `json:"source_account_id"`
+	DestinationAccountID int64         `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+}
+
+// JSON returned by POST /transactions/split
+type SplitTransferResponse struct {
+	TransferGroupID string           `json:"transfer_group_id"`
+	Legs            []SplitLegResult `json:"legs"`
+}
+
+// JSON returned by POST /transactions.
+// Status is pending when the transfer was accepted asynchronously. A dry run
+// reports the status the transfer would have, without a transaction id.
+// TransactionID, the deprecated serial id, is only set while the server is
+// configured to keep serial ids.
+type TransactionResponse struct {
+	ID            string `json:"id,omitempty"`
+	TransactionID int64  `json:"transaction_id,omitempty"` // deprecated, use ID
+	Status        string `json:"status"`
+	DryRun        bool   `json:"dry_run,omitempty"`
+}
+
+// Single entry of GET /accounts/{id}/transactions, also returned by GET /transactions/{id}
+// and POST /transactions/{id}/reverse. ReversalOfID is set on reversals. Kind is
+// transfer, adjustment or fee; a credit adjustment has no source account and a
+// debit no destination, and Reason and AdjustedBy are set on adjustments only.
+// A fee is paid to the fee account by the source of the transfer FeeOfID.
+// TransferGroupID is shared by the legs of a split transfer.
+// InitiatedBy, Channel and RequestID tell who made a transfer, through which
+// channel and with which request, when it was recorded with them.
+// ID is the public UUID of the transaction. TransactionID, ReversalOf and FeeOf
+// are the deprecated serial ids of the transaction and of those it refers to,
+// only set while the server is configured to keep serial ids.
+type Transaction struct {
+	ID                   string        `json:"id"`
+	TransactionID        int64         `json:"transaction_id,omitempty"`
+	SourceAccountID      int64         `json:"source_account_id"`
+	DestinationAccountID int64         `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Currency             string        `json:"currency"`
+	Status               string        `json:"status"`
+	ErrorMessage         string        `json:"error_message,omitempty"`
+	ReversalOfID         string        `json:"reversal_of_id,omitempty"`
+	ReversalOf           int64         `json:"reversal_of,omitempty"`
+	FeeOfID              string        `json:"fee_of_id,omitempty"`
+	FeeOf                int64         `json:"fee_of,omitempty"`
+	TransferGroupID      string        `json:"transfer_group_id,omitempty"`
+	Reference            string        `json:"reference,omitempty"`
+	PurposeCode          string        `json:"purpose_code,omitempty"`
+	Kind                 string        `json:"kind"`
+	Reason               string        `json:"reason,omitempty"`
+	AdjustedBy           string        `json:"adjusted_by,omitempty"`
+	InitiatedBy          string        `json:"initiated_by,omitempty"`
+	Channel              string        `json:"channel,omitempty"`
+	RequestID            string        `json:"request_id,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+}
+
+// JSON returned by GET /accounts/{id}/transactions.
+// NextCursor is empty on the last page.
+type TransactionListResponse struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   string        `json:"next_cursor,omitempty"`
+}
+
+// Incoming payload for POST /admin/apikeys.
+// TenantID defaults to the tenant of the calling admin. AllowedNetworks
+// restricts the key to clients in these CIDRs or at these IP addresses.
+type CreateAPIKeyRequest struct {
+	Name            string   `json:"name"`
+	Role            string   `json:"role"`
+	TenantID        string   `json:"tenant_id,omitempty"`
+	AllowedNetworks []string `json:"allowed_networks,omitempty"`
+}
+
+// JSON returned by the /admin/apikeys endpoints.
+// Key is only set in the response to creation and cannot be retrieved later.
+type APIKeyResponse struct {
+	ID              int64      `json:"id"`
+	Name            string     `json:"name"`
+	Role            string     `json:"role"`
+	TenantID        string     `json:"tenant_id,omitempty"`
+	AllowedNetworks []string   `json:"allowed_networks,omitempty"`
+	Key             string     `json:"key,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Transfer rule kinds and actions; see POST /admin/rules
+const (
+	RuleVelocity        = "velocity"
+	RuleNewCounterparty = "new_counterparty"
+	RuleAmount          = "amount"
+	RuleMatch           = "match"
+
+	RuleBlock           = "block"
+	RuleFlag            = "flag"
+	RuleAllow           = "allow"
+	RuleRequireApproval = "require_approval"
+)
+
+// Incoming payload for POST /admin/rules, and the rules of RULES_FILE.
+// MaxCount and WindowSeconds are only used by velocity rules, which require
+// them. ActiveFrom and ActiveUntil are UTC times of day as HH:MM, given both
+// or neither.
+type CreateRuleRequest struct {
+	Name                 string         `json:"name"`
+	Kind                 string         `json:"kind"`
+	Action               string         `json:"action"`
+	Priority             int            `json:"priority,omitempty"`
+	SourceAccountID      int64          `json:"source_account_id,omitempty"`
+	DestinationAccountID int64          `json:"destination_account_id,omitempty"`
+	MinAmount            *DecimalString `json:"min_amount,omitempty"`
+	MaxAmount            *DecimalString `json:"max_amount,omitempty"`
+	TenantID             string         `json:"tenant_id,omitempty"`
+	ActiveFrom           string         `json:"active_from,omitempty"`
+	ActiveUntil          string         `json:"active_until,omitempty"`
+	MaxCount             int            `json:"max_count,omitempty"`
+	WindowSeconds        int64          `json:"window_seconds,omitempty"`
+}
+
+// JSON returned by the /admin/rules endpoints
+type RuleResponse struct {
+	RuleID               int64          `json:"rule_id"`
+	Name                 string         `json:"name"`
+	Kind                 string         `json:"kind"`
+	Action               string         `json:"action"`
+	Priority             int            `json:"priority"`
+	SourceAccountID      int64          `json:"source_account_id,omitempty"`
+	DestinationAccountID int64          `json:"destination_account_id,omitempty"`
+	MinAmount            *DecimalString `json:"min_amount,omitempty"`
+	MaxAmount            *DecimalString `json:"max_amount,omitempty"`
+	TenantID             string         `json:"tenant_id,omitempty"`
+	ActiveFrom           string         `json:"active_from,omitempty"`
+	ActiveUntil          string         `json:"active_until,omitempty"`
+	MaxCount             int            `json:"max_count,omitempty"`
+	WindowSeconds        int64          `json:"window_seconds,omitempty"`
+	File                 bool           `json:"file,omitempty"` // from RULES_FILE, without an id
+	CreatedAt            time.Time      `json:"created_at"`
+}
+
+// JSON returned by GET /admin/rules, in the order transfers are checked against them
+type RuleListResponse struct {
+	Rules []RuleResponse `json:"rules"`
+}
+
+// Incoming payload for POST /admin/fee-policies. TenantID and AccountTag
+// narrow the source accounts charged; FlatFee and Percentage default to 0 but
+// one of them must be positive.
+type CreateFeePolicyRequest struct {
+	Name         string         `json:"name"`
+	Priority     int            `json:"priority,omitempty"`
+	TenantID     string         `json:"tenant_id,omitempty"`
+	AccountTag   string         `json:"account_tag,omitempty"`
+	Currency     string         `json:"currency"`
+	FlatFee      *DecimalString `json:"flat_fee,omitempty"`
+	Percentage   *DecimalString `json:"percentage,omitempty"`
+	FeeAccountID int64          `json:"fee_account_id"`
+}
+
+// JSON returned by the /admin/fee-policies endpoints
+type FeePolicyResponse struct {
+	FeePolicyID  int64         `json:"fee_policy_id"`
+	Name         string        `json:"name"`
+	Priority     int           `json:"priority"`
+	TenantID     string        `json:"tenant_id,omitempty"`
+	AccountTag   string        `json:"account_tag,omitempty"`
+	Currency     string        `json:"currency"`
+	FlatFee      DecimalString `json:"flat_fee"`
+	Percentage   DecimalString `json:"percentage"`
+	FeeAccountID int64         `json:"fee_account_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// JSON returned by GET /admin/fee-policies, in the order transfers are matched against them
+type FeePolicyListResponse struct {
+	FeePolicies []FeePolicyResponse `json:"fee_policies"`
+}
+
+// JSON returned by GET /admin/clearing-accounts
+type ClearingAccountListResponse struct {
+	AccountIDs []int64 `json:"account_ids"`
+}
+
+// Incoming payload for POST /clearing/transfers
+type CreateClearingTransferRequest struct {
+	SourceAccountID      AccountID     `json:"source_account_id"`
+	DestinationAccountID AccountID     `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Reference            string        `json:"reference,omitempty"`
+}
+
+// JSON returned by POST /clearing/transfers. BusinessDate is the day, as
+// YYYY-MM-DD, whose settlement moves the balances.
+type ClearingTransferResponse struct {
+	ClearingTransferID   int64         `json:"clearing_transfer_id"`
+	BusinessDate         string        `json:"business_date"`
 	SourceAccountID      int64         `json:"source_account_id"`
 	DestinationAccountID int64         `json:"destination_account_id"`
 	Amount               DecimalString `json:"amount"`
+	Currency             string        `json:"currency"`
+	Reference            string        `json:"reference,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+}
+
+// A pair of clearing accounts netted over a business day, account_a being
+// the lower id. Net is what account_a owes account_b, negative when account_b
+// owes account_a. TransactionPublicID is set once settled, unless the net is
+// zero; TransactionID, the deprecated serial id of the settlement transaction,
+// only while the server keeps serial ids.
+type SettlementPositionResponse struct {
+	AccountA            int64         `json:"account_a"`
+	AccountB            int64         `json:"account_b"`
+	Currency            string        `json:"currency"`
+	AToB                DecimalString `json:"a_to_b"`
+	BToA                DecimalString `json:"b_to_a"`
+	Net                 DecimalString `json:"net"`
+	Transfers           int           `json:"transfers"`
+	Settled             bool          `json:"settled"`
+	TransactionPublicID string        `json:"transaction_public_id,omitempty"`
+	TransactionID       int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	SettledAt           *time.Time    `json:"settled_at,omitempty"`
+}
+
+// JSON returned by GET /admin/settlements
+type SettlementReportResponse struct {
+	BusinessDate string                       `json:"business_date"`
+	Cutoff       time.Time                    `json:"cutoff"`
+	Positions    []SettlementPositionResponse `json:"positions"`
+}
+
+// Incoming payload for POST /admin/sweep-rules. The available balance of
+// AccountID above Threshold is swept to TargetAccountID.
+type CreateSweepRuleRequest struct {
+	AccountID       int64          `json:"account_id"`
+	TargetAccountID int64          `json:"target_account_id"`
+	Threshold       *DecimalString `json:"threshold"`
+}
+
+// Incoming payload for PUT /admin/sweep-rules/{id}
+type UpdateSweepRuleRequest struct {
+	TargetAccountID int64          `json:"target_account_id"`
+	Threshold       *DecimalString `json:"threshold"`
+}
+
+// JSON returned by the /admin/sweep-rules endpoints
+type SweepRuleResponse struct {
+	SweepRuleID     int64         `json:"sweep_rule_id"`
+	AccountID       int64         `json:"account_id"`
+	TargetAccountID int64         `json:"target_account_id"`
+	Threshold       DecimalString `json:"threshold"`
+	CreatedAt       time.Time     `json:"created_at"`
+}
+
+// JSON returned by GET /admin/sweep-rules
+type SweepRuleListResponse struct {
+	SweepRules []SweepRuleResponse `json:"sweep_rules"`
+}
+
+// A sweep made by a rule, TransactionPublicID being the public id of the
+// transfer that moved Amount; TransactionID, its deprecated serial id, is only
+// set while the server keeps serial ids.
+type SweepExecutionResponse struct {
+	SweepExecutionID    int64         `json:"sweep_execution_id"`
+	AccountID           int64         `json:"account_id"`
+	TargetAccountID     int64         `json:"target_account_id"`
+	Amount              DecimalString `json:"amount"`
+	TransactionPublicID string        `json:"transaction_public_id"`
+	TransactionID       int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	ExecutedAt          time.Time     `json:"executed_at"`
+}
+
+// JSON returned by GET /admin/sweep-rules/{id}/executions, oldest first
+type SweepExecutionListResponse struct {
+	Executions []SweepExecutionResponse `json:"executions"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
+
+// A transfer flagged by a rule, as returned by the /admin/reviews endpoints.
+// RuleID is omitted once the rule is deleted.
+type ReviewResponse struct {
+	Transaction Transaction `json:"transaction"`
+	RuleID      int64       `json:"rule_id,omitempty"`
+	RuleName    string      `json:"rule_name"`
+	Status      string      `json:"status"`
+	ReviewedBy  string      `json:"reviewed_by,omitempty"`
+	Reason      string      `json:"reason,omitempty"`
+	FlaggedAt   time.Time   `json:"flagged_at"`
+	ReviewedAt  *time.Time  `json:"reviewed_at,omitempty"`
+}
+
+// JSON returned by GET /admin/reviews.
+// NextCursor is empty on the last page.
+type ReviewListResponse struct {
+	Reviews    []ReviewResponse `json:"reviews"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// JSON returned by POST /admin/balance-snapshots
+type BalanceSnapshotResponse struct {
+	TakenAt  time.Time `json:"taken_at"`
+	Accounts int       `json:"accounts"`
+}
+
+// JSON returned by GET /admin/reconciliation/latest
+type ReconciliationReport struct {
+	RunID           int64                       `json:"run_id"`
+	CreatedAt       time.Time                   `json:"created_at"`
+	AccountsChecked int                         `json:"accounts_checked"`
+	Discrepancies   []ReconciliationDiscrepancy `json:"discrepancies"`
+}
+
+// An account whose stored balances disagree with the ledger. LedgerBalance is the
+// initial balance plus the succeeded transfers in minus those out; PendingHolds
+// is the sum of its pending holds, which HeldBalance should equal.
+type ReconciliationDiscrepancy struct {
+	AccountID     int64         `json:"account_id"`
+	Balance       DecimalString `json:"balance"`
+	LedgerBalance DecimalString `json:"ledger_balance"`
+	HeldBalance   DecimalString `json:"held_balance"`
+	PendingHolds  DecimalString `json:"pending_holds"`
+}
+
+// JSON returned by GET /admin/reports/trial-balance. Balances are current;
+// transfer volume and status counts cover [from, to).
+type TrialBalanceReport struct {
+	From                 time.Time        `json:"from"`
+	To                   time.Time        `json:"to"`
+	Currencies           []CurrencyTotals `json:"currencies"`
+	TransfersByPurpose   []PurposeTotals  `json:"transfers_by_purpose"`
+	TransactionsByStatus map[string]int   `json:"transactions_by_status"`
+}
+
+// Volume of the transfers with one purpose code in one currency; an empty
+// purpose code totals the transfers without one
+type PurposeTotals struct {
+	PurposeCode    string        `json:"purpose_code"`
+	Currency       string        `json:"currency"`
+	TransferCount  int           `json:"transfer_count"`
+	TransferVolume DecimalString `json:"transfer_volume"`
+}
+
+// JSON returned by GET /purpose-codes. PurposeCodes is null when any
+// well-formed code is accepted.
+type PurposeCodesResponse struct {
+	PurposeCodes []string `json:"purpose_codes"`
+}
+
+// Totals of the accounts of one currency and the transfers between them
+type CurrencyTotals struct {
+	Currency       string        `json:"currency"`
+	Accounts       int           `json:"accounts"`
+	TotalBalance   DecimalString `json:"total_balance"`
+	HeldBalance    DecimalString `json:"held_balance"`
+	TransferCount  int           `json:"transfer_count"`
+	TransferVolume DecimalString `json:"transfer_volume"`
+}
+
+// JSON returned by GET /accounts/{id}/statements/{period}. Period is the
+// calendar month (UTC) as yyyy-mm.
+type Statement struct {
+	AccountID      int64            `json:"account_id"`
+	Period         string           `json:"period"`
+	Currency       string           `json:"currency"`
+	OpeningBalance DecimalString    `json:"opening_balance"`
+	ClosingBalance DecimalString    `json:"closing_balance"`
+	Entries        []StatementEntry `json:"entries"`
+	GeneratedAt    time.Time        `json:"generated_at"`
+}
+
+// A succeeded transfer on a statement. Amount is positive when the account was
+// credited and negative when it was debited. TransactionID, the deprecated
+// serial id of the transfer, is only set while the server keeps serial ids.
+type StatementEntry struct {
+	TransactionPublicID   string        `json:"transaction_public_id"`
+	TransactionID         int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	SettledAt             time.Time     `json:"settled_at"`
+	CounterpartyAccountID int64         `json:"counterparty_account_id"`
+	Amount                DecimalString `json:"amount"`
+	BalanceAfter          DecimalString `json:"balance_after"`
+	Reference             string        `json:"reference,omitempty"`
+}
+
+// Incoming payload for POST /holds.
+// ExpiresInSeconds defaults to DefaultHoldExpiry when omitted.
+type CreateHoldRequest struct {
+	SourceAccountID      AccountID     `json:"source_account_id"`
+	DestinationAccountID AccountID     `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	ExpiresInSeconds     int64         `json:"expires_in_seconds,omitempty"`
+}
+
+// JSON returned by the /holds endpoints.
+// TransactionPublicID is set once the hold has been captured, and
+// TransactionID, the deprecated serial id, only while the server keeps serial
+// ids. Fee is reserved along with Amount and charged to the source on capture,
+// credited to FeeAccountID.
+type HoldResponse struct {
+	HoldID               int64         `json:"hold_id"`
+	SourceAccountID      int64         `json:"source_account_id"`
+	DestinationAccountID int64         `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Currency             string        `json:"currency"`
+	Status               string        `json:"status"`
+	TransactionPublicID  string        `json:"transaction_public_id,omitempty"`
+	TransactionID        int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	CreatedAt            time.Time     `json:"created_at"`
+	ExpiresAt            time.Time     `json:"expires_at"`
+	Fee                  DecimalString `json:"fee"`
+	FeeAccountID         int64         `json:"fee_account_id,omitempty"`
+}
+
+// Incoming payload for POST /transfers/scheduled
+type CreateScheduledTransferRequest struct {
+	SourceAccountID      AccountID     `json:"source_account_id"`
+	DestinationAccountID AccountID     `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	ExecuteAt            time.Time     `json:"execute_at"`
+}
+
+// JSON returned by the /transfers/scheduled endpoints.
+// TransactionPublicID is set once the transfer succeeded, ErrorMessage once it
+// failed; TransactionID, the deprecated serial id, only while the server keeps
+// serial ids.
+type ScheduledTransferResponse struct {
+	ScheduledTransferID  int64         `json:"scheduled_transfer_id"`
+	SourceAccountID      int64         `json:"source_account_id"`
+	DestinationAccountID int64         `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Status               string        `json:"status"`
+	TransactionPublicID  string        `json:"transaction_public_id,omitempty"`
+	TransactionID        int64         `json:"transaction_id,omitempty"` // deprecated, use TransactionPublicID
+	ErrorMessage         string        `json:"error_message,omitempty"`
+	CreatedAt            time.Time     `json:"created_at"`
+	ExecuteAt            time.Time     `json:"execute_at"`
+	ExecutedAt           *time.Time    `json:"executed_at,omitempty"`
+}
+
+// Rejected row of an account import. Row is the 1-based data row of the file;
+// Field is the column at fault, when known.
+type AccountImportError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// JSON returned by POST /admin/accounts/import
+type AccountImportResponse struct {
+	ImportedRows int `json:"imported_rows"`
+}
+
+// Failed row of a bulk transfer job. Row is the 1-based data row of the uploaded CSV.
+type TransferJobFailure struct {
+	Row                  int           `json:"row"`
+	SourceAccountID      int64         `json:"source_account_id"`
+	DestinationAccountID int64         `json:"destination_account_id"`
+	Amount               DecimalString `json:"amount"`
+	Error                string        `json:"error"`
+}
+
+// JSON returned by POST /jobs/transfers and GET /jobs/{id}.
+// Failures lists at most MaxJobFailures failed rows; FailedRows has the full count.
+type TransferJobResponse struct {
+	JobID         int64                `json:"job_id"`
+	Status        string               `json:"status"`
+	TotalRows     int                  `json:"total_rows"`
+	ProcessedRows int                  `json:"processed_rows"`
+	SucceededRows int                  `json:"succeeded_rows"`
+	FailedRows    int                  `json:"failed_rows"`
+	CreatedAt     time.Time            `json:"created_at"`
+	FinishedAt    *time.Time           `json:"finished_at,omitempty"`
+	Failures      []TransferJobFailure `json:"failures"`
+}
+
+// Types of the messages exchanged on the WebSocket at /ws
+const (
+	WSSubscribe   = "subscribe"   // client: add account_ids to the subscriptions
+	WSUnsubscribe = "unsubscribe" // client: remove account_ids from the subscriptions
+	WSSubscribed  = "subscribed"  // server: the subscriptions after a request
+	WSBalance     = "balance"     // server: the balance of a subscribed account
+	WSTransfer    = "transfer"    // server: a committed transfer of a subscribed account
+	WSError       = "error"       // server: a request failed; the connection stays open
+)
+
+// WSRequest is a message of a WebSocket client
+type WSRequest struct {
+	Type       string  `json:"type"`
+	AccountIDs []int64 `json:"account_ids"`
+}
+
+// WSMessage is a message to a WebSocket client. Only the fields of its type
+// are set: account_ids for subscribed, balance, transfer and event for
+// transfer, whose event is transfer.completed or transfer.failed, and error.
+type WSMessage struct {
+	Type       string           `json:"type"`
+	AccountIDs []int64          `json:"account_ids,omitempty"`
+	Balance    *BalanceResponse `json:"balance,omitempty"`
+	Event      string           `json:"event,omitempty"`
+	Transfer   json.RawMessage  `json:"transfer,omitempty"`
+	Error      *ErrorResponse   `json:"error,omitempty"`
+}
+
+// Levels of the request log, from the most verbose
+const (
+	LogLevelDebug = "debug" // every request, with its query and client address
+	LogLevelInfo  = "info"  // every request
+	LogLevelWarn  = "warn"  // requests failing with 4xx or 5xx
+	LogLevelError = "error" // requests failing with 5xx
+)
+
+// RuntimeConfig holds the settings that can change while the service runs,
+// returned by GET /admin/config. The body of PUT /admin/config has the same
+// fields; absent ones keep their value.
+type RuntimeConfig struct {
+	RateLimitRPS   float64        `json:"rate_limit_rps"` // 0 disables rate limiting
+	RateLimitBurst int            `json:"rate_limit_burst"`
+	TransferLimits TransferLimits `json:"transfer_limits"` // of accounts that do not override them; null fields are unlimited
+	AsyncTransfers bool           `json:"async_transfers"`
+	LogLevel       string         `json:"log_level"`
+}
+
+// Incoming payload for POST /admin/seed. Absent fields take the defaults of
+// the seed package: 100 USD accounts with uniform balances up to 10000, and no
+// transfers, which are spread over 30 days.
+type SeedRequest struct {
+	Accounts     int            `json:"accounts,omitempty"`
+	Currency     string         `json:"currency,omitempty"`
+	Distribution string         `json:"distribution,omitempty"` // uniform, normal or pareto
+	MinBalance   *DecimalString `json:"min_balance,omitempty"`
+	MaxBalance   *DecimalString `json:"max_balance,omitempty"`
+	Transfers    int            `json:"transfers,omitempty"`
+	PeriodDays   int            `json:"period_days,omitempty"`
+	Seed         uint64         `json:"seed,omitempty"`
+}
+
+// JSON returned by POST /admin/seed
+type SeedResponse struct {
+	AccountIDs       []int64   `json:"account_ids"`
+	ExternalIDPrefix string    `json:"external_id_prefix"` // followed by the account's number from 1
+	Transfers        int       `json:"transfers"`
+	Skipped          int       `json:"skipped"` // transfers refused for insufficient funds
+	From             time.Time `json:"from"`
+	To               time.Time `json:"to"`
+}
+
+// A request to the API and its response, as recorded by the capture mode.
+// Credentials are redacted. JSON bodies are kept as JSON, others as strings,
+// cut at the capture limit.
+type CapturedExchange struct {
+	ID                int64               `json:"id"`
+	Time              time.Time           `json:"time"`
+	DurationMS        float64             `json:"duration_ms"`
+	Method            string              `json:"method"`
+	Path              string              `json:"path"`
+	Query             string              `json:"query,omitempty"`
+	RequestHeaders    map[string][]string `json:"request_headers,omitempty"`
+	RequestBody       json.RawMessage     `json:"request_body,omitempty"`
+	RequestTruncated  bool                `json:"request_truncated,omitempty"`
+	Status            int                 `json:"status"`
+	ResponseHeaders   map[string][]string `json:"response_headers,omitempty"`
+	ResponseBody      json.RawMessage     `json:"response_body,omitempty"`
+	ResponseTruncated bool                `json:"response_truncated,omitempty"`
+}
+
+// JSON returned by GET /admin/captures, newest first
+type CaptureListResponse struct {
+	Captures []CapturedExchange `json:"captures"`
+}
+
+// A transfer request the API rejected, as recorded for fraud analysis.
+// Accounts and amount are as sent.
+type TransferAttemptResponse struct {
+	ID                 int64     `json:"id"`
+	Kind               string    `json:"kind"`
+	SourceAccount      string    `json:"source_account,omitempty"`
+	DestinationAccount string    `json:"destination_account,omitempty"`
+	Amount             string    `json:"amount,omitempty"`
+	Reference          string    `json:"reference,omitempty"`
+	Status             int       `json:"status"`
+	Code               ErrorCode `json:"code"`
+	Message            string    `json:"message"`
+	Subject            string    `json:"subject,omitempty"`
+	Tenant             string    `json:"tenant,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// JSON returned by GET /admin/transfer-attempts, newest first
+type TransferAttemptListResponse struct {
+	Attempts   []TransferAttemptResponse `json:"attempts"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
 }
@@ -2,7 +2,14 @@ package model
 
 import (
 	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -29,6 +36,36 @@ func TestDecimalString_UnmarshalJSON_Number(t *testing.T) {
 	}
 }
 
+func TestDecimalString_UnmarshalJSON_NumberModes(t *testing.T) {
+	defer func(m NumberMode) { DecimalNumbers = m }(DecimalNumbers)
+
+	DecimalNumbers = NumbersExact
+	var d DecimalString
+	if err := json.Unmarshal([]byte(`12345678901234567.89`), &d); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.String() != "12345678901234567.89" {
+		t.Fatalf("expected every digit to be kept, got %s", d.String())
+	}
+
+	DecimalNumbers = NumbersRejected
+	if err := json.Unmarshal([]byte(`100.5`), &d); !errors.Is(err, ErrNumericAmount) {
+		t.Fatalf("expected ErrNumericAmount, got %v", err)
+	}
+	if err := json.Unmarshal([]byte(`"100.5"`), &d); err != nil {
+		t.Fatalf("unexpected error for a string: %v", err)
+	}
+
+	for s, want := range map[string]NumberMode{"float": NumbersAsFloat, "exact": NumbersExact, "reject": NumbersRejected} {
+		if m, err := ParseNumberMode(s); err != nil || m != want {
+			t.Fatalf("ParseNumberMode(%q): got %v, %v", s, m, err)
+		}
+	}
+	if _, err := ParseNumberMode("strict"); err == nil {
+		t.Fatal("expected error for an unknown mode")
+	}
+}
+
 func TestCreateAccountRequest_Validate(t *testing.T) {
 	r := CreateAccountRequest{
 		AccountID:      0,
@@ -52,15 +89,15 @@ func TestCreateAccountRequest_Validate(t *testing.T) {
 
 func TestTransactionRequest_Validate(t *testing.T) {
 	r := TransactionRequest{
-		SourceAccountID:      1,
-		DestinationAccountID: 1,
+		SourceAccountID:      AccountID{ID: 1},
+		DestinationAccountID: AccountID{ID: 1},
 		Amount:               DecimalString{decimal.NewFromInt(10)},
 	}
 	if err := r.Validate(); err == nil {
 		t.Fatalf("expected error when source == destination")
 	}
 
-	r.DestinationAccountID = 2
+	r.DestinationAccountID = AccountID{ID: 2}
 	r.Amount = DecimalString{decimal.NewFromInt(0)}
 	if err := r.Validate(); err == nil {
 		t.Fatalf("expected error for zero amount")
@@ -89,6 +126,28 @@ func TestDecimalString_UnmarshalJSON_Invalid(t *testing.T) {
 	}
 }
 
+// TestDecimalString_UnmarshalJSON_OutOfRange tests that decimals with huge
+// exponents or too many digits are refused in every number mode
+func TestDecimalString_UnmarshalJSON_OutOfRange(t *testing.T) {
+	defer func(m NumberMode) { DecimalNumbers = m }(DecimalNumbers)
+	for _, mode := range []NumberMode{NumbersAsFloat, NumbersExact} {
+		DecimalNumbers = mode
+		for _, in := range []string{`"1e200000000"`, `"1e-200000000"`, `"-1E41"`, `"` + strings.Repeat("9", 41) + `"`,
+			`"0.` + strings.Repeat("0", 100) + `1"`, `1e200`, `1e-41`} {
+			var d DecimalString
+			if err := json.Unmarshal([]byte(in), &d); !errors.Is(err, ErrDecimalOutOfRange) {
+				t.Fatalf("mode %d, %s: expected ErrDecimalOutOfRange, got %v", mode, in, err)
+			}
+		}
+		for _, in := range []string{`"1e40"`, `"` + strings.Repeat("9", 40) + `"`, `"0.0000000001"`, `"123456789.0123456789"`} {
+			var d DecimalString
+			if err := json.Unmarshal([]byte(in), &d); err != nil {
+				t.Fatalf("mode %d, %s: %v", mode, in, err)
+			}
+		}
+	}
+}
+
 // TestCreateAccountRequest_Validate_MissingFields tests with minimal valid data
 func TestCreateAccountRequest_Validate_MissingFields(t *testing.T) {
 	// Valid case
@@ -104,8 +163,8 @@ func TestCreateAccountRequest_Validate_MissingFields(t *testing.T) {
 // TestTransactionRequest_Validate_ZeroSourceAccount tests zero source account ID
 func TestTransactionRequest_Validate_ZeroSourceAccount(t *testing.T) {
 	r := TransactionRequest{
-		SourceAccountID:      0,
-		DestinationAccountID: 2,
+		SourceAccountID:      AccountID{ID: 0},
+		DestinationAccountID: AccountID{ID: 2},
 		Amount:               DecimalString{decimal.NewFromInt(10)},
 	}
 	if err := r.Validate(); err == nil {
@@ -116,8 +175,8 @@ func TestTransactionRequest_Validate_ZeroSourceAccount(t *testing.T) {
 // TestTransactionRequest_Validate_ZeroDestinationAccount tests zero destination account ID
 func TestTransactionRequest_Validate_ZeroDestinationAccount(t *testing.T) {
 	r := TransactionRequest{
-		SourceAccountID:      1,
-		DestinationAccountID: 0,
+		SourceAccountID:      AccountID{ID: 1},
+		DestinationAccountID: AccountID{ID: 0},
 		Amount:               DecimalString{decimal.NewFromInt(10)},
 	}
 	if err := r.Validate(); err == nil {
@@ -128,8 +187,8 @@ func TestTransactionRequest_Validate_ZeroDestinationAccount(t *testing.T) {
 // TestTransactionRequest_Validate_NegativeAmount tests negative transfer amount
 func TestTransactionRequest_Validate_NegativeAmount(t *testing.T) {
 	r := TransactionRequest{
-		SourceAccountID:      1,
-		DestinationAccountID: 2,
+		SourceAccountID:      AccountID{ID: 1},
+		DestinationAccountID: AccountID{ID: 2},
 		Amount:               DecimalString{decimal.NewFromInt(-10)},
 	}
 	if err := r.Validate(); err == nil {
@@ -168,3 +227,447 @@ func TestDecimalString_Roundtrip(t *testing.T) {
 		t.Fatalf("roundtrip failed: expected %s, got %s", original.String(), restored.String())
 	}
 }
+
+func TestUpdateAccountRequest_Validate(t *testing.T) {
+	var r UpdateAccountRequest
+	if err := r.Validate(); err != ErrEmptyUpdate {
+		t.Fatalf("expected ErrEmptyUpdate, got %v", err)
+	}
+
+	tags := make([]string, MaxTags+1)
+	for i := range tags {
+		tags[i] = "t"
+	}
+	r.Tags = &tags
+	if err := r.Validate(); err != ErrTooManyTags {
+		t.Fatalf("expected ErrTooManyTags, got %v", err)
+	}
+
+	tags = []string{}
+	name := strings.Repeat("x", MaxDisplayNameLen+1)
+	r.DisplayName = &name
+	if err := r.Validate(); err != ErrDisplayNameTooLong {
+		t.Fatalf("expected ErrDisplayNameTooLong, got %v", err)
+	}
+
+	name = "Payroll"
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCreateAccountRequest_Validate_Currency(t *testing.T) {
+	r := CreateAccountRequest{AccountID: 1, Currency: "EUR"}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range []string{"eur", "EURO", "E1R"} {
+		r.Currency = c
+		if err := r.Validate(); err != ErrInvalidCurrency {
+			t.Fatalf("%s: expected ErrInvalidCurrency, got %v", c, err)
+		}
+	}
+}
+
+func TestCreateHoldRequest_Expiry(t *testing.T) {
+	r := CreateHoldRequest{SourceAccountID: AccountID{ID: 1}, DestinationAccountID: AccountID{ID: 2}, Amount: DecimalString{decimal.NewFromInt(1)}}
+	if err := r.Validate(); err != nil || r.Expiry() != DefaultHoldExpiry {
+		t.Fatalf("expected default expiry, got %s, %v", r.Expiry(), err)
+	}
+
+	r.ExpiresInSeconds = int64(MaxHoldExpiry/time.Second) + 1
+	if err := r.Validate(); err != ErrInvalidHoldExpiry {
+		t.Fatalf("expected ErrInvalidHoldExpiry, got %v", err)
+	}
+}
+
+func TestBatchTransferRequest_Validate(t *testing.T) {
+	item := TransactionRequest{SourceAccountID: AccountID{ID: 1}, DestinationAccountID: AccountID{ID: 2}, Amount: DecimalString{decimal.NewFromInt(1)}}
+	r := BatchTransferRequest{Mode: "all", Transfers: []TransactionRequest{item}}
+	if err := r.Validate(); err != ErrInvalidBatchMode {
+		t.Fatalf("expected ErrInvalidBatchMode, got %v", err)
+	}
+
+	r.Mode = BatchModeAtomic
+	r.Transfers = make([]TransactionRequest, MaxBatchTransfers+1)
+	if err := r.Validate(); err != ErrInvalidBatchSize {
+		t.Fatalf("expected ErrInvalidBatchSize, got %v", err)
+	}
+
+	bad := item
+	bad.DestinationAccountID = AccountID{ID: 1}
+	r.Transfers = []TransactionRequest{item, bad}
+	if err := r.Validate(); !errors.Is(err, ErrSameSourceDestination) {
+		t.Fatalf("expected ErrSameSourceDestination, got %v", err)
+	}
+}
+
+func TestTransferLimits_Validate(t *testing.T) {
+	r := TransferLimits{}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("expected clearing all limits to be valid, got %v", err)
+	}
+	r.DailyTransferLimit = &DecimalString{decimal.NewFromInt(100)}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.MaxTransferAmount = &DecimalString{decimal.NewFromInt(-1)}
+	if err := r.Validate(); err != ErrInvalidLimit {
+		t.Fatalf("expected ErrInvalidLimit, got %v", err)
+	}
+}
+
+func TestSetMinBalanceRequest_Validate(t *testing.T) {
+	r := SetMinBalanceRequest{}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("expected removing the min balance to be valid, got %v", err)
+	}
+	r.MinBalance = &DecimalString{decimal.Zero}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.MinBalance = &DecimalString{decimal.NewFromInt(-1)}
+	if err := r.Validate(); err != ErrInvalidMinBalance {
+		t.Fatalf("expected ErrInvalidMinBalance, got %v", err)
+	}
+}
+
+func TestSetKYCStatusRequest_Validate(t *testing.T) {
+	for _, status := range []string{KYCUnverified, KYCVerified, KYCBlocked} {
+		r := SetKYCStatusRequest{KYCStatus: status}
+		if err := r.Validate(); err != nil {
+			t.Fatalf("%s: unexpected error: %v", status, err)
+		}
+	}
+	for _, status := range []string{"", "Verified", "pending"} {
+		r := SetKYCStatusRequest{KYCStatus: status}
+		if err := r.Validate(); err != ErrInvalidKYCStatus {
+			t.Fatalf("%q: expected ErrInvalidKYCStatus, got %v", status, err)
+		}
+	}
+}
+
+func TestSetCounterpartiesRequest_Validate(t *testing.T) {
+	for name, c := range map[string]struct {
+		req  SetCounterpartiesRequest
+		want error
+	}{
+		"unrestricted": {SetCounterpartiesRequest{}, nil},
+		"none allowed": {SetCounterpartiesRequest{Outgoing: []int64{}, Incoming: []int64{}}, nil},
+		"valid":        {SetCounterpartiesRequest{Outgoing: []int64{2, 3}}, nil},
+		"zero id":      {SetCounterpartiesRequest{Incoming: []int64{0}}, ErrInvalidCounterparties},
+		"negative id":  {SetCounterpartiesRequest{Outgoing: []int64{-2}}, ErrInvalidCounterparties},
+		"too many ids": {SetCounterpartiesRequest{Incoming: make([]int64, MaxCounterparties+1)}, ErrInvalidCounterparties},
+	} {
+		if err := c.req.Validate(); err != c.want {
+			t.Fatalf("%s: expected %v, got %v", name, c.want, err)
+		}
+	}
+}
+
+func TestSetAlertThresholdsRequest_Validate(t *testing.T) {
+	for name, c := range map[string]struct {
+		req  SetAlertThresholdsRequest
+		want error
+	}{
+		"none":                  {SetAlertThresholdsRequest{}, nil},
+		"zero low balance":      {SetAlertThresholdsRequest{LowBalance: &DecimalString{}}, nil},
+		"negative low balance":  {SetAlertThresholdsRequest{LowBalance: &DecimalString{decimal.NewFromInt(-1)}}, ErrInvalidLowBalance},
+		"zero large transfer":   {SetAlertThresholdsRequest{LargeTransfer: &DecimalString{}}, ErrInvalidLargeTransfer},
+		"valid large transfer":  {SetAlertThresholdsRequest{LargeTransfer: &DecimalString{decimal.NewFromInt(10000)}}, nil},
+		"large transfer scaled": {SetAlertThresholdsRequest{LargeTransfer: &DecimalString{decimal.RequireFromString("0.5")}}, nil},
+	} {
+		if err := c.req.Validate(); err != c.want {
+			t.Fatalf("%s: expected %v, got %v", name, c.want, err)
+		}
+	}
+}
+
+func TestCreateFeePolicyRequest_Validate(t *testing.T) {
+	valid := func() CreateFeePolicyRequest {
+		return CreateFeePolicyRequest{
+			Name:         "retail",
+			Currency:     "USD",
+			FlatFee:      &DecimalString{decimal.RequireFromString("0.25")},
+			FeeAccountID: 9,
+		}
+	}
+	r := valid()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.FlatFee, r.Percentage = nil, &DecimalString{decimal.RequireFromString("1.5")}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("expected a percentage alone to be valid, got %v", err)
+	}
+
+	for name, c := range map[string]struct {
+		mutate func(*CreateFeePolicyRequest)
+		want   error
+	}{
+		"no name":           {func(r *CreateFeePolicyRequest) { r.Name = " " }, ErrMissingName},
+		"bad currency":      {func(r *CreateFeePolicyRequest) { r.Currency = "usd" }, ErrInvalidCurrency},
+		"no fee account":    {func(r *CreateFeePolicyRequest) { r.FeeAccountID = 0 }, ErrMissingFeeAccount},
+		"no fee":            {func(r *CreateFeePolicyRequest) { r.FlatFee = nil }, ErrInvalidFee},
+		"negative flat fee": {func(r *CreateFeePolicyRequest) { r.FlatFee = &DecimalString{decimal.NewFromInt(-1)} }, ErrInvalidFee},
+		"percentage of 100": {func(r *CreateFeePolicyRequest) { r.Percentage = &DecimalString{decimal.NewFromInt(100)} }, ErrInvalidFeePercentage},
+		"percentage scale":  {func(r *CreateFeePolicyRequest) { r.Percentage = &DecimalString{decimal.RequireFromString("0.0000001")} }, ErrInvalidFeePercentage},
+	} {
+		r := valid()
+		c.mutate(&r)
+		if err := r.Validate(); err != c.want {
+			t.Fatalf("%s: expected %v, got %v", name, c.want, err)
+		}
+	}
+
+	r = valid()
+	r.FlatFee = &DecimalString{decimal.RequireFromString("0.001")}
+	if err := r.Validate(); !errors.Is(err, ErrAmountScale) {
+		t.Fatalf("expected ErrAmountScale, got %v", err)
+	}
+}
+
+func TestCreateSweepRuleRequest_Validate(t *testing.T) {
+	valid := func() CreateSweepRuleRequest {
+		return CreateSweepRuleRequest{AccountID: 1, TargetAccountID: 2, Threshold: &DecimalString{decimal.NewFromInt(1000)}}
+	}
+	r := valid()
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.Threshold = &DecimalString{}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("expected a zero threshold to be valid, got %v", err)
+	}
+
+	for name, c := range map[string]struct {
+		mutate func(*CreateSweepRuleRequest)
+		want   error
+	}{
+		"no account":         {func(r *CreateSweepRuleRequest) { r.AccountID = 0 }, ErrMissingSweepAccounts},
+		"no target":          {func(r *CreateSweepRuleRequest) { r.TargetAccountID = 0 }, ErrMissingSweepAccounts},
+		"same account":       {func(r *CreateSweepRuleRequest) { r.TargetAccountID = 1 }, ErrSweepSameAccount},
+		"no threshold":       {func(r *CreateSweepRuleRequest) { r.Threshold = nil }, ErrInvalidSweepThreshold},
+		"negative threshold": {func(r *CreateSweepRuleRequest) { r.Threshold = &DecimalString{decimal.NewFromInt(-1)} }, ErrInvalidSweepThreshold},
+	} {
+		r := valid()
+		c.mutate(&r)
+		if err := r.Validate(); err != c.want {
+			t.Fatalf("%s: expected %v, got %v", name, c.want, err)
+		}
+	}
+}
+
+func TestSplitTransferRequest_Validate(t *testing.T) {
+	r := SplitTransferRequest{
+		DestinationAccountID: AccountID{ID: 1},
+		Legs: []SplitLeg{
+			{AccountID: AccountID{ID: 2}, Amount: DecimalString{decimal.NewFromInt(10)}},
+			{AccountID: AccountID{ExternalID: "acme"}, Amount: DecimalString{decimal.NewFromInt(5)}},
+		},
+		Reference: "INV-42",
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ts := r.Transfers()
+	if ts[1].SourceAccountID.ExternalID != "acme" || ts[1].DestinationAccountID.ID != 1 || ts[1].Reference != "INV-42" {
+		t.Fatalf("unexpected transfers: %+v", ts)
+	}
+
+	r.SourceAccountID = AccountID{ID: 3}
+	if err := r.Validate(); err != ErrInvalidSplitSide {
+		t.Fatalf("expected ErrInvalidSplitSide, got %v", err)
+	}
+	r.DestinationAccountID = AccountID{}
+	r.Legs[1].AccountID = AccountID{ID: 3}
+	if err := r.Validate(); !errors.Is(err, ErrSameSourceDestination) {
+		t.Fatalf("expected ErrSameSourceDestination, got %v", err)
+	}
+	r.Legs = nil
+	if err := r.Validate(); err != ErrInvalidSplitSize {
+		t.Fatalf("expected ErrInvalidSplitSize, got %v", err)
+	}
+}
+
+func TestTransactionRequest_Validate_Details(t *testing.T) {
+	r := TransactionRequest{
+		SourceAccountID:      AccountID{ID: 1},
+		DestinationAccountID: AccountID{ID: 2},
+		Amount:               DecimalString{decimal.NewFromInt(10)},
+		Reference:            strings.Repeat("é", MaxReferenceLen),
+		PurposeCode:          "SALA",
+	}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.Reference += "x"
+	if err := r.Validate(); err != ErrReferenceTooLong {
+		t.Fatalf("expected ErrReferenceTooLong, got %v", err)
+	}
+
+	r.Reference = "INV-42"
+	for _, c := range []string{"sala", "SA LA", strings.Repeat("A", MaxPurposeCodeLen+1)} {
+		r.PurposeCode = c
+		if err := r.Validate(); err != ErrInvalidPurposeCode {
+			t.Fatalf("%q: expected ErrInvalidPurposeCode, got %v", c, err)
+		}
+	}
+
+	defer func(codes []string) { PurposeCodes = codes }(PurposeCodes)
+	PurposeCodes = []string{"PAYROLL", "SALA"}
+	r.PurposeCode = "SUPP"
+	if err := r.Validate(); err != ErrUnknownPurposeCode {
+		t.Fatalf("expected ErrUnknownPurposeCode, got %v", err)
+	}
+	r.PurposeCode = "SALA"
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParsePurposeCodes(t *testing.T) {
+	codes, err := ParsePurposeCodes(" REFUND, PAYROLL,,REFUND ")
+	if err != nil || strings.Join(codes, ",") != "PAYROLL,REFUND" {
+		t.Fatalf("expected PAYROLL,REFUND, got %v, %v", codes, err)
+	}
+	if codes, err := ParsePurposeCodes(""); err != nil || codes != nil {
+		t.Fatalf("expected no vocabulary, got %v, %v", codes, err)
+	}
+	if _, err := ParsePurposeCodes("PAYROLL,fee"); !errors.Is(err, ErrInvalidPurposeCode) {
+		t.Fatalf("expected ErrInvalidPurposeCode, got %v", err)
+	}
+}
+
+func TestDecimalString_Scale(t *testing.T) {
+	for s, want := range map[string]int32{"100": 0, "1.50": 1, "0.001": 3, "1.000": 0, "1E+3": 0} {
+		if got := (DecimalString{decimal.RequireFromString(s)}).Scale(); got != want {
+			t.Fatalf("%s: expected scale %d, got %d", s, want, got)
+		}
+	}
+}
+
+func TestAmountRules(t *testing.T) {
+	defer func(r AmountRules) { Amounts = r }(Amounts)
+	Amounts = AmountRules{DefaultScale: 2, Scales: map[string]int32{"JPY": 0}, Max: decimal.NewFromInt(1000)}
+
+	acc := CreateAccountRequest{AccountID: 1, InitialBalance: DecimalString{decimal.RequireFromString("10.5")}, Currency: "JPY"}
+	if err := acc.Validate(); !errors.Is(err, ErrAmountScale) {
+		t.Fatalf("expected ErrAmountScale for a JPY balance with decimals, got %v", err)
+	}
+	acc.Currency = ""
+	if err := acc.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := TransactionRequest{SourceAccountID: AccountID{ID: 1}, DestinationAccountID: AccountID{ID: 2}, Amount: DecimalString{decimal.RequireFromString("0.001")}}
+	if err := r.Validate(); !errors.Is(err, ErrAmountScale) {
+		t.Fatalf("expected ErrAmountScale for 0.001, got %v", err)
+	}
+	r.Amount = DecimalString{decimal.NewFromInt(1000)}
+	if err := r.Validate(); !errors.Is(err, ErrAmountTooLarge) {
+		t.Fatalf("expected ErrAmountTooLarge, got %v", err)
+	}
+	r.Amount = DecimalString{decimal.RequireFromString("999.99")}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Transfers take their currency from the accounts, so the largest scale applies
+	Amounts.Scales["BHD"] = 3
+	r.Amount = DecimalString{decimal.RequireFromString("0.001")}
+	if err := r.Validate(); err != nil {
+		t.Fatalf("unexpected error with a 3 decimal currency configured: %v", err)
+	}
+}
+
+func TestParseCurrencyScales(t *testing.T) {
+	scales, err := ParseCurrencyScales("JPY:0, BHD:3,")
+	if err != nil || len(scales) != 2 || scales["JPY"] != 0 || scales["BHD"] != 3 {
+		t.Fatalf("unexpected result: %v, %v", scales, err)
+	}
+	for _, s := range []string{"JPY", "jpy:0", "JPY:-1", "JPY:11", "JPY:x"} {
+		if _, err := ParseCurrencyScales(s); err == nil {
+			t.Fatalf("%q: expected error", s)
+		}
+	}
+}
+
+func TestAccountID_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    AccountID
+		wantErr bool
+	}{
+		{`100`, AccountID{ID: 100}, false},
+		{`"100"`, AccountID{ID: 100}, false},
+		{`"8f14e45f-ceea-467f-a0e6-1b5e6c7e4c3b"`, AccountID{ExternalID: "8f14e45f-ceea-467f-a0e6-1b5e6c7e4c3b"}, false},
+		{`"cust:42"`, AccountID{ExternalID: "cust:42"}, false},
+		{`"a b"`, AccountID{}, true},
+		{`""`, AccountID{}, true},
+		{`1.5`, AccountID{}, true},
+		{`"100/savings"`, AccountID{ID: 100, Wallet: "savings"}, false},
+		{`"cust:42/fx-eur"`, AccountID{ExternalID: "cust:42", Wallet: "fx-eur"}, false},
+		{`"100/Savings"`, AccountID{}, true},
+		{`"100/"`, AccountID{}, true},
+		{`"100/a/b"`, AccountID{}, true},
+		{`"/savings"`, AccountID{}, true},
+	}
+	for _, tt := range tests {
+		var id AccountID
+		err := json.Unmarshal([]byte(tt.in), &id)
+		if (err != nil) != tt.wantErr || id != tt.want {
+			t.Fatalf("%s: got %+v, %v", tt.in, id, err)
+		}
+	}
+
+	b, _ := json.Marshal(AccountID{ExternalID: "cust:42"})
+	if string(b) != `"cust:42"` {
+		t.Fatalf("expected external id marshalled as a string, got %s", b)
+	}
+	b, _ = json.Marshal(AccountID{ID: 100, Wallet: "savings"})
+	if string(b) != `"100/savings"` {
+		t.Fatalf("expected wallet marshalled as a string, got %s", b)
+	}
+}
+
+// TestErrorCodes tests that the catalog lists every ErrCode constant once,
+// with a description
+func TestErrorCodes(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), "types.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse types.go: %v", err)
+	}
+	consts := map[ErrorCode]bool{}
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || !strings.HasPrefix(spec.Names[0].Name, "ErrCode") {
+			return true
+		}
+		code, err := strconv.Unquote(spec.Values[0].(*ast.BasicLit).Value)
+		if err != nil {
+			t.Fatalf("%s: %v", spec.Names[0].Name, err)
+		}
+		consts[ErrorCode(code)] = true
+		return true
+	})
+
+	seen := map[ErrorCode]bool{}
+	for _, info := range ErrorCodes() {
+		switch {
+		case seen[info.Code]:
+			t.Errorf("%s is listed twice", info.Code)
+		case !consts[info.Code]:
+			t.Errorf("%s is not an ErrCode constant", info.Code)
+		case info.Description == "":
+			t.Errorf("%s has no description", info.Code)
+		}
+		seen[info.Code] = true
+	}
+	for code := range consts {
+		if !seen[code] {
+			t.Errorf("%s is missing from the catalog", code)
+		}
+	}
+}
@@ -2,6 +2,13 @@ package model
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/shopspring/decimal"
 )
@@ -11,22 +18,450 @@ var (
 	ErrInvalidInitialBalance = errors.New("initial_balance must be >= 0")
 	ErrInvalidAmount         = errors.New("amount must be > 0")
 	ErrSameSourceDestination = errors.New("source and destination must differ")
+	ErrMissingName           = errors.New("name is required")
+	ErrDisplayNameTooLong    = fmt.Errorf("display_name must be at most %d characters", MaxDisplayNameLen)
+	ErrOwnerRefTooLong       = fmt.Errorf("owner_ref must be at most %d characters", MaxOwnerRefLen)
+	ErrOwnerIDTooLong        = fmt.Errorf("owner_id must be at most %d characters", MaxOwnerIDLen)
+	ErrTooManyTags           = fmt.Errorf("at most %d tags are allowed", MaxTags)
+	ErrInvalidTag            = fmt.Errorf("tags must be non-empty and at most %d characters", MaxTagLen)
+	ErrEmptyUpdate           = errors.New("at least one field must be set")
+	ErrInvalidCurrency       = errors.New("currency must be a three-letter ISO 4217 code")
+	ErrInvalidBatchMode      = errors.New("mode must be atomic or best_effort")
+	ErrInvalidBatchSize      = fmt.Errorf("transfers must contain between 1 and %d items", MaxBatchTransfers)
+	ErrInvalidSplitSide      = errors.New("exactly one of source_account_id and destination_account_id is required")
+	ErrInvalidSplitSize      = fmt.Errorf("legs must contain between 1 and %d items", MaxBatchTransfers)
+	ErrInvalidAccountBatch   = fmt.Errorf("accounts must contain between 1 and %d items", MaxBatchAccounts)
+	ErrExecuteAtNotFuture    = errors.New("execute_at must be in the future")
+	ErrInvalidHoldExpiry     = fmt.Errorf("expires_in_seconds must be between 1 and %d", int64(MaxHoldExpiry/time.Second))
+	ErrInvalidLimit          = errors.New("limits must be > 0")
+	ErrInvalidMinBalance     = errors.New("min_balance must be >= 0")
+	ErrInvalidLowBalance     = errors.New("low_balance must be >= 0")
+	ErrInvalidLargeTransfer  = errors.New("large_transfer must be > 0")
+	ErrInvalidKYCStatus      = fmt.Errorf("kyc_status must be %s, %s or %s", KYCUnverified, KYCVerified, KYCBlocked)
+	ErrTooManyNetworks       = fmt.Errorf("allowed_networks must hold at most %d networks", MaxAllowedNetworks)
+	ErrInvalidCounterparties = fmt.Errorf("outgoing and incoming must hold at most %d positive account ids", MaxCounterparties)
+	ErrInvalidRateLimit      = errors.New("rate_limit_rps must be >= 0, and rate_limit_burst >= 1 unless rate limiting is disabled")
+	ErrInvalidLogLevel       = fmt.Errorf("log_level must be one of %s, %s, %s, %s", LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError)
+	ErrReferenceTooLong      = fmt.Errorf("reference must be at most %d characters", MaxReferenceLen)
+	ErrInvalidPurposeCode    = fmt.Errorf("purpose_code must be 1 to %d uppercase letters or digits", MaxPurposeCodeLen)
+	ErrUnknownPurposeCode    = errors.New("purpose_code is not one of the purpose codes listed at GET /v1/purpose-codes")
+	ErrInvalidAdjustmentType = errors.New("type must be credit or debit")
+	ErrMissingReason         = errors.New("reason is required")
+	ErrReasonTooLong         = fmt.Errorf("reason must be at most %d characters", MaxReasonLen)
+	ErrInvalidRuleKind       = errors.New("kind must be velocity, new_counterparty, amount or match")
+	ErrInvalidRuleAction     = errors.New("action must be block, flag, allow or require_approval")
+	ErrInvalidRuleAmount     = errors.New("min_amount must be >= 0")
+	ErrInvalidRuleMaxAmount  = errors.New("max_amount must be > 0 and >= min_amount")
+	ErrInvalidRuleHours      = errors.New("active_from and active_until must be given together as different HH:MM times")
+	ErrMissingRuleAmount     = errors.New("min_amount is required for amount rules")
+	ErrInvalidRuleVelocity   = fmt.Errorf("velocity rules require max_count > 0 and window_seconds between 1 and %d", MaxRuleWindowSeconds)
+	ErrInvalidFee            = errors.New("flat_fee and percentage must be >= 0, and one of them > 0")
+	ErrInvalidFeePercentage  = fmt.Errorf("percentage must be below 100 with at most %d decimal places", MaxFeePercentageScale)
+	ErrMissingFeeAccount     = errors.New("fee_account_id is required")
+	ErrMissingSweepAccounts  = errors.New("account_id and target_account_id are required")
+	ErrSweepSameAccount      = errors.New("target_account_id must differ from account_id")
+	ErrInvalidSweepThreshold = errors.New("threshold is required and must be >= 0")
+	ErrInvalidExternalID     = fmt.Errorf("external ids must be 1 to %d letters, digits, '.', '_', ':' or '-', and not only digits", MaxExternalIDLen)
+	ErrInvalidAlias          = fmt.Errorf("aliases must be 1 to %d letters, digits, '.', '_', ':' or '-', and not only digits", MaxExternalIDLen)
+	ErrInvalidWallet         = fmt.Errorf("wallet must be 1 to %d lowercase letters, digits, '_' or '-'", MaxWalletLen)
+	ErrAmountScale           = errors.New("too many decimal places")
+	ErrAmountTooLarge        = errors.New("too large")
+)
+
+// DefaultAmountScale is the decimal places of currencies without their own
+const DefaultAmountScale = 2
+
+// DefaultMaxAmount bounds amounts unless the server configures another maximum
+var DefaultMaxAmount = decimal.New(1, 15)
+
+// MaxAmountScale is the most decimal places stored for an amount
+const MaxAmountScale = 10
+
+// AmountRules bound the monetary amounts accepted in requests.
+type AmountRules struct {
+	DefaultScale int32            // decimal places of currencies not in Scales
+	Scales       map[string]int32 // decimal places by currency
+	Max          decimal.Decimal  // amounts must be below it
+}
+
+// Amounts are the rules Validate checks amounts against. The server sets them
+// from its configuration at startup.
+var Amounts = AmountRules{DefaultScale: DefaultAmountScale, Max: DefaultMaxAmount}
+
+// Scale returns the decimal places allowed in amounts of currency.
+func (r AmountRules) Scale(currency string) int32 {
+	if s, ok := r.Scales[currency]; ok {
+		return s
+	}
+	return r.DefaultScale
+}
+
+// maxScale returns the decimal places allowed in amounts whose currency is
+// not known yet: transfers take it from their accounts.
+func (r AmountRules) maxScale() int32 {
+	scale := r.DefaultScale
+	for _, s := range r.Scales {
+		scale = max(scale, s)
+	}
+	return scale
+}
+
+// check checks the amount in field against scale and the maximum
+func (r AmountRules) check(field string, d DecimalString, scale int32) error {
+	if d.Scale() > scale {
+		return fmt.Errorf("%s has %w (at most %d)", field, ErrAmountScale, scale)
+	}
+	if d.Abs().GreaterThanOrEqual(r.Max) {
+		return fmt.Errorf("%s is %w (must be below %s)", field, ErrAmountTooLarge, r.Max)
+	}
+	return nil
+}
+
+// ParseCurrencyScales parses the decimal places of currencies from a
+// comma-separated list of CODE:SCALE pairs, e.g. "JPY:0,BHD:3".
+func ParseCurrencyScales(s string) (map[string]int32, error) {
+	scales := make(map[string]int32)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		code, scale, ok := strings.Cut(pair, ":")
+		if !ok || !isCurrencyCode(code) {
+			return nil, fmt.Errorf("invalid currency scale %q, expected CODE:SCALE", pair)
+		}
+		n, err := strconv.ParseInt(scale, 10, 32)
+		if err != nil || n < 0 || n > MaxAmountScale {
+			return nil, fmt.Errorf("scale of %s must be between 0 and %d, got %q", code, MaxAmountScale, scale)
+		}
+		scales[code] = int32(n)
+	}
+	return scales, nil
+}
+
+// PurposeCodes is the vocabulary of purpose codes transfers may carry, sorted;
+// nil allows any well-formed code. The server sets it from its configuration.
+var PurposeCodes []string
+
+// ParsePurposeCodes parses a comma-separated list of purpose codes, e.g.
+// "PAYROLL,SETTLEMENT,FEE,REFUND", into a sorted vocabulary without
+// duplicates. An empty list returns nil.
+func ParsePurposeCodes(s string) ([]string, error) {
+	var codes []string
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		if !isPurposeCode(c) {
+			return nil, fmt.Errorf("invalid purpose code %q: %w", c, ErrInvalidPurposeCode)
+		}
+		codes = append(codes, c)
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes), nil
+}
+
+// Limits on account metadata
+const (
+	MaxDisplayNameLen = 200
+	MaxOwnerRefLen    = 200
+	MaxOwnerIDLen     = 200
+	MaxExternalIDLen  = 128
+	MaxWalletLen      = 64
+	MaxTags           = 20
+	MaxTagLen         = 64
+)
+
+// Limits on the details recorded with a transfer
+const (
+	MaxReferenceLen   = 140
+	MaxPurposeCodeLen = 35
+)
+
+// MaxReasonLen caps the reason recorded with a balance adjustment
+const MaxReasonLen = 500
+
+// MaxRuleWindowSeconds caps the window of velocity rules
+const MaxRuleWindowSeconds = 7 * 24 * 60 * 60
+
+// MaxFeePercentageScale is the most decimal places of a fee percentage
+const MaxFeePercentageScale = 6
+
+// MaxBatchTransfers caps the number of transfers in one batch request
+const MaxBatchTransfers = 100
+
+// MaxBatchAccounts caps the number of accounts in one batch request
+const MaxBatchAccounts = 1000
+
+// MaxCounterparties caps each counterparty allowlist of an account
+const MaxCounterparties = 1000
+
+// MaxAllowedNetworks caps the networks an API key is restricted to
+const MaxAllowedNetworks = 100
+
+// Limits on bulk transfer jobs
+const (
+	MaxJobRows     = 10000
+	MaxJobFailures = 1000
+)
+
+// Limits on account imports
+const (
+	MaxImportRows   = 1_000_000
+	MaxImportErrors = 100
+)
+
+// DefaultReportPeriod is the period of a report when its start is omitted
+const DefaultReportPeriod = 7 * 24 * time.Hour
+
+// Hold lifetimes
+const (
+	DefaultHoldExpiry = 7 * 24 * time.Hour
+	MaxHoldExpiry     = 30 * 24 * time.Hour
 )
 
 // ValidateCreateAccount validates CreateAccountRequest
 func (r *CreateAccountRequest) Validate() error {
-	if r.AccountID == 0 {
+	if r.AccountID == 0 && r.ExternalID == "" {
 		return ErrInvalidAccountID
 	}
+	if r.ExternalID != "" && !isExternalID(r.ExternalID) {
+		return ErrInvalidExternalID
+	}
 	if r.InitialBalance.IsNegative() {
 		return ErrInvalidInitialBalance
 	}
+	if r.Currency != "" && !isCurrencyCode(r.Currency) {
+		return ErrInvalidCurrency
+	}
+	currency := r.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if err := Amounts.check("initial_balance", r.InitialBalance, Amounts.Scale(currency)); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(r.OwnerID) > MaxOwnerIDLen {
+		return ErrOwnerIDTooLong
+	}
+	return validateMetadata(&r.DisplayName, &r.OwnerRef, &r.Tags)
+}
+
+// Validate validates CreateWalletRequest. Its currency being the parent
+// account's, initial_balance is checked against the largest scale, like
+// transfer amounts.
+func (r *CreateWalletRequest) Validate() error {
+	if !isWalletName(r.Wallet) {
+		return ErrInvalidWallet
+	}
+	if r.InitialBalance.IsNegative() {
+		return ErrInvalidInitialBalance
+	}
+	if err := Amounts.check("initial_balance", r.InitialBalance, Amounts.maxScale()); err != nil {
+		return err
+	}
+	return validateMetadata(&r.DisplayName, nil, &r.Tags)
+}
+
+// Validate validates the size of BatchCreateAccountRequest; its accounts are
+// validated one by one, so that each gets its own outcome.
+func (r *BatchCreateAccountRequest) Validate() error {
+	if len(r.Accounts) == 0 || len(r.Accounts) > MaxBatchAccounts {
+		return ErrInvalidAccountBatch
+	}
+	return nil
+}
+
+// Validate validates UpdateAccountRequest
+func (r *UpdateAccountRequest) Validate() error {
+	if r.DisplayName == nil && r.OwnerRef == nil && r.Tags == nil {
+		return ErrEmptyUpdate
+	}
+	return validateMetadata(r.DisplayName, r.OwnerRef, r.Tags)
+}
+
+// Validate validates SetOwnerRequest
+func (r *SetOwnerRequest) Validate() error {
+	if utf8.RuneCountInString(r.OwnerID) > MaxOwnerIDLen {
+		return ErrOwnerIDTooLong
+	}
+	return nil
+}
+
+// Validate validates TransferLimits
+func (r *TransferLimits) Validate() error {
+	for _, l := range []struct {
+		field string
+		value *DecimalString
+	}{{"max_transfer_amount", r.MaxTransferAmount}, {"daily_transfer_limit", r.DailyTransferLimit}} {
+		if l.value == nil {
+			continue
+		}
+		if !l.value.IsPositive() {
+			return ErrInvalidLimit
+		}
+		if err := Amounts.check(l.field, *l.value, Amounts.maxScale()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates SetAlertThresholdsRequest
+func (r *SetAlertThresholdsRequest) Validate() error {
+	if r.LowBalance != nil {
+		if r.LowBalance.IsNegative() {
+			return ErrInvalidLowBalance
+		}
+		if err := Amounts.check("low_balance", *r.LowBalance, Amounts.maxScale()); err != nil {
+			return err
+		}
+	}
+	if r.LargeTransfer != nil {
+		if !r.LargeTransfer.IsPositive() {
+			return ErrInvalidLargeTransfer
+		}
+		if err := Amounts.check("large_transfer", *r.LargeTransfer, Amounts.maxScale()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate validates SetKYCStatusRequest
+func (r *SetKYCStatusRequest) Validate() error {
+	switch r.KYCStatus {
+	case KYCUnverified, KYCVerified, KYCBlocked:
+		return nil
+	}
+	return ErrInvalidKYCStatus
+}
+
+// Validate validates SetCounterpartiesRequest
+func (r *SetCounterpartiesRequest) Validate() error {
+	for _, ids := range [][]int64{r.Outgoing, r.Incoming} {
+		if len(ids) > MaxCounterparties {
+			return ErrInvalidCounterparties
+		}
+		for _, id := range ids {
+			if id <= 0 {
+				return ErrInvalidCounterparties
+			}
+		}
+	}
+	return nil
+}
+
+// Validate validates SetMinBalanceRequest
+func (r *SetMinBalanceRequest) Validate() error {
+	if r.MinBalance == nil {
+		return nil
+	}
+	if r.MinBalance.IsNegative() {
+		return ErrInvalidMinBalance
+	}
+	return Amounts.check("min_balance", *r.MinBalance, Amounts.maxScale())
+}
+
+// isCurrencyCode reports whether c has the shape of an ISO 4217 code
+func isCurrencyCode(c string) bool {
+	if len(c) != 3 {
+		return false
+	}
+	for i := 0; i < len(c); i++ {
+		if c[i] < 'A' || c[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// isExternalID reports whether s can be an external account id: path-safe,
+// and distinguishable from a numeric id
+func isExternalID(s string) bool {
+	if len(s) == 0 || len(s) > MaxExternalIDLen {
+		return false
+	}
+	digits := true
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c == '-' && i == 0:
+			// could still be a negative numeric id
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '.', c == '_', c == ':', c == '-':
+			digits = false
+		default:
+			return false
+		}
+	}
+	return !digits
+}
+
+// ValidateAlias checks that s can be an account alias. Aliases are accepted
+// wherever external ids are, so they follow the same rules.
+func ValidateAlias(s string) error {
+	if !isExternalID(s) {
+		return ErrInvalidAlias
+	}
+	return nil
+}
+
+// isWalletName reports whether s can label a wallet: 1 to MaxWalletLen
+// lowercase letters, digits, '_' or '-'
+func isWalletName(s string) bool {
+	if len(s) == 0 || len(s) > MaxWalletLen {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// isPurposeCode reports whether c is a non-empty purpose code of at most
+// MaxPurposeCodeLen uppercase letters and digits
+func isPurposeCode(c string) bool {
+	if len(c) == 0 || len(c) > MaxPurposeCodeLen {
+		return false
+	}
+	for i := 0; i < len(c); i++ {
+		if (c[i] < 'A' || c[i] > 'Z') && (c[i] < '0' || c[i] > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateMetadata checks the non-nil metadata fields against the limits above
+func validateMetadata(displayName, ownerRef *string, tags *[]string) error {
+	if displayName != nil && utf8.RuneCountInString(*displayName) > MaxDisplayNameLen {
+		return ErrDisplayNameTooLong
+	}
+	if ownerRef != nil && utf8.RuneCountInString(*ownerRef) > MaxOwnerRefLen {
+		return ErrOwnerRefTooLong
+	}
+	if tags == nil {
+		return nil
+	}
+	if len(*tags) > MaxTags {
+		return ErrTooManyTags
+	}
+	for _, t := range *tags {
+		if strings.TrimSpace(t) == "" || utf8.RuneCountInString(t) > MaxTagLen {
+			return ErrInvalidTag
+		}
+	}
 	return nil
 }
 
 // ValidateTransaction validates TransactionRequest
 func (r *TransactionRequest) Validate() error {
-	if r.SourceAccountID == 0 || r.DestinationAccountID == 0 {
+	if r.SourceAccountID.IsZero() || r.DestinationAccountID.IsZero() {
 		return ErrInvalidAccountID
 	}
 	if r.SourceAccountID == r.DestinationAccountID {
@@ -35,5 +470,271 @@ func (r *TransactionRequest) Validate() error {
 	if !r.Amount.GreaterThan(decimal.Zero) {
 		return ErrInvalidAmount
 	}
+	if err := Amounts.check("amount", r.Amount, Amounts.maxScale()); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(r.Reference) > MaxReferenceLen {
+		return ErrReferenceTooLong
+	}
+	if r.PurposeCode != "" && !isPurposeCode(r.PurposeCode) {
+		return ErrInvalidPurposeCode
+	}
+	if r.PurposeCode != "" && PurposeCodes != nil && !slices.Contains(PurposeCodes, r.PurposeCode) {
+		return ErrUnknownPurposeCode
+	}
+	return nil
+}
+
+// Validate validates AdjustmentRequest
+func (r *AdjustmentRequest) Validate() error {
+	if r.Type != AdjustmentCredit && r.Type != AdjustmentDebit {
+		return ErrInvalidAdjustmentType
+	}
+	if !r.Amount.GreaterThan(decimal.Zero) {
+		return ErrInvalidAmount
+	}
+	if err := Amounts.check("amount", r.Amount, Amounts.maxScale()); err != nil {
+		return err
+	}
+	if strings.TrimSpace(r.Reason) == "" {
+		return ErrMissingReason
+	}
+	if utf8.RuneCountInString(r.Reason) > MaxReasonLen {
+		return ErrReasonTooLong
+	}
+	return nil
+}
+
+// Validate validates EraseAccountRequest
+func (r *EraseAccountRequest) Validate() error {
+	if strings.TrimSpace(r.Reason) == "" {
+		return ErrMissingReason
+	}
+	if utf8.RuneCountInString(r.Reason) > MaxReasonLen {
+		return ErrReasonTooLong
+	}
+	return nil
+}
+
+// Validate validates CreateRuleRequest
+func (r *CreateRuleRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrMissingName
+	}
+	switch r.Kind {
+	case RuleVelocity:
+		if r.MaxCount <= 0 || r.WindowSeconds <= 0 || r.WindowSeconds > MaxRuleWindowSeconds {
+			return ErrInvalidRuleVelocity
+		}
+	case RuleAmount:
+		if r.MinAmount == nil {
+			return ErrMissingRuleAmount
+		}
+	case RuleNewCounterparty, RuleMatch:
+	default:
+		return ErrInvalidRuleKind
+	}
+	switch r.Action {
+	case RuleBlock, RuleFlag, RuleAllow, RuleRequireApproval:
+	default:
+		return ErrInvalidRuleAction
+	}
+	if r.SourceAccountID < 0 || r.DestinationAccountID < 0 {
+		return ErrInvalidAccountID
+	}
+	if r.MinAmount != nil {
+		if r.MinAmount.IsNegative() {
+			return ErrInvalidRuleAmount
+		}
+		if err := Amounts.check("min_amount", *r.MinAmount, Amounts.maxScale()); err != nil {
+			return err
+		}
+	}
+	if r.MaxAmount != nil {
+		if !r.MaxAmount.IsPositive() || (r.MinAmount != nil && r.MaxAmount.LessThan(r.MinAmount.Decimal)) {
+			return ErrInvalidRuleMaxAmount
+		}
+		if err := Amounts.check("max_amount", *r.MaxAmount, Amounts.maxScale()); err != nil {
+			return err
+		}
+	}
+	if r.ActiveFrom != "" || r.ActiveUntil != "" {
+		from, err1 := ParseTimeOfDay(r.ActiveFrom)
+		until, err2 := ParseTimeOfDay(r.ActiveUntil)
+		if err1 != nil || err2 != nil || from == until {
+			return ErrInvalidRuleHours
+		}
+	}
+	return nil
+}
+
+// ParseTimeOfDay parses an HH:MM time of day into its offset from midnight
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate validates CreateFeePolicyRequest
+func (r *CreateFeePolicyRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrMissingName
+	}
+	if !isCurrencyCode(r.Currency) {
+		return ErrInvalidCurrency
+	}
+	if r.AccountTag != "" && utf8.RuneCountInString(r.AccountTag) > MaxTagLen {
+		return ErrInvalidTag
+	}
+	if r.FeeAccountID <= 0 {
+		return ErrMissingFeeAccount
+	}
+	var flat, pct decimal.Decimal
+	if r.FlatFee != nil {
+		flat = r.FlatFee.Decimal
+	}
+	if r.Percentage != nil {
+		pct = r.Percentage.Decimal
+	}
+	if flat.IsNegative() || pct.IsNegative() || (flat.IsZero() && pct.IsZero()) {
+		return ErrInvalidFee
+	}
+	if r.FlatFee != nil {
+		if err := Amounts.check("flat_fee", *r.FlatFee, Amounts.Scale(r.Currency)); err != nil {
+			return err
+		}
+	}
+	if r.Percentage != nil && (pct.GreaterThanOrEqual(decimal.NewFromInt(100)) || r.Percentage.Scale() > MaxFeePercentageScale) {
+		return ErrInvalidFeePercentage
+	}
+	return nil
+}
+
+// Validate validates CreateSweepRuleRequest
+func (r *CreateSweepRuleRequest) Validate() error {
+	if r.AccountID <= 0 || r.TargetAccountID <= 0 {
+		return ErrMissingSweepAccounts
+	}
+	if r.AccountID == r.TargetAccountID {
+		return ErrSweepSameAccount
+	}
+	return validateSweepThreshold(r.Threshold)
+}
+
+// Validate validates UpdateSweepRuleRequest
+func (r *UpdateSweepRuleRequest) Validate() error {
+	if r.TargetAccountID <= 0 {
+		return ErrMissingSweepAccounts
+	}
+	return validateSweepThreshold(r.Threshold)
+}
+
+// validateSweepThreshold checks the threshold of a sweep rule
+func validateSweepThreshold(t *DecimalString) error {
+	if t == nil || t.IsNegative() {
+		return ErrInvalidSweepThreshold
+	}
+	return Amounts.check("threshold", *t, Amounts.maxScale())
+}
+
+// Validate validates BatchTransferRequest and each of its transfers
+func (r *BatchTransferRequest) Validate() error {
+	if r.Mode != BatchModeAtomic && r.Mode != BatchModeBestEffort {
+		return ErrInvalidBatchMode
+	}
+	if len(r.Transfers) == 0 || len(r.Transfers) > MaxBatchTransfers {
+		return ErrInvalidBatchSize
+	}
+	for i := range r.Transfers {
+		if err := r.Transfers[i].Validate(); err != nil {
+			return fmt.Errorf("transfers[%d]: %w", i, err)
+		}
+	}
 	return nil
 }
+
+// Validate validates SplitTransferRequest and each of its legs
+func (r *SplitTransferRequest) Validate() error {
+	if r.SourceAccountID.IsZero() == r.DestinationAccountID.IsZero() {
+		return ErrInvalidSplitSide
+	}
+	if len(r.Legs) == 0 || len(r.Legs) > MaxBatchTransfers {
+		return ErrInvalidSplitSize
+	}
+	for i, t := range r.Transfers() {
+		if err := t.Validate(); err != nil {
+			return fmt.Errorf("legs[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Validate validates CreateClearingTransferRequest
+func (r *CreateClearingTransferRequest) Validate() error {
+	t := TransactionRequest{SourceAccountID: r.SourceAccountID, DestinationAccountID: r.DestinationAccountID, Amount: r.Amount, Reference: r.Reference}
+	return t.Validate()
+}
+
+// Validate validates CreateHoldRequest
+func (r *CreateHoldRequest) Validate() error {
+	t := TransactionRequest{SourceAccountID: r.SourceAccountID, DestinationAccountID: r.DestinationAccountID, Amount: r.Amount}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if r.ExpiresInSeconds < 0 || r.ExpiresInSeconds > int64(MaxHoldExpiry/time.Second) {
+		return ErrInvalidHoldExpiry
+	}
+	return nil
+}
+
+// Expiry returns how long the requested hold stays pending
+func (r *CreateHoldRequest) Expiry() time.Duration {
+	if r.ExpiresInSeconds == 0 {
+		return DefaultHoldExpiry
+	}
+	return time.Duration(r.ExpiresInSeconds) * time.Second
+}
+
+// Validate validates CreateScheduledTransferRequest against the system clock
+func (r *CreateScheduledTransferRequest) Validate() error {
+	return r.ValidateAt(time.Now())
+}
+
+// ValidateAt validates CreateScheduledTransferRequest, requiring execute_at to
+// be after now
+func (r *CreateScheduledTransferRequest) ValidateAt(now time.Time) error {
+	t := TransactionRequest{SourceAccountID: r.SourceAccountID, DestinationAccountID: r.DestinationAccountID, Amount: r.Amount}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if !r.ExecuteAt.After(now) {
+		return ErrExecuteAtNotFuture
+	}
+	return nil
+}
+
+// Validate validates CreateAPIKeyRequest; the role itself is checked by the auth package
+func (r *CreateAPIKeyRequest) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return ErrMissingName
+	}
+	if len(r.AllowedNetworks) > MaxAllowedNetworks {
+		return ErrTooManyNetworks
+	}
+	return nil
+}
+
+// Validate validates RuntimeConfig
+func (r *RuntimeConfig) Validate() error {
+	if r.RateLimitRPS < 0 || math.IsNaN(r.RateLimitRPS) || math.IsInf(r.RateLimitRPS, 0) || r.RateLimitBurst < 0 || r.RateLimitRPS > 0 && r.RateLimitBurst == 0 {
+		return ErrInvalidRateLimit
+	}
+	switch r.LogLevel {
+	case LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError:
+	default:
+		return ErrInvalidLogLevel
+	}
+	return r.TransferLimits.Validate()
+}
@@ -0,0 +1,71 @@
+// Package email sends notifications by email through an SMTP server.
+package email
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/you/internal-transfers/internal/notify"
+)
+
+// Config is the SMTP server and the addresses of the emails.
+type Config struct {
+	Addr     string // host:port of the SMTP server
+	Username string // PLAIN authentication unless empty
+	Password string
+	From     string
+	To       []string
+}
+
+// Notifier emails each message to the recipients of its Config. Servers
+// supporting STARTTLS are used over TLS.
+type Notifier struct {
+	cfg  Config
+	auth smtp.Auth
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+	now  func() time.Time
+}
+
+var _ notify.Notifier = (*Notifier)(nil)
+
+// New creates a Notifier sending through cfg.Addr.
+func New(cfg Config) *Notifier {
+	n := &Notifier{cfg: cfg, send: smtp.SendMail, now: time.Now}
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.Addr)
+		n.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return n
+}
+
+// Notify implements notify.Notifier. net/smtp takes no context, so a
+// cancelled ctx only stops a message before it is sent.
+func (n *Notifier) Notify(ctx context.Context, m notify.Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := n.send(n.cfg.Addr, n.auth, n.cfg.From, n.cfg.To, n.message(m)); err != nil {
+		return fmt.Errorf("send email: %w", err)
+	}
+	return nil
+}
+
+// message formats m as a plain text email
+func (n *Notifier) message(m notify.Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Text, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
@@ -0,0 +1,56 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/internal-transfers/internal/notify"
+)
+
+// TestNotifier tests the email sent for a message and its errors
+func TestNotifier(t *testing.T) {
+	n := New(Config{Addr: "smtp.example.com:587", Username: "ops", Password: "s3cret",
+		From: "transfers@example.com", To: []string{"ops@example.com", "oncall@example.com"}})
+	n.now = func() time.Time { return time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC) }
+	var sent []string
+	var sendErr error
+	n.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		if addr != "smtp.example.com:587" || a == nil || from != "transfers@example.com" || len(to) != 2 {
+			t.Errorf("unexpected envelope %s %v %s %v", addr, a, from, to)
+		}
+		sent = append(sent, string(msg))
+		return sendErr
+	}
+	m := notify.Message{Kind: notify.KindReconciliation, Subject: "Reconciliation 7: 2 accounts disagree", Text: "account 1\naccount 2"}
+
+	if err := n.Notify(context.Background(), m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 email, got %d", len(sent))
+	}
+	for _, want := range []string{
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Subject: Reconciliation 7: 2 accounts disagree\r\n",
+		"Date: Tue, 05 Mar 2024 12:00:00 +0000\r\n",
+		"\r\n\r\naccount 1\r\naccount 2\r\n",
+	} {
+		if !strings.Contains(sent[0], want) {
+			t.Fatalf("expected %q in email:\n%s", want, sent[0])
+		}
+	}
+
+	sendErr = errors.New("535 authentication failed")
+	if err := n.Notify(context.Background(), m); !errors.Is(err, sendErr) {
+		t.Fatalf("expected the send error, got %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := n.Notify(ctx, m); !errors.Is(err, context.Canceled) || len(sent) != 2 {
+		t.Fatalf("expected nothing sent once cancelled, got %v", err)
+	}
+}
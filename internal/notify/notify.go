@@ -0,0 +1,166 @@
+// Package notify tells operators about events that need their attention,
+// such as alerts and failed reconciliations, through channels like email or
+// Slack.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of Message, by what needs attention
+const (
+	KindAlert          = "alert"          // an account alert was triggered
+	KindReconciliation = "reconciliation" // reconciliation found accounts disagreeing with the ledger
+	KindDeadLetter     = "dead_letter"    // outbox events were dead-lettered
+	KindReview         = "review"         // transfers flagged by a rule await approval
+)
+
+// Kinds lists the kinds of Message
+var Kinds = []string{KindAlert, KindReconciliation, KindDeadLetter, KindReview}
+
+// Message is a notification: a one-line Subject and a plain text Text.
+type Message struct {
+	Kind    string
+	Subject string
+	Text    string
+}
+
+// Notifier delivers messages over a channel.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+// Func adapts a function to a Notifier
+type Func func(ctx context.Context, m Message) error
+
+// Notify calls f
+func (f Func) Notify(ctx context.Context, m Message) error {
+	return f(ctx, m)
+}
+
+// Router delivers each message to the notifiers routed its kind; messages of
+// other kinds are dropped.
+type Router struct {
+	routes map[string][]Notifier
+}
+
+var _ Notifier = (*Router)(nil)
+
+// ParseRoutes routes the kinds to the named channels as configured by s, a
+// comma separated list of KIND:CHANNEL pairs such as
+// "alert:slack,alert:email,dead_letter:email". An empty s routes every kind
+// to every channel.
+func ParseRoutes(s string, channels map[string]Notifier) (*Router, error) {
+	r := &Router{routes: make(map[string][]Notifier)}
+	if strings.TrimSpace(s) == "" {
+		names := make([]string, 0, len(channels))
+		for name := range channels {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, kind := range Kinds {
+			for _, name := range names {
+				r.routes[kind] = append(r.routes[kind], channels[name])
+			}
+		}
+		return r, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kind, name, ok := strings.Cut(pair, ":")
+		if !ok || !slices.Contains(Kinds, kind) {
+			return nil, fmt.Errorf("invalid route %q, expected KIND:CHANNEL with KIND one of %s", pair, strings.Join(Kinds, ", "))
+		}
+		n, ok := channels[name]
+		if !ok {
+			return nil, fmt.Errorf("route %q names a channel that is not configured", pair)
+		}
+		r.routes[kind] = append(r.routes[kind], n)
+	}
+	return r, nil
+}
+
+// Notify implements Notifier, delivering m to every notifier of its kind even
+// if some fail.
+func (r *Router) Notify(ctx context.Context, m Message) error {
+	var errs []error
+	for _, n := range r.routes[m.Kind] {
+		if err := n.Notify(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Routed reports whether messages of kind go anywhere.
+func (r *Router) Routed(kind string) bool {
+	return len(r.routes[kind]) > 0
+}
+
+// DefaultTimeout bounds each delivery of an Async notifier
+const DefaultTimeout = 30 * time.Second
+
+// ErrQueueFull is returned by Async.Notify when the queue is full; the
+// message is dropped.
+var ErrQueueFull = errors.New("notification queue full")
+
+// Async delivers messages to a Notifier in the background, in order, so that
+// callers are not held up by slow channels. Delivery errors are logged.
+type Async struct {
+	n     Notifier
+	queue chan Message
+	done  chan struct{}
+	once  sync.Once
+}
+
+var _ Notifier = (*Async)(nil)
+
+// NewAsync starts delivering to n, keeping up to size messages waiting.
+func NewAsync(n Notifier, size int) *Async {
+	a := &Async{n: n, queue: make(chan Message, size), done: make(chan struct{})}
+	go a.run()
+	return a
+}
+
+func (a *Async) run() {
+	defer close(a.done)
+	for m := range a.queue {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+		if err := a.n.Notify(ctx, m); err != nil {
+			log.Printf("notify %s %q failed: %v", m.Kind, m.Subject, err)
+		}
+		cancel()
+	}
+}
+
+// Notify implements Notifier, queueing m. It fails with ErrQueueFull rather
+// than wait when the queue is full, and must not be called after Close.
+func (a *Async) Notify(ctx context.Context, m Message) error {
+	select {
+	case a.queue <- m:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close delivers the messages waiting and stops, giving up when ctx is done.
+func (a *Async) Close(ctx context.Context) error {
+	a.once.Do(func() { close(a.queue) })
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
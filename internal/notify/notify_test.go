@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// recorder is a Notifier keeping the subjects of the messages it is given
+type recorder struct {
+	subjects []string
+	err      error
+}
+
+func (r *recorder) Notify(ctx context.Context, m Message) error {
+	r.subjects = append(r.subjects, m.Subject)
+	return r.err
+}
+
+// TestParseRoutes tests routing kinds to channels, by default and as configured
+func TestParseRoutes(t *testing.T) {
+	email, slack := &recorder{}, &recorder{err: errors.New("slack down")}
+	channels := map[string]Notifier{"email": email, "slack": slack}
+
+	r, err := ParseRoutes("", channels)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, kind := range Kinds {
+		if !r.Routed(kind) {
+			t.Fatalf("expected %s routed by default", kind)
+		}
+	}
+	if err := r.Notify(context.Background(), Message{Kind: KindAlert, Subject: "a"}); err == nil {
+		t.Fatal("expected the slack error")
+	}
+	if len(email.subjects) != 1 || len(slack.subjects) != 1 {
+		t.Fatalf("expected every channel notified despite the error, got %v and %v", email.subjects, slack.subjects)
+	}
+
+	r, err = ParseRoutes(" alert:email, dead_letter:slack,dead_letter:email", channels)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r.Routed(KindReconciliation) || r.Routed(KindReview) {
+		t.Fatal("expected only the configured kinds routed")
+	}
+	if err := r.Notify(context.Background(), Message{Kind: KindAlert, Subject: "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.Notify(context.Background(), Message{Kind: KindReview, Subject: "c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(email.subjects) != 2 || email.subjects[1] != "b" || len(slack.subjects) != 1 {
+		t.Fatalf("unexpected deliveries %v and %v", email.subjects, slack.subjects)
+	}
+
+	for _, s := range []string{"alert", "fraud:email", "alert:pager"} {
+		if _, err := ParseRoutes(s, channels); err == nil {
+			t.Fatalf("%q: expected an error", s)
+		}
+	}
+}
+
+// TestAsync tests that queued messages are delivered in order, and all of
+// them by Close
+func TestAsync(t *testing.T) {
+	rec := &recorder{err: errors.New("logged")}
+	block := make(chan struct{})
+	a := NewAsync(Func(func(ctx context.Context, m Message) error {
+		<-block
+		return rec.Notify(ctx, m)
+	}), 2)
+
+	// The first message is taken off the queue and blocks, two wait
+	for _, s := range []string{"1", "2", "3"} {
+		if err := a.Notify(context.Background(), Message{Subject: s}); err != nil {
+			t.Fatalf("message %s: unexpected error: %v", s, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := a.Notify(context.Background(), Message{Subject: "4"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	close(block)
+	if err := a.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rec.subjects) != 3 || rec.subjects[0] != "1" || rec.subjects[2] != "3" {
+		t.Fatalf("unexpected deliveries %v", rec.subjects)
+	}
+}
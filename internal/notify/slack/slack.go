@@ -0,0 +1,63 @@
+// Package slack posts notifications to a Slack incoming webhook.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/you/internal-transfers/internal/notify"
+)
+
+// DefaultTimeout bounds each post
+const DefaultTimeout = 10 * time.Second
+
+// Notifier posts each message to an incoming webhook URL, which decides the
+// channel it appears in.
+type Notifier struct {
+	url    string
+	client *http.Client
+}
+
+var _ notify.Notifier = (*Notifier)(nil)
+
+// New creates a Notifier posting to the incoming webhook url.
+func New(url string) *Notifier {
+	return &Notifier{url: url, client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// Payload is the body of a post
+type Payload struct {
+	Text string `json:"text"`
+}
+
+// Notify implements notify.Notifier, posting the subject in bold above the text.
+func (n *Notifier) Notify(ctx context.Context, m notify.Message) error {
+	text := "*" + m.Subject + "*"
+	if m.Text != "" {
+		text += "\n" + m.Text
+	}
+	body, err := json.Marshal(Payload{Text: text})
+	if err != nil {
+		return fmt.Errorf("slack marshal: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack post: %w", err)
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack post: status %d: %s", resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/notify"
+)
+
+// TestNotifier tests that messages are posted as text and that errors of the
+// webhook are reported
+func TestNotifier(t *testing.T) {
+	status := http.StatusOK
+	var got []Payload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		got = append(got, p)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("invalid_token"))
+	}))
+	defer srv.Close()
+	n := New(srv.URL)
+	m := notify.Message{Kind: notify.KindAlert, Subject: "Low balance on account 1", Text: "Available 42 is below 100"}
+
+	if err := n.Notify(context.Background(), m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Text != "*Low balance on account 1*\nAvailable 42 is below 100" {
+		t.Fatalf("unexpected posts %+v", got)
+	}
+
+	status = http.StatusForbidden
+	if err := n.Notify(context.Background(), m); err == nil {
+		t.Fatal("expected an error")
+	}
+}
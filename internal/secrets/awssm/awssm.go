@@ -0,0 +1,184 @@
+// Package awssm resolves secrets from AWS Secrets Manager. Requests are signed
+// with Signature Version 4 using static credentials, such as those of the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN variables.
+package awssm
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/you/internal-transfers/internal/secrets"
+)
+
+// DefaultTimeout bounds each request to Secrets Manager
+const DefaultTimeout = 10 * time.Second
+
+const (
+	service    = "secretsmanager"
+	target     = "secretsmanager.GetSecretValue"
+	algorithm  = "AWS4-HMAC-SHA256"
+	amzDateFmt = "20060102T150405Z"
+)
+
+// Credentials sign the requests of a Provider
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // of temporary credentials, empty otherwise
+}
+
+// Provider reads the secret string of secrets. A name is the secret id (its
+// name or ARN), or "<secret id>#<key>" for the field key of a secret string
+// holding a JSON object.
+type Provider struct {
+	region   string
+	endpoint string
+	creds    Credentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+var _ secrets.Provider = (*Provider)(nil)
+
+// New returns a Provider for region. endpoint overrides the regional endpoint
+// of the service when not empty, e.g. for a VPC endpoint or a local emulator.
+func New(region, endpoint string, creds Credentials) *Provider {
+	if endpoint == "" {
+		endpoint = "https://secretsmanager." + region + ".amazonaws.com"
+	}
+	return &Provider{
+		region:   region,
+		endpoint: strings.TrimRight(endpoint, "/") + "/",
+		creds:    creds,
+		client:   &http.Client{Timeout: DefaultTimeout},
+		now:      time.Now,
+	}
+}
+
+// Get implements secrets.Provider.
+func (p *Provider) Get(ctx context.Context, name string) (string, error) {
+	id, key := secrets.SplitKey(name)
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("secrets manager request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	p.sign(req, body)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets manager get %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("secrets manager get %s: %w", id, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Type string `json:"__type"`
+		}
+		_ = json.Unmarshal(raw, &apiErr)
+		if strings.HasSuffix(apiErr.Type, "ResourceNotFoundException") {
+			return "", fmt.Errorf("secrets manager get %s: %w", id, secrets.ErrNotFound)
+		}
+		return "", fmt.Errorf("secrets manager get %s: status %d %s", id, resp.StatusCode, apiErr.Type)
+	}
+
+	var out struct {
+		SecretString string
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return "", fmt.Errorf("secrets manager get %s: %w", id, err)
+	}
+	if key == "" {
+		if out.SecretString == "" {
+			return "", fmt.Errorf("secrets manager get %s: no secret string: %w", id, secrets.ErrNotFound)
+		}
+		return out.SecretString, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(out.SecretString), &fields); err != nil {
+		return "", fmt.Errorf("secrets manager get %s: secret string is not a JSON object", id)
+	}
+	var v string
+	if f, ok := fields[key]; !ok || json.Unmarshal(f, &v) != nil || v == "" {
+		return "", fmt.Errorf("secrets manager get %s: key %q: %w", id, key, secrets.ErrNotFound)
+	}
+	return v, nil
+}
+
+// sign adds the Signature Version 4 headers of req, whose body is body.
+func (p *Provider) sign(req *http.Request, body []byte) {
+	now := p.now().UTC()
+	amzDate := now.Format(amzDateFmt)
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if p.creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.creds.SessionToken)
+	}
+
+	// Canonical headers, sorted by name
+	headers := []string{"content-type", "host", "x-amz-date"}
+	if p.creds.SessionToken != "" {
+		headers = append(headers, "x-amz-security-token")
+	}
+	headers = append(headers, "x-amz-target")
+	var canonical strings.Builder
+	for _, h := range headers {
+		v := req.Header.Get(h)
+		if h == "host" {
+			v = req.URL.Host
+		}
+		canonical.WriteString(h + ":" + strings.TrimSpace(v) + "\n")
+	}
+	signed := strings.Join(headers, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method, path, canonicalQuery(req.URL), canonical.String(), signed, hexSHA256(body),
+	}, "\n")
+
+	scope := date + "/" + p.region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{algorithm, amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+	key := hmacSHA256([]byte("AWS4"+p.creds.SecretAccessKey), date)
+	for _, part := range []string{p.region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, p.creds.AccessKeyID, scope, signed, signature))
+}
+
+// canonicalQuery returns the query of u sorted as Signature Version 4 expects.
+func canonicalQuery(u *url.URL) string {
+	return strings.ReplaceAll(u.Query().Encode(), "+", "%20")
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,88 @@
+package awssm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/you/internal-transfers/internal/secrets"
+)
+
+var testCreds = Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+// TestSign tests the Signature Version 4 of a GetSecretValue request
+func TestSign(t *testing.T) {
+	p := New("us-east-1", "", testCreds)
+	p.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+	body := []byte(`{"SecretId":"x"}`)
+	req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	p.sign(req, body)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/secretsmanager/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date;x-amz-target, " +
+		"Signature=892def2dd39c6d99e79ad6c340b564ed05208995e7b75e1096380cbe57a57396"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Fatalf("unexpected X-Amz-Date %s", got)
+	}
+}
+
+// TestProvider tests reading secret strings and their JSON fields
+func TestProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != target || !strings.HasPrefix(r.Header.Get("Authorization"), algorithm+" Credential=AKIDEXAMPLE/") ||
+			r.Header.Get("X-Amz-Security-Token") != "session" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		var in struct{ SecretId string }
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		switch in.SecretId {
+		case "transfers/dsn":
+			w.Write([]byte(`{"Name":"transfers/dsn","SecretString":"postgres://aws"}`))
+		case "transfers":
+			w.Write([]byte(`{"Name":"transfers","SecretString":"{\"postgres_dsn\":\"postgres://json\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException","Message":"not found"}`))
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	creds := testCreds
+	creds.SessionToken = "session"
+	p := New("eu-west-1", srv.URL, creds)
+
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"transfers/dsn", "postgres://aws", nil},
+		{"transfers#postgres_dsn", "postgres://json", nil},
+		{"transfers#password", "", secrets.ErrNotFound},
+		{"missing", "", secrets.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := p.Get(ctx, tt.name)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Fatalf("%s: expected %q, %v, got %q, %v", tt.name, tt.want, tt.err, got, err)
+		}
+	}
+	if _, err := p.Get(ctx, "transfers/dsn#postgres_dsn"); err == nil {
+		t.Fatal("expected a key of a plain secret string to fail")
+	}
+}
@@ -0,0 +1,50 @@
+// Package secrets defines the Provider that resolves secrets, such as the
+// database DSN, kept out of the environment: in files mounted by Docker or
+// Kubernetes, or in a secret manager.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by a Provider for a secret it does not hold
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves secrets by name. Values may change between calls when the
+// secret is rotated, so callers fetch them again to pick up a new one.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// Files is a Provider reading each secret from the file it names, as mounted
+// by Docker and Kubernetes secrets. Trailing newlines are dropped.
+type Files struct{}
+
+var _ Provider = Files{}
+
+// Get implements Provider.
+func (Files) Get(_ context.Context, name string) (string, error) {
+	b, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", err
+	}
+	v := strings.TrimRight(string(b), "\r\n")
+	if v == "" {
+		return "", fmt.Errorf("secret file %s is empty", name)
+	}
+	return v, nil
+}
+
+// SplitKey splits a name of the form "<secret>#<key>", naming a field of a
+// secret holding several, into its parts. key is empty if name has none.
+func SplitKey(name string) (secret, key string) {
+	secret, key, _ = strings.Cut(name, "#")
+	return secret, key
+}
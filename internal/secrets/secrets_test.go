@@ -0,0 +1,32 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFiles tests that secrets are read from files without their trailing
+// newline
+func TestFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dsn")
+	if err := os.WriteFile(path, []byte("postgres://u:p@db/transfers\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := (Files{}).Get(ctx, path); err != nil || v != "postgres://u:p@db/transfers" {
+		t.Fatalf("unexpected secret %q, %v", v, err)
+	}
+	if _, err := (Files{}).Get(ctx, filepath.Join(dir, "missing")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := os.WriteFile(path, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Files{}).Get(ctx, path); err == nil {
+		t.Fatal("expected an empty file to be rejected")
+	}
+}
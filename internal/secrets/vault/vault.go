@@ -0,0 +1,82 @@
+// Package vault resolves secrets from HashiCorp Vault through its HTTP API.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/you/internal-transfers/internal/secrets"
+)
+
+// DefaultTimeout bounds each request to Vault
+const DefaultTimeout = 10 * time.Second
+
+// Provider reads secrets of a KV secrets engine, version 1 or 2. A name is
+// "<path>#<key>", such as "secret/data/transfers#postgres_dsn" for KV v2: the
+// field key of the secret read from /v1/<path>.
+type Provider struct {
+	addr   string
+	token  string
+	client *http.Client
+}
+
+var _ secrets.Provider = (*Provider)(nil)
+
+// New returns a Provider for the Vault server at addr, such as
+// "https://vault:8200", authenticating with token.
+func New(addr, token string) *Provider {
+	return &Provider{addr: strings.TrimRight(addr, "/"), token: token, client: &http.Client{Timeout: DefaultTimeout}}
+}
+
+// readResponse is the body of a read; KV v2 nests the fields in data.data
+type readResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+// Get implements secrets.Provider.
+func (p *Provider) Get(ctx context.Context, name string) (string, error) {
+	path, key := secrets.SplitKey(name)
+	if path == "" || key == "" {
+		return "", fmt.Errorf("vault secret %q must be <path>#<key>", name)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return "", fmt.Errorf("vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault read %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return "", fmt.Errorf("vault read %s: %w", path, secrets.ErrNotFound)
+	default:
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("vault read %s: status %d", path, resp.StatusCode)
+	}
+
+	var body readResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("vault read %s: %w", path, err)
+	}
+	fields := body.Data
+	if nested, ok := fields["data"]; ok && fields["metadata"] != nil {
+		fields = nil
+		if err := json.Unmarshal(nested, &fields); err != nil {
+			return "", fmt.Errorf("vault read %s: %w", path, err)
+		}
+	}
+	var v string
+	if raw, ok := fields[key]; !ok || json.Unmarshal(raw, &v) != nil || v == "" {
+		return "", fmt.Errorf("vault read %s: key %q: %w", path, key, secrets.ErrNotFound)
+	}
+	return v, nil
+}
@@ -0,0 +1,55 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/secrets"
+)
+
+// TestProvider tests reading the fields of KV v1 and v2 secrets
+func TestProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "t0ken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/transfers":
+			w.Write([]byte(`{"data":{"data":{"postgres_dsn":"postgres://v2"},"metadata":{"version":3}}}`))
+		case "/v1/kv/transfers":
+			w.Write([]byte(`{"data":{"postgres_dsn":"postgres://v1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	p := New(srv.URL+"/", "t0ken")
+
+	tests := []struct {
+		name string
+		want string
+		err  error
+	}{
+		{"secret/data/transfers#postgres_dsn", "postgres://v2", nil},
+		{"kv/transfers#postgres_dsn", "postgres://v1", nil},
+		{"secret/data/transfers#password", "", secrets.ErrNotFound},
+		{"secret/data/missing#postgres_dsn", "", secrets.ErrNotFound},
+	}
+	for _, tt := range tests {
+		got, err := p.Get(ctx, tt.name)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Fatalf("%s: expected %q, %v, got %q, %v", tt.name, tt.want, tt.err, got, err)
+		}
+	}
+	if _, err := p.Get(ctx, "secret/data/transfers"); err == nil {
+		t.Fatal("expected a name without a key to be rejected")
+	}
+	if _, err := New(srv.URL, "wrong").Get(ctx, "kv/transfers#postgres_dsn"); err == nil {
+		t.Fatal("expected a rejected token to fail")
+	}
+}
@@ -0,0 +1,31 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// Postgres runs Run on the database of pool through a store of its own, which
+// has no default transfer limits or fraud rules, after creating the
+// partitions of the months the transfers fall in. opts configure the store.
+func Postgres(ctx context.Context, pool *pgxpool.Pool, o Options, opts ...store.Option) (Result, error) {
+	if err := o.Validate(); err != nil {
+		return Result{}, err
+	}
+	clk := clock.NewFake(time.Now())
+	s := store.NewStore(pool, append(opts, store.WithClock(clk))...)
+	if o.Transfers > 0 {
+		from, to := store.StatementPeriod(clk.Now().Add(-o.Period)), store.StatementPeriod(clk.Now())
+		months := (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+		if _, err := s.EnsureTransactionPartitions(ctx, from, months); err != nil {
+			return Result{}, fmt.Errorf("create partitions: %w", err)
+		}
+	}
+	return Run(ctx, s, clk, o)
+}
@@ -0,0 +1,213 @@
+// Package seed fills a store with generated accounts and historical transfers
+// between them, for load tests and demo environments.
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/model"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// Balance distributions of the generated accounts
+const (
+	Uniform = "uniform" // equally likely between the minimum and maximum
+	Normal  = "normal"  // centred between them, most within a third of the range of the centre
+	Pareto  = "pareto"  // most near the minimum, a few large ones (80/20)
+)
+
+// Bounds of a seeding run
+const (
+	MaxAccounts  = 100000
+	MaxTransfers = 1000000
+)
+
+// batchSize caps the accounts created per store call
+const batchSize = 1000
+
+// Errors returned by Options.Validate
+var (
+	ErrInvalidAccounts     = fmt.Errorf("accounts must be between 1 and %d", MaxAccounts)
+	ErrInvalidCurrency     = errors.New("currency must be a 3-letter ISO 4217 code")
+	ErrInvalidTransfers    = fmt.Errorf("transfers must be between 0 and %d", MaxTransfers)
+	ErrInvalidDistribution = errors.New("distribution must be uniform, normal or pareto")
+	ErrInvalidBalances     = errors.New("balances must be non-negative, with the minimum not above the maximum")
+	ErrInvalidPeriod       = errors.New("the period of the transfers must be positive")
+)
+
+// Store is the part of a store seeding uses
+type Store interface {
+	CreateAccounts(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error)
+	Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error)
+}
+
+// Options describe the data to generate
+type Options struct {
+	Accounts     int
+	Currency     string
+	Distribution string
+	MinBalance   decimal.Decimal
+	MaxBalance   decimal.Decimal
+	Transfers    int
+	Period       time.Duration // the transfers are spread over the Period before the run
+	Seed         uint64        // of the random generator; runs with the same seed generate the same data
+}
+
+// DefaultOptions returns options generating 100 USD accounts with uniform
+// balances between 0 and 10000, and no transfers.
+func DefaultOptions() Options {
+	return Options{
+		Accounts:     100,
+		Currency:     "USD",
+		Distribution: Uniform,
+		MaxBalance:   decimal.NewFromInt(10000),
+		Period:       30 * 24 * time.Hour,
+	}
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	if o.Accounts < 1 || o.Accounts > MaxAccounts {
+		return ErrInvalidAccounts
+	}
+	if len(o.Currency) != 3 || strings.ContainsFunc(o.Currency, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+		return ErrInvalidCurrency
+	}
+	if o.Transfers < 0 || o.Transfers > MaxTransfers {
+		return ErrInvalidTransfers
+	}
+	switch o.Distribution {
+	case Uniform, Normal, Pareto:
+	default:
+		return ErrInvalidDistribution
+	}
+	if o.MinBalance.IsNegative() || o.MaxBalance.LessThan(o.MinBalance) {
+		return ErrInvalidBalances
+	}
+	if o.Transfers > 0 && o.Period <= 0 {
+		return ErrInvalidPeriod
+	}
+	return nil
+}
+
+// Result tells what a run generated
+type Result struct {
+	Accounts   []int64 // ids of the accounts created
+	ExternalID string  // prefix of their external ids, followed by their number from 1
+	Transfers  int     // transfers made
+	Skipped    int     // transfers refused for insufficient funds
+	From, To   time.Time
+}
+
+// Run creates the accounts described by o and makes the transfers between
+// them. s must tell the time with clk, which Run moves: the accounts are
+// created at the start of the period ending at clk's time, and the transfers
+// at random times within it, in order. Transfers move up to a fifth of the balance of their
+// source account; none are made between fewer than two accounts.
+func Run(ctx context.Context, s Store, clk *clock.Fake, o Options) (Result, error) {
+	if err := o.Validate(); err != nil {
+		return Result{}, err
+	}
+	seed := o.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	scale := model.Amounts.Scale(o.Currency)
+
+	to := clk.Now()
+	res := Result{
+		ExternalID: "seed-" + strconv.FormatInt(to.UnixMilli(), 36) + "-",
+		From:       to.Add(-o.Period),
+		To:         to,
+	}
+	clk.Set(res.From)
+
+	balances := make([]decimal.Decimal, o.Accounts)
+	for lo := 0; lo < o.Accounts; lo += batchSize {
+		batch := make([]store.AccountImport, min(batchSize, o.Accounts-lo))
+		for i := range batch {
+			n := lo + i
+			balances[n] = balance(rng, o).Round(scale)
+			batch[i] = store.AccountImport{
+				ExternalID: res.ExternalID + strconv.Itoa(n+1),
+				Initial:    balances[n],
+				Currency:   o.Currency,
+				Metadata:   store.AccountMetadata{DisplayName: "Seed account " + strconv.Itoa(n+1), Tags: []string{"seed"}},
+			}
+		}
+		results, err := s.CreateAccounts(ctx, batch)
+		if err != nil {
+			return res, fmt.Errorf("create accounts: %w", err)
+		}
+		for i, r := range results {
+			if r.Err != nil {
+				return res, fmt.Errorf("create account %s: %w", batch[i].ExternalID, r.Err)
+			}
+			res.Accounts = append(res.Accounts, r.AccountID)
+		}
+	}
+	if o.Accounts < 2 {
+		return res, nil
+	}
+
+	times := make([]time.Time, o.Transfers)
+	for i := range times {
+		times[i] = res.From.Add(time.Duration(rng.Int64N(int64(o.Period))))
+	}
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+	for _, at := range times {
+		src := rng.IntN(o.Accounts)
+		dst := rng.IntN(o.Accounts - 1)
+		if dst >= src {
+			dst++
+		}
+		amount := balances[src].Mul(decimal.NewFromFloat(rng.Float64() / 5)).Round(scale)
+		if !amount.IsPositive() {
+			res.Skipped++
+			continue
+		}
+		clk.Set(at)
+		if _, err := s.Transfer(ctx, res.Accounts[src], res.Accounts[dst], amount, store.TransferDetails{Reference: "seed"}); err != nil {
+			if errors.Is(err, store.ErrInsufficientFunds) {
+				res.Skipped++
+				continue
+			}
+			return res, fmt.Errorf("transfer: %w", err)
+		}
+		balances[src] = balances[src].Sub(amount)
+		balances[dst] = balances[dst].Add(amount)
+		res.Transfers++
+	}
+	return res, nil
+}
+
+// balance draws the initial balance of an account from o's distribution.
+func balance(rng *rand.Rand, o Options) decimal.Decimal {
+	span := o.MaxBalance.Sub(o.MinBalance).InexactFloat64()
+	var f float64 // fraction of the span above the minimum
+	switch o.Distribution {
+	case Normal:
+		f = 0.5 + rng.NormFloat64()/6
+	case Pareto:
+		// Shape log4(5) puts 80% of the money in 20% of the accounts; the
+		// minimum of the distribution is a hundredth of the span
+		const shape = 1.160964
+		f = 0.01 / math.Pow(1-rng.Float64(), 1/shape)
+	default:
+		f = rng.Float64()
+	}
+	f = min(max(f, 0), 1)
+	return o.MinBalance.Add(decimal.NewFromFloat(f * span))
+}
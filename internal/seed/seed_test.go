@@ -0,0 +1,119 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store/sqlite"
+)
+
+// TestRun tests that the accounts and historical transfers are created, and
+// that the money is conserved
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(time.Now())
+	s, err := sqlite.Open(":memory:", sqlite.WithClock(clk))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	o := DefaultOptions()
+	o.Accounts, o.Transfers, o.Distribution, o.Seed = 20, 200, Pareto, 42
+	res, err := Run(ctx, s, clk, o)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(res.Accounts) != 20 || res.Transfers+res.Skipped != 200 || res.Transfers == 0 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	total := decimal.Zero
+	for _, id := range res.Accounts {
+		acc, err := s.GetAccount(ctx, id)
+		if err != nil {
+			t.Fatalf("get account %d: %v", id, err)
+		}
+		if acc.Balance.IsNegative() || acc.Currency != "USD" || !slices.Equal(acc.Tags, []string{"seed"}) {
+			t.Fatalf("unexpected account %+v", acc)
+		}
+		total = total.Add(acc.Balance)
+
+		txs, _, err := s.ListTransactionsByAccount(ctx, id, 0, 1000)
+		if err != nil {
+			t.Fatalf("list transactions: %v", err)
+		}
+		for _, tx := range txs {
+			if tx.CreatedAt.Before(res.From) || tx.CreatedAt.After(res.To) {
+				t.Fatalf("transaction %d at %v, outside %v to %v", tx.ID, tx.CreatedAt, res.From, res.To)
+			}
+		}
+	}
+
+	// The same seed draws the same balances
+	rng := rand.New(rand.NewPCG(42, 42))
+	initial := decimal.Zero
+	for range res.Accounts {
+		initial = initial.Add(balance(rng, o).Round(2))
+	}
+	if !total.Equal(initial) {
+		t.Fatalf("expected balances to sum to %s, got %s", initial, total)
+	}
+}
+
+// TestBalance tests the balance distributions
+func TestBalance(t *testing.T) {
+	o := DefaultOptions()
+	o.MinBalance, o.MaxBalance = decimal.NewFromInt(100), decimal.NewFromInt(1100)
+	for _, dist := range []string{Uniform, Normal, Pareto} {
+		o.Distribution = dist
+		rng := rand.New(rand.NewPCG(1, 1))
+		var below300 int
+		for range 10000 {
+			b := balance(rng, o)
+			if b.LessThan(o.MinBalance) || b.GreaterThan(o.MaxBalance) {
+				t.Fatalf("%s: balance %s out of bounds", dist, b)
+			}
+			if b.LessThan(decimal.NewFromInt(300)) {
+				below300++
+			}
+		}
+		// A fifth of the range holds a fifth of uniform balances, few normal
+		// ones and most Pareto ones
+		want := map[string][2]int{Uniform: {1800, 2200}, Normal: {0, 500}, Pareto: {8000, 10000}}[dist]
+		if below300 < want[0] || below300 > want[1] {
+			t.Errorf("%s: %d of 10000 balances in the bottom fifth", dist, below300)
+		}
+	}
+}
+
+// TestOptionsValidate tests that invalid options are rejected
+func TestOptionsValidate(t *testing.T) {
+	if err := DefaultOptions().Validate(); err != nil {
+		t.Fatalf("default options: %v", err)
+	}
+	for _, tc := range []struct {
+		change func(*Options)
+		want   error
+	}{
+		{func(o *Options) { o.Accounts = 0 }, ErrInvalidAccounts},
+		{func(o *Options) { o.Currency = "usd" }, ErrInvalidCurrency},
+		{func(o *Options) { o.Transfers = -1 }, ErrInvalidTransfers},
+		{func(o *Options) { o.Distribution = "zipf" }, ErrInvalidDistribution},
+		{func(o *Options) { o.MinBalance = decimal.NewFromInt(20000) }, ErrInvalidBalances},
+		{func(o *Options) { o.Transfers, o.Period = 1, 0 }, ErrInvalidPeriod},
+	} {
+		o := DefaultOptions()
+		tc.change(&o)
+		if err := o.Validate(); !errors.Is(err, tc.want) {
+			t.Errorf("expected %v, got %v", tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// AdjustBalance credits accountID with amount, or debits it if amount is
+// negative, outside a transfer, and returns the adjustment recorded in the
+// ledger with reason and the adjusting admin. Frozen accounts can be adjusted,
+// closed ones cannot; a debit may not exceed the available balance.
+func (s *Store) AdjustBalance(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (_ Transaction, err error) {
+	ctx, span := startSpan(ctx, "AdjustBalance",
+		attribute.Int64("account.id", accountID),
+		attribute.String("adjustment.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if amount.IsZero() {
+		return Transaction{}, fmt.Errorf("amount must be non-zero")
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	accs, err := lockAccounts(ctx, tx, accountID)
+	if err != nil {
+		return Transaction{}, err
+	}
+	acc := accs[accountID]
+	switch {
+	case !inScope(ctx, acc):
+		return Transaction{}, ErrAccountNotFound
+	case acc.Status == AccountClosed:
+		return Transaction{}, ErrAccountInactive
+	case acc.AvailableBalance.Add(amount).IsNegative():
+		return Transaction{}, ErrInsufficientFunds
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, acc.Balance.Add(amount), accountID); err != nil {
+		return Transaction{}, fmt.Errorf("update balance: %w", err)
+	}
+	// A credit comes from no account and a debit goes to none
+	var srcID, dstID *int64
+	if amount.IsPositive() {
+		dstID = &accountID
+	} else {
+		srcID = &accountID
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return Transaction{}, err
+	}
+	t, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, kind, reason, adjusted_by, public_id, created_at, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8::text, ''), $9, $10, $10)
+		RETURNING `+transactionColumns, srcID, dstID, amount.Abs(), acc.Currency, StatusSucceeded, KindAdjustment, reason, adjustedBy, uid, s.clock.Now()))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("insert adjustment: %w", err)
+	}
+	if err := s.writeEvent(ctx, tx, events.BalanceAdjusted, accountID, events.AdjustmentPayload{
+		ID:            t.UUID.String(),
+		TransactionID: t.ID,
+		AccountID:     accountID,
+		Amount:        amount.String(),
+		Currency:      t.Currency,
+		Reason:        reason,
+		AdjustedBy:    adjustedBy,
+	}); err != nil {
+		return Transaction{}, err
+	}
+	if err := s.raiseAlerts(ctx, tx, t); err != nil {
+		return Transaction{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Transaction{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return t, nil
+}
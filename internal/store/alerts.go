@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// Kinds of Alert
+const (
+	AlertLowBalance    = "low_balance"
+	AlertLargeTransfer = "large_transfer"
+)
+
+// Statuses of an Alert
+const (
+	AlertActive   = "active"
+	AlertResolved = "resolved"
+)
+
+// Errors returned by alert operations
+var (
+	ErrAlertNotFound = errors.New("alert not found")
+	ErrAlertResolved = errors.New("alert already resolved")
+)
+
+// AlertThresholds are the alert thresholds of an account, each unset if
+// invalid (NULL). UpdatedAt is zero if they were never set.
+type AlertThresholds struct {
+	AccountID     int64
+	LowBalance    decimal.NullDecimal // alert when the available balance falls below it
+	LargeTransfer decimal.NullDecimal // alert on transfers in or out of at least it
+	UpdatedAt     time.Time
+}
+
+// Alert is a row of the alerts table. Value is the available balance or the
+// transfer amount that raised it.
+type Alert struct {
+	ID              int64
+	AccountID       int64
+	Kind            string
+	Status          string
+	Threshold       decimal.Decimal
+	Value           decimal.Decimal
+	TransactionID   int64
+	TransactionUUID uuid.UUID // the public id of the transaction
+	CreatedAt       time.Time
+	ResolvedAt      *time.Time
+}
+
+// alertColumns is the select list matching scanAlert
+const alertColumns = `id, account_id, kind, status, threshold, value, transaction_id, created_at, resolved_at, transaction_public_id`
+
+// scanAlert scans a row selected with alertColumns.
+func scanAlert(row pgx.Row) (Alert, error) {
+	var al Alert
+	if err := row.Scan(&al.ID, &al.AccountID, &al.Kind, &al.Status, &al.Threshold, &al.Value, &al.TransactionID,
+		&al.CreatedAt, &al.ResolvedAt, &al.TransactionUUID); err != nil {
+		return Alert{}, err
+	}
+	return al, nil
+}
+
+// alertEvent returns the payload of the events of al.
+func alertEvent(al Alert) events.AlertPayload {
+	return events.AlertPayload{
+		AlertID:       al.ID,
+		AccountID:     al.AccountID,
+		Kind:          al.Kind,
+		Threshold:     al.Threshold.String(),
+		Value:         al.Value.String(),
+		TransactionID: al.TransactionID,
+	}
+}
+
+// AlertThresholds returns the alert thresholds of the account, none set if it
+// never had any. It returns ErrAccountNotFound if the account does not exist
+// or is out of ctx's scope.
+func (s *Store) AlertThresholds(ctx context.Context, accountID int64) (_ AlertThresholds, err error) {
+	ctx, span := startSpan(ctx, "AlertThresholds", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetAccount(ctx, accountID); err != nil {
+		return AlertThresholds{}, err
+	}
+	t := AlertThresholds{AccountID: accountID}
+	err = s.read.QueryRow(ctx, `SELECT low_balance, large_transfer, updated_at FROM alert_thresholds WHERE account_id = $1`,
+		accountID).Scan(&t.LowBalance, &t.LargeTransfer, &t.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return AlertThresholds{}, fmt.Errorf("get alert thresholds: %w", err)
+	}
+	return t, nil
+}
+
+// SetAlertThresholds replaces the alert thresholds of t.AccountID and returns
+// them. They apply from the next transfer of the account; alerts already
+// raised are left as they are. It returns ErrAccountNotFound if the account
+// does not exist or is out of ctx's scope.
+func (s *Store) SetAlertThresholds(ctx context.Context, t AlertThresholds) (_ AlertThresholds, err error) {
+	ctx, span := startSpan(ctx, "SetAlertThresholds", attribute.Int64("account.id", t.AccountID))
+	defer func() { endSpan(span, err) }()
+
+	err = s.pool.QueryRow(ctx, `INSERT INTO alert_thresholds (account_id, low_balance, large_transfer, updated_at)
+		SELECT account_id, $2, $3, $4 FROM accounts WHERE account_id = $1 AND `+accountScopeCond(5)+`
+		ON CONFLICT (account_id) DO UPDATE
+			SET low_balance = EXCLUDED.low_balance, large_transfer = EXCLUDED.large_transfer, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`, t.AccountID, t.LowBalance, t.LargeTransfer, s.clock.Now(), tenantArg(ctx), ownerArg(ctx)).Scan(&t.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AlertThresholds{}, ErrAccountNotFound
+		}
+		return AlertThresholds{}, fmt.Errorf("set alert thresholds: %w", err)
+	}
+	return t, nil
+}
+
+// ListAlerts returns up to limit alerts of the account with status, any if
+// empty, newest first, before cursor (exclusive) unless it is 0, and the
+// cursor of the next page, 0 on the last. It returns ErrAccountNotFound if
+// the account does not exist or is out of ctx's scope.
+func (s *Store) ListAlerts(ctx context.Context, accountID int64, status string, cursor int64, limit int) (_ []Alert, _ int64, err error) {
+	ctx, span := startSpan(ctx, "ListAlerts", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetAccount(ctx, accountID); err != nil {
+		return nil, 0, err
+	}
+	rows, err := s.read.Query(ctx, `SELECT `+alertColumns+` FROM alerts
+		WHERE account_id = $1 AND ($2 = '' OR status = $2) AND ($3 = 0 OR id < $3)
+		ORDER BY id DESC LIMIT $4`, accountID, status, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list alerts: %w", err)
+	}
+	alerts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Alert, error) {
+		return scanAlert(row)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list alerts: %w", err)
+	}
+
+	var next int64
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+		next = alerts[limit-1].ID
+	}
+	return alerts, next, nil
+}
+
+// ResolveAlert resolves an active alert of the account and returns it. It
+// returns ErrAlertNotFound unless the account, in ctx's scope, has the alert,
+// and ErrAlertResolved if it was resolved already.
+func (s *Store) ResolveAlert(ctx context.Context, accountID, alertID int64) (_ Alert, err error) {
+	ctx, span := startSpan(ctx, "ResolveAlert",
+		attribute.Int64("account.id", accountID),
+		attribute.Int64("alert.id", alertID),
+	)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Alert{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	al, err := scanAlert(tx.QueryRow(ctx, `SELECT `+alertColumns+` FROM alerts
+		WHERE id = $1 AND account_id = $2 AND EXISTS (SELECT 1 FROM accounts a WHERE a.account_id = alerts.account_id AND `+accountScopeCond(3)+`)
+		FOR UPDATE`, alertID, accountID, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Alert{}, ErrAlertNotFound
+		}
+		return Alert{}, fmt.Errorf("get alert: %w", err)
+	}
+	if al.Status != AlertActive {
+		return Alert{}, ErrAlertResolved
+	}
+	al, err = scanAlert(tx.QueryRow(ctx, `UPDATE alerts SET status = $2, resolved_at = $3 WHERE id = $1 RETURNING `+alertColumns,
+		alertID, AlertResolved, s.clock.Now()))
+	if err != nil {
+		return Alert{}, fmt.Errorf("resolve alert: %w", err)
+	}
+	if err := s.writeEvent(ctx, tx, events.AlertResolved, al.AccountID, alertEvent(al)); err != nil {
+		return Alert{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Alert{}, fmt.Errorf("commit: %w", err)
+	}
+	return al, nil
+}
+
+// evaluateAlertsSQL checks the accounts of the succeeded transaction with
+// public id $1 created at $2 against their alert thresholds, at $3: a
+// transfer of at least an account's large-transfer threshold raises an alert
+// for it; an available balance below the low-balance threshold raises one
+// unless the account has one active, which is resolved once the balance is
+// back at the threshold. It returns the alerts raised, active, and resolved.
+const evaluateAlertsSQL = `WITH t AS (
+		SELECT id, public_id, source_account_id, destination_account_id, amount FROM transactions
+		WHERE public_id = $1 AND created_at = $2 AND status = 'succeeded'
+	), accs AS (
+		SELECT a.account_id, a.balance - a.held_balance AS available, th.low_balance, th.large_transfer, t.id AS transaction_id, t.public_id, t.amount
+		FROM t JOIN alert_thresholds th ON th.account_id IN (t.source_account_id, t.destination_account_id)
+		JOIN accounts a ON a.account_id = th.account_id
+	), large AS (
+		INSERT INTO alerts (account_id, kind, status, threshold, value, transaction_id, transaction_public_id, created_at)
+		SELECT account_id, 'large_transfer', 'active', large_transfer, amount, transaction_id, public_id, $3::timestamptz FROM accs
+		WHERE amount >= large_transfer
+		RETURNING ` + alertColumns + `
+	), low AS (
+		INSERT INTO alerts (account_id, kind, status, threshold, value, transaction_id, transaction_public_id, created_at)
+		SELECT account_id, 'low_balance', 'active', low_balance, available, transaction_id, public_id, $3::timestamptz FROM accs
+		WHERE available < low_balance
+		ON CONFLICT (account_id) WHERE kind = 'low_balance' AND status = 'active' DO NOTHING
+		RETURNING ` + alertColumns + `
+	), resolved AS (
+		UPDATE alerts SET status = 'resolved', resolved_at = $3::timestamptz
+		WHERE kind = 'low_balance' AND status = 'active'
+			AND account_id IN (SELECT account_id FROM accs WHERE available >= low_balance)
+		RETURNING ` + alertColumns + `
+	)
+	SELECT ` + alertColumns + ` FROM large
+	UNION ALL SELECT ` + alertColumns + ` FROM low
+	UNION ALL SELECT ` + alertColumns + ` FROM resolved
+	ORDER BY id`
+
+// raiseAlerts evaluates the alert thresholds of the accounts of t, a
+// transaction recorded in tx, once its balances are moved (see
+// evaluateAlertsSQL), and writes each alert raised or resolved to the outbox
+// as an AlertTriggered or AlertResolved event. It does nothing unless t
+// succeeded.
+func (s *Store) raiseAlerts(ctx context.Context, tx pgx.Tx, t Transaction) error {
+	if t.Status != StatusSucceeded {
+		return nil
+	}
+	alerts, err := queryAll(ctx, tx, scanAlert, evaluateAlertsSQL, t.UUID, t.CreatedAt, s.clock.Now())
+	if err != nil {
+		return fmt.Errorf("evaluate alerts of transaction %d: %w", t.ID, err)
+	}
+	for _, al := range alerts {
+		typ := events.AlertTriggered
+		if al.Status == AlertResolved {
+			typ = events.AlertResolved
+		}
+		if err := s.writeEvent(ctx, tx, typ, al.AccountID, alertEvent(al)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
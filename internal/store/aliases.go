@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Errors returned by account alias operations
+var (
+	ErrAliasTaken    = errors.New("alias is taken")
+	ErrAliasNotFound = errors.New("alias not found")
+)
+
+// AccountAlias is a row of the account_aliases table
+type AccountAlias struct {
+	Alias     string
+	AccountID int64
+	CreatedAt time.Time
+}
+
+// AddAccountAlias registers alias as a name of the account. created is false
+// if the account already had it. It returns ErrAliasTaken if another account
+// has the alias or any account has it as external id, and ErrAccountNotFound
+// if the account does not exist or is out of ctx's scope.
+func (s *Store) AddAccountAlias(ctx context.Context, accountID int64, alias string) (_ AccountAlias, created bool, err error) {
+	ctx, span := startSpan(ctx, "AddAccountAlias",
+		attribute.Int64("account.id", accountID),
+		attribute.String("account.alias", alias),
+	)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return AccountAlias{}, false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	accs, err := lockExistingAccounts(ctx, tx, []int64{accountID})
+	if err != nil {
+		return AccountAlias{}, false, err
+	}
+	if acc, ok := accs[accountID]; !ok || !inScope(ctx, acc) {
+		return AccountAlias{}, false, ErrAccountNotFound
+	}
+	var external bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM accounts WHERE external_id = $1)`, alias).Scan(&external); err != nil {
+		return AccountAlias{}, false, fmt.Errorf("check external ids: %w", err)
+	}
+	if external {
+		return AccountAlias{}, false, ErrAliasTaken
+	}
+
+	a := AccountAlias{Alias: alias}
+	err = tx.QueryRow(ctx, `INSERT INTO account_aliases (alias, account_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (alias) DO NOTHING
+		RETURNING account_id, created_at`, alias, accountID, s.clock.Now()).Scan(&a.AccountID, &a.CreatedAt)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// Taken already, possibly by this account
+		if err := tx.QueryRow(ctx, `SELECT account_id, created_at FROM account_aliases WHERE alias = $1`, alias).Scan(&a.AccountID, &a.CreatedAt); err != nil {
+			return AccountAlias{}, false, fmt.Errorf("get alias: %w", err)
+		}
+		if a.AccountID != accountID {
+			return AccountAlias{}, false, ErrAliasTaken
+		}
+		return a, false, nil
+	case err != nil:
+		return AccountAlias{}, false, fmt.Errorf("add alias: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return AccountAlias{}, false, fmt.Errorf("commit: %w", err)
+	}
+	return a, true, nil
+}
+
+// RemoveAccountAlias unregisters alias from the account. It returns
+// ErrAliasNotFound unless the account, in ctx's scope, has the alias.
+func (s *Store) RemoveAccountAlias(ctx context.Context, accountID int64, alias string) (err error) {
+	ctx, span := startSpan(ctx, "RemoveAccountAlias",
+		attribute.Int64("account.id", accountID),
+		attribute.String("account.alias", alias),
+	)
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM account_aliases al USING accounts a
+		WHERE al.alias = $1 AND al.account_id = $2 AND a.account_id = al.account_id AND `+accountScopeCond(3),
+		alias, accountID, tenantArg(ctx), ownerArg(ctx))
+	if err != nil {
+		return fmt.Errorf("remove alias: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAliasNotFound
+	}
+	return nil
+}
+
+// AccountAliases returns the aliases of the account in alphabetical order. It
+// returns ErrAccountNotFound if the account does not exist or is out of ctx's
+// scope.
+func (s *Store) AccountAliases(ctx context.Context, accountID int64) (_ []AccountAlias, err error) {
+	ctx, span := startSpan(ctx, "AccountAliases", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	rows, err := s.read.Query(ctx, `SELECT alias, account_id, created_at FROM account_aliases
+		WHERE account_id = $1 ORDER BY alias`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("list aliases: %w", err)
+	}
+	aliases, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (AccountAlias, error) {
+		var a AccountAlias
+		err := row.Scan(&a.Alias, &a.AccountID, &a.CreatedAt)
+		return a, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// AliasAccountIDs returns the numeric ids of the accounts with the given
+// aliases, leaving out unknown ones. Like with AccountIDs, accounts of other
+// tenants than the one ctx is scoped to are unknown, and the owner scope is
+// left to the caller.
+func (s *Store) AliasAccountIDs(ctx context.Context, aliases []string) (_ map[string]int64, err error) {
+	ctx, span := startSpan(ctx, "AliasAccountIDs", attribute.Int("account.count", len(aliases)))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT al.alias, al.account_id FROM account_aliases al
+		JOIN accounts a ON a.account_id = al.account_id
+		WHERE al.alias = ANY($1) AND ($2::text IS NULL OR a.tenant_id = $2)`, aliases, tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("resolve aliases: %w", err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64, len(aliases))
+	for rows.Next() {
+		var alias string
+		var id int64
+		if err := rows.Scan(&alias, &id); err != nil {
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		ids[alias] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("resolve aliases: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// AnomalyRuleName is the rule name of the reviews of transfers flagged by
+// DetectAnomalies
+const AnomalyRuleName = "anomaly detection"
+
+// Defaults of AnomalyConfig
+const (
+	DefaultAnomalyWindow     = 30 * 24 * time.Hour
+	DefaultAnomalyMinSamples = 10
+	DefaultAnomalyZScore     = 3.0
+)
+
+// anomalyBatchSize caps the transfers analyzed per DB transaction
+const anomalyBatchSize = 500
+
+// anomalySettleDelay is how long a transfer waits before it is analyzed, so
+// that the transfers committed around it, possibly with lower ids, are
+// analyzed first
+const anomalySettleDelay = time.Minute
+
+// minAmountStddevRatio floors the standard deviation of the amounts of a
+// baseline at this share of their mean, so that the transfers of an account
+// always sending the same amount are still scored
+var minAmountStddevRatio = decimal.RequireFromString("0.1")
+
+// AnomalyConfig configures DetectAnomalies. A transfer is flagged when one of
+// its scores reaches the threshold of the score; a zero threshold turns the
+// score off.
+type AnomalyConfig struct {
+	Window     time.Duration // of the source's transfers making its baseline
+	MinSamples int           // transfers in the window below which nothing is flagged
+
+	AmountZ       float64 // of the amount against the amounts of the baseline
+	FrequencyZ    float64 // of the transfers in the last 24 hours against the daily counts of the baseline
+	CounterpartyZ float64 // of a new destination against the rate of new destinations of the baseline
+}
+
+// DefaultAnomalyConfig returns the default configuration of DetectAnomalies.
+func DefaultAnomalyConfig() AnomalyConfig {
+	return AnomalyConfig{
+		Window:        DefaultAnomalyWindow,
+		MinSamples:    DefaultAnomalyMinSamples,
+		AmountZ:       DefaultAnomalyZScore,
+		FrequencyZ:    DefaultAnomalyZScore,
+		CounterpartyZ: DefaultAnomalyZScore,
+	}
+}
+
+// baseline is the behaviour of a source account over the window before a
+// transfer
+type baseline struct {
+	samples        int
+	amountMean     decimal.Decimal
+	amountStddev   decimal.Decimal
+	lastDay        int // transfers in the 24 hours before the transfer
+	dailyMean      float64
+	dailyStddev    float64
+	counterparties int
+	knownDest      bool
+}
+
+// baselineSQL computes the baseline of the transfers out of $1 before the
+// transaction $2 made at $3, over a window of $4 days: the amount statistics,
+// the transfers of the last 24 hours against the statistics of the daily
+// counts before them, and the counterparties, with whether $5 is one of them.
+// $6 is the succeeded status and $7 the transfer kind.
+const baselineSQL = `
+WITH past AS (
+	SELECT amount, destination_account_id, floor(extract(epoch FROM $3::timestamptz - created_at) / 86400)::int AS day
+	FROM transactions
+	WHERE source_account_id = $1 AND id < $2 AND status = $6 AND kind = $7 AND reversal_of IS NULL
+		AND created_at > $3::timestamptz - make_interval(days => $4) AND created_at <= $3
+), daily AS (
+	SELECT count(past.day) AS n FROM generate_series(1, $4 - 1) AS d LEFT JOIN past ON past.day = d GROUP BY d
+)
+SELECT count(*), COALESCE(avg(amount), 0), COALESCE(stddev_pop(amount), 0), count(*) FILTER (WHERE day = 0),
+	(SELECT COALESCE(avg(n), 0)::float8 FROM daily), (SELECT COALESCE(stddev_pop(n), 0)::float8 FROM daily),
+	count(DISTINCT destination_account_id), COALESCE(bool_or(destination_account_id = $5), false)
+FROM past`
+
+// anomalies returns the reasons t deviates from b beyond the thresholds of
+// cfg, none if it does not.
+func (b baseline) anomalies(t Transaction, cfg AnomalyConfig) []string {
+	if b.samples == 0 || b.samples < cfg.MinSamples {
+		return nil
+	}
+	var reasons []string
+	stddev := decimal.Max(b.amountStddev, b.amountMean.Mul(minAmountStddevRatio))
+	if cfg.AmountZ > 0 && stddev.IsPositive() {
+		z := t.Amount.Sub(b.amountMean).Div(stddev).InexactFloat64()
+		if z >= cfg.AmountZ {
+			reasons = append(reasons, fmt.Sprintf("amount %s is %.1f standard deviations above the mean %s of %d transfers",
+				t.Amount, z, b.amountMean.Round(2), b.samples))
+		}
+	}
+	if cfg.FrequencyZ > 0 && b.dailyStddev > 0 {
+		n := b.lastDay + 1
+		z := (float64(n) - b.dailyMean) / b.dailyStddev
+		if z >= cfg.FrequencyZ {
+			reasons = append(reasons, fmt.Sprintf("%d transfers in 24 hours is %.1f standard deviations above the daily mean %.1f",
+				n, z, b.dailyMean))
+		}
+	}
+	if cfg.CounterpartyZ > 0 && !b.knownDest {
+		// A new destination is a Bernoulli outcome of rate p, the share of
+		// the baseline's transfers that went to a new destination
+		p := float64(b.counterparties) / float64(b.samples)
+		if z := math.Sqrt((1 - p) / p); z >= cfg.CounterpartyZ {
+			reasons = append(reasons, fmt.Sprintf("new counterparty %d is %.1f standard deviations from the %d counterparties of %d transfers",
+				t.DestinationAccountID, z, b.counterparties, b.samples))
+		}
+	}
+	return reasons
+}
+
+// DetectAnomalies analyzes the succeeded transfers made since its last run
+// against the baselines of their source accounts, computed from the
+// account's transfers over the window before each, and queues those that
+// deviate for review with AnomalyRuleName and the reasons. Transfers already
+// flagged by a rule keep their review. The first run starts from the latest
+// transfer. It returns how many transfers it flagged.
+func (s *Store) DetectAnomalies(ctx context.Context, cfg AnomalyConfig) (n int, err error) {
+	ctx, span := startSpan(ctx, "DetectAnomalies")
+	defer func() {
+		span.SetAttributes(attribute.Int("anomaly.flagged", n))
+		endSpan(span, err)
+	}()
+
+	for {
+		flagged, more, err := s.detectAnomalies(ctx, cfg)
+		n += flagged
+		if err != nil || !more {
+			return n, err
+		}
+	}
+}
+
+// detectAnomalies analyzes the next batch of transfers; more is true if
+// there may be others after them.
+func (s *Store) detectAnomalies(ctx context.Context, cfg AnomalyConfig) (n int, more bool, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var cursor int64
+	err = tx.QueryRow(ctx, `SELECT last_transaction_id FROM anomaly_scan FOR UPDATE`).Scan(&cursor)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, err := tx.Exec(ctx, `INSERT INTO anomaly_scan (last_transaction_id) SELECT COALESCE(max(id), 0) FROM transactions`); err != nil {
+			return 0, false, fmt.Errorf("start anomaly scan: %w", err)
+		}
+		return 0, false, tx.Commit(ctx)
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("read anomaly scan: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE id > $1 AND status = $2 AND kind = $3 AND reversal_of IS NULL AND created_at <= $4
+		ORDER BY id LIMIT $5`, cursor, StatusSucceeded, KindTransfer, s.clock.Now().Add(-anomalySettleDelay), anomalyBatchSize)
+	if err != nil {
+		return 0, false, fmt.Errorf("list transfers to analyze: %w", err)
+	}
+	txs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Transaction, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("list transfers to analyze: %w", err)
+	}
+	if len(txs) == 0 {
+		return 0, false, nil
+	}
+
+	days := int(math.Ceil(cfg.Window.Hours() / 24))
+	for _, t := range txs {
+		var b baseline
+		if err := tx.QueryRow(ctx, baselineSQL, t.SourceAccountID, t.ID, t.CreatedAt, days, t.DestinationAccountID, StatusSucceeded, KindTransfer).Scan(
+			&b.samples, &b.amountMean, &b.amountStddev, &b.lastDay, &b.dailyMean, &b.dailyStddev, &b.counterparties, &b.knownDest); err != nil {
+			return 0, false, fmt.Errorf("baseline of transaction %d: %w", t.ID, err)
+		}
+		reasons := b.anomalies(t, cfg)
+		if len(reasons) == 0 {
+			continue
+		}
+		tag, err := tx.Exec(ctx, `INSERT INTO transfer_reviews (transaction_id, rule_name, reason) VALUES ($1, $2, $3)
+			ON CONFLICT (transaction_id) DO NOTHING`, t.ID, AnomalyRuleName, strings.Join(reasons, "; "))
+		if err != nil {
+			return 0, false, fmt.Errorf("flag transaction %d: %w", t.ID, err)
+		}
+		n += int(tag.RowsAffected())
+	}
+	if _, err := tx.Exec(ctx, `UPDATE anomaly_scan SET last_transaction_id = $1`, txs[len(txs)-1].ID); err != nil {
+		return 0, false, fmt.Errorf("advance anomaly scan: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, false, fmt.Errorf("commit: %w", err)
+	}
+	return n, len(txs) == anomalyBatchSize, nil
+}
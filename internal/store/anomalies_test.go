@@ -0,0 +1,62 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestBaselineAnomalies(t *testing.T) {
+	cfg := DefaultAnomalyConfig()
+	// 20 transfers of 100 ± 10 to 2 counterparties, 1 a day ± 0.5
+	usual := baseline{samples: 20, amountMean: decimal.NewFromInt(100), amountStddev: decimal.NewFromInt(10),
+		dailyMean: 1, dailyStddev: 0.5, counterparties: 2, knownDest: true}
+	transfer := func(amount int64, dst int64) Transaction {
+		return Transaction{Amount: decimal.NewFromInt(amount), DestinationAccountID: dst}
+	}
+
+	for name, c := range map[string]struct {
+		b    baseline
+		t    Transaction
+		want []string // prefixes of the reasons
+	}{
+		"usual":                     {usual, transfer(110, 2), nil},
+		"large amount":              {usual, transfer(130, 2), []string{"amount 130 is 3.0 standard deviations"}},
+		"small amount":              {usual, transfer(10, 2), nil},
+		"too few samples":           {baseline{samples: 9, amountMean: usual.amountMean, amountStddev: usual.amountStddev}, transfer(1000, 2), nil},
+		"no spread":                 {baseline{samples: 20, amountMean: decimal.NewFromInt(100), knownDest: true}, transfer(1000, 2), []string{"amount 1000 is 90.0 standard deviations"}},
+		"burst":                     {baseline{samples: 20, amountMean: usual.amountMean, amountStddev: usual.amountStddev, lastDay: 2, dailyMean: 1, dailyStddev: 0.5, knownDest: true}, transfer(100, 2), []string{"3 transfers in 24 hours is 4.0 standard deviations"}},
+		"new counterparty":          {baseline{samples: 20, amountMean: usual.amountMean, amountStddev: usual.amountStddev, counterparties: 2}, transfer(100, 9), []string{"new counterparty 9 is 3.0 standard deviations"}},
+		"new counterparty, diverse": {baseline{samples: 20, amountMean: usual.amountMean, amountStddev: usual.amountStddev, counterparties: 10}, transfer(100, 9), nil},
+		"several": {baseline{samples: 20, amountMean: usual.amountMean, amountStddev: usual.amountStddev, counterparties: 1}, transfer(200, 9),
+			[]string{"amount 200 is 10.0", "new counterparty 9 is 4.4"}},
+	} {
+		got := c.b.anomalies(c.t, cfg)
+		if len(got) != len(c.want) {
+			t.Fatalf("%s: expected %d reasons, got %q", name, len(c.want), got)
+		}
+		for i, prefix := range c.want {
+			if !strings.HasPrefix(got[i], prefix) {
+				t.Fatalf("%s: expected a reason starting with %q, got %q", name, prefix, got[i])
+			}
+		}
+	}
+
+	cfg.AmountZ = 0
+	if got := usual.anomalies(transfer(1000, 2), cfg); len(got) != 0 {
+		t.Fatalf("expected the amount score off, got %q", got)
+	}
+}
+
+// TestBaselineAnomalies_Constant tests that the amounts of an account always
+// sending the same amount are scored against a tenth of their mean
+func TestBaselineAnomalies_Constant(t *testing.T) {
+	constant := baseline{samples: 30, amountMean: decimal.NewFromInt(50), dailyMean: 1, counterparties: 1, knownDest: true}
+	for amount, want := range map[int64]string{50: "", 64: "", 65: "amount 65 is 3.0 standard deviations", 500: "amount 500 is 90.0 standard deviations"} {
+		got := constant.anomalies(Transaction{Amount: decimal.NewFromInt(amount), DestinationAccountID: 2}, DefaultAnomalyConfig())
+		if want == "" && len(got) != 0 || want != "" && (len(got) != 1 || !strings.HasPrefix(got[0], want)) {
+			t.Fatalf("amount %d: expected %q, got %q", amount, want, got)
+		}
+	}
+}
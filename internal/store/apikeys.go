@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrAPIKeyNotFound is returned for unknown or revoked API keys.
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// APIKey is a row of the api_keys table. The key itself is never stored.
+type APIKey struct {
+	ID        int64
+	Name      string
+	Role      string
+	Tenant    string         // callers using the key are scoped to it, if not empty
+	Networks  []netip.Prefix // the key is only accepted from these, if not empty
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+// apiKeyColumns is the select list matching scanAPIKey
+const apiKeyColumns = `id, name, role, tenant_id, allowed_networks, created_at, revoked_at`
+
+// scanAPIKey scans a row selected with apiKeyColumns.
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var k APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Role, &k.Tenant, &k.Networks, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return APIKey{}, err
+	}
+	return k, nil
+}
+
+// CreateAPIKey stores a new key of tenant, accepted from networks (any if
+// empty), by its hash.
+func (s *Store) CreateAPIKey(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (_ APIKey, err error) {
+	ctx, span := startSpan(ctx, "CreateAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	if networks == nil {
+		networks = []netip.Prefix{}
+	}
+	k := APIKey{Name: name, Role: role, Tenant: tenant, Networks: networks}
+	err = s.pool.QueryRow(ctx, `INSERT INTO api_keys (name, role, tenant_id, allowed_networks, key_hash) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		name, role, tenant, networks, keyHash).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return APIKey{}, fmt.Errorf("create api key: %w", err)
+	}
+	return k, nil
+}
+
+// ListAPIKeys returns all keys of the tenant ctx is scoped to (every key if
+// unscoped), including revoked ones, oldest first.
+func (s *Store) ListAPIKeys(ctx context.Context) (_ []APIKey, err error) {
+	ctx, span := startSpan(ctx, "ListAPIKeys")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE $1::text IS NULL OR tenant_id = $1 ORDER BY id`, tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (APIKey, error) {
+		return scanAPIKey(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey marks the key as revoked. Revoking twice is a no-op. Scoped to
+// a tenant, only its keys can be revoked.
+func (s *Store) RevokeAPIKey(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "RevokeAPIKey", attribute.Int64("api_key.id", id))
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now())
+		WHERE id = $1 AND ($2::text IS NULL OR tenant_id = $2)`, id, tenantArg(ctx))
+	if err != nil {
+		return fmt.Errorf("revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// LookupAPIKey returns the active key with the given hash.
+func (s *Store) LookupAPIKey(ctx context.Context, keyHash string) (_ APIKey, err error) {
+	ctx, span := startSpan(ctx, "LookupAPIKey")
+	defer func() { endSpan(span, err) }()
+
+	k, err := scanAPIKey(s.pool.QueryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, keyHash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return APIKey{}, ErrAPIKeyNotFound
+		}
+		return APIKey{}, fmt.Errorf("lookup api key: %w", err)
+	}
+	return k, nil
+}
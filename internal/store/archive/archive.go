@@ -0,0 +1,115 @@
+// Package archive exports archived months of transactions out of the database.
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// Dir writes each archived month to a gzipped JSON lines file of a directory,
+// transactions-YYYY-MM.jsonl.gz, such as a mounted bucket. Files appear
+// complete or not at all, and exporting a month again replaces its file.
+type Dir struct {
+	dir string
+}
+
+var _ store.TransactionExporter = (*Dir)(nil)
+
+// NewDir returns a Dir writing to dir, which must exist.
+func NewDir(dir string) *Dir {
+	return &Dir{dir: dir}
+}
+
+// Record is a line of an archive file
+type Record struct {
+	ID                   int64           `json:"id"`
+	PublicID             string          `json:"public_id"`
+	CreatedAt            time.Time       `json:"created_at"`
+	Kind                 string          `json:"kind"`
+	SourceAccountID      int64           `json:"source_account_id,omitempty"`
+	DestinationAccountID int64           `json:"destination_account_id,omitempty"`
+	Amount               decimal.Decimal `json:"amount"`
+	Currency             string          `json:"currency"`
+	Status               string          `json:"status"`
+	ErrorMessage         string          `json:"error_message,omitempty"`
+	ReversalOf           int64           `json:"reversal_of,omitempty"`
+	Reference            string          `json:"reference,omitempty"`
+	PurposeCode          string          `json:"purpose_code,omitempty"`
+	Reason               string          `json:"reason,omitempty"`
+	AdjustedBy           string          `json:"adjusted_by,omitempty"`
+	InitiatedBy          string          `json:"initiated_by,omitempty"`
+	Channel              string          `json:"channel,omitempty"`
+	RequestID            string          `json:"request_id,omitempty"`
+}
+
+// FileName returns the name of the archive file of the month starting at month.
+func FileName(month time.Time) string {
+	return "transactions-" + month.Format("2006-01") + ".jsonl.gz"
+}
+
+// ExportMonth implements store.TransactionExporter.
+func (d *Dir) ExportMonth(ctx context.Context, month time.Time, read func(fn func(store.Transaction) error) error) (err error) {
+	f, err := os.CreateTemp(d.dir, ".transactions-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create archive file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	gz := gzip.NewWriter(f)
+	enc := json.NewEncoder(gz)
+	if err := read(func(t store.Transaction) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return enc.Encode(Record{
+			ID:                   t.ID,
+			PublicID:             t.UUID.String(),
+			CreatedAt:            t.CreatedAt,
+			Kind:                 t.Kind,
+			SourceAccountID:      t.SourceAccountID,
+			DestinationAccountID: t.DestinationAccountID,
+			Amount:               t.Amount,
+			Currency:             t.Currency,
+			Status:               t.Status,
+			ErrorMessage:         t.ErrorMessage,
+			ReversalOf:           t.ReversalOf,
+			Reference:            t.Reference,
+			PurposeCode:          t.PurposeCode,
+			Reason:               t.Reason,
+			AdjustedBy:           t.AdjustedBy,
+			InitiatedBy:          t.InitiatedBy,
+			Channel:              t.Channel,
+			RequestID:            t.RequestID,
+		})
+	}); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("write archive file: %w", err)
+	}
+	// The month is dropped from the database once exported
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("sync archive file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close archive file: %w", err)
+	}
+	if err := os.Rename(f.Name(), filepath.Join(d.dir, FileName(month))); err != nil {
+		return fmt.Errorf("rename archive file: %w", err)
+	}
+	return nil
+}
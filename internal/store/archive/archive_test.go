@@ -0,0 +1,73 @@
+package archive
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TestDir tests that a month is written as one line per transaction, and that
+// a failed export leaves no file behind
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDir(dir)
+	month := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	txs := []store.Transaction{
+		{ID: 1, SourceAccountID: 1, DestinationAccountID: 2, Amount: decimal.RequireFromString("1.50"), Currency: "USD", Status: store.StatusSucceeded, Kind: store.KindTransfer},
+		{ID: 2, SourceAccountID: 2, DestinationAccountID: 1, Amount: decimal.NewFromInt(3), Currency: "USD", Status: store.StatusFailed, Kind: store.KindTransfer},
+	}
+	read := func(fn func(store.Transaction) error) error {
+		for _, tx := range txs {
+			if err := fn(tx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := d.ExportMonth(context.Background(), month, read); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "transactions-2024-03.jsonl.gz"))
+	if err != nil {
+		t.Fatalf("open archive file: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	dec := json.NewDecoder(gz)
+	var got []Record
+	for dec.More() {
+		var r Record
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 2 || got[0].ID != 1 || !got[0].Amount.Equal(txs[0].Amount) || got[1].Status != store.StatusFailed {
+		t.Fatalf("unexpected records %+v", got)
+	}
+
+	failing := func(fn func(store.Transaction) error) error { return errors.New("connection lost") }
+	if err := d.ExportMonth(context.Background(), month.AddDate(0, 1, 0), failing); err == nil {
+		t.Fatal("expected the export to fail")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the first month's file, got %v", entries)
+	}
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EnqueueTransfer records a pending transfer for ExecutePendingTransfers and
+// returns its transactions row. The accounts are only checked on execution,
+// except that the source account must be in the scope of ctx, and be another
+// account than the destination (see WithSameAccountTransfers).
+func (s *Store) EnqueueTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details TransferDetails) (_ Transaction, err error) {
+	ctx, span := startSpan(ctx, "EnqueueTransfer",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return Transaction{}, err
+	}
+	details, err = s.withTransactionID(details)
+	if err != nil {
+		return Transaction{}, err
+	}
+	// The currency is the source account's, if it exists, so that the pending
+	// row already reports it
+	t, err := scanTransaction(s.pool.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reference, purpose_code, settled_at, public_id, created_at,
+			initiated_by, channel, request_id)
+		SELECT $1::bigint, $2::bigint, $3::numeric, COALESCE((SELECT currency FROM accounts WHERE account_id = $1), 'USD'), $4::text, $5::text, $6::text, NULL, $9::uuid, $10::timestamptz,
+			$11::text, $12::text, $13::text
+		WHERE $7::text IS NULL AND $8::text IS NULL OR EXISTS (SELECT 1 FROM accounts WHERE account_id = $1 AND `+accountScopeCond(7)+`)
+		RETURNING `+transactionColumns, srcID, dstID, amount, StatusPending, details.Reference, details.PurposeCode, tenantArg(ctx), ownerArg(ctx), details.UUID, s.clock.Now(),
+		details.InitiatedBy, details.Channel, details.RequestID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Transaction{}, &AccountNotFoundError{AccountID: srcID, Party: PartySource}
+		}
+		return Transaction{}, fmt.Errorf("enqueue transfer: %w", err)
+	}
+	return t, nil
+}
+
+// ExecutePendingTransfers executes up to limit pending transfers, oldest first,
+// each in its own DB transaction, and returns how many were executed. A transfer
+// that fails its checks is marked failed with the reason; concurrent callers
+// never execute the same transfer. A transfer matching a require_approval
+// rule is queued for review instead, and executed once approved without
+// checking the rules again.
+func (s *Store) ExecutePendingTransfers(ctx context.Context, limit int) (n int, err error) {
+	ctx, span := startSpan(ctx, "ExecutePendingTransfers")
+	defer func() {
+		span.SetAttributes(attribute.Int("transfer.executed", n))
+		endSpan(span, err)
+	}()
+
+	for n < limit {
+		ok, err := s.executePendingTransfer(ctx)
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			break
+		}
+		n++
+	}
+	return n, nil
+}
+
+// executePendingTransfer executes the oldest pending transfer not locked by
+// another caller. ok is false when there is none.
+func (s *Store) executePendingTransfer(ctx context.Context) (ok bool, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Transfers queued for review wait for its approval, and then skip the rules
+	var id, srcID, dstID int64
+	var amount decimal.Decimal
+	var approved bool
+	err = tx.QueryRow(ctx, `SELECT id, source_account_id, destination_account_id, amount,
+			EXISTS (SELECT 1 FROM transfer_reviews WHERE transaction_id = id AND review_status = $2)
+		FROM transactions
+		WHERE status = $1 AND NOT EXISTS (SELECT 1 FROM transfer_reviews WHERE transaction_id = id AND review_status <> $2)
+		ORDER BY id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, StatusPending, ReviewApproved).Scan(&id, &srcID, &dstID, &amount, &approved)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claim pending transfer: %w", err)
+	}
+	feeIDs, err := s.quoteFeeAccounts(ctx, tx, []TransferItem{{SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount}})
+	if err != nil {
+		return false, err
+	}
+	accs, err := lockExistingAccounts(ctx, tx, append([]int64{srcID, dstID}, feeIDs...))
+	if err != nil {
+		return false, err
+	}
+	src, srcOK := accs[srcID]
+	dst, dstOK := accs[dstID]
+	if srcOK {
+		if err := lockFeeAccounts(ctx, tx, accs, s.feeFor(src, dstID, amount)); err != nil {
+			return false, err
+		}
+	}
+	scopeErr := s.checkScope(ctx, src, dst)
+	var transferErr error
+	switch {
+	case s.checkSameAccount(srcID, dstID) != nil:
+		transferErr = ErrSameAccount
+	case !srcOK || !dstOK:
+		transferErr = CheckTransferAccounts(srcID, dstID, srcOK, dstOK)
+	case scopeErr != nil:
+		transferErr = scopeErr
+	case src.Status != AccountActive || dst.Status != AccountActive:
+		transferErr = ErrAccountInactive
+	case src.Currency != dst.Currency:
+		transferErr = ErrCurrencyMismatch
+	}
+	if transferErr == nil {
+		transferErr = s.checkKYC(src, dst)
+	}
+	if transferErr == nil {
+		if err := checkCounterparties(ctx, tx, srcID, dstID); errors.Is(err, ErrCounterpartyNotAllowed) {
+			transferErr = err
+		} else if err != nil {
+			return false, err
+		}
+	}
+	var rule *Rule
+	if transferErr == nil && !approved {
+		if rule, err = s.newRuleChecker(tx).check(ctx, srcID, dstID, amount); err != nil {
+			return false, err
+		}
+		if rule != nil && rule.Action == RuleBlock {
+			transferErr = ErrTransferBlocked
+		}
+	}
+	if transferErr == nil && rule != nil && rule.Action == RuleRequireApproval {
+		// Left pending until the review is approved
+		if err := flagTransfer(ctx, tx, Transaction{ID: id}, rule); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return false, fmt.Errorf("commit: %w", err)
+		}
+		return true, nil
+	}
+	var fee feeCharge
+	if transferErr == nil {
+		fee = s.feeFor(src, dstID, amount)
+		transferErr = checkFeeAccount(fee, accs, src.Currency)
+	}
+	if transferErr == nil {
+		if err := s.newLimitTracker(tx).check(ctx, src, amount); errors.Is(err, ErrLimitExceeded) {
+			transferErr = err
+		} else if err != nil {
+			return false, err
+		}
+	}
+	debit := amount.Add(fee.Amount)
+	if transferErr == nil && src.AvailableBalance.LessThan(debit) {
+		transferErr = ErrInsufficientFunds
+	}
+	if transferErr == nil {
+		transferErr = CheckMinBalance(src, debit)
+	}
+
+	status, errMsg := StatusSucceeded, ""
+	if transferErr != nil {
+		status, errMsg = StatusFailed, transferErr.Error()
+	} else {
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance - $1 WHERE account_id = $2`, debit, srcID); err != nil {
+			return false, fmt.Errorf("update src balance: %w", err)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`, amount, dstID); err != nil {
+			return false, fmt.Errorf("update dst balance: %w", err)
+		}
+		if !fee.Amount.IsZero() {
+			if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`, fee.Amount, fee.AccountID); err != nil {
+				return false, fmt.Errorf("update fee balance: %w", err)
+			}
+		}
+	}
+	t, err := scanTransaction(tx.QueryRow(ctx, `UPDATE transactions SET status = $1, error_message = NULLIF($2::text, ''), settled_at = $4 WHERE id = $3
+		RETURNING `+transactionColumns, status, errMsg, id, s.clock.Now()))
+	if err != nil {
+		return false, fmt.Errorf("update transaction %d: %w", id, err)
+	}
+	if transferErr == nil && rule != nil && rule.Action == RuleFlag {
+		if err := flagTransfer(ctx, tx, t, rule); err != nil {
+			return false, err
+		}
+	}
+	if err := s.finishTransfer(ctx, tx, t); err != nil {
+		return false, err
+	}
+	if err := s.recordFee(ctx, tx, t, fee); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	if transferErr == nil {
+		s.invalidateAccounts(ctx, srcID, dstID)
+		if !fee.Amount.IsZero() {
+			s.invalidateAccounts(ctx, fee.AccountID)
+		}
+	}
+	return true, nil
+}
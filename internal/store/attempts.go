@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TransferAttempt is a transfer request the API rejected, kept for fraud
+// analysis. Accounts and amount are as sent, which may not be valid.
+type TransferAttempt struct {
+	ID                 int64
+	Kind               string // the kind of transfer requested, e.g. "transfer" or "hold"
+	SourceAccount      string // account id or external id
+	DestinationAccount string
+	Amount             string
+	Reference          string
+	Status             int // HTTP status of the rejection
+	Code               string
+	Message            string
+	Subject            string // the caller, empty without authentication
+	Tenant             string
+	CreatedAt          time.Time
+}
+
+// TransferAttemptFilter narrows ListTransferAttempts; empty fields match any attempt.
+type TransferAttemptFilter struct {
+	Subject string
+	Account string // source or destination, as sent
+	Code    string
+}
+
+// transferAttemptColumns is the select list matching scanTransferAttempt
+const transferAttemptColumns = `id, kind, source_account, destination_account, amount, reference, status, code, message, subject, tenant_id, created_at`
+
+// scanTransferAttempt scans a row selected with transferAttemptColumns.
+func scanTransferAttempt(row pgx.Row) (TransferAttempt, error) {
+	var a TransferAttempt
+	if err := row.Scan(&a.ID, &a.Kind, &a.SourceAccount, &a.DestinationAccount, &a.Amount, &a.Reference,
+		&a.Status, &a.Code, &a.Message, &a.Subject, &a.Tenant, &a.CreatedAt); err != nil {
+		return TransferAttempt{}, err
+	}
+	return a, nil
+}
+
+// RecordTransferAttempt stores a rejected transfer request in the tenant of
+// ctx, and returns it with its id and time.
+func (s *Store) RecordTransferAttempt(ctx context.Context, a TransferAttempt) (_ TransferAttempt, err error) {
+	ctx, span := startSpan(ctx, "RecordTransferAttempt", attribute.String("transfer_attempt.code", a.Code))
+	defer func() { endSpan(span, err) }()
+
+	a.Tenant, _ = TenantFromContext(ctx)
+	a, err = scanTransferAttempt(s.pool.QueryRow(ctx, `INSERT INTO transfer_attempts
+		(kind, source_account, destination_account, amount, reference, status, code, message, subject, tenant_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING `+transferAttemptColumns,
+		a.Kind, a.SourceAccount, a.DestinationAccount, a.Amount, a.Reference, a.Status, a.Code, a.Message, a.Subject, a.Tenant, s.clock.Now()))
+	if err != nil {
+		return TransferAttempt{}, fmt.Errorf("record transfer attempt: %w", err)
+	}
+	return a, nil
+}
+
+// ListTransferAttempts returns up to limit attempts matching f, newest first,
+// starting before the id cursor (0 for the first page). Scoped to a tenant,
+// only its attempts are listed. The returned next cursor is zero when there
+// are no further pages.
+func (s *Store) ListTransferAttempts(ctx context.Context, f TransferAttemptFilter, cursor int64, limit int) (_ []TransferAttempt, _ int64, err error) {
+	ctx, span := startSpan(ctx, "ListTransferAttempts")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT `+transferAttemptColumns+` FROM transfer_attempts
+		WHERE ($1::bigint = 0 OR id < $1)
+			AND ($2::text = '' OR subject = $2)
+			AND ($3::text = '' OR source_account = $3 OR destination_account = $3)
+			AND ($4::text = '' OR code = $4)
+			AND ($5::text IS NULL OR tenant_id = $5)
+		ORDER BY id DESC
+		LIMIT $6`, cursor, f.Subject, f.Account, f.Code, tenantArg(ctx), limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transfer attempts: %w", err)
+	}
+	attempts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (TransferAttempt, error) {
+		return scanTransferAttempt(row)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transfer attempts: %w", err)
+	}
+
+	var next int64
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+		next = attempts[limit-1].ID
+	}
+	return attempts, next, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Balance is the balance of an account at a point in time.
+type Balance struct {
+	AccountID int64
+	Amount    decimal.Decimal
+	Currency  string
+	At        time.Time
+}
+
+// ErrHistoryArchived is returned by BalanceAt for a time before the last
+// archived transaction settled.
+var ErrHistoryArchived = errors.New("balance history archived")
+
+// balanceAtSQL reconstructs the balance of account $1 at $2 from the nearest
+// known balance: the snapshots just before and just after $2, or the current
+// balance. The succeeded transfers ($3) settled between that balance and $2
+// are replayed forwards from an earlier snapshot and backwards otherwise.
+// Everything is read in one statement so the balances and history agree. No
+// row is returned if the account is not in tenant $4 or owned by $5 (when not NULL).
+// $6 is the current time, that of the current balance.
+//
+// Snapshots taken before the archived transactions settled cannot be replayed
+// from, and the last column tells whether $2 is before them. A transfer is
+// created before it settles, so bounding created_at as well spares reading
+// the partitions of the months after the replay.
+const balanceAtSQL = `
+WITH horizon AS (
+	SELECT COALESCE(max(settled_through), '-infinity') AS settled_through FROM transaction_archives
+), base AS (
+	SELECT * FROM (
+		(SELECT taken_at AS lo, $2::timestamptz AS hi, 1 AS sign, balance, taken_at
+		FROM balance_snapshots WHERE account_id = $1 AND taken_at <= $2
+			AND taken_at >= (SELECT settled_through FROM horizon)
+		ORDER BY taken_at DESC LIMIT 1)
+		UNION ALL
+		(SELECT $2::timestamptz, taken_at, -1, balance, taken_at
+		FROM balance_snapshots WHERE account_id = $1 AND taken_at > $2
+		ORDER BY taken_at LIMIT 1)
+		UNION ALL
+		SELECT $2::timestamptz, 'infinity'::timestamptz, -1, balance, $6::timestamptz
+		FROM accounts WHERE account_id = $1
+	) b
+	ORDER BY abs(extract(epoch FROM b.taken_at - $2::timestamptz))
+	LIMIT 1
+)
+SELECT base.balance + base.sign * (
+		COALESCE((SELECT sum(amount) FROM transactions
+			WHERE destination_account_id = $1 AND status = $3 AND settled_at > base.lo AND settled_at <= base.hi
+				AND created_at <= base.hi), 0)
+		- COALESCE((SELECT sum(amount) FROM transactions
+			WHERE source_account_id = $1 AND status = $3 AND settled_at > base.lo AND settled_at <= base.hi
+				AND created_at <= base.hi), 0)),
+	a.currency,
+	a.created_at IS NOT NULL AND a.created_at > $2,
+	$2 < horizon.settled_through
+FROM accounts a CROSS JOIN base CROSS JOIN horizon
+WHERE a.account_id = $1 AND ($4::text IS NULL OR a.tenant_id = $4) AND ($5::text IS NULL OR a.owner_id = $5)`
+
+// BalanceAt returns the balance accountID had at the given time, reconstructed
+// from the nearest balance snapshot or the current balance. It returns
+// ErrAccountNotFound if the account did not exist yet at that time, and
+// ErrHistoryArchived if the transactions since have been archived.
+func (s *Store) BalanceAt(ctx context.Context, accountID int64, at time.Time) (_ Balance, err error) {
+	ctx, span := startSpan(ctx, "BalanceAt",
+		attribute.Int64("account.id", accountID),
+		attribute.String("balance.at", at.Format(time.RFC3339Nano)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	b := Balance{AccountID: accountID, At: at}
+	var createdLater, archived bool
+	err = s.read.QueryRow(ctx, balanceAtSQL, accountID, at, StatusSucceeded, tenantArg(ctx), ownerArg(ctx), s.clock.Now()).Scan(&b.Amount, &b.Currency, &createdLater, &archived)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Balance{}, ErrAccountNotFound
+		}
+		return Balance{}, fmt.Errorf("balance at: %w", err)
+	}
+	if createdLater {
+		return Balance{}, ErrAccountNotFound
+	}
+	if archived {
+		return Balance{}, ErrHistoryArchived
+	}
+	return b, nil
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TransferItem is one transfer of a batch
+type TransferItem struct {
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	TransferDetails
+}
+
+// TransferResult is the outcome of one TransferItem. Err is nil on success.
+type TransferResult struct {
+	TransactionID int64
+	UUID          uuid.UUID
+	Err           error
+}
+
+// BatchItemError reports the item that aborted an atomic batch.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("transfer %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// TransferBatch performs items in order within a single DB transaction, with
+// all involved accounts, the fee accounts the items are charged by included,
+// locked up front in ascending id order.
+//
+// If atomic, the first failing item rolls the whole batch back and is returned
+// as a *BatchItemError. Otherwise failing items are recorded as failed
+// transactions, the others are applied, and the per-item outcomes are returned.
+func (s *Store) TransferBatch(ctx context.Context, items []TransferItem, atomic bool) (_ []TransferResult, err error) {
+	ctx, span := startSpan(ctx, "TransferBatch",
+		attribute.Int("batch.size", len(items)),
+		attribute.Bool("batch.atomic", atomic),
+	)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	ids, err := s.quoteFeeAccounts(ctx, tx, items)
+	if err != nil {
+		return nil, err
+	}
+	for _, it := range items {
+		ids = append(ids, it.SourceAccountID, it.DestinationAccountID)
+	}
+	accs, err := lockExistingAccounts(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	// Then the fee accounts of the items, whose fees depend on their locked sources
+	var quoted []feeCharge
+	for _, it := range items {
+		if src, ok := accs[it.SourceAccountID]; ok {
+			quoted = append(quoted, s.feeFor(src, it.DestinationAccountID, it.Amount))
+		}
+	}
+	if err := lockFeeAccounts(ctx, tx, accs, quoted...); err != nil {
+		return nil, err
+	}
+
+	// Apply the items to the locked accounts in memory; each sees the
+	// balances left by the ones before it
+	results := make([]TransferResult, len(items))
+	touched := make(map[int64]bool)
+	limits := s.newLimitTracker(tx)
+	rules := s.newRuleChecker(tx)
+	flagged := make([]*Rule, len(items))
+	fees := make([]feeCharge, len(items))
+	for i, it := range items {
+		src, srcOK := accs[it.SourceAccountID]
+		dst, dstOK := accs[it.DestinationAccountID]
+		scopeErr := s.checkScope(ctx, src, dst)
+		switch {
+		case !it.Amount.IsPositive():
+			results[i].Err = fmt.Errorf("amount must be positive")
+		case s.checkSameAccount(it.SourceAccountID, it.DestinationAccountID) != nil:
+			results[i].Err = ErrSameAccount
+		case !srcOK || !dstOK:
+			results[i].Err = CheckTransferAccounts(it.SourceAccountID, it.DestinationAccountID, srcOK, dstOK)
+		case scopeErr != nil:
+			results[i].Err = scopeErr
+		case src.Status != AccountActive || dst.Status != AccountActive:
+			results[i].Err = ErrAccountInactive
+		case src.Currency != dst.Currency:
+			results[i].Err = ErrCurrencyMismatch
+		}
+		if results[i].Err == nil {
+			results[i].Err = s.checkKYC(src, dst)
+		}
+		if results[i].Err == nil {
+			if err := checkCounterparties(ctx, tx, src.ID, dst.ID); errors.Is(err, ErrCounterpartyNotAllowed) {
+				results[i].Err = err
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		if results[i].Err == nil {
+			rule, err := rules.check(ctx, src.ID, dst.ID, it.Amount)
+			if err != nil {
+				return nil, err
+			}
+			if rule != nil && rule.Action == RuleBlock {
+				results[i].Err = ErrTransferBlocked
+			} else if rule != nil && rule.Action == RuleRequireApproval {
+				results[i].Err = ErrApprovalRequired
+			} else if rule != nil && rule.Action == RuleFlag {
+				flagged[i] = rule
+			}
+		}
+		var fee feeCharge
+		if results[i].Err == nil {
+			fee = s.feeFor(src, dst.ID, it.Amount)
+			results[i].Err = checkFeeAccount(fee, accs, src.Currency)
+		}
+		if results[i].Err == nil {
+			if err := limits.check(ctx, src, it.Amount); errors.Is(err, ErrLimitExceeded) {
+				results[i].Err = err
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		debit := it.Amount.Add(fee.Amount)
+		if results[i].Err == nil && src.AvailableBalance.LessThan(debit) {
+			results[i].Err = ErrInsufficientFunds
+		}
+		if results[i].Err == nil {
+			results[i].Err = CheckMinBalance(src, debit)
+		}
+		if results[i].Err != nil {
+			if atomic {
+				return nil, &BatchItemError{Index: i, Err: results[i].Err}
+			}
+			continue
+		}
+
+		limits.add(src.ID, it.Amount)
+		rules.add(src.ID, dst.ID)
+		src.Balance = src.Balance.Sub(debit)
+		src.AvailableBalance = src.AvailableBalance.Sub(debit)
+		accs[src.ID] = src
+		// Re-read dst in case it is also this item's source account
+		dst = accs[it.DestinationAccountID]
+		dst.Balance = dst.Balance.Add(it.Amount)
+		dst.AvailableBalance = dst.AvailableBalance.Add(it.Amount)
+		accs[dst.ID] = dst
+		touched[src.ID], touched[dst.ID] = true, true
+		if !fee.Amount.IsZero() {
+			// Re-read the fee account too, which may be the destination
+			feeAcc := accs[fee.AccountID]
+			feeAcc.Balance = feeAcc.Balance.Add(fee.Amount)
+			feeAcc.AvailableBalance = feeAcc.AvailableBalance.Add(fee.Amount)
+			accs[feeAcc.ID] = feeAcc
+			touched[feeAcc.ID] = true
+			fees[i] = fee
+		}
+	}
+
+	for id := range touched {
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, accs[id].Balance, id); err != nil {
+			return nil, fmt.Errorf("update balance for account %d: %w", id, err)
+		}
+	}
+	now := s.clock.Now()
+	for i, it := range items {
+		var t Transaction
+		if it.TransferDetails, err = s.withTransactionID(it.TransferDetails); err != nil {
+			return nil, err
+		}
+		if results[i].Err != nil {
+			// In the source account's currency, like transferSQL, if it exists
+			t, err = scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, error_message, reference, purpose_code, public_id, created_at, settled_at,
+					initiated_by, channel, request_id, transfer_group) VALUES ($1,$2,$3,COALESCE(NULLIF($14::text, ''), 'USD'),$4,$5,$6,$7,$8,$9,$9,$10,$11,$12,$13) RETURNING `+transactionColumns,
+				it.SourceAccountID, it.DestinationAccountID, it.Amount, StatusFailed, results[i].Err.Error(), it.Reference, it.PurposeCode, it.UUID, now,
+				it.InitiatedBy, it.Channel, it.RequestID, groupArg(it.GroupID), accs[it.SourceAccountID].Currency))
+		} else {
+			t, err = scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reference, purpose_code, public_id, created_at, settled_at,
+					initiated_by, channel, request_id, transfer_group) VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9,$10,$11,$12,$13) RETURNING `+transactionColumns,
+				it.SourceAccountID, it.DestinationAccountID, it.Amount, accs[it.SourceAccountID].Currency, StatusSucceeded, it.Reference, it.PurposeCode, it.UUID, now,
+				it.InitiatedBy, it.Channel, it.RequestID, groupArg(it.GroupID)))
+			results[i].TransactionID, results[i].UUID = t.ID, t.UUID
+		}
+		if err != nil {
+			return nil, fmt.Errorf("insert transaction log: %w", err)
+		}
+		if results[i].Err == nil && flagged[i] != nil {
+			if err := flagTransfer(ctx, tx, t, flagged[i]); err != nil {
+				return nil, err
+			}
+		}
+		if err := s.finishTransfer(ctx, tx, t); err != nil {
+			return nil, err
+		}
+		if err := s.recordFee(ctx, tx, t, fees[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, slices.Collect(maps.Keys(touched))...)
+	return results, nil
+}
@@ -0,0 +1,91 @@
+//go:build integration
+// +build integration
+
+package store
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// Benchmarks of Store.Transfer against the test database, to compare before
+// and after changes to the transfer path:
+//
+//	go test ./internal/store -tags=integration -run '^$' -bench Transfer -count 5
+
+// benchAccounts creates n accounts, with ids from 1, holding enough for any
+// benchmark run.
+func benchAccounts(b *testing.B, s *Store, n int) {
+	b.Helper()
+	ctx := context.Background()
+	for id := int64(1); id <= int64(n); id++ {
+		if err := s.CreateAccount(ctx, id, decimal.NewFromInt(1_000_000_000), "USD", AccountMetadata{}); err != nil {
+			b.Fatalf("CreateAccount %d failed: %v", id, err)
+		}
+	}
+}
+
+// BenchmarkTransfer measures transfers made one at a time.
+func BenchmarkTransfer(b *testing.B) {
+	s := setupTestStore(b)
+	benchAccounts(b, s, 2)
+	ctx := context.Background()
+	amount := decimal.RequireFromString("0.01")
+
+	b.ResetTimer()
+	for i := range b.N {
+		src, dst := int64(1+i%2), int64(2-i%2)
+		if _, err := s.Transfer(ctx, src, dst, amount, TransferDetails{}); err != nil {
+			b.Fatalf("Transfer failed: %v", err)
+		}
+	}
+}
+
+// BenchmarkTransfer_Parallel measures concurrent transfers between disjoint
+// pairs of accounts, which should not wait for each other's locks.
+func BenchmarkTransfer_Parallel(b *testing.B) {
+	const pairs = 64
+	s := setupTestStore(b)
+	benchAccounts(b, s, 2*pairs)
+	ctx := context.Background()
+	amount := decimal.RequireFromString("0.01")
+	var next atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		pair := next.Add(1) % pairs
+		src, dst := 2*pair+1, 2*pair+2
+		for pb.Next() {
+			if _, err := s.Transfer(ctx, src, dst, amount, TransferDetails{}); err != nil {
+				b.Errorf("Transfer failed: %v", err)
+				return
+			}
+			src, dst = dst, src
+		}
+	})
+}
+
+// BenchmarkTransfer_HotAccount measures concurrent transfers that all credit
+// the same account, as payouts into a treasury account would.
+func BenchmarkTransfer_HotAccount(b *testing.B) {
+	const sources = 64
+	s := setupTestStore(b)
+	benchAccounts(b, s, 1+sources)
+	ctx := context.Background()
+	amount := decimal.RequireFromString("0.01")
+	var next atomic.Int64
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		src := 2 + next.Add(1)%sources
+		for pb.Next() {
+			if _, err := s.Transfer(ctx, src, 1, amount, TransferDetails{}); err != nil {
+				b.Errorf("Transfer failed: %v", err)
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrCircuitOpen is returned by the store operations of a pool whose circuit
+// breaker is open, without waiting on the database.
+var ErrCircuitOpen = errors.New("database circuit breaker open")
+
+// Defaults of NewCircuitBreaker
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 10 * time.Second
+)
+
+// States of a CircuitBreaker, as reported by the store.circuit_breaker.state metric
+const (
+	BreakerClosed   = "closed"    // operations run
+	BreakerOpen     = "open"      // operations fail fast with ErrCircuitOpen
+	BreakerHalfOpen = "half_open" // one probe checks whether the database is back
+)
+
+var breakerStates = map[string]int64{BreakerClosed: 0, BreakerHalfOpen: 1, BreakerOpen: 2}
+
+// breakerRejections counts the connections refused by open circuit breakers.
+var breakerRejections, _ = otel.Meter(tracerName).Int64Counter("store.circuit_breaker.rejections",
+	metric.WithDescription("Store operations failed fast by an open circuit breaker"),
+	metric.WithUnit("{operation}"),
+)
+
+// CircuitBreaker stops a pool from piling operations onto a database that
+// is down. It opens after threshold consecutive connection failures, such as
+// refused connections, broken connections and timeouts; errors reported by
+// Postgres itself show it is up. While open, acquiring a connection fails with
+// ErrCircuitOpen. After the cooldown, one probe connection is pinged: the
+// breaker closes if it succeeds and opens again otherwise. Attach it to a pool
+// with PoolConfig.Breaker.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int       // consecutive connection failures
+	openedAt time.Time // when the breaker last opened
+	probing  time.Time // when the current probe started, zero if none
+}
+
+// NewCircuitBreaker returns a closed breaker opening after threshold
+// consecutive connection failures and probing after cooldown. Non-positive
+// values select the defaults. name labels its metrics, e.g. "primary".
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	b := &CircuitBreaker{name: name, threshold: threshold, cooldown: cooldown, now: time.Now, state: BreakerClosed}
+	attrs := metric.WithAttributes(attribute.String("pool", name))
+	_, _ = otel.Meter(tracerName).Int64ObservableGauge("store.circuit_breaker.state",
+		metric.WithDescription("State of the database circuit breaker: 0 closed, 1 half-open, 2 open"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(breakerStates[b.State()], attrs)
+			return nil
+		}))
+	return b
+}
+
+// State returns the state of the breaker, one of BreakerClosed, BreakerOpen
+// and BreakerHalfOpen.
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.currentState()
+}
+
+// currentState moves an open breaker to half-open once the cooldown is over,
+// and returns its state. b.mu must be held.
+func (b *CircuitBreaker) currentState() string {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		b.state = BreakerHalfOpen
+	}
+	return b.state
+}
+
+// Check reports an error while the breaker is not closed. It suits readiness
+// checks.
+func (b *CircuitBreaker) Check(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentState() {
+	case BreakerOpen:
+		return fmt.Errorf("open since %s after %d consecutive connection failures", b.openedAt.UTC().Format(time.RFC3339), b.failures)
+	case BreakerHalfOpen:
+		return errors.New("half open, probing the database")
+	}
+	return nil
+}
+
+// allow reports whether an operation may use the database. When probe is
+// true, the caller is the single probe of a half-open breaker and must report
+// its outcome with record.
+func (b *CircuitBreaker) allow(ctx context.Context) (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	switch b.currentState() {
+	case BreakerClosed:
+		return false, nil
+	case BreakerHalfOpen:
+		// A probe that never reported back must not keep the breaker shut
+		if b.probing.IsZero() || now.Sub(b.probing) >= b.cooldown {
+			b.probing = now
+			return true, nil
+		}
+	}
+	breakerRejections.Add(ctx, 1, metric.WithAttributes(attribute.String("pool", b.name)))
+	return false, ErrCircuitOpen
+}
+
+// record counts the outcome of a database call.
+func (b *CircuitBreaker) record(err error) {
+	if errors.Is(err, context.Canceled) {
+		// The caller gave up; this says nothing about the database
+		b.mu.Lock()
+		b.probing = time.Time{}
+		b.mu.Unlock()
+		return
+	}
+	failed := connectionFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = time.Time{}
+	if !failed {
+		b.state, b.failures = BreakerClosed, 0
+		return
+	}
+	b.failures++
+	// Failures of calls made before the breaker opened do not extend the cooldown
+	if b.state == BreakerHalfOpen || (b.state == BreakerClosed && b.failures >= b.threshold) {
+		b.state, b.openedAt = BreakerOpen, b.now()
+	}
+}
+
+// connectionFailure reports whether err shows the database unreachable,
+// rather than an error of the statement, which Postgres reports.
+func connectionFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var (
+		pgErr      *pgconn.PgError
+		connectErr *pgconn.ConnectError
+		netErr     net.Error
+	)
+	if errors.As(err, &pgErr) {
+		return false
+	}
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// beforeConnect refuses to open connections while the breaker is open.
+func (b *CircuitBreaker) beforeConnect(ctx context.Context, _ *pgx.ConnConfig) error {
+	// A probe here is the connection attempt, reported by breakerTracer
+	_, err := b.allow(ctx)
+	return err
+}
+
+// prepareConn refuses to hand out pooled connections while the breaker is
+// open. A probe pings the connection first.
+func (b *CircuitBreaker) prepareConn(ctx context.Context, conn *pgx.Conn) (bool, error) {
+	probe, err := b.allow(ctx)
+	if err != nil {
+		return true, err
+	}
+	if probe {
+		err := conn.Ping(ctx)
+		b.record(err)
+		if err != nil {
+			return false, ErrCircuitOpen
+		}
+	}
+	return true, nil
+}
+
+// breakerTracer is queryTracer, also reporting the outcome of connection
+// attempts and queries to a breaker.
+type breakerTracer struct {
+	queryTracer
+	breaker *CircuitBreaker
+}
+
+var _ pgx.ConnectTracer = breakerTracer{}
+
+func (t breakerTracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	t.queryTracer.TraceQueryEnd(ctx, conn, data)
+	t.breaker.record(data.Err)
+}
+
+func (t breakerTracer) TraceConnectStart(ctx context.Context, _ pgx.TraceConnectStartData) context.Context {
+	return ctx
+}
+
+func (t breakerTracer) TraceConnectEnd(_ context.Context, data pgx.TraceConnectEndData) {
+	t.breaker.record(data.Err)
+}
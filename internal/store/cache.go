@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/you/internal-transfers/internal/cache"
+)
+
+// DefaultAccountCacheTTL bounds how long a cached account may be served.
+const DefaultAccountCacheTTL = 5 * time.Second
+
+// WithAccountCache makes GetAccount serve accounts from c, caching them for
+// ttl. Entries are invalidated after every committed change to the account, so
+// staleness is limited to changes racing with a cache fill (and, with a
+// replica, replication lag at fill time), both bounded by ttl.
+func WithAccountCache(c cache.Cache, ttl time.Duration) Option {
+	return func(s *Store) {
+		s.cache = c
+		s.cacheTTL = ttl
+	}
+}
+
+// accountCacheLookups counts GetAccount cache lookups by result (hit or miss).
+var accountCacheLookups, _ = otel.Meter(tracerName).Int64Counter("store.account_cache.lookups",
+	metric.WithDescription("GetAccount cache lookups"),
+	metric.WithUnit("{lookup}"),
+)
+
+func accountCacheKey(id int64) string {
+	return "account:" + strconv.FormatInt(id, 10)
+}
+
+// cachedAccount returns the cached account, if any. Cache errors count as misses.
+func (s *Store) cachedAccount(ctx context.Context, id int64) (Account, bool) {
+	var acc Account
+	val, ok, err := s.cache.Get(ctx, accountCacheKey(id))
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+	if ok {
+		ok = json.Unmarshal(val, &acc) == nil
+	}
+	result := "miss"
+	if ok {
+		result = "hit"
+	}
+	accountCacheLookups.Add(ctx, 1, metric.WithAttributes(attribute.String("cache.result", result)))
+	return acc, ok
+}
+
+// cacheAccount stores acc in the cache, best effort.
+func (s *Store) cacheAccount(ctx context.Context, acc Account) {
+	val, err := json.Marshal(acc)
+	if err == nil {
+		err = s.cache.Set(ctx, accountCacheKey(acc.ID), val, s.cacheTTL)
+	}
+	if err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
+
+// invalidateAccounts drops the cached accounts. It must be called after the
+// change commits, or a concurrent GetAccount could cache the old state again.
+// Failures are recorded on the span; the entries then expire with their TTL.
+func (s *Store) invalidateAccounts(ctx context.Context, ids ...int64) {
+	if s.cache == nil || len(ids) == 0 {
+		return
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = accountCacheKey(id)
+	}
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+}
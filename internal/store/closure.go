@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrAccountNotEmpty is returned by CloseAccount for an account with pending
+// holds, a negative balance, or a positive one and nowhere to sweep it
+var ErrAccountNotEmpty = errors.New("account has a balance or pending holds")
+
+// CloseAccount closes an account for good. Its balance must be zero unless
+// sweepTo is given, in which case the balance is first transferred to sweepTo
+// in the same DB transaction and recorded with details as a succeeded
+// transfer, which is returned; the transaction is the zero value if there was
+// nothing to sweep. The sweep is exempt from limits and fraud rules, but
+// sweepTo must be active, in the same currency, in the same tenant unless
+// cross-tenant transfers are allowed, and allowed by the KYC statuses and
+// counterparty allowlists of both accounts. Accounts with pending holds are
+// not closed.
+func (s *Store) CloseAccount(ctx context.Context, accountID, sweepTo int64, details TransferDetails) (_ Account, _ Transaction, err error) {
+	ctx, span := startSpan(ctx, "CloseAccount",
+		attribute.Int64("account.id", accountID),
+		attribute.Int64("account.sweep_to", sweepTo),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if sweepTo == accountID {
+		return Account{}, Transaction{}, ErrSameAccount
+	}
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Account{}, Transaction{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	ids := []int64{accountID}
+	if sweepTo != 0 {
+		ids = append(ids, sweepTo)
+	}
+	accs, err := lockExistingAccounts(ctx, tx, ids)
+	if err != nil {
+		return Account{}, Transaction{}, err
+	}
+	acc, ok := accs[accountID]
+	if !ok || !inScope(ctx, acc) {
+		return Account{}, Transaction{}, ErrAccountNotFound
+	}
+	if !slices.Contains(accountTransitions[AccountClosed], acc.Status) {
+		return Account{}, Transaction{}, ErrInvalidTransition
+	}
+	if acc.AvailableBalance.LessThan(acc.Balance) || acc.Balance.IsNegative() {
+		return Account{}, Transaction{}, ErrAccountNotEmpty
+	}
+
+	var sweep Transaction
+	if acc.Balance.IsPositive() {
+		if sweepTo == 0 {
+			return Account{}, Transaction{}, ErrAccountNotEmpty
+		}
+		dst, ok := accs[sweepTo]
+		switch {
+		case !ok:
+			return Account{}, Transaction{}, &AccountNotFoundError{AccountID: sweepTo, Party: PartyDestination}
+		case acc.Tenant != dst.Tenant && !s.crossTenant:
+			return Account{}, Transaction{}, ErrCrossTenant
+		case dst.Status != AccountActive:
+			return Account{}, Transaction{}, ErrAccountInactive
+		case dst.Currency != acc.Currency:
+			return Account{}, Transaction{}, ErrCurrencyMismatch
+		}
+		if err := s.checkKYC(acc, dst); err != nil {
+			return Account{}, Transaction{}, err
+		}
+		if err := checkCounterparties(ctx, tx, accountID, sweepTo); err != nil {
+			return Account{}, Transaction{}, err
+		}
+		if details, err = s.withTransactionID(details); err != nil {
+			return Account{}, Transaction{}, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`, acc.Balance, sweepTo); err != nil {
+			return Account{}, Transaction{}, fmt.Errorf("update sweep balance: %w", err)
+		}
+		sweep, err = scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reference, purpose_code, public_id, created_at, settled_at,
+				initiated_by, channel, request_id)
+			VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9,$10,$11,$12)
+			RETURNING `+transactionColumns, accountID, sweepTo, acc.Balance, acc.Currency, StatusSucceeded, details.Reference, details.PurposeCode, details.UUID, s.clock.Now(),
+			details.InitiatedBy, details.Channel, details.RequestID))
+		if err != nil {
+			return Account{}, Transaction{}, fmt.Errorf("insert sweep: %w", err)
+		}
+		if err := s.finishTransfer(ctx, tx, sweep); err != nil {
+			return Account{}, Transaction{}, err
+		}
+	}
+
+	acc, err = scanAccount(tx.QueryRow(ctx, `UPDATE accounts SET status = $2, balance = 0 WHERE account_id = $1
+		RETURNING `+accountColumns, accountID, AccountClosed))
+	if err != nil {
+		return Account{}, Transaction{}, fmt.Errorf("close account: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return Account{}, Transaction{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, ids...)
+	return acc, sweep, nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrCounterpartyNotAllowed is returned for a transfer whose destination is
+// not on the outgoing allowlist of its source, or whose source is not on the
+// incoming allowlist of its destination.
+var ErrCounterpartyNotAllowed = errors.New("counterparty not allowed")
+
+// CounterpartyAllowlist restricts the counterparties of an account. A nil
+// list leaves its direction unrestricted; an empty one allows no
+// counterparty. UpdatedAt is zero if the allowlist was never set.
+type CounterpartyAllowlist struct {
+	AccountID int64
+	Outgoing  []int64 // accounts the account may transfer to
+	Incoming  []int64 // accounts the account may receive transfers from
+	UpdatedAt time.Time
+}
+
+// counterpartyDeniedCond is true if the allowlists forbid a transfer from $1
+// to $2, as transferSQL and checkCounterparties run it.
+const counterpartyDeniedCond = `EXISTS (SELECT 1 FROM counterparty_allowlists
+		WHERE account_id = $1 AND outgoing IS NOT NULL AND NOT $2::bigint = ANY(outgoing)
+			OR account_id = $2 AND incoming IS NOT NULL AND NOT $1::bigint = ANY(incoming))`
+
+// rowQuerier is satisfied by pools, connections and transactions
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// checkCounterparties returns ErrCounterpartyNotAllowed if the allowlists
+// forbid a transfer from srcID to dstID, for the transfer paths checking
+// accounts in Go rather than in transferSQL.
+func checkCounterparties(ctx context.Context, db rowQuerier, srcID, dstID int64) error {
+	var denied bool
+	if err := db.QueryRow(ctx, `SELECT `+counterpartyDeniedCond, srcID, dstID).Scan(&denied); err != nil {
+		return fmt.Errorf("check counterparties: %w", err)
+	}
+	if denied {
+		return ErrCounterpartyNotAllowed
+	}
+	return nil
+}
+
+// CounterpartyAllowlist returns the counterparty allowlist of the account,
+// unrestricted if it never had one. It returns ErrAccountNotFound if the
+// account does not exist or is out of ctx's scope.
+func (s *Store) CounterpartyAllowlist(ctx context.Context, accountID int64) (_ CounterpartyAllowlist, err error) {
+	ctx, span := startSpan(ctx, "CounterpartyAllowlist", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.GetAccount(ctx, accountID); err != nil {
+		return CounterpartyAllowlist{}, err
+	}
+	l := CounterpartyAllowlist{AccountID: accountID}
+	err = s.read.QueryRow(ctx, `SELECT outgoing, incoming, updated_at FROM counterparty_allowlists WHERE account_id = $1`,
+		accountID).Scan(&l.Outgoing, &l.Incoming, &l.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return CounterpartyAllowlist{}, fmt.Errorf("get counterparty allowlist: %w", err)
+	}
+	return l, nil
+}
+
+// SetCounterpartyAllowlist replaces the counterparty allowlist of l.AccountID
+// and returns it, with the ids sorted and deduplicated. It applies from the
+// next transfer of the account, including the capture of holds already
+// placed, sweeps and settlements.
+// The listed accounts need not exist. It returns ErrAccountNotFound if the
+// account does not exist or is out of ctx's scope.
+func (s *Store) SetCounterpartyAllowlist(ctx context.Context, l CounterpartyAllowlist) (_ CounterpartyAllowlist, err error) {
+	ctx, span := startSpan(ctx, "SetCounterpartyAllowlist", attribute.Int64("account.id", l.AccountID))
+	defer func() { endSpan(span, err) }()
+
+	l.Outgoing, l.Incoming = compactIDs(l.Outgoing), compactIDs(l.Incoming)
+	err = s.pool.QueryRow(ctx, `INSERT INTO counterparty_allowlists (account_id, outgoing, incoming, updated_at)
+		SELECT account_id, $2, $3, $4 FROM accounts WHERE account_id = $1 AND `+accountScopeCond(5)+`
+		ON CONFLICT (account_id) DO UPDATE
+			SET outgoing = EXCLUDED.outgoing, incoming = EXCLUDED.incoming, updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`, l.AccountID, l.Outgoing, l.Incoming, s.clock.Now(), tenantArg(ctx), ownerArg(ctx)).Scan(&l.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return CounterpartyAllowlist{}, ErrAccountNotFound
+		}
+		return CounterpartyAllowlist{}, fmt.Errorf("set counterparty allowlist: %w", err)
+	}
+	return l, nil
+}
+
+// compactIDs returns ids sorted without duplicates, keeping nil and empty
+// lists apart.
+func compactIDs(ids []int64) []int64 {
+	if ids == nil {
+		return nil
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// exportFetchSize is the number of rows ExportTransactions fetches per round trip
+const exportFetchSize = 500
+
+// ExportTransactions calls fn for each transaction of accountID created in
+// [from, to), oldest first; zero times leave that end open. Rows are fetched
+// in chunks through a server-side cursor over one snapshot, so memory use does
+// not grow with the size of the export. It returns ErrAccountNotFound, before
+// any call to fn, if the account does not exist, and stops at the first error
+// returned by fn.
+func (s *Store) ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, fn func(Transaction) error) (err error) {
+	ctx, span := startSpan(ctx, "ExportTransactions", attribute.Int64("account.id", accountID))
+	var n int
+	defer func() {
+		span.SetAttributes(attribute.Int("export.rows", n))
+		endSpan(span, err)
+	}()
+
+	tx, err := s.read.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var exists bool
+	if err := tx.QueryRow(ctx, accountExistsSQL, accountID, tenantArg(ctx), ownerArg(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("check account: %w", err)
+	}
+	if !exists {
+		return ErrAccountNotFound
+	}
+
+	var lo, hi *time.Time
+	if !from.IsZero() {
+		lo = &from
+	}
+	if !to.IsZero() {
+		hi = &to
+	}
+	_, err = tx.Exec(ctx, `DECLARE export_transactions NO SCROLL CURSOR FOR
+		SELECT `+transactionColumns+` FROM transactions
+		WHERE (source_account_id = $1 OR destination_account_id = $1)
+			AND ($2::timestamptz IS NULL OR created_at >= $2)
+			AND ($3::timestamptz IS NULL OR created_at < $3)
+		ORDER BY created_at, id`, accountID, lo, hi)
+	if err != nil {
+		return fmt.Errorf("declare cursor: %w", err)
+	}
+
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM export_transactions`, exportFetchSize))
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		txs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Transaction, error) {
+			return scanTransaction(row)
+		})
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		for _, t := range txs {
+			if err := fn(t); err != nil {
+				return err
+			}
+			n++
+		}
+		if len(txs) < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrFeePolicyNotFound is returned by DeleteFeePolicy for an unknown policy.
+	ErrFeePolicyNotFound = errors.New("fee policy not found")
+	// ErrFeeAccountUnavailable is returned for a transfer charged a fee whose
+	// fee account no longer exists, is not active or is in another currency,
+	// and for the reversal of a transfer whose fee account cannot refund it.
+	ErrFeeAccountUnavailable = errors.New("fee account unavailable")
+)
+
+// FeePolicy is a row of the fee_policies table. It charges transfers in
+// Currency out of accounts of Tenant (any if empty) tagged AccountTag (any if
+// empty) FlatFee plus Percentage percent of the amount, credited to
+// FeeAccountID. Transfers are matched against the policies in Priority order,
+// then by ID, and the first matching one decides the fee.
+type FeePolicy struct {
+	ID           int64
+	Name         string
+	Priority     int
+	Tenant       string
+	AccountTag   string
+	Currency     string
+	FlatFee      decimal.Decimal
+	Percentage   decimal.Decimal
+	FeeAccountID int64
+	CreatedAt    time.Time
+}
+
+// Fee returns the fee of a transfer of amount, rounded up to scale decimal places.
+func (p FeePolicy) Fee(amount decimal.Decimal, scale int32) decimal.Decimal {
+	return p.FlatFee.Add(amount.Mul(p.Percentage).Div(decimal.NewFromInt(100))).RoundCeil(scale)
+}
+
+// matches reports whether p charges transfers out of src.
+func (p FeePolicy) matches(src Account) bool {
+	return p.Currency == src.Currency && p.FeeAccountID != src.ID &&
+		(p.Tenant == "" || p.Tenant == src.Tenant) &&
+		(p.AccountTag == "" || slices.Contains(src.Tags, p.AccountTag))
+}
+
+// WithFeeScale sets the decimal places fees are rounded up to, by currency;
+// 2 by default.
+func WithFeeScale(scale func(currency string) int32) Option {
+	return func(s *Store) {
+		s.feeScale = scale
+	}
+}
+
+// feePolicyColumns is the select list matching scanFeePolicy
+const feePolicyColumns = `id, name, priority, COALESCE(tenant_id, ''), COALESCE(account_tag, ''), currency, flat_fee, percentage, fee_account_id, created_at`
+
+// scanFeePolicy scans a row selected with feePolicyColumns.
+func scanFeePolicy(row pgx.Row) (FeePolicy, error) {
+	var p FeePolicy
+	if err := row.Scan(&p.ID, &p.Name, &p.Priority, &p.Tenant, &p.AccountTag, &p.Currency, &p.FlatFee, &p.Percentage, &p.FeeAccountID, &p.CreatedAt); err != nil {
+		return FeePolicy{}, err
+	}
+	return p, nil
+}
+
+// CreateFeePolicy stores p and reloads the fee policies of this store; other
+// instances pick it up on their next ReloadFeePolicies. The fee account must
+// exist, else ErrAccountNotFound is returned, and be in the policy's currency,
+// else ErrCurrencyMismatch.
+func (s *Store) CreateFeePolicy(ctx context.Context, p FeePolicy) (_ FeePolicy, err error) {
+	ctx, span := startSpan(ctx, "CreateFeePolicy", attribute.Int64("fee.account_id", p.FeeAccountID))
+	defer func() { endSpan(span, err) }()
+
+	var currency string
+	if err := s.pool.QueryRow(ctx, `SELECT currency FROM accounts WHERE account_id = $1`, p.FeeAccountID).Scan(&currency); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return FeePolicy{}, ErrAccountNotFound
+		}
+		return FeePolicy{}, fmt.Errorf("check fee account: %w", err)
+	}
+	if currency != p.Currency {
+		return FeePolicy{}, ErrCurrencyMismatch
+	}
+	p, err = scanFeePolicy(s.pool.QueryRow(ctx, `INSERT INTO fee_policies (name, priority, tenant_id, account_tag, currency, flat_fee, percentage, fee_account_id)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8) RETURNING `+feePolicyColumns,
+		p.Name, p.Priority, p.Tenant, p.AccountTag, p.Currency, p.FlatFee, p.Percentage, p.FeeAccountID))
+	if err != nil {
+		return FeePolicy{}, fmt.Errorf("create fee policy: %w", err)
+	}
+	if _, err := s.ReloadFeePolicies(ctx); err != nil {
+		return FeePolicy{}, err
+	}
+	return p, nil
+}
+
+// ListFeePolicies returns all fee policies in the order transfers are matched against them.
+func (s *Store) ListFeePolicies(ctx context.Context) (_ []FeePolicy, err error) {
+	ctx, span := startSpan(ctx, "ListFeePolicies")
+	defer func() { endSpan(span, err) }()
+
+	return listFeePolicies(ctx, s.read)
+}
+
+// listFeePolicies reads the fee policies from db in the order transfers are
+// matched against them.
+func listFeePolicies(ctx context.Context, db querier) ([]FeePolicy, error) {
+	rows, err := db.Query(ctx, `SELECT `+feePolicyColumns+` FROM fee_policies ORDER BY priority, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list fee policies: %w", err)
+	}
+	policies, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (FeePolicy, error) {
+		return scanFeePolicy(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list fee policies: %w", err)
+	}
+	return policies, nil
+}
+
+// DeleteFeePolicy deletes a fee policy and reloads the fee policies of this
+// store. Fees already charged are kept.
+func (s *Store) DeleteFeePolicy(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteFeePolicy", attribute.Int64("fee.policy_id", id))
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM fee_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete fee policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFeePolicyNotFound
+	}
+	_, err = s.ReloadFeePolicies(ctx)
+	return err
+}
+
+// ReloadFeePolicies replaces the fee policies transfers are charged by with
+// those in the database and returns how many there are. Until it first
+// succeeds no fees are charged. Like ReloadRules, it reads the primary.
+func (s *Store) ReloadFeePolicies(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "ReloadFeePolicies")
+	defer func() { endSpan(span, err) }()
+
+	policies, err := listFeePolicies(ctx, s.pool)
+	if err != nil {
+		return 0, err
+	}
+	s.fees.Store(&policies)
+	return len(policies), nil
+}
+
+// loadedFeePolicies returns the fee policies loaded by the last ReloadFeePolicies.
+func (s *Store) loadedFeePolicies() []FeePolicy {
+	if policies := s.fees.Load(); policies != nil {
+		return *policies
+	}
+	return nil
+}
+
+// feeCharge is the fee a transfer is charged, credited to AccountID. The zero
+// value charges nothing.
+type feeCharge struct {
+	AccountID int64
+	Amount    decimal.Decimal
+}
+
+// feeFor returns the fee of a transfer of amount out of src to dstID by the
+// first matching fee policy. Transfers from an account to itself are not
+// charged.
+func (s *Store) feeFor(src Account, dstID int64, amount decimal.Decimal) feeCharge {
+	if src.ID == dstID {
+		return feeCharge{}
+	}
+	for _, p := range s.loadedFeePolicies() {
+		if !p.matches(src) {
+			continue
+		}
+		scale := int32(2)
+		if s.feeScale != nil {
+			scale = s.feeScale(src.Currency)
+		}
+		if fee := p.Fee(amount, scale); fee.IsPositive() {
+			return feeCharge{AccountID: p.FeeAccountID, Amount: fee}
+		}
+		return feeCharge{}
+	}
+	return feeCharge{}
+}
+
+// checkFeeAccount returns ErrFeeAccountUnavailable unless the fee account of
+// fee is among the locked accs, active and in currency.
+func checkFeeAccount(fee feeCharge, accs map[int64]Account, currency string) error {
+	if fee.Amount.IsZero() {
+		return nil
+	}
+	acc, ok := accs[fee.AccountID]
+	if !ok || acc.Status != AccountActive || acc.Currency != currency {
+		return ErrFeeAccountUnavailable
+	}
+	return nil
+}
+
+// quoteFeeAccounts returns the fee accounts items are charged by, quoted from
+// an unlocked read of their sources, for callers to lock along with the
+// items' accounts in ascending id order: a transfer locks the one fee account
+// it credits rather than those of every policy. The fees are worked out again
+// from the locked sources; see lockFeeAccounts.
+func (s *Store) quoteFeeAccounts(ctx context.Context, tx pgx.Tx, items []TransferItem) ([]int64, error) {
+	if len(s.loadedFeePolicies()) == 0 {
+		return nil, nil
+	}
+	srcIDs := make([]int64, len(items))
+	for i, it := range items {
+		srcIDs[i] = it.SourceAccountID
+	}
+	srcs, err := queryAll(ctx, tx, scanAccount, `SELECT `+accountColumns+` FROM accounts WHERE account_id = ANY($1)`, srcIDs)
+	if err != nil {
+		return nil, fmt.Errorf("quote fees: %w", err)
+	}
+	byID := make(map[int64]Account, len(srcs))
+	for _, src := range srcs {
+		byID[src.ID] = src
+	}
+	var ids []int64
+	for _, it := range items {
+		if src, ok := byID[it.SourceAccountID]; ok {
+			if fee := s.feeFor(src, it.DestinationAccountID, it.Amount); !fee.Amount.IsZero() {
+				ids = append(ids, fee.AccountID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// lockFeeAccounts locks the fee accounts credited by fees that are not among
+// the locked accs yet and adds them to accs. Those are fee accounts the locked
+// sources are charged by but quoteFeeAccounts did not quote, the tags or
+// tenant of a source having changed in between, so they are locked out of
+// ascending id order; a deadlock this causes is retried like any other.
+// Callers still check the fee accounts once locked, with checkFeeAccount or
+// transferSQL.
+func lockFeeAccounts(ctx context.Context, tx pgx.Tx, accs map[int64]Account, fees ...feeCharge) error {
+	var ids []int64
+	for _, fee := range fees {
+		if _, ok := accs[fee.AccountID]; !ok && !fee.Amount.IsZero() {
+			ids = append(ids, fee.AccountID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	locked, err := lockExistingAccounts(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+	for id, acc := range locked {
+		accs[id] = acc
+	}
+	return nil
+}
+
+// lockTransferAccounts locks the accounts of a transfer of amount from srcID
+// to dstID in tx, along with the fee account of its fee, and returns the fee,
+// which transferSQL then checks and credits.
+func (s *Store) lockTransferAccounts(ctx context.Context, tx pgx.Tx, srcID, dstID int64, amount decimal.Decimal) (feeCharge, error) {
+	if len(s.loadedFeePolicies()) == 0 {
+		if _, err := tx.Exec(ctx, lockTransferAccountsSQL, srcID, dstID); err != nil {
+			return feeCharge{}, fmt.Errorf("lock accounts: %w", err)
+		}
+		return feeCharge{}, nil
+	}
+	feeIDs, err := s.quoteFeeAccounts(ctx, tx, []TransferItem{{SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount}})
+	if err != nil {
+		return feeCharge{}, err
+	}
+	accs, err := lockExistingAccounts(ctx, tx, append([]int64{srcID, dstID}, feeIDs...))
+	if err != nil {
+		return feeCharge{}, err
+	}
+	src, ok := accs[srcID]
+	if !ok {
+		return feeCharge{}, nil
+	}
+	fee := s.feeFor(src, dstID, amount)
+	if err := lockFeeAccounts(ctx, tx, accs, fee); err != nil {
+		return feeCharge{}, err
+	}
+	return fee, nil
+}
+
+// recordFee records the fee charged on the succeeded transfer t, once the
+// balances are moved, as a transaction of its own linked to t, and writes its
+// event.
+func (s *Store) recordFee(ctx context.Context, tx pgx.Tx, t Transaction, fee feeCharge) error {
+	if fee.Amount.IsZero() || t.Status != StatusSucceeded {
+		return nil
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return err
+	}
+	f, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, kind, fee_of, fee_of_public_id, public_id, created_at,
+			settled_at, initiated_by, channel, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10, $11, $12, $13)
+		RETURNING `+transactionColumns, t.SourceAccountID, fee.AccountID, fee.Amount, t.Currency, StatusSucceeded, KindFee, t.ID, t.UUID, uid, t.CreatedAt,
+		t.InitiatedBy, t.Channel, t.RequestID))
+	if err != nil {
+		return fmt.Errorf("insert fee of transaction %d: %w", t.ID, err)
+	}
+	return s.finishTransfer(ctx, tx, f)
+}
@@ -0,0 +1,40 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFeeFor(t *testing.T) {
+	s := &Store{}
+	s.fees.Store(&[]FeePolicy{
+		{ID: 1, Tenant: "acme", AccountTag: "retail", Currency: "USD", FlatFee: decimal.NewFromInt(1), FeeAccountID: 90},
+		{ID: 2, Currency: "USD", Percentage: decimal.RequireFromString("0.5"), FeeAccountID: 91},
+	})
+	retail := Account{ID: 1, Currency: "USD", Tenant: "acme", AccountMetadata: AccountMetadata{Tags: []string{"retail"}}}
+
+	for name, c := range map[string]struct {
+		src    Account
+		dstID  int64
+		amount string
+		want   feeCharge
+	}{
+		"first match":            {retail, 2, "10", feeCharge{AccountID: 90, Amount: decimal.NewFromInt(1)}},
+		"other tag":              {Account{ID: 1, Currency: "USD", Tenant: "acme"}, 2, "10", feeCharge{AccountID: 91, Amount: decimal.RequireFromString("0.05")}},
+		"rounded up":             {Account{ID: 1, Currency: "USD"}, 2, "0.01", feeCharge{AccountID: 91, Amount: decimal.RequireFromString("0.01")}},
+		"other currency":         {Account{ID: 1, Currency: "EUR"}, 2, "10", feeCharge{}},
+		"out of the fee account": {Account{ID: 91, Currency: "USD"}, 2, "10", feeCharge{}},
+		"same account":           {retail, 1, "10", feeCharge{}},
+	} {
+		got := s.feeFor(c.src, c.dstID, decimal.RequireFromString(c.amount))
+		if got.AccountID != c.want.AccountID || !got.Amount.Equal(c.want.Amount) {
+			t.Fatalf("%s: expected %+v, got %+v", name, c.want, got)
+		}
+	}
+
+	s.feeScale = func(string) int32 { return 0 }
+	if got := s.feeFor(Account{ID: 1, Currency: "USD"}, 2, decimal.NewFromInt(10)); !got.Amount.Equal(decimal.NewFromInt(1)) {
+		t.Fatalf("expected the fee rounded up to 1, got %s", got.Amount)
+	}
+}
@@ -0,0 +1,388 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Errors returned by hold operations
+var (
+	ErrHoldNotFound   = errors.New("hold not found")
+	ErrHoldNotPending = errors.New("hold is no longer pending")
+)
+
+// Hold statuses. Only pending holds reserve funds and may be captured or released.
+const (
+	HoldPending  = "pending"
+	HoldCaptured = "captured"
+	HoldReleased = "released"
+	HoldExpired  = "expired"
+)
+
+// Hold is a row of the holds table: an amount reserved on the source account
+// that is either captured into a transfer to the destination or released.
+// The fee its capture is charged, by the fee policies as of its placement, is
+// reserved along with it.
+type Hold struct {
+	ID                   int64
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Currency             string
+	Status               string
+	TransactionID        int64     // set once captured
+	TransactionUUID      uuid.UUID // likewise, the public id of the transaction
+	CreatedAt            time.Time
+	ExpiresAt            time.Time
+	Fee                  decimal.Decimal
+	FeeAccountID         int64 // 0 if no fee is charged
+}
+
+// reserved is the part of the source account's balance a pending h reserves.
+func (h Hold) reserved() decimal.Decimal {
+	return h.Amount.Add(h.Fee)
+}
+
+// holdColumns is the select list matching scanHold
+const holdColumns = `id, source_account_id, destination_account_id, amount, currency, status, COALESCE(transaction_id, 0), created_at, expires_at, fee, COALESCE(fee_account_id, 0), transaction_public_id`
+
+// scanHold scans a row selected with holdColumns.
+func scanHold(row pgx.Row) (Hold, error) {
+	var h Hold
+	if err := row.Scan(&h.ID, &h.SourceAccountID, &h.DestinationAccountID, &h.Amount, &h.Currency, &h.Status, &h.TransactionID, &h.CreatedAt, &h.ExpiresAt, &h.Fee, &h.FeeAccountID, &h.TransactionUUID); err != nil {
+		return Hold{}, err
+	}
+	return h, nil
+}
+
+// CreateHold reserves amount on srcID for a later transfer to dstID. The hold
+// expires at expiresAt unless captured or released before. srcID and dstID
+// must be different accounts, and amount within the limits of srcID, like a
+// transfer. A hold matching a block rule is refused with ErrTransferBlocked,
+// one matching a require_approval rule with ErrApprovalRequired. The fee of the
+// transfer is reserved along with amount and charged on capture.
+func (s *Store) CreateHold(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (_ Hold, err error) {
+	ctx, span := startSpan(ctx, "CreateHold",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return Hold{}, fmt.Errorf("amount must be positive")
+	}
+	if srcID == dstID {
+		return Hold{}, ErrSameAccount
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Hold{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	feeIDs, err := s.quoteFeeAccounts(ctx, tx, []TransferItem{{SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount}})
+	if err != nil {
+		return Hold{}, err
+	}
+	accs, err := lockExistingAccounts(ctx, tx, append([]int64{srcID, dstID}, feeIDs...))
+	if err != nil {
+		return Hold{}, err
+	}
+	src, srcOK := accs[srcID]
+	dst, dstOK := accs[dstID]
+	if err := CheckTransferAccounts(srcID, dstID, srcOK, dstOK); err != nil {
+		return Hold{}, err
+	}
+	if err := s.checkScope(ctx, src, dst); err != nil {
+		return Hold{}, err
+	}
+	if src.Status != AccountActive || dst.Status != AccountActive {
+		return Hold{}, ErrAccountInactive
+	}
+	if src.Currency != dst.Currency {
+		return Hold{}, ErrCurrencyMismatch
+	}
+	if err := s.checkKYC(src, dst); err != nil {
+		return Hold{}, err
+	}
+	if err := checkCounterparties(ctx, tx, srcID, dstID); err != nil {
+		return Hold{}, err
+	}
+	if err := s.newLimitTracker(tx).check(ctx, src, amount); err != nil {
+		return Hold{}, err
+	}
+	if _, err := s.checkHoldRules(ctx, tx, srcID, dstID, amount); err != nil {
+		return Hold{}, err
+	}
+	fee := s.feeFor(src, dstID, amount)
+	if err := lockFeeAccounts(ctx, tx, accs, fee); err != nil {
+		return Hold{}, err
+	}
+	if err := checkFeeAccount(fee, accs, src.Currency); err != nil {
+		return Hold{}, err
+	}
+	reserved := amount.Add(fee.Amount)
+	if src.AvailableBalance.LessThan(reserved) {
+		return Hold{}, ErrInsufficientFunds
+	}
+	if err := CheckMinBalance(src, reserved); err != nil {
+		return Hold{}, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET held_balance = held_balance + $1 WHERE account_id = $2`, reserved, srcID); err != nil {
+		return Hold{}, fmt.Errorf("reserve amount: %w", err)
+	}
+	now := s.clock.Now()
+	h, err := scanHold(tx.QueryRow(ctx, `INSERT INTO holds (source_account_id, destination_account_id, amount, currency, expires_at, created_at, updated_at, fee, fee_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $6, $7, NULLIF($8::bigint, 0)) RETURNING `+holdColumns,
+		srcID, dstID, amount, src.Currency, expiresAt, now, fee.Amount, fee.AccountID))
+	if err != nil {
+		return Hold{}, fmt.Errorf("insert hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Hold{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, srcID)
+	return h, nil
+}
+
+// checkHoldRules evaluates the rules for a hold of amount from srcID to dstID.
+// Holds cannot await approval, so a require_approval rule refuses them like a
+// block rule; a matching flag rule is returned for the capture to be queued
+// for review.
+func (s *Store) checkHoldRules(ctx context.Context, tx pgx.Tx, srcID, dstID int64, amount decimal.Decimal) (*Rule, error) {
+	rule, err := s.newRuleChecker(tx).check(ctx, srcID, dstID, amount)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case rule != nil && rule.Action == RuleBlock:
+		return nil, ErrTransferBlocked
+	case rule != nil && rule.Action == RuleRequireApproval:
+		return nil, ErrApprovalRequired
+	case rule != nil && rule.Action == RuleFlag:
+		return rule, nil
+	}
+	return nil, nil
+}
+
+// holdScopeCond restricts holds to those on a source account in the scope of
+// tenant $2 and owner $3, unless both are NULL.
+var holdScopeCond = `($2::text IS NULL AND $3::text IS NULL
+	OR EXISTS (SELECT 1 FROM accounts WHERE account_id = holds.source_account_id AND ` + accountScopeCond(2) + `))`
+
+// GetHold fetches a hold by id. Scoped to a tenant or owner, only holds on its accounts are found.
+func (s *Store) GetHold(ctx context.Context, id int64) (_ Hold, err error) {
+	ctx, span := startSpan(ctx, "GetHold", attribute.Int64("hold.id", id))
+	defer func() { endSpan(span, err) }()
+
+	h, err := scanHold(s.read.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1 AND `+holdScopeCond, id, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Hold{}, ErrHoldNotFound
+		}
+		return Hold{}, fmt.Errorf("get hold: %w", err)
+	}
+	return h, nil
+}
+
+// lockPendingHold selects the hold FOR UPDATE and checks that it can still be
+// captured or released. Holds are always locked before their accounts.
+func lockPendingHold(ctx context.Context, tx pgx.Tx, id int64) (Hold, error) {
+	h, err := scanHold(tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1 AND `+holdScopeCond+` FOR UPDATE`, id, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Hold{}, ErrHoldNotFound
+		}
+		return Hold{}, fmt.Errorf("lock hold %d: %w", id, err)
+	}
+	if h.Status != HoldPending {
+		return Hold{}, ErrHoldNotPending
+	}
+	return h, nil
+}
+
+// CaptureHold completes a pending hold by transferring its amount from the
+// source to the destination account. The accounts' KYC statuses, counterparty
+// allowlists, limits and rules are checked again, so a hold they no longer
+// allow since it was placed cannot be captured; the capture counts towards the
+// daily limit like a transfer, and is queued for review if it matches a flag
+// rule. The fee reserved by the hold is charged, as a fee transaction, unless
+// its fee account can no longer be credited (ErrFeeAccountUnavailable).
+func (s *Store) CaptureHold(ctx context.Context, id int64) (_ Hold, err error) {
+	ctx, span := startSpan(ctx, "CaptureHold", attribute.Int64("hold.id", id))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Hold{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	h, err := lockPendingHold(ctx, tx, id)
+	if err != nil {
+		return Hold{}, err
+	}
+	// An expired hold no longer reserves funds, even before the expiry worker has released it
+	now := s.clock.Now()
+	if !h.ExpiresAt.After(now) {
+		return Hold{}, ErrHoldNotPending
+	}
+	ids := []int64{h.SourceAccountID, h.DestinationAccountID}
+	if h.FeeAccountID != 0 {
+		ids = append(ids, h.FeeAccountID)
+	}
+	accs, err := lockAccounts(ctx, tx, ids...)
+	if err != nil {
+		return Hold{}, err
+	}
+	src, dst := accs[h.SourceAccountID], accs[h.DestinationAccountID]
+	if src.Status != AccountActive || dst.Status != AccountActive {
+		return Hold{}, ErrAccountInactive
+	}
+	if err := s.checkKYC(src, dst); err != nil {
+		return Hold{}, err
+	}
+	if err := checkCounterparties(ctx, tx, src.ID, dst.ID); err != nil {
+		return Hold{}, err
+	}
+	if err := s.newLimitTracker(tx).check(ctx, src, h.Amount); err != nil {
+		return Hold{}, err
+	}
+	rule, err := s.checkHoldRules(ctx, tx, src.ID, dst.ID, h.Amount)
+	if err != nil {
+		return Hold{}, err
+	}
+	fee := feeCharge{AccountID: h.FeeAccountID, Amount: h.Fee}
+	if err := checkFeeAccount(fee, accs, h.Currency); err != nil {
+		return Hold{}, err
+	}
+
+	amount := h.Amount
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance - $1, held_balance = held_balance - $1 WHERE account_id = $2`, h.reserved(), h.SourceAccountID); err != nil {
+		return Hold{}, fmt.Errorf("debit source: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`, amount, h.DestinationAccountID); err != nil {
+		return Hold{}, fmt.Errorf("credit destination: %w", err)
+	}
+	if !fee.Amount.IsZero() {
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + $1 WHERE account_id = $2`, fee.Amount, fee.AccountID); err != nil {
+			return Hold{}, fmt.Errorf("credit fee account: %w", err)
+		}
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return Hold{}, err
+	}
+	t, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, public_id, created_at, settled_at) VALUES ($1,$2,$3,$4,$5,$6,$7,$7) RETURNING `+transactionColumns,
+		h.SourceAccountID, h.DestinationAccountID, amount, h.Currency, StatusSucceeded, uid, now))
+	if err != nil {
+		return Hold{}, fmt.Errorf("insert transaction log: %w", err)
+	}
+	if err := s.finishTransfer(ctx, tx, t); err != nil {
+		return Hold{}, err
+	}
+	if rule != nil {
+		if err := flagTransfer(ctx, tx, t, rule); err != nil {
+			return Hold{}, err
+		}
+	}
+	if err := s.recordFee(ctx, tx, t, fee); err != nil {
+		return Hold{}, err
+	}
+	h.TransactionID, h.TransactionUUID = t.ID, t.UUID
+	if _, err := tx.Exec(ctx, `UPDATE holds SET status = $1, transaction_id = $2, transaction_public_id = $5, updated_at = $4 WHERE id = $3`,
+		HoldCaptured, h.TransactionID, id, now, h.TransactionUUID); err != nil {
+		return Hold{}, fmt.Errorf("update hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Hold{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, h.SourceAccountID, h.DestinationAccountID)
+	if !fee.Amount.IsZero() {
+		s.invalidateAccounts(ctx, fee.AccountID)
+	}
+	h.Status = HoldCaptured
+	return h, nil
+}
+
+// ReleaseHold cancels a pending hold and makes its amount available again.
+func (s *Store) ReleaseHold(ctx context.Context, id int64) (_ Hold, err error) {
+	ctx, span := startSpan(ctx, "ReleaseHold", attribute.Int64("hold.id", id))
+	defer func() { endSpan(span, err) }()
+
+	return s.endHold(ctx, id, HoldReleased)
+}
+
+// ExpireHolds releases pending holds whose expiry is at or before now and
+// returns how many were expired.
+func (s *Store) ExpireHolds(ctx context.Context, now time.Time) (_ int, err error) {
+	ctx, span := startSpan(ctx, "ExpireHolds")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `SELECT id FROM holds WHERE status = $1 AND expires_at <= $2 ORDER BY id`, HoldPending, now)
+	if err != nil {
+		return 0, fmt.Errorf("list expired holds: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("list expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		if _, err := s.endHold(ctx, id, HoldExpired); err != nil {
+			// Captured or released concurrently
+			if errors.Is(err, ErrHoldNotPending) {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// endHold moves a pending hold to status without transferring funds.
+func (s *Store) endHold(ctx context.Context, id int64, status string) (Hold, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Hold{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	h, err := lockPendingHold(ctx, tx, id)
+	if err != nil {
+		return Hold{}, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET held_balance = held_balance - $1 WHERE account_id = $2`, h.reserved(), h.SourceAccountID); err != nil {
+		return Hold{}, fmt.Errorf("release amount: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE holds SET status = $1, updated_at = $3 WHERE id = $2`, status, id, s.clock.Now()); err != nil {
+		return Hold{}, fmt.Errorf("update hold: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Hold{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, h.SourceAccountID)
+	h.Status = status
+	return h, nil
+}
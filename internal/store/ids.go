@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// IDGenerator generates the public ids of transactions. They are generated
+// before the row is inserted, so callers can learn a transfer's id before it
+// commits.
+type IDGenerator interface {
+	NewID() (uuid.UUID, error)
+}
+
+// UUIDv7 generates time-ordered UUIDs (RFC 9562 version 7), which index as
+// well as a sequence without revealing how many transactions there are.
+type UUIDv7 struct{}
+
+// NewID implements IDGenerator
+func (UUIDv7) NewID() (uuid.UUID, error) {
+	return uuid.NewV7()
+}
+
+// WithIDGenerator replaces the UUIDv7 generator of transaction ids, e.g. with
+// a deterministic one in tests.
+func WithIDGenerator(g IDGenerator) Option {
+	return func(s *Store) {
+		s.ids = g
+	}
+}
+
+// NewTransactionID returns a new public transaction id. Passed as
+// TransferDetails.UUID, it becomes the id of the transfer.
+func (s *Store) NewTransactionID() (uuid.UUID, error) {
+	id, err := s.ids.NewID()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("generate transaction id: %w", err)
+	}
+	return id, nil
+}
+
+// withTransactionID fills in the public id of details if the caller left it
+// empty. It is done once per transfer so that retries insert the same id.
+func (s *Store) withTransactionID(details TransferDetails) (TransferDetails, error) {
+	if details.UUID != uuid.Nil {
+		return details, nil
+	}
+	id, err := s.NewTransactionID()
+	if err != nil {
+		return TransferDetails{}, err
+	}
+	details.UUID = id
+	return details, nil
+}
+
+// TransactionID returns the serial id of the transaction with public id uid.
+// Scoped to a tenant or owner, only transactions touching one of its accounts
+// are found.
+func (s *Store) TransactionID(ctx context.Context, uid uuid.UUID) (id int64, err error) {
+	ctx, span := startSpan(ctx, "TransactionID", attribute.String("transaction.uuid", uid.String()))
+	defer func() { endSpan(span, err) }()
+
+	// From the primary, like the writes it is made for, so that fresh ids resolve
+	err = s.pool.QueryRow(ctx, `SELECT id FROM transactions
+		WHERE public_id = $1 AND ($2::text IS NULL AND $3::text IS NULL OR `+transactionScopeCond(2)+`)`,
+		uid, tenantArg(ctx), ownerArg(ctx)).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrTransactionNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("resolve transaction id: %w", err)
+	}
+	return id, nil
+}
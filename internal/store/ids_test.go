@@ -0,0 +1,25 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestUUIDv7(t *testing.T) {
+	var prev uuid.UUID
+	for i := 0; i < 100; i++ {
+		id, err := UUIDv7{}.NewID()
+		if err != nil {
+			t.Fatalf("NewID failed: %v", err)
+		}
+		if id.Version() != 7 {
+			t.Fatalf("expected a version 7 UUID, got %s", id)
+		}
+		if bytes.Compare(id[:], prev[:]) <= 0 {
+			t.Fatalf("expected ids in increasing order, got %s after %s", id, prev)
+		}
+		prev = id
+	}
+}
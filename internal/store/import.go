@@ -0,0 +1,426 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// importBatchSize is how many accounts ImportAccounts checks and copies at a time
+const importBatchSize = 10_000
+
+// AccountImport is an account to create with ImportAccounts or CreateAccounts.
+type AccountImport struct {
+	AccountID  int64 // assigned if 0, which requires an ExternalID
+	ExternalID string
+	Initial    decimal.Decimal
+	Currency   string
+	Metadata   AccountMetadata
+	OwnerID    string
+}
+
+// ImportRowError is a row of an import whose Field ("account_id" or
+// "external_id") is taken by an existing account or an earlier row. Row is
+// 1-based.
+type ImportRowError struct {
+	Row   int
+	Field string
+}
+
+// ImportError is returned by ImportAccounts for the rows that conflict,
+// in order; nothing is imported. It matches ErrAccountExists.
+type ImportError struct {
+	Rows []ImportRowError
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("%d rows conflict with existing accounts or earlier rows", len(e.Rows))
+}
+
+func (e *ImportError) Unwrap() error {
+	return ErrAccountExists
+}
+
+// ImportAccounts creates accounts, and their AccountCreated events, in the
+// tenant of ctx, all at once. They are copied with COPY in batches of
+// importBatchSize within one transaction, which is far faster than creating
+// them one by one. Rows whose account or external id is taken fail the import
+// with an *ImportError listing them; an account created concurrently fails it
+// with ErrAccountExists.
+func (s *Store) ImportAccounts(ctx context.Context, accounts []AccountImport) (err error) {
+	ctx, span := startSpan(ctx, "ImportAccounts", attribute.Int("account.count", len(accounts)))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	ids := make([]int64, len(accounts))
+	given := make(map[int64]bool, len(accounts))
+	var assign []int // rows whose id is drawn from the sequence
+	for i, a := range accounts {
+		ids[i] = a.AccountID
+		if a.AccountID == 0 {
+			assign = append(assign, i)
+		} else {
+			given[a.AccountID] = true
+		}
+	}
+	conflicts, err := s.importConflicts(ctx, tx, accounts, ids)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return &ImportError{Rows: conflicts}
+	}
+
+	// Drawn ids already taken by clients, or given to other rows, are drawn
+	// again like in CreateAccountWithExternalID
+	for attempt := 0; len(assign) > 0; attempt++ {
+		if attempt == maxAccountIDAttempts {
+			return fmt.Errorf("assign account ids: %d ids in a row were taken", maxAccountIDAttempts)
+		}
+		drawn, err := drawAccountIDs(ctx, tx, len(assign), given)
+		if err != nil {
+			return err
+		}
+		for j, i := range assign {
+			ids[i] = drawn[j]
+		}
+		taken, err := takenAccountIDs(ctx, tx, drawn)
+		if err != nil {
+			return err
+		}
+		retry := assign[:0]
+		for _, i := range assign {
+			if taken[ids[i]] {
+				retry = append(retry, i)
+			}
+		}
+		assign = retry
+	}
+
+	tenant, _ := TenantFromContext(ctx)
+	now := s.clock.Now()
+	for lo := 0; lo < len(accounts); lo += importBatchSize {
+		hi := min(lo+importBatchSize, len(accounts))
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"accounts"},
+			[]string{"account_id", "balance", "initial_balance", "currency", "tenant_id", "owner_id", "external_id",
+				"display_name", "owner_ref", "tags", "created_at"},
+			pgx.CopyFromSlice(hi-lo, func(i int) ([]any, error) {
+				a := accounts[lo+i]
+				var externalID *string
+				if a.ExternalID != "" {
+					externalID = &a.ExternalID
+				}
+				tags := a.Metadata.Tags
+				if tags == nil {
+					tags = []string{}
+				}
+				return []any{ids[lo+i], a.Initial, a.Initial, a.Currency, tenant, a.OwnerID, externalID,
+					a.Metadata.DisplayName, a.Metadata.OwnerRef, tags, now}, nil
+			}))
+		if err != nil {
+			if isUniqueViolation(err) {
+				return ErrAccountExists
+			}
+			return fmt.Errorf("copy accounts: %w", err)
+		}
+		if err := s.copyAccountEvents(ctx, tx, accounts[lo:hi], ids[lo:hi], tenant); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// AccountResult is the outcome of one account of CreateAccounts: the id it
+// was created with, or Err, ErrAccountExists if its id or external id is taken.
+type AccountResult struct {
+	AccountID int64
+	Err       error
+}
+
+// CreateAccounts creates accounts, and their AccountCreated events, in the
+// tenant of ctx, skipping the ones whose id or external id is taken by an
+// existing account or an earlier one, and returns the outcome of each in
+// order. They are copied into a temporary table and inserted with a single
+// INSERT, within one transaction.
+func (s *Store) CreateAccounts(ctx context.Context, accounts []AccountImport) (results []AccountResult, err error) {
+	ctx, span := startSpan(ctx, "CreateAccounts", attribute.Int("account.count", len(accounts)))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `CREATE TEMP TABLE account_batch (
+			idx INT, account_id BIGINT, balance NUMERIC, currency TEXT, owner_id TEXT, external_id TEXT,
+			display_name TEXT, owner_ref TEXT, tags TEXT[]
+		) ON COMMIT DROP`); err != nil {
+		return nil, fmt.Errorf("create batch table: %w", err)
+	}
+
+	// Later accounts repeating an id or external id are duplicates
+	results = make([]AccountResult, len(accounts))
+	seenIDs := make(map[int64]bool, len(accounts))
+	seenExternal := make(map[string]bool)
+	var pending []int
+	for i, a := range accounts {
+		if a.AccountID != 0 && seenIDs[a.AccountID] || a.ExternalID != "" && seenExternal[a.ExternalID] {
+			results[i].Err = ErrAccountExists
+			continue
+		}
+		seenIDs[a.AccountID] = a.AccountID != 0
+		seenExternal[a.ExternalID] = a.ExternalID != ""
+		results[i].AccountID = a.AccountID
+		pending = append(pending, i)
+	}
+
+	tenant, _ := TenantFromContext(ctx)
+	var created []int
+	// Accounts whose drawn id was taken by a client, but not their external
+	// id, are tried again with another id, like in CreateAccountWithExternalID
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt == maxAccountIDAttempts {
+			return nil, fmt.Errorf("assign account ids: %d ids in a row were taken", maxAccountIDAttempts)
+		}
+		var assign []int
+		for _, i := range pending {
+			if accounts[i].AccountID == 0 {
+				assign = append(assign, i)
+			}
+		}
+		drawn, err := drawAccountIDs(ctx, tx, len(assign), seenIDs)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range assign {
+			results[i].AccountID = drawn[j]
+		}
+
+		if _, err := tx.Exec(ctx, `TRUNCATE account_batch`); err != nil {
+			return nil, fmt.Errorf("clear batch table: %w", err)
+		}
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"account_batch"},
+			[]string{"idx", "account_id", "balance", "currency", "owner_id", "external_id", "display_name", "owner_ref", "tags"},
+			pgx.CopyFromSlice(len(pending), func(j int) ([]any, error) {
+				i := pending[j]
+				a := accounts[i]
+				var externalID *string
+				if a.ExternalID != "" {
+					externalID = &a.ExternalID
+				}
+				tags := a.Metadata.Tags
+				if tags == nil {
+					tags = []string{}
+				}
+				return []any{i, results[i].AccountID, a.Initial, a.Currency, a.OwnerID, externalID,
+					a.Metadata.DisplayName, a.Metadata.OwnerRef, tags}, nil
+			})); err != nil {
+			return nil, fmt.Errorf("copy accounts: %w", err)
+		}
+		rows, err := tx.Query(ctx, `INSERT INTO accounts (account_id, balance, initial_balance, currency, tenant_id, owner_id, external_id, display_name, owner_ref, tags, created_at)
+			SELECT account_id, balance, balance, currency, $1, owner_id, external_id, display_name, owner_ref, tags, $2
+			FROM account_batch ORDER BY idx
+			ON CONFLICT DO NOTHING
+			RETURNING account_id`, tenant, s.clock.Now())
+		if err != nil {
+			return nil, fmt.Errorf("insert accounts: %w", err)
+		}
+		inserted, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return nil, fmt.Errorf("insert accounts: %w", err)
+		}
+		ok := make(map[int64]bool, len(inserted))
+		for _, id := range inserted {
+			ok[id] = true
+		}
+
+		var skipped []string
+		for _, i := range pending {
+			if ok[results[i].AccountID] {
+				created = append(created, i)
+			} else if accounts[i].AccountID == 0 {
+				skipped = append(skipped, accounts[i].ExternalID)
+			}
+		}
+		rows, err = tx.Query(ctx, `SELECT external_id FROM accounts WHERE external_id = ANY($1)`, skipped)
+		if err != nil {
+			return nil, fmt.Errorf("check external ids: %w", err)
+		}
+		taken, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("check external ids: %w", err)
+		}
+		retry := pending[:0]
+		for _, i := range pending {
+			switch {
+			case ok[results[i].AccountID]:
+			case accounts[i].AccountID == 0 && !slices.Contains(taken, accounts[i].ExternalID):
+				retry = append(retry, i)
+			default:
+				results[i] = AccountResult{Err: ErrAccountExists}
+			}
+		}
+		pending = retry
+	}
+
+	slices.Sort(created)
+	batch := make([]AccountImport, len(created))
+	ids := make([]int64, len(created))
+	for j, i := range created {
+		batch[j], ids[j] = accounts[i], results[i].AccountID
+	}
+	if err := s.copyAccountEvents(ctx, tx, batch, ids, tenant); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return results, nil
+}
+
+// drawAccountIDs draws n account ids from the sequence, leaving out the ones
+// in given.
+func drawAccountIDs(ctx context.Context, tx pgx.Tx, n int, given map[int64]bool) ([]int64, error) {
+	ids := make([]int64, 0, n)
+	for attempt := 0; len(ids) < n; attempt++ {
+		if attempt == maxAccountIDAttempts {
+			return nil, fmt.Errorf("assign account ids: %d ids in a row were taken", maxAccountIDAttempts)
+		}
+		rows, err := tx.Query(ctx, `SELECT nextval('accounts_account_id_seq') FROM generate_series(1, $1)`, n-len(ids))
+		if err != nil {
+			return nil, fmt.Errorf("assign account ids: %w", err)
+		}
+		drawn, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil {
+			return nil, fmt.Errorf("assign account ids: %w", err)
+		}
+		for _, id := range drawn {
+			if !given[id] {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// importConflicts returns the rows of accounts whose id or external id is
+// taken by an existing account or an earlier row; ids are their account ids, 0
+// for the ones to assign.
+func (s *Store) importConflicts(ctx context.Context, tx pgx.Tx, accounts []AccountImport, ids []int64) ([]ImportRowError, error) {
+	var conflicts []ImportRowError
+	seenIDs := make(map[int64]bool, len(accounts))
+	seenExternal := make(map[string]bool)
+	for lo := 0; lo < len(accounts); lo += importBatchSize {
+		hi := min(lo+importBatchSize, len(accounts))
+		var batchIDs []int64
+		var batchExternal []string
+		for i := lo; i < hi; i++ {
+			if ids[i] != 0 {
+				batchIDs = append(batchIDs, ids[i])
+			}
+			if accounts[i].ExternalID != "" {
+				batchExternal = append(batchExternal, accounts[i].ExternalID)
+			}
+		}
+		takenIDs, err := takenAccountIDs(ctx, tx, batchIDs)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := tx.Query(ctx, `SELECT external_id FROM accounts WHERE external_id = ANY($1)`, batchExternal)
+		if err != nil {
+			return nil, fmt.Errorf("check external ids: %w", err)
+		}
+		external, err := pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return nil, fmt.Errorf("check external ids: %w", err)
+		}
+		takenExternal := make(map[string]bool, len(external))
+		for _, e := range external {
+			takenExternal[e] = true
+		}
+
+		for i := lo; i < hi; i++ {
+			if id := ids[i]; id != 0 {
+				if takenIDs[id] || seenIDs[id] {
+					conflicts = append(conflicts, ImportRowError{Row: i + 1, Field: "account_id"})
+					continue
+				}
+				seenIDs[id] = true
+			}
+			if e := accounts[i].ExternalID; e != "" {
+				if takenExternal[e] || seenExternal[e] {
+					conflicts = append(conflicts, ImportRowError{Row: i + 1, Field: "external_id"})
+					continue
+				}
+				seenExternal[e] = true
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// takenAccountIDs returns which of ids are taken by existing accounts.
+func takenAccountIDs(ctx context.Context, tx pgx.Tx, ids []int64) (map[int64]bool, error) {
+	rows, err := tx.Query(ctx, `SELECT account_id FROM accounts WHERE account_id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("check account ids: %w", err)
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("check account ids: %w", err)
+	}
+	taken := make(map[int64]bool, len(existing))
+	for _, id := range existing {
+		taken[id] = true
+	}
+	return taken, nil
+}
+
+// copyAccountEvents copies the AccountCreated events of the imported accounts
+// into the outbox, like writeEvent.
+func (s *Store) copyAccountEvents(ctx context.Context, tx pgx.Tx, accounts []AccountImport, ids []int64, tenant string) error {
+	if !s.outbox {
+		return nil
+	}
+	_, err := tx.CopyFrom(ctx, pgx.Identifier{"outbox_events"}, []string{"event_type", "event_key", "payload"},
+		pgx.CopyFromSlice(len(accounts), func(i int) ([]any, error) {
+			a := accounts[i]
+			data, err := json.Marshal(events.AccountCreatedPayload{
+				AccountID:      ids[i],
+				ExternalID:     a.ExternalID,
+				InitialBalance: a.Initial.String(),
+				Currency:       a.Currency,
+				Tenant:         tenant,
+				OwnerID:        a.OwnerID,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("marshal %s event: %w", events.AccountCreated, err)
+			}
+			return []any{events.AccountCreated, strconv.FormatInt(ids[i], 10), data}, nil
+		}))
+	if err != nil {
+		return fmt.Errorf("copy %s events: %w", events.AccountCreated, err)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrTransferJobNotFound is returned for unknown transfer job ids.
+var ErrTransferJobNotFound = errors.New("transfer job not found")
+
+// Transfer job statuses
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+)
+
+// Transfer job row statuses
+const (
+	JobRowPending   = "pending"
+	JobRowSucceeded = "succeeded"
+	JobRowFailed    = "failed"
+)
+
+// TransferJob is a row of the transfer_jobs table with progress counted from its rows
+type TransferJob struct {
+	ID          int64
+	Status      string
+	TotalRows   int
+	Succeeded   int
+	Failed      int
+	CreatedAt   time.Time
+	FinishedAt  *time.Time
+	Tenant      string // rows execute scoped to it, if not empty
+	OwnerID     string // likewise
+	Reclaimed   bool   // claimed again after the lease of an earlier run lapsed
+	InitiatedBy string // the caller that created it, recorded on the rows' transactions
+}
+
+// TransferJobRow is a row of the transfer_job_rows table. Row is 1-based, and
+// UUID is the public id its transaction is made with.
+type TransferJobRow struct {
+	JobID int64
+	Row   int
+	TransferItem
+	Status        string
+	TransactionID int64
+	ErrorMessage  string
+}
+
+// CreateTransferJob queues a job executing items, numbered from 1 in order, in
+// the scope of ctx, as initiated by initiatedBy. Each row is given the public id of its transaction, unless
+// its item has one.
+func (s *Store) CreateTransferJob(ctx context.Context, items []TransferItem, initiatedBy string) (_ TransferJob, err error) {
+	ctx, span := startSpan(ctx, "CreateTransferJob", attribute.Int("job.rows", len(items)))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return TransferJob{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	job := TransferJob{Status: JobQueued, TotalRows: len(items), InitiatedBy: initiatedBy}
+	job.Tenant, _ = TenantFromContext(ctx)
+	job.OwnerID, _ = OwnerFromContext(ctx)
+	if err := tx.QueryRow(ctx, `INSERT INTO transfer_jobs (tenant_id, owner_id, initiated_by) VALUES ($1, $2, $3) RETURNING id, created_at`,
+		job.Tenant, job.OwnerID, job.InitiatedBy).Scan(&job.ID, &job.CreatedAt); err != nil {
+		return TransferJob{}, fmt.Errorf("insert transfer job: %w", err)
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"transfer_job_rows"},
+		[]string{"job_id", "row_number", "source_account_id", "destination_account_id", "amount", "transaction_public_id"},
+		pgx.CopyFromSlice(len(items), func(i int) ([]any, error) {
+			it := items[i]
+			details, err := s.withTransactionID(it.TransferDetails)
+			if err != nil {
+				return nil, err
+			}
+			return []any{job.ID, i + 1, it.SourceAccountID, it.DestinationAccountID, it.Amount, details.UUID}, nil
+		}))
+	if err != nil {
+		return TransferJob{}, fmt.Errorf("insert transfer job rows: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return TransferJob{}, fmt.Errorf("commit: %w", err)
+	}
+	return job, nil
+}
+
+// GetTransferJob fetches a job and its progress. Scoped to a tenant or owner,
+// only jobs created in that scope are found.
+func (s *Store) GetTransferJob(ctx context.Context, id int64) (_ TransferJob, err error) {
+	ctx, span := startSpan(ctx, "GetTransferJob", attribute.Int64("job.id", id))
+	defer func() { endSpan(span, err) }()
+
+	job := TransferJob{ID: id}
+	err = s.read.QueryRow(ctx, `SELECT j.status, j.created_at, j.finished_at, j.tenant_id, j.owner_id,
+			COUNT(r.row_number),
+			COUNT(r.row_number) FILTER (WHERE r.status = $2),
+			COUNT(r.row_number) FILTER (WHERE r.status = $3)
+		FROM transfer_jobs j LEFT JOIN transfer_job_rows r ON r.job_id = j.id
+		WHERE j.id = $1 AND ($4::text IS NULL OR j.tenant_id = $4) AND ($5::text IS NULL OR j.owner_id = $5)
+		GROUP BY j.id`, id, JobRowSucceeded, JobRowFailed, tenantArg(ctx), ownerArg(ctx)).
+		Scan(&job.Status, &job.CreatedAt, &job.FinishedAt, &job.Tenant, &job.OwnerID, &job.TotalRows, &job.Succeeded, &job.Failed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TransferJob{}, ErrTransferJobNotFound
+		}
+		return TransferJob{}, fmt.Errorf("get transfer job: %w", err)
+	}
+	return job, nil
+}
+
+// transferJobRowColumns is the select list matching scanTransferJobRow
+const transferJobRowColumns = `job_id, row_number, source_account_id, destination_account_id, amount, status,
+	COALESCE(transaction_id, 0), COALESCE(error_message, ''), transaction_public_id`
+
+// scanTransferJobRow scans a row selected with transferJobRowColumns.
+func scanTransferJobRow(row pgx.CollectableRow) (TransferJobRow, error) {
+	var r TransferJobRow
+	if err := row.Scan(&r.JobID, &r.Row, &r.SourceAccountID, &r.DestinationAccountID, &r.Amount, &r.Status,
+		&r.TransactionID, &r.ErrorMessage, &r.UUID); err != nil {
+		return TransferJobRow{}, err
+	}
+	return r, nil
+}
+
+// listTransferJobRows returns up to limit rows of the job with status, in row
+// order, read from db. Jobs outside the scope of ctx have no rows.
+func listTransferJobRows(ctx context.Context, db *pgxpool.Pool, jobID int64, status string, limit int) ([]TransferJobRow, error) {
+	rows, err := db.Query(ctx, `SELECT `+transferJobRowColumns+` FROM transfer_job_rows
+		WHERE job_id = $1 AND status = $2
+			AND ($4::text IS NULL AND $5::text IS NULL OR EXISTS (SELECT 1 FROM transfer_jobs j WHERE j.id = job_id AND `+accountScopeCond(4)+`))
+		ORDER BY row_number
+		LIMIT $3`, jobID, status, limit, tenantArg(ctx), ownerArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list transfer job rows: %w", err)
+	}
+	out, err := pgx.CollectRows(rows, scanTransferJobRow)
+	if err != nil {
+		return nil, fmt.Errorf("list transfer job rows: %w", err)
+	}
+	return out, nil
+}
+
+// ListTransferJobFailures returns up to limit failed rows of the job, in row order.
+func (s *Store) ListTransferJobFailures(ctx context.Context, jobID int64, limit int) (_ []TransferJobRow, err error) {
+	ctx, span := startSpan(ctx, "ListTransferJobFailures", attribute.Int64("job.id", jobID))
+	defer func() { endSpan(span, err) }()
+
+	return listTransferJobRows(ctx, s.read, jobID, JobRowFailed, limit)
+}
+
+// ClaimTransferJob moves the oldest queued job to running, or takes over the
+// oldest running job not renewed (see RenewTransferJob) for lease, and returns
+// it holding a fresh lease. ok is false when there is no such job. Concurrent
+// callers never claim the same job.
+func (s *Store) ClaimTransferJob(ctx context.Context, lease time.Duration) (_ TransferJob, ok bool, err error) {
+	ctx, span := startSpan(ctx, "ClaimTransferJob")
+	defer func() { endSpan(span, err) }()
+
+	job := TransferJob{Status: JobRunning}
+	err = s.pool.QueryRow(ctx, `UPDATE transfer_jobs j SET status = $1, claimed_at = now()
+		FROM (SELECT id, status FROM transfer_jobs
+			WHERE status = $2 OR status = $1 AND claimed_at < now() - $3 * interval '1 second'
+			ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED) c
+		WHERE j.id = c.id
+		RETURNING j.id, j.created_at, j.tenant_id, j.owner_id, j.initiated_by, c.status = $1`, JobRunning, JobQueued, lease.Seconds()).
+		Scan(&job.ID, &job.CreatedAt, &job.Tenant, &job.OwnerID, &job.InitiatedBy, &job.Reclaimed)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return TransferJob{}, false, nil
+		}
+		return TransferJob{}, false, fmt.Errorf("claim transfer job: %w", err)
+	}
+	return job, true, nil
+}
+
+// RenewTransferJob extends the lease of a running job, keeping other callers of
+// ClaimTransferJob from taking it over.
+func (s *Store) RenewTransferJob(ctx context.Context, jobID int64) (err error) {
+	ctx, span := startSpan(ctx, "RenewTransferJob", attribute.Int64("job.id", jobID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.pool.Exec(ctx, `UPDATE transfer_jobs SET claimed_at = now() WHERE id = $1 AND status = $2`, jobID, JobRunning); err != nil {
+		return fmt.Errorf("renew transfer job: %w", err)
+	}
+	return nil
+}
+
+// RecoverTransferJobRow records the outcome of a pending row whose transaction
+// was already made, by a run of the job interrupted before recording it, as
+// RecordTransferJobRow would have. ok is false when the row has no transaction
+// yet.
+func (s *Store) RecoverTransferJobRow(ctx context.Context, jobID int64, row TransferJobRow) (ok bool, err error) {
+	ctx, span := startSpan(ctx, "RecoverTransferJobRow", attribute.Int64("job.id", jobID), attribute.Int("job.row", row.Row))
+	defer func() { endSpan(span, err) }()
+
+	t, err := scanTransaction(s.pool.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions WHERE public_id = $1`, row.UUID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("recover transfer job row: %w", err)
+	}
+
+	// The outcome Transfer returned for t
+	txID, transferErr := t.ID, transferOutcome(t)
+	switch {
+	case t.Status == StatusPending:
+		transferErr = ErrAwaitingApproval
+	case transferErr != nil:
+		txID = 0
+	}
+	return true, s.RecordTransferJobRow(ctx, jobID, row.Row, txID, transferErr)
+}
+
+// PendingTransferJobRows returns the rows of the job that have not been executed yet.
+func (s *Store) PendingTransferJobRows(ctx context.Context, jobID int64) (_ []TransferJobRow, err error) {
+	ctx, span := startSpan(ctx, "PendingTransferJobRows", attribute.Int64("job.id", jobID))
+	defer func() { endSpan(span, err) }()
+
+	return listTransferJobRows(ctx, s.pool, jobID, JobRowPending, math.MaxInt32)
+}
+
+// RecordTransferJobRow stores the outcome of executing a row: the transaction
+// id on success, or transferErr when the transfer failed. A transfer awaiting
+// approval is recorded as succeeded with its transaction id and the error.
+func (s *Store) RecordTransferJobRow(ctx context.Context, jobID int64, row int, txID int64, transferErr error) (err error) {
+	ctx, span := startSpan(ctx, "RecordTransferJobRow", attribute.Int64("job.id", jobID), attribute.Int("job.row", row))
+	defer func() { endSpan(span, err) }()
+
+	status, errMsg := JobRowSucceeded, ""
+	switch {
+	case errors.Is(transferErr, ErrAwaitingApproval):
+		// The transaction is executed once approved
+		errMsg = transferErr.Error()
+	case transferErr != nil:
+		status, errMsg = JobRowFailed, transferErr.Error()
+	}
+	_, err = s.pool.Exec(ctx, `UPDATE transfer_job_rows
+		SET status = $1, transaction_id = NULLIF($2::bigint, 0), error_message = NULLIF($3::text, '')
+		WHERE job_id = $4 AND row_number = $5`, status, txID, errMsg, jobID, row)
+	if err != nil {
+		return fmt.Errorf("record transfer job row: %w", err)
+	}
+	return nil
+}
+
+// FinishTransferJob marks a job completed.
+func (s *Store) FinishTransferJob(ctx context.Context, jobID int64) (err error) {
+	ctx, span := startSpan(ctx, "FinishTransferJob", attribute.Int64("job.id", jobID))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.pool.Exec(ctx, `UPDATE transfer_jobs SET status = $1, finished_at = now() WHERE id = $2`, JobCompleted, jobID); err != nil {
+		return fmt.Errorf("finish transfer job: %w", err)
+	}
+	return nil
+}
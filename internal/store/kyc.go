@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// KYC statuses of an account. Accounts start unverified.
+const (
+	KYCUnverified = "unverified"
+	KYCVerified   = "verified"
+	KYCBlocked    = "blocked" // may neither send nor receive transfers
+)
+
+// ErrKYCRestricted is returned for a transfer into or out of a blocked
+// account, or out of an unverified one when the KYCPolicy makes them receive
+// only.
+var ErrKYCRestricted = errors.New("kyc status does not allow the transfer")
+
+// KYCPolicy restricts the transfers of unverified accounts. The zero policy
+// restricts nothing.
+type KYCPolicy struct {
+	UnverifiedReceiveOnly bool                // unverified accounts may not send
+	UnverifiedMaxAmount   decimal.NullDecimal // per transfer out of an unverified account
+}
+
+// WithKYCPolicy sets the restrictions of unverified accounts.
+func WithKYCPolicy(p KYCPolicy) Option {
+	return func(s *Store) {
+		s.kyc = p
+	}
+}
+
+// checkKYC returns ErrKYCRestricted if the KYC statuses of src and dst do
+// not allow a transfer between them. The cap on the amount is checked with
+// the limits (see limitTracker.check).
+func (s *Store) checkKYC(src, dst Account) error {
+	if src.KYCStatus == KYCBlocked || dst.KYCStatus == KYCBlocked || src.KYCStatus == KYCUnverified && s.kyc.UnverifiedReceiveOnly {
+		return ErrKYCRestricted
+	}
+	return nil
+}
+
+// SetKYCStatus sets the KYC status of the account and returns the updated
+// account. It applies from the next transfer, including the capture of holds
+// already placed, sweeps and settlements.
+func (s *Store) SetKYCStatus(ctx context.Context, accountID int64, status string) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "SetKYCStatus",
+		attribute.Int64("account.id", accountID),
+		attribute.String("account.kyc_status", status),
+	)
+	defer func() { endSpan(span, err) }()
+
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET kyc_status = $2
+		WHERE account_id = $1 AND `+accountScopeCond(3)+`
+		RETURNING `+accountColumns, accountID, status, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, fmt.Errorf("set kyc status: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrLimitExceeded is matched (with errors.Is) by the *LimitError a transfer
+// over one of the source account's limits, or below its minimum balance,
+// fails with.
+var ErrLimitExceeded = errors.New("transfer limit exceeded")
+
+// Transfer limits, as reported in LimitError.Limit
+const (
+	LimitPerTransfer = "per_transfer" // maximum amount of a single transfer
+	LimitDaily       = "daily"        // maximum sum of transfers out of an account over the last 24h
+	LimitMinBalance  = "min_balance"  // available balance an account must keep after a transfer out
+	LimitKYC         = "kyc"          // maximum amount of a single transfer out of an unverified account
+)
+
+// LimitError reports which limit a transfer exceeded.
+type LimitError struct {
+	Limit string
+}
+
+func (e *LimitError) Error() string {
+	switch e.Limit {
+	case LimitDaily:
+		return "daily transfer limit exceeded"
+	case LimitMinBalance:
+		return "minimum balance breached"
+	case LimitKYC:
+		return "kyc limit exceeded"
+	}
+	return "per-transfer limit exceeded"
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// TransferLimits caps the transfers out of an account. Invalid (NULL) fields
+// are not limited; on an account they fall back to the store defaults.
+type TransferLimits struct {
+	MaxAmount decimal.NullDecimal // per transfer
+	Daily     decimal.NullDecimal // rolling 24h sum of succeeded transfers
+}
+
+// WithDefaultLimits sets the limits of accounts that do not override them.
+func WithDefaultLimits(l TransferLimits) Option {
+	return func(s *Store) {
+		s.limits.Store(&l)
+	}
+}
+
+// DefaultLimits returns the limits of accounts that do not override them.
+func (s *Store) DefaultLimits() TransferLimits {
+	return *s.limits.Load()
+}
+
+// SetDefaultLimits replaces the limits of accounts that do not override them,
+// from the next transfer on.
+func (s *Store) SetDefaultLimits(l TransferLimits) {
+	s.limits.Store(&l)
+}
+
+// SetAccountLimits replaces the account's own limits and returns the updated account.
+func (s *Store) SetAccountLimits(ctx context.Context, accountID int64, l TransferLimits) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "SetAccountLimits", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET max_transfer_amount = $2, daily_transfer_limit = $3
+		WHERE account_id = $1 AND `+accountScopeCond(4)+`
+		RETURNING `+accountColumns, accountID, l.MaxAmount, l.Daily, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, fmt.Errorf("set account limits: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
+
+// CheckMinBalance returns a *LimitError if moving amount out of acc would take
+// its available balance below its minimum balance. Callers check for
+// insufficient funds first.
+func CheckMinBalance(acc Account, amount decimal.Decimal) error {
+	if acc.MinBalance.Valid && acc.AvailableBalance.Sub(amount).LessThan(acc.MinBalance.Decimal) {
+		return &LimitError{Limit: LimitMinBalance}
+	}
+	return nil
+}
+
+// SetMinBalance sets the minimum balance of the account, or removes it if min
+// is invalid (NULL), and returns the updated account. A balance already below
+// it only blocks the transfers out.
+func (s *Store) SetMinBalance(ctx context.Context, accountID int64, min decimal.NullDecimal) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "SetMinBalance", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET min_balance = $2
+		WHERE account_id = $1 AND `+accountScopeCond(3)+`
+		RETURNING `+accountColumns, accountID, min, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, fmt.Errorf("set min balance: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
+
+// effectiveLimits returns the limits of acc, falling back to the store defaults.
+func (s *Store) effectiveLimits(acc Account) TransferLimits {
+	l, defaults := acc.Limits, s.DefaultLimits()
+	if !l.MaxAmount.Valid {
+		l.MaxAmount = defaults.MaxAmount
+	}
+	if !l.Daily.Valid {
+		l.Daily = defaults.Daily
+	}
+	return l
+}
+
+// dailyOutgoingSQL sums the succeeded transfers out of account $1 over the 24h
+// before $3; $2 is the succeeded status. Debit adjustments do not count.
+const dailyOutgoingSQL = `SELECT COALESCE(SUM(amount), 0) FROM transactions
+	WHERE source_account_id = $1 AND status = $2 AND kind = 'transfer' AND created_at > $3::timestamptz - interval '24 hours'`
+
+// limitTracker checks the transfers made within one DB transaction against the
+// limits of their source accounts, counting the transfers before them. The
+// source accounts must be locked in tx, so that concurrent transfers out of
+// them are counted as well.
+type limitTracker struct {
+	s    *Store
+	tx   pgx.Tx
+	sent map[int64]decimal.Decimal // by source account, over the last 24h
+}
+
+func (s *Store) newLimitTracker(tx pgx.Tx) *limitTracker {
+	return &limitTracker{s: s, tx: tx, sent: make(map[int64]decimal.Decimal)}
+}
+
+// check returns a *LimitError if transferring amount out of src exceeds its
+// limits, or the KYCPolicy cap if src is unverified.
+func (lt *limitTracker) check(ctx context.Context, src Account, amount decimal.Decimal) error {
+	if kyc := lt.s.kyc; src.KYCStatus == KYCUnverified && kyc.UnverifiedMaxAmount.Valid && amount.GreaterThan(kyc.UnverifiedMaxAmount.Decimal) {
+		return &LimitError{Limit: LimitKYC}
+	}
+	l := lt.s.effectiveLimits(src)
+	if l.MaxAmount.Valid && amount.GreaterThan(l.MaxAmount.Decimal) {
+		return &LimitError{Limit: LimitPerTransfer}
+	}
+	if !l.Daily.Valid {
+		return nil
+	}
+	sent, ok := lt.sent[src.ID]
+	if !ok {
+		if err := lt.tx.QueryRow(ctx, dailyOutgoingSQL, src.ID, StatusSucceeded, lt.s.clock.Now()).Scan(&sent); err != nil {
+			return fmt.Errorf("sum daily transfers: %w", err)
+		}
+		lt.sent[src.ID] = sent
+	}
+	if sent.Add(amount).GreaterThan(l.Daily.Decimal) {
+		return &LimitError{Limit: LimitDaily}
+	}
+	return nil
+}
+
+// add counts a transfer that passed check.
+func (lt *limitTracker) add(srcID int64, amount decimal.Decimal) {
+	if sent, ok := lt.sent[srcID]; ok {
+		lt.sent[srcID] = sent.Add(amount)
+	}
+}
@@ -0,0 +1,212 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// TransferChannel is the channel notified of committed transfers by a store
+// created WithNotify.
+const TransferChannel = "transfers"
+
+// TransferNotification is the payload of the notifications on
+// TransferChannel: the transfer event, which is the same as the outbox's.
+type TransferNotification struct {
+	Type string `json:"type"` // events.TransferCompleted or events.TransferFailed
+	events.TransferPayload
+}
+
+// notifyTransfer notifies TransferChannel of a recorded transaction within
+// tx, so that listeners only hear of it once tx commits. It does nothing
+// unless the store was created WithNotify.
+func (s *Store) notifyTransfer(ctx context.Context, tx pgx.Tx, t Transaction) error {
+	if !s.notify {
+		return nil
+	}
+	typ, payload := transferEvent(t)
+	data, err := json.Marshal(TransferNotification{Type: typ, TransferPayload: payload})
+	if err != nil {
+		return fmt.Errorf("marshal transfer notification: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, TransferChannel, string(data)); err != nil {
+		return fmt.Errorf("notify transfer: %w", err)
+	}
+	return nil
+}
+
+// ErrNotListening is returned by Listener.Subscribe while the listen
+// connection is down.
+var ErrNotListening = errors.New("not listening for notifications")
+
+// Notification is a notification received on a Listener's channel
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// Reconnection delays of a Listener, doubling from the first to the last
+const (
+	listenMinBackoff = 100 * time.Millisecond
+	listenMaxBackoff = 30 * time.Second
+)
+
+// listenerReconnects counts the lost listen connections.
+var listenerReconnects, _ = otel.Meter(tracerName).Int64Counter("store.listener.reconnects",
+	metric.WithDescription("Listen connections lost and reopened"),
+)
+
+// Listener holds a dedicated connection listening on a channel and fans the
+// notifications out to in-process subscribers, such as event streams, without
+// polling the database.
+//
+// Notifications sent while the connection is down are lost, so subscriptions
+// never silently miss any: they are closed when the connection is lost, and
+// refused until it is back. Subscribers catch up by reading the database and
+// subscribing again.
+type Listener struct {
+	connect func(ctx context.Context) (*pgx.Conn, error)
+	channel string
+
+	mu        sync.Mutex
+	listening bool
+	subs      map[chan Notification]struct{}
+}
+
+// NewListener returns a Listener on channel, connecting like pool does, with
+// its current DSN, but outside of it. Start it with Run.
+func NewListener(pool *pgxpool.Pool, channel string) *Listener {
+	cfg := pool.Config()
+	return &Listener{
+		connect: func(ctx context.Context) (*pgx.Conn, error) {
+			cc := cfg.ConnConfig.Copy()
+			if cfg.BeforeConnect != nil {
+				if err := cfg.BeforeConnect(ctx, cc); err != nil {
+					return nil, err
+				}
+			}
+			return pgx.ConnectConfig(ctx, cc)
+		},
+		channel: channel,
+		subs:    make(map[chan Notification]struct{}),
+	}
+}
+
+// Run listens until ctx is done, reconnecting after a lost connection with a
+// growing delay, then closes the subscriptions.
+func (l *Listener) Run(ctx context.Context) {
+	backoff := listenMinBackoff
+	for {
+		connected, err := l.listen(ctx)
+		l.disconnected()
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			backoff = listenMinBackoff
+			listenerReconnects.Add(ctx, 1)
+		}
+		log.Printf("listen %s: %v; reconnecting in %s", l.channel, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, listenMaxBackoff)
+	}
+}
+
+// listen opens the connection and relays its notifications until it fails.
+// connected reports whether it got to listen.
+func (l *Listener) listen(ctx context.Context) (connected bool, err error) {
+	conn, err := l.connect(ctx)
+	if err != nil {
+		return false, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return false, fmt.Errorf("listen: %w", err)
+	}
+	l.mu.Lock()
+	l.listening = true
+	l.mu.Unlock()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		l.broadcast(Notification{Channel: n.Channel, Payload: n.Payload})
+	}
+}
+
+// broadcast hands n to every subscriber. Subscribers that have fallen behind
+// by a full buffer are closed rather than wait for.
+func (l *Listener) broadcast(n Notification) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c := range l.subs {
+		select {
+		case c <- n:
+		default:
+			delete(l.subs, c)
+			close(c)
+		}
+	}
+}
+
+// disconnected closes every subscription, which would miss the notifications
+// of the time the connection is down.
+func (l *Listener) disconnected() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listening = false
+	for c := range l.subs {
+		delete(l.subs, c)
+		close(c)
+	}
+}
+
+// Subscribe returns a channel receiving the notifications from now on, with
+// room for buffer of them, and the function ending the subscription. The
+// channel is closed when the subscription ends, including when the connection
+// is lost or the subscriber falls behind. It fails with ErrNotListening while
+// the connection is down.
+func (l *Listener) Subscribe(buffer int) (<-chan Notification, func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.listening {
+		return nil, nil, ErrNotListening
+	}
+	c := make(chan Notification, buffer)
+	l.subs[c] = struct{}{}
+	return c, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[c]; ok {
+			delete(l.subs, c)
+			close(c)
+		}
+	}, nil
+}
+
+// Check reports whether the listen connection is up. It suits readiness checks.
+func (l *Listener) Check(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.listening {
+		return ErrNotListening
+	}
+	return nil
+}
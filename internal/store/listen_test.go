@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestListener_FanOut tests that notifications fan out to every subscriber, that
+// subscribers falling behind are dropped and that a lost connection ends
+// every subscription
+func TestListener_FanOut(t *testing.T) {
+	l := &Listener{channel: TransferChannel, subs: make(map[chan Notification]struct{})}
+	if _, _, err := l.Subscribe(1); !errors.Is(err, ErrNotListening) {
+		t.Fatalf("expected ErrNotListening before the connection is up, got %v", err)
+	}
+	if err := l.Check(context.Background()); !errors.Is(err, ErrNotListening) {
+		t.Fatalf("expected the check to fail, got %v", err)
+	}
+
+	l.listening = true
+	fast, unsubscribe, err := l.Subscribe(2)
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	defer unsubscribe()
+	slow, _, _ := l.Subscribe(1)
+	gone, unsubscribeGone, _ := l.Subscribe(1)
+	unsubscribeGone()
+	if _, ok := <-gone; ok {
+		t.Fatal("expected an ended subscription to be closed")
+	}
+
+	l.broadcast(Notification{Payload: "1"})
+	l.broadcast(Notification{Payload: "2"})
+	if n := <-fast; n.Payload != "1" {
+		t.Fatalf("expected the first notification, got %q", n.Payload)
+	}
+	if n := <-fast; n.Payload != "2" {
+		t.Fatalf("expected the second notification, got %q", n.Payload)
+	}
+	if n := <-slow; n.Payload != "1" {
+		t.Fatalf("expected the first notification, got %q", n.Payload)
+	}
+	if _, ok := <-slow; ok {
+		t.Fatal("expected a subscriber falling behind to be dropped")
+	}
+
+	l.disconnected()
+	if _, ok := <-fast; ok {
+		t.Fatal("expected a lost connection to end the subscriptions")
+	}
+	if _, _, err := l.Subscribe(1); !errors.Is(err, ErrNotListening) {
+		t.Fatalf("expected ErrNotListening once the connection is lost, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Advisory lock keys used for leader election between instances
+const (
+	// SchedulerLockID is held by the instance executing scheduled transfers.
+	SchedulerLockID = 7_265_431_002
+	// OutboxLockID is held by the instance relaying outbox events, which keeps them in order.
+	OutboxLockID = 7_265_431_003
+	// SnapshotLockID is held by the instance taking periodic balance snapshots.
+	SnapshotLockID = 7_265_431_004
+	// ReconciliationLockID is held by the instance reconciling balances against the ledger.
+	ReconciliationLockID = 7_265_431_005
+	// StatementLockID is held by the instance generating monthly statements.
+	StatementLockID = 7_265_431_006
+	// PartitionLockID is held by the instance maintaining and archiving the transaction partitions.
+	PartitionLockID = 7_265_431_007
+	// SettlementLockID is held by the instance settling clearing transfers.
+	SettlementLockID = 7_265_431_008
+	// SweepLockID is held by the instance running the sweep rules.
+	SweepLockID = 7_265_431_009
+	// AnomalyLockID is held by the instance analyzing transfers for anomalies.
+	AnomalyLockID = 7_265_431_010
+)
+
+// TryAdvisoryLock takes the session-level advisory lock key without waiting.
+// If ok, the lock is held on a dedicated connection until unlock is called.
+func (s *Store) TryAdvisoryLock(ctx context.Context, key int64) (unlock func(), ok bool, err error) {
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("acquire conn: %w", err)
+	}
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&ok); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("try advisory lock: %w", err)
+	}
+	if !ok {
+		conn.Release()
+		return nil, false, nil
+	}
+	return func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, key)
+		conn.Release()
+	}, true, nil
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID is the advisory lock key held while migrations run, so
+// instances starting concurrently do not apply the same migration twice.
+const migrationLockID = 7_265_431_001
+
+// Migrate applies the *.sql files in fsys that are not yet recorded in
+// schema_migrations, in lexical filename order, each in its own transaction.
+// It returns the names of the migrations it applied.
+func Migrate(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS) ([]string, error) {
+	files, err := migrationFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire conn: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		_, _ = conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []string
+	for _, name := range files {
+		if applied[name] {
+			continue
+		}
+		sql, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return done, fmt.Errorf("read migration %s: %w", name, err)
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return done, fmt.Errorf("begin tx: %w", err)
+		}
+		// No arguments, so pgx uses the simple protocol and multi-statement files work
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			_ = tx.Rollback(ctx)
+			return done, fmt.Errorf("apply migration %s: %w", name, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, name); err != nil {
+			_ = tx.Rollback(ctx)
+			return done, fmt.Errorf("record migration %s: %w", name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return done, fmt.Errorf("commit migration %s: %w", name, err)
+		}
+		done = append(done, name)
+	}
+	return done, nil
+}
+
+// PendingMigrations returns the names of the *.sql files in fsys not yet
+// recorded in schema_migrations, in apply order, without applying them.
+func PendingMigrations(ctx context.Context, pool *pgxpool.Pool, fsys fs.FS) ([]string, error) {
+	files, err := migrationFiles(fsys)
+	if err != nil {
+		return nil, err
+	}
+	// Before the first migration the table does not exist yet
+	var exists bool
+	if err := pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check schema_migrations: %w", err)
+	}
+	applied := map[string]bool{}
+	if exists {
+		if applied, err = appliedMigrations(ctx, pool); err != nil {
+			return nil, err
+		}
+	}
+	var pending []string
+	for _, name := range files {
+		if !applied[name] {
+			pending = append(pending, name)
+		}
+	}
+	return pending, nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations.
+func appliedMigrations(ctx context.Context, db querier) (map[string]bool, error) {
+	rows, err := db.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("scan migration version: %w", err)
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// migrationFiles lists the *.sql files at the root of fsys in apply order.
+func migrationFiles(fsys fs.FS) ([]string, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("list migrations: %w", err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/you/internal-transfers/migrations"
+)
+
+func TestMigrationFiles_Order(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_b.sql": {Data: []byte("SELECT 2;")},
+		"0001_a.sql": {Data: []byte("SELECT 1;")},
+		"0010_c.sql": {Data: []byte("SELECT 10;")},
+		"README.md":  {Data: []byte("not a migration")},
+	}
+
+	files, err := migrationFiles(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"0001_a.sql", "0002_b.sql", "0010_c.sql"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("expected %v, got %v", expected, files)
+	}
+}
+
+func TestMigrationFiles_Embedded(t *testing.T) {
+	files, err := migrationFiles(migrations.FS)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) == 0 || files[0] != "0001_init.sql" {
+		t.Fatalf("expected embedded migrations starting with 0001_init.sql, got %v", files)
+	}
+}
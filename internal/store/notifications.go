@@ -0,0 +1,103 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ClaimReviewNotifications marks up to limit pending reviews no one was
+// notified of yet as notified and returns them, oldest first. Each review is
+// claimed once, whichever instance asks.
+func (s *Store) ClaimReviewNotifications(ctx context.Context, limit int) (_ []Review, err error) {
+	ctx, span := startSpan(ctx, "ClaimReviewNotifications", attribute.Int("limit", limit))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `WITH claimed AS (
+		UPDATE transfer_reviews SET notified_at = $3
+		WHERE transaction_id IN (SELECT transaction_id FROM transfer_reviews
+			WHERE review_status = $1 AND notified_at IS NULL
+			ORDER BY transaction_id LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING transaction_id
+	)
+	SELECT `+reviewColumns+` FROM `+reviewFrom+`
+	WHERE transaction_id IN (SELECT transaction_id FROM claimed)
+	ORDER BY transaction_id`, ReviewPending, limit, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("claim review notifications: %w", err)
+	}
+	reviews, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Review, error) {
+		return scanReview(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim review notifications: %w", err)
+	}
+	return reviews, nil
+}
+
+// ClaimAlertNotifications marks up to limit active alerts no one was
+// notified of yet as notified and returns them, oldest first. Each alert is
+// claimed once, whichever instance asks.
+func (s *Store) ClaimAlertNotifications(ctx context.Context, limit int) (_ []Alert, err error) {
+	ctx, span := startSpan(ctx, "ClaimAlertNotifications", attribute.Int("limit", limit))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `UPDATE alerts SET notified_at = $3
+		WHERE id IN (SELECT id FROM alerts WHERE status = $1 AND notified_at IS NULL
+			ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING `+alertColumns, AlertActive, limit, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("claim alert notifications: %w", err)
+	}
+	alerts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Alert, error) {
+		return scanAlert(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim alert notifications: %w", err)
+	}
+	// RETURNING keeps no order
+	slices.SortFunc(alerts, func(a, b Alert) int { return cmp.Compare(a.ID, b.ID) })
+	return alerts, nil
+}
+
+// DeadLetter is an outbox event that could never be delivered.
+type DeadLetter struct {
+	ID        int64 // the id the event had in the outbox
+	EventType string
+	EventKey  string
+	Attempts  int
+	LastError string
+	DeadAt    time.Time
+}
+
+// ClaimDeadLetterNotifications marks up to limit dead letters no one was
+// notified of yet as notified and returns them, oldest first. Each dead
+// letter is claimed once, whichever instance asks.
+func (s *Store) ClaimDeadLetterNotifications(ctx context.Context, limit int) (_ []DeadLetter, err error) {
+	ctx, span := startSpan(ctx, "ClaimDeadLetterNotifications", attribute.Int("limit", limit))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `UPDATE outbox_dead_letters SET notified_at = $2
+		WHERE id IN (SELECT id FROM outbox_dead_letters WHERE notified_at IS NULL
+			ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED)
+		RETURNING id, event_type, event_key, attempts, last_error, dead_at`, limit, s.clock.Now())
+	if err != nil {
+		return nil, fmt.Errorf("claim dead letter notifications: %w", err)
+	}
+	dead, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (DeadLetter, error) {
+		var d DeadLetter
+		err := row.Scan(&d.ID, &d.EventType, &d.EventKey, &d.Attempts, &d.LastError, &d.DeadAt)
+		return d, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim dead letter notifications: %w", err)
+	}
+	// RETURNING keeps no order
+	slices.SortFunc(dead, func(a, b DeadLetter) int { return cmp.Compare(a.ID, b.ID) })
+	return dead, nil
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// writeEvent inserts an event into the outbox within tx. It does nothing
+// unless the store was created WithOutbox.
+func (s *Store) writeEvent(ctx context.Context, tx pgx.Tx, typ string, key int64, payload any) error {
+	if !s.outbox {
+		return nil
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", typ, err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO outbox_events (event_type, event_key, payload) VALUES ($1, $2, $3)`,
+		typ, strconv.FormatInt(key, 10), data); err != nil {
+		return fmt.Errorf("insert %s event: %w", typ, err)
+	}
+	return nil
+}
+
+// writeTransferEvent writes the completed or failed event of a recorded
+// transaction, keyed by its source account.
+func (s *Store) writeTransferEvent(ctx context.Context, tx pgx.Tx, t Transaction) error {
+	typ, payload := transferEvent(t)
+	return s.writeEvent(ctx, tx, typ, t.SourceAccountID, payload)
+}
+
+// finishTransfer writes the event of t, a transaction recorded in tx, and
+// raises the alerts of its accounts if it succeeded. Every transaction moving
+// balances goes through it.
+func (s *Store) finishTransfer(ctx context.Context, tx pgx.Tx, t Transaction) error {
+	if err := s.writeTransferEvent(ctx, tx, t); err != nil {
+		return err
+	}
+	return s.raiseAlerts(ctx, tx, t)
+}
+
+// transferEvent returns the type and payload of the event of a recorded transaction.
+func transferEvent(t Transaction) (string, events.TransferPayload) {
+	typ := events.TransferCompleted
+	if t.Status == StatusFailed {
+		typ = events.TransferFailed
+	}
+	return typ, events.TransferPayload{
+		ID:                   t.UUID.String(),
+		TransactionID:        t.ID,
+		SourceAccountID:      t.SourceAccountID,
+		DestinationAccountID: t.DestinationAccountID,
+		Amount:               t.Amount.String(),
+		Currency:             t.Currency,
+		ReversalOf:           t.ReversalOf,
+		FeeOf:                t.FeeOf,
+		Reference:            t.Reference,
+		PurposeCode:          t.PurposeCode,
+		Error:                t.ErrorMessage,
+	}
+}
+
+// ListOutboxEvents returns up to limit events not yet delivered, oldest first.
+func (s *Store) ListOutboxEvents(ctx context.Context, limit int) (_ []events.Event, err error) {
+	ctx, span := startSpan(ctx, "ListOutboxEvents")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `SELECT id, event_type, event_key, payload::text, created_at FROM outbox_events WHERE delivered_at IS NULL ORDER BY id LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list outbox events: %w", err)
+	}
+	evs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (events.Event, error) {
+		var e events.Event
+		var payload string
+		if err := row.Scan(&e.ID, &e.Type, &e.Key, &payload, &e.CreatedAt); err != nil {
+			return events.Event{}, err
+		}
+		e.Payload = []byte(payload)
+		return e, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list outbox events: %w", err)
+	}
+	return evs, nil
+}
+
+// MarkOutboxDelivered marks events delivered, so that they are not relayed again.
+func (s *Store) MarkOutboxDelivered(ctx context.Context, ids []int64) (err error) {
+	ctx, span := startSpan(ctx, "MarkOutboxDelivered", attribute.Int("outbox.events", len(ids)))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.pool.Exec(ctx, `UPDATE outbox_events SET delivered_at = now() WHERE id = ANY($1) AND delivered_at IS NULL`, ids); err != nil {
+		return fmt.Errorf("mark outbox events delivered: %w", err)
+	}
+	return nil
+}
+
+// RecordOutboxFailure counts a failed delivery of the event id, keeping the
+// error for operators.
+func (s *Store) RecordOutboxFailure(ctx context.Context, id int64, msg string) (err error) {
+	ctx, span := startSpan(ctx, "RecordOutboxFailure", attribute.Int64("outbox.event_id", id))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.pool.Exec(ctx, `UPDATE outbox_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1`, id, msg); err != nil {
+		return fmt.Errorf("record outbox failure: %w", err)
+	}
+	return nil
+}
+
+// DeadLetterOutboxEvent moves the event id, which can never be delivered, to
+// outbox_dead_letters with the error that condemned it.
+func (s *Store) DeadLetterOutboxEvent(ctx context.Context, id int64, msg string) (err error) {
+	ctx, span := startSpan(ctx, "DeadLetterOutboxEvent", attribute.Int64("outbox.event_id", id))
+	defer func() { endSpan(span, err) }()
+
+	if _, err := s.pool.Exec(ctx, `
+WITH dead AS (
+	DELETE FROM outbox_events WHERE id = $1 AND delivered_at IS NULL
+	RETURNING id, created_at, event_type, event_key, payload, attempts
+)
+INSERT INTO outbox_dead_letters (id, created_at, event_type, event_key, payload, attempts, last_error)
+SELECT id, created_at, event_type, event_key, payload, attempts + 1, $2 FROM dead`, id, msg); err != nil {
+		return fmt.Errorf("dead-letter outbox event: %w", err)
+	}
+	return nil
+}
+
+// PurgeOutboxEvents deletes the events delivered before before and returns
+// how many it deleted.
+func (s *Store) PurgeOutboxEvents(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeOutboxEvents")
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM outbox_events WHERE delivered_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge outbox events: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,333 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// The transactions table is partitioned by the month (UTC) of created_at; see
+// migrations/0027_transaction_partitions.sql.
+const (
+	partitionPrefix  = "transactions_"
+	partitionFormat  = "2006_01"
+	defaultPartition = "transactions_default"
+)
+
+// partitionLockTimeout bounds the wait for the locks of attaching and
+// detaching partitions, during which the queries on transactions queue up
+// behind them. A busy table fails the run, and the next run tries again.
+const partitionLockTimeout = 5 * time.Second
+
+// ErrArchiveBlocked is returned by ArchiveTransactions for a month that still
+// has pending transfers or transfers awaiting review.
+var ErrArchiveBlocked = errors.New("month has pending transfers or reviews")
+
+// partitionName returns the name of the partition of the month starting at month.
+func partitionName(month time.Time) string {
+	return partitionPrefix + month.Format(partitionFormat)
+}
+
+// partitionBounds returns the bounds of the partition of month as SQL literals.
+func partitionBounds(month time.Time) (from, to string) {
+	return "'" + month.Format(time.RFC3339) + "'", "'" + month.AddDate(0, 1, 0).Format(time.RFC3339) + "'"
+}
+
+// setLockTimeout bounds the lock waits of tx by partitionLockTimeout.
+func setLockTimeout(ctx context.Context, tx pgx.Tx) error {
+	_, err := tx.Exec(ctx, fmt.Sprintf(`SET LOCAL lock_timeout = %d`, partitionLockTimeout.Milliseconds()))
+	return err
+}
+
+// EnsureTransactionPartitions creates the missing partitions of the
+// transactions table for months months from the one containing from, and
+// returns how many it created. Rows the default partition holds for these
+// months are moved into their partition.
+func (s *Store) EnsureTransactionPartitions(ctx context.Context, from time.Time, months int) (n int, err error) {
+	ctx, span := startSpan(ctx, "EnsureTransactionPartitions")
+	defer func() {
+		span.SetAttributes(attribute.Int("partitions.created", n))
+		endSpan(span, err)
+	}()
+
+	month := StatementPeriod(from)
+	for i := 0; i < months; i, month = i+1, month.AddDate(0, 1, 0) {
+		var exists bool
+		if err := s.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, partitionName(month)).Scan(&exists); err != nil {
+			return n, fmt.Errorf("check partition %s: %w", partitionName(month), err)
+		}
+		if exists {
+			continue
+		}
+		if err := s.createPartition(ctx, month); err != nil {
+			return n, fmt.Errorf("create partition %s: %w", partitionName(month), err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// createPartition creates the partition of month and moves its rows out of the
+// default partition. The partition is created apart and then attached, which
+// unlike creating it as a partition does not block the queries on transactions.
+func (s *Store) createPartition(ctx context.Context, month time.Time) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if err := setLockTimeout(ctx, tx); err != nil {
+		return err
+	}
+
+	name := pgx.Identifier{partitionName(month)}.Sanitize()
+	lo, hi := partitionBounds(month)
+	if _, err := tx.Exec(ctx, `CREATE TABLE `+name+` (LIKE transactions INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `WITH moved AS (
+			DELETE FROM `+defaultPartition+` WHERE created_at >= `+lo+` AND created_at < `+hi+` RETURNING *
+		)
+		INSERT INTO `+name+` SELECT * FROM moved`); err != nil {
+		return fmt.Errorf("move rows of the default partition: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `ALTER TABLE transactions ATTACH PARTITION `+name+` FOR VALUES FROM (`+lo+`) TO (`+hi+`)`); err != nil {
+		return fmt.Errorf("attach: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// TransactionExporter writes the transactions of an archived month out of the
+// database, e.g. to object storage, in place of the archive table.
+type TransactionExporter interface {
+	// ExportMonth writes the transactions of the month starting at month,
+	// which read calls fn with in id order. A month whose archiving is
+	// interrupted is exported again, so writing it must be idempotent.
+	ExportMonth(ctx context.Context, month time.Time, read func(fn func(Transaction) error) error) error
+}
+
+// ArchivedMonth is a month of transactions archived by ArchiveTransactions.
+type ArchivedMonth struct {
+	Month time.Time
+	Rows  int64
+}
+
+// ArchiveTransactions archives the months of transactions that ended by
+// before, oldest first, and returns them. Each month's partition is detached
+// from the transactions table and its rows moved to transactions_archive, or
+// handed to exp if not nil and dropped. Archived transactions are gone from
+// the API; their net is kept in archived_balances for reconciliation, and the
+// balance history before them is no longer known (see ErrHistoryArchived).
+//
+// A month with pending transfers or reviews stops the run with
+// ErrArchiveBlocked, so that the archived months stay contiguous.
+func (s *Store) ArchiveTransactions(ctx context.Context, before time.Time, exp TransactionExporter) (months []ArchivedMonth, err error) {
+	ctx, span := startSpan(ctx, "ArchiveTransactions")
+	defer func() {
+		span.SetAttributes(attribute.Int("archive.months", len(months)))
+		endSpan(span, err)
+	}()
+
+	// Months detached by an interrupted run come first
+	rows, err := s.pool.Query(ctx, `SELECT month, row_count FROM transaction_archives WHERE archived_at IS NULL ORDER BY month`)
+	if err != nil {
+		return nil, fmt.Errorf("list detached months: %w", err)
+	}
+	detached, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ArchivedMonth, error) {
+		var m ArchivedMonth
+		err := row.Scan(&m.Month, &m.Rows)
+		m.Month = m.Month.UTC()
+		return m, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list detached months: %w", err)
+	}
+	for _, m := range detached {
+		if err := s.moveArchived(ctx, m.Month, exp); err != nil {
+			return months, fmt.Errorf("archive %s: %w", m.Month.Format("2006-01"), err)
+		}
+		months = append(months, m)
+	}
+
+	partitions, err := s.partitionMonths(ctx)
+	if err != nil {
+		return months, err
+	}
+	cutoff := StatementPeriod(before)
+	for _, month := range partitions {
+		if month.AddDate(0, 1, 0).After(cutoff) {
+			break
+		}
+		n, err := s.detachPartition(ctx, month)
+		if err != nil {
+			return months, fmt.Errorf("archive %s: %w", month.Format("2006-01"), err)
+		}
+		if err := s.moveArchived(ctx, month, exp); err != nil {
+			return months, fmt.Errorf("archive %s: %w", month.Format("2006-01"), err)
+		}
+		months = append(months, ArchivedMonth{Month: month, Rows: n})
+	}
+	return months, nil
+}
+
+// partitionMonths returns the months of the partitions of transactions, oldest first.
+func (s *Store) partitionMonths(ctx context.Context) ([]time.Time, error) {
+	rows, err := s.pool.Query(ctx, `SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = 'transactions'::regclass`)
+	if err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+	names, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list partitions: %w", err)
+	}
+	var months []time.Time
+	for _, name := range names {
+		month, err := time.Parse(partitionFormat, strings.TrimPrefix(name, partitionPrefix))
+		if err != nil {
+			// The default partition
+			continue
+		}
+		months = append(months, month)
+	}
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months, nil
+}
+
+// detachPartition records month as archived, with the net of its succeeded
+// transactions, and detaches its partition, all at once so that the ledger
+// always reconciles. It returns the number of rows of the month.
+func (s *Store) detachPartition(ctx context.Context, month time.Time) (n int64, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if err := setLockTimeout(ctx, tx); err != nil {
+		return 0, err
+	}
+
+	// Transactions of past months only change while pending or under review
+	name := pgx.Identifier{partitionName(month)}.Sanitize()
+	var blocked bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM `+name+` WHERE status = $1)
+		OR EXISTS (SELECT 1 FROM transfer_reviews r JOIN `+name+` t ON t.id = r.transaction_id WHERE r.review_status = $2)`,
+		StatusPending, ReviewPending).Scan(&blocked); err != nil {
+		return 0, fmt.Errorf("check pending: %w", err)
+	}
+	if blocked {
+		return 0, ErrArchiveBlocked
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO archived_balances (account_id, net)
+		SELECT account_id, sum(delta) FROM (
+			SELECT destination_account_id AS account_id, amount AS delta FROM `+name+`
+			WHERE status = $1 AND destination_account_id IS NOT NULL
+			UNION ALL
+			SELECT source_account_id, -amount FROM `+name+`
+			WHERE status = $1 AND source_account_id IS NOT NULL
+		) d
+		GROUP BY account_id
+		ON CONFLICT (account_id) DO UPDATE SET net = archived_balances.net + excluded.net`, StatusSucceeded); err != nil {
+		return 0, fmt.Errorf("add archived balances: %w", err)
+	}
+	if err := tx.QueryRow(ctx, `INSERT INTO transaction_archives (month, row_count, settled_through)
+		SELECT $1, count(*), max(settled_at) FROM `+name+`
+		RETURNING row_count`, month).Scan(&n); err != nil {
+		return 0, fmt.Errorf("record archive: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `ALTER TABLE transactions DETACH PARTITION `+name); err != nil {
+		return 0, fmt.Errorf("detach: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return n, nil
+}
+
+// archivedColumns are the columns moved to transactions_archive. Columns added
+// to transactions after the archive was created come after its archived_at,
+// so they are named rather than copied in order.
+const archivedColumns = `id, created_at, source_account_id, destination_account_id, amount, status, error_message, currency,
+	reversal_of, reference, purpose_code, settled_at, kind, reason, adjusted_by, public_id, initiated_by, channel, request_id, fee_of, transfer_group,
+	reversal_of_public_id, fee_of_public_id`
+
+// moveArchived moves the rows of the detached partition of month to
+// transactions_archive, or to exp if not nil, and drops it.
+func (s *Store) moveArchived(ctx context.Context, month time.Time, exp TransactionExporter) error {
+	name := pgx.Identifier{partitionName(month)}.Sanitize()
+	if exp != nil {
+		read := func(fn func(Transaction) error) error {
+			return s.readPartition(ctx, name, fn)
+		}
+		if err := exp.ExportMonth(ctx, month, read); err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if exp == nil {
+		if _, err := tx.Exec(ctx, `INSERT INTO transactions_archive (`+archivedColumns+`) SELECT `+archivedColumns+` FROM `+name); err != nil {
+			return fmt.Errorf("copy to the archive: %w", err)
+		}
+	}
+	if _, err := tx.Exec(ctx, `DROP TABLE `+name); err != nil {
+		return fmt.Errorf("drop: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE transaction_archives SET archived_at = now(), exported = $2 WHERE month = $1`, month, exp != nil); err != nil {
+		return fmt.Errorf("record archive: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// readPartition calls fn with the rows of the detached partition name in id
+// order, fetched in chunks through a cursor like ExportTransactions.
+func (s *Store) readPartition(ctx context.Context, name string, fn func(Transaction) error) error {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	if _, err := tx.Exec(ctx, `DECLARE archive_transactions NO SCROLL CURSOR FOR
+		SELECT `+transactionColumns+` FROM `+name+` ORDER BY id`); err != nil {
+		return fmt.Errorf("declare cursor: %w", err)
+	}
+	for {
+		rows, err := tx.Query(ctx, fmt.Sprintf(`FETCH %d FROM archive_transactions`, exportFetchSize))
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		txs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Transaction, error) {
+			return scanTransaction(row)
+		})
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		for _, t := range txs {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if len(txs) < exportFetchSize {
+			return nil
+		}
+	}
+}
@@ -2,25 +2,162 @@ package store
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
+	"github.com/jackc/pgerrcode"
+	pgxdecimal "github.com/jackc/pgx-shopspring-decimal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-// Connect opens a pgx connection pool using the given DSN.
-func Connect(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+// DefaultApplicationName is reported to Postgres (pg_stat_activity.application_name)
+// unless configured otherwise.
+const DefaultApplicationName = "internal-transfers"
+
+// PoolConfig tunes the connection pool opened by Connect.
+type PoolConfig struct {
+	MaxConns         int32
+	MinConns         int32
+	MaxConnLifetime  time.Duration // connections are closed after this long
+	MaxConnIdleTime  time.Duration // idle connections above MinConns are closed after this long
+	StatementTimeout time.Duration // 0 means no timeout
+	ApplicationName  string
+	Breaker          *CircuitBreaker // nil for none; give each pool its own
+	Rotation         *RotatingDSN    // nil unless the DSN may be rotated; give each pool its own
+	ConnectWait      time.Duration   // how long Connect waits for the database to come up; 0 skips the check
+}
+
+// Delays between the attempts of Connect to reach the database, doubling from
+// the first to the last
+const (
+	connectMinBackoff = 250 * time.Millisecond
+	connectMaxBackoff = 5 * time.Second
+)
+
+// DefaultPoolConfig returns the settings used unless configured otherwise.
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConns:        10,
+		MinConns:        1,
+		MaxConnLifetime: time.Hour,
+		MaxConnIdleTime: 30 * time.Minute,
+		ApplicationName: DefaultApplicationName,
+		ConnectWait:     30 * time.Second,
+	}
+}
+
+// Connect opens a pgx connection pool using the given DSN and pool settings,
+// and, unless pc.ConnectWait is 0, checks that the database answers. While it
+// is unreachable or starting up, Connect tries again with a growing delay for
+// up to pc.ConnectWait, so that the service can start before the database.
+// Connections are otherwise opened as needed: the pool replaces broken ones by
+// itself once the database is back.
+func Connect(ctx context.Context, dsn string, pc PoolConfig) (*pgxpool.Pool, error) {
 	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	config.MaxConns = 10
-	config.MinConns = 1
+	config.MaxConns = pc.MaxConns
+	config.MinConns = pc.MinConns
+	config.MaxConnLifetime = pc.MaxConnLifetime
+	config.MaxConnIdleTime = pc.MaxConnIdleTime
 	config.HealthCheckPeriod = 30 * time.Second
+	if pc.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(pc.StatementTimeout.Milliseconds(), 10)
+	}
+	if pc.ApplicationName != "" {
+		config.ConnConfig.RuntimeParams["application_name"] = pc.ApplicationName
+	}
+	config.ConnConfig.Tracer = queryTracer{}
+	if b := pc.Breaker; b != nil {
+		config.ConnConfig.Tracer = breakerTracer{breaker: b}
+		config.BeforeConnect = b.beforeConnect
+		config.PrepareConn = b.prepareConn
+	}
+	if r := pc.Rotation; r != nil {
+		r.init(dsn, config.ConnConfig)
+		// The DSN applies before the breaker may refuse the connection
+		next := config.BeforeConnect
+		config.BeforeConnect = func(ctx context.Context, cc *pgx.ConnConfig) error {
+			r.apply(cc)
+			if next == nil {
+				return nil
+			}
+			return next(ctx, cc)
+		}
+	}
+	config.AfterConnect = registerTypes
 
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, err
 	}
+	if r := pc.Rotation; r != nil {
+		r.mu.Lock()
+		r.pool = pool
+		r.mu.Unlock()
+	}
+	if pc.ConnectWait > 0 {
+		if err := waitForDatabase(ctx, pool, pc.ConnectWait); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
 	return pool, nil
 }
+
+// waitForDatabase pings pool until it answers, trying again for up to wait while
+// the database is unreachable or starting up. Other errors, such as failed
+// authentication, are returned at once.
+func waitForDatabase(ctx context.Context, pool *pgxpool.Pool, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	backoff := connectMinBackoff
+	for attempt := 1; ; attempt++ {
+		err := pool.Ping(ctx)
+		if err == nil {
+			if attempt > 1 {
+				log.Printf("db connect: database reachable after %d attempts", attempt)
+			}
+			return nil
+		}
+		if !databaseStarting(err) || ctx.Err() != nil {
+			return err
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return fmt.Errorf("database unreachable after %d attempts in %s: %w", attempt, wait, err)
+		}
+		backoff = min(backoff, left)
+		log.Printf("db connect: %v; retrying in %s", err, backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, connectMaxBackoff)
+	}
+}
+
+// databaseStarting reports whether err may go away once the database is up:
+// it is unreachable, refuses connections while starting up, or the pool's
+// circuit breaker opened on the failed attempts.
+func databaseStarting(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == pgerrcode.CannotConnectNow
+	}
+	return connectionFailure(err) || errors.Is(err, ErrCircuitOpen)
+}
+
+// registerTypes makes numeric columns and parameters scan into and encode from
+// decimal.Decimal natively, without a round trip through text.
+func registerTypes(_ context.Context, conn *pgx.Conn) error {
+	pgxdecimal.Register(conn.TypeMap())
+	return nil
+}
@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// ErrAccountNotClosed is returned by EraseAccount for an account that is
+// not closed
+var ErrAccountNotClosed = errors.New("account is not closed")
+
+// Personal fields of an account cleared by EraseAccount
+const (
+	ErasedDisplayName = "display_name"
+	ErasedOwnerRef    = "owner_ref"
+	ErasedTags        = "tags"
+	ErasedExternalID  = "external_id"
+	ErasedAliases     = "aliases"
+	ErasedWallets     = "wallets" // listed by an account whose wallets held data
+)
+
+// AccountErasure is a row of the account_erasures table
+type AccountErasure struct {
+	ID        int64
+	AccountID int64
+	Reason    string
+	ErasedBy  string   // subject of the admin who asked for it, if any
+	Fields    []string // the Erased fields that held data
+	ErasedAt  time.Time
+}
+
+// accountErasureColumns is the select list matching scanAccountErasure
+const accountErasureColumns = `id, account_id, reason, erased_by, fields, erased_at`
+
+// scanAccountErasure scans a row selected with accountErasureColumns.
+func scanAccountErasure(row pgx.Row) (AccountErasure, error) {
+	var e AccountErasure
+	if err := row.Scan(&e.ID, &e.AccountID, &e.Reason, &e.ErasedBy, &e.Fields, &e.ErasedAt); err != nil {
+		return AccountErasure{}, err
+	}
+	return e, nil
+}
+
+// AccountData is everything held about an account, as returned by
+// AccountData. Counterparties and AlertThresholds have no UpdatedAt if they
+// were never set.
+type AccountData struct {
+	Account            Account
+	Wallets            []Account
+	Aliases            []AccountAlias
+	Counterparties     CounterpartyAllowlist
+	AlertThresholds    AlertThresholds
+	Alerts             []Alert
+	Holds              []Hold              // into or out of the account
+	ScheduledTransfers []ScheduledTransfer // likewise
+	Transactions       []Transaction       // likewise, oldest first; archived ones are left out
+	Erasures           []AccountErasure
+	ExportedAt         time.Time
+}
+
+// AccountData returns everything held about the account, read in one
+// snapshot. It returns ErrAccountNotFound if the account does not exist or
+// is out of ctx's scope.
+func (s *Store) AccountData(ctx context.Context, accountID int64) (_ AccountData, err error) {
+	ctx, span := startSpan(ctx, "AccountData", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.read.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return AccountData{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	d := AccountData{ExportedAt: s.clock.Now()}
+	d.Account, err = scanAccount(tx.QueryRow(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = $1`, accountID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return AccountData{}, ErrAccountNotFound
+		}
+		return AccountData{}, fmt.Errorf("get account: %w", err)
+	}
+	if !inScope(ctx, d.Account) {
+		return AccountData{}, ErrAccountNotFound
+	}
+
+	if d.Wallets, err = queryAll(ctx, tx, scanAccount, `SELECT `+accountColumns+` FROM accounts
+		WHERE parent_account_id = $1 ORDER BY account_id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list wallets: %w", err)
+	}
+	if d.Aliases, err = queryAll(ctx, tx, func(row pgx.Row) (AccountAlias, error) {
+		var a AccountAlias
+		err := row.Scan(&a.Alias, &a.AccountID, &a.CreatedAt)
+		return a, err
+	}, `SELECT alias, account_id, created_at FROM account_aliases WHERE account_id = $1 ORDER BY alias`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list aliases: %w", err)
+	}
+
+	d.Counterparties = CounterpartyAllowlist{AccountID: accountID}
+	err = tx.QueryRow(ctx, `SELECT outgoing, incoming, updated_at FROM counterparty_allowlists WHERE account_id = $1`,
+		accountID).Scan(&d.Counterparties.Outgoing, &d.Counterparties.Incoming, &d.Counterparties.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return AccountData{}, fmt.Errorf("get counterparty allowlist: %w", err)
+	}
+	d.AlertThresholds = AlertThresholds{AccountID: accountID}
+	err = tx.QueryRow(ctx, `SELECT low_balance, large_transfer, updated_at FROM alert_thresholds WHERE account_id = $1`,
+		accountID).Scan(&d.AlertThresholds.LowBalance, &d.AlertThresholds.LargeTransfer, &d.AlertThresholds.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return AccountData{}, fmt.Errorf("get alert thresholds: %w", err)
+	}
+
+	if d.Alerts, err = queryAll(ctx, tx, scanAlert, `SELECT `+alertColumns+` FROM alerts
+		WHERE account_id = $1 ORDER BY id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list alerts: %w", err)
+	}
+	if d.Holds, err = queryAll(ctx, tx, scanHold, `SELECT `+holdColumns+` FROM holds
+		WHERE source_account_id = $1 OR destination_account_id = $1 ORDER BY id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list holds: %w", err)
+	}
+	if d.ScheduledTransfers, err = queryAll(ctx, tx, scanScheduled, `SELECT `+scheduledColumns+` FROM scheduled_transfers
+		WHERE source_account_id = $1 OR destination_account_id = $1 ORDER BY id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list scheduled transfers: %w", err)
+	}
+	if d.Transactions, err = queryAll(ctx, tx, scanTransaction, `SELECT `+transactionColumns+` FROM transactions
+		WHERE source_account_id = $1 OR destination_account_id = $1 ORDER BY created_at, id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list transactions: %w", err)
+	}
+	if d.Erasures, err = queryAll(ctx, tx, scanAccountErasure, `SELECT `+accountErasureColumns+` FROM account_erasures
+		WHERE account_id = $1 ORDER BY id`, accountID); err != nil {
+		return AccountData{}, fmt.Errorf("list erasures: %w", err)
+	}
+	span.SetAttributes(attribute.Int("export.transactions", len(d.Transactions)))
+	return d, nil
+}
+
+// queryAll runs query and scans every row it returns with scan.
+func queryAll[T any](ctx context.Context, tx pgx.Tx, scan func(pgx.Row) (T, error), query string, args ...any) ([]T, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (T, error) {
+		return scan(row)
+	})
+}
+
+// EraseAccount clears the personal data of a closed account and of its
+// wallets: their display name, owner reference, tags and external id, and
+// their aliases, which become free for other accounts. The external ids are
+// also removed from the AccountCreated events kept in the outbox and among
+// the dead letters. The accounts themselves, their balances and their ledger
+// entries are kept. The erasure is recorded with reason and the erasing admin,
+// and published as an AccountErased event, in the same DB transaction; each
+// wallet that held data gets an erasure of its own, and the account's lists
+// ErasedWallets.
+//
+// created is false, and the latest erasure is returned, if the account was
+// erased before and neither it nor its wallets hold personal data since. It
+// returns ErrAccountNotFound if the account does not exist or is out of ctx's
+// scope, and ErrAccountNotClosed unless it and its wallets are closed.
+func (s *Store) EraseAccount(ctx context.Context, accountID int64, reason, erasedBy string) (_ AccountErasure, created bool, err error) {
+	ctx, span := startSpan(ctx, "EraseAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return AccountErasure{}, false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// A closed account gets no new wallets, so the ones listed are all of them
+	rows, err := tx.Query(ctx, `SELECT account_id FROM accounts WHERE parent_account_id = $1`, accountID)
+	if err != nil {
+		return AccountErasure{}, false, fmt.Errorf("list wallets: %w", err)
+	}
+	walletIDs, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return AccountErasure{}, false, fmt.Errorf("list wallets: %w", err)
+	}
+	accs, err := lockExistingAccounts(ctx, tx, append([]int64{accountID}, walletIDs...))
+	if err != nil {
+		return AccountErasure{}, false, err
+	}
+	acc, ok := accs[accountID]
+	if !ok || !inScope(ctx, acc) {
+		return AccountErasure{}, false, ErrAccountNotFound
+	}
+	for _, a := range accs {
+		if a.Status != AccountClosed {
+			return AccountErasure{}, false, ErrAccountNotClosed
+		}
+	}
+
+	walletsErased := false
+	for _, id := range walletIDs {
+		wallet, ok := accs[id]
+		if !ok {
+			continue
+		}
+		fields, err := eraseAccountData(ctx, tx, wallet)
+		if err != nil {
+			return AccountErasure{}, false, err
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := s.recordErasure(ctx, tx, id, reason, erasedBy, fields); err != nil {
+			return AccountErasure{}, false, err
+		}
+		walletsErased = true
+	}
+	fields, err := eraseAccountData(ctx, tx, acc)
+	if err != nil {
+		return AccountErasure{}, false, err
+	}
+	if walletsErased {
+		fields = append(fields, ErasedWallets)
+	}
+
+	if len(fields) == 0 {
+		e, err := scanAccountErasure(tx.QueryRow(ctx, `SELECT `+accountErasureColumns+` FROM account_erasures
+			WHERE account_id = $1 ORDER BY id DESC LIMIT 1`, accountID))
+		if err == nil {
+			return e, false, tx.Commit(ctx)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return AccountErasure{}, false, fmt.Errorf("get erasure: %w", err)
+		}
+	}
+	e, err := s.recordErasure(ctx, tx, accountID, reason, erasedBy, fields)
+	if err != nil {
+		return AccountErasure{}, false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return AccountErasure{}, false, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, append([]int64{accountID}, walletIDs...)...)
+	return e, true, nil
+}
+
+// eraseAccountData clears the personal data of acc, locked in tx, and
+// returns the Erased fields that held some. The external id is removed from
+// the retained AccountCreated events of acc whether or not it still had one,
+// so that the events of accounts erased before are scrubbed as well.
+func eraseAccountData(ctx context.Context, tx pgx.Tx, acc Account) ([]string, error) {
+	fields := []string{}
+	if acc.DisplayName != "" {
+		fields = append(fields, ErasedDisplayName)
+	}
+	if acc.OwnerRef != "" {
+		fields = append(fields, ErasedOwnerRef)
+	}
+	if len(acc.Tags) > 0 {
+		fields = append(fields, ErasedTags)
+	}
+	if acc.ExternalID != "" {
+		fields = append(fields, ErasedExternalID)
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM account_aliases WHERE account_id = $1`, acc.ID)
+	if err != nil {
+		return nil, fmt.Errorf("delete aliases of account %d: %w", acc.ID, err)
+	}
+	if tag.RowsAffected() > 0 {
+		fields = append(fields, ErasedAliases)
+	}
+
+	if len(fields) > 0 {
+		if _, err := tx.Exec(ctx, `UPDATE accounts SET display_name = '', owner_ref = '', tags = '[]'::jsonb, external_id = NULL
+			WHERE account_id = $1`, acc.ID); err != nil {
+			return nil, fmt.Errorf("erase account %d: %w", acc.ID, err)
+		}
+	}
+	key := strconv.FormatInt(acc.ID, 10)
+	for _, table := range []string{"outbox_events", "outbox_dead_letters"} {
+		if _, err := tx.Exec(ctx, `UPDATE `+table+` SET payload = payload - 'external_id'
+			WHERE event_type = $1 AND event_key = $2 AND payload ? 'external_id'`, events.AccountCreated, key); err != nil {
+			return nil, fmt.Errorf("scrub %s of account %d: %w", table, acc.ID, err)
+		}
+	}
+	return fields, nil
+}
+
+// recordErasure records the erasure of fields of accountID and writes its
+// AccountErased event.
+func (s *Store) recordErasure(ctx context.Context, tx pgx.Tx, accountID int64, reason, erasedBy string, fields []string) (AccountErasure, error) {
+	e, err := scanAccountErasure(tx.QueryRow(ctx, `INSERT INTO account_erasures (account_id, reason, erased_by, fields, erased_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING `+accountErasureColumns, accountID, reason, erasedBy, fields, s.clock.Now()))
+	if err != nil {
+		return AccountErasure{}, fmt.Errorf("record erasure of account %d: %w", accountID, err)
+	}
+	if err := s.writeEvent(ctx, tx, events.AccountErased, accountID, events.AccountErasedPayload{
+		ErasureID: e.ID,
+		AccountID: accountID,
+		Fields:    e.Fields,
+	}); err != nil {
+		return AccountErasure{}, err
+	}
+	return e, nil
+}
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ErrReconciliationNotFound is returned by LatestReconciliation before the first run.
+var ErrReconciliationNotFound = errors.New("no reconciliation run")
+
+// Discrepancy is an account whose stored balances disagree with the ledger:
+// Balance should equal LedgerBalance, its initial balance plus the succeeded
+// transfers in minus those out, archived ones included, and HeldBalance should
+// equal PendingHolds, the sum of its pending holds.
+type Discrepancy struct {
+	AccountID     int64
+	Balance       decimal.Decimal
+	LedgerBalance decimal.Decimal
+	HeldBalance   decimal.Decimal
+	PendingHolds  decimal.Decimal
+}
+
+// ReconciliationRun is the outcome of one Reconcile.
+type ReconciliationRun struct {
+	ID              int64
+	CreatedAt       time.Time
+	AccountsChecked int
+	Discrepancies   []Discrepancy
+}
+
+// reconciliationDiscrepancies records the discrepancies found by the latest run.
+var reconciliationDiscrepancies, _ = otel.Meter(tracerName).Int64Gauge("store.reconciliation.discrepancies",
+	metric.WithDescription("Accounts whose stored balances disagreed with the ledger in the latest reconciliation"),
+	metric.WithUnit("{account}"),
+)
+
+// discrepanciesSQL recomputes every account's balances from the ledger and
+// returns those that differ; $1 is the succeeded transaction status and $2 the
+// pending hold status.
+const discrepanciesSQL = `
+SELECT a.account_id, a.balance, a.initial_balance + COALESCE(ar.net, 0) + COALESCE(i.total, 0) - COALESCE(o.total, 0),
+	a.held_balance, COALESCE(h.total, 0)
+FROM accounts a
+LEFT JOIN archived_balances ar ON ar.account_id = a.account_id
+LEFT JOIN (SELECT destination_account_id AS account_id, sum(amount) AS total
+	FROM transactions WHERE status = $1 GROUP BY destination_account_id) i ON i.account_id = a.account_id
+LEFT JOIN (SELECT source_account_id AS account_id, sum(amount) AS total
+	FROM transactions WHERE status = $1 GROUP BY source_account_id) o ON o.account_id = a.account_id
+LEFT JOIN (SELECT source_account_id AS account_id, sum(amount + fee) AS total
+	FROM holds WHERE status = $2 GROUP BY source_account_id) h ON h.account_id = a.account_id
+WHERE a.balance <> a.initial_balance + COALESCE(ar.net, 0) + COALESCE(i.total, 0) - COALESCE(o.total, 0)
+	OR a.held_balance <> COALESCE(h.total, 0)
+ORDER BY a.account_id`
+
+const discrepancyColumns = "account_id, balance, ledger_balance, held_balance, pending_holds"
+
+// Reconcile checks every account's stored balances against the ledger and
+// records the run with the discrepancies found. Accounts and ledger are read
+// from a single snapshot, so concurrent transfers cannot cause false positives.
+func (s *Store) Reconcile(ctx context.Context) (_ ReconciliationRun, err error) {
+	ctx, span := startSpan(ctx, "Reconcile")
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return ReconciliationRun{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	var run ReconciliationRun
+	if err := tx.QueryRow(ctx, `SELECT count(*) FROM accounts`).Scan(&run.AccountsChecked); err != nil {
+		return ReconciliationRun{}, fmt.Errorf("count accounts: %w", err)
+	}
+	run.Discrepancies, err = queryDiscrepancies(ctx, tx, discrepanciesSQL, StatusSucceeded, HoldPending)
+	if err != nil {
+		return ReconciliationRun{}, err
+	}
+
+	err = tx.QueryRow(ctx, `INSERT INTO reconciliation_runs (accounts_checked) VALUES ($1) RETURNING id, created_at`,
+		run.AccountsChecked).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return ReconciliationRun{}, fmt.Errorf("insert reconciliation run: %w", err)
+	}
+	for _, d := range run.Discrepancies {
+		if _, err := tx.Exec(ctx, `INSERT INTO reconciliation_discrepancies (run_id, `+discrepancyColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+			run.ID, d.AccountID, d.Balance, d.LedgerBalance, d.HeldBalance, d.PendingHolds); err != nil {
+			return ReconciliationRun{}, fmt.Errorf("insert discrepancy for account %d: %w", d.AccountID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return ReconciliationRun{}, fmt.Errorf("commit: %w", err)
+	}
+	span.SetAttributes(
+		attribute.Int("reconciliation.accounts_checked", run.AccountsChecked),
+		attribute.Int("reconciliation.discrepancies", len(run.Discrepancies)),
+	)
+	reconciliationDiscrepancies.Record(ctx, int64(len(run.Discrepancies)))
+	return run, nil
+}
+
+// LatestReconciliation returns the latest reconciliation run and its
+// discrepancies, or ErrReconciliationNotFound if there has been none.
+func (s *Store) LatestReconciliation(ctx context.Context) (_ ReconciliationRun, err error) {
+	ctx, span := startSpan(ctx, "LatestReconciliation")
+	defer func() { endSpan(span, err) }()
+
+	var run ReconciliationRun
+	err = s.read.QueryRow(ctx, `SELECT id, created_at, accounts_checked FROM reconciliation_runs ORDER BY id DESC LIMIT 1`).
+		Scan(&run.ID, &run.CreatedAt, &run.AccountsChecked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ReconciliationRun{}, ErrReconciliationNotFound
+		}
+		return ReconciliationRun{}, fmt.Errorf("get reconciliation run: %w", err)
+	}
+	run.Discrepancies, err = queryDiscrepancies(ctx, s.read, `SELECT `+discrepancyColumns+` FROM reconciliation_discrepancies
+		WHERE run_id = $1 ORDER BY account_id`, run.ID)
+	if err != nil {
+		return ReconciliationRun{}, err
+	}
+	return run, nil
+}
+
+// querier is satisfied by pools, connections and transactions
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryDiscrepancies runs a query returning discrepancyColumns
+func queryDiscrepancies(ctx context.Context, db querier, sql string, args ...any) ([]Discrepancy, error) {
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query discrepancies: %w", err)
+	}
+	defer rows.Close()
+
+	ds := []Discrepancy{}
+	for rows.Next() {
+		var d Discrepancy
+		if err := rows.Scan(&d.AccountID, &d.Balance, &d.LedgerBalance, &d.HeldBalance, &d.PendingHolds); err != nil {
+			return nil, fmt.Errorf("scan discrepancy: %w", err)
+		}
+		ds = append(ds, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query discrepancies: %w", err)
+	}
+	return ds, nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CurrencyTotals aggregates the accounts of one currency and the transfers
+// between them.
+type CurrencyTotals struct {
+	Currency       string
+	Accounts       int
+	Balance        decimal.Decimal // sum of current balances
+	HeldBalance    decimal.Decimal // sum of current held balances
+	TransferCount  int             // succeeded transfers settled in the period
+	TransferVolume decimal.Decimal // their total amount
+}
+
+// PurposeTotals aggregates the transfers of one purpose code in one currency.
+// An empty PurposeCode totals the transfers without one.
+type PurposeTotals struct {
+	PurposeCode    string
+	Currency       string
+	TransferCount  int
+	TransferVolume decimal.Decimal
+}
+
+// TrialBalance is the report returned by TrialBalance.
+type TrialBalance struct {
+	From, To     time.Time
+	Currencies   []CurrencyTotals // ordered by currency
+	Purposes     []PurposeTotals  // the transfers of Currencies by purpose code, ordered by purpose code and currency
+	StatusCounts map[string]int   // transactions created in the period by status
+}
+
+// TrialBalance returns the current totals of all accounts by currency, the
+// succeeded transfer volume in [from, to) by currency and by purpose code,
+// and the number of transactions created in [from, to) by status, all read
+// from one snapshot.
+func (s *Store) TrialBalance(ctx context.Context, from, to time.Time) (_ TrialBalance, err error) {
+	ctx, span := startSpan(ctx, "TrialBalance",
+		attribute.String("report.from", from.Format(time.RFC3339)),
+		attribute.String("report.to", to.Format(time.RFC3339)),
+	)
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.read.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return TrialBalance{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	totals := make(map[string]*CurrencyTotals)
+	currency := func(c string) *CurrencyTotals {
+		if totals[c] == nil {
+			totals[c] = &CurrencyTotals{Currency: c}
+		}
+		return totals[c]
+	}
+
+	rows, err := tx.Query(ctx, `SELECT currency, count(*), sum(balance), sum(held_balance) FROM accounts GROUP BY currency`)
+	if err != nil {
+		return TrialBalance{}, fmt.Errorf("sum balances: %w", err)
+	}
+	for rows.Next() {
+		var c string
+		var n int
+		var balance, held decimal.Decimal
+		if err := rows.Scan(&c, &n, &balance, &held); err != nil {
+			rows.Close()
+			return TrialBalance{}, fmt.Errorf("scan balances: %w", err)
+		}
+		t := currency(c)
+		t.Accounts, t.Balance, t.HeldBalance = n, balance, held
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return TrialBalance{}, fmt.Errorf("sum balances: %w", err)
+	}
+
+	report := TrialBalance{From: from, To: to, Currencies: []CurrencyTotals{}, Purposes: []PurposeTotals{}, StatusCounts: make(map[string]int)}
+	rows, err = tx.Query(ctx, `SELECT purpose_code, currency, count(*), sum(amount) FROM transactions
+		WHERE status = $1 AND kind = 'transfer' AND settled_at >= $2 AND settled_at < $3 AND created_at < $3
+		GROUP BY purpose_code, currency
+		ORDER BY purpose_code, currency`, StatusSucceeded, from, to)
+	if err != nil {
+		return TrialBalance{}, fmt.Errorf("sum transfers: %w", err)
+	}
+	for rows.Next() {
+		var p PurposeTotals
+		if err := rows.Scan(&p.PurposeCode, &p.Currency, &p.TransferCount, &p.TransferVolume); err != nil {
+			rows.Close()
+			return TrialBalance{}, fmt.Errorf("scan transfers: %w", err)
+		}
+		report.Purposes = append(report.Purposes, p)
+		t := currency(p.Currency)
+		t.TransferCount += p.TransferCount
+		t.TransferVolume = t.TransferVolume.Add(p.TransferVolume)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return TrialBalance{}, fmt.Errorf("sum transfers: %w", err)
+	}
+
+	rows, err = tx.Query(ctx, `SELECT status, count(*) FROM transactions
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY status`, from, to)
+	if err != nil {
+		return TrialBalance{}, fmt.Errorf("count transactions: %w", err)
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			rows.Close()
+			return TrialBalance{}, fmt.Errorf("scan transaction counts: %w", err)
+		}
+		report.StatusCounts[status] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return TrialBalance{}, fmt.Errorf("count transactions: %w", err)
+	}
+
+	for _, t := range totals {
+		report.Currencies = append(report.Currencies, *t)
+	}
+	sort.Slice(report.Currencies, func(i, j int) bool {
+		return report.Currencies[i].Currency < report.Currencies[j].Currency
+	})
+	return report, nil
+}
@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DefaultMaxRetries is how many times an operation aborted by a serialization
+// failure or deadlock is retried unless configured with WithMaxRetries.
+const DefaultMaxRetries = 3
+
+// Backoff before retry n (starting at 0) is a random duration in
+// [0, min(retryBaseDelay<<n, retryMaxDelay)).
+const (
+	retryBaseDelay = 10 * time.Millisecond
+	retryMaxDelay  = 500 * time.Millisecond
+)
+
+// WithMaxRetries sets how many times an operation aborted by a serialization
+// failure or deadlock is retried; 0 disables retries.
+func WithMaxRetries(n int) Option {
+	return func(s *Store) {
+		s.maxRetries = max(n, 0)
+	}
+}
+
+// retriesCounter counts the retries of store operations by operation and SQLSTATE.
+// It is bound lazily to the global meter provider, so it records nothing until
+// one is installed.
+var retriesCounter, _ = otel.Meter(tracerName).Int64Counter("store.retries",
+	metric.WithDescription("Store operations retried after a serialization failure or deadlock"),
+	metric.WithUnit("{retry}"),
+)
+
+// retryableCode returns the SQLSTATE of err if it is a transient abort that can
+// be retried as a whole: a serialization failure or a deadlock.
+func retryableCode(err error) (string, bool) {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return "", false
+	}
+	switch pgErr.Code {
+	case pgerrcode.SerializationFailure, pgerrcode.DeadlockDetected:
+		return pgErr.Code, true
+	}
+	return "", false
+}
+
+// retry runs fn, running it again up to s.maxRetries times with jittered
+// exponential backoff while it fails with a retryable error. fn must be safe to
+// repeat, i.e. run its own DB transaction.
+func (s *Store) retry(ctx context.Context, op string, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		code, ok := retryableCode(err)
+		if !ok || attempt >= s.maxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		attrs := []attribute.KeyValue{
+			attribute.String("store.operation", op),
+			attribute.String("db.response.status_code", code),
+		}
+		retriesCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+		trace.SpanFromContext(ctx).AddEvent("retry", trace.WithAttributes(append(attrs, attribute.Int("retry.attempt", attempt+1))...))
+
+		delay := min(retryBaseDelay<<min(attempt, 10), retryMaxDelay)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(rand.N(delay)):
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestRetry(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: pgerrcode.DeadlockDetected}
+	serialization := &pgconn.PgError{Code: pgerrcode.SerializationFailure}
+	other := &pgconn.PgError{Code: pgerrcode.UniqueViolation}
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		errs       []error // returned by successive calls; nil once exhausted
+		wantCalls  int
+		wantErr    error
+	}{
+		{"success", 3, nil, 1, nil},
+		{"retried deadlock", 3, []error{deadlock}, 2, nil},
+		{"retried serialization failure", 3, []error{serialization, deadlock}, 3, nil},
+		{"wrapped", 3, []error{fmt.Errorf("transfer: %w", deadlock)}, 2, nil},
+		{"limit reached", 2, []error{deadlock, deadlock, deadlock, deadlock}, 3, deadlock},
+		{"disabled", 0, []error{deadlock}, 1, deadlock},
+		{"not retryable", 3, []error{other}, 1, other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Store{maxRetries: tt.maxRetries}
+			calls := 0
+			err := s.retry(context.Background(), "Test", func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetryStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := &Store{maxRetries: 3}
+	calls := 0
+	err := s.retry(ctx, "Test", func() error {
+		calls++
+		return &pgconn.PgError{Code: pgerrcode.DeadlockDetected}
+	})
+	if calls != 1 || err == nil {
+		t.Errorf("calls = %d, err = %v; want 1 call and the deadlock error", calls, err)
+	}
+}
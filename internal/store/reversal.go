@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Errors returned by ReverseTransaction
+var (
+	ErrAlreadyReversed = errors.New("transaction already reversed")
+	ErrNotReversible   = errors.New("only succeeded transfers can be reversed")
+)
+
+// ReverseTransaction transfers the amount of a succeeded transaction back from
+// its destination to its source and returns the reversal, which is linked to
+// the original through ReversalOf. A transaction can be reversed only once.
+// The reversal must be allowed by the accounts' statuses, currencies, KYC
+// statuses and counterparty allowlists, and the destination must have the
+// funds above its min balance; it is exempt from transfer limits, fraud rules
+// and fees.
+//
+// A fee charged on the original is refunded with it, from the fee account to
+// the source, as a fee of the reversal linked to the original fee through
+// ReversalOf. ErrFeeAccountUnavailable is returned if the fee account can no
+// longer refund it.
+func (s *Store) ReverseTransaction(ctx context.Context, id int64) (_ Transaction, err error) {
+	ctx, span := startSpan(ctx, "ReverseTransaction", attribute.Int64("transaction.id", id))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// Locking the original serializes concurrent reversals of it
+	orig, err := scanTransaction(tx.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE id = $1 AND ($2::text IS NULL AND $3::text IS NULL OR `+transactionScopeCond(2)+`)
+		FOR UPDATE`, id, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Transaction{}, ErrTransactionNotFound
+		}
+		return Transaction{}, fmt.Errorf("lock transaction: %w", err)
+	}
+	if orig.Status != StatusSucceeded || orig.Kind != KindTransfer {
+		return Transaction{}, ErrNotReversible
+	}
+	var reversed bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE reversal_of = $1)`, id).Scan(&reversed); err != nil {
+		return Transaction{}, fmt.Errorf("check reversal: %w", err)
+	}
+	if reversed {
+		return Transaction{}, ErrAlreadyReversed
+	}
+
+	fee, err := scanTransaction(tx.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE fee_of = $1 AND status = $2 FOR UPDATE`, id, StatusSucceeded))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return Transaction{}, fmt.Errorf("lock fee: %w", err)
+	}
+
+	// The fee account, if any, is locked with the others in ascending order
+	srcID, dstID := orig.DestinationAccountID, orig.SourceAccountID
+	ids := []int64{srcID, dstID}
+	if fee.ID != 0 {
+		ids = append(ids, fee.DestinationAccountID)
+	}
+	accs, err := lockExistingAccounts(ctx, tx, ids)
+	if err != nil {
+		return Transaction{}, err
+	}
+	src, dst := accs[srcID], accs[dstID]
+	if src.ID == 0 || dst.ID == 0 {
+		return Transaction{}, ErrAccountNotFound
+	}
+	if err := s.checkScope(ctx, src, dst); err != nil {
+		return Transaction{}, err
+	}
+	switch {
+	case src.Status != AccountActive || dst.Status != AccountActive:
+		return Transaction{}, ErrAccountInactive
+	case src.Currency != dst.Currency:
+		return Transaction{}, ErrCurrencyMismatch
+	case src.AvailableBalance.LessThan(orig.Amount):
+		return Transaction{}, ErrInsufficientFunds
+	}
+	if err := CheckMinBalance(src, orig.Amount); err != nil {
+		return Transaction{}, err
+	}
+	if err := s.checkKYC(src, dst); err != nil {
+		return Transaction{}, err
+	}
+	if err := checkCounterparties(ctx, tx, srcID, dstID); err != nil {
+		return Transaction{}, err
+	}
+	if fee.ID != 0 {
+		// The fee account may also be the destination of the original
+		refund := fee.Amount
+		if fee.DestinationAccountID == srcID {
+			refund = refund.Add(orig.Amount)
+		}
+		feeAcc, ok := accs[fee.DestinationAccountID]
+		if !ok || feeAcc.Status != AccountActive || feeAcc.Currency != fee.Currency || feeAcc.AvailableBalance.LessThan(refund) {
+			return Transaction{}, ErrFeeAccountUnavailable
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, src.Balance.Sub(orig.Amount), srcID); err != nil {
+		return Transaction{}, fmt.Errorf("update src balance: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, dst.Balance.Add(orig.Amount), dstID); err != nil {
+		return Transaction{}, fmt.Errorf("update dst balance: %w", err)
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return Transaction{}, err
+	}
+	rev, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reversal_of, reversal_of_public_id, public_id, created_at, settled_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$9)
+		RETURNING `+transactionColumns, srcID, dstID, orig.Amount, orig.Currency, StatusSucceeded, id, orig.UUID, uid, s.clock.Now()))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("insert reversal: %w", err)
+	}
+	if err := s.finishTransfer(ctx, tx, rev); err != nil {
+		return Transaction{}, err
+	}
+	if fee.ID != 0 {
+		if err := s.refundFee(ctx, tx, fee, rev); err != nil {
+			return Transaction{}, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Transaction{}, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, ids...)
+	return rev, nil
+}
+
+// refundFee moves the fee back from the fee account, locked in tx, to the
+// account it was charged to, as a fee of the reversal rev, and writes its
+// event.
+func (s *Store) refundFee(ctx context.Context, tx pgx.Tx, fee, rev Transaction) error {
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + CASE WHEN account_id = $2 THEN -$1::numeric ELSE $1::numeric END
+		WHERE account_id IN ($2, $3)`, fee.Amount, fee.DestinationAccountID, fee.SourceAccountID); err != nil {
+		return fmt.Errorf("refund fee: %w", err)
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return err
+	}
+	f, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, kind, fee_of, fee_of_public_id, reversal_of,
+			reversal_of_public_id, public_id, created_at, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12)
+		RETURNING `+transactionColumns, fee.DestinationAccountID, fee.SourceAccountID, fee.Amount, fee.Currency, StatusSucceeded, KindFee,
+		rev.ID, rev.UUID, fee.ID, fee.UUID, uid, rev.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("insert fee refund: %w", err)
+	}
+	return s.finishTransfer(ctx, tx, f)
+}
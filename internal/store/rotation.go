@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RotatingDSN lets the DSN of a running pool change, such as when its
+// password is rotated in a secret manager. Attach it to a pool with
+// PoolConfig.Rotation; give each pool its own.
+//
+// Rotate does not close the pool: new connections use the new DSN, idle ones
+// are closed and those in use are closed once released, so requests carry on
+// throughout.
+type RotatingDSN struct {
+	mu     sync.Mutex
+	dsn    string
+	config *pgx.ConnConfig // connection settings of dsn
+	pool   *pgxpool.Pool
+}
+
+// init records the DSN the pool was opened with.
+func (r *RotatingDSN) init(dsn string, config *pgx.ConnConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dsn, r.config = dsn, config.Copy()
+}
+
+// apply points a new connection of the pool at the current DSN.
+func (r *RotatingDSN) apply(cc *pgx.ConnConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	applyDSN(cc, r.config)
+}
+
+// applyDSN sets the server and credentials of cc to those of src, keeping the
+// settings of the pool, such as its runtime parameters and tracer.
+func applyDSN(cc, src *pgx.ConnConfig) {
+	src = src.Copy()
+	cc.Host, cc.Port, cc.Database = src.Host, src.Port, src.Database
+	cc.User, cc.Password, cc.TLSConfig = src.User, src.Password, src.TLSConfig
+	cc.Fallbacks = src.Fallbacks
+}
+
+// Rotate makes the pool connect with dsn from now on, and reports whether it
+// differs from the current DSN. A connection is opened with dsn first: if it
+// fails, the pool keeps the current DSN.
+func (r *RotatingDSN) Rotate(ctx context.Context, dsn string) (changed bool, err error) {
+	r.mu.Lock()
+	pool, same := r.pool, dsn == r.dsn
+	r.mu.Unlock()
+	if pool == nil {
+		return false, errors.New("rotate DSN: no pool attached")
+	}
+	if same {
+		return false, nil
+	}
+
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return false, fmt.Errorf("rotate DSN: %w", err)
+	}
+	probe := pool.Config().ConnConfig
+	applyDSN(probe, config)
+	conn, err := pgx.ConnectConfig(ctx, probe)
+	if err != nil {
+		return false, fmt.Errorf("rotate DSN: connect: %w", err)
+	}
+	err = conn.Ping(ctx)
+	conn.Close(context.Background())
+	if err != nil {
+		return false, fmt.Errorf("rotate DSN: %w", err)
+	}
+
+	r.mu.Lock()
+	r.dsn, r.config = dsn, config
+	r.mu.Unlock()
+	pool.Reset()
+	log.Printf("db: connecting to %s:%d as %s with the rotated DSN", config.Host, config.Port, config.User)
+	return true, nil
+}
@@ -0,0 +1,501 @@
+package store
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrTransferBlocked is returned for a transfer matching a block rule.
+	ErrTransferBlocked = errors.New("transfer blocked by rule")
+	// ErrRuleNotFound is returned by DeleteRule for an unknown rule.
+	ErrRuleNotFound = errors.New("rule not found")
+	// ErrReviewNotFound is returned for a transaction that was not flagged.
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrReviewResolved is returned by ResolveReview for a review that is no longer pending.
+	ErrReviewResolved = errors.New("review already resolved")
+	// ErrAwaitingApproval is returned by Transfer, along with the id of the
+	// pending transaction, for a transfer matching a require_approval rule.
+	ErrAwaitingApproval = errors.New("transfer awaits approval")
+	// ErrApprovalRequired is returned for a batch or split leg matching a
+	// require_approval rule: only single transfers can await approval.
+	ErrApprovalRequired = errors.New("transfer requires approval; submit it on its own")
+)
+
+// approvalRejected is the error message of a transfer whose approval was rejected
+const approvalRejected = "approval rejected"
+
+// Rule kinds; see Rule
+const (
+	RuleVelocity        = "velocity"
+	RuleNewCounterparty = "new_counterparty"
+	RuleAmount          = "amount"
+	RuleMatch           = "match"
+)
+
+// Rule actions
+const (
+	RuleBlock           = "block"            // the transfer is recorded as failed with ErrTransferBlocked
+	RuleFlag            = "flag"             // the transfer goes through and is queued for review
+	RuleAllow           = "allow"            // the transfer goes through; later rules are not checked
+	RuleRequireApproval = "require_approval" // the transfer is recorded pending and executed once its review is approved
+)
+
+// Review statuses
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// Rule is a row of the transfer_rules table, or a rule of the rules file
+// (see WithRulesFile). It matches the transfers out of SourceAccountID to
+// DestinationAccountID (any if 0), over MinAmount and up to MaxAmount (any if
+// invalid), out of an account of TenantID (any if empty), made between
+// ActiveFrom and ActiveUntil (UTC times of day, wrapping past midnight if
+// ActiveFrom is later; any time if equal) that, by Kind:
+//   - RuleVelocity: follow MaxCount succeeded transfers out of the same account within Window
+//   - RuleNewCounterparty: go to an account the source never sent to before
+//   - RuleAmount, RuleMatch: always
+//
+// Transfers are checked against the rules in Priority order, then by ID, and
+// the first matching rule decides the Action.
+type Rule struct {
+	ID                   int64 // 0 for the rules of the rules file
+	Name                 string
+	Kind                 string
+	Action               string
+	Priority             int
+	SourceAccountID      int64
+	DestinationAccountID int64
+	MinAmount            decimal.NullDecimal
+	MaxAmount            decimal.NullDecimal
+	TenantID             string
+	ActiveFrom           time.Duration // since midnight UTC
+	ActiveUntil          time.Duration // since midnight UTC
+	MaxCount             int
+	Window               time.Duration
+	CreatedAt            time.Time
+}
+
+// ruleColumns is the select list matching scanRule
+const ruleColumns = `id, name, kind, action, priority, COALESCE(source_account_id, 0), COALESCE(destination_account_id, 0),
+	min_amount, max_amount, COALESCE(tenant_id, ''), active_from, active_until, COALESCE(max_count, 0), COALESCE(window_seconds, 0), created_at`
+
+// scanRule scans a row selected with ruleColumns.
+func scanRule(row pgx.Row) (Rule, error) {
+	var r Rule
+	var from, until pgtype.Time
+	var windowSeconds int64
+	if err := row.Scan(&r.ID, &r.Name, &r.Kind, &r.Action, &r.Priority, &r.SourceAccountID, &r.DestinationAccountID,
+		&r.MinAmount, &r.MaxAmount, &r.TenantID, &from, &until, &r.MaxCount, &windowSeconds, &r.CreatedAt); err != nil {
+		return Rule{}, err
+	}
+	r.ActiveFrom = time.Duration(from.Microseconds) * time.Microsecond
+	r.ActiveUntil = time.Duration(until.Microseconds) * time.Microsecond
+	r.Window = time.Duration(windowSeconds) * time.Second
+	return r, nil
+}
+
+// timeOfDay is the TIME parameter of d since midnight, NULL if there is no
+// time window.
+func timeOfDay(d time.Duration, window bool) pgtype.Time {
+	return pgtype.Time{Microseconds: d.Microseconds(), Valid: window}
+}
+
+// CreateRule stores r and reloads the rules of this store; other instances
+// pick it up on their next ReloadRules.
+func (s *Store) CreateRule(ctx context.Context, r Rule) (_ Rule, err error) {
+	ctx, span := startSpan(ctx, "CreateRule", attribute.String("rule.kind", r.Kind))
+	defer func() { endSpan(span, err) }()
+
+	var srcID, dstID, tenantID, maxCount, windowSeconds any
+	if r.SourceAccountID != 0 {
+		srcID = r.SourceAccountID
+	}
+	if r.DestinationAccountID != 0 {
+		dstID = r.DestinationAccountID
+	}
+	if r.TenantID != "" {
+		tenantID = r.TenantID
+	}
+	if r.Kind == RuleVelocity {
+		maxCount, windowSeconds = r.MaxCount, int64(r.Window/time.Second)
+	}
+	window := r.ActiveFrom != r.ActiveUntil
+	r, err = scanRule(s.pool.QueryRow(ctx, `INSERT INTO transfer_rules (name, kind, action, priority, source_account_id, destination_account_id,
+			min_amount, max_amount, tenant_id, active_from, active_until, max_count, window_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING `+ruleColumns,
+		r.Name, r.Kind, r.Action, r.Priority, srcID, dstID, r.MinAmount, r.MaxAmount, tenantID,
+		timeOfDay(r.ActiveFrom, window), timeOfDay(r.ActiveUntil, window), maxCount, windowSeconds))
+	if err != nil {
+		return Rule{}, fmt.Errorf("create rule: %w", err)
+	}
+	if _, err := s.ReloadRules(ctx); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+// ListRules returns all rules, those of the rules file as of the last
+// ReloadRules included, in the order transfers are checked against them.
+func (s *Store) ListRules(ctx context.Context) (_ []Rule, err error) {
+	ctx, span := startSpan(ctx, "ListRules")
+	defer func() { endSpan(span, err) }()
+
+	rules, err := listRules(ctx, s.read)
+	if err != nil {
+		return nil, err
+	}
+	var fileRules []Rule
+	if loaded := s.fileRules.Load(); loaded != nil {
+		fileRules = *loaded
+	}
+	return mergeRules(fileRules, rules), nil
+}
+
+// mergeRules returns the rules of the rules file and of the database in the
+// order transfers are checked against them: by priority, the rules of the
+// file first at equal priority.
+func mergeRules(fileRules, dbRules []Rule) []Rule {
+	rules := append(slices.Clone(fileRules), dbRules...)
+	slices.SortStableFunc(rules, func(a, b Rule) int {
+		return cmp.Compare(a.Priority, b.Priority)
+	})
+	return rules
+}
+
+// listRules reads the rules from db in the order transfers are checked against them.
+func listRules(ctx context.Context, db querier) ([]Rule, error) {
+	rows, err := db.Query(ctx, `SELECT `+ruleColumns+` FROM transfer_rules ORDER BY priority, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	rules, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Rule, error) {
+		return scanRule(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list rules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteRule deletes a rule and reloads the rules of this store. Reviews of
+// transfers it flagged are kept.
+func (s *Store) DeleteRule(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteRule", attribute.Int64("rule.id", id))
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM transfer_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRuleNotFound
+	}
+	_, err = s.ReloadRules(ctx)
+	return err
+}
+
+// ReloadRules replaces the rules transfers are checked against with those in
+// the database and those of the rules file, read again, and returns how many
+// there are. Until it first succeeds no rules apply; when it fails, the rules
+// loaded before stay. It reads the primary, so that a rule is never dropped
+// because of replication lag.
+func (s *Store) ReloadRules(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "ReloadRules")
+	defer func() { endSpan(span, err) }()
+
+	var fileRules []Rule
+	if s.rulesFile != nil {
+		if fileRules, err = s.rulesFile(); err != nil {
+			return 0, fmt.Errorf("load rules file: %w", err)
+		}
+	}
+	dbRules, err := listRules(ctx, s.pool)
+	if err != nil {
+		return 0, err
+	}
+	rules := mergeRules(fileRules, dbRules)
+	s.fileRules.Store(&fileRules)
+	s.rules.Store(&rules)
+	return len(rules), nil
+}
+
+// WithRulesFile checks transfers against the rules load returns as well,
+// called again on every ReloadRules so that the file they are read from can
+// change without a restart.
+func WithRulesFile(load func() ([]Rule, error)) Option {
+	return func(s *Store) {
+		s.rulesFile = load
+	}
+}
+
+// loadedRules returns the rules loaded by the last ReloadRules.
+func (s *Store) loadedRules() []Rule {
+	if rules := s.rules.Load(); rules != nil {
+		return *rules
+	}
+	return nil
+}
+
+// ruleChecker checks the transfers made within one DB transaction against the
+// rules, counting the transfers before them like limitTracker. The source
+// accounts must be locked in tx, so that concurrent transfers out of them are
+// counted as well.
+type ruleChecker struct {
+	rules   []Rule
+	tx      pgx.Tx
+	sent    map[ruleWindow]int // succeeded transfers by source account and window
+	paid    map[[2]int64]bool  // whether the source ever sent to the destination
+	tenants map[int64]string   // tenant of the source accounts
+	now     time.Time          // the end of velocity windows, and the time of day of the transfers
+}
+
+type ruleWindow struct {
+	srcID  int64
+	window time.Duration
+}
+
+func (s *Store) newRuleChecker(tx pgx.Tx) *ruleChecker {
+	return &ruleChecker{rules: s.loadedRules(), tx: tx, sent: make(map[ruleWindow]int), paid: make(map[[2]int64]bool),
+		tenants: make(map[int64]string), now: s.clock.Now()}
+}
+
+// check returns the first rule the transfer matches, or nil if none does.
+func (rc *ruleChecker) check(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) (*Rule, error) {
+	for i := range rc.rules {
+		ok, err := rc.matches(ctx, rc.rules[i], srcID, dstID, amount)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return &rc.rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (rc *ruleChecker) matches(ctx context.Context, r Rule, srcID, dstID int64, amount decimal.Decimal) (bool, error) {
+	if r.SourceAccountID != 0 && r.SourceAccountID != srcID || r.DestinationAccountID != 0 && r.DestinationAccountID != dstID {
+		return false, nil
+	}
+	if r.MinAmount.Valid && !amount.GreaterThan(r.MinAmount.Decimal) || r.MaxAmount.Valid && amount.GreaterThan(r.MaxAmount.Decimal) {
+		return false, nil
+	}
+	if !r.activeAt(rc.now) {
+		return false, nil
+	}
+	if r.TenantID != "" {
+		tenant, ok := rc.tenants[srcID]
+		if !ok {
+			err := rc.tx.QueryRow(ctx, `SELECT COALESCE(tenant_id, '') FROM accounts WHERE account_id = $1`, srcID).Scan(&tenant)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return false, fmt.Errorf("get tenant: %w", err)
+			}
+			rc.tenants[srcID] = tenant
+		}
+		if tenant != r.TenantID {
+			return false, nil
+		}
+	}
+	switch r.Kind {
+	case RuleVelocity:
+		key := ruleWindow{srcID, r.Window}
+		sent, ok := rc.sent[key]
+		if !ok {
+			err := rc.tx.QueryRow(ctx, `SELECT count(*) FROM transactions
+				WHERE source_account_id = $1 AND status = $2 AND kind = 'transfer' AND created_at > $4::timestamptz - $3 * interval '1 second'`,
+				srcID, StatusSucceeded, int64(r.Window/time.Second), rc.now).Scan(&sent)
+			if err != nil {
+				return false, fmt.Errorf("count recent transfers: %w", err)
+			}
+			rc.sent[key] = sent
+		}
+		return sent >= r.MaxCount, nil
+	case RuleNewCounterparty:
+		key := [2]int64{srcID, dstID}
+		paid, ok := rc.paid[key]
+		if !ok {
+			err := rc.tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM transactions
+				WHERE source_account_id = $1 AND destination_account_id = $2 AND status = $3 AND kind = 'transfer')`,
+				srcID, dstID, StatusSucceeded).Scan(&paid)
+			if err != nil {
+				return false, fmt.Errorf("check counterparty: %w", err)
+			}
+			rc.paid[key] = paid
+		}
+		return !paid, nil
+	}
+	return true, nil
+}
+
+// activeAt reports whether t falls in the time window of r.
+func (r Rule) activeAt(t time.Time) bool {
+	if r.ActiveFrom == r.ActiveUntil {
+		return true
+	}
+	t = t.UTC()
+	d := t.Sub(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+	if r.ActiveFrom < r.ActiveUntil {
+		return d >= r.ActiveFrom && d < r.ActiveUntil
+	}
+	return d >= r.ActiveFrom || d < r.ActiveUntil
+}
+
+// add counts a transfer that passed check and succeeded.
+func (rc *ruleChecker) add(srcID, dstID int64) {
+	for key, sent := range rc.sent {
+		if key.srcID == srcID {
+			rc.sent[key] = sent + 1
+		}
+	}
+	if _, ok := rc.paid[[2]int64{srcID, dstID}]; ok {
+		rc.paid[[2]int64{srcID, dstID}] = true
+	}
+}
+
+// flagTransfer queues the transfer t, which matched the flag or
+// require_approval rule r, for review.
+func flagTransfer(ctx context.Context, tx pgx.Tx, t Transaction, r *Rule) error {
+	if _, err := tx.Exec(ctx, `INSERT INTO transfer_reviews (transaction_id, rule_id, rule_name) VALUES ($1, NULLIF($2::bigint, 0), $3)`,
+		t.ID, r.ID, r.Name); err != nil {
+		return fmt.Errorf("flag transaction %d: %w", t.ID, err)
+	}
+	return nil
+}
+
+// Review is a transfer flagged by a rule, with the outcome of its review.
+type Review struct {
+	Transaction
+	RuleID     int64 // 0 once the rule is deleted
+	RuleName   string
+	Status     string
+	ReviewedBy string
+	Reason     string // why DetectAnomalies flagged it, empty for rules
+	FlaggedAt  time.Time
+	ReviewedAt *time.Time
+}
+
+// reviewColumns is the select list matching scanReview, over transfer_reviews
+// joined with transactions
+const reviewColumns = transactionColumns + `, COALESCE(rule_id, 0), rule_name, review_status, COALESCE(reviewed_by, ''), COALESCE(reason, ''), flagged_at, reviewed_at`
+
+// reviewFrom joins the reviews with their transactions for reviewColumns
+const reviewFrom = `transfer_reviews JOIN transactions ON transactions.id = transfer_reviews.transaction_id`
+
+// scanReview scans a row selected with reviewColumns.
+func scanReview(row pgx.Row) (Review, error) {
+	var r Review
+	t := &r.Transaction
+	if err := row.Scan(&t.ID, &t.CreatedAt, &t.SourceAccountID, &t.DestinationAccountID, &t.Amount, &t.Currency, &t.Status, &t.ErrorMessage, &t.ReversalOf,
+		&t.Reference, &t.PurposeCode, &t.Kind, &t.Reason, &t.AdjustedBy, &t.UUID, &t.InitiatedBy, &t.Channel, &t.RequestID, &t.FeeOf, &t.GroupID,
+		&t.ReversalOfUUID, &t.FeeOfUUID, &r.RuleID, &r.RuleName, &r.Status, &r.ReviewedBy, &r.Reason, &r.FlaggedAt, &r.ReviewedAt); err != nil {
+		return Review{}, err
+	}
+	return r, nil
+}
+
+// ListReviews returns up to limit reviews with status (any if empty), oldest
+// first, starting after the transaction id cursor (0 for the first page).
+// Scoped to a tenant or owner, only reviews of transfers touching one of its
+// accounts are listed. The returned next cursor is zero when there are no
+// further pages.
+func (s *Store) ListReviews(ctx context.Context, status string, cursor int64, limit int) (_ []Review, _ int64, err error) {
+	ctx, span := startSpan(ctx, "ListReviews", attribute.String("review.status", status))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT `+reviewColumns+` FROM `+reviewFrom+`
+		WHERE ($1::text = '' OR review_status = $1) AND transaction_id > $2
+			AND ($4::text IS NULL AND $5::text IS NULL OR `+transactionScopeCond(4)+`)
+		ORDER BY transaction_id
+		LIMIT $3`, status, cursor, limit+1, tenantArg(ctx), ownerArg(ctx))
+	if err != nil {
+		return nil, 0, fmt.Errorf("list reviews: %w", err)
+	}
+	reviews, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Review, error) {
+		return scanReview(row)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list reviews: %w", err)
+	}
+
+	var next int64
+	if len(reviews) > limit {
+		reviews = reviews[:limit]
+		next = reviews[limit-1].ID
+	}
+	return reviews, next, nil
+}
+
+// ResolveReview records the decision (ReviewApproved or ReviewRejected) of
+// reviewedBy on the pending review of a flagged transaction. A transfer
+// awaiting approval is executed by ExecutePendingTransfers once approved, and
+// failed when rejected; rejecting a transfer that went through does not undo
+// it, reverse it separately if needed.
+func (s *Store) ResolveReview(ctx context.Context, transactionID int64, status, reviewedBy string) (_ Review, err error) {
+	ctx, span := startSpan(ctx, "ResolveReview",
+		attribute.Int64("transaction.id", transactionID),
+		attribute.String("review.status", status),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if status != ReviewApproved && status != ReviewRejected {
+		return Review{}, fmt.Errorf("unknown review status %q", status)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Review{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+	r, err := scanReview(tx.QueryRow(ctx, `UPDATE transfer_reviews SET review_status = $2, reviewed_by = $3, reviewed_at = now()
+		FROM transactions
+		WHERE transactions.id = transfer_reviews.transaction_id AND transaction_id = $1 AND review_status = $4
+			AND ($5::text IS NULL AND $6::text IS NULL OR `+transactionScopeCond(5)+`)
+		RETURNING `+reviewColumns,
+		transactionID, status, reviewedBy, ReviewPending, tenantArg(ctx), ownerArg(ctx)))
+	if err == nil {
+		if status == ReviewRejected && r.Transaction.Status == StatusPending {
+			t, err := scanTransaction(tx.QueryRow(ctx, `UPDATE transactions SET status = $1, error_message = $2, settled_at = $4 WHERE id = $3
+				RETURNING `+transactionColumns, StatusFailed, approvalRejected, transactionID, s.clock.Now()))
+			if err != nil {
+				return Review{}, fmt.Errorf("fail transaction %d: %w", transactionID, err)
+			}
+			if err := s.finishTransfer(ctx, tx, t); err != nil {
+				return Review{}, err
+			}
+			r.Transaction = t
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return Review{}, fmt.Errorf("commit: %w", err)
+		}
+		return r, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return Review{}, fmt.Errorf("resolve review: %w", err)
+	}
+	// Either the transaction was not flagged or its review is over
+	var pending bool
+	err = s.pool.QueryRow(ctx, `SELECT review_status = $2 FROM `+reviewFrom+`
+		WHERE transaction_id = $1 AND ($3::text IS NULL AND $4::text IS NULL OR `+transactionScopeCond(3)+`)`,
+		transactionID, ReviewPending, tenantArg(ctx), ownerArg(ctx)).Scan(&pending)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Review{}, ErrReviewNotFound
+		}
+		return Review{}, fmt.Errorf("resolve review: %w", err)
+	}
+	return Review{}, ErrReviewResolved
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRuleActiveAt(t *testing.T) {
+	at := func(hour, min int) time.Time {
+		return time.Date(2024, 3, 1, hour, min, 0, 0, time.UTC)
+	}
+	day := Rule{ActiveFrom: 9 * time.Hour, ActiveUntil: 17 * time.Hour}
+	night := Rule{ActiveFrom: 22 * time.Hour, ActiveUntil: 6 * time.Hour}
+
+	for name, c := range map[string]struct {
+		r    Rule
+		t    time.Time
+		want bool
+	}{
+		"always":              {Rule{}, at(3, 0), true},
+		"day start":           {day, at(9, 0), true},
+		"day end excluded":    {day, at(17, 0), false},
+		"before day":          {day, at(8, 59), false},
+		"night late":          {night, at(23, 30), true},
+		"night early":         {night, at(5, 59), true},
+		"night midday":        {night, at(12, 0), false},
+		"other zone":          {day, time.Date(2024, 3, 1, 11, 0, 0, 0, time.FixedZone("UTC+5", 5*3600)), false},
+		"other zone in range": {day, time.Date(2024, 3, 1, 15, 0, 0, 0, time.FixedZone("UTC+5", 5*3600)), true},
+	} {
+		if got := c.r.activeAt(c.t); got != c.want {
+			t.Errorf("%s: activeAt(%s) = %v, want %v", name, c.t, got, c.want)
+		}
+	}
+}
+
+func TestMergeRules(t *testing.T) {
+	file := []Rule{{Name: "file-0", Priority: 0}, {Name: "file-10", Priority: 10}}
+	db := []Rule{{ID: 1, Name: "db-minus", Priority: -5}, {ID: 2, Name: "db-0", Priority: 0}, {ID: 3, Name: "db-20", Priority: 20}}
+
+	rules := mergeRules(file, db)
+	want := []string{"db-minus", "file-0", "db-0", "file-10", "db-20"}
+	if len(rules) != len(want) {
+		t.Fatalf("expected %d rules, got %d", len(want), len(rules))
+	}
+	for i, name := range want {
+		if rules[i].Name != name {
+			t.Fatalf("rule %d: expected %s, got %s", i, name, rules[i].Name)
+		}
+	}
+	if file[0].Name != "file-0" || file[1].Name != "file-10" {
+		t.Fatalf("file rules modified: %+v", file)
+	}
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrScheduledTransferNotFound is returned for unknown scheduled transfer ids.
+var ErrScheduledTransferNotFound = errors.New("scheduled transfer not found")
+
+// Scheduled transfer statuses
+const (
+	ScheduledPending   = "pending"
+	ScheduledRunning   = "running"
+	ScheduledSucceeded = "succeeded"
+	ScheduledFailed    = "failed"
+)
+
+// ScheduledTransfer is a row of the scheduled_transfers table
+type ScheduledTransfer struct {
+	ID                   int64
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Status               string
+	TransactionID        int64     // set once succeeded
+	TransactionUUID      uuid.UUID // likewise, the public id of the transaction
+	ErrorMessage         string
+	CreatedAt            time.Time
+	ExecuteAt            time.Time
+	ExecutedAt           *time.Time
+	Tenant               string // the transfer executes scoped to it, if not empty
+	OwnerID              string // likewise
+	InitiatedBy          string // the caller that scheduled it, recorded on the transaction
+}
+
+// scheduledColumns is the select list matching scanScheduled
+const scheduledColumns = `id, source_account_id, destination_account_id, amount, status,
+	COALESCE(transaction_id, 0), COALESCE(error_message, ''), created_at, execute_at, executed_at, tenant_id, owner_id, transaction_public_id, initiated_by`
+
+// scanScheduled scans a row selected with scheduledColumns.
+func scanScheduled(row pgx.Row) (ScheduledTransfer, error) {
+	var st ScheduledTransfer
+	if err := row.Scan(&st.ID, &st.SourceAccountID, &st.DestinationAccountID, &st.Amount, &st.Status,
+		&st.TransactionID, &st.ErrorMessage, &st.CreatedAt, &st.ExecuteAt, &st.ExecutedAt, &st.Tenant, &st.OwnerID, &st.TransactionUUID,
+		&st.InitiatedBy); err != nil {
+		return ScheduledTransfer{}, err
+	}
+	return st, nil
+}
+
+// CreateScheduledTransfer records a transfer to be executed at executeAt, in
+// the scope of ctx, as initiated by initiatedBy. Accounts are checked when the
+// transfer runs, not when it is scheduled, but for a transfer to the source
+// account itself.
+func (s *Store) CreateScheduledTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (_ ScheduledTransfer, err error) {
+	ctx, span := startSpan(ctx, "CreateScheduledTransfer",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return ScheduledTransfer{}, err
+	}
+	tenant, _ := TenantFromContext(ctx)
+	owner, _ := OwnerFromContext(ctx)
+	st, err := scanScheduled(s.pool.QueryRow(ctx, `INSERT INTO scheduled_transfers (source_account_id, destination_account_id, amount, execute_at, tenant_id, owner_id, created_at, initiated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING `+scheduledColumns,
+		srcID, dstID, amount, executeAt, tenant, owner, s.clock.Now(), initiatedBy))
+	if err != nil {
+		return ScheduledTransfer{}, fmt.Errorf("create scheduled transfer: %w", err)
+	}
+	return st, nil
+}
+
+// GetScheduledTransfer fetches a scheduled transfer by id. Scoped to a tenant
+// or owner, only transfers scheduled in that scope are found.
+func (s *Store) GetScheduledTransfer(ctx context.Context, id int64) (_ ScheduledTransfer, err error) {
+	ctx, span := startSpan(ctx, "GetScheduledTransfer", attribute.Int64("scheduled_transfer.id", id))
+	defer func() { endSpan(span, err) }()
+
+	st, err := scanScheduled(s.read.QueryRow(ctx, `SELECT `+scheduledColumns+` FROM scheduled_transfers
+		WHERE id = $1 AND `+accountScopeCond(2), id, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ScheduledTransfer{}, ErrScheduledTransferNotFound
+		}
+		return ScheduledTransfer{}, fmt.Errorf("get scheduled transfer: %w", err)
+	}
+	return st, nil
+}
+
+// ExecuteDueTransfers runs up to limit pending scheduled transfers whose
+// execute_at is at or before now, oldest first, through Transfer, and records
+// each outcome. It returns how many transfers were attempted.
+//
+// Callers must ensure a single instance runs it at a time (see SchedulerLockID).
+func (s *Store) ExecuteDueTransfers(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	ctx, span := startSpan(ctx, "ExecuteDueTransfers")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.pool.Query(ctx, `SELECT `+scheduledColumns+` FROM scheduled_transfers
+		WHERE status = $1 AND execute_at <= $2
+		ORDER BY execute_at, id
+		LIMIT $3`, ScheduledPending, now, limit)
+	if err != nil {
+		return 0, fmt.Errorf("list due transfers: %w", err)
+	}
+	due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ScheduledTransfer, error) {
+		return scanScheduled(row)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list due transfers: %w", err)
+	}
+
+	attempted := 0
+	for _, st := range due {
+		// Claim the row first: a crash after the transfer commits leaves it
+		// running instead of executing it again on restart
+		tag, err := s.pool.Exec(ctx, `UPDATE scheduled_transfers SET status = $1 WHERE id = $2 AND status = $3`,
+			ScheduledRunning, st.ID, ScheduledPending)
+		if err != nil {
+			return attempted, fmt.Errorf("claim scheduled transfer %d: %w", st.ID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			continue
+		}
+		attempted++
+
+		status, errMsg := ScheduledSucceeded, ""
+		uid, err := s.NewTransactionID()
+		if err != nil {
+			return attempted, err
+		}
+		txID, terr := s.Transfer(WithOwner(WithTenant(ctx, st.Tenant), st.OwnerID), st.SourceAccountID, st.DestinationAccountID, st.Amount,
+			TransferDetails{UUID: uid, InitiatedBy: st.InitiatedBy, Channel: ChannelScheduler})
+		switch {
+		case errors.Is(terr, ErrAwaitingApproval):
+			// The transaction is executed once approved
+			errMsg = terr.Error()
+		case terr != nil:
+			status, errMsg = ScheduledFailed, terr.Error()
+		}
+		if _, err := s.pool.Exec(ctx, `UPDATE scheduled_transfers
+			SET status = $1, transaction_id = NULLIF($2::bigint, 0), transaction_public_id = CASE WHEN $2::bigint <> 0 THEN $6::uuid END,
+				error_message = NULLIF($3::text, ''), executed_at = $5
+			WHERE id = $4`, status, txID, errMsg, st.ID, s.clock.Now(), uid); err != nil {
+			return attempted, fmt.Errorf("record scheduled transfer %d: %w", st.ID, err)
+		}
+	}
+	return attempted, nil
+}
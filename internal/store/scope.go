@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCrossTenant is returned for a transfer between accounts of different
+// tenants when the store does not allow them (see WithCrossTenantTransfers).
+var ErrCrossTenant = errors.New("cross-tenant transfers are not allowed")
+
+type (
+	tenantKey struct{}
+	ownerKey  struct{}
+)
+
+// WithTenant returns a copy of ctx scoping store calls made with it to tenant:
+// accounts of other tenants are reported as not found, transactions touching
+// none of the tenant's accounts as not found, and new accounts are created in
+// the tenant. An empty tenant leaves ctx unscoped, which sees every tenant.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	if tenant == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant ctx is scoped to, if any.
+func TenantFromContext(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(tenantKey{}).(string)
+	return t, ok
+}
+
+// WithOwner returns a copy of ctx scoping store calls made with it to the
+// accounts owned by owner, the same way WithTenant scopes them to a tenant:
+// other accounts can still receive transfers, but cannot be read or sent
+// from. New accounts are owned by owner. An empty owner leaves ctx unscoped.
+func WithOwner(ctx context.Context, owner string) context.Context {
+	if owner == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ownerKey{}, owner)
+}
+
+// OwnerFromContext returns the owner ctx is scoped to, if any.
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	o, ok := ctx.Value(ownerKey{}).(string)
+	return o, ok
+}
+
+// tenantArg returns the tenant ctx is scoped to as a query argument, NULL when
+// unscoped, for conditions of the form ($n::text IS NULL OR tenant_id = $n).
+func tenantArg(ctx context.Context) *string {
+	if t, ok := TenantFromContext(ctx); ok {
+		return &t
+	}
+	return nil
+}
+
+// ownerArg is tenantArg for the owner ctx is scoped to.
+func ownerArg(ctx context.Context) *string {
+	if o, ok := OwnerFromContext(ctx); ok {
+		return &o
+	}
+	return nil
+}
+
+// scoped reports whether ctx is scoped to a tenant or an owner.
+func scoped(ctx context.Context) bool {
+	return tenantArg(ctx) != nil || ownerArg(ctx) != nil
+}
+
+// inScope reports whether acc is visible in ctx.
+func inScope(ctx context.Context, acc Account) bool {
+	if t, ok := TenantFromContext(ctx); ok && t != acc.Tenant {
+		return false
+	}
+	if o, ok := OwnerFromContext(ctx); ok && o != acc.OwnerID {
+		return false
+	}
+	return true
+}
+
+// WithCrossTenantTransfers allows transfers between accounts of different
+// tenants. The source account must still be in the caller's tenant.
+func WithCrossTenantTransfers() Option {
+	return func(s *Store) {
+		s.crossTenant = true
+	}
+}
+
+// checkScope returns the error a transfer from src to dst fails with because
+// of their tenants or owners: an AccountNotFoundError if src is not visible in
+// ctx, and ErrCrossTenant if their tenants differ and the store does not allow it.
+func (s *Store) checkScope(ctx context.Context, src, dst Account) error {
+	if !inScope(ctx, src) {
+		return &AccountNotFoundError{AccountID: src.ID, Party: PartySource}
+	}
+	if src.Tenant != dst.Tenant && !s.crossTenant {
+		return ErrCrossTenant
+	}
+	return nil
+}
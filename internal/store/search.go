@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidCursor is returned by SearchTransactions for a cursor it did not issue.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// TransactionFilter restricts the transactions returned by SearchTransactions;
+// zero fields match everything. From is inclusive and To exclusive.
+type TransactionFilter struct {
+	From, To  time.Time
+	Status    string
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	AccountID int64 // source or destination
+}
+
+// Orders of SearchTransactions
+const (
+	OrderCreatedAt = "created_at"
+	OrderAmount    = "amount"
+)
+
+// TransactionOrder sorts search results by Field, then by id in the same direction.
+type TransactionOrder struct {
+	Field string // OrderCreatedAt or OrderAmount
+	Desc  bool
+}
+
+// SearchTransactions returns up to limit transactions matching f sorted by
+// order, starting after cursor ("" for the first page). next is the cursor for
+// the following page, or "" on the last page; it is only valid with the same
+// order.
+func (s *Store) SearchTransactions(ctx context.Context, f TransactionFilter, order TransactionOrder, cursor string, limit int) (_ []Transaction, next string, err error) {
+	ctx, span := startSpan(ctx, "SearchTransactions",
+		attribute.String("search.order", order.Field),
+		attribute.Bool("search.desc", order.Desc),
+	)
+	defer func() { endSpan(span, err) }()
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.MinAmount != nil {
+		add("amount >= $%d", f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("amount <= $%d", f.MaxAmount)
+	}
+	if f.AccountID != 0 {
+		add("(source_account_id = $%[1]d OR destination_account_id = $%[1]d)", f.AccountID)
+	}
+	if scoped(ctx) {
+		args = append(args, tenantArg(ctx), ownerArg(ctx))
+		conds = append(conds, transactionScopeCond(len(args)-1))
+	}
+
+	var col string
+	switch order.Field {
+	case OrderCreatedAt:
+		col = "created_at"
+	case OrderAmount:
+		col = "amount"
+	default:
+		return nil, "", fmt.Errorf("unknown transaction order %q", order.Field)
+	}
+	cmp, dir := ">", "ASC"
+	if order.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if cursor != "" {
+		value, id, err := decodeSearchCursor(order.Field, cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, value, id)
+		conds = append(conds, fmt.Sprintf("(%s, id) %s ($%d, $%d)", col, cmp, len(args)-1, len(args)))
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	// Fetch one extra row to find out whether another page follows
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY %[1]s %[2]s, id %[2]s LIMIT $%[3]d`, col, dir, len(args))
+
+	rows, err := s.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("search transactions: %w", err)
+	}
+	txs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Transaction, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("search transactions: %w", err)
+	}
+
+	if len(txs) > limit {
+		txs = txs[:limit]
+		next = encodeSearchCursor(order.Field, txs[limit-1])
+	}
+	return txs, next, nil
+}
+
+// encodeSearchCursor returns an opaque cursor for the rows sorted after t
+func encodeSearchCursor(field string, t Transaction) string {
+	value := t.Amount.String()
+	if field == OrderCreatedAt {
+		value = t.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(value + "," + strconv.FormatInt(t.ID, 10)))
+}
+
+// decodeSearchCursor returns the sort value and id encoded in cursor
+func decodeSearchCursor(field, cursor string) (value interface{}, id int64, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	v, idStr, ok := strings.Cut(string(b), ",")
+	if !ok {
+		return nil, 0, ErrInvalidCursor
+	}
+	if id, err = strconv.ParseInt(idStr, 10, 64); err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	if field == OrderCreatedAt {
+		value, err = time.Parse(time.RFC3339Nano, v)
+	} else {
+		value, err = decimal.NewFromString(v)
+	}
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return value, id, nil
+}
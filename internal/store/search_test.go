@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestSearchCursorRoundTrip(t *testing.T) {
+	tx := Transaction{
+		ID:        42,
+		CreatedAt: time.Date(2024, 1, 31, 23, 59, 59, 123456000, time.UTC),
+		Amount:    decimal.RequireFromString("10.50"),
+	}
+
+	value, id, err := decodeSearchCursor(OrderCreatedAt, encodeSearchCursor(OrderCreatedAt, tx))
+	if err != nil || id != 42 || !value.(time.Time).Equal(tx.CreatedAt) {
+		t.Fatalf("created_at cursor: got %v, %d, %v", value, id, err)
+	}
+	value, id, err = decodeSearchCursor(OrderAmount, encodeSearchCursor(OrderAmount, tx))
+	if err != nil || id != 42 || !value.(decimal.Decimal).Equal(tx.Amount) {
+		t.Fatalf("amount cursor: got %v, %d, %v", value, id, err)
+	}
+
+	// A cursor issued for one order is not valid for the other
+	if _, _, err := decodeSearchCursor(OrderCreatedAt, encodeSearchCursor(OrderAmount, tx)); err != ErrInvalidCursor {
+		t.Fatalf("expected ErrInvalidCursor, got %v", err)
+	}
+	for _, c := range []string{"!!", "MTA", "eCx5"} {
+		if _, _, err := decodeSearchCursor(OrderAmount, c); err != ErrInvalidCursor {
+			t.Fatalf("%q: expected ErrInvalidCursor, got %v", c, err)
+		}
+	}
+}
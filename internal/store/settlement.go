@@ -0,0 +1,430 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrNotClearingAccount is returned for a clearing transfer involving, or the
+// removal of, an account not designated for clearing.
+var ErrNotClearingAccount = errors.New("account is not a clearing account")
+
+// ClearingTransfer is a row of the clearing_transfers table: a transfer
+// between two clearing accounts accepted during BusinessDate, whose balances
+// move when the day is settled.
+type ClearingTransfer struct {
+	ID                   int64
+	BusinessDate         time.Time
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Currency             string
+	Reference            string
+	SettlementID         int64 // 0 until settled
+	CreatedAt            time.Time
+}
+
+// SettlementPosition is the netted position of a pair of clearing accounts
+// over a business day, AccountA being the lower id. Net is what AccountA owes
+// AccountB, negative when AccountB owes AccountA.
+type SettlementPosition struct {
+	AccountA        int64
+	AccountB        int64
+	Currency        string
+	AToB            decimal.Decimal // gross of the clearing transfers from AccountA to AccountB
+	BToA            decimal.Decimal // and back
+	Net             decimal.Decimal
+	Transfers       int
+	SettlementID    int64      // 0 until settled
+	TransactionID   int64      // the settlement transaction; 0 until settled or if Net is zero
+	TransactionUUID uuid.UUID  // likewise, the public id of the transaction
+	SettledAt       *time.Time // nil until settled
+}
+
+// SettlementReport is the netted positions of a business day, as returned by
+// Store.SettlementReport.
+type SettlementReport struct {
+	BusinessDate time.Time
+	Cutoff       time.Time // when the business day ends
+	Positions    []SettlementPosition
+}
+
+// WithSettlementCutoff sets the time of day, past midnight UTC, ending the
+// business day of clearing transfers; 0, the default, ends it at midnight.
+func WithSettlementCutoff(cutoff time.Duration) Option {
+	return func(s *Store) {
+		s.cutoff = cutoff
+	}
+}
+
+// BusinessDate returns the business day t falls in, at midnight UTC, when
+// days end at cutoff past midnight UTC: a time at or past the cutoff falls in
+// the next day. A cutoff of 0 ends days at midnight.
+func BusinessDate(t time.Time, cutoff time.Duration) time.Time {
+	if cutoff <= 0 {
+		cutoff = 24 * time.Hour
+	}
+	t = t.UTC().Add(24*time.Hour - cutoff)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// BusinessDayEnd returns the cutoff ending the business day date.
+func BusinessDayEnd(date time.Time, cutoff time.Duration) time.Time {
+	if cutoff <= 0 {
+		cutoff = 24 * time.Hour
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Add(cutoff)
+}
+
+// ClearingAccounts returns the ids of the accounts designated for clearing in ascending order.
+func (s *Store) ClearingAccounts(ctx context.Context) (_ []int64, err error) {
+	ctx, span := startSpan(ctx, "ClearingAccounts")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT account_id FROM clearing_accounts ORDER BY account_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list clearing accounts: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return nil, fmt.Errorf("list clearing accounts: %w", err)
+	}
+	return ids, nil
+}
+
+// AddClearingAccount designates accountID for clearing; doing it again is a
+// no-op. It returns ErrAccountNotFound if the account does not exist.
+func (s *Store) AddClearingAccount(ctx context.Context, accountID int64) (err error) {
+	ctx, span := startSpan(ctx, "AddClearingAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	var exists bool
+	err = s.pool.QueryRow(ctx, `WITH acc AS (SELECT account_id FROM accounts WHERE account_id = $1),
+			ins AS (INSERT INTO clearing_accounts (account_id) SELECT account_id FROM acc ON CONFLICT DO NOTHING)
+		SELECT EXISTS (SELECT 1 FROM acc)`, accountID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("add clearing account: %w", err)
+	}
+	if !exists {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// RemoveClearingAccount withdraws the designation of accountID for clearing.
+// The clearing transfers already accepted are still settled. It returns
+// ErrNotClearingAccount if the account is not designated.
+func (s *Store) RemoveClearingAccount(ctx context.Context, accountID int64) (err error) {
+	ctx, span := startSpan(ctx, "RemoveClearingAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM clearing_accounts WHERE account_id = $1`, accountID)
+	if err != nil {
+		return fmt.Errorf("remove clearing account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotClearingAccount
+	}
+	return nil
+}
+
+// CreateClearingTransfer accepts a transfer of amount from srcID to dstID,
+// both clearing accounts, into the current business day; balances move when
+// the day is settled. Both accounts must be active, allowed to transfer by
+// their KYC statuses and counterparty allowlists and in the same currency,
+// and in the same tenant unless cross-tenant transfers are allowed; the KYC
+// statuses and allowlists are checked again at settlement. No balance,
+// limit or fraud rule is checked: the transfer is an obligation netted with
+// the others between the pair.
+func (s *Store) CreateClearingTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (_ ClearingTransfer, err error) {
+	ctx, span := startSpan(ctx, "CreateClearingTransfer",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if !amount.IsPositive() {
+		return ClearingTransfer{}, fmt.Errorf("amount must be positive")
+	}
+	if srcID == dstID {
+		return ClearingTransfer{}, ErrSameAccount
+	}
+	rows, err := s.pool.Query(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = ANY($1)`, []int64{srcID, dstID})
+	if err != nil {
+		return ClearingTransfer{}, fmt.Errorf("read accounts: %w", err)
+	}
+	accs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Account, error) {
+		return scanAccount(row)
+	})
+	if err != nil {
+		return ClearingTransfer{}, fmt.Errorf("read accounts: %w", err)
+	}
+	var src, dst Account
+	for _, acc := range accs {
+		if acc.ID == srcID {
+			src = acc
+		} else {
+			dst = acc
+		}
+	}
+	if err := CheckTransferAccounts(srcID, dstID, src.ID != 0, dst.ID != 0); err != nil {
+		return ClearingTransfer{}, err
+	}
+	if err := s.checkScope(ctx, src, dst); err != nil {
+		return ClearingTransfer{}, err
+	}
+	var clearing int
+	if err := s.pool.QueryRow(ctx, `SELECT count(*) FROM clearing_accounts WHERE account_id = ANY($1)`, []int64{srcID, dstID}).Scan(&clearing); err != nil {
+		return ClearingTransfer{}, fmt.Errorf("check clearing accounts: %w", err)
+	}
+	switch {
+	case clearing < 2:
+		return ClearingTransfer{}, ErrNotClearingAccount
+	case src.Status != AccountActive || dst.Status != AccountActive:
+		return ClearingTransfer{}, ErrAccountInactive
+	case src.Currency != dst.Currency:
+		return ClearingTransfer{}, ErrCurrencyMismatch
+	}
+	if err := s.checkKYC(src, dst); err != nil {
+		return ClearingTransfer{}, err
+	}
+	if err := checkCounterparties(ctx, s.pool, srcID, dstID); err != nil {
+		return ClearingTransfer{}, err
+	}
+
+	now := s.clock.Now()
+	ct := ClearingTransfer{
+		BusinessDate:         BusinessDate(now, s.cutoff),
+		SourceAccountID:      srcID,
+		DestinationAccountID: dstID,
+		Amount:               amount,
+		Currency:             src.Currency,
+		Reference:            reference,
+		CreatedAt:            now,
+	}
+	err = s.pool.QueryRow(ctx, `INSERT INTO clearing_transfers (business_date, source_account_id, destination_account_id, amount, currency, reference, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		ct.BusinessDate, srcID, dstID, amount, ct.Currency, reference, now).Scan(&ct.ID)
+	if err != nil {
+		return ClearingTransfer{}, fmt.Errorf("insert clearing transfer: %w", err)
+	}
+	return ct, nil
+}
+
+// settlementPositionsSQL nets the clearing transfers of business day $1 per
+// pair of accounts, and per settlement, which settles all of a pair's.
+const settlementPositionsSQL = `
+SELECT LEAST(ct.source_account_id, ct.destination_account_id) AS account_a,
+	GREATEST(ct.source_account_id, ct.destination_account_id) AS account_b,
+	min(ct.currency),
+	COALESCE(sum(ct.amount) FILTER (WHERE ct.source_account_id < ct.destination_account_id), 0),
+	COALESCE(sum(ct.amount) FILTER (WHERE ct.source_account_id > ct.destination_account_id), 0),
+	count(*), COALESCE(ct.settlement_id, 0), COALESCE(st.transaction_id, 0), st.transaction_public_id, st.settled_at
+FROM clearing_transfers ct LEFT JOIN settlements st ON st.id = ct.settlement_id
+WHERE ct.business_date = $1
+GROUP BY account_a, account_b, ct.settlement_id, st.transaction_id, st.transaction_public_id, st.settled_at
+ORDER BY account_a, account_b, ct.settlement_id`
+
+// SettlementReport returns the positions of the pairs of clearing accounts
+// netted over the business day date, or over the current one if date is zero.
+// Until the day is settled they are those it would be settled with so far.
+func (s *Store) SettlementReport(ctx context.Context, date time.Time) (_ SettlementReport, err error) {
+	if date.IsZero() {
+		date = BusinessDate(s.clock.Now(), s.cutoff)
+	}
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	ctx, span := startSpan(ctx, "SettlementReport", attribute.String("settlement.business_date", date.Format(time.DateOnly)))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, settlementPositionsSQL, date)
+	if err != nil {
+		return SettlementReport{}, fmt.Errorf("settlement positions: %w", err)
+	}
+	positions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SettlementPosition, error) {
+		var p SettlementPosition
+		err := row.Scan(&p.AccountA, &p.AccountB, &p.Currency, &p.AToB, &p.BToA, &p.Transfers, &p.SettlementID, &p.TransactionID, &p.TransactionUUID, &p.SettledAt)
+		p.Net = p.AToB.Sub(p.BToA)
+		return p, err
+	})
+	if err != nil {
+		return SettlementReport{}, fmt.Errorf("settlement positions: %w", err)
+	}
+	return SettlementReport{BusinessDate: date, Cutoff: BusinessDayEnd(date, s.cutoff), Positions: positions}, nil
+}
+
+// Settle settles the clearing transfers of every business day whose cutoff
+// has passed, and returns how many pairs of accounts were settled. Each pair
+// is settled in a DB transaction of its own, moving the net from the account
+// owing it to the other as a succeeded transaction of kind KindSettlement,
+// exempt from limits, fraud rules and fees. A pair that cannot be settled,
+// because an account is no longer active or allowed to transfer by its KYC
+// status or counterparty allowlist, or the one owing lacks the funds or would
+// be taken below its minimum balance, is left for the next run; its error is returned along with the others once the
+// remaining pairs are settled.
+func (s *Store) Settle(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "Settle")
+	defer func() {
+		span.SetAttributes(attribute.Int("settlement.settled", n))
+		endSpan(span, err)
+	}()
+
+	// Clearing transfers accepted just before the cutoff may not be committed yet
+	last := BusinessDate(s.clock.Now().Add(-snapshotDelay), s.cutoff).AddDate(0, 0, -1)
+	rows, err := s.pool.Query(ctx, `SELECT DISTINCT business_date,
+			LEAST(source_account_id, destination_account_id), GREATEST(source_account_id, destination_account_id)
+		FROM clearing_transfers WHERE settlement_id IS NULL AND business_date <= $1
+		ORDER BY 1, 2, 3`, last)
+	if err != nil {
+		return 0, fmt.Errorf("list unsettled clearing transfers: %w", err)
+	}
+	type pair struct {
+		date time.Time
+		a, b int64
+	}
+	pairs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pair, error) {
+		var p pair
+		err := row.Scan(&p.date, &p.a, &p.b)
+		return p, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("list unsettled clearing transfers: %w", err)
+	}
+
+	var errs []error
+	for _, p := range pairs {
+		ok, err := s.settlePair(ctx, p.date, p.a, p.b)
+		if err != nil {
+			if ctx.Err() != nil {
+				return n, err
+			}
+			errs = append(errs, fmt.Errorf("settle accounts %d and %d for %s: %w", p.a, p.b, p.date.Format(time.DateOnly), err))
+			continue
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// settlePair settles the unsettled clearing transfers between accounts a < b
+// of business day date. ok is false if another run settled them first.
+func (s *Store) settlePair(ctx context.Context, date time.Time, a, b int64) (ok bool, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	accs, err := lockExistingAccounts(ctx, tx, []int64{a, b})
+	if err != nil {
+		return false, err
+	}
+	rows, err := tx.Query(ctx, `SELECT id, source_account_id, amount, currency FROM clearing_transfers
+		WHERE business_date = $1 AND LEAST(source_account_id, destination_account_id) = $2
+			AND GREATEST(source_account_id, destination_account_id) = $3 AND settlement_id IS NULL
+		ORDER BY id FOR UPDATE`, date, a, b)
+	if err != nil {
+		return false, fmt.Errorf("lock clearing transfers: %w", err)
+	}
+	var ids []int64
+	var net decimal.Decimal
+	var currency string
+	for rows.Next() {
+		var id, srcID int64
+		var amount decimal.Decimal
+		if err := rows.Scan(&id, &srcID, &amount, &currency); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("scan clearing transfer: %w", err)
+		}
+		ids = append(ids, id)
+		if srcID == a {
+			net = net.Add(amount)
+		} else {
+			net = net.Sub(amount)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("lock clearing transfers: %w", err)
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	var txnID *int64
+	var txnUUID *uuid.UUID
+	if !net.IsZero() {
+		src, dst := accs[a], accs[b]
+		if net.IsNegative() {
+			src, dst = dst, src
+		}
+		t, err := s.settleNet(ctx, tx, src, dst, net.Abs(), date)
+		if err != nil {
+			return false, err
+		}
+		txnID, txnUUID = &t.ID, &t.UUID
+	}
+	var settlementID int64
+	err = tx.QueryRow(ctx, `INSERT INTO settlements (business_date, account_a, account_b, currency, net, transaction_id, transaction_public_id, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id`, date, a, b, currency, net, txnID, txnUUID, s.clock.Now()).Scan(&settlementID)
+	if err != nil {
+		return false, fmt.Errorf("insert settlement: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE clearing_transfers SET settlement_id = $1 WHERE id = ANY($2)`, settlementID, ids); err != nil {
+		return false, fmt.Errorf("mark clearing transfers settled: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, a, b)
+	return true, nil
+}
+
+// settleNet moves amount from src to dst, both locked in tx, as the
+// settlement of business day date, and records it with its event.
+func (s *Store) settleNet(ctx context.Context, tx pgx.Tx, src, dst Account, amount decimal.Decimal, date time.Time) (Transaction, error) {
+	switch {
+	case src.ID == 0 || dst.ID == 0:
+		return Transaction{}, ErrAccountNotFound
+	case src.Status != AccountActive || dst.Status != AccountActive:
+		return Transaction{}, ErrAccountInactive
+	case src.AvailableBalance.LessThan(amount):
+		return Transaction{}, ErrInsufficientFunds
+	}
+	if err := CheckMinBalance(src, amount); err != nil {
+		return Transaction{}, err
+	}
+	if err := s.checkKYC(src, dst); err != nil {
+		return Transaction{}, err
+	}
+	if err := checkCounterparties(ctx, tx, src.ID, dst.ID); err != nil {
+		return Transaction{}, err
+	}
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return Transaction{}, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + CASE WHEN account_id = $2 THEN -$1::numeric ELSE $1::numeric END
+		WHERE account_id IN ($2, $3)`, amount, src.ID, dst.ID); err != nil {
+		return Transaction{}, fmt.Errorf("update balances: %w", err)
+	}
+	t, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, kind, reference, public_id, created_at, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		RETURNING `+transactionColumns, src.ID, dst.ID, amount, src.Currency, StatusSucceeded, KindSettlement,
+		"settlement "+date.Format(time.DateOnly), uid, s.clock.Now()))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("insert settlement transaction: %w", err)
+	}
+	return t, s.finishTransfer(ctx, tx, t)
+}
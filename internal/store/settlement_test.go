@@ -0,0 +1,34 @@
+package store
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusinessDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	for name, c := range map[string]struct {
+		at     time.Time
+		cutoff time.Duration
+		want   time.Time
+	}{
+		"midnight cutoff":       {time.Date(2024, 3, 5, 23, 59, 0, 0, time.UTC), 0, day(5)},
+		"at midnight":           {time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), 0, day(5)},
+		"before the cutoff":     {time.Date(2024, 3, 5, 16, 59, 0, 0, time.UTC), 17 * time.Hour, day(5)},
+		"at the cutoff":         {time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC), 17 * time.Hour, day(6)},
+		"past the cutoff":       {time.Date(2024, 3, 5, 23, 0, 0, 0, time.UTC), 17 * time.Hour, day(6)},
+		"in another time zone":  {time.Date(2024, 3, 5, 18, 0, 0, 0, time.FixedZone("EST", -5*3600)), 17 * time.Hour, day(6)},
+		"across the month turn": {time.Date(2024, 2, 29, 18, 0, 0, 0, time.UTC), 17 * time.Hour, day(1)},
+	} {
+		if got := BusinessDate(c.at, c.cutoff); !got.Equal(c.want) {
+			t.Fatalf("%s: expected %s, got %s", name, c.want.Format(time.DateOnly), got.Format(time.DateOnly))
+		}
+	}
+
+	if got, want := BusinessDayEnd(day(5), 17*time.Hour), time.Date(2024, 3, 5, 17, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Fatalf("expected day 5 to end at %s, got %s", want, got)
+	}
+	if got, want := BusinessDayEnd(day(5), 0), day(6); !got.Equal(want) {
+		t.Fatalf("expected day 5 to end at %s with a midnight cutoff, got %s", want, got)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecordSignature records the signature of an accepted request until
+// expiresAt and reports whether it was not recorded already, or only past
+// its expiry. It is shared by every instance using the database.
+func (s *Store) RecordSignature(ctx context.Context, sig string, expiresAt time.Time) (_ bool, err error) {
+	ctx, span := startSpan(ctx, "RecordSignature")
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `INSERT INTO request_signatures (signature, expires_at) VALUES ($1, $2)
+		ON CONFLICT (signature) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE request_signatures.expires_at < $3`, sig, expiresAt, s.clock.Now())
+	if err != nil {
+		return false, fmt.Errorf("record signature: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// PurgeSignatures deletes the signatures expired before before and returns
+// how many it deleted.
+func (s *Store) PurgeSignatures(ctx context.Context, before time.Time) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeSignatures")
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM request_signatures WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("purge signatures: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
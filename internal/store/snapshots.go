@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// snapshotDelay is how far in the past SnapshotBalances takes its snapshots.
+// A transfer settles at the start of its DB transaction but is only visible
+// once committed, so a snapshot as of now could miss transfers in flight.
+const snapshotDelay = time.Minute
+
+// snapshotSQL records the balance of every account that existed at $1,
+// replaying the transfers settled since backwards from the current balances;
+// $2 is the succeeded status. It returns the snapshot time and the
+// number of accounts recorded.
+const snapshotSQL = `
+WITH c AS (
+	SELECT $1::timestamptz AS taken_at
+), moved AS (
+	SELECT account_id, sum(delta) AS net FROM (
+		SELECT t.destination_account_id AS account_id, t.amount AS delta
+		FROM transactions t, c WHERE t.status = $2 AND t.settled_at > c.taken_at
+		UNION ALL
+		SELECT t.source_account_id, -t.amount
+		FROM transactions t, c WHERE t.status = $2 AND t.settled_at > c.taken_at
+	) d
+	GROUP BY account_id
+), ins AS (
+	INSERT INTO balance_snapshots (account_id, taken_at, balance)
+	SELECT a.account_id, c.taken_at, a.balance - COALESCE(m.net, 0)
+	FROM accounts a CROSS JOIN c
+	LEFT JOIN moved m ON m.account_id = a.account_id
+	WHERE a.created_at IS NULL OR a.created_at <= c.taken_at
+	ON CONFLICT DO NOTHING
+	RETURNING 1
+)
+SELECT (SELECT taken_at FROM c), (SELECT count(*) FROM ins)`
+
+// SnapshotRun describes the balance snapshot taken by SnapshotBalances.
+type SnapshotRun struct {
+	TakenAt  time.Time
+	Accounts int
+}
+
+// SnapshotBalances records the balance of every account as of a minute ago,
+// in a single statement so that all balances are consistent with each other.
+func (s *Store) SnapshotBalances(ctx context.Context) (_ SnapshotRun, err error) {
+	ctx, span := startSpan(ctx, "SnapshotBalances")
+	defer func() { endSpan(span, err) }()
+
+	var run SnapshotRun
+	if err := s.pool.QueryRow(ctx, snapshotSQL, s.clock.Now().Add(-snapshotDelay), StatusSucceeded).Scan(&run.TakenAt, &run.Accounts); err != nil {
+		return SnapshotRun{}, fmt.Errorf("snapshot balances: %w", err)
+	}
+	span.SetAttributes(attribute.Int("snapshot.accounts", run.Accounts))
+	return run, nil
+}
+
+// LastBalanceSnapshot returns when the latest balance snapshot was taken, or
+// the zero time if there is none.
+func (s *Store) LastBalanceSnapshot(ctx context.Context) (_ time.Time, err error) {
+	ctx, span := startSpan(ctx, "LastBalanceSnapshot")
+	defer func() { endSpan(span, err) }()
+
+	var at *time.Time
+	if err := s.pool.QueryRow(ctx, `SELECT max(taken_at) FROM balance_snapshots`).Scan(&at); err != nil {
+		return time.Time{}, fmt.Errorf("last balance snapshot: %w", err)
+	}
+	if at == nil {
+		return time.Time{}, nil
+	}
+	return *at, nil
+}
@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrInvalidSplit is returned by SplitTransfer for legs that share neither
+// their source nor their destination account
+var ErrInvalidSplit = errors.New("split legs must all have the same source or the same destination account")
+
+// CheckSplit returns ErrInvalidSplit unless items, the legs of a split
+// transfer, all have the same source account or all the same destination.
+func CheckSplit(items []TransferItem) error {
+	if len(items) == 0 {
+		return ErrInvalidSplit
+	}
+	sameSrc, sameDst := true, true
+	for _, it := range items[1:] {
+		sameSrc = sameSrc && it.SourceAccountID == items[0].SourceAccountID
+		sameDst = sameDst && it.DestinationAccountID == items[0].DestinationAccountID
+	}
+	if !sameSrc && !sameDst {
+		return ErrInvalidSplit
+	}
+	return nil
+}
+
+// groupArg returns the transfer_group argument of a transaction in group,
+// NULL if it is in none.
+func groupArg(group uuid.UUID) any {
+	if group == uuid.Nil {
+		return nil
+	}
+	return group
+}
+
+// SplitTransfer performs items, the legs of a split transfer in which one
+// source funds several destinations or several sources fund one destination,
+// as an atomic TransferBatch: all the accounts are locked up front in
+// ascending id order and the first failing leg rolls the split back, returned
+// as a *BatchItemError. The legs are recorded under a new transfer group id,
+// which is returned with their outcomes.
+func (s *Store) SplitTransfer(ctx context.Context, items []TransferItem) (_ uuid.UUID, _ []TransferResult, err error) {
+	ctx, span := startSpan(ctx, "SplitTransfer", attribute.Int("split.legs", len(items)))
+	defer func() { endSpan(span, err) }()
+
+	if err := CheckSplit(items); err != nil {
+		return uuid.Nil, nil, err
+	}
+	group, err := s.NewTransactionID()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	items = slices.Clone(items)
+	for i := range items {
+		items[i].GroupID = group
+	}
+	results, err := s.TransferBatch(ctx, items, true)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return group, results, nil
+}
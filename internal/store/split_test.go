@@ -0,0 +1,20 @@
+package store
+
+import "testing"
+
+func TestCheckSplit(t *testing.T) {
+	for name, c := range map[string]struct {
+		items []TransferItem
+		ok    bool
+	}{
+		"one source":      {[]TransferItem{{SourceAccountID: 1, DestinationAccountID: 2}, {SourceAccountID: 1, DestinationAccountID: 3}}, true},
+		"one destination": {[]TransferItem{{SourceAccountID: 2, DestinationAccountID: 1}, {SourceAccountID: 3, DestinationAccountID: 1}}, true},
+		"single leg":      {[]TransferItem{{SourceAccountID: 1, DestinationAccountID: 2}}, true},
+		"no legs":         {nil, false},
+		"unrelated legs":  {[]TransferItem{{SourceAccountID: 1, DestinationAccountID: 2}, {SourceAccountID: 3, DestinationAccountID: 4}}, false},
+	} {
+		if err := CheckSplit(c.items); (err == nil) != c.ok {
+			t.Fatalf("%s: unexpected result %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// AddAccountAlias registers alias as a name of the account, as in store.Store.
+// created is false if the account already had it.
+func (s *Store) AddAccountAlias(ctx context.Context, accountID int64, alias string) (store.AccountAlias, bool, error) {
+	a := store.AccountAlias{Alias: alias}
+	created := false
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		acc, err := getAccount(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		if !inScope(ctx, acc) {
+			return store.ErrAccountNotFound
+		}
+		var external bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM accounts WHERE external_id = ?1)`, alias).Scan(&external); err != nil {
+			return fmt.Errorf("check external ids: %w", err)
+		}
+		if external {
+			return store.ErrAliasTaken
+		}
+
+		var createdAt int64
+		err = tx.QueryRowContext(ctx, `SELECT account_id, created_at FROM account_aliases WHERE alias = ?1`, alias).Scan(&a.AccountID, &createdAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			a.AccountID, a.CreatedAt, created = accountID, s.clock.Now(), true
+			if _, err := tx.ExecContext(ctx, `INSERT INTO account_aliases (alias, account_id, created_at) VALUES (?1, ?2, ?3)`,
+				alias, accountID, a.CreatedAt.UnixNano()); err != nil {
+				return fmt.Errorf("add alias: %w", err)
+			}
+			return nil
+		case err != nil:
+			return fmt.Errorf("get alias: %w", err)
+		case a.AccountID != accountID:
+			return store.ErrAliasTaken
+		}
+		a.CreatedAt = time.Unix(0, createdAt)
+		return nil
+	})
+	if err != nil {
+		return store.AccountAlias{}, false, err
+	}
+	return a, created, nil
+}
+
+// RemoveAccountAlias unregisters alias from the account. It returns
+// store.ErrAliasNotFound unless the account, in ctx's scope, has the alias.
+func (s *Store) RemoveAccountAlias(ctx context.Context, accountID int64, alias string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM account_aliases WHERE alias = ?4 AND account_id = ?1
+		AND EXISTS (SELECT 1 FROM accounts WHERE account_id = ?1 AND `+accountScopeCond+`)`,
+		accountID, tenantArg(ctx), ownerArg(ctx), alias)
+	if err != nil {
+		return fmt.Errorf("remove alias: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("remove alias: %w", err)
+	} else if n == 0 {
+		return store.ErrAliasNotFound
+	}
+	return nil
+}
+
+// AccountAliases returns the aliases of the account in alphabetical order.
+func (s *Store) AccountAliases(ctx context.Context, accountID int64) ([]store.AccountAlias, error) {
+	if _, err := s.GetAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT alias, account_id, created_at FROM account_aliases
+		WHERE account_id = ?1 ORDER BY alias`, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("list aliases: %w", err)
+	}
+	defer rows.Close()
+	aliases := []store.AccountAlias{}
+	for rows.Next() {
+		var a store.AccountAlias
+		var createdAt int64
+		if err := rows.Scan(&a.Alias, &a.AccountID, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		a.CreatedAt = time.Unix(0, createdAt)
+		aliases = append(aliases, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list aliases: %w", err)
+	}
+	return aliases, nil
+}
+
+// AliasAccountIDs returns the numeric ids of the accounts with the given
+// aliases, leaving out unknown ones and those of other tenants than ctx's.
+func (s *Store) AliasAccountIDs(ctx context.Context, aliases []string) (map[string]int64, error) {
+	encoded, err := json.Marshal(aliases)
+	if err != nil {
+		return nil, fmt.Errorf("encode aliases: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT al.alias, al.account_id FROM account_aliases al
+		JOIN accounts a ON a.account_id = al.account_id
+		WHERE al.alias IN (SELECT value FROM json_each(?1)) AND (?2 IS NULL OR a.tenant_id = ?2)`, string(encoded), tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("resolve aliases: %w", err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64, len(aliases))
+	for rows.Next() {
+		var alias string
+		var id int64
+		if err := rows.Scan(&alias, &id); err != nil {
+			return nil, fmt.Errorf("scan alias: %w", err)
+		}
+		ids[alias] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("resolve aliases: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,247 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// holdColumns is the select list matching scanHold
+const holdColumns = `id, source_account_id, destination_account_id, amount, currency, status, COALESCE(transaction_id, 0), created_at, expires_at,
+	(SELECT public_id FROM transactions WHERE id = holds.transaction_id)`
+
+// scanHold scans a row selected with holdColumns.
+func scanHold(r row) (store.Hold, error) {
+	var h store.Hold
+	var created, expires int64
+	if err := r.Scan(&h.ID, &h.SourceAccountID, &h.DestinationAccountID, &h.Amount, &h.Currency, &h.Status, &h.TransactionID, &created, &expires, &h.TransactionUUID); err != nil {
+		return store.Hold{}, err
+	}
+	h.CreatedAt, h.ExpiresAt = time.Unix(0, created), time.Unix(0, expires)
+	return h, nil
+}
+
+// holdScopeCond restricts holds to those on a source account in the scope of
+// tenant ?2 and owner ?3, unless both are NULL.
+const holdScopeCond = `(?2 IS NULL AND ?3 IS NULL
+	OR EXISTS (SELECT 1 FROM accounts WHERE account_id = holds.source_account_id AND ` + accountScopeCond + `))`
+
+// setHeld adds delta to the amount held on the account.
+func setHeld(ctx context.Context, q querier, accountID int64, delta decimal.Decimal) error {
+	var held decimal.Decimal
+	if err := q.QueryRowContext(ctx, `SELECT held_balance FROM accounts WHERE account_id = ?1`, accountID).Scan(&held); err != nil {
+		return fmt.Errorf("get held balance: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `UPDATE accounts SET held_balance = ?1 WHERE account_id = ?2`, held.Add(delta).String(), accountID); err != nil {
+		return fmt.Errorf("update held balance: %w", err)
+	}
+	return nil
+}
+
+// CreateHold reserves amount on srcID for a later transfer to dstID. The hold
+// expires at expiresAt unless captured or released before. srcID and dstID
+// must be different accounts, and amount within the limits of srcID, like a
+// transfer.
+func (s *Store) CreateHold(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, expiresAt time.Time) (store.Hold, error) {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return store.Hold{}, fmt.Errorf("amount must be positive")
+	}
+	if srcID == dstID {
+		return store.Hold{}, store.ErrSameAccount
+	}
+
+	var h store.Hold
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		accs, err := getAccounts(ctx, tx, srcID, dstID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkTransfer(ctx, srcID, dstID, accs, amount, s.newLimitTracker(tx)); err != nil {
+			return err
+		}
+		src := accs[srcID]
+
+		if err := setHeld(ctx, tx, srcID, amount); err != nil {
+			return err
+		}
+		now := s.clock.Now()
+		res, err := tx.ExecContext(ctx, `INSERT INTO holds (source_account_id, destination_account_id, amount, currency, status, created_at, updated_at, expires_at)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?6, ?7)`,
+			srcID, dstID, amount.String(), src.Currency, store.HoldPending, now.UnixNano(), expiresAt.UnixNano())
+		if err != nil {
+			return fmt.Errorf("insert hold: %w", err)
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("insert hold: %w", err)
+		}
+		h = store.Hold{ID: id, SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Currency: src.Currency,
+			Status: store.HoldPending, CreatedAt: now, ExpiresAt: expiresAt}
+		return nil
+	})
+	if err != nil {
+		return store.Hold{}, err
+	}
+	return h, nil
+}
+
+// getHold fetches a hold by id if it is visible in ctx.
+func getHold(ctx context.Context, q querier, id int64) (store.Hold, error) {
+	h, err := scanHold(q.QueryRowContext(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = ?1 AND `+holdScopeCond, id, tenantArg(ctx), ownerArg(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Hold{}, store.ErrHoldNotFound
+	}
+	if err != nil {
+		return store.Hold{}, fmt.Errorf("get hold: %w", err)
+	}
+	return h, nil
+}
+
+// GetHold fetches a hold by id. Scoped to a tenant or owner, only holds on its accounts are found.
+func (s *Store) GetHold(ctx context.Context, id int64) (store.Hold, error) {
+	return getHold(ctx, s.db, id)
+}
+
+// getPendingHold fetches the hold and checks that it can still be captured or released.
+func getPendingHold(ctx context.Context, q querier, id int64) (store.Hold, error) {
+	h, err := getHold(ctx, q, id)
+	if err != nil {
+		return store.Hold{}, err
+	}
+	if h.Status != store.HoldPending {
+		return store.Hold{}, store.ErrHoldNotPending
+	}
+	return h, nil
+}
+
+// CaptureHold completes a pending hold by transferring its amount from the
+// source to the destination account. The limits of the source are checked
+// again, and the capture counts towards its daily limit like a transfer.
+func (s *Store) CaptureHold(ctx context.Context, id int64) (store.Hold, error) {
+	var h store.Hold
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		h, err = getPendingHold(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		// An expired hold no longer reserves funds, even before the expiry worker has released it
+		if !h.ExpiresAt.After(s.clock.Now()) {
+			return store.ErrHoldNotPending
+		}
+		accs, err := getAccounts(ctx, tx, h.SourceAccountID, h.DestinationAccountID)
+		if err != nil {
+			return err
+		}
+		src, srcOK := accs[h.SourceAccountID]
+		dst, dstOK := accs[h.DestinationAccountID]
+		if !srcOK || !dstOK {
+			return store.ErrAccountNotFound
+		}
+		if src.Status != store.AccountActive || dst.Status != store.AccountActive {
+			return store.ErrAccountInactive
+		}
+		if err := s.newLimitTracker(tx).check(ctx, src, h.Amount); err != nil {
+			return err
+		}
+
+		if err := setHeld(ctx, tx, src.ID, h.Amount.Neg()); err != nil {
+			return err
+		}
+		src.Balance = src.Balance.Sub(h.Amount)
+		dst.Balance = dst.Balance.Add(h.Amount)
+		if err := setBalance(ctx, tx, src); err != nil {
+			return err
+		}
+		if err := setBalance(ctx, tx, dst); err != nil {
+			return err
+		}
+		uid, err := s.NewTransactionID()
+		if err != nil {
+			return err
+		}
+		t := store.Transaction{SourceAccountID: src.ID, DestinationAccountID: dst.ID, Amount: h.Amount, Currency: h.Currency,
+			Status: store.StatusSucceeded, TransferDetails: store.TransferDetails{UUID: uid}}
+		if err := s.insertTransaction(ctx, tx, &t); err != nil {
+			return err
+		}
+		h.TransactionID, h.TransactionUUID, h.Status = t.ID, t.UUID, store.HoldCaptured
+		if _, err := tx.ExecContext(ctx, `UPDATE holds SET status = ?1, transaction_id = ?2, updated_at = ?3 WHERE id = ?4`,
+			h.Status, h.TransactionID, s.clock.Now().UnixNano(), id); err != nil {
+			return fmt.Errorf("update hold: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return store.Hold{}, err
+	}
+	return h, nil
+}
+
+// ReleaseHold cancels a pending hold and makes its amount available again.
+func (s *Store) ReleaseHold(ctx context.Context, id int64) (store.Hold, error) {
+	return s.endHold(ctx, id, store.HoldReleased)
+}
+
+// ExpireHolds releases pending holds whose expiry is at or before now and
+// returns how many were expired.
+func (s *Store) ExpireHolds(ctx context.Context, now time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM holds WHERE status = ?1 AND expires_at <= ?2 ORDER BY id`, store.HoldPending, now.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("list expired holds: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("list expired holds: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("list expired holds: %w", err)
+	}
+
+	expired := 0
+	for _, id := range ids {
+		if _, err := s.endHold(ctx, id, store.HoldExpired); err != nil {
+			// Captured or released in between
+			if errors.Is(err, store.ErrHoldNotPending) {
+				continue
+			}
+			return expired, err
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// endHold moves a pending hold to status without transferring funds.
+func (s *Store) endHold(ctx context.Context, id int64, status string) (store.Hold, error) {
+	var h store.Hold
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		h, err = getPendingHold(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if err := setHeld(ctx, tx, h.SourceAccountID, h.Amount.Neg()); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE holds SET status = ?1, updated_at = ?2 WHERE id = ?3`, status, s.clock.Now().UnixNano(), id); err != nil {
+			return fmt.Errorf("update hold: %w", err)
+		}
+		h.Status = status
+		return nil
+	})
+	if err != nil {
+		return store.Hold{}, err
+	}
+	return h, nil
+}
@@ -0,0 +1,102 @@
+package sqlite
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+	"github.com/you/internal-transfers/internal/store/storetest"
+)
+
+// Shape of the workloads of FuzzTransferInvariants
+const (
+	invariantAccounts  = 5
+	invariantTransfers = 200
+	invariantWorkers   = 8
+)
+
+// FuzzTransferInvariants runs random transfers from concurrent callers and
+// checks the balances against the ledger. Run with -fuzz to explore more
+// seeds than the corpus.
+func FuzzTransferInvariants(f *testing.F) {
+	for _, seed := range []int64{1, 2, 3, 42} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		s, err := Open(":memory:")
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer s.Close()
+		ctx := context.Background()
+
+		r := rand.New(rand.NewSource(seed))
+		initial := make(map[int64]decimal.Decimal, invariantAccounts)
+		for id := int64(1); id <= invariantAccounts; id++ {
+			initial[id] = decimal.New(r.Int63n(10000), -2)
+			if err := s.CreateAccount(ctx, id, initial[id], "USD", store.AccountMetadata{}); err != nil {
+				t.Fatalf("create account %d: %v", id, err)
+			}
+		}
+
+		transfers := storetest.RandomTransfers(r, invariantTransfers, invariantAccounts, 5000)
+		var ok atomic.Int64
+		var wg sync.WaitGroup
+		for w := 0; w < invariantWorkers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := w; i < len(transfers); i += invariantWorkers {
+					tr := transfers[i]
+					id, err := s.Transfer(ctx, tr.Src, tr.Dst, tr.Amount, store.TransferDetails{})
+					if err == nil && id != 0 {
+						ok.Add(1)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		final := make(map[int64]decimal.Decimal, invariantAccounts)
+		for id := range initial {
+			acc, err := s.GetAccount(ctx, id)
+			if err != nil {
+				t.Fatalf("get account %d: %v", id, err)
+			}
+			final[id] = acc.Balance
+		}
+		succeeded := succeededTransfers(t, s)
+		if int64(len(succeeded)) != ok.Load() {
+			t.Fatalf("%d transfers succeeded, but the ledger records %d", ok.Load(), len(succeeded))
+		}
+		storetest.CheckInvariants(t, initial, final, succeeded)
+	})
+}
+
+// succeededTransfers reads the succeeded transfers of the ledger.
+func succeededTransfers(t *testing.T, s *Store) []storetest.Transfer {
+	t.Helper()
+	rows, err := s.db.Query(`SELECT source_account_id, destination_account_id, amount FROM transactions WHERE status = ?1 AND kind = ?2`,
+		store.StatusSucceeded, store.KindTransfer)
+	if err != nil {
+		t.Fatalf("read ledger: %v", err)
+	}
+	defer rows.Close()
+	var transfers []storetest.Transfer
+	for rows.Next() {
+		var tr storetest.Transfer
+		if err := rows.Scan(&tr.Src, &tr.Dst, &tr.Amount); err != nil {
+			t.Fatalf("read ledger: %v", err)
+		}
+		transfers = append(transfers, tr)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("read ledger: %v", err)
+	}
+	return transfers
+}
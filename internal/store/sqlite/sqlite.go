@@ -0,0 +1,842 @@
+// Package sqlite implements the account and transfer operations of the store
+// on an SQLite database, for single-node edge deployments and for tests that
+// should not need a Postgres server. Features that rely on background workers
+// or Postgres-specific queries return store.ErrNotSupported.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// schema creates the tables on first use. Amounts are stored as decimal text
+// and compared in Go, since SQLite has no exact numeric type; times are Unix
+// nanoseconds.
+const schema = `
+CREATE TABLE IF NOT EXISTS accounts (
+	account_id           INTEGER PRIMARY KEY,
+	balance              TEXT NOT NULL,
+	initial_balance      TEXT NOT NULL,
+	held_balance         TEXT NOT NULL DEFAULT '0',
+	currency             TEXT NOT NULL,
+	status               TEXT NOT NULL DEFAULT 'active',
+	max_transfer_amount  TEXT,
+	daily_transfer_limit TEXT,
+	tenant_id            TEXT NOT NULL DEFAULT '',
+	owner_id             TEXT NOT NULL DEFAULT '',
+	external_id          TEXT UNIQUE,
+	parent_account_id    INTEGER REFERENCES accounts(account_id),
+	wallet               TEXT,
+	min_balance          TEXT,
+	display_name         TEXT NOT NULL DEFAULT '',
+	owner_ref            TEXT NOT NULL DEFAULT '',
+	tags                 TEXT NOT NULL DEFAULT '[]',
+	created_at           INTEGER NOT NULL,
+	UNIQUE (parent_account_id, wallet)
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+	id                     INTEGER PRIMARY KEY AUTOINCREMENT,
+	public_id              TEXT NOT NULL UNIQUE,
+	created_at             INTEGER NOT NULL,
+	source_account_id      INTEGER,
+	destination_account_id INTEGER,
+	amount                 TEXT NOT NULL,
+	currency               TEXT NOT NULL,
+	status                 TEXT NOT NULL,
+	error_message          TEXT,
+	reversal_of            INTEGER,
+	reference              TEXT NOT NULL DEFAULT '',
+	purpose_code           TEXT NOT NULL DEFAULT '',
+	kind                   TEXT NOT NULL DEFAULT 'transfer',
+	reason                 TEXT,
+	adjusted_by            TEXT,
+	initiated_by           TEXT NOT NULL DEFAULT '',
+	channel                TEXT NOT NULL DEFAULT '',
+	request_id             TEXT NOT NULL DEFAULT '',
+	transfer_group         TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_transactions_source ON transactions(source_account_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_destination ON transactions(destination_account_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions(reversal_of);
+
+CREATE TABLE IF NOT EXISTS holds (
+	id                     INTEGER PRIMARY KEY AUTOINCREMENT,
+	source_account_id      INTEGER NOT NULL,
+	destination_account_id INTEGER NOT NULL,
+	amount                 TEXT NOT NULL,
+	currency               TEXT NOT NULL,
+	status                 TEXT NOT NULL DEFAULT 'pending',
+	transaction_id         INTEGER,
+	created_at             INTEGER NOT NULL,
+	updated_at             INTEGER NOT NULL,
+	expires_at             INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_holds_pending ON holds(status, expires_at);
+
+CREATE TABLE IF NOT EXISTS account_aliases (
+	alias      TEXT PRIMARY KEY,
+	account_id INTEGER NOT NULL REFERENCES accounts(account_id),
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_account_aliases_account ON account_aliases(account_id);
+`
+
+// dsnParams are added to DSNs that do not set them: DB transactions take the
+// write lock as they begin, so that another process using the same file cannot
+// change balances a transfer has read, and wait for it rather than fail.
+var dsnParams = []string{"_txlock=immediate", "_busy_timeout=5000"}
+
+// Store is a store on an SQLite database.
+type Store struct {
+	db          *sql.DB
+	limits      atomic.Pointer[store.TransferLimits] // defaults for accounts without their own
+	crossTenant bool
+	sameAccount bool
+	ids         store.IDGenerator
+	clock       clock.Clock
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithDefaultLimits sets the limits of accounts that do not override them.
+func WithDefaultLimits(l store.TransferLimits) Option {
+	return func(s *Store) {
+		s.limits.Store(&l)
+	}
+}
+
+// DefaultLimits returns the limits of accounts that do not override them.
+func (s *Store) DefaultLimits() store.TransferLimits {
+	return *s.limits.Load()
+}
+
+// SetDefaultLimits replaces the limits of accounts that do not override them,
+// from the next transfer on.
+func (s *Store) SetDefaultLimits(l store.TransferLimits) {
+	s.limits.Store(&l)
+}
+
+// WithCrossTenantTransfers allows transfers between accounts of different
+// tenants. The source account must still be in the caller's tenant.
+func WithCrossTenantTransfers() Option {
+	return func(s *Store) {
+		s.crossTenant = true
+	}
+}
+
+// WithSameAccountTransfers records transfers from an account to itself as
+// succeeded transactions that leave its balance unchanged, rather than
+// rejecting them with store.ErrSameAccount. Holds are rejected either way.
+func WithSameAccountTransfers() Option {
+	return func(s *Store) {
+		s.sameAccount = true
+	}
+}
+
+// checkSameAccount returns store.ErrSameAccount for a transfer from srcID to
+// itself, unless the store allows it.
+func (s *Store) checkSameAccount(srcID, dstID int64) error {
+	if srcID == dstID && !s.sameAccount {
+		return store.ErrSameAccount
+	}
+	return nil
+}
+
+// WithIDGenerator replaces the UUIDv7 generator of transaction ids.
+func WithIDGenerator(g store.IDGenerator) Option {
+	return func(s *Store) {
+		s.ids = g
+	}
+}
+
+// WithClock replaces the system clock, which stamps the rows the store creates
+// and is the present of daily limits and hold expiry.
+func WithClock(c clock.Clock) Option {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// Open opens the database at dsn, a file name or "file:" URI, or ":memory:"
+// for a database that lives as long as the Store, and creates the schema if
+// it is missing.
+func Open(dsn string, opts ...Option) (*Store, error) {
+	db, err := sql.Open("sqlite3", withDefaultParams(dsn))
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+	// A single connection runs the DB transactions of the store one at a
+	// time, which makes transfers atomic and isolated without row locks, and
+	// keeps an in-memory database alive between calls
+	db.SetMaxOpenConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+
+	s := &Store{db: db, ids: store.UUIDv7{}, clock: clock.Real{}}
+	s.limits.Store(&store.TransferLimits{})
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// withDefaultParams adds the dsnParams that dsn does not set.
+func withDefaultParams(dsn string) string {
+	for _, p := range dsnParams {
+		name, _, _ := strings.Cut(p, "=")
+		if strings.Contains(dsn, name+"=") {
+			continue
+		}
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + p
+	}
+	return dsn
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks that the database can be used.
+func (s *Store) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// inTx runs fn in a DB transaction, which is committed if fn returns nil.
+func (s *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
+
+// tenantArg returns the tenant ctx is scoped to as a query argument, NULL when
+// unscoped, for conditions of the form (?2 IS NULL OR tenant_id = ?2).
+func tenantArg(ctx context.Context) any {
+	if t, ok := store.TenantFromContext(ctx); ok {
+		return t
+	}
+	return nil
+}
+
+// ownerArg is tenantArg for the owner ctx is scoped to.
+func ownerArg(ctx context.Context) any {
+	if o, ok := store.OwnerFromContext(ctx); ok {
+		return o
+	}
+	return nil
+}
+
+// accountScopeCond restricts accounts to the tenant in ?2 and the owner in ?3; NULL matches any.
+const accountScopeCond = `(?2 IS NULL OR tenant_id = ?2) AND (?3 IS NULL OR owner_id = ?3)`
+
+// inScope reports whether acc is visible in ctx.
+func inScope(ctx context.Context, acc store.Account) bool {
+	if t, ok := store.TenantFromContext(ctx); ok && t != acc.Tenant {
+		return false
+	}
+	if o, ok := store.OwnerFromContext(ctx); ok && o != acc.OwnerID {
+		return false
+	}
+	return true
+}
+
+// checkScope returns the error a transfer from src to dst fails with because
+// of their tenants or owners, as store.Store does.
+func (s *Store) checkScope(ctx context.Context, src, dst store.Account) error {
+	if !inScope(ctx, src) {
+		return &store.AccountNotFoundError{AccountID: src.ID, Party: store.PartySource}
+	}
+	if src.Tenant != dst.Tenant && !s.crossTenant {
+		return store.ErrCrossTenant
+	}
+	return nil
+}
+
+// accountTransitions lists, per target status, the statuses an account may
+// move from, as in store.Store.
+var accountTransitions = map[string][]string{
+	store.AccountActive: {store.AccountFrozen},
+	store.AccountFrozen: {store.AccountActive},
+	store.AccountClosed: {store.AccountActive, store.AccountFrozen},
+}
+
+// accountColumns is the select list matching scanAccount
+const accountColumns = `account_id, balance, held_balance, initial_balance, currency, status, max_transfer_amount, daily_transfer_limit,
+	tenant_id, owner_id, COALESCE(external_id, ''), COALESCE(parent_account_id, 0), COALESCE(wallet, ''), min_balance, display_name, owner_ref, tags, created_at`
+
+// row is implemented by *sql.Row and *sql.Rows
+type row interface {
+	Scan(dest ...any) error
+}
+
+// scanAccount scans a row selected with accountColumns, along with its creation time.
+func scanAccount(r row) (store.Account, time.Time, error) {
+	var acc store.Account
+	var held decimal.Decimal
+	var tags string
+	var created int64
+	if err := r.Scan(&acc.ID, &acc.Balance, &held, &acc.InitialBalance, &acc.Currency, &acc.Status, &acc.Limits.MaxAmount, &acc.Limits.Daily,
+		&acc.Tenant, &acc.OwnerID, &acc.ExternalID, &acc.ParentID, &acc.Wallet, &acc.MinBalance, &acc.DisplayName, &acc.OwnerRef, &tags, &created); err != nil {
+		return store.Account{}, time.Time{}, err
+	}
+	acc.AvailableBalance = acc.Balance.Sub(held)
+	if err := json.Unmarshal([]byte(tags), &acc.Tags); err != nil {
+		return store.Account{}, time.Time{}, fmt.Errorf("decode tags: %w", err)
+	}
+	return acc, time.Unix(0, created), nil
+}
+
+// getAccount fetches an account of any tenant.
+func getAccount(ctx context.Context, q querier, accountID int64) (store.Account, error) {
+	acc, _, err := scanAccount(q.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = ?1`, accountID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Account{}, store.ErrAccountNotFound
+	}
+	if err != nil {
+		return store.Account{}, fmt.Errorf("get account: %w", err)
+	}
+	return acc, nil
+}
+
+// getAccounts fetches the accounts with the given ids, of any tenant, keyed by
+// id. Unknown ids are missing from the result.
+func getAccounts(ctx context.Context, q querier, ids ...int64) (map[int64]store.Account, error) {
+	accs := make(map[int64]store.Account, len(ids))
+	for _, id := range ids {
+		if _, ok := accs[id]; ok {
+			continue
+		}
+		acc, err := getAccount(ctx, q, id)
+		if errors.Is(err, store.ErrAccountNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		accs[id] = acc
+	}
+	return accs, nil
+}
+
+// setBalance saves the balance of acc.
+func setBalance(ctx context.Context, q querier, acc store.Account) error {
+	if _, err := q.ExecContext(ctx, `UPDATE accounts SET balance = ?1 WHERE account_id = ?2`, acc.Balance.String(), acc.ID); err != nil {
+		return fmt.Errorf("update balance for account %d: %w", acc.ID, err)
+	}
+	return nil
+}
+
+// isConstraintViolation reports whether err is an SQLite unique or primary key violation.
+func isConstraintViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code == sqlite3.ErrConstraint
+}
+
+// CreateAccount inserts a new account with initial balance in currency and
+// metadata, in the tenant and owned by the owner ctx is scoped to.
+func (s *Store) CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error {
+	_, err := s.createAccount(ctx, accountID, "", initial, currency, meta)
+	return err
+}
+
+// CreateAccountWithExternalID creates an account addressable by externalID and
+// returns its numeric id. If accountID is 0 the next free one is assigned. It
+// returns store.ErrAccountExists if either id is taken.
+func (s *Store) CreateAccountWithExternalID(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error) {
+	return s.createAccount(ctx, accountID, externalID, initial, currency, meta)
+}
+
+func (s *Store) createAccount(ctx context.Context, accountID int64, externalID string, initial decimal.Decimal, currency string, meta store.AccountMetadata) (int64, error) {
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return 0, fmt.Errorf("encode tags: %w", err)
+	}
+	var ext any
+	if externalID != "" {
+		ext = externalID
+	}
+	tenant, _ := store.TenantFromContext(ctx)
+	owner, _ := store.OwnerFromContext(ctx)
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		if accountID == 0 {
+			if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(account_id), 0) + 1 FROM accounts`).Scan(&accountID); err != nil {
+				return fmt.Errorf("assign account id: %w", err)
+			}
+		}
+		_, err := tx.ExecContext(ctx, `INSERT INTO accounts (account_id, balance, initial_balance, currency, tenant_id, owner_id, external_id, display_name, owner_ref, tags, created_at)
+			VALUES (?1, ?2, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)`,
+			accountID, initial.String(), currency, tenant, owner, ext, meta.DisplayName, meta.OwnerRef, string(encoded), s.clock.Now().UnixNano())
+		if isConstraintViolation(err) {
+			return store.ErrAccountExists
+		}
+		if err != nil {
+			return fmt.Errorf("create account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return accountID, nil
+}
+
+// CreateWallet creates the wallet of parentID labelled wallet in the currency,
+// tenant and owner of the parent, as in store.Store, and returns it. If
+// accountID is 0 the next free one is assigned.
+func (s *Store) CreateWallet(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta store.AccountMetadata) (store.Account, error) {
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return store.Account{}, fmt.Errorf("encode tags: %w", err)
+	}
+
+	var acc store.Account
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		parent, err := getAccount(ctx, tx, parentID)
+		switch {
+		case err != nil:
+			return err
+		case !inScope(ctx, parent):
+			return store.ErrAccountNotFound
+		case parent.ParentID != 0:
+			return store.ErrNestedWallet
+		case parent.Status != store.AccountActive:
+			return store.ErrAccountInactive
+		}
+		if accountID == 0 {
+			if err := tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(account_id), 0) + 1 FROM accounts`).Scan(&accountID); err != nil {
+				return fmt.Errorf("assign account id: %w", err)
+			}
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO accounts (account_id, balance, initial_balance, currency, tenant_id, owner_id,
+				parent_account_id, wallet, display_name, owner_ref, tags, created_at)
+			VALUES (?1, ?2, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11)`,
+			accountID, initial.String(), parent.Currency, parent.Tenant, parent.OwnerID, parentID, wallet,
+			meta.DisplayName, meta.OwnerRef, string(encoded), s.clock.Now().UnixNano())
+		if isConstraintViolation(err) {
+			return store.ErrAccountExists
+		}
+		if err != nil {
+			return fmt.Errorf("create wallet: %w", err)
+		}
+		acc, err = getAccount(ctx, tx, accountID)
+		return err
+	})
+	if err != nil {
+		return store.Account{}, err
+	}
+	return acc, nil
+}
+
+// WalletIDs returns the numeric ids of the given wallets, leaving out unknown
+// ones and those of other tenants than ctx's.
+func (s *Store) WalletIDs(ctx context.Context, refs []store.WalletRef) (map[store.WalletRef]int64, error) {
+	ids := make(map[store.WalletRef]int64, len(refs))
+	for _, ref := range refs {
+		var id int64
+		err := s.db.QueryRowContext(ctx, `SELECT account_id FROM accounts
+			WHERE parent_account_id = ?1 AND wallet = ?2 AND (?3 IS NULL OR tenant_id = ?3)`, ref.ParentID, ref.Wallet, tenantArg(ctx)).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("resolve wallets: %w", err)
+		}
+		ids[ref] = id
+	}
+	return ids, nil
+}
+
+// Wallets returns the account with its wallets and their balances added up.
+func (s *Store) Wallets(ctx context.Context, accountID int64) (store.Wallets, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts
+		WHERE account_id = ?1 OR parent_account_id = ?1 ORDER BY account_id`, accountID)
+	if err != nil {
+		return store.Wallets{}, fmt.Errorf("list wallets: %w", err)
+	}
+	defer rows.Close()
+	var accs []store.Account
+	for rows.Next() {
+		acc, _, err := scanAccount(rows)
+		if err != nil {
+			return store.Wallets{}, fmt.Errorf("scan account: %w", err)
+		}
+		accs = append(accs, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return store.Wallets{}, fmt.Errorf("list wallets: %w", err)
+	}
+	w := store.SumWallets(accountID, accs)
+	if w.Account.ID == 0 || !inScope(ctx, w.Account) {
+		return store.Wallets{}, store.ErrAccountNotFound
+	}
+	return w, nil
+}
+
+// CreateAccounts creates accounts one by one, skipping the ones whose id or
+// external id is taken, and returns the outcome of each in order.
+func (s *Store) CreateAccounts(ctx context.Context, accounts []store.AccountImport) ([]store.AccountResult, error) {
+	results := make([]store.AccountResult, len(accounts))
+	for i, a := range accounts {
+		id, err := s.createAccount(store.WithOwner(ctx, a.OwnerID), a.AccountID, a.ExternalID, a.Initial, a.Currency, a.Metadata)
+		if err != nil && !errors.Is(err, store.ErrAccountExists) {
+			return nil, err
+		}
+		results[i] = store.AccountResult{AccountID: id, Err: err}
+	}
+	return results, nil
+}
+
+// AccountIDs returns the numeric ids of the accounts with the given external
+// ids, leaving out unknown ones and those of other tenants than ctx's.
+func (s *Store) AccountIDs(ctx context.Context, externalIDs []string) (map[string]int64, error) {
+	encoded, err := json.Marshal(externalIDs)
+	if err != nil {
+		return nil, fmt.Errorf("encode external ids: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT external_id, account_id FROM accounts
+		WHERE external_id IN (SELECT value FROM json_each(?1)) AND (?2 IS NULL OR tenant_id = ?2)`, string(encoded), tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("resolve external ids: %w", err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64, len(externalIDs))
+	for rows.Next() {
+		var ext string
+		var id int64
+		if err := rows.Scan(&ext, &id); err != nil {
+			return nil, fmt.Errorf("scan external id: %w", err)
+		}
+		ids[ext] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("resolve external ids: %w", err)
+	}
+	return ids, nil
+}
+
+// GetAccount fetches the account with its current balance.
+func (s *Store) GetAccount(ctx context.Context, accountID int64) (store.Account, error) {
+	acc, err := getAccount(ctx, s.db, accountID)
+	if err != nil {
+		return store.Account{}, err
+	}
+	if !inScope(ctx, acc) {
+		return store.Account{}, store.ErrAccountNotFound
+	}
+	return acc, nil
+}
+
+// ListAccounts returns up to limit accounts matching f in ascending id order,
+// starting after the account id in after (nil for the first page). next is
+// the cursor for the following page, or nil on the last page.
+func (s *Store) ListAccounts(ctx context.Context, f store.AccountFilter, after *int64, limit int) ([]store.Account, *int64, error) {
+	var afterID int64
+	if after != nil {
+		afterID = *after
+	}
+	// The balance bounds are checked in Go, balances being stored as text
+	rows, err := s.db.QueryContext(ctx, `SELECT `+accountColumns+` FROM accounts
+		WHERE account_id > ?1 AND `+accountScopeCond+` AND (?4 = '' OR status = ?4)
+			AND (?5 = '' OR EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?5))
+		ORDER BY account_id`, afterID, tenantArg(ctx), ownerArg(ctx), f.Status, f.Tag)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	// Collect one extra account to find out whether another page follows
+	accs := make([]store.Account, 0, limit)
+	for len(accs) <= limit && rows.Next() {
+		acc, _, err := scanAccount(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan account: %w", err)
+		}
+		if f.MinBalance != nil && acc.Balance.LessThan(*f.MinBalance) || f.MaxBalance != nil && acc.Balance.GreaterThan(*f.MaxBalance) {
+			continue
+		}
+		accs = append(accs, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	var next *int64
+	if len(accs) > limit {
+		accs = accs[:limit]
+		last := accs[limit-1].ID
+		next = &last
+	}
+	return accs, next, nil
+}
+
+// updateAccount applies fn to the account, if visible in ctx, and saves the
+// fields fn may change: status, limits, owner and metadata.
+func (s *Store) updateAccount(ctx context.Context, accountID int64, fn func(acc *store.Account) error) (store.Account, error) {
+	var acc store.Account
+	err := s.inTx(ctx, func(tx *sql.Tx) (err error) {
+		acc, err = getAccount(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		if !inScope(ctx, acc) {
+			return store.ErrAccountNotFound
+		}
+		if err := fn(&acc); err != nil {
+			return err
+		}
+		if acc.Tags == nil {
+			acc.Tags = []string{}
+		}
+		tags, err := json.Marshal(acc.Tags)
+		if err != nil {
+			return fmt.Errorf("encode tags: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE accounts SET status = ?2, max_transfer_amount = ?3, daily_transfer_limit = ?4,
+				owner_id = ?5, display_name = ?6, owner_ref = ?7, tags = ?8, min_balance = ?9
+			WHERE account_id = ?1`,
+			accountID, acc.Status, acc.Limits.MaxAmount, acc.Limits.Daily, acc.OwnerID, acc.DisplayName, acc.OwnerRef, string(tags), acc.MinBalance)
+		if err != nil {
+			return fmt.Errorf("update account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return store.Account{}, err
+	}
+	return acc, nil
+}
+
+// UpdateAccountMetadata applies upd to the account and returns the updated account.
+func (s *Store) UpdateAccountMetadata(ctx context.Context, accountID int64, upd store.MetadataUpdate) (store.Account, error) {
+	return s.updateAccount(ctx, accountID, func(acc *store.Account) error {
+		if upd.DisplayName != nil {
+			acc.DisplayName = *upd.DisplayName
+		}
+		if upd.OwnerRef != nil {
+			acc.OwnerRef = *upd.OwnerRef
+		}
+		if upd.Tags != nil {
+			acc.Tags = *upd.Tags
+		}
+		return nil
+	})
+}
+
+// UpdateAccountStatus moves the account to status if allowed from its current
+// status and returns the updated account.
+func (s *Store) UpdateAccountStatus(ctx context.Context, accountID int64, status string) (store.Account, error) {
+	from, ok := accountTransitions[status]
+	if !ok {
+		return store.Account{}, fmt.Errorf("unknown account status %q", status)
+	}
+	return s.updateAccount(ctx, accountID, func(acc *store.Account) error {
+		for _, st := range from {
+			if acc.Status == st {
+				acc.Status = status
+				return nil
+			}
+		}
+		return store.ErrInvalidTransition
+	})
+}
+
+// CloseAccount closes an account for good, first sweeping its balance to
+// sweepTo if given, as in store.Store.
+func (s *Store) CloseAccount(ctx context.Context, accountID, sweepTo int64, details store.TransferDetails) (store.Account, store.Transaction, error) {
+	if sweepTo == accountID {
+		return store.Account{}, store.Transaction{}, store.ErrSameAccount
+	}
+	var acc store.Account
+	var sweep store.Transaction
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		accs, err := getAccounts(ctx, tx, accountID, sweepTo)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if acc, ok = accs[accountID]; !ok || !inScope(ctx, acc) {
+			return store.ErrAccountNotFound
+		}
+		if !slices.Contains(accountTransitions[store.AccountClosed], acc.Status) {
+			return store.ErrInvalidTransition
+		}
+		if acc.AvailableBalance.LessThan(acc.Balance) || acc.Balance.IsNegative() {
+			return store.ErrAccountNotEmpty
+		}
+
+		if acc.Balance.IsPositive() {
+			if sweepTo == 0 {
+				return store.ErrAccountNotEmpty
+			}
+			dst, ok := accs[sweepTo]
+			switch {
+			case !ok:
+				return &store.AccountNotFoundError{AccountID: sweepTo, Party: store.PartyDestination}
+			case acc.Tenant != dst.Tenant && !s.crossTenant:
+				return store.ErrCrossTenant
+			case dst.Status != store.AccountActive:
+				return store.ErrAccountInactive
+			case dst.Currency != acc.Currency:
+				return store.ErrCurrencyMismatch
+			}
+			if details, err = s.withTransactionID(details); err != nil {
+				return err
+			}
+			dst.Balance = dst.Balance.Add(acc.Balance)
+			if err := setBalance(ctx, tx, dst); err != nil {
+				return err
+			}
+			sweep = store.Transaction{SourceAccountID: accountID, DestinationAccountID: sweepTo, Amount: acc.Balance, Currency: acc.Currency,
+				Status: store.StatusSucceeded, TransferDetails: details}
+			if err := s.insertTransaction(ctx, tx, &sweep); err != nil {
+				return err
+			}
+		}
+
+		acc.Balance, acc.AvailableBalance, acc.Status = decimal.Zero, decimal.Zero, store.AccountClosed
+		if err := setBalance(ctx, tx, acc); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE accounts SET status = ?1 WHERE account_id = ?2`, acc.Status, accountID); err != nil {
+			return fmt.Errorf("close account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return store.Account{}, store.Transaction{}, err
+	}
+	return acc, sweep, nil
+}
+
+// SetAccountLimits replaces the account's own limits and returns the updated account.
+func (s *Store) SetAccountLimits(ctx context.Context, accountID int64, l store.TransferLimits) (store.Account, error) {
+	return s.updateAccount(ctx, accountID, func(acc *store.Account) error {
+		acc.Limits = l
+		return nil
+	})
+}
+
+// SetMinBalance sets the minimum balance of the account, or removes it if min
+// is invalid (NULL).
+func (s *Store) SetMinBalance(ctx context.Context, accountID int64, min decimal.NullDecimal) (store.Account, error) {
+	return s.updateAccount(ctx, accountID, func(acc *store.Account) error {
+		acc.MinBalance = min
+		return nil
+	})
+}
+
+// SetAccountOwner hands the account over to owner; an empty owner leaves it to admins.
+func (s *Store) SetAccountOwner(ctx context.Context, accountID int64, owner string) (store.Account, error) {
+	return s.updateAccount(ctx, accountID, func(acc *store.Account) error {
+		acc.OwnerID = owner
+		return nil
+	})
+}
+
+// BalanceAt returns the balance accountID had at the given time, replayed
+// backwards from the current balance. It returns store.ErrAccountNotFound if
+// the account did not exist yet at that time.
+func (s *Store) BalanceAt(ctx context.Context, accountID int64, at time.Time) (store.Balance, error) {
+	var b store.Balance
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		acc, created, err := scanAccount(tx.QueryRowContext(ctx, `SELECT `+accountColumns+` FROM accounts
+			WHERE account_id = ?1 AND `+accountScopeCond, accountID, tenantArg(ctx), ownerArg(ctx)))
+		if errors.Is(err, sql.ErrNoRows) || err == nil && created.After(at) {
+			return store.ErrAccountNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("balance at: %w", err)
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT COALESCE(source_account_id, 0), amount FROM transactions
+			WHERE (source_account_id = ?1 OR destination_account_id = ?1) AND status = ?2 AND created_at > ?3`,
+			accountID, store.StatusSucceeded, at.UnixNano())
+		if err != nil {
+			return fmt.Errorf("balance at: %w", err)
+		}
+		defer rows.Close()
+		amount := acc.Balance
+		for rows.Next() {
+			var src int64
+			var amt decimal.Decimal
+			if err := rows.Scan(&src, &amt); err != nil {
+				return fmt.Errorf("scan transaction: %w", err)
+			}
+			if src == accountID {
+				amount = amount.Add(amt)
+			} else {
+				amount = amount.Sub(amt)
+			}
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("balance at: %w", err)
+		}
+		b = store.Balance{AccountID: accountID, Amount: amount, Currency: acc.Currency, At: at}
+		return nil
+	})
+	if err != nil {
+		return store.Balance{}, err
+	}
+	return b, nil
+}
+
+// NewTransactionID returns a new public transaction id.
+func (s *Store) NewTransactionID() (uuid.UUID, error) {
+	id, err := s.ids.NewID()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("generate transaction id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,645 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// openTestStore opens an in-memory store with accounts 1 (balance 100) and 2 (balance 0)
+func openTestStore(t *testing.T, opts ...Option) *Store {
+	t.Helper()
+	s, err := Open(":memory:", opts...)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	ctx := context.Background()
+	if err := s.CreateAccount(ctx, 1, decimal.NewFromInt(100), "USD", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account 1: %v", err)
+	}
+	if err := s.CreateAccount(ctx, 2, decimal.Zero, "USD", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account 2: %v", err)
+	}
+	return s
+}
+
+// assertBalance fails the test unless the account has the expected balance
+func assertBalance(t *testing.T, s *Store, accountID int64, want string) {
+	t.Helper()
+	acc, err := s.GetAccount(context.Background(), accountID)
+	if err != nil {
+		t.Fatalf("get account %d: %v", accountID, err)
+	}
+	if !acc.Balance.Equal(decimal.RequireFromString(want)) {
+		t.Fatalf("account %d: expected balance %s, got %s", accountID, want, acc.Balance)
+	}
+}
+
+// TestTransfer tests that a transfer moves funds and a rejected one is recorded as failed
+func TestTransfer(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	id, err := s.Transfer(ctx, 1, 2, decimal.RequireFromString("40.5"),
+		store.TransferDetails{Reference: "inv-1", InitiatedBy: "payments", Channel: store.ChannelHTTP, RequestID: "req-1"})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	assertBalance(t, s, 1, "59.5")
+	assertBalance(t, s, 2, "40.5")
+	if acc, err := s.GetAccount(ctx, 1); err != nil || !acc.InitialBalance.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected initial balance 100, got %+v, %v", acc, err)
+	}
+	tx, err := s.GetTransaction(ctx, id)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if tx.Status != store.StatusSucceeded || tx.Reference != "inv-1" || tx.Currency != "USD" ||
+		tx.InitiatedBy != "payments" || tx.Channel != store.ChannelHTTP || tx.RequestID != "req-1" {
+		t.Fatalf("unexpected transaction %+v", tx)
+	}
+	if got, err := s.TransactionID(ctx, tx.UUID); err != nil || got != id {
+		t.Fatalf("expected public id to resolve to %d, got %d, %v", id, got, err)
+	}
+
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(60), store.TransferDetails{}); !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %v", err)
+	}
+	var notFound *store.AccountNotFoundError
+	_, err = s.Transfer(ctx, 1, 3, decimal.NewFromInt(1), store.TransferDetails{})
+	if !errors.As(err, &notFound) || notFound.AccountID != 3 || notFound.Party != store.PartyDestination {
+		t.Fatalf("expected destination account 3 not to be found, got %v", err)
+	}
+	err = s.DryRunTransfer(ctx, 4, 1, decimal.NewFromInt(1), store.TransferDetails{})
+	if !errors.As(err, &notFound) || notFound.AccountID != 4 || notFound.Party != store.PartySource {
+		t.Fatalf("expected source account 4 not to be found, got %v", err)
+	}
+	assertBalance(t, s, 1, "59.5")
+
+	txs, _, err := s.ListTransactionsByAccount(ctx, 1, 0, 10)
+	if err != nil {
+		t.Fatalf("list transactions: %v", err)
+	}
+	if len(txs) != 3 || txs[0].Status != store.StatusFailed || txs[1].ErrorMessage != store.ErrInsufficientFunds.Error() {
+		t.Fatalf("expected the rejected transfers recorded as failed, got %+v", txs)
+	}
+}
+
+// TestTransfer_SameAccount tests that a transfer from an account to itself is
+// rejected, or recorded without moving funds when the store allows it
+func TestTransfer_SameAccount(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	if _, err := s.Transfer(ctx, 1, 1, decimal.NewFromInt(10), store.TransferDetails{}); !errors.Is(err, store.ErrSameAccount) {
+		t.Fatalf("expected ErrSameAccount, got %v", err)
+	}
+	results, err := s.TransferBatch(ctx, []store.TransferItem{{SourceAccountID: 1, DestinationAccountID: 1, Amount: decimal.NewFromInt(10)}}, false)
+	if err != nil || !errors.Is(results[0].Err, store.ErrSameAccount) {
+		t.Fatalf("expected the batch item rejected with ErrSameAccount, got %+v, %v", results, err)
+	}
+	if _, err := s.CreateHold(ctx, 1, 1, decimal.NewFromInt(10), time.Now().Add(time.Hour)); !errors.Is(err, store.ErrSameAccount) {
+		t.Fatalf("expected the hold rejected with ErrSameAccount, got %v", err)
+	}
+
+	s = openTestStore(t, WithSameAccountTransfers())
+	id, err := s.Transfer(ctx, 1, 1, decimal.NewFromInt(10), store.TransferDetails{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	if tx, err := s.GetTransaction(ctx, id); err != nil || tx.Status != store.StatusSucceeded {
+		t.Fatalf("expected a succeeded transaction, got %+v, %v", tx, err)
+	}
+	if _, err := s.Transfer(ctx, 1, 1, decimal.NewFromInt(101), store.TransferDetails{}); !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected the usual checks, got %v", err)
+	}
+	assertBalance(t, s, 1, "100")
+}
+
+// TestTransferLimits tests the per-transfer and daily limits
+func TestTransferLimits(t *testing.T) {
+	s := openTestStore(t, WithDefaultLimits(store.TransferLimits{Daily: decimal.NewNullDecimal(decimal.NewFromInt(50))}))
+	ctx := context.Background()
+
+	if _, err := s.SetAccountLimits(ctx, 1, store.TransferLimits{MaxAmount: decimal.NewNullDecimal(decimal.NewFromInt(30))}); err != nil {
+		t.Fatalf("set limits: %v", err)
+	}
+	var limitErr *store.LimitError
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(31), store.TransferDetails{}); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitPerTransfer {
+		t.Fatalf("expected the per-transfer limit exceeded, got %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(25), store.TransferDetails{}); err != nil {
+			t.Fatalf("transfer %d: %v", i, err)
+		}
+	}
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(1), store.TransferDetails{}); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitDaily {
+		t.Fatalf("expected the daily limit exceeded, got %v", err)
+	}
+
+	// Holds are subject to the same limits
+	expires := time.Now().Add(time.Hour)
+	if _, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(31), expires); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitPerTransfer {
+		t.Fatalf("expected a hold over the per-transfer limit to be refused, got %v", err)
+	}
+	if _, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(1), expires); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitDaily {
+		t.Fatalf("expected a hold over the daily limit to be refused, got %v", err)
+	}
+}
+
+// TestTransferLimits_DailyWindow tests that transfers stop counting towards
+// the daily limit 24h after they were made
+func TestTransferLimits_DailyWindow(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	s := openTestStore(t, WithClock(clk), WithDefaultLimits(store.TransferLimits{Daily: decimal.NewNullDecimal(decimal.NewFromInt(50))}))
+	ctx := context.Background()
+
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(50), store.TransferDetails{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	clk.Advance(23 * time.Hour)
+	var limitErr *store.LimitError
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(1), store.TransferDetails{}); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitDaily {
+		t.Fatalf("expected the daily limit exceeded, got %v", err)
+	}
+	clk.Advance(time.Hour)
+	id, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(1), store.TransferDetails{})
+	if err != nil {
+		t.Fatalf("transfer after 24h: %v", err)
+	}
+	tx, err := s.GetTransaction(ctx, id)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if !tx.CreatedAt.Equal(clk.Now()) {
+		t.Errorf("expected the transaction created at %v, got %v", clk.Now(), tx.CreatedAt)
+	}
+}
+
+// TestMinBalance tests that transfers and holds may not take an account below
+// its minimum balance, and that a failing transfer is recorded
+func TestMinBalance(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	acc, err := s.SetMinBalance(ctx, 1, decimal.NewNullDecimal(decimal.NewFromInt(40)))
+	if err != nil || !acc.MinBalance.Valid || !acc.MinBalance.Decimal.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("expected min balance 40, got %+v, %v", acc.MinBalance, err)
+	}
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(60), store.TransferDetails{}); err != nil {
+		t.Fatalf("transfer down to the min balance: %v", err)
+	}
+	var limitErr *store.LimitError
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(1), store.TransferDetails{}); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitMinBalance {
+		t.Fatalf("expected the min balance breached, got %v", err)
+	}
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(41), store.TransferDetails{}); !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected insufficient funds first, got %v", err)
+	}
+	if _, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(1), time.Now().Add(time.Hour)); !errors.As(err, &limitErr) || limitErr.Limit != store.LimitMinBalance {
+		t.Fatalf("expected the hold to breach the min balance, got %v", err)
+	}
+	txs, _, err := s.ListTransactionsByAccount(ctx, 1, 0, 10)
+	if err != nil || len(txs) != 3 || txs[1].Status != store.StatusFailed || txs[1].ErrorMessage != "minimum balance breached" {
+		t.Fatalf("expected the breach recorded as a failed transfer, got %+v, %v", txs, err)
+	}
+	assertBalance(t, s, 1, "40")
+
+	if _, err := s.SetMinBalance(ctx, 1, decimal.NullDecimal{}); err != nil {
+		t.Fatalf("remove min balance: %v", err)
+	}
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(40), store.TransferDetails{}); err != nil {
+		t.Fatalf("transfer without a min balance: %v", err)
+	}
+	if _, err := s.SetMinBalance(ctx, 99, decimal.NullDecimal{}); !errors.Is(err, store.ErrAccountNotFound) {
+		t.Fatalf("expected ErrAccountNotFound, got %v", err)
+	}
+}
+
+// TestSplitTransfer tests that the legs of a split share their group id and
+// fail together
+func TestSplitTransfer(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	if err := s.CreateAccount(ctx, 3, decimal.Zero, "USD", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account 3: %v", err)
+	}
+
+	legs := []store.TransferItem{
+		{SourceAccountID: 1, DestinationAccountID: 2, Amount: decimal.NewFromInt(30)},
+		{SourceAccountID: 1, DestinationAccountID: 3, Amount: decimal.NewFromInt(20)},
+	}
+	group, results, err := s.SplitTransfer(ctx, legs)
+	if err != nil {
+		t.Fatalf("split: %v", err)
+	}
+	for _, res := range results {
+		tx, err := s.GetTransaction(ctx, res.TransactionID)
+		if err != nil || tx.GroupID != group {
+			t.Fatalf("expected leg %d in group %s, got %+v, %v", res.TransactionID, group, tx, err)
+		}
+	}
+	assertBalance(t, s, 1, "50")
+	assertBalance(t, s, 3, "20")
+
+	legs[1].Amount = decimal.NewFromInt(100)
+	var itemErr *store.BatchItemError
+	if _, _, err := s.SplitTransfer(ctx, legs); !errors.As(err, &itemErr) || itemErr.Index != 1 {
+		t.Fatalf("expected the second leg to fail, got %v", err)
+	}
+	assertBalance(t, s, 1, "50")
+
+	legs[1] = store.TransferItem{SourceAccountID: 2, DestinationAccountID: 3, Amount: decimal.NewFromInt(1)}
+	if _, _, err := s.SplitTransfer(ctx, legs); !errors.Is(err, store.ErrInvalidSplit) {
+		t.Fatalf("expected ErrInvalidSplit, got %v", err)
+	}
+}
+
+// TestTransferBatch_Atomic tests that a failing item rolls back the whole batch
+func TestTransferBatch_Atomic(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	items := []store.TransferItem{
+		{SourceAccountID: 1, DestinationAccountID: 2, Amount: decimal.NewFromInt(70)},
+		{SourceAccountID: 1, DestinationAccountID: 2, Amount: decimal.NewFromInt(70)},
+	}
+	_, err := s.TransferBatch(ctx, items, true)
+	var itemErr *store.BatchItemError
+	if !errors.As(err, &itemErr) || itemErr.Index != 1 || !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected item 1 to fail with insufficient funds, got %v", err)
+	}
+	assertBalance(t, s, 1, "100")
+
+	results, err := s.TransferBatch(ctx, items, false)
+	if err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if results[0].Err != nil || results[0].TransactionID == 0 || !errors.Is(results[1].Err, store.ErrInsufficientFunds) {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	assertBalance(t, s, 1, "30")
+	assertBalance(t, s, 2, "70")
+}
+
+// TestHolds tests that a hold reserves funds until captured
+func TestHolds(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	h, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(80), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(30), store.TransferDetails{}); !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected held funds to be unavailable, got %v", err)
+	}
+	h, err = s.CaptureHold(ctx, h.ID)
+	if err != nil {
+		t.Fatalf("capture hold: %v", err)
+	}
+	if h.Status != store.HoldCaptured || h.TransactionID == 0 {
+		t.Fatalf("unexpected hold %+v", h)
+	}
+	if _, err := s.ReleaseHold(ctx, h.ID); !errors.Is(err, store.ErrHoldNotPending) {
+		t.Fatalf("expected ErrHoldNotPending, got %v", err)
+	}
+	acc, err := s.GetAccount(ctx, 1)
+	if err != nil {
+		t.Fatalf("get account: %v", err)
+	}
+	if !acc.Balance.Equal(decimal.NewFromInt(20)) || !acc.AvailableBalance.Equal(decimal.NewFromInt(20)) {
+		t.Fatalf("expected balance and available balance 20, got %s and %s", acc.Balance, acc.AvailableBalance)
+	}
+
+	h, err = s.CreateHold(ctx, 1, 2, decimal.NewFromInt(20), time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+	if n, err := s.ExpireHolds(ctx, time.Now()); err != nil || n != 1 {
+		t.Fatalf("expected 1 hold expired, got %d, %v", n, err)
+	}
+	if h, err = s.GetHold(ctx, h.ID); err != nil || h.Status != store.HoldExpired {
+		t.Fatalf("expected an expired hold, got %+v, %v", h, err)
+	}
+}
+
+// TestHolds_Expiry tests that a hold can no longer be captured once expired,
+// even before ExpireHolds releases it
+func TestHolds_Expiry(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	s := openTestStore(t, WithClock(clk))
+	ctx := context.Background()
+
+	h, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(60), clk.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+	if !h.CreatedAt.Equal(clk.Now()) {
+		t.Errorf("expected the hold created at %v, got %v", clk.Now(), h.CreatedAt)
+	}
+	clk.Advance(time.Hour - time.Second)
+	if n, err := s.ExpireHolds(ctx, clk.Now()); err != nil || n != 0 {
+		t.Fatalf("expected no hold expired, got %d, %v", n, err)
+	}
+
+	clk.Advance(time.Second)
+	if _, err := s.CaptureHold(ctx, h.ID); !errors.Is(err, store.ErrHoldNotPending) {
+		t.Fatalf("expected ErrHoldNotPending capturing an expired hold, got %v", err)
+	}
+	if n, err := s.ExpireHolds(ctx, clk.Now()); err != nil || n != 1 {
+		t.Fatalf("expected 1 hold expired, got %d, %v", n, err)
+	}
+	assertBalance(t, s, 1, "100")
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(100), store.TransferDetails{}); err != nil {
+		t.Fatalf("expected the released amount available, got %v", err)
+	}
+}
+
+// TestReverseAndAdjust tests reversals and balance adjustments
+func TestReverseAndAdjust(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	id, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(10), store.TransferDetails{})
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	rev, err := s.ReverseTransaction(ctx, id)
+	if err != nil {
+		t.Fatalf("reverse: %v", err)
+	}
+	if rev.ReversalOf != id || rev.SourceAccountID != 2 {
+		t.Fatalf("unexpected reversal %+v", rev)
+	}
+	orig, err := s.GetTransaction(ctx, id)
+	if err != nil {
+		t.Fatalf("get transaction: %v", err)
+	}
+	if got, err := s.GetTransaction(ctx, rev.ID); err != nil || got.ReversalOfUUID != orig.UUID || rev.ReversalOfUUID != orig.UUID {
+		t.Fatalf("expected the reversal to reference %s, got %+v, %v", orig.UUID, got, err)
+	}
+	if _, err := s.ReverseTransaction(ctx, id); !errors.Is(err, store.ErrAlreadyReversed) {
+		t.Fatalf("expected ErrAlreadyReversed, got %v", err)
+	}
+
+	adj, err := s.AdjustBalance(ctx, 2, decimal.NewFromInt(-1), "fee", "ops")
+	if !errors.Is(err, store.ErrInsufficientFunds) {
+		t.Fatalf("expected ErrInsufficientFunds, got %+v, %v", adj, err)
+	}
+	if adj, err = s.AdjustBalance(ctx, 1, decimal.NewFromInt(-5), "fee", "ops"); err != nil {
+		t.Fatalf("adjust: %v", err)
+	}
+	if adj.Kind != store.KindAdjustment || adj.SourceAccountID != 1 || adj.DestinationAccountID != 0 {
+		t.Fatalf("unexpected adjustment %+v", adj)
+	}
+	assertBalance(t, s, 1, "95")
+	assertBalance(t, s, 2, "0")
+}
+
+// TestCloseAccount tests that only empty accounts are closed, unless their
+// balance is swept to another account first
+func TestCloseAccount(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	if _, _, err := s.CloseAccount(ctx, 1, 0, store.TransferDetails{}); !errors.Is(err, store.ErrAccountNotEmpty) {
+		t.Fatalf("expected ErrAccountNotEmpty without sweep_to, got %v", err)
+	}
+	h, err := s.CreateHold(ctx, 1, 2, decimal.NewFromInt(10), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("create hold: %v", err)
+	}
+	if _, _, err := s.CloseAccount(ctx, 1, 2, store.TransferDetails{}); !errors.Is(err, store.ErrAccountNotEmpty) {
+		t.Fatalf("expected pending holds to block closing, got %v", err)
+	}
+	if _, err := s.ReleaseHold(ctx, h.ID); err != nil {
+		t.Fatalf("release hold: %v", err)
+	}
+
+	acc, sweep, err := s.CloseAccount(ctx, 1, 2, store.TransferDetails{Channel: store.ChannelHTTP})
+	if err != nil {
+		t.Fatalf("close with sweep: %v", err)
+	}
+	if acc.Status != store.AccountClosed || !acc.Balance.IsZero() {
+		t.Fatalf("unexpected closed account %+v", acc)
+	}
+	if sweep.ID == 0 || sweep.DestinationAccountID != 2 || !sweep.Amount.Equal(decimal.NewFromInt(100)) || sweep.Channel != store.ChannelHTTP {
+		t.Fatalf("unexpected sweep %+v", sweep)
+	}
+	assertBalance(t, s, 1, "0")
+	assertBalance(t, s, 2, "100")
+	if _, _, err := s.CloseAccount(ctx, 1, 0, store.TransferDetails{}); !errors.Is(err, store.ErrInvalidTransition) {
+		t.Fatalf("expected ErrInvalidTransition closing twice, got %v", err)
+	}
+	if _, _, err := s.CloseAccount(ctx, 2, 1, store.TransferDetails{}); !errors.Is(err, store.ErrAccountInactive) {
+		t.Fatalf("expected ErrAccountInactive sweeping to a closed account, got %v", err)
+	}
+
+	if err := s.CreateAccount(ctx, 3, decimal.Zero, "USD", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account 3: %v", err)
+	}
+	if _, sweep, err := s.CloseAccount(ctx, 3, 0, store.TransferDetails{}); err != nil || sweep.ID != 0 {
+		t.Fatalf("expected an empty account closed without a sweep, got %+v, %v", sweep, err)
+	}
+}
+
+// TestWallets tests that wallets are accounts of their own, resolved by their
+// parent and label, whose balances add up to their parent's aggregate balance
+func TestWallets(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	savings, err := s.CreateWallet(ctx, 1, "savings", 0, decimal.NewFromInt(5), store.AccountMetadata{})
+	if err != nil {
+		t.Fatalf("create wallet: %v", err)
+	}
+	if savings.ID == 0 || savings.ParentID != 1 || savings.Wallet != "savings" || savings.Currency != "USD" {
+		t.Fatalf("unexpected wallet %+v", savings)
+	}
+	if _, err := s.CreateWallet(ctx, 1, "savings", 0, decimal.Zero, store.AccountMetadata{}); !errors.Is(err, store.ErrAccountExists) {
+		t.Fatalf("expected ErrAccountExists, got %v", err)
+	}
+	if _, err := s.CreateWallet(ctx, savings.ID, "nested", 0, decimal.Zero, store.AccountMetadata{}); !errors.Is(err, store.ErrNestedWallet) {
+		t.Fatalf("expected ErrNestedWallet, got %v", err)
+	}
+	if _, err := s.CreateWallet(ctx, 99, "savings", 0, decimal.Zero, store.AccountMetadata{}); !errors.Is(err, store.ErrAccountNotFound) {
+		t.Fatalf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	ids, err := s.WalletIDs(ctx, []store.WalletRef{{ParentID: 1, Wallet: "savings"}, {ParentID: 2, Wallet: "savings"}})
+	if err != nil || len(ids) != 1 || ids[store.WalletRef{ParentID: 1, Wallet: "savings"}] != savings.ID {
+		t.Fatalf("expected only the savings wallet of account 1 resolved, got %v, %v", ids, err)
+	}
+	if _, err := s.Transfer(ctx, 1, savings.ID, decimal.NewFromInt(30), store.TransferDetails{}); err != nil {
+		t.Fatalf("transfer to wallet: %v", err)
+	}
+
+	w, err := s.Wallets(ctx, 1)
+	if err != nil {
+		t.Fatalf("wallets: %v", err)
+	}
+	if w.Account.ID != 1 || len(w.Wallets) != 1 || w.Wallets[0].ID != savings.ID {
+		t.Fatalf("unexpected wallets %+v", w)
+	}
+	if !w.Balance.Equal(decimal.NewFromInt(105)) || !w.Wallets[0].Balance.Equal(decimal.NewFromInt(35)) {
+		t.Fatalf("expected an aggregate balance of 105 with 35 in the wallet, got %s and %s", w.Balance, w.Wallets[0].Balance)
+	}
+}
+
+// TestAccountAliases tests that aliases are unique and resolve to their account
+func TestAccountAliases(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	al, created, err := s.AddAccountAlias(ctx, 1, "ops-float-usd")
+	if err != nil || !created || al.AccountID != 1 {
+		t.Fatalf("expected the alias added, got %+v, %v, %v", al, created, err)
+	}
+	if _, created, err := s.AddAccountAlias(ctx, 1, "ops-float-usd"); err != nil || created {
+		t.Fatalf("expected re-adding the alias to be a no-op, got %v, %v", created, err)
+	}
+	if _, _, err := s.AddAccountAlias(ctx, 2, "ops-float-usd"); !errors.Is(err, store.ErrAliasTaken) {
+		t.Fatalf("expected ErrAliasTaken, got %v", err)
+	}
+	if _, err := s.CreateAccountWithExternalID(ctx, "acme", 3, decimal.Zero, "USD", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account 3: %v", err)
+	}
+	if _, _, err := s.AddAccountAlias(ctx, 2, "acme"); !errors.Is(err, store.ErrAliasTaken) {
+		t.Fatalf("expected an external id to be refused as alias, got %v", err)
+	}
+	if _, _, err := s.AddAccountAlias(ctx, 99, "other"); !errors.Is(err, store.ErrAccountNotFound) {
+		t.Fatalf("expected ErrAccountNotFound, got %v", err)
+	}
+
+	ids, err := s.AliasAccountIDs(ctx, []string{"ops-float-usd", "unknown"})
+	if err != nil || len(ids) != 1 || ids["ops-float-usd"] != 1 {
+		t.Fatalf("expected only ops-float-usd resolved, got %v, %v", ids, err)
+	}
+	if aliases, err := s.AccountAliases(ctx, 1); err != nil || len(aliases) != 1 || aliases[0].Alias != "ops-float-usd" {
+		t.Fatalf("unexpected aliases %+v, %v", aliases, err)
+	}
+
+	if err := s.RemoveAccountAlias(ctx, 2, "ops-float-usd"); !errors.Is(err, store.ErrAliasNotFound) {
+		t.Fatalf("expected ErrAliasNotFound removing another account's alias, got %v", err)
+	}
+	if err := s.RemoveAccountAlias(ctx, 1, "ops-float-usd"); err != nil {
+		t.Fatalf("remove alias: %v", err)
+	}
+	if _, created, err := s.AddAccountAlias(ctx, 2, "ops-float-usd"); err != nil || !created {
+		t.Fatalf("expected the freed alias added to account 2, got %v, %v", created, err)
+	}
+}
+
+// TestBalanceAt tests that past balances are replayed from the ledger
+func TestBalanceAt(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	before := time.Now()
+	if _, err := s.Transfer(ctx, 1, 2, decimal.NewFromInt(25), store.TransferDetails{}); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	b, err := s.BalanceAt(ctx, 1, before)
+	if err != nil {
+		t.Fatalf("balance at: %v", err)
+	}
+	if !b.Amount.Equal(decimal.NewFromInt(100)) {
+		t.Fatalf("expected a balance of 100 before the transfer, got %s", b.Amount)
+	}
+	if _, err := s.BalanceAt(ctx, 1, before.Add(-time.Hour)); !errors.Is(err, store.ErrAccountNotFound) {
+		t.Fatalf("expected ErrAccountNotFound before the account existed, got %v", err)
+	}
+}
+
+// TestScopes tests that tenant-scoped callers only see their accounts
+func TestScopes(t *testing.T) {
+	s := openTestStore(t)
+	ctx := store.WithTenant(context.Background(), "acme")
+
+	id, err := s.CreateAccountWithExternalID(ctx, "acme-1", 0, decimal.NewFromInt(5), "USD", store.AccountMetadata{Tags: []string{"vip"}})
+	if err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	if id != 3 {
+		t.Fatalf("expected the next free id 3, got %d", id)
+	}
+	if _, err := s.CreateAccountWithExternalID(ctx, "acme-1", 0, decimal.Zero, "USD", store.AccountMetadata{}); !errors.Is(err, store.ErrAccountExists) {
+		t.Fatalf("expected ErrAccountExists, got %v", err)
+	}
+	if _, err := s.GetAccount(ctx, 1); !errors.Is(err, store.ErrAccountNotFound) {
+		t.Fatalf("expected another tenant's account not to be found, got %v", err)
+	}
+	if _, err := s.Transfer(ctx, 3, 1, decimal.NewFromInt(1), store.TransferDetails{}); !errors.Is(err, store.ErrCrossTenant) {
+		t.Fatalf("expected ErrCrossTenant, got %v", err)
+	}
+	accs, _, err := s.ListAccounts(ctx, store.AccountFilter{Tag: "vip"}, nil, 10)
+	if err != nil || len(accs) != 1 || accs[0].ExternalID != "acme-1" {
+		t.Fatalf("expected only acme-1, got %+v, %v", accs, err)
+	}
+	ids, err := s.AccountIDs(context.Background(), []string{"acme-1", "unknown"})
+	if err != nil || len(ids) != 1 || ids["acme-1"] != 3 {
+		t.Fatalf("expected acme-1 to resolve to 3, got %v, %v", ids, err)
+	}
+}
+
+// TestCreateAccounts tests that the accounts of a batch are created but for
+// the ones whose ids are taken
+func TestCreateAccounts(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	results, err := s.CreateAccounts(ctx, []store.AccountImport{
+		{AccountID: 5, Initial: decimal.NewFromInt(5), Currency: "USD"},
+		{AccountID: 1, Initial: decimal.Zero, Currency: "USD"},
+		{ExternalID: "acme-1", Initial: decimal.Zero, Currency: "EUR", OwnerID: "alice"},
+		{ExternalID: "acme-1", Initial: decimal.Zero, Currency: "EUR"},
+	})
+	if err != nil {
+		t.Fatalf("create accounts: %v", err)
+	}
+	if results[0].AccountID != 5 || results[0].Err != nil || results[2].AccountID != 6 || results[2].Err != nil {
+		t.Fatalf("expected accounts 5 and 6 created, got %+v", results)
+	}
+	for _, i := range []int{1, 3} {
+		if !errors.Is(results[i].Err, store.ErrAccountExists) {
+			t.Fatalf("expected account %d to exist, got %+v", i, results[i])
+		}
+	}
+	acc, err := s.GetAccount(ctx, 6)
+	if err != nil || acc.OwnerID != "alice" || acc.Currency != "EUR" {
+		t.Fatalf("unexpected account 6: %+v, %v", acc, err)
+	}
+	assertBalance(t, s, 5, "5")
+}
+
+// TestOpen_File tests that a file database keeps its data across Open calls
+func TestOpen_File(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "transfers.db")
+	ctx := context.Background()
+
+	s, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if err := s.CreateAccount(ctx, 1, decimal.NewFromInt(7), "EUR", store.AccountMetadata{}); err != nil {
+		t.Fatalf("create account: %v", err)
+	}
+	s.Close()
+
+	s, err = Open(dsn)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	assertBalance(t, s, 1, "7")
+}
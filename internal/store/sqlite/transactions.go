@@ -0,0 +1,651 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// transactionColumns is the select list matching scanTransaction, over an
+// unaliased transactions table. The public id of a reversed transaction is
+// looked up rather than stored.
+const transactionColumns = `id, created_at, COALESCE(source_account_id, 0), COALESCE(destination_account_id, 0), amount, currency, status,
+	COALESCE(error_message, ''), COALESCE(reversal_of, 0), reference, purpose_code, kind, COALESCE(reason, ''), COALESCE(adjusted_by, ''), public_id,
+	initiated_by, channel, request_id, transfer_group, (SELECT r.public_id FROM transactions r WHERE r.id = transactions.reversal_of)`
+
+// scanTransaction scans a row selected with transactionColumns.
+func scanTransaction(r row) (store.Transaction, error) {
+	var t store.Transaction
+	var created int64
+	if err := r.Scan(&t.ID, &created, &t.SourceAccountID, &t.DestinationAccountID, &t.Amount, &t.Currency, &t.Status, &t.ErrorMessage, &t.ReversalOf,
+		&t.Reference, &t.PurposeCode, &t.Kind, &t.Reason, &t.AdjustedBy, &t.UUID,
+		&t.InitiatedBy, &t.Channel, &t.RequestID, &t.GroupID, &t.ReversalOfUUID); err != nil {
+		return store.Transaction{}, err
+	}
+	t.CreatedAt = time.Unix(0, created)
+	return t, nil
+}
+
+// transactionScopeCond restricts transactions to those touching an account in
+// the scope of tenant ?2 and owner ?3, unless both are NULL.
+const transactionScopeCond = `(?2 IS NULL AND ?3 IS NULL OR EXISTS (SELECT 1 FROM accounts
+	WHERE account_id IN (transactions.source_account_id, transactions.destination_account_id) AND ` + accountScopeCond + `))`
+
+// nullID is id as a query argument, NULL if zero.
+func nullID(id int64) any {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// nullString is s as a query argument, NULL if empty.
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullGroup is the transfer group id as a query argument, NULL if there is none.
+func nullGroup(group uuid.UUID) any {
+	if group == uuid.Nil {
+		return nil
+	}
+	return group.String()
+}
+
+// insertTransaction records t, filling in its id, creation time and kind.
+func (s *Store) insertTransaction(ctx context.Context, q querier, t *store.Transaction) error {
+	t.CreatedAt = s.clock.Now()
+	if t.Kind == "" {
+		t.Kind = store.KindTransfer
+	}
+	res, err := q.ExecContext(ctx, `INSERT INTO transactions (public_id, created_at, source_account_id, destination_account_id, amount, currency,
+			status, error_message, reversal_of, reference, purpose_code, kind, reason, adjusted_by, initiated_by, channel, request_id, transfer_group)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16, ?17, ?18)`,
+		t.UUID.String(), t.CreatedAt.UnixNano(), nullID(t.SourceAccountID), nullID(t.DestinationAccountID), t.Amount.String(), t.Currency,
+		t.Status, nullString(t.ErrorMessage), nullID(t.ReversalOf), t.Reference, t.PurposeCode, t.Kind, nullString(t.Reason), nullString(t.AdjustedBy),
+		t.InitiatedBy, t.Channel, t.RequestID, nullGroup(t.GroupID))
+	if err != nil {
+		return fmt.Errorf("insert transaction log: %w", err)
+	}
+	if t.ID, err = res.LastInsertId(); err != nil {
+		return fmt.Errorf("insert transaction log: %w", err)
+	}
+	return nil
+}
+
+// withTransactionID fills in the public id of details if the caller left it empty.
+func (s *Store) withTransactionID(details store.TransferDetails) (store.TransferDetails, error) {
+	if details.UUID != uuid.Nil {
+		return details, nil
+	}
+	id, err := s.NewTransactionID()
+	if err != nil {
+		return store.TransferDetails{}, err
+	}
+	details.UUID = id
+	return details, nil
+}
+
+// effectiveLimits returns the limits of acc, falling back to the store defaults.
+func (s *Store) effectiveLimits(acc store.Account) store.TransferLimits {
+	l, defaults := acc.Limits, s.DefaultLimits()
+	if !l.MaxAmount.Valid {
+		l.MaxAmount = defaults.MaxAmount
+	}
+	if !l.Daily.Valid {
+		l.Daily = defaults.Daily
+	}
+	return l
+}
+
+// limitTracker checks the transfers made within one DB transaction against
+// the limits of their source accounts, counting the transfers before them.
+type limitTracker struct {
+	s    *Store
+	q    querier
+	sent map[int64]decimal.Decimal // by source account, over the last 24h
+}
+
+func (s *Store) newLimitTracker(q querier) *limitTracker {
+	return &limitTracker{s: s, q: q, sent: make(map[int64]decimal.Decimal)}
+}
+
+// check returns a *store.LimitError if transferring amount out of src exceeds its limits.
+func (lt *limitTracker) check(ctx context.Context, src store.Account, amount decimal.Decimal) error {
+	l := lt.s.effectiveLimits(src)
+	if l.MaxAmount.Valid && amount.GreaterThan(l.MaxAmount.Decimal) {
+		return &store.LimitError{Limit: store.LimitPerTransfer}
+	}
+	if !l.Daily.Valid {
+		return nil
+	}
+	sent, ok := lt.sent[src.ID]
+	if !ok {
+		var err error
+		if sent, err = lt.s.dailyOutgoing(ctx, lt.q, src.ID); err != nil {
+			return err
+		}
+		lt.sent[src.ID] = sent
+	}
+	if sent.Add(amount).GreaterThan(l.Daily.Decimal) {
+		return &store.LimitError{Limit: store.LimitDaily}
+	}
+	return nil
+}
+
+// add counts a transfer that passed check.
+func (lt *limitTracker) add(srcID int64, amount decimal.Decimal) {
+	if sent, ok := lt.sent[srcID]; ok {
+		lt.sent[srcID] = sent.Add(amount)
+	}
+}
+
+// dailyOutgoing sums the succeeded transfers out of accountID over the last
+// 24h. Debit adjustments do not count.
+func (s *Store) dailyOutgoing(ctx context.Context, q querier, accountID int64) (decimal.Decimal, error) {
+	rows, err := q.QueryContext(ctx, `SELECT amount FROM transactions
+		WHERE source_account_id = ?1 AND status = ?2 AND kind = ?3 AND created_at > ?4`,
+		accountID, store.StatusSucceeded, store.KindTransfer, s.clock.Now().Add(-24*time.Hour).UnixNano())
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("sum daily transfers: %w", err)
+	}
+	defer rows.Close()
+	sum := decimal.Zero
+	for rows.Next() {
+		var amt decimal.Decimal
+		if err := rows.Scan(&amt); err != nil {
+			return decimal.Zero, fmt.Errorf("sum daily transfers: %w", err)
+		}
+		sum = sum.Add(amt)
+	}
+	if err := rows.Err(); err != nil {
+		return decimal.Zero, fmt.Errorf("sum daily transfers: %w", err)
+	}
+	return sum, nil
+}
+
+// checkTransfer returns the error a transfer of amount from srcID to dstID
+// fails with, if any, in the order store.Store checks them. accs holds the
+// accounts that exist.
+func (s *Store) checkTransfer(ctx context.Context, srcID, dstID int64, accs map[int64]store.Account, amount decimal.Decimal, limits *limitTracker) error {
+	src, srcOK := accs[srcID]
+	dst, dstOK := accs[dstID]
+	if err := store.CheckTransferAccounts(srcID, dstID, srcOK, dstOK); err != nil {
+		return err
+	}
+	if err := s.checkScope(ctx, src, dst); err != nil {
+		return err
+	}
+	switch {
+	case src.Status != store.AccountActive || dst.Status != store.AccountActive:
+		return store.ErrAccountInactive
+	case src.Currency != dst.Currency:
+		return store.ErrCurrencyMismatch
+	}
+	if err := limits.check(ctx, src, amount); err != nil {
+		return err
+	}
+	if src.AvailableBalance.LessThan(amount) {
+		return store.ErrInsufficientFunds
+	}
+	return store.CheckMinBalance(src, amount)
+}
+
+// failedTransfer reports whether err is a rejection recorded as a failed transfer.
+func failedTransfer(err error) bool {
+	for _, ferr := range []error{store.ErrAccountNotFound, store.ErrCrossTenant, store.ErrAccountInactive,
+		store.ErrCurrencyMismatch, store.ErrLimitExceeded, store.ErrInsufficientFunds} {
+		if errors.Is(err, ferr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Transfer performs an atomic transfer from srcID -> dstID of amount, recorded
+// with details, and returns the ID of the recorded transactions row. A
+// rejected transfer is recorded as a failed row and reported with the
+// matching error.
+func (s *Store) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return 0, fmt.Errorf("amount must be positive")
+	}
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return 0, err
+	}
+	details, err := s.withTransactionID(details)
+	if err != nil {
+		return 0, err
+	}
+
+	var t store.Transaction
+	var rejected error
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		accs, err := getAccounts(ctx, tx, srcID, dstID)
+		if err != nil {
+			return err
+		}
+		src, srcOK := accs[srcID]
+		dst := accs[dstID]
+		t = store.Transaction{SourceAccountID: srcID, DestinationAccountID: dstID, Amount: amount, Currency: "USD", TransferDetails: details}
+		if srcOK {
+			t.Currency = src.Currency
+		}
+
+		rejected = s.checkTransfer(ctx, srcID, dstID, accs, amount, s.newLimitTracker(tx))
+		if rejected != nil && !failedTransfer(rejected) {
+			return rejected
+		}
+		if rejected != nil {
+			t.Status, t.ErrorMessage = store.StatusFailed, rejected.Error()
+			return s.insertTransaction(ctx, tx, &t)
+		}
+		// A transfer to the same account leaves its balance as it is
+		if srcID != dstID {
+			src.Balance = src.Balance.Sub(amount)
+			dst.Balance = dst.Balance.Add(amount)
+			if err := setBalance(ctx, tx, src); err != nil {
+				return err
+			}
+			if err := setBalance(ctx, tx, dst); err != nil {
+				return err
+			}
+		}
+		t.Status = store.StatusSucceeded
+		return s.insertTransaction(ctx, tx, &t)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rejected != nil {
+		return 0, rejected
+	}
+	return t.ID, nil
+}
+
+// DryRunTransfer runs every check of Transfer and returns the error Transfer
+// would return. Nothing is recorded.
+func (s *Store) DryRunTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) error {
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("amount must be positive")
+	}
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return err
+	}
+
+	accs, err := getAccounts(ctx, s.db, srcID, dstID)
+	if err != nil {
+		return err
+	}
+	return s.checkTransfer(ctx, srcID, dstID, accs, amount, s.newLimitTracker(s.db))
+}
+
+// TransferBatch performs items in order within a single DB transaction.
+//
+// If atomic, the first failing item rolls the whole batch back and is returned
+// as a *store.BatchItemError. Otherwise failing items are recorded as failed
+// transactions, the others are applied, and the per-item outcomes are returned.
+func (s *Store) TransferBatch(ctx context.Context, items []store.TransferItem, atomic bool) ([]store.TransferResult, error) {
+	results := make([]store.TransferResult, len(items))
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		ids := make([]int64, 0, 2*len(items))
+		for _, it := range items {
+			ids = append(ids, it.SourceAccountID, it.DestinationAccountID)
+		}
+		accs, err := getAccounts(ctx, tx, ids...)
+		if err != nil {
+			return err
+		}
+
+		// Each item sees the balances left by the ones before it
+		limits := s.newLimitTracker(tx)
+		touched := make(map[int64]bool)
+		for i, it := range items {
+			if it.TransferDetails, err = s.withTransactionID(it.TransferDetails); err != nil {
+				return err
+			}
+			src, srcOK := accs[it.SourceAccountID]
+			dst := accs[it.DestinationAccountID]
+			if !it.Amount.IsPositive() {
+				results[i].Err = fmt.Errorf("amount must be positive")
+			} else if err := s.checkSameAccount(it.SourceAccountID, it.DestinationAccountID); err != nil {
+				results[i].Err = err
+			} else if err := s.checkTransfer(ctx, it.SourceAccountID, it.DestinationAccountID, accs, it.Amount, limits); failedTransfer(err) {
+				results[i].Err = err
+			} else if err != nil {
+				return err
+			}
+			if results[i].Err != nil && atomic {
+				return &store.BatchItemError{Index: i, Err: results[i].Err}
+			}
+
+			t := store.Transaction{SourceAccountID: it.SourceAccountID, DestinationAccountID: it.DestinationAccountID,
+				Amount: it.Amount, Currency: "USD", TransferDetails: it.TransferDetails}
+			if srcOK {
+				t.Currency = src.Currency
+			}
+			if results[i].Err != nil {
+				t.Status, t.ErrorMessage = store.StatusFailed, results[i].Err.Error()
+				if err := s.insertTransaction(ctx, tx, &t); err != nil {
+					return err
+				}
+				continue
+			}
+
+			limits.add(src.ID, it.Amount)
+			src.Balance = src.Balance.Sub(it.Amount)
+			src.AvailableBalance = src.AvailableBalance.Sub(it.Amount)
+			accs[src.ID] = src
+			dst = accs[dst.ID]
+			dst.Balance = dst.Balance.Add(it.Amount)
+			dst.AvailableBalance = dst.AvailableBalance.Add(it.Amount)
+			accs[dst.ID] = dst
+			touched[src.ID], touched[dst.ID] = true, true
+
+			t.Status = store.StatusSucceeded
+			if err := s.insertTransaction(ctx, tx, &t); err != nil {
+				return err
+			}
+			results[i].TransactionID, results[i].UUID = t.ID, t.UUID
+		}
+		for id := range touched {
+			if err := setBalance(ctx, tx, accs[id]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SplitTransfer performs items, the legs of a split transfer, as an atomic
+// TransferBatch recorded under a new transfer group id, which is returned with
+// their outcomes. The legs must all have the same source account or all the
+// same destination, else store.ErrInvalidSplit is returned.
+func (s *Store) SplitTransfer(ctx context.Context, items []store.TransferItem) (uuid.UUID, []store.TransferResult, error) {
+	if err := store.CheckSplit(items); err != nil {
+		return uuid.Nil, nil, err
+	}
+	group, err := s.NewTransactionID()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	items = slices.Clone(items)
+	for i := range items {
+		items[i].GroupID = group
+	}
+	results, err := s.TransferBatch(ctx, items, true)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+	return group, results, nil
+}
+
+// TransactionID returns the serial id of the transaction with public id uid.
+func (s *Store) TransactionID(ctx context.Context, uid uuid.UUID) (int64, error) {
+	var id int64
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM transactions WHERE public_id = ?1 AND `+transactionScopeCond,
+		uid.String(), tenantArg(ctx), ownerArg(ctx)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, store.ErrTransactionNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("resolve transaction id: %w", err)
+	}
+	return id, nil
+}
+
+// getTransaction fetches a transaction by id if it is visible in ctx.
+func getTransaction(ctx context.Context, q querier, id int64) (store.Transaction, error) {
+	t, err := scanTransaction(q.QueryRowContext(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE id = ?1 AND `+transactionScopeCond, id, tenantArg(ctx), ownerArg(ctx)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.Transaction{}, store.ErrTransactionNotFound
+	}
+	if err != nil {
+		return store.Transaction{}, fmt.Errorf("get transaction: %w", err)
+	}
+	return t, nil
+}
+
+// GetTransaction fetches a transaction by id. Scoped to a tenant or owner,
+// only transactions touching one of its accounts are found.
+func (s *Store) GetTransaction(ctx context.Context, id int64) (store.Transaction, error) {
+	return getTransaction(ctx, s.db, id)
+}
+
+// checkAccountExists returns store.ErrAccountNotFound unless the account exists in the scope of ctx.
+func checkAccountExists(ctx context.Context, q querier, accountID int64) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM accounts WHERE account_id = ?1 AND `+accountScopeCond+`)`,
+		accountID, tenantArg(ctx), ownerArg(ctx)).Scan(&exists); err != nil {
+		return fmt.Errorf("check account: %w", err)
+	}
+	if !exists {
+		return store.ErrAccountNotFound
+	}
+	return nil
+}
+
+// collectTransactions scans rows selected with transactionColumns and closes them.
+func collectTransactions(rows *sql.Rows) ([]store.Transaction, error) {
+	defer rows.Close()
+	var txs []store.Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// ListTransactionsByAccount returns up to limit transactions where accountID was
+// the source or destination, newest first. A zero cursor starts from the newest
+// transaction; otherwise only transactions with an ID below cursor are returned.
+// The returned next cursor is zero when there are no further pages.
+func (s *Store) ListTransactionsByAccount(ctx context.Context, accountID, cursor int64, limit int) ([]store.Transaction, int64, error) {
+	if err := checkAccountExists(ctx, s.db, accountID); err != nil {
+		return nil, 0, err
+	}
+
+	// Fetch one extra row to find out whether another page follows
+	rows, err := s.db.QueryContext(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE (source_account_id = ?1 OR destination_account_id = ?1) AND (?2 = 0 OR id < ?2)
+		ORDER BY id DESC
+		LIMIT ?3`, accountID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transactions: %w", err)
+	}
+	txs, err := collectTransactions(rows)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transactions: %w", err)
+	}
+
+	var next int64
+	if len(txs) > limit {
+		txs = txs[:limit]
+		next = txs[limit-1].ID
+	}
+	return txs, next, nil
+}
+
+// exportFetchSize is the number of rows ExportTransactions fetches per query
+const exportFetchSize = 500
+
+// ExportTransactions calls fn for each transaction of accountID created in
+// [from, to), oldest first; zero times leave that end open. Rows are fetched
+// in chunks, and the connection is free while fn runs. It returns
+// store.ErrAccountNotFound, before any call to fn, if the account does not
+// exist, and stops at the first error returned by fn.
+func (s *Store) ExportTransactions(ctx context.Context, accountID int64, from, to time.Time, fn func(store.Transaction) error) error {
+	if err := checkAccountExists(ctx, s.db, accountID); err != nil {
+		return err
+	}
+
+	var lo, hi any
+	if !from.IsZero() {
+		lo = from.UnixNano()
+	}
+	if !to.IsZero() {
+		hi = to.UnixNano()
+	}
+	var afterCreated, afterID int64 = -1 << 63, 0
+	for {
+		rows, err := s.db.QueryContext(ctx, `SELECT `+transactionColumns+` FROM transactions
+			WHERE (source_account_id = ?1 OR destination_account_id = ?1)
+				AND (?2 IS NULL OR created_at >= ?2) AND (?3 IS NULL OR created_at < ?3)
+				AND (created_at, id) > (?4, ?5)
+			ORDER BY created_at, id
+			LIMIT ?6`, accountID, lo, hi, afterCreated, afterID, exportFetchSize)
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		txs, err := collectTransactions(rows)
+		if err != nil {
+			return fmt.Errorf("fetch transactions: %w", err)
+		}
+		for _, t := range txs {
+			if err := fn(t); err != nil {
+				return err
+			}
+		}
+		if len(txs) < exportFetchSize {
+			return nil
+		}
+		last := txs[len(txs)-1]
+		afterCreated, afterID = last.CreatedAt.UnixNano(), last.ID
+	}
+}
+
+// ReverseTransaction transfers the amount of a succeeded transfer back from
+// its destination to its source and returns the reversal. A transaction can
+// be reversed only once, and the reversal is subject to the same checks as a
+// regular transfer, including the destination's min balance, limits aside.
+func (s *Store) ReverseTransaction(ctx context.Context, id int64) (store.Transaction, error) {
+	var rev store.Transaction
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		orig, err := getTransaction(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if orig.Status != store.StatusSucceeded || orig.Kind != store.KindTransfer {
+			return store.ErrNotReversible
+		}
+		var reversed bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM transactions WHERE reversal_of = ?1)`, id).Scan(&reversed); err != nil {
+			return fmt.Errorf("check reversal: %w", err)
+		}
+		if reversed {
+			return store.ErrAlreadyReversed
+		}
+
+		srcID, dstID := orig.DestinationAccountID, orig.SourceAccountID
+		accs, err := getAccounts(ctx, tx, srcID, dstID)
+		if err != nil {
+			return err
+		}
+		src, srcOK := accs[srcID]
+		dst, dstOK := accs[dstID]
+		if !srcOK || !dstOK {
+			return store.ErrAccountNotFound
+		}
+		if err := s.checkScope(ctx, src, dst); err != nil {
+			return err
+		}
+		switch {
+		case src.Status != store.AccountActive || dst.Status != store.AccountActive:
+			return store.ErrAccountInactive
+		case src.Currency != dst.Currency:
+			return store.ErrCurrencyMismatch
+		case src.AvailableBalance.LessThan(orig.Amount):
+			return store.ErrInsufficientFunds
+		}
+		if err := store.CheckMinBalance(src, orig.Amount); err != nil {
+			return err
+		}
+
+		src.Balance = src.Balance.Sub(orig.Amount)
+		dst.Balance = dst.Balance.Add(orig.Amount)
+		if err := setBalance(ctx, tx, src); err != nil {
+			return err
+		}
+		if err := setBalance(ctx, tx, dst); err != nil {
+			return err
+		}
+		uid, err := s.NewTransactionID()
+		if err != nil {
+			return err
+		}
+		rev = store.Transaction{SourceAccountID: srcID, DestinationAccountID: dstID, Amount: orig.Amount, Currency: orig.Currency,
+			Status: store.StatusSucceeded, ReversalOf: id, ReversalOfUUID: orig.UUID, TransferDetails: store.TransferDetails{UUID: uid}}
+		return s.insertTransaction(ctx, tx, &rev)
+	})
+	if err != nil {
+		return store.Transaction{}, err
+	}
+	return rev, nil
+}
+
+// AdjustBalance credits accountID with amount, or debits it if amount is
+// negative, outside a transfer, and returns the adjustment recorded in the
+// ledger. Frozen accounts can be adjusted, closed ones cannot; a debit may
+// not exceed the available balance.
+func (s *Store) AdjustBalance(ctx context.Context, accountID int64, amount decimal.Decimal, reason, adjustedBy string) (store.Transaction, error) {
+	if amount.IsZero() {
+		return store.Transaction{}, fmt.Errorf("amount must be non-zero")
+	}
+
+	var t store.Transaction
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		acc, err := getAccount(ctx, tx, accountID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case !inScope(ctx, acc):
+			return store.ErrAccountNotFound
+		case acc.Status == store.AccountClosed:
+			return store.ErrAccountInactive
+		case acc.AvailableBalance.Add(amount).IsNegative():
+			return store.ErrInsufficientFunds
+		}
+
+		acc.Balance = acc.Balance.Add(amount)
+		if err := setBalance(ctx, tx, acc); err != nil {
+			return err
+		}
+		uid, err := s.NewTransactionID()
+		if err != nil {
+			return err
+		}
+		// A credit comes from no account and a debit goes to none
+		t = store.Transaction{Amount: amount.Abs(), Currency: acc.Currency, Status: store.StatusSucceeded, Kind: store.KindAdjustment,
+			Reason: reason, AdjustedBy: adjustedBy, TransferDetails: store.TransferDetails{UUID: uid}}
+		if amount.IsPositive() {
+			t.DestinationAccountID = accountID
+		} else {
+			t.SourceAccountID = accountID
+		}
+		return s.insertTransaction(ctx, tx, &t)
+	})
+	if err != nil {
+		return store.Transaction{}, err
+	}
+	return t, nil
+}
@@ -0,0 +1,224 @@
+package sqlite
+
+import (
+	"context"
+	"net/netip"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// The operations below need background workers, which only run against
+// Postgres, or Postgres-specific queries. They return store.ErrNotSupported.
+
+// EnqueueTransfer is not supported; transfers are made synchronously.
+func (s *Store) EnqueueTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (store.Transaction, error) {
+	return store.Transaction{}, store.ErrNotSupported
+}
+
+// SearchTransactions is not supported.
+func (s *Store) SearchTransactions(ctx context.Context, f store.TransactionFilter, order store.TransactionOrder, cursor string, limit int) ([]store.Transaction, string, error) {
+	return nil, "", store.ErrNotSupported
+}
+
+// GetStatement is not supported.
+func (s *Store) GetStatement(ctx context.Context, accountID int64, period time.Time) (store.Statement, error) {
+	return store.Statement{}, store.ErrNotSupported
+}
+
+// CreateScheduledTransfer is not supported.
+func (s *Store) CreateScheduledTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, executeAt time.Time, initiatedBy string) (store.ScheduledTransfer, error) {
+	return store.ScheduledTransfer{}, store.ErrNotSupported
+}
+
+// GetScheduledTransfer is not supported.
+func (s *Store) GetScheduledTransfer(ctx context.Context, id int64) (store.ScheduledTransfer, error) {
+	return store.ScheduledTransfer{}, store.ErrNotSupported
+}
+
+// ImportAccounts is not supported; accounts are created one by one.
+func (s *Store) ImportAccounts(ctx context.Context, accounts []store.AccountImport) error {
+	return store.ErrNotSupported
+}
+
+// CreateTransferJob is not supported.
+func (s *Store) CreateTransferJob(ctx context.Context, items []store.TransferItem, initiatedBy string) (store.TransferJob, error) {
+	return store.TransferJob{}, store.ErrNotSupported
+}
+
+// GetTransferJob is not supported.
+func (s *Store) GetTransferJob(ctx context.Context, id int64) (store.TransferJob, error) {
+	return store.TransferJob{}, store.ErrNotSupported
+}
+
+// ListTransferJobFailures is not supported.
+func (s *Store) ListTransferJobFailures(ctx context.Context, jobID int64, limit int) ([]store.TransferJobRow, error) {
+	return nil, store.ErrNotSupported
+}
+
+// CreateRule is not supported.
+func (s *Store) CreateRule(ctx context.Context, r store.Rule) (store.Rule, error) {
+	return store.Rule{}, store.ErrNotSupported
+}
+
+// ListRules is not supported.
+func (s *Store) ListRules(ctx context.Context) ([]store.Rule, error) {
+	return nil, store.ErrNotSupported
+}
+
+// DeleteRule is not supported.
+func (s *Store) DeleteRule(ctx context.Context, id int64) error {
+	return store.ErrNotSupported
+}
+
+// CreateFeePolicy is not supported.
+func (s *Store) CreateFeePolicy(ctx context.Context, p store.FeePolicy) (store.FeePolicy, error) {
+	return store.FeePolicy{}, store.ErrNotSupported
+}
+
+// ListFeePolicies is not supported.
+func (s *Store) ListFeePolicies(ctx context.Context) ([]store.FeePolicy, error) {
+	return nil, store.ErrNotSupported
+}
+
+// DeleteFeePolicy is not supported.
+func (s *Store) DeleteFeePolicy(ctx context.Context, id int64) error {
+	return store.ErrNotSupported
+}
+
+// ClearingAccounts is not supported.
+func (s *Store) ClearingAccounts(ctx context.Context) ([]int64, error) {
+	return nil, store.ErrNotSupported
+}
+
+// AddClearingAccount is not supported.
+func (s *Store) AddClearingAccount(ctx context.Context, accountID int64) error {
+	return store.ErrNotSupported
+}
+
+// RemoveClearingAccount is not supported.
+func (s *Store) RemoveClearingAccount(ctx context.Context, accountID int64) error {
+	return store.ErrNotSupported
+}
+
+// CreateClearingTransfer is not supported.
+func (s *Store) CreateClearingTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, reference string) (store.ClearingTransfer, error) {
+	return store.ClearingTransfer{}, store.ErrNotSupported
+}
+
+// SettlementReport is not supported.
+func (s *Store) SettlementReport(ctx context.Context, date time.Time) (store.SettlementReport, error) {
+	return store.SettlementReport{}, store.ErrNotSupported
+}
+
+// CreateSweepRule is not supported.
+func (s *Store) CreateSweepRule(ctx context.Context, r store.SweepRule) (store.SweepRule, error) {
+	return store.SweepRule{}, store.ErrNotSupported
+}
+
+// ListSweepRules is not supported.
+func (s *Store) ListSweepRules(ctx context.Context) ([]store.SweepRule, error) {
+	return nil, store.ErrNotSupported
+}
+
+// UpdateSweepRule is not supported.
+func (s *Store) UpdateSweepRule(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (store.SweepRule, error) {
+	return store.SweepRule{}, store.ErrNotSupported
+}
+
+// DeleteSweepRule is not supported.
+func (s *Store) DeleteSweepRule(ctx context.Context, id int64) error {
+	return store.ErrNotSupported
+}
+
+// SetKYCStatus is not supported.
+func (s *Store) SetKYCStatus(ctx context.Context, accountID int64, status string) (store.Account, error) {
+	return store.Account{}, store.ErrNotSupported
+}
+
+// CounterpartyAllowlist is not supported.
+func (s *Store) CounterpartyAllowlist(ctx context.Context, accountID int64) (store.CounterpartyAllowlist, error) {
+	return store.CounterpartyAllowlist{}, store.ErrNotSupported
+}
+
+// SetCounterpartyAllowlist is not supported.
+func (s *Store) SetCounterpartyAllowlist(ctx context.Context, l store.CounterpartyAllowlist) (store.CounterpartyAllowlist, error) {
+	return store.CounterpartyAllowlist{}, store.ErrNotSupported
+}
+
+// AccountData is not supported.
+func (s *Store) AccountData(ctx context.Context, accountID int64) (store.AccountData, error) {
+	return store.AccountData{}, store.ErrNotSupported
+}
+
+// EraseAccount is not supported.
+func (s *Store) EraseAccount(ctx context.Context, accountID int64, reason, erasedBy string) (store.AccountErasure, bool, error) {
+	return store.AccountErasure{}, false, store.ErrNotSupported
+}
+
+// AlertThresholds is not supported.
+func (s *Store) AlertThresholds(ctx context.Context, accountID int64) (store.AlertThresholds, error) {
+	return store.AlertThresholds{}, store.ErrNotSupported
+}
+
+// SetAlertThresholds is not supported.
+func (s *Store) SetAlertThresholds(ctx context.Context, t store.AlertThresholds) (store.AlertThresholds, error) {
+	return store.AlertThresholds{}, store.ErrNotSupported
+}
+
+// ListAlerts is not supported.
+func (s *Store) ListAlerts(ctx context.Context, accountID int64, status string, cursor int64, limit int) ([]store.Alert, int64, error) {
+	return nil, 0, store.ErrNotSupported
+}
+
+// ResolveAlert is not supported.
+func (s *Store) ResolveAlert(ctx context.Context, accountID, alertID int64) (store.Alert, error) {
+	return store.Alert{}, store.ErrNotSupported
+}
+
+// ListSweepExecutions is not supported.
+func (s *Store) ListSweepExecutions(ctx context.Context, ruleID, cursor int64, limit int) ([]store.SweepExecution, int64, error) {
+	return nil, 0, store.ErrNotSupported
+}
+
+// ListReviews is not supported.
+func (s *Store) ListReviews(ctx context.Context, status string, cursor int64, limit int) ([]store.Review, int64, error) {
+	return nil, 0, store.ErrNotSupported
+}
+
+// ResolveReview is not supported.
+func (s *Store) ResolveReview(ctx context.Context, transactionID int64, status, reviewedBy string) (store.Review, error) {
+	return store.Review{}, store.ErrNotSupported
+}
+
+// CreateAPIKey is not supported; API keys can still be configured statically.
+func (s *Store) CreateAPIKey(ctx context.Context, name, role, tenant string, networks []netip.Prefix, keyHash string) (store.APIKey, error) {
+	return store.APIKey{}, store.ErrNotSupported
+}
+
+// ListAPIKeys is not supported.
+func (s *Store) ListAPIKeys(ctx context.Context) ([]store.APIKey, error) {
+	return nil, store.ErrNotSupported
+}
+
+// RevokeAPIKey is not supported.
+func (s *Store) RevokeAPIKey(ctx context.Context, id int64) error {
+	return store.ErrNotSupported
+}
+
+// SnapshotBalances is not supported.
+func (s *Store) SnapshotBalances(ctx context.Context) (store.SnapshotRun, error) {
+	return store.SnapshotRun{}, store.ErrNotSupported
+}
+
+// LatestReconciliation is not supported.
+func (s *Store) LatestReconciliation(ctx context.Context) (store.ReconciliationRun, error) {
+	return store.ReconciliationRun{}, store.ErrNotSupported
+}
+
+// TrialBalance is not supported.
+func (s *Store) TrialBalance(ctx context.Context, from, to time.Time) (store.TrialBalance, error) {
+	return store.TrialBalance{}, store.ErrNotSupported
+}
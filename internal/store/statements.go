@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrStatementNotFound is returned by GetStatement when no statement was generated for the period.
+	ErrStatementNotFound = errors.New("statement not found")
+	// ErrStatementPeriodOpen is returned by GenerateStatements for a month that has not ended yet.
+	ErrStatementPeriodOpen = errors.New("statement period has not ended")
+)
+
+// Statement is an account's statement for one calendar month (UTC).
+type Statement struct {
+	AccountID      int64
+	Period         time.Time // first day of the month
+	Currency       string
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+	Entries        []StatementEntry // in settlement order
+	CreatedAt      time.Time
+}
+
+// StatementEntry is a succeeded transfer or adjustment on a statement, as seen
+// by its account.
+type StatementEntry struct {
+	TransactionID         int64
+	TransactionUUID       uuid.UUID // the public id of the transaction
+	SettledAt             time.Time
+	CounterpartyAccountID int64           // 0 for adjustments
+	Amount                decimal.Decimal // positive when credited, negative when debited
+	BalanceAfter          decimal.Decimal
+	Reference             string // the reason of adjustments
+}
+
+// StatementPeriod returns the statement period containing t: the first day of its month in UTC.
+func StatementPeriod(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// statementEntriesSQL selects the succeeded transfers of account $1 settled in
+// [$3, $4) as statement entries; $2 is the succeeded status. Adjustments have
+// no counterparty and show their reason as reference. Transfers from an account
+// to itself do not move its balance and are left out. Those settled in the
+// period were created before its end, which spares reading later partitions.
+const statementEntriesSQL = `
+SELECT id, public_id, settled_at,
+	COALESCE(CASE WHEN destination_account_id = $1 THEN source_account_id ELSE destination_account_id END, 0),
+	CASE WHEN destination_account_id = $1 THEN amount ELSE -amount END,
+	CASE WHEN kind = 'adjustment' THEN reason ELSE reference END
+FROM transactions
+WHERE (source_account_id = $1 OR destination_account_id = $1) AND source_account_id IS DISTINCT FROM destination_account_id
+	AND status = $2 AND settled_at >= $3 AND settled_at < $4 AND created_at < $4
+ORDER BY settled_at, id`
+
+// GenerateStatements generates the statement for the month containing period
+// of every account that existed before the month ended and has none yet, and
+// returns how many were generated. It returns ErrStatementPeriodOpen until the
+// month has ended.
+func (s *Store) GenerateStatements(ctx context.Context, period time.Time) (n int, err error) {
+	period = StatementPeriod(period)
+	ctx, span := startSpan(ctx, "GenerateStatements", attribute.String("statement.period", period.Format("2006-01")))
+	defer func() {
+		span.SetAttributes(attribute.Int("statement.generated", n))
+		endSpan(span, err)
+	}()
+
+	end := period.AddDate(0, 1, 0)
+	// Transfers settling just before the end may not be committed yet
+	if end.After(s.clock.Now().Add(-snapshotDelay)) {
+		return 0, ErrStatementPeriodOpen
+	}
+
+	rows, err := s.pool.Query(ctx, `SELECT account_id FROM accounts a
+		WHERE (a.created_at IS NULL OR a.created_at < $2)
+			AND NOT EXISTS (SELECT 1 FROM statements st WHERE st.account_id = a.account_id AND st.period = $1)
+		ORDER BY account_id`, period, end)
+	if err != nil {
+		return 0, fmt.Errorf("list accounts without statement: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("list accounts without statement: %w", err)
+	}
+
+	for _, id := range ids {
+		ok, err := s.generateStatement(ctx, id, period, end)
+		if err != nil {
+			return n, fmt.Errorf("generate statement for account %d: %w", id, err)
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// generateStatement generates and stores the statement of accountID for
+// [period, end). ok is false if a statement for the period already exists.
+func (s *Store) generateStatement(ctx context.Context, accountID int64, period, end time.Time) (ok bool, err error) {
+	tx, err := s.pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead})
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	// The opening balance is the balance just before the period; for an
+	// account opened during the period that is the balance it was opened with
+	st := Statement{AccountID: accountID, Period: period}
+	var createdLater, archived bool
+	err = tx.QueryRow(ctx, balanceAtSQL, accountID, period.Add(-time.Microsecond), StatusSucceeded, nil, nil, s.clock.Now()).
+		Scan(&st.OpeningBalance, &st.Currency, &createdLater, &archived)
+	if err != nil {
+		return false, fmt.Errorf("opening balance: %w", err)
+	}
+	if archived {
+		return false, fmt.Errorf("opening balance: %w", ErrHistoryArchived)
+	}
+
+	rows, err := tx.Query(ctx, statementEntriesSQL, accountID, StatusSucceeded, period, end)
+	if err != nil {
+		return false, fmt.Errorf("query entries: %w", err)
+	}
+	balance := st.OpeningBalance
+	st.Entries, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (StatementEntry, error) {
+		var e StatementEntry
+		err := row.Scan(&e.TransactionID, &e.TransactionUUID, &e.SettledAt, &e.CounterpartyAccountID, &e.Amount, &e.Reference)
+		balance = balance.Add(e.Amount)
+		e.BalanceAfter = balance
+		return e, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("query entries: %w", err)
+	}
+	st.ClosingBalance = balance
+
+	tag, err := tx.Exec(ctx, `INSERT INTO statements (account_id, period, currency, opening_balance, closing_balance)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+		accountID, period, st.Currency, st.OpeningBalance, st.ClosingBalance)
+	if err != nil {
+		return false, fmt.Errorf("insert statement: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	for _, e := range st.Entries {
+		if _, err := tx.Exec(ctx, `INSERT INTO statement_entries (account_id, period, transaction_id, transaction_public_id, settled_at, counterparty_account_id, amount, balance_after, reference)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			accountID, period, e.TransactionID, e.TransactionUUID, e.SettledAt, e.CounterpartyAccountID, e.Amount, e.BalanceAfter, e.Reference); err != nil {
+			return false, fmt.Errorf("insert entry for transaction %d: %w", e.TransactionID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return true, nil
+}
+
+// GetStatement returns the statement of accountID for the month containing
+// period, or ErrStatementNotFound if none has been generated.
+func (s *Store) GetStatement(ctx context.Context, accountID int64, period time.Time) (_ Statement, err error) {
+	period = StatementPeriod(period)
+	ctx, span := startSpan(ctx, "GetStatement",
+		attribute.Int64("account.id", accountID),
+		attribute.String("statement.period", period.Format("2006-01")),
+	)
+	defer func() { endSpan(span, err) }()
+
+	st := Statement{AccountID: accountID, Period: period}
+	err = s.read.QueryRow(ctx, `SELECT currency, opening_balance, closing_balance, created_at FROM statements
+		WHERE account_id = $1 AND period = $2
+			AND EXISTS (SELECT 1 FROM accounts WHERE account_id = $1 AND `+accountScopeCond(3)+`)`, accountID, period, tenantArg(ctx), ownerArg(ctx)).
+		Scan(&st.Currency, &st.OpeningBalance, &st.ClosingBalance, &st.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Statement{}, ErrStatementNotFound
+		}
+		return Statement{}, fmt.Errorf("get statement: %w", err)
+	}
+
+	rows, err := s.read.Query(ctx, `SELECT transaction_id, transaction_public_id, settled_at, counterparty_account_id, amount, balance_after, reference
+		FROM statement_entries WHERE account_id = $1 AND period = $2
+		ORDER BY settled_at, transaction_id`, accountID, period)
+	if err != nil {
+		return Statement{}, fmt.Errorf("get statement entries: %w", err)
+	}
+	st.Entries, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (StatementEntry, error) {
+		var e StatementEntry
+		err := row.Scan(&e.TransactionID, &e.TransactionUUID, &e.SettledAt, &e.CounterpartyAccountID, &e.Amount, &e.BalanceAfter, &e.Reference)
+		return e, err
+	})
+	if err != nil {
+		return Statement{}, fmt.Errorf("get statement entries: %w", err)
+	}
+	return st, nil
+}
@@ -5,136 +5,1057 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/cache"
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/events"
 )
 
 // Errors returned by store operations
 var (
-	ErrInsufficientFunds = errors.New("insufficient funds")
-	ErrAccountNotFound   = errors.New("account not found")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrAccountExists       = errors.New("account already exists")
+	ErrAccountInactive     = errors.New("account is not active")
+	ErrInvalidTransition   = errors.New("account status transition not allowed")
+	ErrCurrencyMismatch    = errors.New("accounts have different currencies")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	// ErrSameAccount is returned for a transfer or hold from an account to
+	// itself, unless the store allows same-account transfers
+	ErrSameAccount = errors.New("source and destination accounts are the same")
+	// ErrNotSupported is returned by stores other than Store for features they lack
+	ErrNotSupported = errors.New("not supported by this store")
 )
 
-// Store wraps a pgxpool.Pool
-type Store struct {
-	pool *pgxpool.Pool
+// Accounts of a transfer, as reported in AccountNotFoundError.Party
+const (
+	PartySource      = "source"
+	PartyDestination = "destination"
+)
+
+// AccountNotFoundError reports which account of a transfer does not exist or
+// is not visible to the caller. It matches ErrAccountNotFound.
+type AccountNotFoundError struct {
+	AccountID int64
+	Party     string
+}
+
+func (e *AccountNotFoundError) Error() string {
+	return e.Party + " account not found"
+}
+
+func (e *AccountNotFoundError) Is(target error) bool {
+	return target == ErrAccountNotFound
+}
+
+// CheckTransferAccounts returns the error of a transfer from srcID to dstID
+// when one of them does not exist, as told by srcOK and dstOK, the source
+// first. It returns nil if both exist.
+func CheckTransferAccounts(srcID, dstID int64, srcOK, dstOK bool) error {
+	switch {
+	case !srcOK:
+		return &AccountNotFoundError{AccountID: srcID, Party: PartySource}
+	case !dstOK:
+		return &AccountNotFoundError{AccountID: dstID, Party: PartyDestination}
+	}
+	return nil
+}
+
+// Account statuses. Only active accounts may send or receive transfers.
+const (
+	AccountActive = "active"
+	AccountFrozen = "frozen"
+	AccountClosed = "closed"
+)
+
+// accountTransitions lists, per target status, the statuses an account may move from.
+// Closed is terminal.
+var accountTransitions = map[string][]string{
+	AccountActive: {AccountFrozen},
+	AccountFrozen: {AccountActive},
+	AccountClosed: {AccountActive, AccountFrozen},
+}
+
+// Transaction statuses recorded in the transactions table
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Kinds of transactions recorded in the transactions table
+const (
+	KindTransfer   = "transfer"
+	KindAdjustment = "adjustment" // one account credited or debited outside a transfer
+	KindFee        = "fee"        // charged on a transfer by a FeePolicy, from its source to the fee account, or refunded by its reversal
+	KindSettlement = "settlement" // the net of a business day's clearing transfers between two clearing accounts
+)
+
+// Account is a row of the accounts table.
+// Balance is the ledger balance; AvailableBalance excludes amounts reserved by pending holds.
+type Account struct {
+	ID               int64
+	Balance          decimal.Decimal
+	AvailableBalance decimal.Decimal
+	InitialBalance   decimal.Decimal // the balance the account was opened with
+	Currency         string
+	Status           string
+	KYCStatus        string
+	Limits           TransferLimits      // the account's own; see Store.effectiveLimits
+	MinBalance       decimal.NullDecimal // the available balance transfers out must leave, if any
+	Tenant           string
+	OwnerID          string // the only non-admin caller allowed to use the account, if set
+	ExternalID       string // the id upstream systems know the account by, if any
+	ParentID         int64  // the account this one is a wallet of, if any
+	Wallet           string // the wallet's label among its parent's wallets
+	AccountMetadata
 }
 
-// NewStore creates a new Store
-func NewStore(pool *pgxpool.Pool) *Store {
-	return &Store{pool: pool}
+// AccountMetadata is descriptive information about an account used by ops tooling
+type AccountMetadata struct {
+	DisplayName string
+	OwnerRef    string
+	Tags        []string
 }
 
-// CreateAccount inserts a new account with initial balance.
-func (s *Store) CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal) error {
-	_, err := s.pool.Exec(ctx, `INSERT INTO accounts (account_id, balance) VALUES ($1, $2)`, accountID, initial.String())
+// MetadataUpdate changes the metadata fields that are non-nil
+type MetadataUpdate struct {
+	DisplayName *string
+	OwnerRef    *string
+	Tags        *[]string
+}
+
+// accountColumns is the select list matching scanAccount
+const accountColumns = `account_id, balance, balance - held_balance, initial_balance, currency, status, kyc_status, max_transfer_amount, daily_transfer_limit,
+	tenant_id, owner_id, COALESCE(external_id, ''), COALESCE(parent_account_id, 0), COALESCE(wallet, ''), min_balance, display_name, owner_ref, tags`
+
+// scanAccount scans a row selected with accountColumns.
+func scanAccount(row pgx.Row) (Account, error) {
+	var acc Account
+	if err := row.Scan(&acc.ID, &acc.Balance, &acc.AvailableBalance, &acc.InitialBalance, &acc.Currency, &acc.Status, &acc.KYCStatus, &acc.Limits.MaxAmount, &acc.Limits.Daily,
+		&acc.Tenant, &acc.OwnerID, &acc.ExternalID, &acc.ParentID, &acc.Wallet, &acc.MinBalance, &acc.DisplayName, &acc.OwnerRef, &acc.Tags); err != nil {
+		return Account{}, err
+	}
+	return acc, nil
+}
+
+// lockAccounts selects the accounts FOR UPDATE in ascending id order, so that
+// concurrent callers cannot deadlock, and returns them keyed by id.
+func lockAccounts(ctx context.Context, tx pgx.Tx, ids ...int64) (map[int64]Account, error) {
+	accs, err := lockExistingAccounts(ctx, tx, ids)
 	if err != nil {
-		return fmt.Errorf("create account: %w", err)
+		return nil, err
+	}
+	for _, id := range ids {
+		if _, ok := accs[id]; !ok {
+			return nil, ErrAccountNotFound
+		}
+	}
+	return accs, nil
+}
+
+// lockExistingAccounts is lockAccounts without the existence check: unknown
+// ids are missing from the result. Accounts of every tenant are locked; callers
+// check them with Store.checkScope or inScope.
+func lockExistingAccounts(ctx context.Context, tx pgx.Tx, ids []int64) (map[int64]Account, error) {
+	sorted := append([]int64(nil), ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	accs := make(map[int64]Account, len(sorted))
+	for i, id := range sorted {
+		if i > 0 && sorted[i-1] == id {
+			continue
+		}
+		acc, err := scanAccount(tx.QueryRow(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = $1 FOR UPDATE`, id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			return nil, fmt.Errorf("lock account %d: %w", id, err)
+		}
+		accs[id] = acc
+	}
+	return accs, nil
+}
+
+// Transaction is a row of the transactions table. An adjustment has no
+// source account if it is a credit and no destination account if a debit.
+type Transaction struct {
+	ID                   int64
+	CreatedAt            time.Time
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Currency             string
+	Status               string
+	ErrorMessage         string
+	ReversalOf           int64     // id of the reversed transaction, if this is a reversal
+	ReversalOfUUID       uuid.UUID // public id of the reversed transaction
+	FeeOf                int64     // id of the transfer charged, if this is a fee
+	FeeOfUUID            uuid.UUID // public id of the transfer charged
+	TransferDetails
+	Kind       string
+	Reason     string // why an adjustment was made
+	AdjustedBy string // who made an adjustment
+}
+
+// TransferDetails is caller-supplied information recorded with a transfer
+type TransferDetails struct {
+	UUID        uuid.UUID // public id of the transaction; generated if zero
+	GroupID     uuid.UUID // links the legs of a split transfer; only recorded by TransferBatch
+	Reference   string    // free text, e.g. an invoice number
+	PurposeCode string
+	InitiatedBy string // subject of the API key or JWT that asked for it, if any
+	Channel     string // one of the Channel constants
+	RequestID   string // id of the request that asked for it, if any
+}
+
+// Channels a transfer can be initiated through
+const (
+	ChannelHTTP      = "http"      // a single transfer request to the API
+	ChannelBatch     = "batch"     // a batch request or transfer job
+	ChannelScheduler = "scheduler" // a scheduled transfer coming due
+)
+
+// transactionColumns is the select list matching scanTransaction
+const transactionColumns = "id, created_at, COALESCE(source_account_id, 0), COALESCE(destination_account_id, 0), amount, currency, status, COALESCE(error_message, ''), COALESCE(reversal_of, 0), reference, purpose_code, kind, COALESCE(reason, ''), COALESCE(adjusted_by, ''), public_id, initiated_by, channel, request_id, COALESCE(fee_of, 0), transfer_group, reversal_of_public_id, fee_of_public_id"
+
+// scanTransaction scans a row selected with transactionColumns.
+func scanTransaction(row pgx.Row) (Transaction, error) {
+	var t Transaction
+	if err := row.Scan(&t.ID, &t.CreatedAt, &t.SourceAccountID, &t.DestinationAccountID, &t.Amount, &t.Currency, &t.Status, &t.ErrorMessage, &t.ReversalOf,
+		&t.Reference, &t.PurposeCode, &t.Kind, &t.Reason, &t.AdjustedBy, &t.UUID, &t.InitiatedBy, &t.Channel, &t.RequestID, &t.FeeOf, &t.GroupID,
+		&t.ReversalOfUUID, &t.FeeOfUUID); err != nil {
+		return Transaction{}, err
+	}
+	return t, nil
+}
+
+// Store wraps a pgxpool.Pool. Reads that tolerate replication lag go to the
+// read pool, which is the primary itself unless a replica is configured.
+type Store struct {
+	pool        *pgxpool.Pool
+	read        *pgxpool.Pool
+	outbox      bool
+	notify      bool
+	maxRetries  int
+	cache       cache.Cache
+	cacheTTL    time.Duration
+	limits      atomic.Pointer[TransferLimits] // defaults for accounts without their own
+	crossTenant bool
+	sameAccount bool
+	kyc         KYCPolicy
+	rules       atomic.Pointer[[]Rule]      // as of the last ReloadRules
+	fileRules   atomic.Pointer[[]Rule]      // of rulesFile, as of the last ReloadRules
+	fees        atomic.Pointer[[]FeePolicy] // as of the last ReloadFeePolicies
+	rulesFile   func() ([]Rule, error)
+	feeScale    func(currency string) int32
+	cutoff      time.Duration // ending the business day; see BusinessDate
+	ids         IDGenerator
+	clock       clock.Clock
+}
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithOutbox makes the store write account and transfer events to the outbox
+// table, to be relayed to a message broker.
+func WithOutbox() Option {
+	return func(s *Store) {
+		s.outbox = true
+	}
+}
+
+// WithNotify makes Transfer notify TransferChannel when it commits, for a
+// Listener to fan the transfers out to live consumers.
+func WithNotify() Option {
+	return func(s *Store) {
+		s.notify = true
+	}
+}
+
+// WithReplica sends reads that tolerate replication lag, such as GetAccount and
+// ListTransactionsByAccount, to a read-only replica. Writes, and reads made to
+// decide on a write, always go to the primary.
+func WithReplica(replica *pgxpool.Pool) Option {
+	return func(s *Store) {
+		s.read = replica
+	}
+}
+
+// WithClock replaces the system clock, e.g. with a clock.Fake in tests. It
+// stamps the accounts, transactions, holds and scheduled transfers the store
+// creates, and is the present of daily limits, velocity rules, hold expiry and
+// statement periods. Bookkeeping rows such as outbox events and API keys keep
+// the database's time.
+func WithClock(c clock.Clock) Option {
+	return func(s *Store) {
+		s.clock = c
+	}
+}
+
+// WithSameAccountTransfers records transfers from an account to itself as
+// succeeded transactions that leave its balance unchanged, after the checks
+// of any other transfer, rather than rejecting them with ErrSameAccount. Holds
+// are rejected either way.
+func WithSameAccountTransfers() Option {
+	return func(s *Store) {
+		s.sameAccount = true
+	}
+}
+
+// checkSameAccount returns ErrSameAccount for a transfer from srcID to itself,
+// unless the store allows it.
+func (s *Store) checkSameAccount(srcID, dstID int64) error {
+	if srcID == dstID && !s.sameAccount {
+		return ErrSameAccount
 	}
 	return nil
 }
 
-// GetAccount fetches the current balance for accountID.
-func (s *Store) GetAccount(ctx context.Context, accountID int64) (decimal.Decimal, error) {
-	var balStr string
-	err := s.pool.QueryRow(ctx, `SELECT balance::text FROM accounts WHERE account_id = $1`, accountID).Scan(&balStr)
+// NewStore creates a new Store on the primary pool
+func NewStore(pool *pgxpool.Pool, opts ...Option) *Store {
+	s := &Store{pool: pool, maxRetries: DefaultMaxRetries, ids: UUIDv7{}, clock: clock.Real{}}
+	s.limits.Store(&TransferLimits{})
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.read == nil {
+		s.read = pool
+	}
+	return s
+}
+
+// CreateAccount inserts a new account with initial balance in currency (an ISO 4217 code) and metadata,
+// in the tenant and owned by the owner ctx is scoped to.
+func (s *Store) CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta AccountMetadata) (err error) {
+	ctx, span := startSpan(ctx, "CreateAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	_, err = s.createAccount(ctx, accountID, "", initial, currency, meta)
+	return err
+}
+
+// maxAccountIDAttempts bounds the ids drawn from accounts_account_id_seq for
+// one account, in case clients already took them
+const maxAccountIDAttempts = 5
+
+// CreateAccountWithExternalID creates an account addressable by externalID and
+// returns its numeric id. If accountID is 0 one is assigned. It returns
+// ErrAccountExists if either id is taken.
+func (s *Store) CreateAccountWithExternalID(ctx context.Context, externalID string, accountID int64, initial decimal.Decimal, currency string, meta AccountMetadata) (id int64, err error) {
+	ctx, span := startSpan(ctx, "CreateAccountWithExternalID", attribute.String("account.external_id", externalID))
+	defer func() {
+		span.SetAttributes(attribute.Int64("account.id", id))
+		endSpan(span, err)
+	}()
+
+	if accountID != 0 {
+		return s.createAccount(ctx, accountID, externalID, initial, currency, meta)
+	}
+	for i := 0; i < maxAccountIDAttempts; i++ {
+		if err := s.pool.QueryRow(ctx, `SELECT nextval('accounts_account_id_seq')`).Scan(&accountID); err != nil {
+			return 0, fmt.Errorf("assign account id: %w", err)
+		}
+		id, err = s.createAccount(ctx, accountID, externalID, initial, currency, meta)
+		if !errors.Is(err, errAccountIDTaken) {
+			return id, err
+		}
+	}
+	return 0, fmt.Errorf("assign account id: %d ids in a row were taken", maxAccountIDAttempts)
+}
+
+// errAccountIDTaken is the ErrAccountExists of createAccount when the numeric id
+// is the one taken.
+var errAccountIDTaken = fmt.Errorf("%w: account id taken", ErrAccountExists)
+
+// createAccount inserts the account and its AccountCreated event.
+func (s *Store) createAccount(ctx context.Context, accountID int64, externalID string, initial decimal.Decimal, currency string, meta AccountMetadata) (_ int64, err error) {
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	tenant, _ := TenantFromContext(ctx)
+	owner, _ := OwnerFromContext(ctx)
+	_, err = tx.Exec(ctx, `INSERT INTO accounts (account_id, balance, initial_balance, currency, tenant_id, owner_id, external_id, display_name, owner_ref, tags, created_at)
+		VALUES ($1, $2, $2, $3, $4, $5, NULLIF($6, ''), $7, $8, $9, $10)`,
+		accountID, initial, currency, tenant, owner, externalID, meta.DisplayName, meta.OwnerRef, tags, s.clock.Now())
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "accounts_pkey" {
+			return 0, errAccountIDTaken
+		}
+		if isUniqueViolation(err) {
+			return 0, ErrAccountExists
+		}
+		return 0, fmt.Errorf("create account: %w", err)
+	}
+	if err := s.writeEvent(ctx, tx, events.AccountCreated, accountID, events.AccountCreatedPayload{
+		AccountID:      accountID,
+		ExternalID:     externalID,
+		InitialBalance: initial.String(),
+		Currency:       currency,
+		Tenant:         tenant,
+		OwnerID:        owner,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("commit: %w", err)
+	}
+	return accountID, nil
+}
+
+// AccountIDs returns the numeric ids of the accounts with the given external
+// ids, leaving out unknown ones. Accounts of other tenants than the one ctx
+// is scoped to are unknown; the owner scope is left to the caller, since
+// transfers may go to accounts of any owner.
+func (s *Store) AccountIDs(ctx context.Context, externalIDs []string) (_ map[string]int64, err error) {
+	ctx, span := startSpan(ctx, "AccountIDs", attribute.Int("account.count", len(externalIDs)))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT external_id, account_id FROM accounts
+		WHERE external_id = ANY($1) AND ($2::text IS NULL OR tenant_id = $2)`, externalIDs, tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("resolve external ids: %w", err)
+	}
+	defer rows.Close()
+	ids := make(map[string]int64, len(externalIDs))
+	for rows.Next() {
+		var ext string
+		var id int64
+		if err := rows.Scan(&ext, &id); err != nil {
+			return nil, fmt.Errorf("scan external id: %w", err)
+		}
+		ids[ext] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("resolve external ids: %w", err)
+	}
+	return ids, nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation
+}
+
+// GetAccount fetches the account with its current balance.
+func (s *Store) GetAccount(ctx context.Context, accountID int64) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "GetAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	// Cache entries are shared by every caller, so the scope is checked last
+	var acc Account
+	ok := false
+	if s.cache != nil {
+		acc, ok = s.cachedAccount(ctx, accountID)
+	}
+	if !ok {
+		acc, err = scanAccount(s.read.QueryRow(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = $1`, accountID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return Account{}, ErrAccountNotFound
+			}
+			return Account{}, fmt.Errorf("get account: %w", err)
+		}
+		if s.cache != nil {
+			s.cacheAccount(ctx, acc)
+		}
+	}
+	if !inScope(ctx, acc) {
+		return Account{}, ErrAccountNotFound
+	}
+	return acc, nil
+}
+
+// AccountFilter restricts the accounts returned by ListAccounts; zero fields match everything.
+type AccountFilter struct {
+	Status     string
+	MinBalance *decimal.Decimal
+	MaxBalance *decimal.Decimal
+	Tag        string
+}
+
+// ListAccounts returns up to limit accounts matching f in ascending id order,
+// starting after the account id in after (nil for the first page).
+// next is the cursor for the following page, or nil on the last page.
+func (s *Store) ListAccounts(ctx context.Context, f AccountFilter, after *int64, limit int) (_ []Account, next *int64, err error) {
+	ctx, span := startSpan(ctx, "ListAccounts")
+	defer func() { endSpan(span, err) }()
+
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if t := tenantArg(ctx); t != nil {
+		add("tenant_id = $%d", *t)
+	}
+	if o := ownerArg(ctx); o != nil {
+		add("owner_id = $%d", *o)
+	}
+	if after != nil {
+		add("account_id > $%d", *after)
+	}
+	if f.Status != "" {
+		add("status = $%d", f.Status)
+	}
+	if f.MinBalance != nil {
+		add("balance >= $%d", f.MinBalance)
+	}
+	if f.MaxBalance != nil {
+		add("balance <= $%d", f.MaxBalance)
+	}
+	if f.Tag != "" {
+		add("tags ? $%d", f.Tag)
+	}
+	query := `SELECT ` + accountColumns + ` FROM accounts`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	// Fetch one extra row to find out whether another page follows
+	args = append(args, limit+1)
+	query += fmt.Sprintf(` ORDER BY account_id LIMIT $%d`, len(args))
+
+	rows, err := s.read.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accs := make([]Account, 0, limit)
+	for rows.Next() {
+		acc, err := scanAccount(rows)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scan account: %w", err)
+		}
+		accs = append(accs, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	if len(accs) > limit {
+		accs = accs[:limit]
+		last := accs[limit-1].ID
+		next = &last
+	}
+	return accs, next, nil
+}
+
+// UpdateAccountMetadata applies upd to the account and returns the updated account.
+func (s *Store) UpdateAccountMetadata(ctx context.Context, accountID int64, upd MetadataUpdate) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "UpdateAccountMetadata", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	// A NULL parameter keeps the current value
+	var tags interface{}
+	if upd.Tags != nil {
+		tags = *upd.Tags
+		if *upd.Tags == nil {
+			tags = []string{}
+		}
+	}
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET
+			display_name = COALESCE($2, display_name),
+			owner_ref = COALESCE($3, owner_ref),
+			tags = COALESCE($4::jsonb, tags)
+		WHERE account_id = $1 AND `+accountScopeCond(5)+`
+		RETURNING `+accountColumns, accountID, upd.DisplayName, upd.OwnerRef, tags, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, fmt.Errorf("update account metadata: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
+
+// UpdateAccountStatus moves the account to status if allowed from its current
+// status (see accountTransitions) and returns the updated account.
+func (s *Store) UpdateAccountStatus(ctx context.Context, accountID int64, status string) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "UpdateAccountStatus",
+		attribute.Int64("account.id", accountID),
+		attribute.String("account.status", status),
+	)
+	defer func() { endSpan(span, err) }()
+
+	from, ok := accountTransitions[status]
+	if !ok {
+		return Account{}, fmt.Errorf("unknown account status %q", status)
+	}
+
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET status = $2
+		WHERE account_id = $1 AND status = ANY($3) AND `+accountScopeCond(4)+`
+		RETURNING `+accountColumns, accountID, status, from, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return Account{}, fmt.Errorf("update account status: %w", err)
+		}
+		// Either the account does not exist or its current status forbids the move
+		if _, err := s.GetAccount(ctx, accountID); err != nil {
+			return Account{}, err
+		}
+		return Account{}, ErrInvalidTransition
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
+
+// SetAccountOwner hands the account over to owner, the only non-admin caller
+// allowed to use it from then on; an empty owner leaves it to admins.
+func (s *Store) SetAccountOwner(ctx context.Context, accountID int64, owner string) (_ Account, err error) {
+	ctx, span := startSpan(ctx, "SetAccountOwner", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	acc, err := scanAccount(s.pool.QueryRow(ctx, `UPDATE accounts SET owner_id = $2
+		WHERE account_id = $1 AND `+accountScopeCond(3)+`
+		RETURNING `+accountColumns, accountID, owner, tenantArg(ctx), ownerArg(ctx)))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return decimal.Zero, ErrAccountNotFound
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, fmt.Errorf("set account owner: %w", err)
+	}
+	s.invalidateAccounts(ctx, accountID)
+	return acc, nil
+}
+
+// lockTransferAccountsSQL locks the accounts of a transfer ($1, $2) in ascending
+// id order, so that concurrent transfers cannot deadlock.
+const lockTransferAccountsSQL = `SELECT account_id FROM accounts WHERE account_id IN ($1::bigint, $2::bigint) ORDER BY account_id FOR UPDATE`
+
+// transferSQL performs a transfer on accounts locked by lockTransferAccountsSQL:
+// it checks them, moves the amount if the checks pass, and logs the attempt as
+// a succeeded or failed transactions row, which it returns. The reasons of
+// failed rows are the keys of transferFailures. Running it after the lock is
+// granted gives it a snapshot that includes every transfer committed before,
+// which the daily limit check relies on. A missing account is reported as the
+// source or destination one, which transferOutcome tells apart.
+//
+// $1 source, $2 destination, $3 amount, $4 active account status,
+// $5 succeeded and $6 failed transaction status, $7 default per-transfer and
+// $8 default daily limit (NULL for none), $9 reference, $10 purpose code,
+// $11 the caller's tenant (NULL if unscoped), $12 whether cross-tenant
+// transfers are allowed, $13 the caller's owner (NULL if unscoped), $14
+// whether a rule blocks the transfer, $15 the public id, $16 the current
+// time, $17 the initiator, $18 channel and $19 request id, and $20 the fee
+// charged on top of the amount and $21 the fee account credited with it (NULL
+// for none), which must be locked as well, and $22 whether unverified accounts
+// are receive only and $23 their cap per transfer (NULL for none).
+const transferSQL = `WITH accs AS (
+		SELECT account_id, balance - held_balance AS available, currency, status, kyc_status, tenant_id, owner_id,
+			COALESCE(max_transfer_amount, $7::numeric) AS max_amount,
+			COALESCE(daily_transfer_limit, $8::numeric) AS daily_limit, min_balance
+		FROM accounts
+		WHERE account_id IN ($1::bigint, $2::bigint)
+	), src AS (
+		SELECT * FROM accs WHERE account_id = $1
+	), dst AS (
+		SELECT * FROM accs WHERE account_id = $2
+	), checked AS (
+		SELECT
+			CASE
+				WHEN NOT EXISTS (SELECT 1 FROM src)
+					OR $11::text IS NOT NULL AND (SELECT tenant_id FROM src) <> $11::text
+					OR $13::text IS NOT NULL AND (SELECT owner_id FROM src) <> $13::text THEN 'source account not found'
+				WHEN NOT EXISTS (SELECT 1 FROM dst) THEN 'destination account not found'
+				WHEN (SELECT tenant_id FROM src) <> (SELECT tenant_id FROM dst) AND NOT $12::boolean THEN 'cross-tenant transfer'
+				WHEN (SELECT status FROM src) <> $4::text OR (SELECT status FROM dst) <> $4::text THEN 'account inactive'
+				WHEN (SELECT currency FROM src) <> (SELECT currency FROM dst) THEN 'currency mismatch'
+				WHEN 'blocked' IN ((SELECT kyc_status FROM src), (SELECT kyc_status FROM dst))
+					OR $22::boolean AND (SELECT kyc_status FROM src) = 'unverified' THEN 'kyc restricted'
+				WHEN ` + counterpartyDeniedCond + ` THEN 'counterparty not allowed'
+				WHEN $14::boolean THEN 'transfer blocked by rule'
+				WHEN $21::bigint IS NOT NULL AND NOT EXISTS (SELECT 1 FROM accounts
+					WHERE account_id = $21 AND status = $4 AND currency = (SELECT currency FROM src)) THEN 'fee account unavailable'
+				WHEN (SELECT kyc_status FROM src) = 'unverified' AND $3::numeric > $23::numeric THEN 'kyc limit exceeded'
+				WHEN $3::numeric > (SELECT max_amount FROM src) THEN 'per-transfer limit exceeded'
+				WHEN (SELECT daily_limit FROM src) IS NOT NULL
+					AND $3::numeric + (SELECT COALESCE(SUM(amount), 0) FROM transactions
+						WHERE source_account_id = $1 AND status = $5 AND kind = 'transfer' AND created_at > $16::timestamptz - interval '24 hours')
+						> (SELECT daily_limit FROM src) THEN 'daily transfer limit exceeded'
+				WHEN (SELECT available FROM src) < $3::numeric + $20::numeric THEN 'insufficient funds'
+				WHEN (SELECT available FROM src) - $3::numeric - $20::numeric < (SELECT min_balance FROM src) THEN 'minimum balance breached'
+			END AS reason,
+			COALESCE((SELECT currency FROM src), 'USD') AS currency
+	), moved AS (
+		UPDATE accounts
+		SET balance = balance + CASE WHEN account_id = $1 THEN -$3::numeric - $20::numeric ELSE 0 END
+			+ CASE WHEN account_id = $2 THEN $3::numeric ELSE 0 END
+			+ CASE WHEN account_id = $21 THEN $20::numeric ELSE 0 END
+		WHERE account_id IN ($1, $2, $21) AND $1 <> $2 AND (SELECT reason FROM checked) IS NULL
+	)
+	INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, error_message, reference, purpose_code, public_id, created_at, settled_at,
+		initiated_by, channel, request_id)
+	SELECT $1, $2, $3, currency, CASE WHEN reason IS NULL THEN $5::text ELSE $6::text END, reason, $9::text, $10::text, $15::uuid, $16::timestamptz, $16::timestamptz,
+		$17::text, $18::text, $19::text
+	FROM checked
+	RETURNING ` + transactionColumns
+
+// transferFailures maps the reasons recorded by transferSQL to the errors
+// Transfer returns, but for missing accounts (see transferOutcome)
+var transferFailures = map[string]error{
+	"cross-tenant transfer":         ErrCrossTenant,
+	"account inactive":              ErrAccountInactive,
+	"currency mismatch":             ErrCurrencyMismatch,
+	"counterparty not allowed":      ErrCounterpartyNotAllowed,
+	"kyc restricted":                ErrKYCRestricted,
+	"transfer blocked by rule":      ErrTransferBlocked,
+	"fee account unavailable":       ErrFeeAccountUnavailable,
+	"kyc limit exceeded":            &LimitError{Limit: LimitKYC},
+	"per-transfer limit exceeded":   &LimitError{Limit: LimitPerTransfer},
+	"daily transfer limit exceeded": &LimitError{Limit: LimitDaily},
+	"insufficient funds":            ErrInsufficientFunds,
+	"minimum balance breached":      &LimitError{Limit: LimitMinBalance},
+}
+
+// batchSender is implemented by *pgxpool.Pool and pgx.Tx
+type batchSender interface {
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// runTransfer sends lockTransferAccountsSQL and transferSQL to db in a single
+// round trip. Outside a transaction, a batch runs in an implicit one. It does
+// not check the rules; see runCheckedTransfer. With alerts, evaluateAlertsSQL
+// is sent along for callers that do not go through finishTransfer; the alerts
+// write no events, which only stores without an outbox can do without.
+func runTransfer(ctx context.Context, db batchSender, args []any, alerts bool) (_ Transaction, err error) {
+	b := &pgx.Batch{}
+	b.Queue(lockTransferAccountsSQL, args[0], args[1])
+	b.Queue(transferSQL, args...)
+	if alerts {
+		// The transfer is found by its public id and creation time, $15 and $16
+		b.Queue(evaluateAlertsSQL, args[14], args[15], args[15])
+	}
+	br := db.SendBatch(ctx, b)
+	defer func() {
+		if cerr := br.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("transfer: %w", cerr)
+		}
+	}()
+
+	if _, err := br.Exec(); err != nil {
+		return Transaction{}, fmt.Errorf("lock accounts: %w", err)
+	}
+	t, err := scanTransaction(br.QueryRow())
+	if err != nil {
+		return Transaction{}, fmt.Errorf("transfer: %w", err)
+	}
+	if alerts {
+		if _, err := br.Exec(); err != nil {
+			return Transaction{}, fmt.Errorf("evaluate alerts: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// runCheckedTransfer is runTransfer in tx, with the rules checked and the fee
+// worked out once the accounts are locked: a transfer matching a block rule is
+// recorded as failed, one matching a flag rule is queued for review if it
+// succeeds, and the fee of a succeeded one is recorded. It returns the fee
+// account credited, if any, along with the transfer.
+func (s *Store) runCheckedTransfer(ctx context.Context, tx pgx.Tx, srcID, dstID int64, amount decimal.Decimal, details TransferDetails) (Transaction, int64, error) {
+	fee, err := s.lockTransferAccounts(ctx, tx, srcID, dstID, amount)
+	if err != nil {
+		return Transaction{}, 0, err
+	}
+	rule, err := s.newRuleChecker(tx).check(ctx, srcID, dstID, amount)
+	if err != nil {
+		return Transaction{}, 0, err
+	}
+	if rule != nil && rule.Action == RuleRequireApproval {
+		t, err := s.awaitApproval(ctx, tx, s.transferArgs(ctx, srcID, dstID, amount, details, false, fee), rule)
+		return t, 0, err
+	}
+	blocked := rule != nil && rule.Action == RuleBlock
+	t, err := scanTransaction(tx.QueryRow(ctx, transferSQL, s.transferArgs(ctx, srcID, dstID, amount, details, blocked, fee)...))
+	if err != nil {
+		return Transaction{}, 0, fmt.Errorf("transfer: %w", err)
+	}
+	if rule != nil && rule.Action == RuleFlag && t.Status == StatusSucceeded {
+		if err := flagTransfer(ctx, tx, t, rule); err != nil {
+			return Transaction{}, 0, err
 		}
-		return decimal.Zero, fmt.Errorf("get account: %w", err)
 	}
-	d, err := decimal.NewFromString(balStr)
+	if err := s.recordFee(ctx, tx, t, fee); err != nil {
+		return Transaction{}, 0, err
+	}
+	return t, fee.AccountID, nil
+}
+
+// awaitApproval records the transfer of args, which matched the
+// require_approval rule r, as pending, queued for review: ExecutePendingTransfers
+// executes it once the review is approved. A transfer that would fail anyway
+// is recorded as failed instead.
+func (s *Store) awaitApproval(ctx context.Context, tx pgx.Tx, args []any, r *Rule) (Transaction, error) {
+	// The transfer is tried in a savepoint rolled back right after
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return Transaction{}, fmt.Errorf("begin savepoint: %w", err)
+	}
+	t, err := scanTransaction(sp.QueryRow(ctx, transferSQL, args...))
 	if err != nil {
-		return decimal.Zero, fmt.Errorf("parse balance: %w", err)
+		_ = sp.Rollback(ctx)
+		return Transaction{}, fmt.Errorf("transfer: %w", err)
+	}
+	if err := sp.Rollback(ctx); err != nil {
+		return Transaction{}, fmt.Errorf("rollback savepoint: %w", err)
 	}
-	return d, nil
+	if t.Status == StatusFailed {
+		if t, err = scanTransaction(tx.QueryRow(ctx, transferSQL, args...)); err != nil {
+			return Transaction{}, fmt.Errorf("transfer: %w", err)
+		}
+		return t, nil
+	}
+
+	t, err = scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reference, purpose_code, public_id, created_at,
+			initiated_by, channel, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+transactionColumns, t.SourceAccountID, t.DestinationAccountID, t.Amount, t.Currency, StatusPending, t.Reference, t.PurposeCode, t.UUID, t.CreatedAt,
+		t.InitiatedBy, t.Channel, t.RequestID))
+	if err != nil {
+		return Transaction{}, fmt.Errorf("record transfer awaiting approval: %w", err)
+	}
+	return t, flagTransfer(ctx, tx, t, r)
 }
 
-// Transfer performs an atomic transfer from srcID -> dstID of amount.
-func (s *Store) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal) error {
+// Transfer performs an atomic transfer from srcID -> dstID of amount, recorded
+// with details, and returns the ID of the recorded transactions row. A rejected transfer is
+// recorded as a failed row and reported with the matching error. A transfer
+// matching a require_approval rule is recorded pending and reported with
+// ErrAwaitingApproval. Serialization failures and deadlocks are retried (see
+// WithMaxRetries).
+func (s *Store) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details TransferDetails) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "Transfer",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
 	// having some validations upfront
 	if amount.LessThanOrEqual(decimal.Zero) {
-		return fmt.Errorf("amount must be positive")
+		return 0, fmt.Errorf("amount must be positive")
+	}
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return 0, err
+	}
+	details, err = s.withTransactionID(details)
+	if err != nil {
+		return 0, err
 	}
 
-	// No-op when transferring to the same account. Prevents double-lock/update bug.
-	if srcID == dstID {
-		return nil
+	// The batch is atomic on its own; an explicit DB transaction is only
+	// needed to check the rules or work out the fee in between, or to write
+	// the outbox event or the notification along with it
+	checked := len(s.loadedRules()) > 0 || len(s.loadedFeePolicies()) > 0
+	var t Transaction
+	var feeAccountID int64
+	err = s.retry(ctx, "Transfer", func() (err error) {
+		if s.outbox || s.notify || checked {
+			return pgx.BeginFunc(ctx, s.pool, func(tx pgx.Tx) error {
+				if checked {
+					t, feeAccountID, err = s.runCheckedTransfer(ctx, tx, srcID, dstID, amount, details)
+				} else {
+					t, err = runTransfer(ctx, tx, s.transferArgs(ctx, srcID, dstID, amount, details, false, feeCharge{}), false)
+				}
+				if err != nil || t.Status == StatusPending {
+					return err
+				}
+				if err := s.finishTransfer(ctx, tx, t); err != nil {
+					return err
+				}
+				return s.notifyTransfer(ctx, tx, t)
+			})
+		}
+		t, err = runTransfer(ctx, s.pool, s.transferArgs(ctx, srcID, dstID, amount, details, false, feeCharge{}), true)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if t.Status == StatusPending {
+		return t.ID, ErrAwaitingApproval
+	}
+	if err := transferOutcome(t); err != nil {
+		return 0, err
+	}
+	if feeAccountID != 0 {
+		s.invalidateAccounts(ctx, feeAccountID)
+	}
+	s.invalidateAccounts(ctx, srcID, dstID)
+	return t.ID, nil
+}
+
+// DryRunTransfer runs every check of Transfer, including balance, limits and rules,
+// in a DB transaction that is rolled back, and returns the error Transfer
+// would return. Nothing is recorded.
+func (s *Store) DryRunTransfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details TransferDetails) (err error) {
+	ctx, span := startSpan(ctx, "DryRunTransfer",
+		attribute.Int64("transfer.source_account_id", srcID),
+		attribute.Int64("transfer.destination_account_id", dstID),
+		attribute.String("transfer.amount", amount.String()),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if amount.LessThanOrEqual(decimal.Zero) {
+		return fmt.Errorf("amount must be positive")
+	}
+	if err := s.checkSameAccount(srcID, dstID); err != nil {
+		return err
+	}
+	details, err = s.withTransactionID(details)
+	if err != nil {
+		return err
 	}
 
-	// Begin a DB transaction
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("begin tx: %w", err)
 	}
-	// Ensure rollback if not committed
 	defer func() {
 		_ = tx.Rollback(ctx)
 	}()
 
-	// To avoid deadlocks, locking rows in ascending order of account_id.
-	ids := []int64{srcID, dstID}
-	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	t, _, err := s.runCheckedTransfer(ctx, tx, srcID, dstID, amount, details)
+	if err != nil {
+		return err
+	}
+	if t.Status == StatusPending {
+		return ErrAwaitingApproval
+	}
+	return transferOutcome(t)
+}
 
-	// Fetch balances FOR UPDATE in deterministic order
-	balances := make(map[int64]decimal.Decimal, 2)
-	for _, id := range ids {
-		var balStr string
-		row := tx.QueryRow(ctx, `SELECT balance::text FROM accounts WHERE account_id = $1 FOR UPDATE`, id)
-		if err := row.Scan(&balStr); err != nil {
-			if errors.Is(err, pgx.ErrNoRows) {
-				_, _ = tx.Exec(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, status, error_message) VALUES ($1,$2,$3,$4,$5)`,
-					srcID, dstID, amount.String(), "failed", "account not found")
-				return ErrAccountNotFound
-			}
-			return fmt.Errorf("select balance for account %d: %w", id, err)
-		}
-		dec, err := decimal.NewFromString(balStr)
-		if err != nil {
-			return fmt.Errorf("parse balance for account %d: %w", id, err)
-		}
-		balances[id] = dec
+// transferArgs returns the arguments of transferSQL for a transfer made with
+// ctx, blocked by a rule or not, and charged fee.
+func (s *Store) transferArgs(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details TransferDetails, blocked bool, fee feeCharge) []any {
+	limits := s.DefaultLimits()
+	var feeAccountID any
+	if fee.AccountID != 0 {
+		feeAccountID = fee.AccountID
 	}
+	return []any{srcID, dstID, amount, AccountActive, StatusSucceeded, StatusFailed, limits.MaxAmount, limits.Daily,
+		details.Reference, details.PurposeCode, tenantArg(ctx), s.crossTenant, ownerArg(ctx), blocked, details.UUID, s.clock.Now(),
+		details.InitiatedBy, details.Channel, details.RequestID, fee.Amount, feeAccountID, s.kyc.UnverifiedReceiveOnly, s.kyc.UnverifiedMaxAmount}
+}
 
-	// Map balances to source/dest
-	srcBal, ok1 := balances[srcID]
-	dstBal, ok2 := balances[dstID]
-	if !ok1 || !ok2 {
-		_, _ = tx.Exec(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, status, error_message) VALUES ($1,$2,$3,$4,$5)`,
-			srcID, dstID, amount.String(), "failed", "account not found")
-		return ErrAccountNotFound
+// transferOutcome returns the error a transfer recorded as t fails with, if any.
+func transferOutcome(t Transaction) error {
+	if t.Status != StatusFailed {
+		return nil
+	}
+	switch t.ErrorMessage {
+	case PartySource + " account not found":
+		return &AccountNotFoundError{AccountID: t.SourceAccountID, Party: PartySource}
+	case PartyDestination + " account not found":
+		return &AccountNotFoundError{AccountID: t.DestinationAccountID, Party: PartyDestination}
 	}
+	if ferr, ok := transferFailures[t.ErrorMessage]; ok {
+		return ferr
+	}
+	return fmt.Errorf("transfer failed: %s", t.ErrorMessage)
+}
 
-	// Check sufficient funds
-	if srcBal.LessThan(amount) {
-		_, _ = tx.Exec(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, status, error_message) VALUES ($1,$2,$3,$4,$5)`,
-			srcID, dstID, amount.String(), "failed", "insufficient funds")
-		return ErrInsufficientFunds
+// accountScopeCond restricts accounts to the tenant in parameter $n and the
+// owner in $n+1; NULL matches any.
+func accountScopeCond(n int) string {
+	return fmt.Sprintf(`($%[1]d::text IS NULL OR tenant_id = $%[1]d) AND ($%[2]d::text IS NULL OR owner_id = $%[2]d)`, n, n+1)
+}
+
+// transactionScopeCond restricts transactions to those touching an account in
+// the scope of parameters $n and $n+1 (see accountScopeCond). It is only meant
+// for scoped callers: unscoped ones also see transfers that failed because
+// neither account exists.
+func transactionScopeCond(n int) string {
+	return `EXISTS (SELECT 1 FROM accounts a
+		WHERE a.account_id IN (transactions.source_account_id, transactions.destination_account_id)
+			AND ` + accountScopeCond(n) + `)`
+}
+
+// GetTransaction fetches a transaction by id. Scoped to a tenant or owner,
+// only transactions touching one of its accounts are found.
+func (s *Store) GetTransaction(ctx context.Context, id int64) (_ Transaction, err error) {
+	ctx, span := startSpan(ctx, "GetTransaction", attribute.Int64("transaction.id", id))
+	defer func() { endSpan(span, err) }()
+
+	t, err := scanTransaction(s.read.QueryRow(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE id = $1 AND ($2::text IS NULL AND $3::text IS NULL OR `+transactionScopeCond(2)+`)`, id, tenantArg(ctx), ownerArg(ctx)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Transaction{}, ErrTransactionNotFound
+		}
+		return Transaction{}, fmt.Errorf("get transaction: %w", err)
 	}
+	return t, nil
+}
+
+// accountExistsSQL checks that account $1 exists in the scope of tenant $2 and owner $3 (any if NULL)
+var accountExistsSQL = `SELECT EXISTS (SELECT 1 FROM accounts WHERE account_id = $1 AND ` + accountScopeCond(2) + `)`
 
-	newSrc := srcBal.Sub(amount)
-	newDst := dstBal.Add(amount)
+// ListTransactionsByAccount returns up to limit transactions where accountID was
+// the source or destination, newest first. A zero cursor starts from the newest
+// transaction; otherwise only transactions with an ID below cursor are returned.
+// The returned next cursor is zero when there are no further pages.
+func (s *Store) ListTransactionsByAccount(ctx context.Context, accountID, cursor int64, limit int) (_ []Transaction, _ int64, err error) {
+	ctx, span := startSpan(ctx, "ListTransactionsByAccount", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
 
-	// Update account balances
-	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, newSrc.String(), srcID); err != nil {
-		return fmt.Errorf("update src balance: %w", err)
+	var exists bool
+	if err := s.read.QueryRow(ctx, accountExistsSQL, accountID, tenantArg(ctx), ownerArg(ctx)).Scan(&exists); err != nil {
+		return nil, 0, fmt.Errorf("check account: %w", err)
 	}
-	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = $1 WHERE account_id = $2`, newDst.String(), dstID); err != nil {
-		return fmt.Errorf("update dst balance: %w", err)
+	if !exists {
+		return nil, 0, ErrAccountNotFound
 	}
 
-	// Insert succeeded transaction row
-	if _, err := tx.Exec(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, status) VALUES ($1,$2,$3,$4)`,
-		srcID, dstID, amount.String(), "succeeded"); err != nil {
-		return fmt.Errorf("insert transaction log: %w", err)
+	// Fetch one extra row to find out whether another page follows
+	rows, err := s.read.Query(ctx, `SELECT `+transactionColumns+` FROM transactions
+		WHERE (source_account_id = $1 OR destination_account_id = $1) AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`, accountID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transactions: %w", err)
 	}
 
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit: %w", err)
+	txs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Transaction, error) {
+		return scanTransaction(row)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list transactions: %w", err)
 	}
-	return nil
+
+	var next int64
+	if len(txs) > limit {
+		txs = txs[:limit]
+		next = txs[limit-1].ID
+	}
+	return txs, next, nil
 }
@@ -0,0 +1,58 @@
+package storetest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// Transfer is a transfer of a generated workload or of a ledger
+type Transfer struct {
+	Src, Dst int64
+	Amount   decimal.Decimal
+}
+
+// RandomTransfers draws n transfers between accounts 1 to accounts, with
+// amounts of up to maxAmount in cents. Some go from an account to itself or
+// to the unknown account accounts+1, to exercise the failure paths too.
+func RandomTransfers(r *rand.Rand, n, accounts int, maxAmount int64) []Transfer {
+	transfers := make([]Transfer, n)
+	for i := range transfers {
+		transfers[i] = Transfer{
+			Src:    1 + r.Int63n(int64(accounts)),
+			Dst:    1 + r.Int63n(int64(accounts)+1),
+			Amount: decimal.New(1+r.Int63n(maxAmount), -2),
+		}
+	}
+	return transfers
+}
+
+// CheckInvariants checks the balances left by a workload against the ledger:
+// the total is conserved, no balance is negative, and every account's balance
+// moved by the sum of the succeeded transfers in and out of it.
+func CheckInvariants(t testing.TB, initial, final map[int64]decimal.Decimal, succeeded []Transfer) {
+	t.Helper()
+	expected := make(map[int64]decimal.Decimal, len(initial))
+	totalBefore, totalAfter := decimal.Zero, decimal.Zero
+	for id, b := range initial {
+		expected[id] = b
+		totalBefore = totalBefore.Add(b)
+	}
+	for _, tr := range succeeded {
+		expected[tr.Src] = expected[tr.Src].Sub(tr.Amount)
+		expected[tr.Dst] = expected[tr.Dst].Add(tr.Amount)
+	}
+	for id, b := range final {
+		totalAfter = totalAfter.Add(b)
+		if b.IsNegative() {
+			t.Errorf("account %d: negative balance %s", id, b)
+		}
+		if !b.Equal(expected[id]) {
+			t.Errorf("account %d: balance %s does not match the ledger, which gives %s", id, b, expected[id])
+		}
+	}
+	if !totalAfter.Equal(totalBefore) {
+		t.Errorf("total balance changed from %s to %s", totalBefore, totalAfter)
+	}
+}
@@ -0,0 +1,81 @@
+// Package storetest provides the Postgres server of integration tests: the one
+// at POSTGRES_DSN if set, otherwise a throwaway container started with
+// testcontainers-go, so that the tests only need a Docker daemon. Migrations
+// are left to the caller (see store.Migrate).
+//
+// A package's integration tests share one server, torn down by Terminate:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		storetest.Terminate()
+//		os.Exit(code)
+//	}
+package storetest
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Image is the Postgres image started when POSTGRES_DSN is not set, the one
+// docker-compose.yml runs
+const Image = "postgres:15"
+
+var (
+	once      sync.Once
+	dsn       string
+	container *postgres.PostgresContainer
+	startErr  error
+)
+
+// PostgresDSN returns the DSN of the Postgres server, starting the container
+// on first use. It fails t if the container cannot be started.
+func PostgresDSN(t testing.TB) string {
+	t.Helper()
+	once.Do(start)
+	if startErr != nil {
+		t.Fatalf("start postgres: %v", startErr)
+	}
+	return dsn
+}
+
+func start() {
+	if dsn = os.Getenv("POSTGRES_DSN"); dsn != "" {
+		return
+	}
+	ctx := context.Background()
+	// Postgres restarts once after running the init scripts, so it is only
+	// ready on the second log line
+	container, startErr = postgres.Run(ctx, Image,
+		postgres.WithDatabase("transfers"),
+		postgres.WithUsername("test"),
+		postgres.WithPassword("test"),
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(time.Minute)),
+	)
+	if startErr != nil {
+		return
+	}
+	dsn, startErr = container.ConnectionString(ctx, "sslmode=disable")
+}
+
+// Terminate stops the container started by PostgresDSN, if any. Without it
+// the container is only removed by the testcontainers reaper once the test
+// binary exits.
+func Terminate() {
+	if container == nil {
+		return
+	}
+	if err := testcontainers.TerminateContainer(container); err != nil {
+		log.Printf("terminate postgres container: %v", err)
+	}
+}
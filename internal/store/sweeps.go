@@ -0,0 +1,335 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrSweepRuleNotFound is returned for an unknown sweep rule.
+	ErrSweepRuleNotFound = errors.New("sweep rule not found")
+	// ErrSweepRuleExists is returned by CreateSweepRule for an account that already has one.
+	ErrSweepRuleExists = errors.New("account already has a sweep rule")
+)
+
+// SweepRule is a row of the sweep_rules table. RunSweeps moves the available
+// balance of AccountID above Threshold to TargetAccountID.
+type SweepRule struct {
+	ID              int64
+	AccountID       int64
+	TargetAccountID int64
+	Threshold       decimal.Decimal
+	CreatedAt       time.Time
+}
+
+// SweepExecution is a row of the sweep_executions table: a sweep made by a
+// rule and the transaction that moved the excess.
+type SweepExecution struct {
+	ID              int64
+	RuleID          int64
+	AccountID       int64
+	TargetAccountID int64
+	Amount          decimal.Decimal
+	TransactionID   int64
+	TransactionUUID uuid.UUID // the public id of the transaction
+	ExecutedAt      time.Time
+}
+
+// sweepRuleColumns is the select list matching scanSweepRule
+const sweepRuleColumns = `id, account_id, target_account_id, threshold, created_at`
+
+// scanSweepRule scans a row selected with sweepRuleColumns.
+func scanSweepRule(row pgx.Row) (SweepRule, error) {
+	var r SweepRule
+	if err := row.Scan(&r.ID, &r.AccountID, &r.TargetAccountID, &r.Threshold, &r.CreatedAt); err != nil {
+		return SweepRule{}, err
+	}
+	return r, nil
+}
+
+// checkSweepAccounts returns the error a sweep from accountID to targetID
+// fails with: an AccountNotFoundError if either does not exist, the account
+// being the source, ErrSameAccount if they are the same, ErrCurrencyMismatch
+// if their currencies differ, and ErrCrossTenant if their tenants differ and
+// the store does not allow it.
+func (s *Store) checkSweepAccounts(ctx context.Context, accountID, targetID int64) error {
+	if accountID == targetID {
+		return ErrSameAccount
+	}
+	rows, err := s.pool.Query(ctx, `SELECT `+accountColumns+` FROM accounts WHERE account_id = ANY($1)`, []int64{accountID, targetID})
+	if err != nil {
+		return fmt.Errorf("read accounts: %w", err)
+	}
+	accs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Account, error) {
+		return scanAccount(row)
+	})
+	if err != nil {
+		return fmt.Errorf("read accounts: %w", err)
+	}
+	var acc, target Account
+	for _, a := range accs {
+		if a.ID == accountID {
+			acc = a
+		} else {
+			target = a
+		}
+	}
+	if err := CheckTransferAccounts(accountID, targetID, acc.ID != 0, target.ID != 0); err != nil {
+		return err
+	}
+	if acc.Currency != target.Currency {
+		return ErrCurrencyMismatch
+	}
+	if acc.Tenant != target.Tenant && !s.crossTenant {
+		return ErrCrossTenant
+	}
+	return nil
+}
+
+// CreateSweepRule stores r. The accounts must exist, else an
+// AccountNotFoundError is returned, be in the same currency and, unless
+// cross-tenant transfers are allowed, in the same tenant. It returns
+// ErrSweepRuleExists if the account already has a rule.
+func (s *Store) CreateSweepRule(ctx context.Context, r SweepRule) (_ SweepRule, err error) {
+	ctx, span := startSpan(ctx, "CreateSweepRule",
+		attribute.Int64("account.id", r.AccountID),
+		attribute.Int64("sweep.target_account_id", r.TargetAccountID),
+	)
+	defer func() { endSpan(span, err) }()
+
+	if err := s.checkSweepAccounts(ctx, r.AccountID, r.TargetAccountID); err != nil {
+		return SweepRule{}, err
+	}
+	r, err = scanSweepRule(s.pool.QueryRow(ctx, `INSERT INTO sweep_rules (account_id, target_account_id, threshold)
+		VALUES ($1, $2, $3) RETURNING `+sweepRuleColumns, r.AccountID, r.TargetAccountID, r.Threshold))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return SweepRule{}, ErrSweepRuleExists
+		}
+		return SweepRule{}, fmt.Errorf("create sweep rule: %w", err)
+	}
+	return r, nil
+}
+
+// UpdateSweepRule changes the target account and threshold of a sweep rule,
+// checking the target like CreateSweepRule does. It returns
+// ErrSweepRuleNotFound for an unknown rule.
+func (s *Store) UpdateSweepRule(ctx context.Context, id, targetID int64, threshold decimal.Decimal) (_ SweepRule, err error) {
+	ctx, span := startSpan(ctx, "UpdateSweepRule", attribute.Int64("sweep.rule_id", id))
+	defer func() { endSpan(span, err) }()
+
+	var accountID int64
+	if err := s.pool.QueryRow(ctx, `SELECT account_id FROM sweep_rules WHERE id = $1`, id).Scan(&accountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SweepRule{}, ErrSweepRuleNotFound
+		}
+		return SweepRule{}, fmt.Errorf("read sweep rule: %w", err)
+	}
+	if err := s.checkSweepAccounts(ctx, accountID, targetID); err != nil {
+		return SweepRule{}, err
+	}
+	r, err := scanSweepRule(s.pool.QueryRow(ctx, `UPDATE sweep_rules SET target_account_id = $2, threshold = $3
+		WHERE id = $1 RETURNING `+sweepRuleColumns, id, targetID, threshold))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return SweepRule{}, ErrSweepRuleNotFound
+		}
+		return SweepRule{}, fmt.Errorf("update sweep rule: %w", err)
+	}
+	return r, nil
+}
+
+// ListSweepRules returns all sweep rules by id.
+func (s *Store) ListSweepRules(ctx context.Context) (_ []SweepRule, err error) {
+	ctx, span := startSpan(ctx, "ListSweepRules")
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT `+sweepRuleColumns+` FROM sweep_rules ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list sweep rules: %w", err)
+	}
+	rules, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SweepRule, error) {
+		return scanSweepRule(row)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list sweep rules: %w", err)
+	}
+	return rules, nil
+}
+
+// DeleteSweepRule deletes a sweep rule with its execution history; the
+// transactions of its sweeps are kept.
+func (s *Store) DeleteSweepRule(ctx context.Context, id int64) (err error) {
+	ctx, span := startSpan(ctx, "DeleteSweepRule", attribute.Int64("sweep.rule_id", id))
+	defer func() { endSpan(span, err) }()
+
+	tag, err := s.pool.Exec(ctx, `DELETE FROM sweep_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("delete sweep rule: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSweepRuleNotFound
+	}
+	return nil
+}
+
+// ListSweepExecutions returns up to limit sweeps of a rule by id, after cursor
+// (exclusive), and the cursor of the next page, 0 on the last. It returns
+// ErrSweepRuleNotFound for an unknown rule.
+func (s *Store) ListSweepExecutions(ctx context.Context, ruleID, cursor int64, limit int) (_ []SweepExecution, _ int64, err error) {
+	ctx, span := startSpan(ctx, "ListSweepExecutions", attribute.Int64("sweep.rule_id", ruleID))
+	defer func() { endSpan(span, err) }()
+
+	var exists bool
+	if err := s.read.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM sweep_rules WHERE id = $1)`, ruleID).Scan(&exists); err != nil {
+		return nil, 0, fmt.Errorf("read sweep rule: %w", err)
+	}
+	if !exists {
+		return nil, 0, ErrSweepRuleNotFound
+	}
+	rows, err := s.read.Query(ctx, `SELECT id, rule_id, account_id, target_account_id, amount, transaction_id, transaction_public_id, executed_at
+		FROM sweep_executions WHERE rule_id = $1 AND id > $2 ORDER BY id LIMIT $3`, ruleID, cursor, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list sweep executions: %w", err)
+	}
+	execs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SweepExecution, error) {
+		var e SweepExecution
+		err := row.Scan(&e.ID, &e.RuleID, &e.AccountID, &e.TargetAccountID, &e.Amount, &e.TransactionID, &e.TransactionUUID, &e.ExecutedAt)
+		return e, err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("list sweep executions: %w", err)
+	}
+
+	var next int64
+	if len(execs) > limit {
+		execs = execs[:limit]
+		next = execs[limit-1].ID
+	}
+	return execs, next, nil
+}
+
+// RunSweeps makes the sweeps due and returns how many were made. A rule is
+// due when both its accounts are active, their KYC statuses and counterparty
+// allowlists allow the transfer, and the available balance of its
+// account is above its threshold and, if higher, the account's min balance;
+// the excess is moved to the target as a succeeded transfer, exempt from
+// limits, fraud rules and fees, and recorded in the rule's history. Each sweep
+// runs in a DB transaction of its own; one that fails is retried on the next
+// run and its error returned along with the others once the remaining rules
+// are evaluated.
+func (s *Store) RunSweeps(ctx context.Context) (n int, err error) {
+	ctx, span := startSpan(ctx, "RunSweeps")
+	defer func() {
+		span.SetAttributes(attribute.Int("sweep.executed", n))
+		endSpan(span, err)
+	}()
+
+	rows, err := s.pool.Query(ctx, `SELECT r.id FROM sweep_rules r
+			JOIN accounts a ON a.account_id = r.account_id
+			JOIN accounts t ON t.account_id = r.target_account_id
+		WHERE a.status = $1 AND t.status = $1 AND a.balance - a.held_balance > GREATEST(r.threshold, COALESCE(a.min_balance, 0))
+		ORDER BY r.id`, AccountActive)
+	if err != nil {
+		return 0, fmt.Errorf("list due sweep rules: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("list due sweep rules: %w", err)
+	}
+
+	var errs []error
+	for _, id := range ids {
+		ok, err := s.sweep(ctx, id)
+		if err != nil {
+			if ctx.Err() != nil {
+				return n, err
+			}
+			errs = append(errs, fmt.Errorf("sweep rule %d: %w", id, err))
+			continue
+		}
+		if ok {
+			n++
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// sweep makes the sweep of rule id if it is still due once its accounts are
+// locked. ok is false if it no longer was.
+func (s *Store) sweep(ctx context.Context, id int64) (ok bool, err error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	r, err := scanSweepRule(tx.QueryRow(ctx, `SELECT `+sweepRuleColumns+` FROM sweep_rules WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("read sweep rule: %w", err)
+	}
+	accs, err := lockExistingAccounts(ctx, tx, []int64{r.AccountID, r.TargetAccountID})
+	if err != nil {
+		return false, err
+	}
+	acc, target := accs[r.AccountID], accs[r.TargetAccountID]
+	floor := r.Threshold
+	if acc.MinBalance.Valid && acc.MinBalance.Decimal.GreaterThan(floor) {
+		floor = acc.MinBalance.Decimal
+	}
+	excess := acc.AvailableBalance.Sub(floor)
+	if acc.Status != AccountActive || target.Status != AccountActive || !excess.IsPositive() || s.checkKYC(acc, target) != nil {
+		return false, nil
+	}
+	if acc.Currency != target.Currency {
+		return false, ErrCurrencyMismatch
+	}
+	if err := checkCounterparties(ctx, tx, acc.ID, target.ID); err != nil {
+		if errors.Is(err, ErrCounterpartyNotAllowed) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	uid, err := s.NewTransactionID()
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE accounts SET balance = balance + CASE WHEN account_id = $2 THEN -$1::numeric ELSE $1::numeric END
+		WHERE account_id IN ($2, $3)`, excess, r.AccountID, r.TargetAccountID); err != nil {
+		return false, fmt.Errorf("update balances: %w", err)
+	}
+	now := s.clock.Now()
+	t, err := scanTransaction(tx.QueryRow(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount, currency, status, reference, public_id, created_at, settled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		RETURNING `+transactionColumns, r.AccountID, r.TargetAccountID, excess, acc.Currency, StatusSucceeded,
+		fmt.Sprintf("sweep rule %d", r.ID), uid, now))
+	if err != nil {
+		return false, fmt.Errorf("insert sweep: %w", err)
+	}
+	if err := s.finishTransfer(ctx, tx, t); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO sweep_executions (rule_id, account_id, target_account_id, amount, transaction_id, transaction_public_id, executed_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`, r.ID, r.AccountID, r.TargetAccountID, excess, t.ID, t.UUID, now); err != nil {
+		return false, fmt.Errorf("record sweep: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	s.invalidateAccounts(ctx, r.AccountID, r.TargetAccountID)
+	return true, nil
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// ErrNestedWallet is returned by CreateWallet when the parent is itself a wallet
+var ErrNestedWallet = errors.New("wallets cannot have wallets")
+
+// WalletRef names the wallet of an account
+type WalletRef struct {
+	ParentID int64
+	Wallet   string
+}
+
+// Wallets is an account with its wallets, as returned by Store.Wallets.
+// Balance and AvailableBalance add up the account's and its wallets'.
+type Wallets struct {
+	Account          Account
+	Wallets          []Account
+	Balance          decimal.Decimal
+	AvailableBalance decimal.Decimal
+}
+
+// SumWallets returns the account and wallets of accs, the parent being the
+// account with id parentID, with their balances added up.
+func SumWallets(parentID int64, accs []Account) Wallets {
+	w := Wallets{Wallets: []Account{}}
+	for _, acc := range accs {
+		if acc.ID == parentID {
+			w.Account = acc
+		} else {
+			w.Wallets = append(w.Wallets, acc)
+		}
+		w.Balance = w.Balance.Add(acc.Balance)
+		w.AvailableBalance = w.AvailableBalance.Add(acc.AvailableBalance)
+	}
+	return w
+}
+
+// CreateWallet creates the wallet of parentID labelled wallet, an account of
+// its own in the currency, tenant and owner of the parent, and returns it. If
+// accountID is 0 one is assigned. It returns ErrAccountExists if the parent
+// already has the wallet or accountID is taken, ErrAccountNotFound if the
+// parent does not exist, ErrNestedWallet if it is a wallet, and
+// ErrAccountInactive unless it is active.
+func (s *Store) CreateWallet(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta AccountMetadata) (acc Account, err error) {
+	ctx, span := startSpan(ctx, "CreateWallet",
+		attribute.Int64("account.parent_id", parentID),
+		attribute.String("account.wallet", wallet),
+	)
+	defer func() {
+		span.SetAttributes(attribute.Int64("account.id", acc.ID))
+		endSpan(span, err)
+	}()
+
+	if accountID != 0 {
+		return s.createWallet(ctx, parentID, wallet, accountID, initial, meta)
+	}
+	for i := 0; i < maxAccountIDAttempts; i++ {
+		if err := s.pool.QueryRow(ctx, `SELECT nextval('accounts_account_id_seq')`).Scan(&accountID); err != nil {
+			return Account{}, fmt.Errorf("assign account id: %w", err)
+		}
+		acc, err = s.createWallet(ctx, parentID, wallet, accountID, initial, meta)
+		if !errors.Is(err, errAccountIDTaken) {
+			return acc, err
+		}
+	}
+	return Account{}, fmt.Errorf("assign account id: %d ids in a row were taken", maxAccountIDAttempts)
+}
+
+// createWallet inserts the wallet and its AccountCreated event, the parent
+// locked so that it cannot be closed meanwhile.
+func (s *Store) createWallet(ctx context.Context, parentID int64, wallet string, accountID int64, initial decimal.Decimal, meta AccountMetadata) (_ Account, err error) {
+	tags := meta.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return Account{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	accs, err := lockExistingAccounts(ctx, tx, []int64{parentID})
+	if err != nil {
+		return Account{}, err
+	}
+	parent, ok := accs[parentID]
+	switch {
+	case !ok || !inScope(ctx, parent):
+		return Account{}, ErrAccountNotFound
+	case parent.ParentID != 0:
+		return Account{}, ErrNestedWallet
+	case parent.Status != AccountActive:
+		return Account{}, ErrAccountInactive
+	}
+
+	acc, err := scanAccount(tx.QueryRow(ctx, `INSERT INTO accounts (account_id, balance, initial_balance, currency, tenant_id, owner_id,
+			parent_account_id, wallet, display_name, owner_ref, tags, created_at)
+		VALUES ($1, $2, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+accountColumns,
+		accountID, initial, parent.Currency, parent.Tenant, parent.OwnerID, parentID, wallet, meta.DisplayName, meta.OwnerRef, tags, s.clock.Now()))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation && pgErr.ConstraintName == "accounts_pkey" {
+			return Account{}, errAccountIDTaken
+		}
+		if isUniqueViolation(err) {
+			return Account{}, ErrAccountExists
+		}
+		return Account{}, fmt.Errorf("create wallet: %w", err)
+	}
+	if err := s.writeEvent(ctx, tx, events.AccountCreated, accountID, events.AccountCreatedPayload{
+		AccountID:       accountID,
+		InitialBalance:  initial.String(),
+		Currency:        acc.Currency,
+		Tenant:          acc.Tenant,
+		OwnerID:         acc.OwnerID,
+		ParentAccountID: parentID,
+		Wallet:          wallet,
+	}); err != nil {
+		return Account{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return Account{}, fmt.Errorf("commit: %w", err)
+	}
+	return acc, nil
+}
+
+// WalletIDs returns the numeric ids of the given wallets, leaving out unknown
+// ones. Like with AccountIDs, wallets of other tenants than the one ctx is
+// scoped to are unknown, and the owner scope is left to the caller.
+func (s *Store) WalletIDs(ctx context.Context, refs []WalletRef) (_ map[WalletRef]int64, err error) {
+	ctx, span := startSpan(ctx, "WalletIDs", attribute.Int("account.count", len(refs)))
+	defer func() { endSpan(span, err) }()
+
+	parents := make([]int64, len(refs))
+	wallets := make([]string, len(refs))
+	for i, ref := range refs {
+		parents[i], wallets[i] = ref.ParentID, ref.Wallet
+	}
+	rows, err := s.read.Query(ctx, `SELECT a.parent_account_id, a.wallet, a.account_id
+		FROM accounts a JOIN unnest($1::bigint[], $2::text[]) AS w(parent_account_id, wallet)
+			ON a.parent_account_id = w.parent_account_id AND a.wallet = w.wallet
+		WHERE $3::text IS NULL OR a.tenant_id = $3`, parents, wallets, tenantArg(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("resolve wallets: %w", err)
+	}
+	defer rows.Close()
+	ids := make(map[WalletRef]int64, len(refs))
+	for rows.Next() {
+		var ref WalletRef
+		var id int64
+		if err := rows.Scan(&ref.ParentID, &ref.Wallet, &id); err != nil {
+			return nil, fmt.Errorf("scan wallet: %w", err)
+		}
+		ids[ref] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("resolve wallets: %w", err)
+	}
+	return ids, nil
+}
+
+// Wallets returns the account with its wallets in ascending id order and
+// their balances added up, all read in one statement so that the total is
+// consistent. It returns ErrAccountNotFound if the account does not exist or
+// is out of ctx's scope.
+func (s *Store) Wallets(ctx context.Context, accountID int64) (_ Wallets, err error) {
+	ctx, span := startSpan(ctx, "Wallets", attribute.Int64("account.id", accountID))
+	defer func() { endSpan(span, err) }()
+
+	rows, err := s.read.Query(ctx, `SELECT `+accountColumns+` FROM accounts
+		WHERE account_id = $1 OR parent_account_id = $1 ORDER BY account_id`, accountID)
+	if err != nil {
+		return Wallets{}, fmt.Errorf("list wallets: %w", err)
+	}
+	accs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Account, error) {
+		return scanAccount(row)
+	})
+	if err != nil {
+		return Wallets{}, fmt.Errorf("list wallets: %w", err)
+	}
+	w := SumWallets(accountID, accs)
+	if w.Account.ID == 0 || !inScope(ctx, w.Account) {
+		return Wallets{}, ErrAccountNotFound
+	}
+	return w, nil
+}
@@ -0,0 +1,31 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// RegisterBuildInfo reports the running build as the build_info gauge, always
+// 1, with the version, git revision and Go version as attributes, so that
+// dashboards can join other series on it to tell builds apart.
+func RegisterBuildInfo(version, revision, goVersion string) error {
+	attrs := metric.WithAttributes(
+		attribute.String("version", version),
+		attribute.String("revision", revision),
+		attribute.String("goversion", goVersion),
+	)
+	_, err := otel.Meter(DefaultServiceName).Int64ObservableGauge("build_info",
+		metric.WithDescription("Build of the running server; always 1"),
+		metric.WithInt64Callback(func(_ context.Context, o metric.Int64Observer) error {
+			o.Observe(1, attrs)
+			return nil
+		}))
+	if err != nil {
+		return fmt.Errorf("register build_info: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// MetricsEnabled reports whether an OTLP endpoint is configured through the
+// standard OTEL_EXPORTER_OTLP_ENDPOINT / OTEL_EXPORTER_OTLP_METRICS_ENDPOINT variables.
+func MetricsEnabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT") != ""
+}
+
+// SetupMetrics installs a global meter provider periodically exporting metrics
+// over OTLP/HTTP. As with SetupTracing, exporter settings are read from the
+// standard OTEL_* environment variables and the global no-op provider is left in
+// place when no endpoint is configured. The returned function flushes and stops
+// the exporter.
+func SetupMetrics(ctx context.Context) (func(context.Context) error, error) {
+	if !MetricsEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlpmetrichttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp metric exporter: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(DefaultServiceName)),
+		resource.Default(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build resource: %w", err)
+	}
+
+	mp := metric.NewMeterProvider(
+		metric.WithReader(metric.NewPeriodicReader(exporter)),
+		metric.WithResource(res),
+	)
+	otel.SetMeterProvider(mp)
+	return mp.Shutdown, nil
+}
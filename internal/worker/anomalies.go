@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"context"
+	"log"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// AnomalyStore is the storage analyzed for anomalies.
+type AnomalyStore interface {
+	DetectAnomalies(ctx context.Context, cfg store.AnomalyConfig) (int, error)
+}
+
+// Anomalies returns a run function for Every analyzing the transfers made
+// since the last run against the baselines of their source accounts, queueing
+// those that deviate beyond the z-scores of cfg for review.
+func Anomalies(s AnomalyStore, cfg store.AnomalyConfig) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := s.DetectAnomalies(ctx, cfg)
+		if n > 0 {
+			log.Printf("flagged %d anomalous transfers for review", n)
+		}
+		return err
+	}
+}
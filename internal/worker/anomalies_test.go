@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakeAnomalyStore records the configuration of each analysis
+type fakeAnomalyStore struct {
+	cfgs []store.AnomalyConfig
+	err  error
+}
+
+func (f *fakeAnomalyStore) DetectAnomalies(ctx context.Context, cfg store.AnomalyConfig) (int, error) {
+	f.cfgs = append(f.cfgs, cfg)
+	return 2, f.err
+}
+
+// TestAnomalies tests that each run analyzes with the configuration given
+// and reports its errors
+func TestAnomalies(t *testing.T) {
+	f := &fakeAnomalyStore{}
+	cfg := store.DefaultAnomalyConfig()
+	cfg.AmountZ = 4
+	if err := Anomalies(f, cfg)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.err = errors.New("baseline of transaction 7: canceled")
+	if err := Anomalies(f, cfg)(context.Background()); !errors.Is(err, f.err) {
+		t.Fatalf("expected the analysis error, got %v", err)
+	}
+	if len(f.cfgs) != 2 || f.cfgs[0] != cfg {
+		t.Fatalf("unexpected analyses %+v", f.cfgs)
+	}
+}
@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// TransferJobStore is the storage used to process bulk transfer jobs.
+type TransferJobStore interface {
+	ClaimTransferJob(ctx context.Context, lease time.Duration) (store.TransferJob, bool, error)
+	RenewTransferJob(ctx context.Context, jobID int64) error
+	PendingTransferJobRows(ctx context.Context, jobID int64) ([]store.TransferJobRow, error)
+	RecoverTransferJobRow(ctx context.Context, jobID int64, row store.TransferJobRow) (bool, error)
+	Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error)
+	RecordTransferJobRow(ctx context.Context, jobID int64, row int, txID int64, transferErr error) error
+	FinishTransferJob(ctx context.Context, jobID int64) error
+}
+
+// TransferJobs returns a run function for Every that processes queued transfer
+// jobs until none is left. The rows of a job are executed by concurrency
+// goroutines, each row as an independent transfer. A job is leased for lease
+// and renewed while it runs, so that a job left running by an interrupted
+// run is taken over once its lease lapses.
+func TransferJobs(s TransferJobStore, concurrency int, lease time.Duration) func(context.Context) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return func(ctx context.Context) error {
+		for {
+			job, ok, err := s.ClaimTransferJob(ctx, lease)
+			if err != nil || !ok {
+				return err
+			}
+			// Rows run in the scope the job was created in
+			jobCtx := store.WithOwner(store.WithTenant(ctx, job.Tenant), job.OwnerID)
+			if err := runTransferJob(jobCtx, s, job, concurrency, lease); err != nil {
+				return fmt.Errorf("transfer job %d: %w", job.ID, err)
+			}
+		}
+	}
+}
+
+// runTransferJob executes the pending rows of a claimed job, renewing its
+// lease, and marks it completed. The transfers are recorded as initiated by
+// the job's creator. Rows of a reclaimed job that were transferred by the
+// interrupted run are recorded with the outcome they had instead.
+func runTransferJob(ctx context.Context, s TransferJobStore, job store.TransferJob, concurrency int, lease time.Duration) error {
+	rows, err := s.PendingTransferJobRows(ctx, job.ID)
+	if err != nil {
+		return err
+	}
+
+	// A renewal that fails is retried on the next tick; should the lease
+	// lapse meanwhile, the rows' transaction ids keep a second run from
+	// paying them again
+	renewCtx, stopRenewing := context.WithCancel(ctx)
+	defer stopRenewing()
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				_ = s.RenewTransferJob(renewCtx, job.ID)
+			}
+		}
+	}()
+
+	queue := make(chan store.TransferJobRow)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fail := func(err error) {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+			for r := range queue {
+				if job.Reclaimed {
+					recovered, err := s.RecoverTransferJobRow(ctx, job.ID, r)
+					if err != nil {
+						fail(err)
+						continue
+					}
+					if recovered {
+						continue
+					}
+				}
+				r.InitiatedBy, r.Channel = job.InitiatedBy, store.ChannelBatch
+				txID, transferErr := s.Transfer(ctx, r.SourceAccountID, r.DestinationAccountID, r.Amount, r.TransferDetails)
+				if err := s.RecordTransferJobRow(ctx, job.ID, r.Row, txID, transferErr); err != nil {
+					fail(err)
+				}
+			}
+		}()
+	}
+	for _, r := range rows {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- r
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.FinishTransferJob(ctx, job.ID)
+}
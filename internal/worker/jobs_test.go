@@ -0,0 +1,129 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakeJobStore serves one queued job whose even rows fail. If reclaimed, the
+// job was left running by an earlier run which transferred the rows in done.
+type fakeJobStore struct {
+	mu          sync.Mutex
+	claimed     bool
+	reclaimed   bool
+	rows        []store.TransferJobRow
+	done        map[int]bool
+	transferred map[int64]int
+	recorded    map[int]error
+	finished    bool
+}
+
+func (f *fakeJobStore) ClaimTransferJob(ctx context.Context, lease time.Duration) (store.TransferJob, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.claimed {
+		return store.TransferJob{}, false, nil
+	}
+	f.claimed = true
+	return store.TransferJob{ID: 1, Status: store.JobRunning, Reclaimed: f.reclaimed, InitiatedBy: "ops"}, true, nil
+}
+
+func (f *fakeJobStore) RenewTransferJob(ctx context.Context, jobID int64) error {
+	return nil
+}
+
+func (f *fakeJobStore) PendingTransferJobRows(ctx context.Context, jobID int64) ([]store.TransferJobRow, error) {
+	return f.rows, nil
+}
+
+func (f *fakeJobStore) RecoverTransferJobRow(ctx context.Context, jobID int64, row store.TransferJobRow) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.done[row.Row] {
+		return false, nil
+	}
+	f.recorded[row.Row] = nil
+	return true, nil
+}
+
+func (f *fakeJobStore) Transfer(ctx context.Context, srcID, dstID int64, amount decimal.Decimal, details store.TransferDetails) (int64, error) {
+	f.mu.Lock()
+	f.transferred[srcID]++
+	f.mu.Unlock()
+	if details.InitiatedBy != "ops" {
+		return 0, errors.New("expected the transfer initiated by the job's creator")
+	}
+	if srcID%2 == 0 {
+		return 0, store.ErrInsufficientFunds
+	}
+	return srcID * 100, nil
+}
+
+func (f *fakeJobStore) RecordTransferJobRow(ctx context.Context, jobID int64, row int, txID int64, transferErr error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.recorded[row] = transferErr
+	return nil
+}
+
+func (f *fakeJobStore) FinishTransferJob(ctx context.Context, jobID int64) error {
+	f.finished = true
+	return nil
+}
+
+// TestTransferJobs tests that every row is executed, as initiated by the job's
+// creator, and recorded before the job completes
+func TestTransferJobs(t *testing.T) {
+	f := &fakeJobStore{transferred: make(map[int64]int), recorded: make(map[int]error)}
+	for i := 1; i <= 10; i++ {
+		f.rows = append(f.rows, store.TransferJobRow{JobID: 1, Row: i, TransferItem: store.TransferItem{
+			SourceAccountID: int64(i), DestinationAccountID: 100, Amount: decimal.NewFromInt(1),
+		}})
+	}
+
+	if err := TransferJobs(f, 3, time.Minute)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.finished {
+		t.Fatal("expected the job to be finished")
+	}
+	if len(f.recorded) != 10 {
+		t.Fatalf("expected 10 recorded rows, got %d", len(f.recorded))
+	}
+	for row, err := range f.recorded {
+		if wantFail := row%2 == 0; wantFail != errors.Is(err, store.ErrInsufficientFunds) {
+			t.Fatalf("row %d: unexpected outcome %v", row, err)
+		}
+	}
+}
+
+// TestTransferJobs_Reclaimed tests that the rows a reclaimed job already
+// transferred are recovered rather than transferred again
+func TestTransferJobs_Reclaimed(t *testing.T) {
+	f := &fakeJobStore{reclaimed: true, done: map[int]bool{1: true, 3: true},
+		transferred: make(map[int64]int), recorded: make(map[int]error)}
+	for i := 1; i <= 4; i++ {
+		f.rows = append(f.rows, store.TransferJobRow{JobID: 1, Row: i, TransferItem: store.TransferItem{
+			SourceAccountID: int64(i), DestinationAccountID: 100, Amount: decimal.NewFromInt(1),
+		}})
+	}
+
+	if err := TransferJobs(f, 2, time.Minute)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.finished || len(f.recorded) != 4 {
+		t.Fatalf("expected every row recorded and the job finished, got %v, finished=%t", f.recorded, f.finished)
+	}
+	for src, want := range map[int64]int{1: 0, 2: 1, 3: 0, 4: 1} {
+		if f.transferred[src] != want {
+			t.Fatalf("row %d: expected %d transfers, got %d", src, want, f.transferred[src])
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/you/internal-transfers/internal/notify"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// ReconciliationStore is the storage reconciled by Reconciliation.
+type ReconciliationStore interface {
+	Reconcile(ctx context.Context) (store.ReconciliationRun, error)
+}
+
+// Reconciliation returns a run function for Every reconciling the stored
+// balances with the ledger and, when accounts disagree, logging it and
+// notifying n unless it is nil.
+func Reconciliation(s ReconciliationStore, n notify.Notifier) func(context.Context) error {
+	return func(ctx context.Context) error {
+		run, err := s.Reconcile(ctx)
+		if err != nil || len(run.Discrepancies) == 0 {
+			return err
+		}
+		log.Printf("reconciliation %d: %d of %d accounts disagree with the ledger", run.ID, len(run.Discrepancies), run.AccountsChecked)
+		if n == nil {
+			return nil
+		}
+		var b strings.Builder
+		for _, d := range run.Discrepancies {
+			fmt.Fprintf(&b, "account %d: balance %s, ledger %s; held %s, pending holds %s\n",
+				d.AccountID, d.Balance, d.LedgerBalance, d.HeldBalance, d.PendingHolds)
+		}
+		return n.Notify(ctx, notify.Message{
+			Kind:    notify.KindReconciliation,
+			Subject: fmt.Sprintf("Reconciliation %d: %d of %d accounts disagree with the ledger", run.ID, len(run.Discrepancies), run.AccountsChecked),
+			Text:    b.String(),
+		})
+	}
+}
+
+// ReviewNotificationStore is the storage of ReviewNotifications.
+type ReviewNotificationStore interface {
+	ClaimReviewNotifications(ctx context.Context, limit int) ([]store.Review, error)
+}
+
+// ReviewNotifications returns a run function for Every notifying n of the
+// transfers newly flagged for review, batchSize at most per message.
+func ReviewNotifications(s ReviewNotificationStore, n notify.Notifier, batchSize int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			reviews, err := s.ClaimReviewNotifications(ctx, batchSize)
+			if err != nil || len(reviews) == 0 {
+				return err
+			}
+			var b strings.Builder
+			for _, r := range reviews {
+				fmt.Fprintf(&b, "transaction %d: %s %s from account %d to %d, flagged by rule %q",
+					r.ID, r.Amount, r.Currency, r.SourceAccountID, r.DestinationAccountID, r.RuleName)
+				if r.Reason != "" {
+					b.WriteString(": " + r.Reason)
+				}
+				b.WriteString("\n")
+			}
+			m := notify.Message{Kind: notify.KindReview, Subject: fmt.Sprintf("%d transfers flagged for review await approval", len(reviews)), Text: b.String()}
+			if len(reviews) == 1 {
+				m.Subject = fmt.Sprintf("Transfer %d flagged for review awaits approval", reviews[0].ID)
+			}
+			if err := n.Notify(ctx, m); err != nil {
+				return err
+			}
+			if len(reviews) < batchSize {
+				return nil
+			}
+		}
+	}
+}
+
+// AlertNotificationStore is the storage of AlertNotifications.
+type AlertNotificationStore interface {
+	ClaimAlertNotifications(ctx context.Context, limit int) ([]store.Alert, error)
+}
+
+// AlertNotifications returns a run function for Every notifying n of each
+// alert newly raised, claiming batchSize at a time.
+func AlertNotifications(s AlertNotificationStore, n notify.Notifier, batchSize int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			alerts, err := s.ClaimAlertNotifications(ctx, batchSize)
+			if err != nil || len(alerts) == 0 {
+				return err
+			}
+			for _, al := range alerts {
+				if err := n.Notify(ctx, alertMessage(al)); err != nil {
+					return err
+				}
+			}
+			if len(alerts) < batchSize {
+				return nil
+			}
+		}
+	}
+}
+
+// alertMessage is the notification of a raised alert
+func alertMessage(al store.Alert) notify.Message {
+	m := notify.Message{Kind: notify.KindAlert}
+	switch al.Kind {
+	case store.AlertLowBalance:
+		m.Subject = fmt.Sprintf("Low balance on account %d", al.AccountID)
+		m.Text = fmt.Sprintf("Available balance %s is below %s after transaction %d", al.Value, al.Threshold, al.TransactionID)
+	default:
+		m.Subject = fmt.Sprintf("Large transfer on account %d", al.AccountID)
+		m.Text = fmt.Sprintf("Transaction %d of %s is at least %s", al.TransactionID, al.Value, al.Threshold)
+	}
+	m.Text += fmt.Sprintf(" (alert %d)", al.ID)
+	return m
+}
+
+// DeadLetterNotificationStore is the storage of DeadLetterNotifications.
+type DeadLetterNotificationStore interface {
+	ClaimDeadLetterNotifications(ctx context.Context, limit int) ([]store.DeadLetter, error)
+}
+
+// DeadLetterNotifications returns a run function for Every notifying n of
+// the outbox events newly dead-lettered, batchSize at most per message.
+func DeadLetterNotifications(s DeadLetterNotificationStore, n notify.Notifier, batchSize int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		for {
+			dead, err := s.ClaimDeadLetterNotifications(ctx, batchSize)
+			if err != nil || len(dead) == 0 {
+				return err
+			}
+			var b strings.Builder
+			for _, d := range dead {
+				fmt.Fprintf(&b, "event %d (%s, key %s) after %d attempts: %s\n", d.ID, d.EventType, d.EventKey, d.Attempts, d.LastError)
+			}
+			m := notify.Message{Kind: notify.KindDeadLetter, Subject: fmt.Sprintf("%d outbox events dead-lettered", len(dead)), Text: b.String()}
+			if len(dead) == 1 {
+				m.Subject = fmt.Sprintf("Outbox event %d dead-lettered", dead[0].ID)
+			}
+			if err := n.Notify(ctx, m); err != nil {
+				return err
+			}
+			if len(dead) < batchSize {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/notify"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// notifications records the messages it is notified of
+type notifications []notify.Message
+
+func (n *notifications) Notify(ctx context.Context, m notify.Message) error {
+	*n = append(*n, m)
+	return nil
+}
+
+// fakeReconciliationStore returns run
+type fakeReconciliationStore struct {
+	run store.ReconciliationRun
+	err error
+}
+
+func (f *fakeReconciliationStore) Reconcile(ctx context.Context) (store.ReconciliationRun, error) {
+	return f.run, f.err
+}
+
+// TestReconciliation tests that only runs finding discrepancies notify
+func TestReconciliation(t *testing.T) {
+	var got notifications
+	f := &fakeReconciliationStore{run: store.ReconciliationRun{ID: 7, AccountsChecked: 10}}
+	if err := Reconciliation(f, &got)(context.Background()); err != nil || len(got) != 0 {
+		t.Fatalf("expected no notification, got %v, %v", got, err)
+	}
+	f.err = errors.New("connection refused")
+	if err := Reconciliation(f, &got)(context.Background()); !errors.Is(err, f.err) || len(got) != 0 {
+		t.Fatalf("expected the error and no notification, got %v, %v", got, err)
+	}
+
+	f.err = nil
+	f.run.Discrepancies = []store.Discrepancy{{AccountID: 3, Balance: decimal.NewFromInt(10), LedgerBalance: decimal.NewFromInt(12),
+		HeldBalance: decimal.Zero, PendingHolds: decimal.Zero}}
+	if err := Reconciliation(f, &got)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Kind != notify.KindReconciliation || !strings.HasPrefix(got[0].Subject, "Reconciliation 7: 1 of 10 accounts") ||
+		got[0].Text != "account 3: balance 10, ledger 12; held 0, pending holds 0\n" {
+		t.Fatalf("unexpected notifications %+v", got)
+	}
+	if err := Reconciliation(f, nil)(context.Background()); err != nil {
+		t.Fatalf("unexpected error without a notifier: %v", err)
+	}
+}
+
+// fakeClaimStore hands out reviews, alerts and dead letters in claims of up to limit
+type fakeClaimStore struct {
+	reviews []store.Review
+	alerts  []store.Alert
+	dead    []store.DeadLetter
+}
+
+func (f *fakeClaimStore) ClaimReviewNotifications(ctx context.Context, limit int) ([]store.Review, error) {
+	n := min(limit, len(f.reviews))
+	claimed := f.reviews[:n]
+	f.reviews = f.reviews[n:]
+	return claimed, nil
+}
+
+func (f *fakeClaimStore) ClaimAlertNotifications(ctx context.Context, limit int) ([]store.Alert, error) {
+	n := min(limit, len(f.alerts))
+	claimed := f.alerts[:n]
+	f.alerts = f.alerts[n:]
+	return claimed, nil
+}
+
+func (f *fakeClaimStore) ClaimDeadLetterNotifications(ctx context.Context, limit int) ([]store.DeadLetter, error) {
+	n := min(limit, len(f.dead))
+	claimed := f.dead[:n]
+	f.dead = f.dead[n:]
+	return claimed, nil
+}
+
+// TestClaimNotifications tests that flagged transfers, alerts and dead
+// letters are notified in batches until none are left
+func TestClaimNotifications(t *testing.T) {
+	f := &fakeClaimStore{}
+	for i := int64(1); i <= 3; i++ {
+		r := store.Review{RuleName: "burst"}
+		r.ID, r.Amount, r.Currency, r.SourceAccountID, r.DestinationAccountID = i, decimal.NewFromInt(5), "USD", 1, 2
+		f.reviews = append(f.reviews, r)
+	}
+	f.alerts = []store.Alert{
+		{ID: 4, AccountID: 1, Kind: store.AlertLowBalance, Threshold: decimal.NewFromInt(100), Value: decimal.NewFromInt(20), TransactionID: 7},
+		{ID: 5, AccountID: 2, Kind: store.AlertLargeTransfer, Threshold: decimal.NewFromInt(50), Value: decimal.NewFromInt(80), TransactionID: 8},
+		{ID: 6, AccountID: 2, Kind: store.AlertLargeTransfer, Threshold: decimal.NewFromInt(50), Value: decimal.NewFromInt(60), TransactionID: 9},
+	}
+	f.dead = []store.DeadLetter{{ID: 9, EventType: "transfer.completed", EventKey: "1", Attempts: 1, LastError: "status 400"}}
+
+	var got notifications
+	if err := ReviewNotifications(f, &got, 2)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].Subject != "2 transfers flagged for review await approval" || got[1].Subject != "Transfer 3 flagged for review awaits approval" ||
+		!strings.Contains(got[0].Text, `transaction 2: 5 USD from account 1 to 2, flagged by rule "burst"`) {
+		t.Fatalf("unexpected review notifications %+v", got)
+	}
+
+	got = nil
+	if err := AlertNotifications(f, &got, 2)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := notify.Message{Kind: notify.KindAlert, Subject: "Low balance on account 1", Text: "Available balance 20 is below 100 after transaction 7 (alert 4)"}
+	if len(got) != 3 || got[0] != want || got[1].Subject != "Large transfer on account 2" ||
+		got[1].Text != "Transaction 8 of 80 is at least 50 (alert 5)" || len(f.alerts) != 0 {
+		t.Fatalf("unexpected alert notifications %+v", got)
+	}
+
+	got = nil
+	if err := DeadLetterNotifications(f, &got, 2)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Kind != notify.KindDeadLetter || got[0].Subject != "Outbox event 9 dead-lettered" ||
+		got[0].Text != "event 9 (transfer.completed, key 1) after 1 attempts: status 400\n" {
+		t.Fatalf("unexpected dead letter notifications %+v", got)
+	}
+	if err := DeadLetterNotifications(f, &got, 2)(context.Background()); err != nil || len(got) != 1 {
+		t.Fatalf("expected nothing left to notify, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// meterName is the instrumentation scope of the worker metrics
+const meterName = "github.com/you/internal-transfers/internal/worker"
+
+// Metrics of the outbox relay
+var (
+	outboxLag, _ = otel.Meter(meterName).Float64Gauge("outbox.lag",
+		metric.WithDescription("Age of the oldest event waiting in the outbox when the relay last looked, 0 once it is empty"),
+		metric.WithUnit("s"),
+	)
+	outboxDelivered, _ = otel.Meter(meterName).Int64Counter("outbox.events.delivered",
+		metric.WithDescription("Outbox events delivered to the publisher"),
+		metric.WithUnit("{event}"),
+	)
+	outboxFailures, _ = otel.Meter(meterName).Int64Counter("outbox.publish.failures",
+		metric.WithDescription("Failed deliveries of a single outbox event"),
+		metric.WithUnit("{event}"),
+	)
+	outboxDeadLettered, _ = otel.Meter(meterName).Int64Counter("outbox.events.dead_lettered",
+		metric.WithDescription("Outbox events moved to the dead letters because they can never be delivered"),
+		metric.WithUnit("{event}"),
+	)
+)
+
+// OutboxStore is the storage read by the outbox relay.
+type OutboxStore interface {
+	ListOutboxEvents(ctx context.Context, limit int) ([]events.Event, error)
+	MarkOutboxDelivered(ctx context.Context, ids []int64) error
+	RecordOutboxFailure(ctx context.Context, id int64, msg string) error
+	DeadLetterOutboxEvent(ctx context.Context, id int64, msg string) error
+}
+
+// Outbox returns a run function for Every that relays outbox events to p in
+// batches of batchSize until the outbox is empty. Events are marked delivered
+// only once p has acknowledged them, and consumers deduplicate the redeliveries
+// of a crash in between on the event id, so each event takes effect once.
+//
+// When a batch fails its events are sent one at a time to find the culprit:
+// an event failing with an events.Permanent error is dead-lettered and the
+// relay moves on, while any other failure is counted against the event and
+// ends the run, so that the events behind it keep their order.
+func Outbox(s OutboxStore, p events.Publisher, batchSize int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		_, err := relay(ctx, s, p, batchSize)
+		return err
+	}
+}
+
+// OutboxCatchUp returns a run function relaying, like Outbox, the events that
+// piled up while no instance was relaying them, and logging how many it
+// relayed. It suits a first run at startup with batches larger than the
+// steady state's.
+func OutboxCatchUp(s OutboxStore, p events.Publisher, batchSize int) func(context.Context) error {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		n, err := relay(ctx, s, p, batchSize)
+		if n > 0 {
+			log.Printf("outbox catch-up: relayed %d events in %s", n, time.Since(start).Round(time.Millisecond))
+		}
+		return err
+	}
+}
+
+// relay relays events until the outbox is empty and returns how many it delivered.
+func relay(ctx context.Context, s OutboxStore, p events.Publisher, batchSize int) (int, error) {
+	var n int
+	for {
+		evs, err := s.ListOutboxEvents(ctx, batchSize)
+		if err != nil {
+			return n, err
+		}
+		if len(evs) == 0 {
+			outboxLag.Record(ctx, 0)
+			return n, nil
+		}
+		outboxLag.Record(ctx, time.Since(evs[0].CreatedAt).Seconds())
+		delivered, err := publish(ctx, s, p, evs)
+		n += delivered
+		if err != nil {
+			return n, err
+		}
+		if len(evs) < batchSize {
+			outboxLag.Record(ctx, 0)
+			return n, nil
+		}
+	}
+}
+
+// publish delivers evs in order, falling back to one event at a time when the
+// batch fails, and returns how many it delivered.
+func publish(ctx context.Context, s OutboxStore, p events.Publisher, evs []events.Event) (int, error) {
+	err := p.Publish(ctx, evs)
+	if err == nil {
+		return len(evs), markDelivered(ctx, s, evs)
+	}
+	if len(evs) == 1 {
+		return 0, failed(ctx, s, evs[0], err)
+	}
+
+	var delivered, start int
+	for i := range evs {
+		err := p.Publish(ctx, evs[i:i+1])
+		if err == nil {
+			continue
+		}
+		if err := markDelivered(ctx, s, evs[start:i]); err != nil {
+			return delivered, err
+		}
+		delivered += i - start
+		start = i + 1
+		if err := failed(ctx, s, evs[i], err); err != nil {
+			return delivered, err
+		}
+	}
+	if err := markDelivered(ctx, s, evs[start:]); err != nil {
+		return delivered, err
+	}
+	return delivered + len(evs) - start, nil
+}
+
+// markDelivered marks evs delivered.
+func markDelivered(ctx context.Context, s OutboxStore, evs []events.Event) error {
+	if len(evs) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(evs))
+	for i, e := range evs {
+		ids[i] = e.ID
+	}
+	if err := s.MarkOutboxDelivered(ctx, ids); err != nil {
+		return err
+	}
+	outboxDelivered.Add(ctx, int64(len(evs)))
+	return nil
+}
+
+// failed handles the failed delivery of e: a permanent error dead-letters it
+// and returns nil, any other is counted against it and returned.
+func failed(ctx context.Context, s OutboxStore, e events.Event, err error) error {
+	outboxFailures.Add(ctx, 1)
+	if events.IsPermanent(err) {
+		if err := s.DeadLetterOutboxEvent(ctx, e.ID, err.Error()); err != nil {
+			return err
+		}
+		outboxDeadLettered.Add(ctx, 1)
+		log.Printf("outbox: event %d (%s) dead-lettered: %v", e.ID, e.Type, err)
+		return nil
+	}
+	if recErr := s.RecordOutboxFailure(ctx, e.ID, err.Error()); recErr != nil {
+		return errors.Join(err, recErr)
+	}
+	return err
+}
+
+// OutboxPurgeStore is the storage cleaned by PurgeOutbox.
+type OutboxPurgeStore interface {
+	PurgeOutboxEvents(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PurgeOutbox returns a run function for Every deleting the outbox events
+// delivered more than retention ago.
+func PurgeOutbox(s OutboxPurgeStore, retention time.Duration, c clock.Clock) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := s.PurgeOutboxEvents(ctx, c.Now().Add(-retention))
+		if n > 0 {
+			log.Printf("outbox: purged %d delivered events", n)
+		}
+		return err
+	}
+}
@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/events"
+)
+
+// fakeOutbox is an in-memory outbox
+type fakeOutbox struct {
+	evs      []events.Event
+	attempts map[int64]int
+	dead     []int64
+}
+
+func (f *fakeOutbox) ListOutboxEvents(ctx context.Context, limit int) ([]events.Event, error) {
+	return append([]events.Event(nil), f.evs[:min(limit, len(f.evs))]...), nil
+}
+
+func (f *fakeOutbox) MarkOutboxDelivered(ctx context.Context, ids []int64) error {
+	f.remove(ids...)
+	return nil
+}
+
+func (f *fakeOutbox) RecordOutboxFailure(ctx context.Context, id int64, msg string) error {
+	if f.attempts == nil {
+		f.attempts = make(map[int64]int)
+	}
+	f.attempts[id]++
+	return nil
+}
+
+func (f *fakeOutbox) DeadLetterOutboxEvent(ctx context.Context, id int64, msg string) error {
+	f.remove(id)
+	f.dead = append(f.dead, id)
+	return nil
+}
+
+func (f *fakeOutbox) remove(ids ...int64) {
+	for _, id := range ids {
+		for i, e := range f.evs {
+			if e.ID == id {
+				f.evs = append(f.evs[:i], f.evs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// fakePublisher records published event ids. It fails while err is set, and
+// fails any batch holding a poison event, with a permanent error when alone.
+type fakePublisher struct {
+	published []int64
+	err       error
+	poison    int64
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, evs []events.Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	for _, e := range evs {
+		if e.ID == p.poison {
+			if len(evs) == 1 {
+				return events.Permanent(errors.New("message too large"))
+			}
+			return errors.New("batch rejected")
+		}
+	}
+	for _, e := range evs {
+		p.published = append(p.published, e.ID)
+	}
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+// TestOutbox tests that events are relayed in order and kept until published
+func TestOutbox(t *testing.T) {
+	out := &fakeOutbox{}
+	for id := int64(1); id <= 5; id++ {
+		out.evs = append(out.evs, events.Event{ID: id, Type: events.TransferCompleted})
+	}
+	pub := &fakePublisher{err: errors.New("broker down")}
+	relay := Outbox(out, pub, 2)
+
+	if err := relay(context.Background()); err == nil {
+		t.Fatal("expected the publish error")
+	}
+	if len(out.evs) != 5 {
+		t.Fatalf("expected unpublished events to stay in the outbox, got %d left", len(out.evs))
+	}
+	if out.attempts[1] != 1 || len(out.dead) != 0 {
+		t.Fatalf("expected a failure counted against the first event only, got attempts=%v dead=%v", out.attempts, out.dead)
+	}
+
+	pub.err = nil
+	if err := relay(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.evs) != 0 || len(pub.published) != 5 {
+		t.Fatalf("expected all events relayed, got published=%v left=%d", pub.published, len(out.evs))
+	}
+	for i, id := range pub.published {
+		if id != int64(i+1) {
+			t.Fatalf("expected events in order, got %v", pub.published)
+		}
+	}
+}
+
+// TestOutbox_Poison tests that an event that can never be delivered is
+// dead-lettered without holding back the events behind it
+func TestOutbox_Poison(t *testing.T) {
+	out := &fakeOutbox{}
+	for id := int64(1); id <= 5; id++ {
+		out.evs = append(out.evs, events.Event{ID: id, Type: events.TransferCompleted})
+	}
+	pub := &fakePublisher{poison: 3}
+
+	if err := Outbox(out, pub, 10)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(out.evs) != 0 {
+		t.Fatalf("expected the outbox emptied, got %d left", len(out.evs))
+	}
+	if len(out.dead) != 1 || out.dead[0] != 3 {
+		t.Fatalf("expected event 3 dead-lettered, got %v", out.dead)
+	}
+	want := []int64{1, 2, 4, 5}
+	if len(pub.published) != len(want) {
+		t.Fatalf("expected %v published, got %v", want, pub.published)
+	}
+	for i, id := range want {
+		if pub.published[i] != id {
+			t.Fatalf("expected %v published, got %v", want, pub.published)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// partitionsAhead is the number of months, the current one included, whose
+// partitions of the transactions table are kept created ahead of time.
+const partitionsAhead = 4
+
+// PartitionStore is the storage maintained by TransactionPartitions.
+type PartitionStore interface {
+	EnsureTransactionPartitions(ctx context.Context, from time.Time, months int) (int, error)
+	ArchiveTransactions(ctx context.Context, before time.Time, exp store.TransactionExporter) ([]store.ArchivedMonth, error)
+}
+
+// TransactionPartitions returns a run function for Every creating the
+// partitions of the transactions table for the coming months as of c and,
+// when retention is positive, archiving the months older than the current one
+// and the retention months before it, to exp if not nil (see
+// store.ArchiveTransactions).
+func TransactionPartitions(s PartitionStore, retention int, exp store.TransactionExporter, c clock.Clock) func(context.Context) error {
+	return func(ctx context.Context) error {
+		now := c.Now()
+		n, err := s.EnsureTransactionPartitions(ctx, now, partitionsAhead)
+		if n > 0 {
+			log.Printf("created %d transaction partitions", n)
+		}
+		if err != nil || retention <= 0 {
+			return err
+		}
+		months, err := s.ArchiveTransactions(ctx, store.StatementPeriod(now).AddDate(0, -retention, 0), exp)
+		for _, m := range months {
+			log.Printf("archived %d transactions of %s", m.Rows, m.Month.Format("2006-01"))
+		}
+		return err
+	}
+}
@@ -0,0 +1,48 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakePartitionStore records the partitions created and the archive cutoffs
+type fakePartitionStore struct {
+	from    time.Time
+	months  int
+	cutoffs []time.Time
+}
+
+func (f *fakePartitionStore) EnsureTransactionPartitions(ctx context.Context, from time.Time, months int) (int, error) {
+	f.from, f.months = from, months
+	return 0, nil
+}
+
+func (f *fakePartitionStore) ArchiveTransactions(ctx context.Context, before time.Time, exp store.TransactionExporter) ([]store.ArchivedMonth, error) {
+	f.cutoffs = append(f.cutoffs, before)
+	return nil, nil
+}
+
+// TestTransactionPartitions tests that partitions are created ahead, and that
+// the months before the retention period are archived only when it is set
+func TestTransactionPartitions(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 17, 9, 0, 0, 0, time.UTC))
+	f := &fakePartitionStore{}
+	if err := TransactionPartitions(f, 0, nil, clk)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.from.Equal(clk.Now()) || f.months != partitionsAhead || len(f.cutoffs) != 0 {
+		t.Fatalf("expected partitions from now and no archiving, got %+v", f)
+	}
+
+	if err := TransactionPartitions(f, 3, nil, clk)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if len(f.cutoffs) != 1 || !f.cutoffs[0].Equal(want) {
+		t.Fatalf("expected the months before %s archived, got %v", want.Format("2006-01"), f.cutoffs)
+	}
+}
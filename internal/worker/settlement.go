@@ -0,0 +1,24 @@
+package worker
+
+import (
+	"context"
+	"log"
+)
+
+// SettlementStore is the storage used to settle clearing transfers.
+type SettlementStore interface {
+	Settle(ctx context.Context) (int, error)
+}
+
+// Settlement returns a run function for Every that settles the clearing
+// transfers of the business days whose cutoff has passed. Runs with nothing
+// to settle are cheap, so polling is enough to pick up each cutoff.
+func Settlement(s SettlementStore) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := s.Settle(ctx)
+		if n > 0 {
+			log.Printf("settled %d pairs of clearing accounts", n)
+		}
+		return err
+	}
+}
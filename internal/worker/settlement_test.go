@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeSettlementStore counts the settlement runs
+type fakeSettlementStore struct {
+	runs int
+	err  error
+}
+
+func (f *fakeSettlementStore) Settle(ctx context.Context) (int, error) {
+	f.runs++
+	return 1, f.err
+}
+
+// TestSettlement tests that each run settles and reports the pairs it could not
+func TestSettlement(t *testing.T) {
+	f := &fakeSettlementStore{}
+	if err := Settlement(f)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.err = errors.New("insufficient funds")
+	if err := Settlement(f)(context.Background()); !errors.Is(err, f.err) {
+		t.Fatalf("expected the settlement error, got %v", err)
+	}
+	if f.runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", f.runs)
+	}
+}
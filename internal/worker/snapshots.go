@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// SnapshotStore is the storage used to take periodic balance snapshots.
+type SnapshotStore interface {
+	LastBalanceSnapshot(ctx context.Context) (time.Time, error)
+	SnapshotBalances(ctx context.Context) (store.SnapshotRun, error)
+}
+
+// BalanceSnapshots returns a run function for Every that snapshots all balances
+// once the latest snapshot is at least interval old as of c. Polling the latest
+// snapshot rather than ticking at interval keeps the schedule across restarts
+// and instances.
+func BalanceSnapshots(s SnapshotStore, interval time.Duration, c clock.Clock) func(context.Context) error {
+	return func(ctx context.Context) error {
+		last, err := s.LastBalanceSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+		if !last.IsZero() && c.Now().Sub(last) < interval {
+			return nil
+		}
+		run, err := s.SnapshotBalances(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("snapshotted %d account balances as of %s", run.Accounts, run.TakenAt.Format(time.RFC3339))
+		return nil
+	}
+}
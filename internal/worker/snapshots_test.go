@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakeSnapshotStore records when snapshots are taken
+type fakeSnapshotStore struct {
+	clock *clock.Fake
+	last  time.Time
+	taken int
+}
+
+func (f *fakeSnapshotStore) LastBalanceSnapshot(ctx context.Context) (time.Time, error) {
+	return f.last, nil
+}
+
+func (f *fakeSnapshotStore) SnapshotBalances(ctx context.Context) (store.SnapshotRun, error) {
+	f.taken++
+	f.last = f.clock.Now().Add(-time.Minute)
+	return store.SnapshotRun{TakenAt: f.last, Accounts: 3}, nil
+}
+
+// TestBalanceSnapshots tests that a snapshot is taken only once the last one is interval old
+func TestBalanceSnapshots(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC))
+	f := &fakeSnapshotStore{clock: clk}
+	run := BalanceSnapshots(f, 24*time.Hour, clk)
+
+	for range 2 {
+		if err := run(context.Background()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if f.taken != 1 {
+		t.Fatalf("expected the first run only to snapshot, got %d snapshots", f.taken)
+	}
+
+	clk.Advance(24 * time.Hour)
+	if err := run(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.taken != 2 {
+		t.Fatalf("expected a snapshot once the last one is a day old, got %d snapshots", f.taken)
+	}
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// StatementStore is the storage used to generate monthly statements.
+type StatementStore interface {
+	GenerateStatements(ctx context.Context, period time.Time) (int, error)
+}
+
+// MonthlyStatements returns a run function for Every that generates last
+// month's statements, as of c, for the accounts that have none yet. Runs after
+// the first are cheap, so polling is enough to pick up each new month.
+func MonthlyStatements(s StatementStore, c clock.Clock) func(context.Context) error {
+	return func(ctx context.Context) error {
+		period := store.StatementPeriod(c.Now()).AddDate(0, -1, 0)
+		n, err := s.GenerateStatements(ctx, period)
+		if errors.Is(err, store.ErrStatementPeriodOpen) {
+			// Right at the turn of the month; the next run generates them
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			log.Printf("generated %d statements for %s", n, period.Format("2006-01"))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/you/internal-transfers/internal/clock"
+	"github.com/you/internal-transfers/internal/store"
+)
+
+// fakeStatementStore records the periods statements are generated for
+type fakeStatementStore struct {
+	periods []time.Time
+	err     error
+}
+
+func (f *fakeStatementStore) GenerateStatements(ctx context.Context, period time.Time) (int, error) {
+	f.periods = append(f.periods, period)
+	return 2, f.err
+}
+
+// TestMonthlyStatements tests that statements are generated for the previous month
+func TestMonthlyStatements(t *testing.T) {
+	f := &fakeStatementStore{}
+	clk := clock.NewFake(time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC))
+	if err := MonthlyStatements(f, clk)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if len(f.periods) != 1 || !f.periods[0].Equal(want) {
+		t.Fatalf("expected statements for %s, got %v", want.Format("2006-01"), f.periods)
+	}
+
+	f.err = store.ErrStatementPeriodOpen
+	if err := MonthlyStatements(f, clk)(context.Background()); err != nil {
+		t.Fatalf("expected an open period to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package worker
+
+import (
+	"context"
+	"log"
+)
+
+// SweepStore is the storage used to run the sweep rules.
+type SweepStore interface {
+	RunSweeps(ctx context.Context) (int, error)
+}
+
+// Sweeps returns a run function for Every that sweeps the balance of each
+// account above its rule's threshold to the rule's target account.
+func Sweeps(s SweepStore) func(context.Context) error {
+	return func(ctx context.Context) error {
+		n, err := s.RunSweeps(ctx)
+		if n > 0 {
+			log.Printf("made %d sweeps", n)
+		}
+		return err
+	}
+}
@@ -0,0 +1,33 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeSweepStore counts the sweep runs
+type fakeSweepStore struct {
+	runs int
+	err  error
+}
+
+func (f *fakeSweepStore) RunSweeps(ctx context.Context) (int, error) {
+	f.runs++
+	return 1, f.err
+}
+
+// TestSweeps tests that each run sweeps and reports the rules it could not run
+func TestSweeps(t *testing.T) {
+	f := &fakeSweepStore{}
+	if err := Sweeps(f)(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.err = errors.New("sweep rule 1: currency mismatch")
+	if err := Sweeps(f)(context.Background()); !errors.Is(err, f.err) {
+		t.Fatalf("expected the sweep error, got %v", err)
+	}
+	if f.runs != 2 {
+		t.Fatalf("expected 2 runs, got %d", f.runs)
+	}
+}
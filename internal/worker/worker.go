@@ -0,0 +1,173 @@
+// Package worker runs periodic background jobs alongside the HTTP server.
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Every calls fn every interval until ctx is cancelled. A failing run is logged
+// and retried on the next tick. Every blocks, so callers usually start it in a goroutine.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	every(ctx, ctx.Done(), name, interval, fn, func() {})
+}
+
+// every is Every, also returning, between runs, once stop is closed, and
+// calling beat after each run.
+func every(ctx context.Context, stop <-chan struct{}, name string, interval time.Duration, fn func(context.Context) error, beat func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := fn(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("worker %s: %v", name, err)
+			}
+			beat()
+		}
+	}
+}
+
+// Group runs background jobs that can be stopped gracefully, letting the runs
+// in progress finish so that they do not outlive the store they use.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	once   sync.Once
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	beats []*heartbeat
+	now   func() time.Time
+}
+
+// heartbeat records when a job of a Group last finished a run
+type heartbeat struct {
+	name     string
+	interval time.Duration
+	last     time.Time
+}
+
+// Heartbeats are stale once a job has not finished a run for staleBeats
+// intervals, and at least minStaleAfter, which leaves frequent jobs room for a
+// slow run.
+const (
+	staleBeats    = 3
+	minStaleAfter = time.Minute
+)
+
+// NewGroup returns a Group whose runs use a context derived from ctx.
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel, stop: make(chan struct{}), now: time.Now}
+}
+
+// Go runs fn every interval in a goroutine, as Every does, until the group is
+// stopped.
+func (g *Group) Go(name string, interval time.Duration, fn func(context.Context) error) {
+	g.mu.Lock()
+	hb := &heartbeat{name: name, interval: interval, last: g.now()}
+	g.beats = append(g.beats, hb)
+	g.mu.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		every(g.ctx, g.stop, name, interval, fn, func() {
+			g.mu.Lock()
+			hb.last = g.now()
+			g.mu.Unlock()
+		})
+	}()
+}
+
+// Once runs fn a single time in a goroutine, logging its error like Every,
+// unless the group is stopped first. Stop waits for it like for other runs.
+func (g *Group) Once(name string, fn func(context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		select {
+		case <-g.stop:
+			return
+		default:
+		}
+		if err := fn(g.ctx); err != nil && g.ctx.Err() == nil {
+			log.Printf("worker %s: %v", name, err)
+		}
+	}()
+}
+
+// Check reports the jobs whose heartbeat is stale: those that have not
+// finished a run, failed or not, for several intervals, because a run is stuck
+// or the job stopped. It suits readiness checks.
+func (g *Group) Check(ctx context.Context) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.now()
+	var stale []string
+	for _, hb := range g.beats {
+		staleAfter := staleBeats * hb.interval
+		if staleAfter < minStaleAfter {
+			staleAfter = minStaleAfter
+		}
+		if since := now.Sub(hb.last); since > staleAfter {
+			stale = append(stale, fmt.Sprintf("%s (last run %s ago)", hb.name, since.Round(time.Second)))
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("stale workers: %s", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// Stop starts no more runs and waits for those in progress. If ctx is done
+// first, it cancels them and returns ctx.Err() once they have returned.
+func (g *Group) Stop(ctx context.Context) error {
+	g.once.Do(func() { close(g.stop) })
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	defer g.cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+// LockFunc tries to take a lock shared between instances without waiting.
+// When ok, unlock must be called to give the lock up.
+type LockFunc func(ctx context.Context) (unlock func(), ok bool, err error)
+
+// Exclusive wraps fn so that each run first takes lock, which elects a single
+// leader among instances for that run. Runs where another instance holds the
+// lock are skipped.
+func Exclusive(lock LockFunc, fn func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		unlock, ok, err := lock(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		defer unlock()
+		return fn(ctx)
+	}
+}
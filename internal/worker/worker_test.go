@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEvery_RunsUntilCancelled tests that failing runs do not stop the loop and cancellation does
+func TestEvery_RunsUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		Every(ctx, "test", time.Millisecond, func(context.Context) error {
+			if runs.Add(1) == 3 {
+				cancel()
+			}
+			return errors.New("boom")
+		})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Every did not return after cancellation")
+	}
+	if n := runs.Load(); n < 3 {
+		t.Fatalf("expected at least 3 runs, got %d", n)
+	}
+}
+
+// TestGroup_Stop tests that stopping a group lets the run in progress finish
+func TestGroup_Stop(t *testing.T) {
+	g := NewGroup(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	g.Go("test", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+			return nil
+		}
+		select {
+		case <-release:
+			finished.Store(true)
+		case <-ctx.Done():
+		}
+		return nil
+	})
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- g.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned before the run finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil || !finished.Load() {
+		t.Fatalf("expected the run to finish, got finished=%v err=%v", finished.Load(), err)
+	}
+}
+
+// TestGroup_StopTimeout tests that runs outliving the stop deadline are cancelled
+// TestGroup_Once tests that a one-off run starts at once and is waited for by Stop
+func TestGroup_Once(t *testing.T) {
+	g := NewGroup(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var runs atomic.Int32
+	g.Once("test", func(ctx context.Context) error {
+		runs.Add(1)
+		close(started)
+		<-release
+		return nil
+	})
+	<-started
+
+	stopped := make(chan error)
+	go func() { stopped <- g.Stop(context.Background()) }()
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned before the run finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-stopped; err != nil || runs.Load() != 1 {
+		t.Fatalf("expected a single run, got runs=%d err=%v", runs.Load(), err)
+	}
+}
+
+func TestGroup_StopTimeout(t *testing.T) {
+	g := NewGroup(context.Background())
+	started := make(chan struct{}, 1)
+	g.Go("test", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := g.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline exceeded, got %v", err)
+	}
+}
+
+// TestExclusive tests that fn only runs while the lock is held
+func TestExclusive(t *testing.T) {
+	held := false
+	unlocked := 0
+	lock := func(context.Context) (func(), bool, error) {
+		if held {
+			return nil, false, nil
+		}
+		return func() { unlocked++ }, true, nil
+	}
+	runs := 0
+	fn := Exclusive(lock, func(context.Context) error {
+		runs++
+		return nil
+	})
+
+	if err := fn(context.Background()); err != nil || runs != 1 || unlocked != 1 {
+		t.Fatalf("expected one run and unlock, got runs=%d unlocked=%d err=%v", runs, unlocked, err)
+	}
+	held = true
+	if err := fn(context.Background()); err != nil || runs != 1 {
+		t.Fatalf("expected run to be skipped while another instance holds the lock, got runs=%d err=%v", runs, err)
+	}
+}
+
+// TestGroup_Check tests that jobs not finishing runs make the heartbeat stale
+func TestGroup_Check(t *testing.T) {
+	g := NewGroup(context.Background())
+	defer g.Stop(context.Background())
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	g.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	ran := make(chan struct{}, 1)
+	g.Go("fast", time.Millisecond, func(ctx context.Context) error {
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	g.Go("stuck", time.Hour, func(ctx context.Context) error { return nil })
+
+	if err := g.Check(context.Background()); err != nil {
+		t.Fatalf("expected fresh heartbeats, got %v", err)
+	}
+	advance(2 * time.Minute)
+	// The run starting third follows one that finished after the clock moved
+	for i := 0; i < 3; i++ {
+		<-ran
+	}
+	if err := g.Check(context.Background()); err != nil {
+		t.Fatalf("expected fresh heartbeats, got %v", err)
+	}
+	advance(3*time.Hour + time.Second)
+	if err := g.Check(context.Background()); err == nil || !strings.Contains(err.Error(), "stuck") {
+		t.Fatalf("expected the stuck job to be stale, got %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+-- migrations/0003_account_status.sql
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active'
+    CHECK (status IN ('active', 'frozen', 'closed'));
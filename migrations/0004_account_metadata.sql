@@ -0,0 +1,9 @@
+-- migrations/0004_account_metadata.sql
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS display_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS owner_ref TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_owner_ref ON accounts(owner_ref);
+CREATE INDEX IF NOT EXISTS idx_accounts_tags ON accounts USING GIN (tags);
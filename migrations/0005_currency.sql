@@ -0,0 +1,10 @@
+-- migrations/0005_currency.sql
+-- Existing rows predate multi-currency support and are all USD.
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'USD'
+    CHECK (currency ~ '^[A-Z]{3}$');
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS currency TEXT NOT NULL DEFAULT 'USD'
+    CHECK (currency ~ '^[A-Z]{3}$');
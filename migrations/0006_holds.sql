@@ -0,0 +1,23 @@
+-- migrations/0006_holds.sql
+-- held_balance is the part of balance reserved by pending holds;
+-- the available balance is balance - held_balance.
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS held_balance NUMERIC(30,10) NOT NULL DEFAULT 0
+    CHECK (held_balance >= 0 AND held_balance <= balance);
+
+CREATE TABLE IF NOT EXISTS holds (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'captured', 'released', 'expired')),
+    transaction_id BIGINT REFERENCES transactions(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_pending_expiry ON holds(expires_at) WHERE status = 'pending';
@@ -0,0 +1,19 @@
+-- migrations/0007_scheduled_transfers.sql
+-- Future-dated transfers executed by the scheduler. A row is moved to 'running'
+-- before its transfer is attempted, so it is executed at most once.
+
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    execute_at TIMESTAMPTZ NOT NULL,
+    executed_at TIMESTAMPTZ,
+    source_account_id BIGINT NOT NULL,
+    destination_account_id BIGINT NOT NULL,
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    transaction_id BIGINT REFERENCES transactions(id),
+    error_message TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers(execute_at) WHERE status = 'pending';
@@ -0,0 +1,26 @@
+-- migrations/0008_transfer_jobs.sql
+-- Bulk transfer uploads. Rows are executed by the job processor through the
+-- regular transfer path; progress is derived from the row statuses.
+
+CREATE TABLE IF NOT EXISTS transfer_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at TIMESTAMPTZ,
+    status TEXT NOT NULL DEFAULT 'queued'
+        CHECK (status IN ('queued', 'running', 'completed'))
+);
+
+CREATE TABLE IF NOT EXISTS transfer_job_rows (
+    job_id BIGINT NOT NULL REFERENCES transfer_jobs(id) ON DELETE CASCADE,
+    row_number INT NOT NULL,
+    source_account_id BIGINT NOT NULL,
+    destination_account_id BIGINT NOT NULL,
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'succeeded', 'failed')),
+    transaction_id BIGINT REFERENCES transactions(id),
+    error_message TEXT,
+    PRIMARY KEY (job_id, row_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_jobs_queued ON transfer_jobs(id) WHERE status = 'queued';
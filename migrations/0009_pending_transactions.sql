@@ -0,0 +1,5 @@
+-- migrations/0009_pending_transactions.sql
+-- Transfers accepted asynchronously are inserted as 'pending' transactions and
+-- executed in id order by a background worker; this index is the queue.
+
+CREATE INDEX IF NOT EXISTS idx_transactions_pending ON transactions(id) WHERE status = 'pending';
@@ -0,0 +1,8 @@
+-- migrations/0010_transaction_reversals.sql
+-- A reversal is a succeeded transfer in the opposite direction pointing at the
+-- transaction it compensates. The unique index allows one reversal per transaction.
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS reversal_of BIGINT REFERENCES transactions(id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_reversal_of ON transactions(reversal_of) WHERE reversal_of IS NOT NULL;
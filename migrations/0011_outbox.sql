@@ -0,0 +1,11 @@
+-- migrations/0011_outbox.sql
+-- Transactional outbox: events are inserted in the same DB transaction as the
+-- change they describe and deleted by the relay once the broker has them.
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    event_type TEXT NOT NULL,
+    event_key TEXT NOT NULL,
+    payload JSONB NOT NULL
+);
@@ -0,0 +1,9 @@
+-- migrations/0012_transfer_limits.sql
+-- Per-account transfer limits; NULL falls back to the service-wide default.
+-- The index serves the rolling 24h sum of outgoing transfers.
+
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS max_transfer_amount NUMERIC(30,10) CHECK (max_transfer_amount > 0),
+    ADD COLUMN IF NOT EXISTS daily_transfer_limit NUMERIC(30,10) CHECK (daily_transfer_limit > 0);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_source_succeeded ON transactions(source_account_id, created_at) WHERE status = 'succeeded';
@@ -0,0 +1,6 @@
+-- migrations/0013_transfer_reference.sql
+-- Free-text reference and purpose code supplied with a transfer, for reconciliation.
+
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS reference TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS purpose_code TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+-- migrations/0014_balance_history.sql
+-- settled_at is when a transaction reached its final status, i.e. when a
+-- succeeded transfer moved the balances; it is NULL while a transfer is pending.
+-- Rows recorded before this migration settled when they were created.
+-- accounts.created_at is unknown (NULL) for accounts that predate it.
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS settled_at TIMESTAMPTZ;
+UPDATE transactions SET settled_at = created_at WHERE settled_at IS NULL AND status <> 'pending';
+ALTER TABLE transactions ALTER COLUMN settled_at SET DEFAULT now();
+
+CREATE INDEX IF NOT EXISTS idx_transactions_source_settled
+    ON transactions(source_account_id, settled_at) WHERE status = 'succeeded';
+CREATE INDEX IF NOT EXISTS idx_transactions_destination_settled
+    ON transactions(destination_account_id, settled_at) WHERE status = 'succeeded';
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ;
+ALTER TABLE accounts ALTER COLUMN created_at SET DEFAULT now();
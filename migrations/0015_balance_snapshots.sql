@@ -0,0 +1,13 @@
+-- migrations/0015_balance_snapshots.sql
+-- Periodic per-account balances, the starting points of point-in-time balance
+-- queries, so that only the history since the nearest snapshot is replayed.
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    taken_at TIMESTAMPTZ NOT NULL,
+    balance NUMERIC(30,10) NOT NULL,
+    PRIMARY KEY (account_id, taken_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_balance_snapshots_taken_at ON balance_snapshots(taken_at);
+CREATE INDEX IF NOT EXISTS idx_transactions_settled ON transactions(settled_at) WHERE status = 'succeeded';
@@ -0,0 +1,32 @@
+-- migrations/0016_reconciliation.sql
+-- initial_balance is the balance an account was opened with, so that its
+-- balance can be recomputed from the ledger. Accounts that predate it are
+-- baselined on their balance net of their succeeded transfers.
+-- Reconciliation runs record the accounts whose stored balances disagree with
+-- the ledger.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS initial_balance NUMERIC(30,10);
+UPDATE accounts a SET initial_balance = a.balance
+    - COALESCE((SELECT sum(amount) FROM transactions
+        WHERE destination_account_id = a.account_id AND status = 'succeeded'), 0)
+    + COALESCE((SELECT sum(amount) FROM transactions
+        WHERE source_account_id = a.account_id AND status = 'succeeded'), 0)
+WHERE initial_balance IS NULL;
+ALTER TABLE accounts ALTER COLUMN initial_balance SET DEFAULT 0;
+ALTER TABLE accounts ALTER COLUMN initial_balance SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS reconciliation_runs (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    accounts_checked INT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS reconciliation_discrepancies (
+    run_id BIGINT NOT NULL REFERENCES reconciliation_runs(id) ON DELETE CASCADE,
+    account_id BIGINT NOT NULL,
+    balance NUMERIC(30,10) NOT NULL,
+    ledger_balance NUMERIC(30,10) NOT NULL,
+    held_balance NUMERIC(30,10) NOT NULL,
+    pending_holds NUMERIC(30,10) NOT NULL,
+    PRIMARY KEY (run_id, account_id)
+);
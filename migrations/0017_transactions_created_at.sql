@@ -0,0 +1,4 @@
+-- migrations/0017_transactions_created_at.sql
+-- Reports and searches filter transactions by creation time.
+
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at ON transactions(created_at);
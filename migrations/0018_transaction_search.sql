@@ -0,0 +1,7 @@
+-- migrations/0018_transaction_search.sql
+-- Transaction search pages through results ordered by creation time or amount,
+-- with the id as tie-breaker.
+
+DROP INDEX IF EXISTS idx_transactions_created_at;
+CREATE INDEX IF NOT EXISTS idx_transactions_created_at_id ON transactions(created_at, id);
+CREATE INDEX IF NOT EXISTS idx_transactions_amount_id ON transactions(amount, id);
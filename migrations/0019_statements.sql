@@ -0,0 +1,27 @@
+-- migrations/0019_statements.sql
+-- Monthly account statements: the opening and closing balance of a calendar
+-- month (UTC) and the succeeded transfers settled in it. period is the first
+-- day of the month.
+
+CREATE TABLE IF NOT EXISTS statements (
+    account_id BIGINT NOT NULL,
+    period DATE NOT NULL,
+    currency TEXT NOT NULL,
+    opening_balance NUMERIC(30,10) NOT NULL,
+    closing_balance NUMERIC(30,10) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (account_id, period)
+);
+
+CREATE TABLE IF NOT EXISTS statement_entries (
+    account_id BIGINT NOT NULL,
+    period DATE NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    settled_at TIMESTAMPTZ NOT NULL,
+    counterparty_account_id BIGINT NOT NULL,
+    amount NUMERIC(30,10) NOT NULL,
+    balance_after NUMERIC(30,10) NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY (account_id, period, transaction_id),
+    FOREIGN KEY (account_id, period) REFERENCES statements(account_id, period) ON DELETE CASCADE
+);
@@ -0,0 +1,18 @@
+-- migrations/0020_adjustments.sql
+-- Adjustments credit or debit one account outside a transfer, e.g. to correct
+-- a balance. They are recorded in the ledger with the other side NULL: a credit
+-- has no source account and a debit no destination. The reason and the admin
+-- who made the adjustment are kept for the audit trail.
+
+ALTER TABLE transactions ALTER COLUMN source_account_id DROP NOT NULL;
+ALTER TABLE transactions ALTER COLUMN destination_account_id DROP NOT NULL;
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'transfer',
+    ADD COLUMN IF NOT EXISTS reason TEXT,
+    ADD COLUMN IF NOT EXISTS adjusted_by TEXT;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check CHECK (
+    (kind = 'transfer' AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL)
+    OR (kind = 'adjustment' AND (source_account_id IS NULL) <> (destination_account_id IS NULL) AND reason <> '')
+);
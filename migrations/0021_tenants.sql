@@ -0,0 +1,15 @@
+-- migrations/0021_tenants.sql
+-- Accounts belong to a tenant, so that several business units can share one
+-- deployment. Callers scoped to a tenant only see its accounts and the
+-- transactions touching them. The empty tenant holds the accounts created
+-- before tenants existed and by callers without a tenant.
+--
+-- API keys carry the tenant of their holder. Scheduled transfers and transfer
+-- jobs keep the tenant they were created in and execute in it.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_accounts_tenant ON accounts(tenant_id, account_id);
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE scheduled_transfers ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE transfer_jobs ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- migrations/0022_account_owners.sql
+-- Accounts are owned by the caller (auth subject) allowed to use them. Callers
+-- other than admins can only read and send from the accounts they own; an
+-- account without an owner is only usable by admins until one is assigned.
+--
+-- Scheduled transfers and transfer jobs keep the owner they were created by
+-- and execute on its behalf.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_accounts_owner ON accounts(owner_id, account_id);
+
+ALTER TABLE scheduled_transfers ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE transfer_jobs ADD COLUMN IF NOT EXISTS owner_id TEXT NOT NULL DEFAULT '';
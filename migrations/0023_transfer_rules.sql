@@ -0,0 +1,45 @@
+-- migrations/0023_transfer_rules.sql
+-- Fraud and velocity rules evaluated before a transfer is committed. Rules are
+-- checked in priority order (then id) and the first matching one decides:
+-- block records the transfer as failed, flag lets it through and queues it for
+-- review, allow lets it through without looking at later rules. Instances
+-- reload the rules periodically, so changes apply without a restart.
+--
+-- A rule matches transfers out of source_account_id (any if NULL) over
+-- min_amount (any if NULL) that, depending on its kind:
+-- - velocity: follow max_count succeeded transfers out of the same account
+--   within the last window_seconds
+-- - new_counterparty: go to an account the source never sent to before
+-- - amount: always
+
+CREATE TABLE IF NOT EXISTS transfer_rules (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('velocity', 'new_counterparty', 'amount')),
+    action TEXT NOT NULL CHECK (action IN ('block', 'flag', 'allow')),
+    priority INT NOT NULL DEFAULT 0,
+    source_account_id BIGINT,
+    min_amount NUMERIC(30,10),
+    max_count INT,
+    window_seconds INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (kind <> 'velocity' OR (max_count > 0 AND window_seconds > 0))
+);
+
+-- Flagged transfers awaiting (or having had) an admin's review. The rule name
+-- is copied so that reviews outlive the rule.
+CREATE TABLE IF NOT EXISTS transfer_reviews (
+    transaction_id BIGINT PRIMARY KEY REFERENCES transactions(id),
+    rule_id BIGINT REFERENCES transfer_rules(id) ON DELETE SET NULL,
+    rule_name TEXT NOT NULL,
+    review_status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (review_status IN ('pending', 'approved', 'rejected')),
+    reviewed_by TEXT,
+    flagged_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_reviews_status ON transfer_reviews(review_status, transaction_id);
+-- Lookups of earlier transfers between the same accounts for new_counterparty rules
+CREATE INDEX IF NOT EXISTS idx_transactions_pair_succeeded
+    ON transactions(source_account_id, destination_account_id) WHERE status = 'succeeded';
@@ -0,0 +1,11 @@
+-- migrations/0024_account_external_ids.sql
+-- Accounts can carry the identifier upstream systems know them by (an opaque
+-- string, typically a UUID) and be addressed by it instead of account_id.
+-- Accounts created with only an external id get their account_id from
+-- accounts_account_id_seq, which starts past the ids chosen by clients so far.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS external_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_external_id ON accounts(external_id);
+
+CREATE SEQUENCE IF NOT EXISTS accounts_account_id_seq;
+SELECT setval('accounts_account_id_seq', GREATEST((SELECT MAX(account_id) FROM accounts), 0) + 1, false);
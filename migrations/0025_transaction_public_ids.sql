@@ -0,0 +1,10 @@
+-- migrations/0025_transaction_public_ids.sql
+-- Transactions get a public UUID that the API exposes in place of the serial
+-- id, which reveals how many transactions there are. The service generates
+-- UUIDv7s before inserting, so there is no default once existing rows are
+-- backfilled (with random v4 UUIDs: their creation order is already known
+-- from created_at).
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE transactions ALTER COLUMN public_id DROP DEFAULT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_transactions_public_id ON transactions(public_id);
@@ -0,0 +1,23 @@
+-- migrations/0026_outbox_delivery.sql
+-- The relay marks events delivered instead of deleting them, so that recent
+-- deliveries can be audited and replayed; delivered events are purged after a
+-- retention period. Failed deliveries are counted on the event, and events
+-- that can never be delivered move to outbox_dead_letters rather than block
+-- those behind them.
+
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS delivered_at TIMESTAMPTZ;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS last_error TEXT;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_undelivered ON outbox_events(id) WHERE delivered_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_delivered_at ON outbox_events(delivered_at) WHERE delivered_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS outbox_dead_letters (
+    id BIGINT PRIMARY KEY, -- the id the event had in outbox_events
+    created_at TIMESTAMPTZ NOT NULL,
+    event_type TEXT NOT NULL,
+    event_key TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INT NOT NULL,
+    last_error TEXT NOT NULL,
+    dead_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
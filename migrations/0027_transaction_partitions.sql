@@ -0,0 +1,113 @@
+-- migrations/0027_transaction_partitions.sql
+-- Transactions are partitioned by the month (UTC) they were created in, so
+-- that queries bounded in time only read the months they cover and old months
+-- can be archived by detaching their partition instead of deleting rows. The
+-- existing rows are copied into one partition per month; the service creates
+-- the partitions of the months ahead, and the default partition only catches
+-- rows outside of them until their month gets its partition.
+--
+-- Unique indexes of a partitioned table must include the partition key, so
+-- the primary key becomes (id, created_at): ids still come from the sequence,
+-- and public ids are UUIDv7s generated by the service. Foreign keys can only
+-- reference a unique constraint, and archived transactions leave the table, so
+-- holds, scheduled transfers, job rows, reviews and reversals keep the id of
+-- their transaction without a foreign key. Reversals are still one per
+-- transaction: ReverseTransaction locks the original while checking.
+
+SET LOCAL TIME ZONE 'UTC';
+
+ALTER TABLE transactions RENAME TO transactions_unpartitioned;
+ALTER TABLE transactions_unpartitioned RENAME CONSTRAINT transactions_pkey TO transactions_unpartitioned_pkey;
+ALTER TABLE transactions_unpartitioned DROP CONSTRAINT IF EXISTS transactions_reversal_of_fkey;
+ALTER TABLE holds DROP CONSTRAINT IF EXISTS holds_transaction_id_fkey;
+ALTER TABLE scheduled_transfers DROP CONSTRAINT IF EXISTS scheduled_transfers_transaction_id_fkey;
+ALTER TABLE transfer_job_rows DROP CONSTRAINT IF EXISTS transfer_job_rows_transaction_id_fkey;
+ALTER TABLE transfer_reviews DROP CONSTRAINT IF EXISTS transfer_reviews_transaction_id_fkey;
+
+CREATE TABLE transactions (
+    id BIGINT NOT NULL DEFAULT nextval('transactions_id_seq'),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    source_account_id BIGINT,
+    destination_account_id BIGINT,
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    status TEXT NOT NULL,
+    error_message TEXT,
+    currency TEXT NOT NULL DEFAULT 'USD' CHECK (currency ~ '^[A-Z]{3}$'),
+    reversal_of BIGINT,
+    reference TEXT NOT NULL DEFAULT '',
+    purpose_code TEXT NOT NULL DEFAULT '',
+    settled_at TIMESTAMPTZ DEFAULT now(),
+    kind TEXT NOT NULL DEFAULT 'transfer',
+    reason TEXT,
+    adjusted_by TEXT,
+    public_id UUID NOT NULL,
+    PRIMARY KEY (id, created_at),
+    CONSTRAINT transactions_kind_check CHECK (
+        (kind = 'transfer' AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL)
+        OR (kind = 'adjustment' AND (source_account_id IS NULL) <> (destination_account_id IS NULL) AND reason <> '')
+    )
+) PARTITION BY RANGE (created_at);
+ALTER SEQUENCE transactions_id_seq OWNED BY transactions.id;
+
+CREATE TABLE transactions_default PARTITION OF transactions DEFAULT;
+
+-- One partition per month, from the oldest transaction to three months ahead
+DO $$
+DECLARE
+    m TIMESTAMPTZ := date_trunc('month', LEAST(now(), (SELECT min(created_at) FROM transactions_unpartitioned)));
+BEGIN
+    WHILE m < date_trunc('month', now()) + interval '4 months' LOOP
+        EXECUTE format('CREATE TABLE %I PARTITION OF transactions FOR VALUES FROM (%L) TO (%L)',
+            'transactions_' || to_char(m, 'YYYY_MM'), m, m + interval '1 month');
+        m := m + interval '1 month';
+    END LOOP;
+END $$;
+
+INSERT INTO transactions (id, created_at, source_account_id, destination_account_id, amount, status, error_message, currency,
+    reversal_of, reference, purpose_code, settled_at, kind, reason, adjusted_by, public_id)
+SELECT id, created_at, source_account_id, destination_account_id, amount, status, error_message, currency,
+    reversal_of, reference, purpose_code, settled_at, kind, reason, adjusted_by, public_id
+FROM transactions_unpartitioned;
+
+DROP TABLE transactions_unpartitioned;
+
+CREATE INDEX idx_transactions_source ON transactions(source_account_id);
+CREATE INDEX idx_transactions_destination ON transactions(destination_account_id);
+CREATE INDEX idx_transactions_pending ON transactions(id) WHERE status = 'pending';
+CREATE INDEX idx_transactions_reversal_of ON transactions(reversal_of) WHERE reversal_of IS NOT NULL;
+CREATE INDEX idx_transactions_source_succeeded ON transactions(source_account_id, created_at) WHERE status = 'succeeded';
+CREATE INDEX idx_transactions_source_settled ON transactions(source_account_id, settled_at) WHERE status = 'succeeded';
+CREATE INDEX idx_transactions_destination_settled ON transactions(destination_account_id, settled_at) WHERE status = 'succeeded';
+CREATE INDEX idx_transactions_settled ON transactions(settled_at) WHERE status = 'succeeded';
+CREATE INDEX idx_transactions_created_at_id ON transactions(created_at, id);
+CREATE INDEX idx_transactions_amount_id ON transactions(amount, id);
+CREATE INDEX idx_transactions_pair_succeeded ON transactions(source_account_id, destination_account_id) WHERE status = 'succeeded';
+CREATE UNIQUE INDEX idx_transactions_public_id ON transactions(public_id, created_at);
+
+-- Archived months. A month is recorded, with the net of its succeeded
+-- transactions added to archived_balances, in the transaction detaching its
+-- partition, so that balances always reconcile; archived_at is set once its
+-- rows have moved to transactions_archive or been exported. settled_through
+-- is when the last of its transactions settled: balance history before it is
+-- no longer known.
+CREATE TABLE IF NOT EXISTS transaction_archives (
+    month TIMESTAMPTZ PRIMARY KEY,
+    row_count BIGINT NOT NULL,
+    settled_through TIMESTAMPTZ,
+    exported BOOLEAN NOT NULL DEFAULT false,
+    detached_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    archived_at TIMESTAMPTZ
+);
+
+-- The net of the archived succeeded transactions of each account, part of its
+-- ledger balance
+CREATE TABLE IF NOT EXISTS archived_balances (
+    account_id BIGINT PRIMARY KEY,
+    net NUMERIC(30,10) NOT NULL
+);
+
+-- Archived transactions, unless they are exported out of the database
+CREATE TABLE IF NOT EXISTS transactions_archive (LIKE transactions);
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE transactions_archive ADD PRIMARY KEY (id);
+CREATE INDEX IF NOT EXISTS idx_transactions_archive_public_id ON transactions_archive(public_id);
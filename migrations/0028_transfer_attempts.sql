@@ -0,0 +1,22 @@
+-- migrations/0028_transfer_attempts.sql
+-- Transfer requests the API rejected, e.g. for an invalid amount, the same
+-- account on both sides or a frozen account, with the caller and a summary of
+-- the payload, for fraud analysis. Accounts and amounts are kept as sent since
+-- they may not be valid. Only written when RECORD_TRANSFER_ATTEMPTS is on.
+
+CREATE TABLE IF NOT EXISTS transfer_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    source_account TEXT NOT NULL DEFAULT '',
+    destination_account TEXT NOT NULL DEFAULT '',
+    amount TEXT NOT NULL DEFAULT '',
+    reference TEXT NOT NULL DEFAULT '',
+    status INT NOT NULL,
+    code TEXT NOT NULL,
+    message TEXT NOT NULL,
+    subject TEXT NOT NULL DEFAULT '',
+    tenant_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_transfer_attempts_tenant ON transfer_attempts(tenant_id, id);
+CREATE INDEX IF NOT EXISTS idx_transfer_attempts_subject ON transfer_attempts(subject, id);
@@ -0,0 +1,13 @@
+-- migrations/0029_transaction_origin.sql
+-- Who initiated each transaction (the API key or JWT subject, empty without
+-- authentication), the channel it came through (http, batch or scheduler)
+-- and the id of the request that made it. Rows from before this migration,
+-- hold captures, reversals and adjustments leave them empty.
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS initiated_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS initiated_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT '';
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
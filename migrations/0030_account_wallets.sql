@@ -0,0 +1,9 @@
+-- migrations/0030_account_wallets.sql
+-- Accounts can hold labelled wallets: accounts of their own, with their own
+-- balance and ledger, that name their parent and are addressed as
+-- "<parent>/<wallet>" in transfers. A wallet takes the currency, tenant and
+-- owner of its parent and cannot have wallets itself.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS parent_account_id BIGINT REFERENCES accounts(account_id);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS wallet TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_wallet ON accounts(parent_account_id, wallet);
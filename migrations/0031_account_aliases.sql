@@ -0,0 +1,13 @@
+-- migrations/0031_account_aliases.sql
+-- Human-readable names of accounts, e.g. "ops-float-usd", accepted wherever an
+-- account id is. An account may have several; an alias names one account.
+-- Names are looked up as external ids first, so an alias equal to an
+-- external id is refused when registered.
+
+CREATE TABLE IF NOT EXISTS account_aliases (
+    alias TEXT PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_aliases_account ON account_aliases(account_id);
@@ -0,0 +1,6 @@
+-- migrations/0032_account_min_balance.sql
+-- The balance transfers out of an account may not take it below, for float
+-- accounts that must keep a buffer; NULL means none. Unlike the transfer
+-- limits it has no service-wide default.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS min_balance NUMERIC(30,10) CHECK (min_balance >= 0);
@@ -0,0 +1,32 @@
+-- migrations/0033_fee_policies.sql
+-- Fee policies charge transfers in their currency a flat fee plus a
+-- percentage of the amount, credited to fee_account_id. A policy applies to
+-- the accounts of tenant_id (any if NULL) tagged account_tag (any if NULL);
+-- the first matching one by priority, then id, decides the fee. The fee is
+-- recorded as a transaction of its own, of kind 'fee', from the source of
+-- the transfer to the fee account, with fee_of the transfer it was charged on.
+
+CREATE TABLE IF NOT EXISTS fee_policies (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 0,
+    tenant_id TEXT,
+    account_tag TEXT,
+    currency TEXT NOT NULL CHECK (currency ~ '^[A-Z]{3}$'),
+    flat_fee NUMERIC(30,10) NOT NULL DEFAULT 0 CHECK (flat_fee >= 0),
+    percentage NUMERIC(9,6) NOT NULL DEFAULT 0 CHECK (percentage >= 0 AND percentage < 100),
+    fee_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (flat_fee > 0 OR percentage > 0)
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_of BIGINT;
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS fee_of BIGINT;
+CREATE INDEX IF NOT EXISTS idx_transactions_fee_of ON transactions(fee_of) WHERE fee_of IS NOT NULL;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check CHECK (
+    (kind = 'transfer' AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL)
+    OR (kind = 'adjustment' AND (source_account_id IS NULL) <> (destination_account_id IS NULL) AND reason <> '')
+    OR (kind = 'fee' AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL AND fee_of IS NOT NULL)
+);
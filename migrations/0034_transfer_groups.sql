@@ -0,0 +1,8 @@
+-- migrations/0034_transfer_groups.sql
+-- The legs of a split transfer, one source funding several destinations or
+-- several sources funding one destination, share a transfer_group made up
+-- when the split is performed. It is NULL for other transactions.
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS transfer_group UUID;
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS transfer_group UUID;
+CREATE INDEX IF NOT EXISTS idx_transactions_transfer_group ON transactions(transfer_group) WHERE transfer_group IS NOT NULL;
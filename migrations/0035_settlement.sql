@@ -0,0 +1,50 @@
+-- migrations/0035_settlement.sql
+-- Net settlement between clearing accounts. Transfers between two accounts
+-- designated in clearing_accounts are accepted into clearing_transfers
+-- without moving balances, dated with the business day they fall in. Once the
+-- cutoff ending the day has passed, they are netted per pair of accounts and
+-- one transaction, of kind 'settlement', moves the net from the account
+-- owing it to the other. settlements records it per pair and day, account_a
+-- being the lower id and net what account_a owes account_b; transaction_id
+-- is NULL when the net is zero.
+
+CREATE TABLE IF NOT EXISTS clearing_accounts (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(account_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS settlements (
+    id BIGSERIAL PRIMARY KEY,
+    business_date DATE NOT NULL,
+    account_a BIGINT NOT NULL,
+    account_b BIGINT NOT NULL,
+    currency TEXT NOT NULL,
+    net NUMERIC(30,10) NOT NULL,
+    transaction_id BIGINT,
+    settled_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (business_date, account_a, account_b),
+    CHECK (account_a < account_b)
+);
+
+CREATE TABLE IF NOT EXISTS clearing_transfers (
+    id BIGSERIAL PRIMARY KEY,
+    business_date DATE NOT NULL,
+    source_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    settlement_id BIGINT REFERENCES settlements(id),
+    CHECK (source_account_id <> destination_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_clearing_transfers_business_date ON clearing_transfers(business_date);
+CREATE INDEX IF NOT EXISTS idx_clearing_transfers_unsettled ON clearing_transfers(business_date) WHERE settlement_id IS NULL;
+
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_kind_check;
+ALTER TABLE transactions ADD CONSTRAINT transactions_kind_check CHECK (
+    (kind IN ('transfer', 'settlement') AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL)
+    OR (kind = 'adjustment' AND (source_account_id IS NULL) <> (destination_account_id IS NULL) AND reason <> '')
+    OR (kind = 'fee' AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL AND fee_of IS NOT NULL)
+);
@@ -0,0 +1,26 @@
+-- migrations/0036_sweep_rules.sql
+-- Sweep rules move the available balance of account_id above threshold to
+-- target_account_id; a background worker evaluates them. An account has at
+-- most one rule. Each sweep made is kept in sweep_executions, with the
+-- transaction that moved the excess, until its rule is deleted.
+
+CREATE TABLE IF NOT EXISTS sweep_rules (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL UNIQUE REFERENCES accounts(account_id),
+    target_account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    threshold NUMERIC(30,10) NOT NULL CHECK (threshold >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CHECK (account_id <> target_account_id)
+);
+
+CREATE TABLE IF NOT EXISTS sweep_executions (
+    id BIGSERIAL PRIMARY KEY,
+    rule_id BIGINT NOT NULL REFERENCES sweep_rules(id) ON DELETE CASCADE,
+    account_id BIGINT NOT NULL,
+    target_account_id BIGINT NOT NULL,
+    amount NUMERIC(30,10) NOT NULL CHECK (amount > 0),
+    transaction_id BIGINT NOT NULL,
+    executed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sweep_executions_rule ON sweep_executions(rule_id, id);
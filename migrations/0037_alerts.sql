@@ -0,0 +1,30 @@
+-- migrations/0037_alerts.sql
+-- Alert thresholds of accounts, and the alerts raised when a transfer crosses
+-- them: the available balance falling below low_balance, or a transfer into
+-- or out of the account of at least large_transfer. An account has at most
+-- one active low-balance alert, resolved once a later transfer leaves the
+-- balance at or above the threshold; other alerts stay active until resolved
+-- through the API. value is the balance or amount that raised the alert.
+
+CREATE TABLE IF NOT EXISTS alert_thresholds (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(account_id),
+    low_balance NUMERIC(30,10) CHECK (low_balance >= 0),
+    large_transfer NUMERIC(30,10) CHECK (large_transfer > 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS alerts (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    kind TEXT NOT NULL CHECK (kind IN ('low_balance', 'large_transfer')),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'resolved')),
+    threshold NUMERIC(30,10) NOT NULL,
+    value NUMERIC(30,10) NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_alerts_account ON alerts(account_id, status, id);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_alerts_low_balance ON alerts(account_id)
+    WHERE kind = 'low_balance' AND status = 'active';
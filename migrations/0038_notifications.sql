@@ -0,0 +1,14 @@
+-- migrations/0038_notifications.sql
+-- Operators are notified once of each transfer flagged for review and of
+-- each dead-lettered outbox event: notified_at records that a worker claimed
+-- it for a notification. Dead letters from before notifications existed are
+-- marked as notified, so that they do not all arrive at once.
+
+ALTER TABLE transfer_reviews ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_transfer_reviews_unnotified ON transfer_reviews(transaction_id)
+    WHERE review_status = 'pending' AND notified_at IS NULL;
+
+ALTER TABLE outbox_dead_letters ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ;
+UPDATE outbox_dead_letters SET notified_at = dead_at WHERE notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_dead_letters_unnotified ON outbox_dead_letters(id)
+    WHERE notified_at IS NULL;
@@ -0,0 +1,12 @@
+-- migrations/0039_anomaly_detection.sql
+-- A background analyzer compares each succeeded transfer with the baseline
+-- of its source account and queues those that deviate in transfer_reviews,
+-- alongside the transfers flagged by rules, with the reason. anomaly_scan
+-- holds the id of the last transaction it analyzed.
+
+ALTER TABLE transfer_reviews ADD COLUMN IF NOT EXISTS reason TEXT;
+
+CREATE TABLE IF NOT EXISTS anomaly_scan (
+    id BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    last_transaction_id BIGINT NOT NULL
+);
@@ -0,0 +1,12 @@
+-- migrations/0040_counterparty_allowlists.sql
+-- Counterparty allowlists of accounts: the accounts an account may transfer
+-- to (outgoing) and receive transfers from (incoming). A NULL list leaves the
+-- direction unrestricted; an empty one allows no counterparty. Transfers
+-- between a pair either list excludes fail with 'counterparty not allowed'.
+
+CREATE TABLE IF NOT EXISTS counterparty_allowlists (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(account_id),
+    outgoing BIGINT[],
+    incoming BIGINT[],
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,9 @@
+-- migrations/0041_account_kyc_status.sql
+-- KYC status of accounts: unverified, verified or blocked. Accounts opened
+-- from now on start unverified; existing ones are taken as verified. Blocked
+-- accounts can neither send nor receive transfers; what unverified ones may
+-- do is configured on the server.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS kyc_status TEXT NOT NULL DEFAULT 'verified'
+    CHECK (kyc_status IN ('unverified', 'verified', 'blocked'));
+ALTER TABLE accounts ALTER COLUMN kyc_status SET DEFAULT 'unverified';
@@ -0,0 +1,24 @@
+-- migrations/0042_rule_conditions.sql
+-- Transfer rules gain conditions on the destination account, a maximum
+-- amount, the tenant of the source account and the time of day (UTC, the
+-- window wrapping past midnight when active_from is later than active_until),
+-- the match kind, which matches on these conditions alone, and the
+-- require_approval action: a matching transfer is recorded pending, queued for
+-- review, and executed once the review is approved.
+
+ALTER TABLE transfer_rules
+    ADD COLUMN IF NOT EXISTS destination_account_id BIGINT,
+    ADD COLUMN IF NOT EXISTS max_amount NUMERIC(30,10),
+    ADD COLUMN IF NOT EXISTS tenant_id TEXT,
+    ADD COLUMN IF NOT EXISTS active_from TIME,
+    ADD COLUMN IF NOT EXISTS active_until TIME;
+
+ALTER TABLE transfer_rules DROP CONSTRAINT IF EXISTS transfer_rules_kind_check;
+ALTER TABLE transfer_rules ADD CONSTRAINT transfer_rules_kind_check
+    CHECK (kind IN ('velocity', 'new_counterparty', 'amount', 'match'));
+ALTER TABLE transfer_rules DROP CONSTRAINT IF EXISTS transfer_rules_action_check;
+ALTER TABLE transfer_rules ADD CONSTRAINT transfer_rules_action_check
+    CHECK (action IN ('block', 'flag', 'allow', 'require_approval'));
+ALTER TABLE transfer_rules DROP CONSTRAINT IF EXISTS transfer_rules_active_check;
+ALTER TABLE transfer_rules ADD CONSTRAINT transfer_rules_active_check
+    CHECK ((active_from IS NULL) = (active_until IS NULL));
@@ -0,0 +1,6 @@
+-- migrations/0043_api_key_networks.sql
+-- API keys can be restricted to the networks their holder connects from;
+-- requests with the key from other addresses are refused. An empty list
+-- allows any network.
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS allowed_networks CIDR[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,16 @@
+-- migrations/0044_account_erasures.sql
+-- Erasures of the personal data of closed accounts: their display name, owner
+-- reference, tags, external id and aliases are cleared, while the account and
+-- its ledger entries are kept. Each erasure is recorded here with the admin
+-- who made it, the reason and the fields that held data, for the audit trail.
+
+CREATE TABLE IF NOT EXISTS account_erasures (
+    id BIGSERIAL PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES accounts(account_id),
+    reason TEXT NOT NULL CHECK (reason <> ''),
+    erased_by TEXT NOT NULL DEFAULT '',
+    fields TEXT[] NOT NULL DEFAULT '{}',
+    erased_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS account_erasures_account_id_idx ON account_erasures (account_id, id);
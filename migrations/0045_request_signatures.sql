@@ -0,0 +1,11 @@
+-- migrations/0045_request_signatures.sql
+-- Signatures of the signed requests accepted by any instance, kept until their
+-- timestamp leaves the allowed clock skew, so that a captured request cannot
+-- be replayed against another instance.
+
+CREATE TABLE IF NOT EXISTS request_signatures (
+    signature TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS request_signatures_expires_at_idx ON request_signatures (expires_at);
@@ -0,0 +1,10 @@
+-- migrations/0046_alert_notifications.sql
+-- Alerts are raised by the store in the DB transaction of every balance
+-- change, and operators are notified of each once, like flagged transfers:
+-- notified_at records that a worker claimed it for a notification. Alerts
+-- from before are marked as notified, since they were notified when raised.
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS notified_at TIMESTAMPTZ;
+UPDATE alerts SET notified_at = created_at WHERE notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_alerts_unnotified ON alerts(id)
+    WHERE status = 'active' AND notified_at IS NULL;
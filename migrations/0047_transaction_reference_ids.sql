@@ -0,0 +1,25 @@
+-- migrations/0047_transaction_reference_ids.sql
+-- Reversals and fees reference the transaction they reverse or are charged
+-- on by its public UUID as well as its serial id, so that the API can link
+-- them without exposing serial ids. Existing references are backfilled from
+-- both the live and the archived transactions.
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reversal_of_public_id UUID;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_of_public_id UUID;
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS reversal_of_public_id UUID;
+ALTER TABLE transactions_archive ADD COLUMN IF NOT EXISTS fee_of_public_id UUID;
+
+CREATE TEMPORARY TABLE transaction_public_ids ON COMMIT DROP AS
+    SELECT id, public_id FROM transactions
+    UNION ALL
+    SELECT id, public_id FROM transactions_archive;
+CREATE INDEX ON transaction_public_ids(id);
+
+UPDATE transactions t SET reversal_of_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = t.reversal_of AND t.reversal_of_public_id IS NULL;
+UPDATE transactions t SET fee_of_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = t.fee_of AND t.fee_of_public_id IS NULL;
+UPDATE transactions_archive t SET reversal_of_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = t.reversal_of AND t.reversal_of_public_id IS NULL;
+UPDATE transactions_archive t SET fee_of_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = t.fee_of AND t.fee_of_public_id IS NULL;
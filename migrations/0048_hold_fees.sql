@@ -0,0 +1,7 @@
+-- migrations/0048_hold_fees.sql
+-- A hold reserves the fee its capture will be charged along with its amount:
+-- fee is credited to fee_account_id on capture, and held_balance covers
+-- amount + fee of the pending holds. Existing holds are charged no fee.
+
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS fee NUMERIC(30,10) NOT NULL DEFAULT 0 CHECK (fee >= 0);
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS fee_account_id BIGINT REFERENCES accounts(account_id);
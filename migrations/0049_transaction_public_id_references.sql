@@ -0,0 +1,32 @@
+-- migrations/0049_transaction_public_id_references.sql
+-- Holds, scheduled transfers, alerts, sweep executions, settlements and
+-- statement entries reference their transaction by its public UUID as well as
+-- its serial id, so that the API can return it without exposing serial ids.
+-- Existing references are backfilled from both the live and the archived
+-- transactions.
+
+ALTER TABLE holds ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+ALTER TABLE scheduled_transfers ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+ALTER TABLE sweep_executions ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+ALTER TABLE settlements ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+ALTER TABLE statement_entries ADD COLUMN IF NOT EXISTS transaction_public_id UUID;
+
+CREATE TEMPORARY TABLE transaction_public_ids ON COMMIT DROP AS
+    SELECT id, public_id FROM transactions
+    UNION ALL
+    SELECT id, public_id FROM transactions_archive;
+CREATE INDEX ON transaction_public_ids(id);
+
+UPDATE holds r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
+UPDATE scheduled_transfers r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
+UPDATE alerts r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
+UPDATE sweep_executions r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
+UPDATE settlements r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
+UPDATE statement_entries r SET transaction_public_id = p.public_id
+    FROM transaction_public_ids p WHERE p.id = r.transaction_id AND r.transaction_public_id IS NULL;
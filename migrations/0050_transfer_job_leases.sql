@@ -0,0 +1,13 @@
+-- migrations/0050_transfer_job_leases.sql
+-- A claimed transfer job holds a lease renewed while its rows run; a running
+-- job whose lease lapsed, e.g. after a crash, is claimed again. Each row gets
+-- the public id of its transaction up front, so a row transferred before the
+-- interruption is found instead of being paid twice. Jobs already running
+-- start a lease now, and existing rows are backfilled with random v4 UUIDs.
+
+ALTER TABLE transfer_jobs ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+UPDATE transfer_jobs SET claimed_at = now() WHERE status = 'running' AND claimed_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_transfer_jobs_running ON transfer_jobs(claimed_at) WHERE status = 'running';
+
+ALTER TABLE transfer_job_rows ADD COLUMN IF NOT EXISTS transaction_public_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE transfer_job_rows ALTER COLUMN transaction_public_id DROP DEFAULT;
@@ -0,0 +1,10 @@
+-- migrations/0051_initiated_by.sql
+-- Scheduled transfers and transfer jobs keep the subject of the caller that
+-- created them, which their transactions are recorded as initiated by. The
+-- owner, empty for admins, was used before; existing rows keep it.
+
+ALTER TABLE scheduled_transfers ADD COLUMN IF NOT EXISTS initiated_by TEXT NOT NULL DEFAULT '';
+UPDATE scheduled_transfers SET initiated_by = owner_id WHERE initiated_by = '';
+
+ALTER TABLE transfer_jobs ADD COLUMN IF NOT EXISTS initiated_by TEXT NOT NULL DEFAULT '';
+UPDATE transfer_jobs SET initiated_by = owner_id WHERE initiated_by = '';
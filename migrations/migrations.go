@@ -0,0 +1,11 @@
+// Package migrations embeds the SQL schema migrations so the server can
+// apply them on startup.
+package migrations
+
+import "embed"
+
+// FS holds the *.sql migration files. They are applied in lexical filename
+// order, so new files must sort after the existing ones (0002_..., 0003_...).
+//
+//go:embed *.sql
+var FS embed.FS
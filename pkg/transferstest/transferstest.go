@@ -0,0 +1,157 @@
+// Package transferstest runs the transfers API in-process for the tests of
+// services that call it, so that they need neither the real service nor a
+// database server.
+//
+//	srv := transferstest.NewServer(t)
+//	srv.SeedAccount(1, "100")
+//	srv.SeedAccount(2, "0")
+//	// ... point the client under test at srv.URL and make a transfer ...
+//	srv.AssertBalance(2, "25")
+package transferstest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+
+	"github.com/you/internal-transfers/internal/api"
+	"github.com/you/internal-transfers/internal/store"
+	"github.com/you/internal-transfers/internal/store/sqlite"
+	"github.com/you/internal-transfers/migrations"
+)
+
+// DefaultCurrency is the currency of seeded accounts that do not set one
+const DefaultCurrency = "USD"
+
+// backend is the part of the stores the harness needs besides the API
+type backend interface {
+	api.StoreAPI
+	CreateAccount(ctx context.Context, accountID int64, initial decimal.Decimal, currency string, meta store.AccountMetadata) error
+}
+
+// Server is an httptest.Server serving the versioned API routes (/v1/...)
+// from a store of its own. It is closed when the test ends.
+type Server struct {
+	*httptest.Server
+	t     testing.TB
+	store backend
+}
+
+type config struct {
+	postgresDSN string
+}
+
+// Option configures a Server
+type Option func(*config)
+
+// WithPostgres serves the API from the Postgres database at dsn, applying
+// pending migrations first, instead of an in-memory SQLite database. Tests
+// sharing the database must seed distinct account ids.
+func WithPostgres(dsn string) Option {
+	return func(c *config) {
+		c.postgresDSN = dsn
+	}
+}
+
+// NewServer starts a Server backed by an in-memory SQLite database, unless
+// WithPostgres is given. It fails t if the store cannot be set up.
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	var cfg config
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var s backend
+	if cfg.postgresDSN != "" {
+		s = openPostgres(t, cfg.postgresDSN)
+	} else {
+		lite, err := sqlite.Open(":memory:")
+		if err != nil {
+			t.Fatalf("transferstest: open sqlite: %v", err)
+		}
+		t.Cleanup(func() { _ = lite.Close() })
+		s = lite
+	}
+
+	r := mux.NewRouter()
+	r.NotFoundHandler = api.NotFoundHandler()
+	r.MethodNotAllowedHandler = api.MethodNotAllowedHandler()
+	api.New(s).RegisterRoutes(r)
+
+	srv := &Server{Server: httptest.NewServer(r), t: t, store: s}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// openPostgres connects to dsn and brings its schema up to date.
+func openPostgres(t testing.TB, dsn string) *store.Store {
+	t.Helper()
+	ctx := context.Background()
+	pool, err := store.Connect(ctx, dsn, store.DefaultPoolConfig())
+	if err != nil {
+		t.Fatalf("transferstest: connect to postgres: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	if _, err := store.Migrate(ctx, pool, migrations.FS); err != nil {
+		t.Fatalf("transferstest: migrate: %v", err)
+	}
+	return store.NewStore(pool)
+}
+
+// Account is an account to seed
+type Account struct {
+	ID       int64
+	Balance  string // a decimal, e.g. "100.50"
+	Currency string // DefaultCurrency if empty
+}
+
+// Seed creates the accounts, failing the test if one cannot be created.
+func (s *Server) Seed(accs ...Account) {
+	s.t.Helper()
+	for _, acc := range accs {
+		balance, err := decimal.NewFromString(acc.Balance)
+		if err != nil {
+			s.t.Fatalf("transferstest: account %d: invalid balance %q: %v", acc.ID, acc.Balance, err)
+		}
+		currency := acc.Currency
+		if currency == "" {
+			currency = DefaultCurrency
+		}
+		if err := s.store.CreateAccount(context.Background(), acc.ID, balance, currency, store.AccountMetadata{}); err != nil {
+			s.t.Fatalf("transferstest: create account %d: %v", acc.ID, err)
+		}
+	}
+}
+
+// SeedAccount creates an account with balance in DefaultCurrency.
+func (s *Server) SeedAccount(id int64, balance string) {
+	s.t.Helper()
+	s.Seed(Account{ID: id, Balance: balance})
+}
+
+// Balance returns the current balance of the account, failing the test if it does not exist.
+func (s *Server) Balance(id int64) decimal.Decimal {
+	s.t.Helper()
+	acc, err := s.store.GetAccount(context.Background(), id)
+	if err != nil {
+		s.t.Fatalf("transferstest: get account %d: %v", id, err)
+	}
+	return acc.Balance
+}
+
+// AssertBalance fails the test unless the account's balance equals want, a
+// decimal compared by value ("10" equals "10.00").
+func (s *Server) AssertBalance(id int64, want string) {
+	s.t.Helper()
+	w, err := decimal.NewFromString(want)
+	if err != nil {
+		s.t.Fatalf("transferstest: invalid balance %q: %v", want, err)
+	}
+	if got := s.Balance(id); !got.Equal(w) {
+		s.t.Errorf("account %d: expected balance %s, got %s", id, w, got)
+	}
+}
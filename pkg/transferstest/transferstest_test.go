@@ -0,0 +1,45 @@
+package transferstest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/you/internal-transfers/internal/model"
+)
+
+// TestServer tests a transfer made over HTTP against seeded accounts
+func TestServer(t *testing.T) {
+	srv := NewServer(t)
+	srv.Seed(Account{ID: 1, Balance: "100"}, Account{ID: 2, Balance: "0.50", Currency: "USD"})
+
+	resp, err := srv.Client().Post(srv.URL+"/v1/transactions", "application/json",
+		strings.NewReader(`{"source_account_id": 1, "destination_account_id": 2, "amount": "25.25"}`))
+	if err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	var tx model.TransactionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tx); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if tx.ID == "" {
+		t.Fatalf("expected a transaction id, got %+v", tx)
+	}
+
+	srv.AssertBalance(1, "74.75")
+	srv.AssertBalance(2, "25.75")
+}
+
+// TestServer_Isolated tests that every server has a database of its own
+func TestServer_Isolated(t *testing.T) {
+	for i := 0; i < 2; i++ {
+		srv := NewServer(t)
+		srv.SeedAccount(1, "10")
+		srv.AssertBalance(1, "10")
+	}
+}
@@ -32,8 +32,8 @@ echo ""
 
 # 2. Create Account 1
 echo "2️⃣  Create Account (ID: $ACCOUNT_1, Balance: 1000)"
-echo "   POST /accounts"
-curl -X POST "$BASE_URL/accounts" \
+echo "   POST /v1/accounts"
+curl -X POST "$BASE_URL/v1/accounts" \
   -H "Content-Type: application/json" \
   -d '{"account_id": '$ACCOUNT_1', "initial_balance": "1000.00"}'
 echo ""
@@ -41,8 +41,8 @@ echo ""
 
 # 3. Create Account 2
 echo "3️⃣  Create Account (ID: $ACCOUNT_2, Balance: 500)"
-echo "   POST /accounts"
-curl -X POST "$BASE_URL/accounts" \
+echo "   POST /v1/accounts"
+curl -X POST "$BASE_URL/v1/accounts" \
   -H "Content-Type: application/json" \
   -d '{"account_id": '$ACCOUNT_2', "initial_balance": "500.00"}'
 echo ""
@@ -50,22 +50,22 @@ echo ""
 
 # 4. Get Account 1 Balance
 echo "4️⃣  Get Account Balance (ID: $ACCOUNT_1)"
-echo "   GET /accounts/$ACCOUNT_1"
-curl -X GET "$BASE_URL/accounts/$ACCOUNT_1"
+echo "   GET /v1/accounts/$ACCOUNT_1"
+curl -X GET "$BASE_URL/v1/accounts/$ACCOUNT_1"
 echo ""
 echo ""
 
 # 5. Get Account 2 Balance
 echo "5️⃣  Get Account Balance (ID: $ACCOUNT_2)"
-echo "   GET /accounts/$ACCOUNT_2"
-curl -X GET "$BASE_URL/accounts/$ACCOUNT_2"
+echo "   GET /v1/accounts/$ACCOUNT_2"
+curl -X GET "$BASE_URL/v1/accounts/$ACCOUNT_2"
 echo ""
 echo ""
 
 # 6. Transfer Money (Account 1 -> Account 2)
 echo "6️⃣  Transfer Money (From: $ACCOUNT_1, To: $ACCOUNT_2, Amount: 100.50)"
-echo "   POST /transactions"
-curl -X POST "$BASE_URL/transactions" \
+echo "   POST /v1/transactions"
+curl -X POST "$BASE_URL/v1/transactions" \
   -H "Content-Type: application/json" \
   -d '{"source_account_id": '$ACCOUNT_1', "destination_account_id": '$ACCOUNT_2', "amount": "100.50"}'
 echo ""
@@ -73,21 +73,21 @@ echo ""
 
 # 7. Get Updated Balances
 echo "7️⃣  Get Updated Balance (ID: $ACCOUNT_1, Should be 899.50)"
-echo "   GET /accounts/$ACCOUNT_1"
-curl -X GET "$BASE_URL/accounts/$ACCOUNT_1"
+echo "   GET /v1/accounts/$ACCOUNT_1"
+curl -X GET "$BASE_URL/v1/accounts/$ACCOUNT_1"
 echo ""
 echo ""
 
 echo "8️⃣  Get Updated Balance (ID: $ACCOUNT_2, Should be 600.50)"
-echo "   GET /accounts/$ACCOUNT_2"
-curl -X GET "$BASE_URL/accounts/$ACCOUNT_2"
+echo "   GET /v1/accounts/$ACCOUNT_2"
+curl -X GET "$BASE_URL/v1/accounts/$ACCOUNT_2"
 echo ""
 echo ""
 
 # 9. Test Insufficient Funds
 echo "9️⃣  Test Insufficient Funds (Transfer: 1000 from Account 1)"
-echo "   POST /transactions (Expected: 409 Conflict)"
-curl -X POST "$BASE_URL/transactions" \
+echo "   POST /v1/transactions (Expected: 409 Conflict)"
+curl -X POST "$BASE_URL/v1/transactions" \
   -H "Content-Type: application/json" \
   -d '{"source_account_id": '$ACCOUNT_1', "destination_account_id": '$ACCOUNT_2', "amount": "1000.00"}'
 echo ""
@@ -95,8 +95,8 @@ echo ""
 
 # 10. Test Invalid Account
 echo "🔟 Test Invalid Account (ID: 999)"
-echo "   GET /accounts/999 (Expected: 404 Not Found)"
-curl -X GET "$BASE_URL/accounts/999"
+echo "   GET /v1/accounts/999 (Expected: 404 Not Found)"
+curl -X GET "$BASE_URL/v1/accounts/999"
 echo ""
 echo ""
 